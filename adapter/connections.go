@@ -0,0 +1,26 @@
+package adapter
+
+import (
+	"time"
+)
+
+type TrackedConnection struct {
+	ID           string     `json:"id"`
+	Network      string     `json:"network"`
+	Inbound      string     `json:"inbound,omitempty"`
+	InboundType  string     `json:"inbound_type"`
+	Source       string     `json:"source"`
+	Destination  string     `json:"destination"`
+	Protocol     string     `json:"protocol,omitempty"`
+	Domain       string     `json:"domain,omitempty"`
+	ProcessPath  string     `json:"process_path,omitempty"`
+	Rule         string     `json:"rule"`
+	Outbound     string     `json:"outbound"`
+	Chains       []string   `json:"chains"`
+	OutboundType string     `json:"outbound_type"`
+	Upload       int64      `json:"upload"`
+	Download     int64      `json:"download"`
+	CreatedAt    time.Time  `json:"created_at"`
+	ClosedAt     *time.Time `json:"closed_at,omitempty"`
+	Duration     string     `json:"duration"`
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"encoding/binary"
 	"net"
+	"net/netip"
 	"time"
 
 	"github.com/sagernet/sing-box/common/urltest"
@@ -31,14 +32,26 @@ type CacheFile interface {
 
 	StoreFakeIP() bool
 	FakeIPStorage
+	FakeIPLoadAll() map[netip.Addr]string
+	FakeIPDelete(address netip.Addr, domain string) error
 
 	StoreRDRC() bool
 	dns.RDRCStore
 
+	StoreTLSSession() bool
+	LoadTLSSession(key string) []byte
+	SaveTLSSession(key string, session []byte) error
+
+	StoreDNS() bool
+	LoadDNSCache() []SavedDNSResponse
+	SaveDNSCache(responses []SavedDNSResponse) error
+
 	LoadMode() string
 	StoreMode(mode string) error
 	LoadSelected(group string) string
 	StoreSelected(group string, selected string) error
+	LoadSelectedIdentity(group string) string
+	StoreSelectedIdentity(group string, identity string) error
 	LoadGroupExpand(group string) (isExpand bool, loaded bool)
 	StoreGroupExpand(group string, expand bool) error
 	LoadRuleSet(tag string) *SavedRuleSet
@@ -46,6 +59,16 @@ type CacheFile interface {
 
 	LoadOutboundProviderInfo(tag string) *OutboundProviderInfo
 	SaveOutboundProviderInfo(tag string, info *OutboundProviderInfo) error
+
+	urltest.HistoryCache
+}
+
+// SavedDNSResponse is a cached DNS response kept across restarts. ExpireAt
+// is zero if the cache does not expire.
+type SavedDNSResponse struct {
+	Transport string
+	Message   []byte
+	ExpireAt  time.Time
 }
 
 type SavedRuleSet struct {
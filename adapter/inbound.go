@@ -4,7 +4,9 @@ import (
 	"context"
 	"net"
 	"net/netip"
+	"time"
 
+	"github.com/sagernet/sing-box/common/domaintrie"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/option"
 	M "github.com/sagernet/sing/common/metadata"
@@ -24,6 +26,14 @@ type InjectableInbound interface {
 	NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata InboundContext) error
 }
 
+// TunInbound is implemented by TUN inbounds, whose TCP/IP stack can be
+// switched at runtime.
+type TunInbound interface {
+	Inbound
+	Stack() string
+	SetStack(stack string) error
+}
+
 type InboundContext struct {
 	Inbound     string
 	InboundType string
@@ -35,6 +45,7 @@ type InboundContext struct {
 	Protocol    string
 	User        string
 	Outbound    string
+	UDPTimeout  time.Duration
 
 	// cache
 
@@ -48,6 +59,9 @@ type InboundContext struct {
 	ProcessInfo          *process.Info
 	QueryType            uint16
 	FakeIP               bool
+	DisableFakeIP        bool
+	DNSSECValidated      bool
+	DomainTrieCache      domaintrie.Cache
 
 	// rule cache
 
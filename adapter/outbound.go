@@ -26,4 +26,13 @@ type OutboundProvider interface {
 	Outbound(tag string) (Outbound, bool)
 	BasicOutbounds() []Outbound
 	GroupOutbounds() []OutboundGroup
+	// OutboundIdentity returns a stable identity of a provided outbound that
+	// survives reordering and renaming across updates, or empty if unknown.
+	OutboundIdentity(tag string) string
+}
+
+// OutboundProviderUpdateListener is implemented by groups taking members
+// from outbound providers, to pick up the outbounds replaced by an update.
+type OutboundProviderUpdateListener interface {
+	OutboundProviderUpdated(tag string) error
 }
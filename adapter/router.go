@@ -7,6 +7,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-box/option"
 	dns "github.com/sagernet/sing-dns"
 	tun "github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common/control"
@@ -24,6 +25,7 @@ type Router interface {
 	PostStarter
 	Cleanup() error
 
+	Inbounds() []Inbound
 	Outbounds() []Outbound
 	Outbound(tag string) (Outbound, bool)
 	DefaultOutbound(network string) (Outbound, error)
@@ -32,13 +34,15 @@ type Router interface {
 	OutboundProviders() []OutboundProvider
 	RegisterOutboundProvider(tag string, provider OutboundProvider) error
 	CheckOutboundProvider(tag string) error
+	NotifyOutboundProviderUpdated(tag string)
 
 	FakeIPStore() FakeIPStore
 
 	ConnectionRouter
 
 	GeoIPReader() *geoip.Reader
-	LoadGeosite(code string) (Rule, error)
+	ASNReader() *geoip.ASNReader
+	LoadGeosite(codes ...string) (Rule, error)
 
 	RuleSet(tag string) (RuleSet, bool)
 	RuleSets() []RuleSet
@@ -49,6 +53,7 @@ type Router interface {
 	Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error)
 	LookupDefault(ctx context.Context, domain string) ([]netip.Addr, error)
 	ClearDNSCache()
+	DNSAdBlocker() DNSAdBlocker
 
 	InterfaceFinder() control.InterfaceFinder
 	UpdateInterfaces() error
@@ -56,12 +61,15 @@ type Router interface {
 	AutoDetectInterface() bool
 	AutoDetectInterfaceFunc() control.Func
 	DefaultMark() uint32
+	DefaultBuffer() *option.BufferOptions
 	RegisterAutoRedirectOutputMark(mark uint32) error
 	AutoRedirectOutputMark() uint32
+	RegisterMultipathTCPInterfaces(interfaces []string, flags uint32) error
 	NetworkMonitor() tun.NetworkUpdateMonitor
 	InterfaceMonitor() tun.DefaultInterfaceMonitor
 	PackageManager() tun.PackageManager
 	WIFIState() WIFIState
+	TimeFunc() func() time.Time
 	Rules() []Rule
 
 	ClashServer() ClashServer
@@ -70,6 +78,12 @@ type Router interface {
 	V2RayServer() V2RayServer
 	SetV2RayServer(server V2RayServer)
 
+	AppendTracker(tracker ConnectionTracker)
+	Connections() []TrackedConnection
+	ClosedConnections() []TrackedConnection
+	CloseConnection(id string) bool
+	TraceNextConnection(ctx context.Context, match func(metadata *InboundContext, outbound string) bool) (context.Context, error)
+
 	ResetNetwork() error
 }
 
@@ -92,6 +106,9 @@ type Rule interface {
 	Type() string
 	UpdateGeosite() error
 	Outbound() string
+	UDPTimeout() time.Duration
+	Mirror() string
+	Action() option.RuleAction
 }
 
 type DNSRule interface {
@@ -99,10 +116,44 @@ type DNSRule interface {
 	DisableCache() bool
 	RewriteTTL() *uint32
 	ClientSubnet() *netip.Prefix
+	DisableFakeIP() bool
+	StaticTransport() DNSStaticTransport
+	ResponseFilter() DNSResponseFilter
+	Detour() string
 	WithAddressLimit() bool
 	MatchAddressLimit(metadata *InboundContext) bool
 }
 
+// DNSStaticTransport answers queries for the static DNS rule action.
+type DNSStaticTransport interface {
+	dns.Transport
+	Contains(domain string) bool
+}
+
+// DNSResponseFilter applies block_ipv6, block_ip_cidr and rewrite_rcode of a
+// DNS rule.
+type DNSResponseFilter interface {
+	FilterQuery(message *mdns.Msg) *mdns.Msg
+	FilterResponse(response *mdns.Msg) *mdns.Msg
+	FilterAddresses(addresses []netip.Addr) ([]netip.Addr, error)
+}
+
+// DNSAdBlocker blocks DNS queries matched by the rule-sets of the adblock
+// options and keeps statistics of each client.
+type DNSAdBlocker interface {
+	Enabled() bool
+	SetEnabled(enabled bool)
+	Statistics() []DNSAdBlockStatistics
+	ResetStatistics()
+}
+
+type DNSAdBlockStatistics struct {
+	Client      netip.Addr
+	Queries     uint64
+	Blocked     uint64
+	LastBlocked string
+}
+
 type RuleSet interface {
 	Name() string
 	Type() string
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"runtime/debug"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -14,7 +15,12 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental"
 	"github.com/sagernet/sing-box/experimental/cachefile"
+	"github.com/sagernet/sing-box/experimental/fileserver"
+	"github.com/sagernet/sing-box/experimental/flowlog"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
+	"github.com/sagernet/sing-box/experimental/nativeapi"
+	"github.com/sagernet/sing-box/experimental/pacserver"
+	"github.com/sagernet/sing-box/experimental/tasks"
 	"github.com/sagernet/sing-box/inbound"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -30,21 +36,29 @@ import (
 var _ adapter.Service = (*Box)(nil)
 
 type Box struct {
-	createdAt    time.Time
-	router       adapter.Router
-	inbounds     []adapter.Inbound
-	outbounds    []adapter.Outbound
-	logFactory   log.Factory
-	logger       log.ContextLogger
-	scripts      []*script.Script
-	preServices1 map[string]adapter.Service
-	preServices2 map[string]adapter.Service
-	postServices map[string]adapter.Service
-	done         chan struct{}
+	createdAt         time.Time
+	ctx               context.Context
+	options           option.Options
+	platformInterface platform.Interface
+	router            *route.Router
+	inbounds          []adapter.Inbound
+	outbounds         []adapter.Outbound
+	logFactory        log.Factory
+	logger            log.ContextLogger
+	scripts           []*script.Script
+	preServices1      map[string]adapter.Service
+	preServices2      map[string]adapter.Service
+	postServices      map[string]adapter.Service
+	concurrency       int
+	reloadAccess      sync.Mutex
+	done              chan struct{}
 }
 
 type Options struct {
 	option.Options
+	// ConfigPaths are files or directories of configuration fragments merged
+	// into Options before use.
+	ConfigPaths       []string
 	Context           context.Context
 	PlatformInterface platform.Interface
 	PlatformLogWriter log.PlatformWriter
@@ -56,6 +70,13 @@ func New(options Options) (*Box, error) {
 	if ctx == nil {
 		ctx = context.Background()
 	}
+	if len(options.ConfigPaths) > 0 {
+		mergedOptions, err := mergeConfigPaths(options.Options, options.ConfigPaths)
+		if err != nil {
+			return nil, err
+		}
+		options.Options = mergedOptions
+	}
 	ctx = service.ContextWithDefaultRegistry(ctx)
 	ctx = pause.WithDefaultManager(ctx)
 	experimentalOptions := common.PtrValueOrDefault(options.Experimental)
@@ -98,6 +119,10 @@ func New(options Options) (*Box, error) {
 			scripts = append(scripts, s)
 		}
 	}
+	startConcurrency := common.PtrValueOrDefault(options.Route).StartConcurrency
+	if startConcurrency <= 0 {
+		startConcurrency = C.DefaultStartConcurrency
+	}
 	router, err := route.NewRouter(
 		ctx,
 		logFactory,
@@ -187,6 +212,7 @@ func New(options Options) (*Box, error) {
 			return nil, E.Cause(err, "create clash api server")
 		}
 		router.SetClashServer(clashServer)
+		router.EnableConnectionTracking()
 		preServices2["clash api"] = clashServer
 	}
 	if needV2RayAPI {
@@ -197,18 +223,59 @@ func New(options Options) (*Box, error) {
 		router.SetV2RayServer(v2rayServer)
 		preServices2["v2ray api"] = v2rayServer
 	}
+	if experimentalOptions.NativeAPI != nil && experimentalOptions.NativeAPI.Listen != "" {
+		nativeServer, err := nativeapi.NewServer(router, logFactory.NewLogger("native-api"), common.PtrValueOrDefault(experimentalOptions.NativeAPI))
+		if err != nil {
+			return nil, E.Cause(err, "create native api server")
+		}
+		router.EnableConnectionTracking()
+		preServices2["native api"] = nativeServer
+	}
+	if experimentalOptions.FlowLog != nil && experimentalOptions.FlowLog.Enabled {
+		flowLog, err := flowlog.NewService(ctx, logFactory.NewLogger("flow-log"), common.PtrValueOrDefault(experimentalOptions.FlowLog))
+		if err != nil {
+			return nil, E.Cause(err, "create flow log")
+		}
+		router.AppendTracker(flowLog)
+		preServices2["flow log"] = flowLog
+	}
+	if experimentalOptions.PACServer != nil && experimentalOptions.PACServer.Enabled {
+		pacServer, err := pacserver.NewService(ctx, logFactory.NewLogger("pac-server"), common.PtrValueOrDefault(experimentalOptions.PACServer), options.Inbounds, options.Outbounds, common.PtrValueOrDefault(options.Route))
+		if err != nil {
+			return nil, E.Cause(err, "create pac server")
+		}
+		postServices["pac server"] = pacServer
+	}
+	if experimentalOptions.FileServer != nil && experimentalOptions.FileServer.Enabled {
+		fileServer, err := fileserver.NewService(ctx, logFactory.NewLogger("file-server"), common.PtrValueOrDefault(experimentalOptions.FileServer))
+		if err != nil {
+			return nil, E.Cause(err, "create file server")
+		}
+		postServices["file server"] = fileServer
+	}
+	if len(experimentalOptions.Tasks) > 0 {
+		taskService, err := tasks.NewService(ctx, router, logFactory, experimentalOptions.Tasks)
+		if err != nil {
+			return nil, E.Cause(err, "create tasks")
+		}
+		postServices["tasks"] = taskService
+	}
 	return &Box{
-		router:       router,
-		inbounds:     inbounds,
-		outbounds:    outbounds,
-		createdAt:    createdAt,
-		logFactory:   logFactory,
-		logger:       logFactory.Logger(),
-		scripts:      scripts,
-		preServices1: preServices1,
-		preServices2: preServices2,
-		postServices: postServices,
-		done:         make(chan struct{}),
+		ctx:               ctx,
+		options:           options.Options,
+		platformInterface: options.PlatformInterface,
+		router:            router,
+		inbounds:          inbounds,
+		outbounds:         outbounds,
+		createdAt:         createdAt,
+		logFactory:        logFactory,
+		logger:            logFactory.Logger(),
+		scripts:           scripts,
+		preServices1:      preServices1,
+		preServices2:      preServices2,
+		postServices:      postServices,
+		concurrency:       startConcurrency,
+		done:              make(chan struct{}),
 	}, nil
 }
 
@@ -260,13 +327,14 @@ func (s *Box) Start() error {
 }
 
 func (s *Box) preStart() error {
-	monitor := taskmonitor.New(s.logger, C.StartTimeout)
+	monitor := taskmonitor.NewProfile(s.logger, C.StartTimeout)
 	monitor.Start("start logger")
 	err := s.logFactory.Start()
 	monitor.Finish()
 	if err != nil {
 		return E.Cause(err, "start logger")
 	}
+	s.logger.Debug("create finished (", F.Seconds(time.Since(s.createdAt).Seconds()), "s)")
 
 	// Scripts
 	for i, sc := range s.scripts {
@@ -300,7 +368,7 @@ func (s *Box) preStart() error {
 	if err != nil {
 		return E.Cause(err, "pre-start router")
 	}
-	err = s.startOutbounds()
+	err = s.startOutbounds(s.outbounds, nil)
 	if err != nil {
 		return err
 	}
@@ -312,14 +380,19 @@ func (s *Box) start() error {
 	if err != nil {
 		return err
 	}
+	monitor := taskmonitor.NewProfile(s.logger, C.StartTimeout)
 	for serviceName, service := range s.preServices1 {
+		monitor.Start("start ", serviceName)
 		err = service.Start()
+		monitor.Finish()
 		if err != nil {
 			return E.Cause(err, "start ", serviceName)
 		}
 	}
 	for serviceName, service := range s.preServices2 {
+		monitor.Start("start ", serviceName)
 		err = service.Start()
+		monitor.Finish()
 		if err != nil {
 			return E.Cause(err, "start ", serviceName)
 		}
@@ -331,7 +404,9 @@ func (s *Box) start() error {
 		} else {
 			tag = in.Tag()
 		}
+		monitor.Start("initialize inbound/", in.Type(), "[", tag, "]")
 		err = in.Start()
+		monitor.Finish()
 		if err != nil {
 			return E.Cause(err, "initialize inbound/", in.Type(), "[", tag, "]")
 		}
@@ -353,8 +428,11 @@ func (s *Box) start() error {
 }
 
 func (s *Box) postStart() error {
+	monitor := taskmonitor.NewProfile(s.logger, C.StartTimeout)
 	for serviceName, service := range s.postServices {
+		monitor.Start("start ", serviceName)
 		err := service.Start()
+		monitor.Finish()
 		if err != nil {
 			return E.Cause(err, "start ", serviceName)
 		}
@@ -362,7 +440,9 @@ func (s *Box) postStart() error {
 	// TODO: reorganize ALL start order
 	for _, out := range s.outbounds {
 		if lateOutbound, isLateOutbound := out.(adapter.PostStarter); isLateOutbound {
+			monitor.Start("post-start outbound/", out.Tag())
 			err := lateOutbound.PostStart()
+			monitor.Finish()
 			if err != nil {
 				return E.Cause(err, "post-start outbound/", out.Tag())
 			}
@@ -374,7 +454,9 @@ func (s *Box) postStart() error {
 	}
 	for _, in := range s.inbounds {
 		if lateInbound, isLateInbound := in.(adapter.PostStarter); isLateInbound {
+			monitor.Start("post-start inbound/", in.Tag())
 			err = lateInbound.PostStart()
+			monitor.Finish()
 			if err != nil {
 				return E.Cause(err, "post-start inbound/", in.Tag())
 			}
@@ -0,0 +1,79 @@
+package box
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/configformat"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
+)
+
+// mergeConfigPaths deep-merges the JSON configuration fragments at paths into
+// options. Directories contribute their files of supported formats sorted by
+// name. Fragments are merged in order: arrays are concatenated and scalar
+// values set earlier, starting with options itself, take precedence.
+func mergeConfigPaths(options option.Options, paths []string) (option.Options, error) {
+	mergedMessage := options.RawMessage
+	if mergedMessage == nil {
+		var err error
+		mergedMessage, err = json.Marshal(options)
+		if err != nil {
+			return option.Options{}, E.Cause(err, "marshal options")
+		}
+	}
+	for _, path := range paths {
+		fragmentPaths, err := expandConfigPath(path)
+		if err != nil {
+			return option.Options{}, err
+		}
+		for _, fragmentPath := range fragmentPaths {
+			content, err := os.ReadFile(fragmentPath)
+			if err != nil {
+				return option.Options{}, E.Cause(err, "read config at ", fragmentPath)
+			}
+			content, err = configformat.ToJSON(fragmentPath, content)
+			if err != nil {
+				return option.Options{}, E.Cause(err, "decode config at ", fragmentPath)
+			}
+			mergedMessage, err = badjson.MergeJSON(content, mergedMessage, false)
+			if err != nil {
+				return option.Options{}, E.Cause(err, "merge config at ", fragmentPath)
+			}
+		}
+	}
+	var mergedOptions option.Options
+	err := mergedOptions.UnmarshalJSON(mergedMessage)
+	if err != nil {
+		return option.Options{}, E.Cause(err, "decode merged config")
+	}
+	return mergedOptions, nil
+}
+
+func expandConfigPath(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, E.Cause(err, "read config at ", path)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, E.Cause(err, "read config directory at ", path)
+	}
+	var paths []string
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		if common.Contains(configformat.Extensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			paths = append(paths, filepath.Join(path, entry.Name()))
+		}
+	}
+	return paths, nil
+}
@@ -1,6 +1,7 @@
 package box
 
 import (
+	"context"
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -9,13 +10,15 @@ import (
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/task"
 )
 
-func (s *Box) startOutbounds() error {
-	monitor := taskmonitor.New(s.logger, C.StartTimeout)
+// startOutbounds starts outboundsToStart in dependency order, startedTags are
+// outbounds already running that they may depend on.
+func (s *Box) startOutbounds(outboundsToStart []adapter.Outbound, startedTags []string) error {
 	outboundTags := make(map[adapter.Outbound]string)
 	outbounds := make(map[string]adapter.Outbound)
-	for i, outboundToStart := range s.outbounds {
+	for i, outboundToStart := range outboundsToStart {
 		var outboundTag string
 		if outboundToStart.Tag() == "" {
 			outboundTag = F.ToString(i)
@@ -29,32 +32,59 @@ func (s *Box) startOutbounds() error {
 		outbounds[outboundTag] = outboundToStart
 	}
 	started := make(map[string]bool)
+	for _, tag := range startedTags {
+		started[tag] = true
+	}
 	for {
-		canContinue := false
-	startOne:
-		for _, outboundToStart := range s.outbounds {
-			outboundTag := outboundTags[outboundToStart]
-			if started[outboundTag] {
+		var readyOutbounds []adapter.Outbound
+	findReady:
+		for _, outboundToStart := range outboundsToStart {
+			if started[outboundTags[outboundToStart]] {
 				continue
 			}
-			dependencies := outboundToStart.Dependencies()
-			for _, dependency := range dependencies {
+			for _, dependency := range outboundToStart.Dependencies() {
 				if !started[dependency] {
-					continue startOne
+					continue findReady
 				}
 			}
+			readyOutbounds = append(readyOutbounds, outboundToStart)
+		}
+		canContinue := len(readyOutbounds) > 0
+		var (
+			startGroup task.Group
+			startCount int
+		)
+		for _, outboundToStart := range readyOutbounds {
+			outboundTag := outboundTags[outboundToStart]
 			started[outboundTag] = true
-			canContinue = true
-			if starter, isStarter := outboundToStart.(interface {
+			starter, isStarter := outboundToStart.(interface {
 				Start() error
-			}); isStarter {
-				monitor.Start("initialize outbound/", outboundToStart.Type(), "[", outboundTag, "]")
+			})
+			if !isStarter {
+				continue
+			}
+			outboundType := outboundToStart.Type()
+			startCount++
+			startGroup.Append0(func(ctx context.Context) error {
+				monitor := taskmonitor.New(s.logger, C.StartTimeout)
+				monitor.Start("initialize outbound/", outboundType, "[", outboundTag, "]")
 				err := starter.Start()
 				monitor.Finish()
 				if err != nil {
-					return E.Cause(err, "initialize outbound/", outboundToStart.Type(), "[", outboundTag, "]")
+					return E.Cause(err, "initialize outbound/", outboundType, "[", outboundTag, "]")
 				}
+				return nil
+			})
+		}
+		if startCount > 0 {
+			startGroup.Concurrency(s.concurrency)
+			startGroup.FastFail()
+			err := startGroup.Run(context.Background())
+			if err != nil {
+				return err
 			}
+		}
+		for _, outboundToStart := range readyOutbounds {
 			if provider, isProvider := outboundToStart.(adapter.OutboundProvider); isProvider {
 				for _, outbound := range provider.BasicOutbounds() {
 					outboundTags[outbound] = outbound.Tag()
@@ -68,13 +98,13 @@ func (s *Box) startOutbounds() error {
 				}
 			}
 		}
-		if len(started) == len(outbounds) {
+		if len(started) == len(outbounds)+len(startedTags) {
 			break
 		}
 		if canContinue {
 			continue
 		}
-		currentOutbound := common.Find(s.outbounds, func(it adapter.Outbound) bool {
+		currentOutbound := common.Find(outboundsToStart, func(it adapter.Outbound) bool {
 			return !started[outboundTags[it]]
 		})
 		var lintOutbound func(oTree []string, oCurrent adapter.Outbound) error
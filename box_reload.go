@@ -0,0 +1,352 @@
+package box
+
+import (
+	"bytes"
+	"os"
+	"slices"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/inbound"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+// Reload applies newOptions to the running box, replacing only the inbounds
+// and outbounds whose options changed, along with outbounds depending on
+// them, and the route rules. Unaffected listeners and established
+// connections are kept. Changes to anything else require a restart, which is
+// reported as an error without touching the running box. Once the new
+// configuration is published, failures to close old services or to start new
+// inbounds are logged only.
+func (s *Box) Reload(newOptions option.Options) error {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	return s.reload(newOptions)
+}
+
+// AddOutbound creates and starts a new outbound and publishes it to the
+// router, without affecting the running outbounds.
+func (s *Box) AddOutbound(options option.Outbound) error {
+	if options.Tag == "" {
+		return E.New("missing outbound tag")
+	}
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	if common.Any(s.options.Outbounds, func(it option.Outbound) bool {
+		return it.Tag == options.Tag
+	}) {
+		return E.New("outbound tag ", options.Tag, " duplicated")
+	}
+	newOptions := s.options
+	newOptions.Outbounds = append(slices.Clone(s.options.Outbounds), options)
+	return s.reload(newOptions)
+}
+
+// RemoveOutbound retires an outbound. It fails if a rule, the final outbound
+// or another outbound still refers to it.
+func (s *Box) RemoveOutbound(tag string) error {
+	s.reloadAccess.Lock()
+	defer s.reloadAccess.Unlock()
+	index := slices.IndexFunc(s.options.Outbounds, func(it option.Outbound) bool {
+		return it.Tag == tag
+	})
+	if index == -1 {
+		return E.New("outbound not found: ", tag)
+	}
+	for _, out := range s.outbounds {
+		if out.Tag() != tag && common.Contains(out.Dependencies(), tag) {
+			return E.New("outbound ", tag, " is used by outbound ", out.Tag())
+		}
+	}
+	newOptions := s.options
+	newOptions.Outbounds = slices.Delete(slices.Clone(s.options.Outbounds), index, index+1)
+	return s.reload(newOptions)
+}
+
+func (s *Box) reload(newOptions option.Options) error {
+	select {
+	case <-s.done:
+		return os.ErrClosed
+	default:
+	}
+	err := checkReloadable(s.options, newOptions)
+	if err != nil {
+		return err
+	}
+
+	currentOutbounds := make(map[string]adapter.Outbound)
+	currentOutboundOptions := make(map[string]option.Outbound)
+	for i, outboundOptions := range s.options.Outbounds {
+		tag := outboundTag(i, outboundOptions)
+		currentOutbounds[tag] = s.outbounds[i]
+		currentOutboundOptions[tag] = outboundOptions
+	}
+	var defaultOutbound adapter.Outbound
+	if len(s.outbounds) > len(s.options.Outbounds) {
+		defaultOutbound = s.outbounds[len(s.options.Outbounds)]
+	}
+	replaced := make(map[string]bool)
+	for tag := range currentOutbounds {
+		replaced[tag] = true
+	}
+	newOutboundOptions := make(map[string]option.Outbound)
+	for i, outboundOptions := range newOptions.Outbounds {
+		tag := outboundTag(i, outboundOptions)
+		if _, exists := newOutboundOptions[tag]; exists {
+			return E.New("outbound tag ", tag, " duplicated")
+		}
+		newOutboundOptions[tag] = outboundOptions
+		if current, loaded := currentOutboundOptions[tag]; loaded && optionsEqual(&current, &outboundOptions) {
+			delete(replaced, tag)
+		}
+	}
+	// outbounds resolve their dependencies when started, so they must be
+	// recreated along with them
+	for {
+		var changed bool
+		for tag, current := range currentOutbounds {
+			if replaced[tag] {
+				continue
+			}
+			if common.Any(current.Dependencies(), func(dependency string) bool {
+				return replaced[dependency]
+			}) {
+				replaced[tag] = true
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+	for tag := range replaced {
+		if currentOutboundOptions[tag].Type == C.TypeProvider || newOutboundOptions[tag].Type == C.TypeProvider {
+			return E.New("outbound provider ", tag, " requires a restart")
+		}
+	}
+
+	stagingRouter := &reloadRouter{
+		Router:        s.router,
+		outboundByTag: make(map[string]adapter.Outbound),
+	}
+	outbounds := make([]adapter.Outbound, 0, len(newOptions.Outbounds)+1)
+	var (
+		createdOutbounds []adapter.Outbound
+		keptTags         []string
+	)
+	for i, outboundOptions := range newOptions.Outbounds {
+		tag := outboundTag(i, outboundOptions)
+		if !replaced[tag] {
+			if current, loaded := currentOutbounds[tag]; loaded {
+				outbounds = append(outbounds, current)
+				stagingRouter.outboundByTag[tag] = current
+				keptTags = append(keptTags, tag)
+				continue
+			}
+		}
+		out, err := outbound.New(
+			s.ctx,
+			stagingRouter,
+			s.logFactory,
+			s.logFactory.NewLogger(F.ToString("outbound/", outboundOptions.Type, "[", tag, "]")),
+			tag,
+			outboundOptions)
+		if err != nil {
+			closeAll(createdOutbounds)
+			return E.Cause(err, "parse outbound[", i, "]")
+		}
+		outbounds = append(outbounds, out)
+		createdOutbounds = append(createdOutbounds, out)
+		stagingRouter.outboundByTag[tag] = out
+	}
+
+	currentInbounds := make(map[string]adapter.Inbound)
+	currentInboundOptions := make(map[string]option.Inbound)
+	for i, inboundOptions := range s.options.Inbounds {
+		tag := inboundTag(i, inboundOptions)
+		currentInbounds[tag] = s.inbounds[i]
+		currentInboundOptions[tag] = inboundOptions
+	}
+	inbounds := make([]adapter.Inbound, 0, len(newOptions.Inbounds))
+	var createdInbounds []adapter.Inbound
+	keptInbounds := make(map[string]bool)
+	for i, inboundOptions := range newOptions.Inbounds {
+		tag := inboundTag(i, inboundOptions)
+		if keptInbounds[tag] {
+			closeAll(createdInbounds)
+			closeAll(createdOutbounds)
+			return E.New("inbound tag ", tag, " duplicated")
+		}
+		if current, loaded := currentInboundOptions[tag]; loaded && optionsEqual(&current, &inboundOptions) {
+			inbounds = append(inbounds, currentInbounds[tag])
+			keptInbounds[tag] = true
+			continue
+		}
+		in, err := inbound.New(
+			s.ctx,
+			s.router,
+			s.logFactory.NewLogger(F.ToString("inbound/", inboundOptions.Type, "[", tag, "]")),
+			tag,
+			inboundOptions,
+			s.platformInterface,
+		)
+		if err != nil {
+			closeAll(createdInbounds)
+			closeAll(createdOutbounds)
+			return E.Cause(err, "parse inbound[", i, "]")
+		}
+		inbounds = append(inbounds, in)
+		createdInbounds = append(createdInbounds, in)
+	}
+
+	var startedTags []string
+	if defaultOutbound != nil {
+		startedTags = append(keptTags, defaultOutbound.Tag())
+	} else {
+		startedTags = keptTags
+	}
+	err = s.startOutbounds(createdOutbounds, startedTags)
+	if err != nil {
+		closeAll(createdInbounds)
+		closeAll(createdOutbounds)
+		return err
+	}
+	routeOptions := common.PtrValueOrDefault(newOptions.Route)
+	var usedDefaultOutbound bool
+	err = s.router.Reload(inbounds, outbounds, routeOptions.Rules, routeOptions.Final, func() adapter.Outbound {
+		usedDefaultOutbound = true
+		if defaultOutbound == nil {
+			var oErr error
+			defaultOutbound, oErr = outbound.New(s.ctx, s.router, s.logFactory, s.logFactory.NewLogger("outbound/direct"), "direct", option.Outbound{Type: "direct", Tag: "default"})
+			common.Must(oErr)
+		}
+		return defaultOutbound
+	})
+	if err != nil {
+		closeAll(createdInbounds)
+		closeAll(createdOutbounds)
+		return err
+	}
+	stagingRouter.committed.Store(true)
+	// the new state is published, so failures below are only logged instead
+	// of reported as a failed reload
+	if usedDefaultOutbound {
+		outbounds = append(outbounds, defaultOutbound)
+	} else if defaultOutbound != nil {
+		err = common.Close(defaultOutbound)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "close outbound/direct[", defaultOutbound.Tag(), "]"))
+		}
+	}
+	for i, inboundOptions := range s.options.Inbounds {
+		if keptInbounds[inboundTag(i, inboundOptions)] {
+			continue
+		}
+		err = s.inbounds[i].Close()
+		if err != nil {
+			s.logger.Error(E.Cause(err, "close inbound/", s.inbounds[i].Type(), "[", s.inbounds[i].Tag(), "]"))
+		}
+	}
+	for _, in := range createdInbounds {
+		err = in.Start()
+		if err != nil {
+			s.logger.Error(E.Cause(err, "initialize inbound/", in.Type(), "[", in.Tag(), "]"))
+		}
+	}
+	for tag := range replaced {
+		out := currentOutbounds[tag]
+		err = common.Close(out)
+		if err != nil {
+			s.logger.Error(E.Cause(err, "close outbound/", out.Type(), "[", tag, "]"))
+		}
+	}
+	s.options.Inbounds = newOptions.Inbounds
+	s.options.Outbounds = newOptions.Outbounds
+	s.options.Route = newOptions.Route
+	s.inbounds = inbounds
+	s.outbounds = outbounds
+	s.logger.Info("sing-box reloaded: ", len(createdInbounds), " inbounds and ", len(createdOutbounds), " outbounds replaced")
+	return nil
+}
+
+// checkReloadable rejects changes outside of inbounds, outbounds and the
+// route rules and final outbound.
+func checkReloadable(current option.Options, next option.Options) error {
+	currentRoute := common.PtrValueOrDefault(current.Route)
+	nextRoute := common.PtrValueOrDefault(next.Route)
+	currentRoute.Rules, currentRoute.Final = nil, ""
+	nextRoute.Rules, nextRoute.Final = nil, ""
+	for _, section := range []struct {
+		name    string
+		current any
+		next    any
+	}{
+		{"log", current.Log, next.Log},
+		{"dns", current.DNS, next.DNS},
+		{"ntp", current.NTP, next.NTP},
+		{"route", &currentRoute, &nextRoute},
+		{"experimental", current.Experimental, next.Experimental},
+		{"scripts", current.Scripts, next.Scripts},
+	} {
+		if !optionsEqual(section.current, section.next) {
+			return E.New(section.name, " options changed, restart required")
+		}
+	}
+	return nil
+}
+
+func optionsEqual(current any, next any) bool {
+	currentContent, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+	nextContent, err := json.Marshal(next)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(currentContent, nextContent)
+}
+
+func inboundTag(index int, options option.Inbound) string {
+	if options.Tag != "" {
+		return options.Tag
+	}
+	return F.ToString(index)
+}
+
+func outboundTag(index int, options option.Outbound) string {
+	if options.Tag != "" {
+		return options.Tag
+	}
+	return F.ToString(index)
+}
+
+func closeAll[T any](services []T) {
+	for _, service := range services {
+		common.Close(service)
+	}
+}
+
+// reloadRouter lets outbounds created by a reload find each other before the
+// router publishes them. Once committed, lookups go to the router only, so
+// that outbounds replaced by later reloads are not returned.
+type reloadRouter struct {
+	adapter.Router
+	outboundByTag map[string]adapter.Outbound
+	committed     atomic.Bool
+}
+
+func (r *reloadRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	if !r.committed.Load() {
+		if outbound, loaded := r.outboundByTag[tag]; loaded {
+			return outbound, true
+		}
+	}
+	return r.Router.Outbound(tag)
+}
@@ -2,18 +2,39 @@ package main
 
 import (
 	"context"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
 
 	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 
 	"github.com/spf13/cobra"
 )
 
+var (
+	commandCheckFlagConnectivity bool
+	commandCheckFlagURL          string
+	commandCheckFlagTimeout      time.Duration
+)
+
 var commandCheck = &cobra.Command{
 	Use:   "check",
 	Short: "Check configuration",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := check()
+		var err error
+		if commandCheckFlagConnectivity {
+			err = checkConnectivity()
+		} else {
+			err = check()
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -22,6 +43,9 @@ var commandCheck = &cobra.Command{
 }
 
 func init() {
+	commandCheck.Flags().BoolVar(&commandCheckFlagConnectivity, "connectivity", false, "Start outbounds and test a request through each of them")
+	commandCheck.Flags().StringVar(&commandCheckFlagURL, "connectivity-url", "", "URL requested by the connectivity check")
+	commandCheck.Flags().DurationVar(&commandCheckFlagTimeout, "connectivity-timeout", C.TCPTimeout, "Timeout of each connectivity test")
 	mainCommand.AddCommand(commandCheck)
 }
 
@@ -41,3 +65,78 @@ func check() error {
 	cancel()
 	return err
 }
+
+type connectivityResult struct {
+	outbound adapter.Outbound
+	delay    uint16
+	err      error
+}
+
+func checkConnectivity() error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	options.Inbounds = nil
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	instance, err := box.New(box.Options{
+		Context: ctx,
+		Options: options,
+	})
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	defer instance.Close()
+	err = instance.PreStart()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	var outbounds []adapter.Outbound
+	for _, outboundOptions := range options.Outbounds {
+		outbound, loaded := instance.Router().Outbound(outboundOptions.Tag)
+		if !loaded {
+			continue
+		}
+		if provider, isProvider := outbound.(adapter.OutboundProvider); isProvider {
+			outbounds = append(outbounds, provider.BasicOutbounds()...)
+			continue
+		}
+		if _, isGroup := outbound.(adapter.OutboundGroup); isGroup {
+			continue
+		}
+		switch outbound.Type() {
+		case C.TypeBlock, C.TypeDNS:
+			continue
+		}
+		outbounds = append(outbounds, outbound)
+	}
+	results := make([]connectivityResult, len(outbounds))
+	var waitGroup sync.WaitGroup
+	for i, outbound := range outbounds {
+		results[i].outbound = outbound
+		waitGroup.Add(1)
+		go func(result *connectivityResult) {
+			defer waitGroup.Done()
+			testCtx, testCancel := context.WithTimeout(ctx, commandCheckFlagTimeout)
+			defer testCancel()
+			result.delay, result.err = urltest.URLTest(testCtx, commandCheckFlagURL, result.outbound)
+		}(&results[i])
+	}
+	waitGroup.Wait()
+	var failed int
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, result := range results {
+		status := F.ToString("ok\t", result.delay, "ms")
+		if result.err != nil {
+			failed++
+			status = "failed\t" + strings.ReplaceAll(result.err.Error(), "\n", " ")
+		}
+		writer.Write([]byte(result.outbound.Tag() + "\t" + result.outbound.Type() + "\t" + status + "\n"))
+	}
+	writer.Flush()
+	if failed > 0 {
+		return E.New(failed, "/", len(results), " outbounds failed")
+	}
+	return nil
+}
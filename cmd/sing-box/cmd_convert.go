@@ -0,0 +1,108 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/common/convert"
+	"github.com/sagernet/sing-box/common/jsonc"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	flagConvertFrom   string
+	flagConvertTo     string
+	flagConvertOutput string
+)
+
+var commandConvert = &cobra.Command{
+	Use:   "convert <path>",
+	Short: "Convert configurations between sing-box, Clash and Surge",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := convertConfig(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandConvert.Flags().StringVar(&flagConvertFrom, "from", "clash", "Source format: clash, surge or singbox")
+	commandConvert.Flags().StringVar(&flagConvertTo, "to", "singbox", "Target format: singbox or clash")
+	commandConvert.Flags().StringVarP(&flagConvertOutput, "output", "o", "stdout", "Output file")
+	mainCommand.AddCommand(commandConvert)
+}
+
+func convertConfig(sourcePath string) error {
+	var (
+		content []byte
+		err     error
+	)
+	if sourcePath == "stdin" {
+		content, err = io.ReadAll(os.Stdin)
+	} else {
+		content, err = os.ReadFile(sourcePath)
+	}
+	if err != nil {
+		return err
+	}
+	var (
+		options  *option.Options
+		warnings convert.Warnings
+	)
+	switch flagConvertFrom {
+	case "clash":
+		options, warnings, err = convert.FromClash(content)
+	case "surge":
+		options, warnings, err = convert.FromSurge(content)
+	case "singbox":
+		var singboxOptions option.Options
+		content, err = jsonc.Standardize(content)
+		if err != nil {
+			return err
+		}
+		singboxOptions, err = json.UnmarshalExtended[option.Options](content)
+		options = &singboxOptions
+	default:
+		return E.New("unknown source format: ", flagConvertFrom)
+	}
+	if err != nil {
+		return err
+	}
+	var output []byte
+	switch flagConvertTo {
+	case "singbox":
+		buffer := new(bytes.Buffer)
+		encoder := json.NewEncoder(buffer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(options)
+		if err != nil {
+			return E.Cause(err, "encode config")
+		}
+		output = buffer.Bytes()
+	case "clash":
+		var exportWarnings convert.Warnings
+		output, exportWarnings, err = convert.ToClash(options)
+		if err != nil {
+			return err
+		}
+		warnings = append(warnings, exportWarnings...)
+	default:
+		return E.New("unknown target format: ", flagConvertTo)
+	}
+	for _, warning := range warnings {
+		log.Warn(warning)
+	}
+	if flagConvertOutput == "stdout" {
+		_, err = os.Stdout.Write(output)
+		return err
+	}
+	return os.WriteFile(flagConvertOutput, output, 0o644)
+}
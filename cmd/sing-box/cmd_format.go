@@ -4,11 +4,11 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/sagernet/sing-box/common/jsonc"
 	"github.com/sagernet/sing-box/log"
 	E "github.com/sagernet/sing/common/exceptions"
-	"github.com/sagernet/sing/common/json"
-	"github.com/sagernet/sing/common/json/badjson"
 
 	"github.com/spf13/cobra"
 )
@@ -38,17 +38,16 @@ func format() error {
 		return err
 	}
 	for _, optionsEntry := range optionsList {
-		optionsEntry.options, err = badjson.Omitempty(optionsEntry.options)
-		if err != nil {
-			return err
+		switch strings.ToLower(filepath.Ext(optionsEntry.path)) {
+		case ".yaml", ".yml", ".toml":
+			log.Warn("skip formatting non-JSON config at ", optionsEntry.path)
+			continue
 		}
-		buffer := new(bytes.Buffer)
-		encoder := json.NewEncoder(buffer)
-		encoder.SetIndent("", "  ")
-		err = encoder.Encode(optionsEntry.options)
+		content, err := jsonc.Format(optionsEntry.content, "  ")
 		if err != nil {
-			return E.Cause(err, "encode config")
+			return E.Cause(err, "format config at ", optionsEntry.path)
 		}
+		buffer := bytes.NewBuffer(content)
 		outputPath, _ := filepath.Abs(optionsEntry.path)
 		if !commandFormatFlagWrite {
 			if len(optionsList) > 1 {
@@ -0,0 +1,305 @@
+package main
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"io"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/gofrs/uuid/v5"
+	"github.com/spf13/cobra"
+	"golang.zx2c4.com/wireguard/wgctrl/wgtypes"
+)
+
+var (
+	flagGenerateConfigProtocol   string
+	flagGenerateConfigServer     string
+	flagGenerateConfigPort       uint16
+	flagGenerateConfigServerName string
+	flagGenerateConfigOutput     string
+)
+
+var commandGenerateConfig = &cobra.Command{
+	Use:   "config",
+	Short: "Generate matching server and client configurations",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := generateConfig()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandGenerateConfig.Flags().StringVar(&flagGenerateConfigProtocol, "protocol", "", "Protocol: vless-reality, hysteria2 or shadowsocks-2022")
+	commandGenerateConfig.Flags().StringVar(&flagGenerateConfigServer, "server", "", "Server address used by the client")
+	commandGenerateConfig.Flags().Uint16Var(&flagGenerateConfigPort, "port", 443, "Server port")
+	commandGenerateConfig.Flags().StringVar(&flagGenerateConfigServerName, "server-name", "", "TLS server name, or the REALITY handshake server")
+	commandGenerateConfig.Flags().StringVarP(&flagGenerateConfigOutput, "output", "o", "", "Write server.json and client.json to the directory instead of stdout")
+	commandGenerateConfig.MarkFlagRequired("protocol")
+	commandGenerateConfig.MarkFlagRequired("server")
+	commandGenerate.AddCommand(commandGenerateConfig)
+}
+
+func generateConfig() error {
+	var (
+		inbound  option.Inbound
+		outbound option.Outbound
+		err      error
+	)
+	switch flagGenerateConfigProtocol {
+	case "vless-reality":
+		inbound, outbound, err = generateVLESSRealityConfig()
+	case "hysteria2":
+		inbound, outbound, err = generateHysteria2Config()
+	case "shadowsocks-2022":
+		inbound, outbound, err = generateShadowsocks2022Config()
+	default:
+		return E.New("unknown protocol: ", flagGenerateConfigProtocol)
+	}
+	if err != nil {
+		return err
+	}
+	inbound.Tag = flagGenerateConfigProtocol + "-in"
+	outbound.Tag = "proxy"
+	serverOptions := option.Options{
+		Log:       &option.LogOptions{Level: "info"},
+		Inbounds:  []option.Inbound{inbound},
+		Outbounds: []option.Outbound{{Type: C.TypeDirect, Tag: "direct"}},
+	}
+	clientOptions := option.Options{
+		Log: &option.LogOptions{Level: "info"},
+		Inbounds: []option.Inbound{{
+			Type: C.TypeMixed,
+			Tag:  "mixed-in",
+			MixedOptions: option.HTTPMixedInboundOptions{
+				ListenOptions: option.ListenOptions{
+					Listen:     option.NewListenAddress(netip.AddrFrom4([4]byte{127, 0, 0, 1})),
+					ListenPort: 2080,
+				},
+			},
+		}},
+		Outbounds: []option.Outbound{outbound, {Type: C.TypeDirect, Tag: "direct"}},
+	}
+	if flagGenerateConfigOutput == "" {
+		return encodeGeneratedConfig(os.Stdout, map[string]option.Options{
+			"server": serverOptions,
+			"client": clientOptions,
+		})
+	}
+	err = os.MkdirAll(flagGenerateConfigOutput, 0o755)
+	if err != nil {
+		return err
+	}
+	for name, options := range map[string]option.Options{"server.json": serverOptions, "client.json": clientOptions} {
+		buffer := new(bytes.Buffer)
+		err = encodeGeneratedConfig(buffer, options)
+		if err != nil {
+			return err
+		}
+		outputPath := filepath.Join(flagGenerateConfigOutput, name)
+		err = os.WriteFile(outputPath, buffer.Bytes(), 0o600)
+		if err != nil {
+			return err
+		}
+		os.Stderr.WriteString(outputPath + "\n")
+	}
+	return nil
+}
+
+func encodeGeneratedConfig(writer io.Writer, v any) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+func generateServerListen() option.ListenOptions {
+	return option.ListenOptions{
+		Listen:     option.NewListenAddress(netip.IPv6Unspecified()),
+		ListenPort: flagGenerateConfigPort,
+	}
+}
+
+func generateClientServer() option.ServerOptions {
+	return option.ServerOptions{
+		Server:     flagGenerateConfigServer,
+		ServerPort: flagGenerateConfigPort,
+	}
+}
+
+func generateVLESSRealityConfig() (option.Inbound, option.Outbound, error) {
+	userUUID, err := uuid.NewV4()
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	privateKey, err := wgtypes.GeneratePrivateKey()
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	publicKey := privateKey.PublicKey()
+	shortID := make([]byte, 8)
+	_, err = rand.Read(shortID)
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	serverName := flagGenerateConfigServerName
+	if serverName == "" {
+		serverName = "www.apple.com"
+	}
+	inbound := option.Inbound{
+		Type: C.TypeVLESS,
+		VLESSOptions: option.VLESSInboundOptions{
+			ListenOptions: generateServerListen(),
+			Users: []option.VLESSUser{{
+				Name: "user",
+				UUID: userUUID.String(),
+				Flow: "xtls-rprx-vision",
+			}},
+			InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+				TLS: &option.InboundTLSOptions{
+					Enabled:    true,
+					ServerName: serverName,
+					Reality: &option.InboundRealityOptions{
+						Enabled: true,
+						Handshake: option.InboundRealityHandshakeOptions{
+							ServerOptions: option.ServerOptions{Server: serverName, ServerPort: 443},
+						},
+						PrivateKey: base64.RawURLEncoding.EncodeToString(privateKey[:]),
+						ShortID:    option.Listable[string]{hex.EncodeToString(shortID)},
+					},
+				},
+			},
+		},
+	}
+	outbound := option.Outbound{
+		Type: C.TypeVLESS,
+		VLESSOptions: option.VLESSOutboundOptions{
+			ServerOptions: generateClientServer(),
+			UUID:          userUUID.String(),
+			Flow:          "xtls-rprx-vision",
+			OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+				TLS: &option.OutboundTLSOptions{
+					Enabled:    true,
+					ServerName: serverName,
+					UTLS: &option.OutboundUTLSOptions{
+						Enabled:     true,
+						Fingerprint: "chrome",
+					},
+					Reality: &option.OutboundRealityOptions{
+						Enabled:   true,
+						PublicKey: base64.RawURLEncoding.EncodeToString(publicKey[:]),
+						ShortID:   hex.EncodeToString(shortID),
+					},
+				},
+			},
+		},
+	}
+	return inbound, outbound, nil
+}
+
+func generateHysteria2Config() (option.Inbound, option.Outbound, error) {
+	password, err := generatePassword(16)
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	serverName := flagGenerateConfigServerName
+	if serverName == "" {
+		serverName = flagGenerateConfigServer
+		if M.ParseAddr(serverName).IsValid() {
+			serverName = "localhost"
+		}
+	}
+	privateKeyPem, certificatePem, err := tls.GenerateKeyPair(time.Now, serverName, time.Now().AddDate(10, 0, 0))
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	certificate := pemLines(certificatePem)
+	inbound := option.Inbound{
+		Type: C.TypeHysteria2,
+		Hysteria2Options: option.Hysteria2InboundOptions{
+			ListenOptions: generateServerListen(),
+			Users: []option.Hysteria2User{{
+				Name:     "user",
+				Password: password,
+			}},
+			InboundTLSOptionsContainer: option.InboundTLSOptionsContainer{
+				TLS: &option.InboundTLSOptions{
+					Enabled:     true,
+					ServerName:  serverName,
+					ALPN:        option.Listable[string]{"h3"},
+					Certificate: certificate,
+					Key:         pemLines(privateKeyPem),
+				},
+			},
+		},
+	}
+	outbound := option.Outbound{
+		Type: C.TypeHysteria2,
+		Hysteria2Options: option.Hysteria2OutboundOptions{
+			ServerOptions: generateClientServer(),
+			Password:      password,
+			OutboundTLSOptionsContainer: option.OutboundTLSOptionsContainer{
+				TLS: &option.OutboundTLSOptions{
+					Enabled:     true,
+					ServerName:  serverName,
+					ALPN:        option.Listable[string]{"h3"},
+					Certificate: certificate,
+				},
+			},
+		},
+	}
+	return inbound, outbound, nil
+}
+
+func generateShadowsocks2022Config() (option.Inbound, option.Outbound, error) {
+	const method = "2022-blake3-aes-128-gcm"
+	password, err := generatePassword(16)
+	if err != nil {
+		return option.Inbound{}, option.Outbound{}, err
+	}
+	inbound := option.Inbound{
+		Type: C.TypeShadowsocks,
+		ShadowsocksOptions: option.ShadowsocksInboundOptions{
+			ListenOptions: generateServerListen(),
+			Method:        method,
+			Password:      password,
+		},
+	}
+	outbound := option.Outbound{
+		Type: C.TypeShadowsocks,
+		ShadowsocksOptions: option.ShadowsocksOutboundOptions{
+			ServerOptions: generateClientServer(),
+			Method:        method,
+			Password:      password,
+		},
+	}
+	return inbound, outbound, nil
+}
+
+func generatePassword(length int) (string, error) {
+	password := make([]byte, length)
+	_, err := rand.Read(password)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(password), nil
+}
+
+func pemLines(content []byte) option.Listable[string] {
+	return strings.Split(strings.TrimSpace(string(content)), "\n")
+}
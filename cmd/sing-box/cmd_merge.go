@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 
 	C "github.com/sagernet/sing-box/constant"
@@ -11,30 +12,63 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
+	"github.com/sagernet/sing/common/json/badjson"
 	"github.com/sagernet/sing/common/rw"
 
 	"github.com/spf13/cobra"
 )
 
+var commandMergeFlagOutput string
+
 var commandMerge = &cobra.Command{
-	Use:   "merge <output>",
+	Use:   "merge [<source-path>...] <output>",
 	Short: "Merge configurations",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := merge(args[0])
+		var err error
+		if commandMergeFlagOutput != "" {
+			err = merge(args, commandMergeFlagOutput)
+		} else if len(args) == 1 {
+			err = merge(nil, args[0])
+		} else {
+			err = E.New("missing output path")
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
 	},
-	Args: cobra.ExactArgs(1),
+	Args: cobra.MinimumNArgs(1),
 }
 
 func init() {
+	commandMerge.Flags().StringVarP(&commandMergeFlagOutput, "output", "o", "", "Output file")
 	mainCommand.AddCommand(commandMerge)
 }
 
-func merge(outputPath string) error {
-	mergedOptions, err := readConfigAndMerge()
+func merge(sourcePaths []string, outputPath string) error {
+	var (
+		optionsList []*OptionsEntry
+		err         error
+	)
+	if len(sourcePaths) > 0 {
+		for _, sourcePath := range sourcePaths {
+			var optionsEntry *OptionsEntry
+			optionsEntry, err = readConfigAt(sourcePath)
+			if err != nil {
+				return err
+			}
+			optionsList = append(optionsList, optionsEntry)
+		}
+	} else {
+		optionsList, err = readConfig()
+		if err != nil {
+			return err
+		}
+	}
+	mergedOptions, err := mergeConfigEntries(optionsList, func(path string, entry *OptionsEntry, value any, mergedValue any) {
+		log.Warn("conflict at ", path, ": value ", formatMergeValue(value), " from ", entry.path, " is ignored, keeping ", formatMergeValue(mergedValue))
+	})
 	if err != nil {
 		return err
 	}
@@ -152,3 +186,50 @@ func trimStringArray(array []string) []string {
 		return strings.TrimSpace(it) != ""
 	})
 }
+
+func reportMergeConflicts(entry *OptionsEntry, mergedMessage json.RawMessage, conflictHandler func(path string, entry *OptionsEntry, value any, mergedValue any)) error {
+	source, err := badjson.Decode(entry.options.RawMessage)
+	if err != nil {
+		return E.Cause(err, "decode source")
+	}
+	destination, err := badjson.Decode(mergedMessage)
+	if err != nil {
+		return E.Cause(err, "decode destination")
+	}
+	walkMergeConflicts("", source, destination, func(path string, value any, mergedValue any) {
+		conflictHandler(path, entry, value, mergedValue)
+	})
+	return nil
+}
+
+// walkMergeConflicts mirrors badjson.MergeJSON: objects are merged by key,
+// arrays are appended, and any other value of the destination is kept.
+func walkMergeConflicts(path string, source any, destination any, conflictHandler func(path string, value any, mergedValue any)) {
+	switch destinationValue := destination.(type) {
+	case badjson.JSONArray:
+		return
+	case *badjson.JSONObject:
+		sourceObject, isObject := source.(*badjson.JSONObject)
+		if !isObject {
+			return
+		}
+		for _, entry := range sourceObject.Entries() {
+			mergedValue, loaded := destinationValue.Get(entry.Key)
+			if loaded {
+				walkMergeConflicts(path+"."+entry.Key, entry.Value, mergedValue, conflictHandler)
+			}
+		}
+	default:
+		if !reflect.DeepEqual(source, destination) {
+			conflictHandler(strings.TrimPrefix(path, "."), source, destination)
+		}
+	}
+}
+
+func formatMergeValue(value any) string {
+	content, err := json.Marshal(value)
+	if err != nil {
+		return F.ToString(value)
+	}
+	return string(content)
+}
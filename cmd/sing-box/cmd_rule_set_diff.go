@@ -0,0 +1,100 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var commandRuleSetDiff = &cobra.Command{
+	Use:   "diff <old-path> <new-path>",
+	Short: "Print entries added or removed between two rule-sets",
+	Args:  cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := diffRuleSets(args[0], args[1])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetDiff)
+}
+
+func diffRuleSets(oldPath string, newPath string) error {
+	oldRuleSet, err := readRuleSet(oldPath)
+	if err != nil {
+		return err
+	}
+	newRuleSet, err := readRuleSet(newPath)
+	if err != nil {
+		return err
+	}
+	oldEntries, err := ruleSetEntries(oldRuleSet)
+	if err != nil {
+		return err
+	}
+	newEntries, err := ruleSetEntries(newRuleSet)
+	if err != nil {
+		return err
+	}
+	oldExists := make(map[string]bool, len(oldEntries))
+	for _, entry := range oldEntries {
+		oldExists[entry] = true
+	}
+	newExists := make(map[string]bool, len(newEntries))
+	for _, entry := range newEntries {
+		newExists[entry] = true
+	}
+	var output strings.Builder
+	for _, entry := range oldEntries {
+		if !newExists[entry] {
+			output.WriteString("- " + entry + "\n")
+		}
+	}
+	for _, entry := range newEntries {
+		if !oldExists[entry] {
+			output.WriteString("+ " + entry + "\n")
+		}
+	}
+	_, err = os.Stdout.WriteString(output.String())
+	return err
+}
+
+// ruleSetEntries flattens a rule-set into unique comparable entries: one per
+// item for rules whose items can be combined, and one per rule otherwise.
+func ruleSetEntries(ruleSet option.PlainRuleSet) ([]string, error) {
+	var (
+		entries []string
+		exists  = make(map[string]bool)
+	)
+	addEntry := func(entry string) {
+		if !exists[entry] {
+			exists[entry] = true
+			entries = append(entries, entry)
+		}
+	}
+	for _, rule := range ruleSet.Rules {
+		ruleItems, loaded := headlessRuleItems(rule)
+		if !loaded {
+			content, err := json.Marshal(rule)
+			if err != nil {
+				return nil, err
+			}
+			addEntry("rule " + string(content))
+			continue
+		}
+		for _, field := range sortedFields(ruleItems) {
+			for _, item := range ruleItems[field] {
+				addEntry(field + " " + strings.Trim(string(item), "\""))
+			}
+		}
+	}
+	return entries, nil
+}
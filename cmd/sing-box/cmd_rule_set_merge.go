@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/srs"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/spf13/cobra"
+)
+
+var commandRuleSetMerge = &cobra.Command{
+	Use:   "merge <output-path> <source-path>...",
+	Short: "Merge rule-sets and remove duplicate entries",
+	Args:  cobra.MinimumNArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := mergeRuleSets(args[0], args[1:])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandRuleSet.AddCommand(commandRuleSetMerge)
+}
+
+func mergeRuleSets(outputPath string, sourcePaths []string) error {
+	var rules []option.HeadlessRule
+	for _, sourcePath := range sourcePaths {
+		ruleSet, err := readRuleSet(sourcePath)
+		if err != nil {
+			return err
+		}
+		rules = append(rules, ruleSet.Rules...)
+	}
+	mergedRules, err := dedupeHeadlessRules(rules)
+	if err != nil {
+		return err
+	}
+	ruleSet := option.PlainRuleSet{Rules: mergedRules}
+	buffer := new(bytes.Buffer)
+	if strings.HasSuffix(outputPath, ".srs") {
+		err = srs.Write(buffer, ruleSet)
+	} else {
+		encoder := json.NewEncoder(buffer)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(option.PlainRuleSetCompat{
+			Version: C.RuleSetVersion1,
+			Options: ruleSet,
+		})
+	}
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outputPath, buffer.Bytes(), 0o644)
+}
+
+var destinationAddressFields = []string{"domain", "domain_suffix", "domain_keyword", "domain_regex", "ip_cidr"}
+
+// readRuleSet reads a rule-set in either source or binary format.
+func readRuleSet(path string) (option.PlainRuleSet, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return option.PlainRuleSet{}, err
+	}
+	if bytes.HasPrefix(content, srs.MagicBytes[:]) {
+		ruleSet, err := srs.Read(bytes.NewReader(content), true)
+		if err != nil {
+			return option.PlainRuleSet{}, E.Cause(err, "read rule-set at ", path)
+		}
+		return ruleSet, nil
+	}
+	compat, err := json.UnmarshalExtended[option.PlainRuleSetCompat](content)
+	if err != nil {
+		return option.PlainRuleSet{}, E.Cause(err, "read rule-set at ", path)
+	}
+	return compat.Upgrade()
+}
+
+// headlessRuleItems splits a rule into its items per field if the items can
+// be combined freely with those of other rules: either the rule matches on a
+// single field, or all its fields are destination address fields, which a
+// rule matches in disjunction.
+func headlessRuleItems(rule option.HeadlessRule) (map[string][]json.RawMessage, bool) {
+	if rule.Type != C.RuleTypeDefault || rule.DefaultOptions.Invert {
+		return nil, false
+	}
+	content, err := json.Marshal(rule)
+	if err != nil {
+		return nil, false
+	}
+	var fields map[string]json.RawMessage
+	err = json.Unmarshal(content, &fields)
+	if err != nil || len(fields) == 0 {
+		return nil, false
+	}
+	if len(fields) > 1 {
+		for field := range fields {
+			if !common.Contains(destinationAddressFields, field) {
+				return nil, false
+			}
+		}
+	}
+	fieldItems := make(map[string][]json.RawMessage)
+	for field, value := range fields {
+		var items []json.RawMessage
+		// Listable fields with a single item are encoded as a plain value.
+		if json.Unmarshal(value, &items) != nil {
+			items = []json.RawMessage{value}
+		}
+		fieldItems[field] = items
+	}
+	return fieldItems, true
+}
+
+func dedupeHeadlessRules(rules []option.HeadlessRule) ([]option.HeadlessRule, error) {
+	var (
+		fields      []string
+		fieldItems  = make(map[string][]json.RawMessage)
+		itemExists  = make(map[string]bool)
+		otherRules  []option.HeadlessRule
+		otherExists = make(map[string]bool)
+	)
+	for _, rule := range rules {
+		ruleItems, loaded := headlessRuleItems(rule)
+		if !loaded {
+			content, err := json.Marshal(rule)
+			if err != nil {
+				return nil, err
+			}
+			if !otherExists[string(content)] {
+				otherExists[string(content)] = true
+				otherRules = append(otherRules, rule)
+			}
+			continue
+		}
+		for _, field := range sortedFields(ruleItems) {
+			if _, exists := fieldItems[field]; !exists {
+				fields = append(fields, field)
+			}
+			for _, item := range ruleItems[field] {
+				key := field + "\x00" + string(item)
+				if !itemExists[key] {
+					itemExists[key] = true
+					fieldItems[field] = append(fieldItems[field], item)
+				}
+			}
+		}
+	}
+	mergedRules := make([]option.HeadlessRule, 0, len(fields)+len(otherRules))
+	for _, field := range fields {
+		content, err := json.Marshal(map[string]any{field: fieldItems[field]})
+		if err != nil {
+			return nil, err
+		}
+		var rule option.HeadlessRule
+		err = json.Unmarshal(content, &rule)
+		if err != nil {
+			return nil, err
+		}
+		mergedRules = append(mergedRules, rule)
+	}
+	return append(mergedRules, otherRules...), nil
+}
+
+func sortedFields(fieldItems map[string][]json.RawMessage) []string {
+	fields := make([]string, 0, len(fieldItems))
+	for field := range fieldItems {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
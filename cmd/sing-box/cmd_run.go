@@ -13,9 +13,11 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/common/configformat"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/json/badjson"
@@ -27,7 +29,10 @@ var commandRun = &cobra.Command{
 	Use:   "run",
 	Short: "Run service",
 	Run: func(cmd *cobra.Command, args []string) {
-		err := run()
+		isService, err := runService()
+		if !isService {
+			err = run()
+		}
 		if err != nil {
 			log.Fatal(err)
 		}
@@ -57,7 +62,11 @@ func readConfigAt(path string) (*OptionsEntry, error) {
 	if err != nil {
 		return nil, E.Cause(err, "read config at ", path)
 	}
-	options, err := json.UnmarshalExtended[option.Options](configContent)
+	standardContent, err := configformat.ToJSON(path, configContent)
+	if err != nil {
+		return nil, E.Cause(err, "decode config at ", path)
+	}
+	options, err := json.UnmarshalExtended[option.Options](standardContent)
 	if err != nil {
 		return nil, E.Cause(err, "decode config at ", path)
 	}
@@ -83,7 +92,7 @@ func readConfig() ([]*OptionsEntry, error) {
 			return nil, E.Cause(err, "read config directory at ", directory)
 		}
 		for _, entry := range entries {
-			if !strings.HasSuffix(entry.Name(), ".json") || entry.IsDir() {
+			if entry.IsDir() || !common.Contains(configformat.Extensions, strings.ToLower(filepath.Ext(entry.Name()))) {
 				continue
 			}
 			optionsEntry, err := readConfigAt(filepath.Join(directory, entry.Name()))
@@ -104,11 +113,27 @@ func readConfigAndMerge() (option.Options, error) {
 	if err != nil {
 		return option.Options{}, err
 	}
+	return mergeConfigEntries(optionsList, nil)
+}
+
+// mergeConfigEntries merges configurations in order. Scalar values set by an
+// earlier entry take precedence; conflictHandler, if set, is called for each
+// value of a later entry that is ignored because of that.
+func mergeConfigEntries(optionsList []*OptionsEntry, conflictHandler func(path string, entry *OptionsEntry, value any, mergedValue any)) (option.Options, error) {
 	if len(optionsList) == 1 {
 		return optionsList[0].options, nil
 	}
-	var mergedMessage json.RawMessage
+	var (
+		mergedMessage json.RawMessage
+		err           error
+	)
 	for _, options := range optionsList {
+		if conflictHandler != nil && mergedMessage != nil {
+			err = reportMergeConflicts(options, mergedMessage, conflictHandler)
+			if err != nil {
+				return option.Options{}, E.Cause(err, "merge config at ", options.path)
+			}
+		}
 		mergedMessage, err = badjson.MergeJSON(options.options.RawMessage, mergedMessage, false)
 		if err != nil {
 			return option.Options{}, E.Cause(err, "merge config at ", options.path)
@@ -166,6 +191,20 @@ func create() (*box.Box, context.CancelFunc, error) {
 	return instance, cancel, nil
 }
 
+func reload(instance *box.Box) error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	if disableColor {
+		if options.Log == nil {
+			options.Log = &option.LogOptions{}
+		}
+		options.Log.DisableColor = true
+	}
+	return instance.Reload(options)
+}
+
 func run() error {
 	osSignals := make(chan os.Signal, 1)
 	signal.Notify(osSignals, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
@@ -184,6 +223,11 @@ func run() error {
 					log.Error(E.Cause(err, "reload service"))
 					continue
 				}
+				err = reload(instance)
+				if err == nil {
+					continue
+				}
+				log.Warn(E.Cause(err, "reload service in place"), ", restarting")
 			}
 			cancel()
 			closeCtx, closed := context.WithCancel(context.Background())
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"github.com/sagernet/sing-box/log"
+
+	"github.com/spf13/cobra"
+)
+
+var commandServiceFlagName string
+
+var commandService = &cobra.Command{
+	Use:   "service",
+	Short: "Manage the system service (Windows only)",
+}
+
+var commandServiceInstall = &cobra.Command{
+	Use:   "install",
+	Short: "Register sing-box as a system service with the current configuration",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := installService(commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceUninstall = &cobra.Command{
+	Use:   "uninstall",
+	Short: "Stop and remove the system service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := uninstallService(commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStart = &cobra.Command{
+	Use:   "start",
+	Short: "Start the system service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := startService(commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+var commandServiceStop = &cobra.Command{
+	Use:   "stop",
+	Short: "Stop the system service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := stopService(commandServiceFlagName)
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandService.PersistentFlags().StringVarP(&commandServiceFlagName, "name", "n", "sing-box", "Service name")
+	commandService.AddCommand(commandServiceInstall)
+	commandService.AddCommand(commandServiceUninstall)
+	commandService.AddCommand(commandServiceStart)
+	commandService.AddCommand(commandServiceStop)
+	mainCommand.AddCommand(commandService)
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var errServiceUnsupported = E.New("service management is only supported on Windows")
+
+func runService() (bool, error) {
+	return false, nil
+}
+
+func installService(name string) error {
+	return errServiceUnsupported
+}
+
+func uninstallService(name string) error {
+	return errServiceUnsupported
+}
+
+func startService(name string) error {
+	return errServiceUnsupported
+}
+
+func stopService(name string) error {
+	return errServiceUnsupported
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	runtimeDebug "runtime/debug"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const (
+	serviceEventStart uint32 = 1
+	serviceEventStop  uint32 = 2
+	serviceEventError uint32 = 3
+)
+
+// runService runs the service control loop when the process was started by
+// the service control manager.
+func runService() (bool, error) {
+	isService, err := svc.IsWindowsService()
+	if err != nil {
+		return false, E.Cause(err, "detect windows service")
+	}
+	if !isService {
+		return false, nil
+	}
+	return true, svc.Run("", new(windowsService))
+}
+
+type windowsService struct{}
+
+func (s *windowsService) Execute(args []string, requests <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
+	var eventLog *eventlog.Log
+	if len(args) > 0 {
+		eventLog, _ = eventlog.Open(args[0])
+	}
+	if eventLog != nil {
+		defer eventLog.Close()
+	}
+	status <- svc.Status{State: svc.StartPending}
+	instance, cancel, err := create()
+	if err != nil {
+		if eventLog != nil {
+			eventLog.Error(serviceEventError, err.Error())
+		}
+		return true, 1
+	}
+	runtimeDebug.FreeOSMemory()
+	status <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown}
+	if eventLog != nil {
+		eventLog.Info(serviceEventStart, "sing-box started")
+	}
+	for request := range requests {
+		switch request.Cmd {
+		case svc.Interrogate:
+			status <- request.CurrentStatus
+		case svc.Stop, svc.Shutdown:
+			status <- svc.Status{State: svc.StopPending}
+			cancel()
+			closeCtx, closed := context.WithCancel(context.Background())
+			go closeMonitor(closeCtx)
+			err = instance.Close()
+			closed()
+			if eventLog != nil {
+				if err != nil {
+					eventLog.Warning(serviceEventStop, E.Cause(err, "sing-box stopped").Error())
+				} else {
+					eventLog.Info(serviceEventStop, "sing-box stopped")
+				}
+			}
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+func installService(name string) error {
+	executablePath, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	executablePath, err = filepath.Abs(executablePath)
+	if err != nil {
+		return err
+	}
+	workingDirectory, err := os.Getwd()
+	if err != nil {
+		return err
+	}
+	serviceArgs := []string{"run", "-D", workingDirectory}
+	for _, path := range configPaths {
+		serviceArgs = append(serviceArgs, "-c", path)
+	}
+	for _, directory := range configDirectories {
+		serviceArgs = append(serviceArgs, "-C", directory)
+	}
+	if disableColor {
+		serviceArgs = append(serviceArgs, "--disable-color")
+	}
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err == nil {
+		service.Close()
+		return E.New("service ", name, " already exists")
+	}
+	service, err = manager.CreateService(name, executablePath, mgr.Config{
+		DisplayName: "sing-box (" + name + ")",
+		Description: "The universal proxy platform.",
+		StartType:   mgr.StartAutomatic,
+	}, serviceArgs...)
+	if err != nil {
+		return E.Cause(err, "create service")
+	}
+	defer service.Close()
+	err = service.SetRecoveryActions([]mgr.RecoveryAction{
+		{Type: mgr.ServiceRestart, Delay: 5 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: 30 * time.Second},
+		{Type: mgr.ServiceRestart, Delay: time.Minute},
+	}, uint32((24 * time.Hour).Seconds()))
+	if err == nil {
+		err = service.SetRecoveryActionsOnNonCrashFailures(true)
+	}
+	if err != nil {
+		service.Delete()
+		return E.Cause(err, "set recovery actions")
+	}
+	err = eventlog.InstallAsEventCreate(name, eventlog.Error|eventlog.Warning|eventlog.Info)
+	if err != nil {
+		service.Delete()
+		return E.Cause(err, "install event log source")
+	}
+	return nil
+}
+
+func uninstallService(name string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err != nil {
+		return E.Cause(err, "open service ", name)
+	}
+	defer service.Close()
+	serviceStatus, err := service.Query()
+	if err != nil {
+		return E.Cause(err, "query service")
+	}
+	if serviceStatus.State != svc.Stopped {
+		err = stopAndWait(service)
+		if err != nil {
+			return err
+		}
+	}
+	err = service.Delete()
+	if err != nil {
+		return E.Cause(err, "delete service")
+	}
+	err = eventlog.Remove(name)
+	if err != nil && !errors.Is(err, windows.ERROR_FILE_NOT_FOUND) {
+		return E.Cause(err, "remove event log source")
+	}
+	return nil
+}
+
+func startService(name string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err != nil {
+		return E.Cause(err, "open service ", name)
+	}
+	defer service.Close()
+	err = service.Start()
+	if err != nil {
+		return E.Cause(err, "start service")
+	}
+	return nil
+}
+
+func stopService(name string) error {
+	manager, err := mgr.Connect()
+	if err != nil {
+		return E.Cause(err, "connect to service manager")
+	}
+	defer manager.Disconnect()
+	service, err := manager.OpenService(name)
+	if err != nil {
+		return E.Cause(err, "open service ", name)
+	}
+	defer service.Close()
+	return stopAndWait(service)
+}
+
+func stopAndWait(service *mgr.Service) error {
+	serviceStatus, err := service.Control(svc.Stop)
+	if err != nil {
+		return E.Cause(err, "stop service")
+	}
+	timeout := time.Now().Add(15 * time.Second)
+	for serviceStatus.State != svc.Stopped {
+		if time.Now().After(timeout) {
+			return E.New("timeout waiting for service to stop")
+		}
+		time.Sleep(300 * time.Millisecond)
+		serviceStatus, err = service.Query()
+		if err != nil {
+			return E.Cause(err, "query service")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"strconv"
+	"sync/atomic"
+	"text/tabwriter"
+	"time"
+
+	"github.com/sagernet/sing-box"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandBenchFlagDuration    time.Duration
+	commandBenchFlagConnections int
+	commandBenchFlagPacketSize  int
+	commandBenchFlagUDPRate     int
+)
+
+var commandBench = &cobra.Command{
+	Use:   "bench",
+	Short: "Measure throughput and connection setup rate of outbounds",
+	Long: "Pair each outbound in the configuration with a generated loopback inbound of the same protocol\n" +
+		"and measure it in-process, independent of the network path to the configured server.\n" +
+		"Server certificates are replaced with a self-signed one; REALITY and shadowsocks plugins are not supported.",
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := bench()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandBench.Flags().DurationVarP(&commandBenchFlagDuration, "duration", "d", 5*time.Second, "Set throughput test duration")
+	commandBench.Flags().IntVarP(&commandBenchFlagConnections, "connections", "n", 100, "Set connection count for the setup rate test")
+	commandBench.Flags().IntVar(&commandBenchFlagPacketSize, "packet-size", 1200, "Set UDP payload size")
+	commandBench.Flags().IntVar(&commandBenchFlagUDPRate, "udp-rate", 200, "Set offered UDP load in Mbps")
+	commandTools.AddCommand(commandBench)
+}
+
+type benchResult struct {
+	tag          string
+	outboundType string
+	setupRate    float64
+	setupLatency time.Duration
+	tcpRate      float64
+	udpRate      float64
+	udpLoss      float64
+	err          error
+}
+
+func bench() error {
+	options, err := readConfigAndMerge()
+	if err != nil {
+		return err
+	}
+	var outbounds []option.Outbound
+	if commandToolsFlagOutbound != "" {
+		for _, outbound := range options.Outbounds {
+			if outbound.Tag == commandToolsFlagOutbound {
+				outbounds = append(outbounds, outbound)
+				break
+			}
+		}
+		if len(outbounds) == 0 {
+			return E.New("outbound not found: ", commandToolsFlagOutbound)
+		}
+	} else {
+		outbounds = common.Filter(options.Outbounds, func(it option.Outbound) bool {
+			switch it.Type {
+			case C.TypeDirect, C.TypeBlock, C.TypeDNS, C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback:
+				return false
+			}
+			return true
+		})
+	}
+	sink, err := newBenchSink()
+	if err != nil {
+		return err
+	}
+	defer sink.Close()
+	results := make([]benchResult, 0, len(outbounds))
+	for _, outbound := range outbounds {
+		log.Info("benchmarking outbound/", outbound.Type, "[", outbound.Tag, "]")
+		results = append(results, benchLoopback(outbound, sink))
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	writer.Write([]byte("OUTBOUND\tTYPE\tSETUP\tLATENCY\tTCP\tUDP\tUDP LOSS\n"))
+	for _, result := range results {
+		if result.err != nil {
+			writer.Write([]byte(F.ToString(result.tag, "\t", result.outboundType, "\terror: ", result.err, "\n")))
+			continue
+		}
+		udpRate, udpLoss := "-", "-"
+		if result.udpRate >= 0 {
+			udpRate = formatBenchRate(result.udpRate)
+			udpLoss = strconv.FormatFloat(result.udpLoss*100, 'f', 1, 64) + "%"
+		}
+		writer.Write([]byte(F.ToString(
+			result.tag, "\t",
+			result.outboundType, "\t",
+			strconv.FormatFloat(result.setupRate, 'f', 1, 64), " conn/s\t",
+			result.setupLatency.Round(time.Microsecond), "\t",
+			formatBenchRate(result.tcpRate), "\t",
+			udpRate, "\t",
+			udpLoss, "\n",
+		)))
+	}
+	return writer.Flush()
+}
+
+// benchLoopback starts an in-process instance with the outbound pointed at a
+// generated loopback inbound of the same protocol, so that only the protocol
+// itself is measured, not the network path to the configured server.
+func benchLoopback(outboundOptions option.Outbound, sink *benchSink) benchResult {
+	result := benchResult{
+		tag:          outboundOptions.Tag,
+		outboundType: outboundOptions.Type,
+		udpRate:      -1,
+	}
+	port, err := benchFreePort()
+	if err != nil {
+		result.err = err
+		return result
+	}
+	inboundOptions, err := benchLoopbackPair(&outboundOptions, port)
+	if err != nil {
+		result.err = err
+		return result
+	}
+	inboundOptions.Tag = "bench-in"
+	outboundOptions.Tag = "bench-out"
+	instance, err := box.New(box.Options{
+		Context: context.Background(),
+		Options: option.Options{
+			Log:       &option.LogOptions{Disabled: true},
+			Inbounds:  []option.Inbound{inboundOptions},
+			Outbounds: []option.Outbound{{Type: C.TypeDirect, Tag: "direct"}, outboundOptions},
+			Route:     &option.RouteOptions{Final: "direct"},
+		},
+	})
+	if err != nil {
+		result.err = E.Cause(err, "create loopback service")
+		return result
+	}
+	defer instance.Close()
+	err = instance.Start()
+	if err != nil {
+		result.err = E.Cause(err, "start loopback service")
+		return result
+	}
+	outbound, _ := instance.Router().Outbound(outboundOptions.Tag)
+	benchOutbound(outbound, sink, &result)
+	return result
+}
+
+// benchLoopbackPair rewrites the outbound to dial 127.0.0.1:port and returns a
+// matching inbound listening there.
+func benchLoopbackPair(outbound *option.Outbound, port uint16) (option.Inbound, error) {
+	listen := option.ListenOptions{
+		Listen:     option.NewListenAddress(netip.AddrFrom4([4]byte{127, 0, 0, 1})),
+		ListenPort: port,
+	}
+	server := option.ServerOptions{
+		Server:     "127.0.0.1",
+		ServerPort: port,
+	}
+	inbound := option.Inbound{Type: outbound.Type}
+	var err error
+	switch outbound.Type {
+	case C.TypeSOCKS:
+		options := &outbound.SocksOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.SocksOptions = option.SocksInboundOptions{
+			ListenOptions: listen,
+			Users:         benchAuthUsers(options.Username, options.Password),
+		}
+	case C.TypeHTTP:
+		options := &outbound.HTTPOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.HTTPOptions = option.HTTPMixedInboundOptions{
+			ListenOptions: listen,
+			Users:         benchAuthUsers(options.Username, options.Password),
+		}
+		inbound.HTTPOptions.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	case C.TypeShadowsocks:
+		options := &outbound.ShadowsocksOptions
+		if options.Plugin != "" {
+			return option.Inbound{}, E.New("plugin is not supported")
+		}
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.ShadowsocksOptions = option.ShadowsocksInboundOptions{
+			ListenOptions: listen,
+			Method:        options.Method,
+			Password:      options.Password,
+			Multiplex:     benchLoopbackMultiplex(options.Multiplex),
+		}
+	case C.TypeVMess:
+		options := &outbound.VMessOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.VMessOptions = option.VMessInboundOptions{
+			ListenOptions: listen,
+			Users:         []option.VMessUser{{UUID: options.UUID, AlterId: options.AlterId}},
+			Multiplex:     benchLoopbackMultiplex(options.Multiplex),
+			Transport:     options.Transport,
+		}
+		inbound.VMessOptions.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	case C.TypeVLESS:
+		options := &outbound.VLESSOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.VLESSOptions = option.VLESSInboundOptions{
+			ListenOptions: listen,
+			Users:         []option.VLESSUser{{UUID: options.UUID, Flow: options.Flow}},
+			Multiplex:     benchLoopbackMultiplex(options.Multiplex),
+			Transport:     options.Transport,
+		}
+		inbound.VLESSOptions.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	case C.TypeTrojan:
+		options := &outbound.TrojanOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.TrojanOptions = option.TrojanInboundOptions{
+			ListenOptions: listen,
+			Users:         []option.TrojanUser{{Password: options.Password}},
+			Multiplex:     benchLoopbackMultiplex(options.Multiplex),
+			Transport:     options.Transport,
+		}
+		inbound.TrojanOptions.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	case C.TypeHysteria2:
+		options := &outbound.Hysteria2Options
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.Hysteria2Options = option.Hysteria2InboundOptions{
+			ListenOptions: listen,
+			Obfs:          options.Obfs,
+			Users:         []option.Hysteria2User{{Password: options.Password}},
+		}
+		inbound.Hysteria2Options.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	case C.TypeTUIC:
+		options := &outbound.TUICOptions
+		options.DialerOptions = option.DialerOptions{}
+		options.ServerOptions = server
+		inbound.TUICOptions = option.TUICInboundOptions{
+			ListenOptions:     listen,
+			Users:             []option.TUICUser{{UUID: options.UUID, Password: options.Password}},
+			CongestionControl: options.CongestionControl,
+			ZeroRTTHandshake:  options.ZeroRTTHandshake,
+		}
+		inbound.TUICOptions.TLS, options.TLS, err = benchLoopbackTLS(options.TLS)
+	default:
+		return option.Inbound{}, E.New("loopback benchmark is not supported for ", outbound.Type)
+	}
+	if err != nil {
+		return option.Inbound{}, err
+	}
+	return inbound, nil
+}
+
+// benchLoopbackTLS replaces the server certificate with a generated one and
+// makes the client trust it.
+func benchLoopbackTLS(options *option.OutboundTLSOptions) (*option.InboundTLSOptions, *option.OutboundTLSOptions, error) {
+	if options == nil || !options.Enabled {
+		return nil, options, nil
+	}
+	if options.Reality != nil && options.Reality.Enabled {
+		return nil, nil, E.New("reality is not supported")
+	}
+	serverName := options.ServerName
+	if serverName == "" || M.ParseAddr(serverName).IsValid() {
+		serverName = "localhost"
+	}
+	privateKeyPem, certificatePem, err := tls.GenerateKeyPair(time.Now, serverName, time.Now().Add(time.Hour))
+	if err != nil {
+		return nil, nil, err
+	}
+	clientOptions := *options
+	clientOptions.ServerName = serverName
+	clientOptions.Insecure = true
+	clientOptions.Certificate = nil
+	clientOptions.CertificatePath = ""
+	clientOptions.CertificateDirectoryPath = ""
+	clientOptions.CertificatePublicKeySHA256 = nil
+	clientOptions.ECH = nil
+	return &option.InboundTLSOptions{
+		Enabled:     true,
+		ServerName:  serverName,
+		ALPN:        options.ALPN,
+		Certificate: pemLines(certificatePem),
+		Key:         pemLines(privateKeyPem),
+	}, &clientOptions, nil
+}
+
+func benchLoopbackMultiplex(options *option.OutboundMultiplexOptions) *option.InboundMultiplexOptions {
+	if options == nil || !options.Enabled {
+		return nil
+	}
+	return &option.InboundMultiplexOptions{
+		Enabled: true,
+		Padding: options.Padding,
+		Brutal:  options.Brutal,
+	}
+}
+
+func benchAuthUsers(username string, password string) []auth.User {
+	if username == "" {
+		return nil
+	}
+	return []auth.User{{Username: username, Password: password}}
+}
+
+// benchFreePort returns a loopback port that is free for both TCP and UDP, as
+// QUIC based protocols and transports listen on UDP.
+func benchFreePort() (uint16, error) {
+	for i := 0; i < 10; i++ {
+		listener, err := net.Listen(N.NetworkTCP, "127.0.0.1:0")
+		if err != nil {
+			return 0, err
+		}
+		port := M.SocksaddrFromNet(listener.Addr()).Port
+		packetConn, err := net.ListenPacket(N.NetworkUDP, net.JoinHostPort("127.0.0.1", F.ToString(port)))
+		listener.Close()
+		if err == nil {
+			packetConn.Close()
+			return port, nil
+		}
+	}
+	return 0, E.New("no free loopback port")
+}
+
+func benchOutbound(outbound adapter.Outbound, sink *benchSink, result *benchResult) {
+	ctx := context.Background()
+	startAt := time.Now()
+	for i := 0; i < commandBenchFlagConnections; i++ {
+		conn, err := outbound.DialContext(ctx, N.NetworkTCP, sink.echoAddress)
+		if err == nil {
+			err = benchEcho(conn)
+			conn.Close()
+		}
+		if err != nil {
+			result.err = E.Cause(err, "setup")
+			return
+		}
+	}
+	setupDuration := time.Since(startAt)
+	result.setupRate = float64(commandBenchFlagConnections) / setupDuration.Seconds()
+	result.setupLatency = setupDuration / time.Duration(commandBenchFlagConnections)
+
+	conn, err := outbound.DialContext(ctx, N.NetworkTCP, sink.discardAddress)
+	if err != nil {
+		result.err = E.Cause(err, "tcp")
+		return
+	}
+	received := sink.tcpReceived.Load()
+	buffer := make([]byte, 32*1024)
+	startAt = time.Now()
+	conn.SetWriteDeadline(startAt.Add(commandBenchFlagDuration))
+	for time.Since(startAt) < commandBenchFlagDuration {
+		_, err = conn.Write(buffer)
+		if err != nil {
+			break
+		}
+	}
+	conn.Close()
+	time.Sleep(100 * time.Millisecond)
+	result.tcpRate = float64(sink.tcpReceived.Load()-received) / time.Since(startAt).Seconds()
+
+	if common.Contains(outbound.Network(), N.NetworkUDP) {
+		packetConn, err := outbound.ListenPacket(ctx, sink.udpAddress)
+		if err != nil {
+			result.err = E.Cause(err, "udp")
+			return
+		}
+		received = sink.udpReceived.Load()
+		packet := make([]byte, commandBenchFlagPacketSize)
+		bytesPerSecond := float64(commandBenchFlagUDPRate) * 1000 * 1000 / 8
+		var sent int64
+		startAt = time.Now()
+		for {
+			elapsed := time.Since(startAt)
+			if elapsed >= commandBenchFlagDuration {
+				break
+			}
+			if float64(sent) >= bytesPerSecond*elapsed.Seconds() {
+				time.Sleep(time.Millisecond)
+				continue
+			}
+			_, err = packetConn.WriteTo(packet, sink.udpAddress.UDPAddr())
+			if err != nil {
+				break
+			}
+			sent += int64(len(packet))
+		}
+		time.Sleep(100 * time.Millisecond)
+		packetConn.Close()
+		udpReceived := sink.udpReceived.Load() - received
+		result.udpRate = float64(udpReceived) / time.Since(startAt).Seconds()
+		if sent > 0 {
+			result.udpLoss = 1 - float64(udpReceived)/float64(sent)
+		}
+	}
+}
+
+func benchEcho(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(C.TCPTimeout))
+	_, err := conn.Write([]byte{0})
+	if err != nil {
+		return err
+	}
+	_, err = io.ReadFull(conn, make([]byte, 1))
+	return err
+}
+
+func formatBenchRate(bytesPerSecond float64) string {
+	return strconv.FormatFloat(bytesPerSecond*8/1000/1000, 'f', 1, 64) + " Mbps"
+}
+
+type benchSink struct {
+	echoListener    net.Listener
+	discardListener net.Listener
+	udpConn         net.PacketConn
+	echoAddress     M.Socksaddr
+	discardAddress  M.Socksaddr
+	udpAddress      M.Socksaddr
+	tcpReceived     atomic.Int64
+	udpReceived     atomic.Int64
+}
+
+func newBenchSink() (*benchSink, error) {
+	sink := &benchSink{}
+	var err error
+	sink.echoListener, err = net.Listen(N.NetworkTCP, "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	sink.discardListener, err = net.Listen(N.NetworkTCP, "127.0.0.1:0")
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	sink.udpConn, err = net.ListenPacket(N.NetworkUDP, "127.0.0.1:0")
+	if err != nil {
+		sink.Close()
+		return nil, err
+	}
+	sink.echoAddress = M.SocksaddrFromNet(sink.echoListener.Addr())
+	sink.discardAddress = M.SocksaddrFromNet(sink.discardListener.Addr())
+	sink.udpAddress = M.SocksaddrFromNet(sink.udpConn.LocalAddr())
+	go sink.acceptLoop(sink.echoListener, func(conn net.Conn) {
+		io.Copy(conn, conn)
+	})
+	go sink.acceptLoop(sink.discardListener, func(conn net.Conn) {
+		buffer := make([]byte, 32*1024)
+		for {
+			n, err := conn.Read(buffer)
+			sink.tcpReceived.Add(int64(n))
+			if err != nil {
+				return
+			}
+		}
+	})
+	go func() {
+		buffer := make([]byte, 65535)
+		for {
+			n, _, err := sink.udpConn.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			sink.udpReceived.Add(int64(n))
+		}
+	}()
+	return sink, nil
+}
+
+func (s *benchSink) acceptLoop(listener net.Listener, handler func(conn net.Conn)) {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			defer conn.Close()
+			handler(conn)
+		}()
+	}
+}
+
+func (s *benchSink) Close() error {
+	return common.Close(s.echoListener, s.discardListener, s.udpConn)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandKnockFlagKey  string
+	commandKnockFlagPort uint16
+)
+
+var commandKnock = &cobra.Command{
+	Use:   "knock <address>",
+	Short: "Send a port knocking packet",
+	Args:  cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		err := sendKnock(args[0])
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandKnock.Flags().StringVarP(&commandKnockFlagKey, "key", "k", "", "knock key")
+	commandKnock.Flags().Uint16VarP(&commandKnockFlagPort, "port", "p", 0, "knock port, overrides the port in address")
+	commandTools.AddCommand(commandKnock)
+}
+
+func sendKnock(address string) error {
+	if commandKnockFlagKey == "" {
+		return E.New("missing knock key")
+	}
+	destination := M.ParseSocksaddr(address)
+	if commandKnockFlagPort != 0 {
+		destination.Port = commandKnockFlagPort
+	}
+	if destination.Port == 0 {
+		return E.New("missing knock port")
+	}
+	instance, err := createPreStartedClient()
+	if err != nil {
+		return err
+	}
+	defer instance.Close()
+	udpDialer, err := createDialer(instance, N.NetworkUDP, commandToolsFlagOutbound)
+	if err != nil {
+		return err
+	}
+	err = dialer.Knock(context.Background(), udpDialer, destination, []byte(commandKnockFlagKey))
+	if err != nil {
+		return E.Cause(err, "send knock")
+	}
+	log.Info("knocked ", destination)
+	return nil
+}
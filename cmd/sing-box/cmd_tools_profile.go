@@ -0,0 +1,187 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/spf13/cobra"
+)
+
+var (
+	commandProfileFlagAddress  string
+	commandProfileFlagDuration time.Duration
+	commandProfileFlagOutput   string
+	commandProfileFlagBlock    int
+	commandProfileFlagMutex    int
+)
+
+var commandProfile = &cobra.Command{
+	Use:   "profile",
+	Short: "Collect profiles from a running instance via the debug service",
+	Args:  cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		err := collectProfile()
+		if err != nil {
+			log.Fatal(err)
+		}
+	},
+}
+
+func init() {
+	commandProfile.Flags().StringVarP(&commandProfileFlagAddress, "address", "a", "", "Set debug service address (default: experimental.debug.listen from config)")
+	commandProfile.Flags().DurationVarP(&commandProfileFlagDuration, "duration", "d", 30*time.Second, "Set CPU profile duration")
+	commandProfile.Flags().IntVar(&commandProfileFlagBlock, "block-rate", 10000, "Set block profile rate in nanoseconds during the capture window")
+	commandProfile.Flags().IntVar(&commandProfileFlagMutex, "mutex-fraction", 10, "Set mutex profile fraction during the capture window")
+	commandProfile.Flags().StringVar(&commandProfileFlagOutput, "output", "", "Set output bundle path (default: sing-box-profile-<time>.tar.gz)")
+	commandTools.AddCommand(commandProfile)
+}
+
+type profileEntry struct {
+	name string
+	path string
+}
+
+func collectProfile() error {
+	address := commandProfileFlagAddress
+	if address == "" {
+		options, err := readConfigAndMerge()
+		if err != nil {
+			return err
+		}
+		if options.Experimental == nil || options.Experimental.Debug == nil || options.Experimental.Debug.Listen == "" {
+			return E.New("missing debug service address: set experimental.debug.listen or --address")
+		}
+		address = options.Experimental.Debug.Listen
+	}
+	host, port, err := net.SplitHostPort(address)
+	if err != nil {
+		return E.Cause(err, "parse debug service address")
+	}
+	if host == "" || host == "0.0.0.0" || host == "::" {
+		host = "127.0.0.1"
+	}
+	baseURL := "http://" + net.JoinHostPort(host, port) + "/debug"
+	seconds := int(commandProfileFlagDuration.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	outputPath := commandProfileFlagOutput
+	if outputPath == "" {
+		outputPath = "sing-box-profile-" + time.Now().Format("20060102-150405") + ".tar.gz"
+	}
+	entries := []profileEntry{
+		{"memory.json", "/memory"},
+		{"goroutine.txt", "/pprof/goroutine?debug=2"},
+		{"heap.pprof", "/pprof/heap"},
+		{"allocs.pprof", "/pprof/allocs"},
+		{"cpu.pprof", "/pprof/profile?seconds=" + strconv.Itoa(seconds)},
+		{"block.pprof", "/pprof/block"},
+		{"mutex.pprof", "/pprof/mutex"},
+		{"goroutine-after.txt", "/pprof/goroutine?debug=1"},
+	}
+	outputFile, err := os.Create(outputPath)
+	if err != nil {
+		return err
+	}
+	gzipWriter := gzip.NewWriter(outputFile)
+	tarWriter := tar.NewWriter(gzipWriter)
+	httpClient := &http.Client{
+		Timeout: commandProfileFlagDuration + C.TCPTimeout*2,
+	}
+	// block and mutex events are only recorded while sampling is enabled, so
+	// enable it for the CPU profile window and collect both right after it.
+	err = setProfileRate(httpClient, baseURL, commandProfileFlagBlock, commandProfileFlagMutex)
+	if err != nil {
+		common.Close(tarWriter, gzipWriter, outputFile)
+		os.Remove(outputPath)
+		return E.Cause(err, "enable block and mutex profiling")
+	}
+	defer func() {
+		err := setProfileRate(httpClient, baseURL, 0, 0)
+		if err != nil {
+			log.Warn(E.Cause(err, "reset block and mutex profiling"))
+		}
+	}()
+	for _, entry := range entries {
+		if entry.name == "cpu.pprof" {
+			log.Info("collecting CPU, block and mutex profiles for ", seconds, "s")
+		}
+		content, err := fetchProfile(httpClient, baseURL+entry.path)
+		if err != nil {
+			common.Close(tarWriter, gzipWriter, outputFile)
+			os.Remove(outputPath)
+			return E.Cause(err, "collect ", entry.name)
+		}
+		err = tarWriter.WriteHeader(&tar.Header{
+			Name:    entry.name,
+			Mode:    0o644,
+			Size:    int64(len(content)),
+			ModTime: time.Now(),
+		})
+		if err == nil {
+			_, err = tarWriter.Write(content)
+		}
+		if err != nil {
+			common.Close(tarWriter, gzipWriter, outputFile)
+			return E.Cause(err, "write ", entry.name)
+		}
+	}
+	err = tarWriter.Close()
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err == nil {
+		err = outputFile.Close()
+	} else {
+		outputFile.Close()
+	}
+	if err != nil {
+		return err
+	}
+	log.Info("profile bundle saved to ", outputPath)
+	return nil
+}
+
+func setProfileRate(httpClient *http.Client, baseURL string, blockRate int, mutexFraction int) error {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodPost, baseURL+"/profile_rate?block="+strconv.Itoa(blockRate)+"&mutex="+strconv.Itoa(mutexFraction), nil)
+	if err != nil {
+		return err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return err
+	}
+	response.Body.Close()
+	if response.StatusCode != http.StatusNoContent {
+		return E.New("unexpected status: ", response.Status)
+	}
+	return nil
+}
+
+func fetchProfile(httpClient *http.Client, url string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	return io.ReadAll(response.Body)
+}
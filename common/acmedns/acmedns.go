@@ -0,0 +1,63 @@
+package acmedns
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/libdns/libdns"
+)
+
+const defaultTTL = 2 * time.Minute
+
+func recordTTL(record libdns.Record) time.Duration {
+	if record.TTL <= 0 {
+		return defaultTTL
+	}
+	return record.TTL
+}
+
+func checkResponse(response *http.Response) error {
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return nil
+	}
+	content, _ := io.ReadAll(io.LimitReader(response.Body, 4096))
+	return E.New("unexpected status: ", response.Status, ": ", strings.TrimSpace(string(content)))
+}
+
+func appendValues(values []string, records []libdns.Record) []string {
+	for _, record := range records {
+		if !common.Contains(values, record.Value) {
+			values = append(values, record.Value)
+		}
+	}
+	return values
+}
+
+func removeValues(values []string, records []libdns.Record) []string {
+	return common.Filter(values, func(value string) bool {
+		return !common.Any(records, func(record libdns.Record) bool {
+			return record.Value == value
+		})
+	})
+}
+
+// groupByName groups records by their absolute name, keeping the input order.
+func groupByName(zone string, records []libdns.Record) ([]string, map[string][]libdns.Record) {
+	var (
+		names  []string
+		groups = make(map[string][]libdns.Record)
+	)
+	for _, record := range records {
+		name := libdns.AbsoluteName(record.Name, zone)
+		if _, loaded := groups[name]; !loaded {
+			names = append(names, name)
+		}
+		groups[name] = append(groups[name], record)
+	}
+	return names, groups
+}
@@ -0,0 +1,122 @@
+package acmedns
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/libdns/libdns"
+)
+
+const desecMinimumTTL = time.Hour
+
+// DeSEC manages TXT rrsets through the deSEC REST API.
+type DeSEC struct {
+	Token  string
+	access sync.Mutex
+}
+
+type desecRRSet struct {
+	SubName string   `json:"subname"`
+	Type    string   `json:"type"`
+	TTL     int      `json:"ttl,omitempty"`
+	Records []string `json:"records"`
+}
+
+func (p *DeSEC) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, appendValues)
+}
+
+func (p *DeSEC) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, removeValues)
+}
+
+func (p *DeSEC) update(ctx context.Context, zone string, records []libdns.Record, apply func([]string, []libdns.Record) []string) error {
+	p.access.Lock()
+	defer p.access.Unlock()
+	domain := strings.TrimSuffix(zone, ".")
+	names, groups := groupByName(zone, records)
+	rrSets := make([]desecRRSet, 0, len(names))
+	for _, name := range names {
+		subName := libdns.RelativeName(name, zone)
+		values, err := p.getValues(ctx, domain, subName)
+		if err != nil {
+			return err
+		}
+		ttl := recordTTL(groups[name][0])
+		if ttl < desecMinimumTTL {
+			ttl = desecMinimumTTL
+		}
+		rrSets = append(rrSets, desecRRSet{
+			SubName: subName,
+			Type:    "TXT",
+			TTL:     int(ttl.Seconds()),
+			Records: common.Map(apply(values, groups[name]), strconv.Quote),
+		})
+	}
+	content, err := json.Marshal(rrSets)
+	if err != nil {
+		return err
+	}
+	_, err = p.do(ctx, http.MethodPatch, "/domains/"+domain+"/rrsets/", content)
+	return err
+}
+
+func (p *DeSEC) getValues(ctx context.Context, domain string, subName string) ([]string, error) {
+	pathName := subName
+	if pathName == "" {
+		pathName = "@"
+	}
+	response, err := p.do(ctx, http.MethodGet, "/domains/"+domain+"/rrsets/"+pathName+"/TXT/", nil)
+	if err != nil || response == nil {
+		return nil, err
+	}
+	var rrSet desecRRSet
+	err = json.Unmarshal(response, &rrSet)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	for _, record := range rrSet.Records {
+		value, err := strconv.Unquote(record)
+		if err != nil {
+			value = record
+		}
+		values = append(values, value)
+	}
+	return values, nil
+}
+
+// do sends an API request, returning nil content for missing resources.
+func (p *DeSEC) do(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, "https://desec.io/api/v1"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "Token "+p.Token)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return nil, nil
+	}
+	err = checkResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(response.Body)
+	return buffer.Bytes(), err
+}
@@ -0,0 +1,72 @@
+package acmedns
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/libdns/libdns"
+)
+
+// DuckDNS sets the single TXT record DuckDNS keeps for each subdomain.
+type DuckDNS struct {
+	Token string
+}
+
+func (p *DuckDNS) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.update(ctx, libdns.AbsoluteName(record.Name, zone), record.Value, false)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *DuckDNS) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	for _, record := range records {
+		err := p.update(ctx, libdns.AbsoluteName(record.Name, zone), "", true)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return records, nil
+}
+
+func (p *DuckDNS) update(ctx context.Context, name string, value string, clear bool) error {
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "."), ".duckdns.org")
+	domain := name[strings.LastIndexByte(name, '.')+1:]
+	query := url.Values{
+		"domains": {domain},
+		"token":   {p.Token},
+		"txt":     {value},
+	}
+	if clear {
+		query.Set("clear", "true")
+	}
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://www.duckdns.org/update?"+query.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	err = checkResponse(response)
+	if err != nil {
+		return err
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, 64))
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(string(content), "OK") {
+		return E.New("duckdns update rejected for ", domain)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package acmedns
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"os/exec"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/libdns/libdns"
+)
+
+// Exec runs an external program as `<command...> present|cleanup <fqdn> <value>`.
+type Exec struct {
+	Command []string
+}
+
+func (p *Exec) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.run(ctx, "present", zone, records)
+}
+
+func (p *Exec) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.run(ctx, "cleanup", zone, records)
+}
+
+func (p *Exec) run(ctx context.Context, action string, zone string, records []libdns.Record) error {
+	if len(p.Command) == 0 {
+		return E.New("missing command")
+	}
+	for _, record := range records {
+		arguments := append(append([]string{}, p.Command[1:]...), action, libdns.AbsoluteName(record.Name, zone), record.Value)
+		output, err := exec.CommandContext(ctx, p.Command[0], arguments...).CombinedOutput()
+		if err != nil {
+			if message := strings.TrimSpace(string(output)); message != "" {
+				return E.Cause(err, "run ", action, ": ", message)
+			}
+			return E.Cause(err, "run ", action)
+		}
+	}
+	return nil
+}
+
+// Webhook posts a JSON object describing each change to a URL.
+type Webhook struct {
+	URL    string
+	Header http.Header
+}
+
+type webhookRequest struct {
+	Action string `json:"action"`
+	FQDN   string `json:"fqdn"`
+	Value  string `json:"value"`
+	TTL    int    `json:"ttl"`
+}
+
+func (p *Webhook) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.send(ctx, "present", zone, records)
+}
+
+func (p *Webhook) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.send(ctx, "cleanup", zone, records)
+}
+
+func (p *Webhook) send(ctx context.Context, action string, zone string, records []libdns.Record) error {
+	for _, record := range records {
+		content, err := json.Marshal(webhookRequest{
+			Action: action,
+			FQDN:   libdns.AbsoluteName(record.Name, zone),
+			Value:  record.Value,
+			TTL:    int(recordTTL(record).Seconds()),
+		})
+		if err != nil {
+			return err
+		}
+		request, err := http.NewRequestWithContext(ctx, http.MethodPost, p.URL, bytes.NewReader(content))
+		if err != nil {
+			return err
+		}
+		for name, values := range p.Header {
+			request.Header[name] = values
+		}
+		request.Header.Set("Content-Type", "application/json")
+		response, err := http.DefaultClient.Do(request)
+		if err != nil {
+			return err
+		}
+		err = checkResponse(response)
+		response.Body.Close()
+		if err != nil {
+			return E.Cause(err, "webhook ", action)
+		}
+	}
+	return nil
+}
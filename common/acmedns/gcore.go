@@ -0,0 +1,116 @@
+package acmedns
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/libdns/libdns"
+)
+
+// Gcore manages TXT rrsets through the Gcore DNS API.
+type Gcore struct {
+	APIKey string
+	access sync.Mutex
+}
+
+type gcoreRRSet struct {
+	TTL             int                   `json:"ttl"`
+	ResourceRecords []gcoreResourceRecord `json:"resource_records"`
+}
+
+type gcoreResourceRecord struct {
+	Content []string `json:"content"`
+}
+
+func (p *Gcore) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, appendValues)
+}
+
+func (p *Gcore) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, removeValues)
+}
+
+func (p *Gcore) update(ctx context.Context, zone string, records []libdns.Record, apply func([]string, []libdns.Record) []string) error {
+	p.access.Lock()
+	defer p.access.Unlock()
+	names, groups := groupByName(zone, records)
+	for _, name := range names {
+		path := "/dns/v2/zones/" + strings.TrimSuffix(zone, ".") + "/" + strings.TrimSuffix(name, ".") + "/TXT"
+		content, err := p.do(ctx, http.MethodGet, path, nil)
+		if err != nil {
+			return err
+		}
+		exists := content != nil
+		var values []string
+		if exists {
+			var rrSet gcoreRRSet
+			err = json.Unmarshal(content, &rrSet)
+			if err != nil {
+				return err
+			}
+			for _, record := range rrSet.ResourceRecords {
+				values = append(values, record.Content...)
+			}
+		}
+		values = apply(values, groups[name])
+		var method string
+		switch {
+		case len(values) == 0 && !exists:
+			continue
+		case len(values) == 0:
+			method = http.MethodDelete
+			content = nil
+		case exists:
+			method = http.MethodPut
+		default:
+			method = http.MethodPost
+		}
+		if len(values) > 0 {
+			rrSet := gcoreRRSet{TTL: int(recordTTL(groups[name][0]).Seconds())}
+			for _, value := range values {
+				rrSet.ResourceRecords = append(rrSet.ResourceRecords, gcoreResourceRecord{Content: []string{value}})
+			}
+			content, err = json.Marshal(rrSet)
+			if err != nil {
+				return err
+			}
+		}
+		_, err = p.do(ctx, method, path, content)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// do sends an API request, returning nil content for missing resources.
+func (p *Gcore) do(ctx context.Context, method string, path string, body []byte) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, method, "https://api.gcore.com"+path, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Authorization", "APIKey "+p.APIKey)
+	if body != nil {
+		request.Header.Set("Content-Type", "application/json")
+	}
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return nil, nil
+	}
+	err = checkResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(response.Body)
+	return buffer.Bytes(), err
+}
@@ -0,0 +1,82 @@
+package acmedns
+
+import (
+	"context"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/libdns/libdns"
+	mDNS "github.com/miekg/dns"
+)
+
+// RFC2136 sends dynamic updates, optionally signed with TSIG, to the primary server.
+type RFC2136 struct {
+	Server        string
+	TSIGKeyName   string
+	TSIGSecret    string
+	TSIGAlgorithm string
+}
+
+func (p *RFC2136) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, true)
+}
+
+func (p *RFC2136) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, false)
+}
+
+func (p *RFC2136) update(ctx context.Context, zone string, records []libdns.Record, insert bool) error {
+	serverAddr := M.ParseSocksaddr(p.Server)
+	if serverAddr.Port == 0 {
+		serverAddr.Port = 53
+	}
+	if !serverAddr.IsValid() {
+		return E.New("invalid rfc2136 server: ", p.Server)
+	}
+	rrList := make([]mDNS.RR, 0, len(records))
+	for _, record := range records {
+		rrList = append(rrList, &mDNS.TXT{
+			Hdr: mDNS.RR_Header{
+				Name:   libdns.AbsoluteName(record.Name, mDNS.Fqdn(zone)),
+				Rrtype: mDNS.TypeTXT,
+				Class:  mDNS.ClassINET,
+				Ttl:    uint32(recordTTL(record).Seconds()),
+			},
+			Txt: []string{record.Value},
+		})
+	}
+	message := new(mDNS.Msg)
+	message.SetUpdate(mDNS.Fqdn(zone))
+	if insert {
+		message.Insert(rrList)
+	} else {
+		message.Remove(rrList)
+	}
+	client := &mDNS.Client{Net: "tcp"}
+	if p.TSIGKeyName != "" {
+		var algorithm string
+		switch p.TSIGAlgorithm {
+		case "hmac-sha1":
+			algorithm = mDNS.HmacSHA1
+		case "", "hmac-sha256":
+			algorithm = mDNS.HmacSHA256
+		case "hmac-sha512":
+			algorithm = mDNS.HmacSHA512
+		default:
+			return E.New("unsupported tsig algorithm: ", p.TSIGAlgorithm)
+		}
+		keyName := mDNS.Fqdn(p.TSIGKeyName)
+		client.TsigSecret = map[string]string{keyName: p.TSIGSecret}
+		message.SetTsig(keyName, algorithm, 300, time.Now().Unix())
+	}
+	response, _, err := client.ExchangeContext(ctx, message, serverAddr.String())
+	if err != nil {
+		return E.Cause(err, "rfc2136 update")
+	}
+	if response.Rcode != mDNS.RcodeSuccess {
+		return E.New("rfc2136 update rejected: ", mDNS.RcodeToString[response.Rcode])
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package acmedns
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/libdns/libdns"
+)
+
+const (
+	route53Endpoint = "route53.amazonaws.com"
+	route53Region   = "us-east-1"
+)
+
+// Route53 changes TXT record sets through the Route 53 API, signing requests
+// with AWS Signature Version 4.
+type Route53 struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	HostedZoneID    string
+	access          sync.Mutex
+	hostedZones     map[string]string
+}
+
+type route53ResourceRecordSet struct {
+	Name            string                  `xml:"Name"`
+	Type            string                  `xml:"Type"`
+	TTL             int64                   `xml:"TTL"`
+	ResourceRecords []route53ResourceRecord `xml:"ResourceRecords>ResourceRecord"`
+}
+
+type route53ResourceRecord struct {
+	Value string `xml:"Value"`
+}
+
+type route53Change struct {
+	Action            string                   `xml:"Action"`
+	ResourceRecordSet route53ResourceRecordSet `xml:"ResourceRecordSet"`
+}
+
+type route53ChangeRequest struct {
+	XMLName xml.Name        `xml:"https://route53.amazonaws.com/doc/2013-04-01/ ChangeResourceRecordSetsRequest"`
+	Changes []route53Change `xml:"ChangeBatch>Changes>Change"`
+}
+
+type route53ListResponse struct {
+	ResourceRecordSets []route53ResourceRecordSet `xml:"ResourceRecordSets>ResourceRecordSet"`
+}
+
+type route53HostedZonesResponse struct {
+	HostedZones []struct {
+		ID   string `xml:"Id"`
+		Name string `xml:"Name"`
+	} `xml:"HostedZones>HostedZone"`
+}
+
+func (p *Route53) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, appendValues)
+}
+
+func (p *Route53) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	return records, p.update(ctx, zone, records, removeValues)
+}
+
+func (p *Route53) update(ctx context.Context, zone string, records []libdns.Record, apply func([]string, []libdns.Record) []string) error {
+	p.access.Lock()
+	defer p.access.Unlock()
+	hostedZoneID, err := p.lookupHostedZone(ctx, zone)
+	if err != nil {
+		return err
+	}
+	var changeRequest route53ChangeRequest
+	names, groups := groupByName(zone, records)
+	for _, name := range names {
+		current, err := p.getRecordSet(ctx, hostedZoneID, name)
+		if err != nil {
+			return err
+		}
+		var values []string
+		if current != nil {
+			for _, record := range current.ResourceRecords {
+				value, err := strconv.Unquote(record.Value)
+				if err != nil {
+					value = record.Value
+				}
+				values = append(values, value)
+			}
+		}
+		values = apply(values, groups[name])
+		if len(values) == 0 {
+			if current != nil {
+				changeRequest.Changes = append(changeRequest.Changes, route53Change{Action: "DELETE", ResourceRecordSet: *current})
+			}
+			continue
+		}
+		changeRequest.Changes = append(changeRequest.Changes, route53Change{
+			Action: "UPSERT",
+			ResourceRecordSet: route53ResourceRecordSet{
+				Name: name,
+				Type: "TXT",
+				TTL:  int64(recordTTL(groups[name][0]).Seconds()),
+				ResourceRecords: common.Map(values, func(it string) route53ResourceRecord {
+					return route53ResourceRecord{Value: strconv.Quote(it)}
+				}),
+			},
+		})
+	}
+	if len(changeRequest.Changes) == 0 {
+		return nil
+	}
+	content, err := xml.Marshal(changeRequest)
+	if err != nil {
+		return err
+	}
+	_, err = p.do(ctx, http.MethodPost, "/2013-04-01/hostedzone/"+hostedZoneID+"/rrset", nil, append([]byte(xml.Header), content...))
+	return err
+}
+
+func (p *Route53) lookupHostedZone(ctx context.Context, zone string) (string, error) {
+	if p.HostedZoneID != "" {
+		return strings.TrimPrefix(p.HostedZoneID, "/hostedzone/"), nil
+	}
+	if hostedZoneID, loaded := p.hostedZones[zone]; loaded {
+		return hostedZoneID, nil
+	}
+	content, err := p.do(ctx, http.MethodGet, "/2013-04-01/hostedzonesbyname", url.Values{
+		"dnsname":  {zone},
+		"maxitems": {"1"},
+	}, nil)
+	if err != nil {
+		return "", E.Cause(err, "lookup hosted zone for ", zone)
+	}
+	var response route53HostedZonesResponse
+	err = xml.Unmarshal(content, &response)
+	if err != nil {
+		return "", err
+	}
+	if len(response.HostedZones) == 0 || !strings.EqualFold(response.HostedZones[0].Name, zone) {
+		return "", E.New("hosted zone not found: ", zone)
+	}
+	hostedZoneID := strings.TrimPrefix(response.HostedZones[0].ID, "/hostedzone/")
+	if p.hostedZones == nil {
+		p.hostedZones = make(map[string]string)
+	}
+	p.hostedZones[zone] = hostedZoneID
+	return hostedZoneID, nil
+}
+
+func (p *Route53) getRecordSet(ctx context.Context, hostedZoneID string, name string) (*route53ResourceRecordSet, error) {
+	content, err := p.do(ctx, http.MethodGet, "/2013-04-01/hostedzone/"+hostedZoneID+"/rrset", url.Values{
+		"name":     {name},
+		"type":     {"TXT"},
+		"maxitems": {"1"},
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+	var response route53ListResponse
+	err = xml.Unmarshal(content, &response)
+	if err != nil {
+		return nil, err
+	}
+	if len(response.ResourceRecordSets) == 0 {
+		return nil, nil
+	}
+	recordSet := response.ResourceRecordSets[0]
+	if recordSet.Type != "TXT" || !strings.EqualFold(recordSet.Name, name) {
+		return nil, nil
+	}
+	return &recordSet, nil
+}
+
+func (p *Route53) do(ctx context.Context, method string, path string, query url.Values, body []byte) ([]byte, error) {
+	rawQuery := strings.ReplaceAll(query.Encode(), "+", "%20")
+	requestURL := "https://" + route53Endpoint + path
+	if rawQuery != "" {
+		requestURL += "?" + rawQuery
+	}
+	request, err := http.NewRequestWithContext(ctx, method, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	p.sign(request, path, rawQuery, body)
+	response, err := http.DefaultClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	err = checkResponse(response)
+	if err != nil {
+		return nil, err
+	}
+	var buffer bytes.Buffer
+	_, err = buffer.ReadFrom(response.Body)
+	return buffer.Bytes(), err
+}
+
+func (p *Route53) sign(request *http.Request, path string, rawQuery string, body []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256.Sum256(body)
+	request.Header.Set("X-Amz-Date", amzDate)
+	canonicalHeaders := "host:" + route53Endpoint + "\nx-amz-date:" + amzDate + "\n"
+	signedHeaders := "host;x-amz-date"
+	if p.SessionToken != "" {
+		request.Header.Set("X-Amz-Security-Token", p.SessionToken)
+		canonicalHeaders += "x-amz-security-token:" + p.SessionToken + "\n"
+		signedHeaders += ";x-amz-security-token"
+	}
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		path,
+		rawQuery,
+		canonicalHeaders,
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	canonicalRequestHash := sha256.Sum256([]byte(canonicalRequest))
+	scope := date + "/" + route53Region + "/route53/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalRequestHash[:])
+	key := []byte("AWS4" + p.SecretAccessKey)
+	for _, part := range []string{date, route53Region, "route53", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	request.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+p.AccessKeyID+"/"+scope+", SignedHeaders="+signedHeaders+", Signature="+hex.EncodeToString(hmacSHA256(key, stringToSign)))
+}
+
+func hmacSHA256(key []byte, content string) []byte {
+	hash := hmac.New(sha256.New, key)
+	hash.Write([]byte(content))
+	return hash.Sum(nil)
+}
@@ -0,0 +1,96 @@
+package cgroup
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+	"github.com/sagernet/sing-tun"
+	F "github.com/sagernet/sing/common/format"
+)
+
+const (
+	DefaultMark = 0x2025
+
+	bypassTable = "sing-box-cgroup"
+)
+
+// BypassOptions describes the rules that route traffic of cgroups around the
+// tun interface. Packets of excluded cgroups, or of cgroups not included,
+// are marked with Mark, routed by the main table and masqueraded, since the
+// source address may have been selected for the tun interface.
+type BypassOptions struct {
+	TunOptions *tun.Options
+	Include    []string
+	Exclude    []string
+	Mark       uint32
+}
+
+// Path returns the cgroup v2 path relative to the root and its level.
+func Path(path string) (string, int) {
+	for _, mountPoint := range []string{"/sys/fs/cgroup/unified/", "/sys/fs/cgroup/"} {
+		if strings.HasPrefix(path, mountPoint) {
+			path = path[len(mountPoint):]
+			break
+		}
+	}
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return "", 0
+	}
+	return path, strings.Count(path, "/") + 1
+}
+
+// nftablesScript returns the script for `nft -f -` creating the table.
+func (o *BypassOptions) nftablesScript() string {
+	var builder strings.Builder
+	mark := netfilter.FormatMark(o.Mark)
+	builder.WriteString("table inet " + bypassTable + " {\n")
+	builder.WriteString("\tchain output {\n")
+	builder.WriteString("\t\ttype route hook output priority mangle; policy accept;\n")
+	builder.WriteString("\t\tmeta mark != 0x0 return\n")
+	if len(o.Include) > 0 {
+		for _, path := range o.Include {
+			builder.WriteString("\t\t" + matchCgroup(path) + " return\n")
+		}
+		builder.WriteString("\t\tmeta mark set " + mark + "\n")
+	} else {
+		for _, path := range o.Exclude {
+			builder.WriteString("\t\t" + matchCgroup(path) + " meta mark set " + mark + "\n")
+		}
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tchain postrouting {\n")
+	builder.WriteString("\t\ttype nat hook postrouting priority srcnat; policy accept;\n")
+	builder.WriteString("\t\tmeta mark " + mark + " oifname != " + strconv.Quote(o.TunOptions.Name) + " masquerade\n")
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+func matchCgroup(path string) string {
+	path, level := Path(path)
+	return F.ToString("socket cgroupv2 level ", level, " ", strconv.Quote(path))
+}
+
+// routeCommands returns the commands routing marked packets by the main
+// table before the rules of the tun interface, and the commands removing
+// them.
+func (o *BypassOptions) routeCommands() (setup []netfilter.Command, cleanup []netfilter.Command) {
+	priority := F.ToString(o.TunOptions.IPRoute2RuleIndex - 1)
+	for _, family := range []struct {
+		enabled bool
+		flag    string
+	}{
+		{len(o.TunOptions.Inet4Address) > 0, "-4"},
+		{len(o.TunOptions.Inet6Address) > 0, "-6"},
+	} {
+		if !family.enabled {
+			continue
+		}
+		arguments := []string{"fwmark", netfilter.FormatMark(o.Mark), "lookup", "main", "priority", priority}
+		setup = append(setup, netfilter.Command{Name: "ip", Arguments: append([]string{family.flag, "rule", "add"}, arguments...)})
+		cleanup = append(cleanup, netfilter.Command{Name: "ip", Arguments: append([]string{family.flag, "rule", "del"}, arguments...)})
+	}
+	return
+}
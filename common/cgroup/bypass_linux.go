@@ -0,0 +1,84 @@
+package cgroup
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type Bypass struct {
+	options BypassOptions
+	started bool
+}
+
+func NewBypass(options BypassOptions) (*Bypass, error) {
+	for _, path := range append(options.Include, options.Exclude...) {
+		if relative, _ := Path(path); relative == "" {
+			return nil, E.New("invalid cgroup path: ", path)
+		}
+	}
+	for _, name := range []string{"nft", "ip"} {
+		_, err := exec.LookPath(name)
+		if err != nil {
+			return nil, E.Cause(err, "find ", name)
+		}
+	}
+	return &Bypass{options: options}, nil
+}
+
+func (b *Bypass) Start() error {
+	mountPoint := cgroup2MountPoint()
+	for _, path := range append(b.options.Include, b.options.Exclude...) {
+		path, _ = Path(path)
+		// nftables resolves paths when rules are added
+		_, err := os.Stat(filepath.Join(mountPoint, path))
+		if err != nil {
+			return E.Cause(err, "find cgroup")
+		}
+	}
+	err := netfilter.Install(b.setup, b.cleanup)
+	if err != nil {
+		return err
+	}
+	b.started = true
+	return nil
+}
+
+func (b *Bypass) Close() error {
+	if !b.started {
+		return nil
+	}
+	b.started = false
+	return b.cleanup()
+}
+
+func (b *Bypass) setup() error {
+	err := netfilter.Run(netfilter.Command{Name: "nft", Arguments: []string{"-f", "-"}}, b.options.nftablesScript())
+	if err != nil {
+		return err
+	}
+	setup, _ := b.options.routeCommands()
+	return netfilter.RunAll(setup)
+}
+
+func (b *Bypass) cleanup() error {
+	_, cleanup := b.options.routeCommands()
+	return netfilter.RunEach(append([]netfilter.Command{{Name: "nft", Arguments: []string{"delete", "table", "inet", bypassTable}}}, cleanup...))
+}
+
+func cgroup2MountPoint() string {
+	content, err := os.ReadFile("/proc/mounts")
+	if err == nil {
+		for _, line := range strings.Split(string(content), "\n") {
+			fields := strings.Fields(line)
+			if len(fields) > 2 && fields[2] == "cgroup2" {
+				return fields[1]
+			}
+		}
+	}
+	return "/sys/fs/cgroup"
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package cgroup
+
+import (
+	"os"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type Bypass struct{}
+
+func NewBypass(options BypassOptions) (*Bypass, error) {
+	return nil, E.New("only supported on Linux")
+}
+
+func (b *Bypass) Start() error {
+	return os.ErrInvalid
+}
+
+func (b *Bypass) Close() error {
+	return nil
+}
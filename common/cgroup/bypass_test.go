@@ -0,0 +1,64 @@
+package cgroup
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+	"github.com/sagernet/sing-tun"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPath(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		path     string
+		relative string
+		level    int
+	}{
+		{"system.slice/sshd.service", "system.slice/sshd.service", 2},
+		{"/system.slice/", "system.slice", 1},
+		{"/sys/fs/cgroup/user.slice/user-1000.slice/app.slice", "user.slice/user-1000.slice/app.slice", 3},
+		{"/sys/fs/cgroup/unified/system.slice", "system.slice", 1},
+		{"/", "", 0},
+	} {
+		relative, level := Path(testCase.path)
+		require.Equal(t, testCase.relative, relative, testCase.path)
+		require.Equal(t, testCase.level, level, testCase.path)
+	}
+}
+
+func TestBypassNFTablesScript(t *testing.T) {
+	t.Parallel()
+	tunOptions := &tun.Options{Name: "tun0"}
+	exclude := BypassOptions{TunOptions: tunOptions, Exclude: []string{"/system.slice/sshd.service"}, Mark: DefaultMark}
+	script := exclude.nftablesScript()
+	require.Contains(t, script, "\t\tsocket cgroupv2 level 2 \"system.slice/sshd.service\" meta mark set 0x2025\n")
+	require.Contains(t, script, "\t\tmeta mark 0x2025 oifname != \"tun0\" masquerade\n")
+	require.NotContains(t, script, " return\n\t\tmeta mark set")
+
+	include := BypassOptions{TunOptions: tunOptions, Include: []string{"user.slice", "machine.slice"}, Mark: DefaultMark}
+	script = include.nftablesScript()
+	require.Contains(t, script, "\t\tsocket cgroupv2 level 1 \"user.slice\" return\n")
+	require.Contains(t, script, "\t\tsocket cgroupv2 level 1 \"machine.slice\" return\n\t\tmeta mark set 0x2025\n")
+}
+
+func TestBypassRouteCommands(t *testing.T) {
+	t.Parallel()
+	bypass := BypassOptions{
+		TunOptions: &tun.Options{
+			Inet4Address:      []netip.Prefix{netip.MustParsePrefix("172.19.0.1/30")},
+			Inet6Address:      []netip.Prefix{netip.MustParsePrefix("fdfe:dcba:9876::1/126")},
+			IPRoute2RuleIndex: 9000,
+		},
+		Mark: DefaultMark,
+	}
+	setup, cleanup := bypass.routeCommands()
+	require.Equal(t, []netfilter.Command{
+		{Name: "ip", Arguments: []string{"-4", "rule", "add", "fwmark", "0x2025", "lookup", "main", "priority", "8999"}},
+		{Name: "ip", Arguments: []string{"-6", "rule", "add", "fwmark", "0x2025", "lookup", "main", "priority", "8999"}},
+	}, setup)
+	require.Equal(t, "del", cleanup[0].Arguments[2])
+	require.Len(t, cleanup, 2)
+}
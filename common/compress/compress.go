@@ -0,0 +1,92 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+const AcceptEncoding = "zstd, br, gzip"
+
+var zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+// SetAcceptEncoding advertises the supported encodings, which disables the
+// transparent gzip handling of net/http; decode the body with DecodeResponse.
+func SetAcceptEncoding(request *http.Request) {
+	request.Header.Set("Accept-Encoding", AcceptEncoding)
+}
+
+func DecodeResponse(response *http.Response) (io.ReadCloser, error) {
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get("Content-Encoding")))
+	switch encoding {
+	case "", "identity":
+		return response.Body, nil
+	case "gzip", "x-gzip":
+		reader, err := gzip.NewReader(response.Body)
+		if err != nil {
+			return nil, E.Cause(err, "decode gzip response")
+		}
+		return &decodedBody{reader, response.Body, reader}, nil
+	case "br":
+		return &decodedBody{brotli.NewReader(response.Body), response.Body, nil}, nil
+	case "zstd":
+		decoder, err := zstd.NewReader(response.Body, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, E.Cause(err, "decode zstd response")
+		}
+		return &decodedBody{decoder, response.Body, zstdDecoderCloser{decoder}}, nil
+	default:
+		return nil, E.New("unsupported content encoding: ", encoding)
+	}
+}
+
+type decodedBody struct {
+	io.Reader
+	body    io.Closer
+	decoder io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	return common.Close(b.decoder, b.body)
+}
+
+type zstdDecoderCloser struct {
+	decoder *zstd.Decoder
+}
+
+func (c zstdDecoderCloser) Close() error {
+	c.decoder.Close()
+	return nil
+}
+
+// Compress encodes content with zstd for storage.
+func Compress(content []byte) ([]byte, error) {
+	encoder, err := zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedBetterCompression))
+	if err != nil {
+		return nil, err
+	}
+	defer encoder.Close()
+	return encoder.EncodeAll(content, nil), nil
+}
+
+// Decompress reverses Compress, and returns content stored before
+// compression was introduced unchanged.
+func Decompress(content []byte) ([]byte, error) {
+	if !bytes.HasPrefix(content, zstdMagic) {
+		return content, nil
+	}
+	decoder, err := zstd.NewReader(nil, zstd.WithDecoderConcurrency(1))
+	if err != nil {
+		return nil, err
+	}
+	defer decoder.Close()
+	return decoder.DecodeAll(content, nil)
+}
@@ -0,0 +1,127 @@
+package compress
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeResponse(t *testing.T) {
+	t.Parallel()
+	content := bytes.Repeat([]byte("sing-box "), 1024)
+	for _, testCase := range []struct {
+		encoding string
+		encode   func(t *testing.T, content []byte) []byte
+	}{
+		{"", identity},
+		{"identity", identity},
+		{"gzip", encodeGzip},
+		{"x-gzip", encodeGzip},
+		{" GZIP ", encodeGzip},
+		{"br", encodeBrotli},
+		{"zstd", encodeZstd},
+	} {
+		testCase := testCase
+		t.Run(testCase.encoding, func(t *testing.T) {
+			t.Parallel()
+			response := &http.Response{
+				Header: http.Header{"Content-Encoding": []string{testCase.encoding}},
+				Body:   io.NopCloser(bytes.NewReader(testCase.encode(t, content))),
+			}
+			body, err := DecodeResponse(response)
+			require.NoError(t, err)
+			decoded, err := io.ReadAll(body)
+			require.NoError(t, err)
+			require.NoError(t, body.Close())
+			require.Equal(t, content, decoded)
+		})
+	}
+}
+
+func TestDecodeResponseError(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		encoding string
+		body     []byte
+	}{
+		{"deflate", nil},
+		{"gzip", []byte("not gzip")},
+	} {
+		response := &http.Response{
+			Header: http.Header{"Content-Encoding": []string{testCase.encoding}},
+			Body:   io.NopCloser(bytes.NewReader(testCase.body)),
+		}
+		_, err := DecodeResponse(response)
+		require.Error(t, err, testCase.encoding)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		content []byte
+	}{
+		{"text", bytes.Repeat([]byte("sing-box "), 1024)},
+		{"binary", []byte{0x00, 0xff, 0x28, 0xb5}},
+		{"magic", zstdMagic},
+	} {
+		compressed, err := Compress(testCase.content)
+		require.NoError(t, err, testCase.name)
+		require.True(t, bytes.HasPrefix(compressed, zstdMagic), testCase.name)
+		decompressed, err := Decompress(compressed)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, len(testCase.content), len(decompressed), testCase.name)
+		require.True(t, bytes.Equal(testCase.content, decompressed), testCase.name)
+	}
+}
+
+func TestDecompressLegacy(t *testing.T) {
+	t.Parallel()
+	for _, content := range [][]byte{
+		nil,
+		[]byte(`{"version": 1}`),
+		{0x28, 0xb5, 0x2f},
+	} {
+		decompressed, err := Decompress(content)
+		require.NoError(t, err)
+		require.Equal(t, content, decompressed)
+	}
+	_, err := Decompress(append(bytes.Clone(zstdMagic), 0x00))
+	require.Error(t, err)
+}
+
+func identity(t *testing.T, content []byte) []byte {
+	return content
+}
+
+func encodeGzip(t *testing.T, content []byte) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func encodeBrotli(t *testing.T, content []byte) []byte {
+	var buffer bytes.Buffer
+	writer := brotli.NewWriter(&buffer)
+	_, err := writer.Write(content)
+	require.NoError(t, err)
+	require.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
+
+func encodeZstd(t *testing.T, content []byte) []byte {
+	encoder, err := zstd.NewWriter(nil)
+	require.NoError(t, err)
+	defer encoder.Close()
+	return encoder.EncodeAll(content, nil)
+}
@@ -0,0 +1,100 @@
+package configformat
+
+import (
+	"bytes"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/common/jsonc"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Extensions are the file extensions of supported configuration formats.
+var Extensions = []string{".json", ".jsonc", ".yaml", ".yml", ".toml"}
+
+// ToJSON converts configuration content to JSON, selecting the source format
+// by the file extension.
+func ToJSON(path string, content []byte) ([]byte, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var node yaml.Node
+		err := yaml.Unmarshal(content, &node)
+		if err != nil {
+			return nil, E.Cause(err, "decode yaml")
+		}
+		if len(node.Content) == 0 {
+			return []byte("{}"), nil
+		}
+		buffer := new(bytes.Buffer)
+		err = writeYAMLNode(buffer, node.Content[0])
+		if err != nil {
+			return nil, err
+		}
+		return buffer.Bytes(), nil
+	case ".toml":
+		var value map[string]any
+		err := toml.Unmarshal(content, &value)
+		if err != nil {
+			return nil, E.Cause(err, "decode toml")
+		}
+		return json.Marshal(value)
+	default:
+		return jsonc.Standardize(content)
+	}
+}
+
+// writeYAMLNode writes a YAML node as JSON, keeping the key order of mappings.
+func writeYAMLNode(buffer *bytes.Buffer, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		return writeYAMLNode(buffer, node.Content[0])
+	case yaml.AliasNode:
+		return writeYAMLNode(buffer, node.Alias)
+	case yaml.MappingNode:
+		buffer.WriteByte('{')
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			key, err := json.Marshal(node.Content[i].Value)
+			if err != nil {
+				return err
+			}
+			buffer.Write(key)
+			buffer.WriteByte(':')
+			err = writeYAMLNode(buffer, node.Content[i+1])
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte('}')
+	case yaml.SequenceNode:
+		buffer.WriteByte('[')
+		for i, item := range node.Content {
+			if i > 0 {
+				buffer.WriteByte(',')
+			}
+			err := writeYAMLNode(buffer, item)
+			if err != nil {
+				return err
+			}
+		}
+		buffer.WriteByte(']')
+	default:
+		var value any
+		err := node.Decode(&value)
+		if err != nil {
+			return E.Cause(err, "decode yaml value at line ", node.Line)
+		}
+		content, err := json.Marshal(value)
+		if err != nil {
+			return E.Cause(err, "encode yaml value at line ", node.Line)
+		}
+		buffer.Write(content)
+	}
+	return nil
+}
@@ -1,43 +1,39 @@
 package conntrack
 
 import (
-	"io"
 	"net"
-
-	"github.com/sagernet/sing/common/x/list"
 )
 
 type Conn struct {
 	net.Conn
-	element *list.Element[io.Closer]
+	tracker
 }
 
 func NewConn(conn net.Conn) (net.Conn, error) {
-	connAccess.Lock()
-	element := openConnection.PushBack(conn)
-	connAccess.Unlock()
+	trackerConn := &Conn{Conn: conn}
+	track(&trackerConn.tracker, trackerConn)
 	if KillerEnabled {
 		err := KillerCheck()
 		if err != nil {
-			conn.Close()
+			trackerConn.Close()
 			return nil, err
 		}
 	}
-	return &Conn{
-		Conn:    conn,
-		element: element,
-	}, nil
+	return trackerConn, nil
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	c.touch()
+	return c.Conn.Read(p)
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	c.touch()
+	return c.Conn.Write(p)
 }
 
 func (c *Conn) Close() error {
-	if c.element.Value != nil {
-		connAccess.Lock()
-		if c.element.Value != nil {
-			openConnection.Remove(c.element)
-			c.element.Value = nil
-		}
-		connAccess.Unlock()
-	}
+	c.untrack()
 	return c.Conn.Close()
 }
 
@@ -46,9 +42,9 @@ func (c *Conn) Upstream() any {
 }
 
 func (c *Conn) ReaderReplaceable() bool {
-	return true
+	return replaceable()
 }
 
 func (c *Conn) WriterReplaceable() bool {
-	return true
+	return replaceable()
 }
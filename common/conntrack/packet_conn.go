@@ -1,44 +1,41 @@
 package conntrack
 
 import (
-	"io"
 	"net"
 
 	"github.com/sagernet/sing/common/bufio"
-	"github.com/sagernet/sing/common/x/list"
 )
 
 type PacketConn struct {
 	net.PacketConn
-	element *list.Element[io.Closer]
+	tracker
 }
 
 func NewPacketConn(conn net.PacketConn) (net.PacketConn, error) {
-	connAccess.Lock()
-	element := openConnection.PushBack(conn)
-	connAccess.Unlock()
+	trackerConn := &PacketConn{PacketConn: conn}
+	track(&trackerConn.tracker, trackerConn)
 	if KillerEnabled {
 		err := KillerCheck()
 		if err != nil {
-			conn.Close()
+			trackerConn.Close()
 			return nil, err
 		}
 	}
-	return &PacketConn{
-		PacketConn: conn,
-		element:    element,
-	}, nil
+	return trackerConn, nil
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	c.touch()
+	return c.PacketConn.ReadFrom(p)
+}
+
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	c.touch()
+	return c.PacketConn.WriteTo(p, addr)
 }
 
 func (c *PacketConn) Close() error {
-	if c.element.Value != nil {
-		connAccess.Lock()
-		if c.element.Value != nil {
-			openConnection.Remove(c.element)
-			c.element.Value = nil
-		}
-		connAccess.Unlock()
-	}
+	c.untrack()
 	return c.PacketConn.Close()
 }
 
@@ -47,9 +44,9 @@ func (c *PacketConn) Upstream() any {
 }
 
 func (c *PacketConn) ReaderReplaceable() bool {
-	return true
+	return replaceable()
 }
 
 func (c *PacketConn) WriterReplaceable() bool {
-	return true
+	return replaceable()
 }
@@ -3,16 +3,78 @@ package conntrack
 import (
 	"io"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/x/list"
 )
 
 var (
+	MaxConnections int
 	connAccess     sync.RWMutex
 	openConnection list.List[io.Closer]
 )
 
+// tracker records when a connection was last used, so that the least recently
+// used connection is closed when MaxConnections is exceeded.
+type tracker struct {
+	element    *list.Element[io.Closer]
+	lastActive atomic.Int64
+}
+
+type trackedConn interface {
+	io.Closer
+	activeAt() int64
+}
+
+func (t *tracker) touch() {
+	t.lastActive.Store(time.Now().UnixNano())
+}
+
+func (t *tracker) activeAt() int64 {
+	return t.lastActive.Load()
+}
+
+func (t *tracker) untrack() {
+	if t.element.Value != nil {
+		connAccess.Lock()
+		if t.element.Value != nil {
+			openConnection.Remove(t.element)
+			t.element.Value = nil
+		}
+		connAccess.Unlock()
+	}
+}
+
+// replaceable reports whether the wrapper may be bypassed by bufio.Copy. With
+// a connection limit, reads and writes must pass through to record activity.
+func replaceable() bool {
+	return MaxConnections == 0
+}
+
+func track(t *tracker, conn trackedConn) {
+	t.touch()
+	var evicted []io.Closer
+	connAccess.Lock()
+	t.element = openConnection.PushBack(conn)
+	for MaxConnections > 0 && openConnection.Len() > MaxConnections {
+		leastRecent := openConnection.Front()
+		for element := leastRecent.Next(); element != nil; element = element.Next() {
+			if element.Value.(trackedConn).activeAt() < leastRecent.Value.(trackedConn).activeAt() {
+				leastRecent = element
+			}
+		}
+		evicted = append(evicted, leastRecent.Value)
+		openConnection.Remove(leastRecent)
+		leastRecent.Value = nil
+	}
+	connAccess.Unlock()
+	for _, oldConn := range evicted {
+		common.Close(oldConn)
+	}
+}
+
 func Count() int {
 	if !Enabled {
 		return 0
@@ -37,11 +99,15 @@ func Close() {
 	if !Enabled {
 		return
 	}
+	var connList []io.Closer
 	connAccess.Lock()
-	defer connAccess.Unlock()
 	for element := openConnection.Front(); element != nil; element = element.Next() {
-		common.Close(element.Value)
+		connList = append(connList, element.Value)
 		element.Value = nil
 	}
 	openConnection.Init()
+	connAccess.Unlock()
+	for _, conn := range connList {
+		common.Close(conn)
+	}
 }
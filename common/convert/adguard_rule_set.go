@@ -0,0 +1,196 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"net/netip"
+	"regexp"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+)
+
+// ParseAdGuardRuleSet converts an AdGuard DNS filter list into headless
+// rules. Basic rules and hosts lines block domains, `@@` rules are exceptions
+// within the same list, and rules with unsupported modifiers or cosmetic
+// rules are skipped.
+func ParseAdGuardRuleSet(content []byte) ([]option.HeadlessRule, Warnings, error) {
+	var (
+		warnings Warnings
+		blocked  option.DefaultHeadlessRule
+		allowed  option.DefaultHeadlessRule
+		skipped  int
+	)
+	for _, line := range filterListLines(content, "!", "#", "[") {
+		if strings.Contains(line, "##") || strings.Contains(line, "#@#") || strings.Contains(line, "#$#") || strings.Contains(line, "#?#") {
+			skipped++
+			continue
+		}
+		if domains, isHosts := parseHostsLine(line); isHosts {
+			blocked.Domain = append(blocked.Domain, domains...)
+			continue
+		}
+		rule := &blocked
+		if strings.HasPrefix(line, "@@") {
+			rule = &allowed
+			line = line[2:]
+		}
+		if !adGuardPattern(rule, line) {
+			skipped++
+		}
+	}
+	if skipped > 0 {
+		warnings.Add("skipped ", skipped, " unsupported rules")
+	}
+	rules := headlessRules(blocked)
+	if len(rules) == 0 || !allowed.IsValid() {
+		return rules, warnings, nil
+	}
+	allowed.Invert = true
+	return []option.HeadlessRule{{
+		Type: C.RuleTypeLogical,
+		LogicalOptions: option.LogicalHeadlessRule{
+			Mode:  C.LogicalTypeAnd,
+			Rules: append(rules, headlessRules(allowed)...),
+		},
+	}}, warnings, nil
+}
+
+// ParseHostsRuleSet converts a hosts file into headless rules matching the
+// listed domains. Addresses are ignored.
+func ParseHostsRuleSet(content []byte) ([]option.HeadlessRule, Warnings, error) {
+	var (
+		warnings Warnings
+		rule     option.DefaultHeadlessRule
+	)
+	for _, line := range filterListLines(content, "#") {
+		domains, isHosts := parseHostsLine(line)
+		if !isHosts {
+			warnings.Add("invalid hosts line: ", line)
+			continue
+		}
+		rule.Domain = append(rule.Domain, domains...)
+	}
+	return headlessRules(rule), warnings, nil
+}
+
+func filterListLines(content []byte, commentPrefixes ...string) []string {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+scan:
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		for _, prefix := range commentPrefixes {
+			if strings.HasPrefix(line, prefix) {
+				continue scan
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+var hostsIgnoredDomains = map[string]bool{
+	"localhost":             true,
+	"localhost.localdomain": true,
+	"local":                 true,
+	"broadcasthost":         true,
+	"ip6-localhost":         true,
+	"ip6-loopback":          true,
+	"ip6-localnet":          true,
+	"ip6-mcastprefix":       true,
+	"ip6-allnodes":          true,
+	"ip6-allrouters":        true,
+	"ip6-allhosts":          true,
+	"0.0.0.0":               true,
+}
+
+// parseHostsLine returns the domains of a hosts line, or false if line does
+// not start with an address.
+func parseHostsLine(line string) ([]string, bool) {
+	if index := strings.IndexByte(line, '#'); index != -1 {
+		line = line[:index]
+	}
+	fields := strings.Fields(line)
+	if len(fields) < 2 {
+		return nil, false
+	}
+	if _, err := netip.ParseAddr(fields[0]); err != nil {
+		return nil, false
+	}
+	var domains []string
+	for _, domain := range fields[1:] {
+		domain = strings.ToLower(domain)
+		if !hostsIgnoredDomains[domain] {
+			domains = append(domains, domain)
+		}
+	}
+	return domains, true
+}
+
+// adGuardPattern adds an adblock-style pattern to rule. `||domain^` matches
+// the domain and its subdomains, `|domain^` matches the domain only, plain
+// domains match as suffixes and other patterns are converted to regular
+// expressions.
+func adGuardPattern(rule *option.DefaultHeadlessRule, pattern string) bool {
+	if len(pattern) > 1 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		expression := pattern[1 : len(pattern)-1]
+		if _, err := regexp.Compile(expression); err != nil {
+			return false
+		}
+		rule.DomainRegex = append(rule.DomainRegex, expression)
+		return true
+	}
+	if index := strings.LastIndexByte(pattern, '$'); index != -1 {
+		for _, modifier := range strings.Split(pattern[index+1:], ",") {
+			if modifier != "important" {
+				return false
+			}
+		}
+		pattern = pattern[:index]
+	}
+	pattern = strings.ToLower(pattern)
+	var (
+		subdomains bool
+		exact      bool
+		end        bool
+	)
+	if strings.HasSuffix(pattern, "^") || strings.HasSuffix(pattern, "|") {
+		end = true
+		pattern = strings.TrimSuffix(strings.TrimSuffix(pattern, "|"), "^")
+	}
+	if strings.HasPrefix(pattern, "||") {
+		subdomains = true
+		pattern = pattern[2:]
+	} else if strings.HasPrefix(pattern, "|") {
+		exact = true
+		pattern = pattern[1:]
+	}
+	if pattern == "" || strings.ContainsAny(pattern, "/:|") {
+		return false
+	}
+	if !strings.ContainsAny(pattern, "*^") {
+		if exact {
+			rule.Domain = append(rule.Domain, pattern)
+		} else {
+			rule.DomainSuffix = append(rule.DomainSuffix, pattern)
+		}
+		return true
+	}
+	expression := strings.ReplaceAll(strings.ReplaceAll(regexp.QuoteMeta(pattern), `\*`, ".*"), `\^`, `(\.|$)`)
+	if subdomains {
+		expression = `^(.*\.)?` + expression
+	} else if exact {
+		expression = "^" + expression
+	}
+	if end {
+		expression += "$"
+	}
+	rule.DomainRegex = append(rule.DomainRegex, expression)
+	return true
+}
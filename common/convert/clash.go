@@ -0,0 +1,393 @@
+package convert
+
+import (
+	"net/netip"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/common/proxyparser/clash"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+	"gopkg.in/yaml.v3"
+)
+
+type clashConfig struct {
+	Port          uint16                        `yaml:"port"`
+	SocksPort     uint16                        `yaml:"socks-port"`
+	MixedPort     uint16                        `yaml:"mixed-port"`
+	RedirPort     uint16                        `yaml:"redir-port"`
+	TProxyPort    uint16                        `yaml:"tproxy-port"`
+	AllowLan      bool                          `yaml:"allow-lan"`
+	DNS           *clashDNS                     `yaml:"dns"`
+	Proxies       []yaml.Node                   `yaml:"proxies"`
+	ProxyGroups   []clashProxyGroup             `yaml:"proxy-groups"`
+	ProxyProvider map[string]clashProxyProvider `yaml:"proxy-providers"`
+	RuleProviders map[string]clashRuleProvider  `yaml:"rule-providers"`
+	Rules         []string                      `yaml:"rules"`
+}
+
+type clashDNS struct {
+	Enable       bool     `yaml:"enable"`
+	EnhancedMode string   `yaml:"enhanced-mode"`
+	FakeIPRange  string   `yaml:"fake-ip-range"`
+	Nameserver   []string `yaml:"nameserver"`
+}
+
+type clashProxyGroup struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`
+	Proxies   []string `yaml:"proxies"`
+	Use       []string `yaml:"use"`
+	URL       string   `yaml:"url"`
+	Interval  int      `yaml:"interval"`
+	Tolerance uint16   `yaml:"tolerance"`
+	Filter    string   `yaml:"filter"`
+	Strategy  string   `yaml:"strategy"`
+}
+
+type clashProxyProvider struct {
+	Type     string `yaml:"type"`
+	URL      string `yaml:"url"`
+	Path     string `yaml:"path"`
+	Interval int    `yaml:"interval"`
+}
+
+type clashRuleProvider struct {
+	Type     string `yaml:"type"`
+	Behavior string `yaml:"behavior"`
+	Format   string `yaml:"format"`
+	URL      string `yaml:"url"`
+	Path     string `yaml:"path"`
+	Interval int    `yaml:"interval"`
+}
+
+// FromClash converts a Clash configuration. Entries without a sing-box
+// equivalent are skipped and reported as warnings.
+func FromClash(content []byte) (*option.Options, Warnings, error) {
+	var config clashConfig
+	err := yaml.Unmarshal(content, &config)
+	if err != nil {
+		return nil, nil, E.Cause(err, "decode clash config")
+	}
+	var (
+		options  option.Options
+		warnings Warnings
+	)
+	options.Inbounds = clashInbounds(&config)
+	options.DNS = clashDNSOptions(config.DNS, &warnings)
+	outbounds := clashProxies(config.Proxies, &warnings)
+	providerTags := make([]string, 0, len(config.ProxyProvider))
+	for _, name := range sortedKeys(config.ProxyProvider) {
+		provider := config.ProxyProvider[name]
+		if provider.Type != "http" || provider.URL == "" {
+			warnings.Add("proxy-provider ", name, ": unsupported type: ", provider.Type)
+			continue
+		}
+		outbounds = append(outbounds, option.Outbound{
+			Type: C.TypeProvider,
+			Tag:  name,
+			ProviderOptions: option.ProviderOutboundOptions{
+				URL:            provider.URL,
+				UpdateInterval: secondsDuration(provider.Interval),
+			},
+		})
+		providerTags = append(providerTags, name)
+	}
+	for _, group := range config.ProxyGroups {
+		outbound, loaded := clashGroup(group, providerTags, &warnings)
+		if loaded {
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	options.Outbounds = append(outbounds, defaultOutbounds()...)
+	route := &option.RouteOptions{}
+	converter := ruleConverter{ruleSetTags: make(map[string]string)}
+	for _, name := range sortedKeys(config.RuleProviders) {
+		ruleSet, err := clashRuleSet(name, config.RuleProviders[name])
+		if err != nil {
+			warnings.Add("rule-provider ", name, ": ", err)
+			continue
+		}
+		route.RuleSet = append(route.RuleSet, ruleSet)
+		converter.ruleSetTags[name] = name
+	}
+	for _, line := range config.Rules {
+		parts := splitRule(line)
+		switch len(parts) {
+		case 2:
+			err = converter.add(parts[0], parts[1], "")
+		case 3:
+			err = converter.add(parts[0], parts[1], parts[2])
+		default:
+			err = E.New("invalid rule")
+		}
+		if err != nil {
+			warnings.Add("rule ", line, ": ", err)
+		}
+	}
+	route.Rules = append(route.Rules, converter.rules...)
+	route.Final = converter.final
+	options.Route = route
+	return &options, warnings, nil
+}
+
+func clashInbounds(config *clashConfig) []option.Inbound {
+	listen := netip.IPv4Unspecified()
+	if !config.AllowLan {
+		listen = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+	}
+	listenOptions := func(port uint16) option.ListenOptions {
+		return option.ListenOptions{
+			Listen:     option.NewListenAddress(listen),
+			ListenPort: port,
+		}
+	}
+	var inbounds []option.Inbound
+	if config.MixedPort != 0 {
+		inbounds = append(inbounds, option.Inbound{
+			Type:         C.TypeMixed,
+			Tag:          "mixed-in",
+			MixedOptions: option.HTTPMixedInboundOptions{ListenOptions: listenOptions(config.MixedPort)},
+		})
+	}
+	if config.Port != 0 {
+		inbounds = append(inbounds, option.Inbound{
+			Type:        C.TypeHTTP,
+			Tag:         "http-in",
+			HTTPOptions: option.HTTPMixedInboundOptions{ListenOptions: listenOptions(config.Port)},
+		})
+	}
+	if config.SocksPort != 0 {
+		inbounds = append(inbounds, option.Inbound{
+			Type:         C.TypeSOCKS,
+			Tag:          "socks-in",
+			SocksOptions: option.SocksInboundOptions{ListenOptions: listenOptions(config.SocksPort)},
+		})
+	}
+	if config.RedirPort != 0 {
+		inbounds = append(inbounds, option.Inbound{
+			Type:            C.TypeRedirect,
+			Tag:             "redir-in",
+			RedirectOptions: option.RedirectInboundOptions{ListenOptions: listenOptions(config.RedirPort)},
+		})
+	}
+	if config.TProxyPort != 0 {
+		inbounds = append(inbounds, option.Inbound{
+			Type:          C.TypeTProxy,
+			Tag:           "tproxy-in",
+			TProxyOptions: option.TProxyInboundOptions{ListenOptions: listenOptions(config.TProxyPort)},
+		})
+	}
+	return inbounds
+}
+
+func clashDNSOptions(config *clashDNS, warnings *Warnings) *option.DNSOptions {
+	if config == nil || !config.Enable || len(config.Nameserver) == 0 {
+		return nil
+	}
+	options := &option.DNSOptions{}
+	for i, server := range config.Nameserver {
+		options.Servers = append(options.Servers, option.DNSServerOptions{
+			Tag:     "dns-" + strconv.Itoa(i),
+			Address: server,
+		})
+	}
+	if config.EnhancedMode == "fake-ip" {
+		fakeIPRange, err := netip.ParsePrefix(config.FakeIPRange)
+		if config.FakeIPRange == "" {
+			fakeIPRange, err = netip.MustParsePrefix("198.18.0.0/15"), nil
+		}
+		if err != nil {
+			warnings.Add("dns: parse fake-ip-range: ", err)
+		} else {
+			options.FakeIP = &option.DNSFakeIPOptions{
+				Enabled:    true,
+				Inet4Range: &fakeIPRange,
+			}
+			options.Servers = append(options.Servers, option.DNSServerOptions{
+				Tag:     "fakeip",
+				Address: "fakeip",
+			})
+			options.Rules = append(options.Rules, option.DNSRule{
+				Type: C.RuleTypeDefault,
+				DefaultOptions: option.DefaultDNSRule{
+					QueryType: option.Listable[option.DNSQueryType]{option.DNSQueryType(mDNS.TypeA), option.DNSQueryType(mDNS.TypeAAAA)},
+					Server:    "fakeip",
+				},
+			})
+		}
+	}
+	options.Final = options.Servers[0].Tag
+	return options
+}
+
+func clashProxies(nodes []yaml.Node, warnings *Warnings) []option.Outbound {
+	var outbounds []option.Outbound
+	for i := range nodes {
+		var proxy clash.ClashProxy
+		err := nodes[i].Decode(&proxy)
+		if err != nil {
+			warnings.Add("proxy[", i, "]: ", err)
+			continue
+		}
+		outbound, err := proxy.Proxy.GenerateOptions()
+		if err != nil {
+			warnings.Add("proxy ", proxy.Proxy.Tag(), ": ", err)
+			continue
+		}
+		outbounds = append(outbounds, *outbound)
+	}
+	return outbounds
+}
+
+func clashGroup(group clashProxyGroup, providerTags []string, warnings *Warnings) (option.Outbound, bool) {
+	members := make([]string, 0, len(group.Proxies))
+	for _, proxy := range group.Proxies {
+		members = append(members, policyTag(proxy))
+	}
+	var providers option.Listable[option.ProviderGroupOutboundOptions]
+	for _, use := range group.Use {
+		if !common.Contains(providerTags, use) {
+			warnings.Add("proxy-group ", group.Name, ": unknown provider: ", use)
+			continue
+		}
+		filter := group.Filter
+		if filter == "" {
+			filter = ".*"
+		}
+		providers = append(providers, option.ProviderGroupOutboundOptions{
+			Tag: use,
+			OutboundFilterOptions: option.OutboundFilterOptions{
+				Rules: option.Listable[string]{filter},
+			},
+		})
+	}
+	if len(members) == 0 && len(providers) == 0 {
+		warnings.Add("proxy-group ", group.Name, ": no proxies")
+		return option.Outbound{}, false
+	}
+	switch group.Type {
+	case "select":
+		return option.Outbound{
+			Type: C.TypeSelector,
+			Tag:  group.Name,
+			SelectorOptions: option.SelectorOutboundOptions{
+				Outbounds: members,
+				Providers: providers,
+			},
+		}, true
+	case "load-balance":
+		var strategy string
+		switch group.Strategy {
+		case "", "consistent-hashing":
+			strategy = C.LoadBalanceStrategyConsistentHashing
+		case "round-robin":
+			strategy = C.LoadBalanceStrategyRoundRobin
+		default:
+			warnings.Add("proxy-group ", group.Name, ": strategy ", group.Strategy, " converted to consistent_hashing")
+			strategy = C.LoadBalanceStrategyConsistentHashing
+		}
+		return option.Outbound{
+			Type: C.TypeLoadBalance,
+			Tag:  group.Name,
+			LoadBalanceOptions: option.LoadBalanceOutboundOptions{
+				Outbounds: members,
+				Strategy:  strategy,
+				Providers: providers,
+			},
+		}, true
+	case "fallback":
+		return option.Outbound{
+			Type: C.TypeFallback,
+			Tag:  group.Name,
+			FallbackOptions: option.FallbackOutboundOptions{
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  secondsDuration(group.Interval),
+				Providers: providers,
+			},
+		}, true
+	case "relay":
+		if len(providers) > 0 {
+			warnings.Add("proxy-group ", group.Name, ": providers dropped")
+		}
+		return option.Outbound{
+			Type: C.TypeRelay,
+			Tag:  group.Name,
+			RelayOptions: option.RelayOutboundOptions{
+				Outbounds: members,
+			},
+		}, true
+	case "url-test":
+		return option.Outbound{
+			Type: C.TypeURLTest,
+			Tag:  group.Name,
+			URLTestOptions: option.URLTestOutboundOptions{
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  secondsDuration(group.Interval),
+				Tolerance: group.Tolerance,
+				Providers: providers,
+			},
+		}, true
+	default:
+		warnings.Add("proxy-group ", group.Name, ": unsupported type: ", group.Type)
+		return option.Outbound{}, false
+	}
+}
+
+func clashRuleSet(name string, provider clashRuleProvider) (option.RuleSet, error) {
+	var location string
+	switch provider.Type {
+	case "http":
+		location = provider.URL
+	case "file":
+		location = provider.Path
+	default:
+		return option.RuleSet{}, E.New("unsupported type: ", provider.Type)
+	}
+	if location == "" {
+		return option.RuleSet{}, E.New("missing ", provider.Type, " location")
+	}
+	ruleSet := option.RuleSet{
+		Tag: name,
+	}
+	switch {
+	case provider.Format == "mrs":
+		return option.RuleSet{}, E.New("unsupported format: mrs")
+	case strings.HasSuffix(path.Base(location), ".srs"):
+		ruleSet.Format = C.RuleSetFormatBinary
+	case strings.HasSuffix(path.Base(location), ".json"):
+		ruleSet.Format = C.RuleSetFormatSource
+	default:
+		switch provider.Behavior {
+		case C.ClashRuleSetBehaviorDomain, C.ClashRuleSetBehaviorIPCIDR, C.ClashRuleSetBehaviorClassical:
+		default:
+			return option.RuleSet{}, E.New("unsupported behavior: ", provider.Behavior)
+		}
+		ruleSet.Format = C.RuleSetFormatClash
+		ruleSet.Behavior = provider.Behavior
+	}
+	if provider.Type == "file" {
+		ruleSet.Type = C.RuleSetTypeLocal
+		ruleSet.LocalOptions.Path = provider.Path
+	} else {
+		ruleSet.Type = C.RuleSetTypeRemote
+		ruleSet.RemoteOptions = option.RemoteRuleSet{
+			URL:            provider.URL,
+			UpdateInterval: secondsDuration(provider.Interval),
+			Path:           provider.Path,
+		}
+	}
+	return ruleSet, nil
+}
+
+func secondsDuration(seconds int) option.Duration {
+	return option.Duration(time.Duration(seconds) * time.Second)
+}
@@ -0,0 +1,405 @@
+package convert
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"gopkg.in/yaml.v3"
+)
+
+type clashExport struct {
+	MixedPort   uint16           `yaml:"mixed-port,omitempty"`
+	SocksPort   uint16           `yaml:"socks-port,omitempty"`
+	Port        uint16           `yaml:"port,omitempty"`
+	AllowLan    bool             `yaml:"allow-lan,omitempty"`
+	Proxies     []map[string]any `yaml:"proxies"`
+	ProxyGroups []map[string]any `yaml:"proxy-groups,omitempty"`
+	Rules       []string         `yaml:"rules,omitempty"`
+}
+
+// ToClash exports a sing-box configuration as a Clash configuration on a
+// best-effort basis: outbounds and rules without a Clash equivalent are
+// dropped and reported as warnings.
+func ToClash(options *option.Options) ([]byte, Warnings, error) {
+	var (
+		config   clashExport
+		warnings Warnings
+	)
+	for _, inbound := range options.Inbounds {
+		var listenOptions option.ListenOptions
+		switch inbound.Type {
+		case C.TypeMixed:
+			listenOptions = inbound.MixedOptions.ListenOptions
+			config.MixedPort = listenOptions.ListenPort
+		case C.TypeSOCKS:
+			listenOptions = inbound.SocksOptions.ListenOptions
+			config.SocksPort = listenOptions.ListenPort
+		case C.TypeHTTP:
+			listenOptions = inbound.HTTPOptions.ListenOptions
+			config.Port = listenOptions.ListenPort
+		default:
+			warnings.Add("inbound ", inbound.Tag, ": unsupported type: ", inbound.Type)
+			continue
+		}
+		if listenOptions.Listen != nil && listenOptions.Listen.Build().IsUnspecified() {
+			config.AllowLan = true
+		}
+	}
+	exported := map[string]string{
+		TagDirect: "DIRECT",
+		TagBlock:  "REJECT",
+	}
+	for _, outbound := range options.Outbounds {
+		switch outbound.Type {
+		case C.TypeDirect:
+			exported[outbound.Tag] = "DIRECT"
+		case C.TypeBlock:
+			exported[outbound.Tag] = "REJECT"
+		case C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback, C.TypeRelay, C.TypeDNS:
+		default:
+			proxy, err := clashExportProxy(outbound)
+			if err != nil {
+				warnings.Add("outbound ", outbound.Tag, ": ", err)
+				continue
+			}
+			config.Proxies = append(config.Proxies, proxy)
+			exported[outbound.Tag] = outbound.Tag
+		}
+	}
+	for _, outbound := range options.Outbounds {
+		var (
+			group     map[string]any
+			members   []string
+			providers int
+		)
+		switch outbound.Type {
+		case C.TypeSelector:
+			group = map[string]any{"type": "select"}
+			members = outbound.SelectorOptions.Outbounds
+			providers = len(outbound.SelectorOptions.Providers)
+		case C.TypeURLTest:
+			urlTestOptions := outbound.URLTestOptions
+			group = map[string]any{
+				"type":     "url-test",
+				"url":      urlTestOptions.URL,
+				"interval": int(time.Duration(urlTestOptions.Interval).Seconds()),
+			}
+			if group["url"] == "" {
+				group["url"] = "https://www.gstatic.com/generate_204"
+			}
+			if group["interval"] == 0 {
+				group["interval"] = 180
+			}
+			if urlTestOptions.Tolerance > 0 {
+				group["tolerance"] = urlTestOptions.Tolerance
+			}
+			members = urlTestOptions.Outbounds
+			providers = len(urlTestOptions.Providers)
+		case C.TypeFallback:
+			fallbackOptions := outbound.FallbackOptions
+			group = map[string]any{
+				"type":     "fallback",
+				"url":      fallbackOptions.URL,
+				"interval": int(time.Duration(fallbackOptions.Interval).Seconds()),
+			}
+			if group["url"] == "" {
+				group["url"] = "https://www.gstatic.com/generate_204"
+			}
+			if group["interval"] == 0 {
+				group["interval"] = 180
+			}
+			if fallbackOptions.Tolerance > 0 {
+				warnings.Add("outbound ", outbound.Tag, ": tolerance dropped")
+			}
+			members = fallbackOptions.Outbounds
+			providers = len(fallbackOptions.Providers)
+		case C.TypeRelay:
+			group = map[string]any{"type": "relay"}
+			members = outbound.RelayOptions.Outbounds
+		case C.TypeLoadBalance:
+			loadBalanceOptions := outbound.LoadBalanceOptions
+			strategy := "round-robin"
+			switch loadBalanceOptions.Strategy {
+			case C.LoadBalanceStrategyConsistentHashing:
+				strategy = "consistent-hashing"
+			case C.LoadBalanceStrategyLeastConnections:
+				warnings.Add("outbound ", outbound.Tag, ": strategy ", loadBalanceOptions.Strategy, " converted to round-robin")
+			}
+			group = map[string]any{
+				"type":     "load-balance",
+				"strategy": strategy,
+				"url":      "https://www.gstatic.com/generate_204",
+				"interval": 180,
+			}
+			members = loadBalanceOptions.Outbounds
+			providers = len(loadBalanceOptions.Providers)
+		default:
+			continue
+		}
+		if providers > 0 {
+			warnings.Add("outbound ", outbound.Tag, ": providers dropped")
+		}
+		group["name"] = outbound.Tag
+		proxies := append([]string(nil), members...)
+		if len(proxies) == 0 {
+			warnings.Add("outbound ", outbound.Tag, ": no proxies")
+			continue
+		}
+		group["proxies"] = proxies
+		config.ProxyGroups = append(config.ProxyGroups, group)
+		exported[outbound.Tag] = outbound.Tag
+	}
+	for _, group := range config.ProxyGroups {
+		proxies := group["proxies"].([]string)
+		for i, member := range proxies {
+			if policy, loaded := exported[member]; loaded {
+				proxies[i] = policy
+			} else {
+				warnings.Add("outbound ", group["name"], ": unknown member: ", member)
+			}
+		}
+	}
+	if options.Route != nil {
+		for i, rule := range options.Route.Rules {
+			lines, err := clashExportRule(rule, exported)
+			if err != nil {
+				warnings.Add("rule[", i, "]: ", err)
+				continue
+			}
+			config.Rules = append(config.Rules, lines...)
+		}
+		if options.Route.Final != "" {
+			if policy, loaded := exported[options.Route.Final]; loaded {
+				config.Rules = append(config.Rules, "MATCH,"+policy)
+			} else {
+				warnings.Add("route: unknown final outbound: ", options.Route.Final)
+			}
+		}
+	}
+	buffer := new(bytes.Buffer)
+	encoder := yaml.NewEncoder(buffer)
+	encoder.SetIndent(2)
+	err := encoder.Encode(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return buffer.Bytes(), warnings, nil
+}
+
+func clashExportProxy(outbound option.Outbound) (map[string]any, error) {
+	proxy := map[string]any{"name": outbound.Tag}
+	var (
+		serverOptions option.ServerOptions
+		tlsOptions    *option.OutboundTLSOptions
+		transport     *option.V2RayTransportOptions
+	)
+	switch outbound.Type {
+	case C.TypeShadowsocks:
+		ssOptions := outbound.ShadowsocksOptions
+		if ssOptions.Plugin != "" {
+			return nil, E.New("plugins are not supported")
+		}
+		serverOptions = ssOptions.ServerOptions
+		proxy["type"] = "ss"
+		proxy["cipher"] = ssOptions.Method
+		proxy["password"] = ssOptions.Password
+		proxy["udp"] = true
+	case C.TypeVMess:
+		vmessOptions := outbound.VMessOptions
+		serverOptions = vmessOptions.ServerOptions
+		tlsOptions = vmessOptions.TLS
+		transport = vmessOptions.Transport
+		proxy["type"] = "vmess"
+		proxy["uuid"] = vmessOptions.UUID
+		proxy["alterId"] = vmessOptions.AlterId
+		proxy["cipher"] = vmessOptions.Security
+		if proxy["cipher"] == "" {
+			proxy["cipher"] = "auto"
+		}
+		proxy["udp"] = true
+	case C.TypeTrojan:
+		trojanOptions := outbound.TrojanOptions
+		serverOptions = trojanOptions.ServerOptions
+		tlsOptions = trojanOptions.TLS
+		transport = trojanOptions.Transport
+		proxy["type"] = "trojan"
+		proxy["password"] = trojanOptions.Password
+		proxy["udp"] = true
+	case C.TypeVLESS:
+		vlessOptions := outbound.VLESSOptions
+		serverOptions = vlessOptions.ServerOptions
+		tlsOptions = vlessOptions.TLS
+		transport = vlessOptions.Transport
+		proxy["type"] = "vless"
+		proxy["uuid"] = vlessOptions.UUID
+		if vlessOptions.Flow != "" {
+			proxy["flow"] = vlessOptions.Flow
+		}
+		proxy["udp"] = true
+	case C.TypeSOCKS:
+		socksOptions := outbound.SocksOptions
+		serverOptions = socksOptions.ServerOptions
+		proxy["type"] = "socks5"
+		if socksOptions.Username != "" {
+			proxy["username"] = socksOptions.Username
+			proxy["password"] = socksOptions.Password
+		}
+		proxy["udp"] = true
+	case C.TypeHTTP:
+		httpOptions := outbound.HTTPOptions
+		serverOptions = httpOptions.ServerOptions
+		tlsOptions = httpOptions.TLS
+		proxy["type"] = "http"
+		if httpOptions.Username != "" {
+			proxy["username"] = httpOptions.Username
+			proxy["password"] = httpOptions.Password
+		}
+	case C.TypeHysteria2:
+		hysteria2Options := outbound.Hysteria2Options
+		serverOptions = hysteria2Options.ServerOptions
+		tlsOptions = hysteria2Options.TLS
+		proxy["type"] = "hysteria2"
+		proxy["password"] = hysteria2Options.Password
+		if hysteria2Options.Obfs != nil && hysteria2Options.Obfs.Type != "" {
+			proxy["obfs"] = hysteria2Options.Obfs.Type
+			proxy["obfs-password"] = hysteria2Options.Obfs.Password
+		}
+		if hysteria2Options.UpMbps > 0 {
+			proxy["up"] = strconv.Itoa(hysteria2Options.UpMbps) + " Mbps"
+		}
+		if hysteria2Options.DownMbps > 0 {
+			proxy["down"] = strconv.Itoa(hysteria2Options.DownMbps) + " Mbps"
+		}
+	case C.TypeTUIC:
+		tuicOptions := outbound.TUICOptions
+		serverOptions = tuicOptions.ServerOptions
+		tlsOptions = tuicOptions.TLS
+		proxy["type"] = "tuic"
+		proxy["uuid"] = tuicOptions.UUID
+		proxy["password"] = tuicOptions.Password
+		if tuicOptions.CongestionControl != "" {
+			proxy["congestion-controller"] = tuicOptions.CongestionControl
+		}
+		if tuicOptions.UDPRelayMode != "" {
+			proxy["udp-relay-mode"] = tuicOptions.UDPRelayMode
+		}
+	default:
+		return nil, E.New("unsupported type: ", outbound.Type)
+	}
+	proxy["server"] = serverOptions.Server
+	proxy["port"] = serverOptions.ServerPort
+	if tlsOptions != nil && tlsOptions.Enabled {
+		switch outbound.Type {
+		case C.TypeVMess, C.TypeVLESS, C.TypeHTTP:
+			proxy["tls"] = true
+			if tlsOptions.ServerName != "" {
+				proxy["servername"] = tlsOptions.ServerName
+			}
+		default:
+			if tlsOptions.ServerName != "" {
+				proxy["sni"] = tlsOptions.ServerName
+			}
+		}
+		if tlsOptions.Insecure {
+			proxy["skip-cert-verify"] = true
+		}
+		if len(tlsOptions.ALPN) > 0 {
+			proxy["alpn"] = []string(tlsOptions.ALPN)
+		}
+		if tlsOptions.UTLS != nil && tlsOptions.UTLS.Enabled {
+			proxy["client-fingerprint"] = tlsOptions.UTLS.Fingerprint
+		}
+		if tlsOptions.Reality != nil && tlsOptions.Reality.Enabled {
+			proxy["reality-opts"] = map[string]any{
+				"public-key": tlsOptions.Reality.PublicKey,
+				"short-id":   tlsOptions.Reality.ShortID,
+			}
+		}
+	}
+	if transport != nil {
+		switch transport.Type {
+		case C.V2RayTransportTypeWebsocket:
+			wsOptions := map[string]any{"path": transport.WebsocketOptions.Path}
+			if len(transport.WebsocketOptions.Headers) > 0 {
+				headers := make(map[string]string)
+				for key, values := range transport.WebsocketOptions.Headers {
+					headers[key] = strings.Join(values, ",")
+				}
+				wsOptions["headers"] = headers
+			}
+			proxy["network"] = "ws"
+			proxy["ws-opts"] = wsOptions
+		case C.V2RayTransportTypeGRPC:
+			proxy["network"] = "grpc"
+			proxy["grpc-opts"] = map[string]any{"grpc-service-name": transport.GRPCOptions.ServiceName}
+		default:
+			return nil, E.New("unsupported transport: ", transport.Type)
+		}
+	}
+	return proxy, nil
+}
+
+func clashExportRule(rule option.Rule, exported map[string]string) ([]string, error) {
+	if rule.Type != C.RuleTypeDefault && rule.Type != "" {
+		return nil, E.New("logical rules are not supported")
+	}
+	defaultRule := rule.DefaultOptions
+	if defaultRule.Invert {
+		return nil, E.New("inverted rules are not supported")
+	}
+	policy, loaded := exported[defaultRule.Outbound]
+	if !loaded {
+		return nil, E.New("unknown outbound: ", defaultRule.Outbound)
+	}
+	// A sing-box rule matches when any item of every field matches, which is
+	// only expressible in Clash when the rule uses a single field.
+	var lines []string
+	fields := 0
+	addField := func(ruleType string, values []string) {
+		if len(values) == 0 {
+			return
+		}
+		fields++
+		for _, value := range values {
+			lines = append(lines, ruleType+","+value+","+policy)
+		}
+	}
+	addField("DOMAIN", defaultRule.Domain)
+	addField("DOMAIN-SUFFIX", defaultRule.DomainSuffix)
+	addField("DOMAIN-KEYWORD", defaultRule.DomainKeyword)
+	addField("DOMAIN-REGEX", defaultRule.DomainRegex)
+	addField("GEOSITE", defaultRule.Geosite)
+	addField("GEOIP", defaultRule.GeoIP)
+	addField("IP-CIDR", defaultRule.IPCIDR)
+	addField("SRC-IP-CIDR", defaultRule.SourceIPCIDR)
+	addField("PROCESS-NAME", defaultRule.ProcessName)
+	addField("PROCESS-PATH", defaultRule.ProcessPath)
+	addField("RULE-SET", defaultRule.RuleSet)
+	var ports []string
+	for _, port := range defaultRule.Port {
+		ports = append(ports, strconv.Itoa(int(port)))
+	}
+	for _, portRange := range defaultRule.PortRange {
+		ports = append(ports, strings.Replace(portRange, ":", "-", 1))
+	}
+	addField("DST-PORT", ports)
+	if defaultRule.IPIsPrivate {
+		addField("GEOIP", []string{"LAN"})
+	}
+	if len(defaultRule.Network) == 1 {
+		addField("NETWORK", []string{strings.ToUpper(defaultRule.Network[0])})
+	}
+	if fields == 0 {
+		return nil, E.New("no supported fields")
+	}
+	if fields > 1 {
+		return nil, E.New("rules with multiple fields are not supported")
+	}
+	return lines, nil
+}
@@ -0,0 +1,163 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ParseClashRuleSet converts the payload of a Clash rule provider, either
+// a YAML document with a payload list or plain text with one entry per line,
+// into headless rules. Entries that cannot be converted are skipped and
+// reported in warnings.
+func ParseClashRuleSet(content []byte, behavior string) ([]option.HeadlessRule, Warnings, error) {
+	var warnings Warnings
+	entries := clashRuleSetEntries(content)
+	switch behavior {
+	case C.ClashRuleSetBehaviorDomain:
+		var rule option.DefaultHeadlessRule
+		for _, entry := range entries {
+			clashDomain(&rule, entry)
+		}
+		return headlessRules(rule), warnings, nil
+	case C.ClashRuleSetBehaviorIPCIDR:
+		var rule option.DefaultHeadlessRule
+		rule.IPCIDR = entries
+		return headlessRules(rule), warnings, nil
+	case C.ClashRuleSetBehaviorClassical:
+		// rules of different types must not be merged, as items of
+		// different kinds are required to match together
+		var (
+			ruleTypes []string
+			ruleMap   = make(map[string]*option.DefaultHeadlessRule)
+			skipped   = make(map[string]bool)
+		)
+		for _, entry := range entries {
+			parts := splitRule(entry)
+			if len(parts) < 2 {
+				warnings.Add("invalid rule: ", entry)
+				continue
+			}
+			ruleType := strings.ToUpper(parts[0])
+			rule := ruleMap[ruleType]
+			if rule == nil {
+				rule = new(option.DefaultHeadlessRule)
+			}
+			err := applyHeadless(rule, ruleType, parts[1])
+			if err != nil {
+				if !skipped[ruleType] {
+					skipped[ruleType] = true
+					warnings.Add(err)
+				}
+				continue
+			}
+			if ruleMap[ruleType] == nil {
+				ruleMap[ruleType] = rule
+				ruleTypes = append(ruleTypes, ruleType)
+			}
+		}
+		var rules []option.HeadlessRule
+		for _, ruleType := range ruleTypes {
+			rules = append(rules, headlessRules(*ruleMap[ruleType])...)
+		}
+		return rules, warnings, nil
+	default:
+		return nil, nil, E.New("unknown clash rule-set behavior: ", behavior)
+	}
+}
+
+func clashRuleSetEntries(content []byte) []string {
+	var document struct {
+		Payload []string `yaml:"payload"`
+	}
+	if yaml.Unmarshal(content, &document) == nil && document.Payload != nil {
+		return common.FilterNotDefault(common.Map(document.Payload, strings.TrimSpace))
+	}
+	var entries []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	scanner.Buffer(nil, 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		entries = append(entries, line)
+	}
+	return entries
+}
+
+// clashDomain adds a Clash domain entry, where `+.` matches the domain and
+// all its subdomains, a leading dot matches subdomains only and `*` matches
+// exactly one label.
+func clashDomain(rule *option.DefaultHeadlessRule, entry string) {
+	switch {
+	case strings.HasPrefix(entry, "+."):
+		rule.DomainSuffix = append(rule.DomainSuffix, entry[2:])
+	case strings.Contains(entry, "*"):
+		labels := strings.Split(entry, ".")
+		for i, label := range labels {
+			if label == "*" {
+				labels[i] = "[^.]+"
+			} else {
+				labels[i] = regexp.QuoteMeta(label)
+			}
+		}
+		rule.DomainRegex = append(rule.DomainRegex, "^"+strings.Join(labels, `\.`)+"$")
+	case strings.HasPrefix(entry, "."):
+		rule.DomainSuffix = append(rule.DomainSuffix, entry)
+	default:
+		rule.Domain = append(rule.Domain, entry)
+	}
+}
+
+func applyHeadless(rule *option.DefaultHeadlessRule, ruleType string, payload string) error {
+	switch ruleType {
+	case "DOMAIN":
+		rule.Domain = append(rule.Domain, payload)
+	case "DOMAIN-SUFFIX":
+		rule.DomainSuffix = append(rule.DomainSuffix, payload)
+	case "DOMAIN-KEYWORD":
+		rule.DomainKeyword = append(rule.DomainKeyword, payload)
+	case "DOMAIN-REGEX":
+		rule.DomainRegex = append(rule.DomainRegex, payload)
+	case "IP-CIDR", "IP-CIDR6":
+		rule.IPCIDR = append(rule.IPCIDR, payload)
+	case "SRC-IP-CIDR", "SRC-IP":
+		rule.SourceIPCIDR = append(rule.SourceIPCIDR, payload)
+	case "DST-PORT", "DEST-PORT":
+		return appendPort(&rule.Port, &rule.PortRange, payload)
+	case "SRC-PORT":
+		return appendPort(&rule.SourcePort, &rule.SourcePortRange, payload)
+	case "PROCESS-NAME":
+		rule.ProcessName = append(rule.ProcessName, payload)
+	case "PROCESS-PATH":
+		rule.ProcessPath = append(rule.ProcessPath, payload)
+	case "NETWORK":
+		network := strings.ToLower(payload)
+		if network != "tcp" && network != "udp" {
+			return E.New("unsupported network: ", payload)
+		}
+		rule.Network = append(rule.Network, network)
+	default:
+		return E.New("unsupported rule type: ", ruleType)
+	}
+	return nil
+}
+
+func headlessRules(rule option.DefaultHeadlessRule) []option.HeadlessRule {
+	if !rule.IsValid() {
+		return nil
+	}
+	return []option.HeadlessRule{{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: rule,
+	}}
+}
@@ -0,0 +1,172 @@
+package convert
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+const (
+	TagDirect = "direct"
+	TagBlock  = "block"
+)
+
+type Warnings []string
+
+func (w *Warnings) Add(message ...any) {
+	*w = append(*w, F.ToString(message...))
+}
+
+// policyTag maps a Clash or Surge policy name to an outbound tag.
+func policyTag(policy string) string {
+	switch strings.ToUpper(policy) {
+	case "DIRECT":
+		return TagDirect
+	case "REJECT", "REJECT-DROP", "REJECT-TINYGIF", "REJECT-NO-DROP":
+		return TagBlock
+	default:
+		return policy
+	}
+}
+
+type ruleConverter struct {
+	rules       []option.Rule
+	ruleSetTags map[string]string
+	final       string
+	lastType    string
+}
+
+// add converts one rule line split into its type, payload and policy,
+// merging it into the previous rule when both share type and policy.
+func (c *ruleConverter) add(ruleType string, payload string, policy string) error {
+	ruleType = strings.ToUpper(ruleType)
+	if ruleType == "MATCH" || ruleType == "FINAL" {
+		c.final = policyTag(payload)
+		return nil
+	}
+	outbound := policyTag(policy)
+	merge := c.lastType == ruleType && len(c.rules) > 0 && c.rules[len(c.rules)-1].DefaultOptions.Outbound == outbound
+	if !merge {
+		c.rules = append(c.rules, option.Rule{
+			Type: C.RuleTypeDefault,
+			DefaultOptions: option.DefaultRule{
+				Outbound: outbound,
+			},
+		})
+	}
+	err := c.apply(&c.rules[len(c.rules)-1].DefaultOptions, ruleType, payload)
+	if err != nil {
+		if !merge {
+			c.rules = c.rules[:len(c.rules)-1]
+			c.lastType = ""
+		}
+		return err
+	}
+	c.lastType = ruleType
+	return nil
+}
+
+func (c *ruleConverter) apply(rule *option.DefaultRule, ruleType string, payload string) error {
+	switch ruleType {
+	case "DOMAIN":
+		rule.Domain = append(rule.Domain, payload)
+	case "DOMAIN-SUFFIX":
+		rule.DomainSuffix = append(rule.DomainSuffix, payload)
+	case "DOMAIN-KEYWORD":
+		rule.DomainKeyword = append(rule.DomainKeyword, payload)
+	case "DOMAIN-REGEX":
+		rule.DomainRegex = append(rule.DomainRegex, payload)
+	case "GEOSITE":
+		rule.Geosite = append(rule.Geosite, strings.ToLower(payload))
+	case "GEOIP":
+		if strings.EqualFold(payload, "LAN") {
+			rule.IPIsPrivate = true
+		} else {
+			rule.GeoIP = append(rule.GeoIP, strings.ToLower(payload))
+		}
+	case "IP-CIDR", "IP-CIDR6":
+		rule.IPCIDR = append(rule.IPCIDR, payload)
+	case "SRC-IP-CIDR", "SRC-IP":
+		rule.SourceIPCIDR = append(rule.SourceIPCIDR, payload)
+	case "DST-PORT", "DEST-PORT":
+		return appendPort(&rule.Port, &rule.PortRange, payload)
+	case "SRC-PORT":
+		return appendPort(&rule.SourcePort, &rule.SourcePortRange, payload)
+	case "PROCESS-NAME":
+		rule.ProcessName = append(rule.ProcessName, payload)
+	case "PROCESS-PATH":
+		rule.ProcessPath = append(rule.ProcessPath, payload)
+	case "NETWORK", "PROTOCOL":
+		network := strings.ToLower(payload)
+		if network != "tcp" && network != "udp" {
+			return E.New("unsupported network: ", payload)
+		}
+		rule.Network = append(rule.Network, network)
+	case "RULE-SET":
+		if strings.EqualFold(payload, "LAN") {
+			rule.IPIsPrivate = true
+			return nil
+		}
+		tag, loaded := c.ruleSetTags[payload]
+		if !loaded {
+			return E.New("unsupported rule-set: ", payload)
+		}
+		rule.RuleSet = append(rule.RuleSet, tag)
+	default:
+		return E.New("unsupported rule type: ", ruleType)
+	}
+	return nil
+}
+
+func appendPort(ports *option.Listable[uint16], portRanges *option.Listable[string], payload string) error {
+	if strings.Contains(payload, "-") {
+		*portRanges = append(*portRanges, strings.Replace(payload, "-", ":", 1))
+		return nil
+	}
+	port, err := strconv.ParseUint(payload, 10, 16)
+	if err != nil {
+		return E.Cause(err, "parse port")
+	}
+	*ports = append(*ports, uint16(port))
+	return nil
+}
+
+// splitRule splits a comma separated rule line, dropping trailing flags
+// such as no-resolve.
+func splitRule(line string) []string {
+	parts := strings.Split(line, ",")
+	for i := range parts {
+		parts[i] = strings.TrimSpace(parts[i])
+	}
+	for len(parts) > 2 {
+		switch strings.ToLower(parts[len(parts)-1]) {
+		case "no-resolve", "src", "extended-matching":
+			parts = parts[:len(parts)-1]
+			continue
+		}
+		break
+	}
+	return parts
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// defaultOutbounds returns the builtin outbounds referenced by converted policies.
+func defaultOutbounds() []option.Outbound {
+	return []option.Outbound{
+		{Type: C.TypeDirect, Tag: TagDirect},
+		{Type: C.TypeBlock, Tag: TagBlock},
+	}
+}
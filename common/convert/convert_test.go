@@ -0,0 +1,320 @@
+package convert
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSplitRule(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		line  string
+		parts []string
+	}{
+		{"MATCH,DIRECT", []string{"MATCH", "DIRECT"}},
+		{"DOMAIN-SUFFIX, google.com , Proxy", []string{"DOMAIN-SUFFIX", "google.com", "Proxy"}},
+		{"IP-CIDR,10.0.0.0/8,DIRECT,no-resolve", []string{"IP-CIDR", "10.0.0.0/8", "DIRECT"}},
+		{"IP-CIDR,10.0.0.0/8,DIRECT,no-resolve,extended-matching", []string{"IP-CIDR", "10.0.0.0/8", "DIRECT"}},
+		{"GEOIP,CN,DIRECT,src", []string{"GEOIP", "CN", "DIRECT"}},
+	} {
+		require.Equal(t, testCase.parts, splitRule(testCase.line), testCase.line)
+	}
+}
+
+func TestPolicyTag(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		policy string
+		tag    string
+	}{
+		{"DIRECT", TagDirect},
+		{"direct", TagDirect},
+		{"REJECT", TagBlock},
+		{"REJECT-TINYGIF", TagBlock},
+		{"Proxy", "Proxy"},
+	} {
+		require.Equal(t, testCase.tag, policyTag(testCase.policy), testCase.policy)
+	}
+}
+
+func TestRuleConverter(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name   string
+		lines  [][3]string
+		rules  []option.DefaultRule
+		final  string
+		errors int
+	}{
+		{
+			name: "merge same type and policy",
+			lines: [][3]string{
+				{"DOMAIN-SUFFIX", "google.com", "Proxy"},
+				{"domain-suffix", "youtube.com", "Proxy"},
+			},
+			rules: []option.DefaultRule{
+				{DomainSuffix: []string{"google.com", "youtube.com"}, Outbound: "Proxy"},
+			},
+		},
+		{
+			name: "split on policy",
+			lines: [][3]string{
+				{"DOMAIN", "a.com", "Proxy"},
+				{"DOMAIN", "b.com", "DIRECT"},
+			},
+			rules: []option.DefaultRule{
+				{Domain: []string{"a.com"}, Outbound: "Proxy"},
+				{Domain: []string{"b.com"}, Outbound: TagDirect},
+			},
+		},
+		{
+			name: "ports and lan",
+			lines: [][3]string{
+				{"DST-PORT", "443", "Proxy"},
+				{"DST-PORT", "8000-9000", "Proxy"},
+				{"GEOIP", "LAN", "DIRECT"},
+			},
+			rules: []option.DefaultRule{
+				{Port: []uint16{443}, PortRange: []string{"8000:9000"}, Outbound: "Proxy"},
+				{IPIsPrivate: true, Outbound: TagDirect},
+			},
+		},
+		{
+			name: "final",
+			lines: [][3]string{
+				{"MATCH", "Proxy", ""},
+			},
+			final: "Proxy",
+		},
+		{
+			name: "unsupported does not break merging",
+			lines: [][3]string{
+				{"DOMAIN", "a.com", "Proxy"},
+				{"USER-AGENT", "curl*", "Proxy"},
+				{"DOMAIN", "b.com", "Proxy"},
+				{"DST-PORT", "invalid", "DIRECT"},
+				{"RULE-SET", "unknown", "DIRECT"},
+			},
+			rules: []option.DefaultRule{
+				{Domain: []string{"a.com"}, Outbound: "Proxy"},
+				{Domain: []string{"b.com"}, Outbound: "Proxy"},
+			},
+			errors: 3,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			converter := ruleConverter{ruleSetTags: make(map[string]string)}
+			var errors int
+			for _, line := range testCase.lines {
+				if converter.add(line[0], line[1], line[2]) != nil {
+					errors++
+				}
+			}
+			require.Equal(t, testCase.errors, errors)
+			require.Equal(t, testCase.final, converter.final)
+			require.Len(t, converter.rules, len(testCase.rules))
+			for i, rule := range converter.rules {
+				require.Equal(t, C.RuleTypeDefault, rule.Type)
+				require.Equal(t, testCase.rules[i], rule.DefaultOptions)
+			}
+		})
+	}
+}
+
+func TestParseSurgeLine(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		line   string
+		loaded bool
+		result surgeLine
+	}{
+		{"invalid", false, surgeLine{}},
+		{
+			"Proxy = ss, 1.2.3.4, 8388, encrypt-method=aes-128-gcm, password=p=w",
+			true,
+			surgeLine{
+				name:   "Proxy",
+				fields: []string{"ss", "1.2.3.4", "8388"},
+				params: map[string]string{"encrypt-method": "aes-128-gcm", "password": "p=w"},
+			},
+		},
+		{
+			"Auto = url-test, A, B,",
+			true,
+			surgeLine{
+				name:   "Auto",
+				fields: []string{"url-test", "A", "B"},
+				params: map[string]string{},
+			},
+		},
+	} {
+		result, loaded := parseSurgeLine(testCase.line)
+		require.Equal(t, testCase.loaded, loaded, testCase.line)
+		require.Equal(t, testCase.result, result, testCase.line)
+	}
+}
+
+func TestFromClash(t *testing.T) {
+	t.Parallel()
+	options, warnings, err := FromClash([]byte(`
+mixed-port: 7890
+proxies:
+  - name: ss
+    type: ss
+    server: 1.2.3.4
+    port: 8388
+    cipher: aes-128-gcm
+    password: password
+proxy-groups:
+  - name: Proxy
+    type: select
+    proxies: [ss, DIRECT]
+  - name: Balance
+    type: load-balance
+    strategy: round-robin
+    proxies: [ss]
+  - name: Backup
+    type: fallback
+    proxies: [ss, DIRECT]
+  - name: Relay
+    type: relay
+    proxies: [ss]
+rules:
+  - DOMAIN-SUFFIX,google.com,Proxy
+  - DOMAIN-SUFFIX,youtube.com,Proxy
+  - GEOIP,LAN,DIRECT
+  - MATCH,Proxy
+`))
+	require.NoError(t, err)
+	require.Empty(t, warnings)
+	var tags []string
+	for _, outbound := range options.Outbounds {
+		tags = append(tags, outbound.Tag)
+	}
+	require.Equal(t, []string{"ss", "Proxy", "Balance", "Backup", "Relay", TagDirect, TagBlock}, tags)
+	require.Equal(t, []string{"ss", TagDirect}, options.Outbounds[1].SelectorOptions.Outbounds)
+	require.Equal(t, C.TypeLoadBalance, options.Outbounds[2].Type)
+	require.Equal(t, C.LoadBalanceStrategyRoundRobin, options.Outbounds[2].LoadBalanceOptions.Strategy)
+	require.Equal(t, C.TypeFallback, options.Outbounds[3].Type)
+	require.Equal(t, []string{"ss", TagDirect}, options.Outbounds[3].FallbackOptions.Outbounds)
+	require.Equal(t, []string{"ss"}, options.Outbounds[4].RelayOptions.Outbounds)
+	require.Len(t, options.Inbounds, 1)
+	require.Equal(t, C.TypeMixed, options.Inbounds[0].Type)
+	require.Len(t, options.Route.Rules, 2)
+	require.Equal(t, "Proxy", options.Route.Final)
+}
+
+func TestParseClashRuleSet(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		behavior string
+		content  string
+		rules    []option.DefaultHeadlessRule
+		warnings int
+	}{
+		{
+			name:     "domain yaml",
+			behavior: C.ClashRuleSetBehaviorDomain,
+			content:  "payload:\n  - '+.google.com'\n  - '.youtube.com'\n  - 'example.com'\n  - '*.cdn.example.org'\n",
+			rules: []option.DefaultHeadlessRule{{
+				Domain:       []string{"example.com"},
+				DomainSuffix: []string{"google.com", ".youtube.com"},
+				DomainRegex:  []string{`^[^.]+\.cdn\.example\.org$`},
+			}},
+		},
+		{
+			name:     "ipcidr text",
+			behavior: C.ClashRuleSetBehaviorIPCIDR,
+			content:  "# comment\n10.0.0.0/8\n\n2001:db8::/32\n",
+			rules: []option.DefaultHeadlessRule{{
+				IPCIDR: []string{"10.0.0.0/8", "2001:db8::/32"},
+			}},
+		},
+		{
+			name:     "classical yaml",
+			behavior: C.ClashRuleSetBehaviorClassical,
+			content:  "payload:\n  - DOMAIN-SUFFIX,google.com\n  - IP-CIDR,1.1.1.1/32,no-resolve\n  - DOMAIN-SUFFIX,youtube.com\n  - DST-PORT,8000-9000\n",
+			rules: []option.DefaultHeadlessRule{
+				{DomainSuffix: []string{"google.com", "youtube.com"}},
+				{IPCIDR: []string{"1.1.1.1/32"}},
+				{PortRange: []string{"8000:9000"}},
+			},
+		},
+		{
+			name:     "classical text with unsupported",
+			behavior: C.ClashRuleSetBehaviorClassical,
+			content:  "DOMAIN,example.com\nGEOIP,CN\nGEOIP,US\nPROCESS-NAME,curl\n",
+			rules: []option.DefaultHeadlessRule{
+				{Domain: []string{"example.com"}},
+				{ProcessName: []string{"curl"}},
+			},
+			warnings: 1,
+		},
+	} {
+		rules, warnings, err := ParseClashRuleSet([]byte(testCase.content), testCase.behavior)
+		require.NoError(t, err, testCase.name)
+		require.Len(t, warnings, testCase.warnings, testCase.name)
+		require.Len(t, rules, len(testCase.rules), testCase.name)
+		for i, rule := range rules {
+			require.Equal(t, C.RuleTypeDefault, rule.Type, testCase.name)
+			require.Equal(t, testCase.rules[i], rule.DefaultOptions, testCase.name)
+		}
+	}
+	_, _, err := ParseClashRuleSet(nil, "unknown")
+	require.Error(t, err)
+}
+
+func TestParseAdGuardRuleSet(t *testing.T) {
+	t.Parallel()
+	content := `[Adblock Plus 2.0]
+! Title: test
+||ads.example.com^
+||tracker.example.org^$important
+|exact.example.net^
+banner.example
+||ad*.cdn.example^
+/^pixel[0-9]+\.example\.com$/
+0.0.0.0 hosts.example localhost
+@@||good.ads.example.com^
+||client.example^$client=192.168.1.2
+example.com##.banner
+`
+	rules, warnings, err := ParseAdGuardRuleSet([]byte(content))
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Len(t, rules, 1)
+	require.Equal(t, C.RuleTypeLogical, rules[0].Type)
+	logical := rules[0].LogicalOptions
+	require.Equal(t, C.LogicalTypeAnd, logical.Mode)
+	require.Len(t, logical.Rules, 2)
+	require.Equal(t, option.DefaultHeadlessRule{
+		Domain:       []string{"exact.example.net", "hosts.example"},
+		DomainSuffix: []string{"ads.example.com", "tracker.example.org", "banner.example"},
+		DomainRegex:  []string{`^(.*\.)?ad.*\.cdn\.example$`, `^pixel[0-9]+\.example\.com$`},
+	}, logical.Rules[0].DefaultOptions)
+	require.Equal(t, option.DefaultHeadlessRule{
+		DomainSuffix: []string{"good.ads.example.com"},
+		Invert:       true,
+	}, logical.Rules[1].DefaultOptions)
+	rules, _, err = ParseAdGuardRuleSet([]byte("||ads.example.com^\n"))
+	require.NoError(t, err)
+	require.Len(t, rules, 1)
+	require.Equal(t, C.RuleTypeDefault, rules[0].Type)
+}
+
+func TestParseHostsRuleSet(t *testing.T) {
+	t.Parallel()
+	content := "# hosts\n127.0.0.1 localhost\n0.0.0.0 ads.example.com tracker.example.org # trailing\n:: ADS.example.net\ninvalid\n"
+	rules, warnings, err := ParseHostsRuleSet([]byte(content))
+	require.NoError(t, err)
+	require.Len(t, warnings, 1)
+	require.Len(t, rules, 1)
+	require.Equal(t, option.Listable[string]{"ads.example.com", "tracker.example.org", "ads.example.net"}, rules[0].DefaultOptions.Domain)
+}
@@ -0,0 +1,273 @@
+package convert
+
+import (
+	"bufio"
+	"bytes"
+	"strconv"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"gopkg.in/yaml.v3"
+)
+
+type surgeLine struct {
+	name   string
+	fields []string
+	params map[string]string
+}
+
+// parseSurgeLine parses `name = field, field, key=value, ...`.
+func parseSurgeLine(line string) (surgeLine, bool) {
+	name, value, found := strings.Cut(line, "=")
+	if !found {
+		return surgeLine{}, false
+	}
+	result := surgeLine{
+		name:   strings.TrimSpace(name),
+		params: make(map[string]string),
+	}
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if key, paramValue, isParam := strings.Cut(field, "="); isParam {
+			result.params[strings.TrimSpace(key)] = strings.TrimSpace(paramValue)
+		} else if field != "" {
+			result.fields = append(result.fields, field)
+		}
+	}
+	return result, true
+}
+
+// FromSurge converts a Surge profile. Proxies are translated into their Clash
+// equivalents first and then share the Clash conversion.
+func FromSurge(content []byte) (*option.Options, Warnings, error) {
+	var (
+		section  string
+		general  = make(map[string]string)
+		proxies  []yaml.Node
+		groups   []surgeLine
+		rules    []string
+		warnings Warnings
+	)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "//") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line[1 : len(line)-1])
+			continue
+		}
+		switch section {
+		case "general":
+			key, value, found := strings.Cut(line, "=")
+			if found {
+				general[strings.TrimSpace(key)] = strings.TrimSpace(value)
+			}
+		case "proxy":
+			proxy, loaded := parseSurgeLine(line)
+			if !loaded {
+				warnings.Add("proxy ", line, ": invalid line")
+				continue
+			}
+			node, err := surgeProxy(proxy)
+			if err != nil {
+				warnings.Add("proxy ", proxy.name, ": ", err)
+				continue
+			}
+			if node != nil {
+				proxies = append(proxies, *node)
+			}
+		case "proxy group":
+			group, loaded := parseSurgeLine(line)
+			if !loaded {
+				warnings.Add("proxy-group ", line, ": invalid line")
+				continue
+			}
+			groups = append(groups, group)
+		case "rule":
+			rules = append(rules, line)
+		}
+	}
+	err := scanner.Err()
+	if err != nil {
+		return nil, nil, err
+	}
+	var options option.Options
+	if servers := general["dns-server"]; servers != "" {
+		dnsOptions := &option.DNSOptions{}
+		for _, server := range strings.Split(servers, ",") {
+			server = strings.TrimSpace(server)
+			if server == "" || server == "system" {
+				continue
+			}
+			dnsOptions.Servers = append(dnsOptions.Servers, option.DNSServerOptions{
+				Tag:     "dns-" + strconv.Itoa(len(dnsOptions.Servers)),
+				Address: server,
+			})
+		}
+		if len(dnsOptions.Servers) > 0 {
+			dnsOptions.Final = dnsOptions.Servers[0].Tag
+			options.DNS = dnsOptions
+		}
+	}
+	outbounds := clashProxies(proxies, &warnings)
+	var providerTags []string
+	for _, group := range groups {
+		clashGroupOptions := clashProxyGroup{
+			Name:    group.name,
+			URL:     group.params["url"],
+			Proxies: group.fields[min(1, len(group.fields)):],
+		}
+		if len(group.fields) > 0 {
+			clashGroupOptions.Type = group.fields[0]
+		}
+		clashGroupOptions.Interval, _ = strconv.Atoi(group.params["interval"])
+		if tolerance, err := strconv.ParseUint(group.params["tolerance"], 10, 16); err == nil {
+			clashGroupOptions.Tolerance = uint16(tolerance)
+		}
+		if policyPath := group.params["policy-path"]; policyPath != "" {
+			providerTag := group.name + "-provider"
+			outbounds = append(outbounds, option.Outbound{
+				Type: C.TypeProvider,
+				Tag:  providerTag,
+				ProviderOptions: option.ProviderOutboundOptions{
+					URL: policyPath,
+				},
+			})
+			providerTags = append(providerTags, providerTag)
+			clashGroupOptions.Use = []string{providerTag}
+			clashGroupOptions.Filter = group.params["policy-regex-filter"]
+		}
+		outbound, loaded := clashGroup(clashGroupOptions, providerTags, &warnings)
+		if loaded {
+			outbounds = append(outbounds, outbound)
+		}
+	}
+	options.Outbounds = append(outbounds, defaultOutbounds()...)
+	var converter ruleConverter
+	for _, line := range rules {
+		parts := splitRule(line)
+		switch len(parts) {
+		case 2:
+			err = converter.add(parts[0], parts[1], "")
+		case 3:
+			err = converter.add(parts[0], parts[1], parts[2])
+		default:
+			err = E.New("invalid rule")
+		}
+		if err != nil {
+			warnings.Add("rule ", line, ": ", err)
+		}
+	}
+	options.Route = &option.RouteOptions{
+		Rules: converter.rules,
+		Final: converter.final,
+	}
+	return &options, warnings, nil
+}
+
+// surgeProxy translates a Surge proxy line into a Clash proxy node.
+func surgeProxy(proxy surgeLine) (*yaml.Node, error) {
+	if len(proxy.fields) == 0 {
+		return nil, E.New("missing type")
+	}
+	proxyType := strings.ToLower(proxy.fields[0])
+	switch proxyType {
+	case "direct", "reject", "reject-drop", "reject-tinygif":
+		return nil, nil
+	}
+	if len(proxy.fields) < 3 {
+		return nil, E.New("missing server")
+	}
+	params := proxy.params
+	clashProxy := map[string]any{
+		"name":   proxy.name,
+		"server": proxy.fields[1],
+		"port":   proxy.fields[2],
+	}
+	if sni := params["sni"]; sni != "" {
+		clashProxy["sni"] = sni
+		clashProxy["servername"] = sni
+	}
+	if params["skip-cert-verify"] == "true" {
+		clashProxy["skip-cert-verify"] = true
+	}
+	if params["udp-relay"] == "true" {
+		clashProxy["udp"] = true
+	}
+	username, password := params["username"], params["password"]
+	if len(proxy.fields) >= 5 {
+		username, password = proxy.fields[3], proxy.fields[4]
+	}
+	switch proxyType {
+	case "ss":
+		clashProxy["type"] = "ss"
+		clashProxy["cipher"] = params["encrypt-method"]
+		clashProxy["password"] = params["password"]
+		if obfs := params["obfs"]; obfs != "" {
+			clashProxy["plugin"] = "obfs"
+			clashProxy["plugin-opts"] = map[string]any{
+				"mode": obfs,
+				"host": params["obfs-host"],
+			}
+		}
+	case "vmess":
+		clashProxy["type"] = "vmess"
+		clashProxy["uuid"] = params["username"]
+		clashProxy["cipher"] = "auto"
+		clashProxy["tls"] = params["tls"] == "true"
+		if params["ws"] == "true" {
+			clashProxy["network"] = "ws"
+			wsOptions := map[string]any{"path": params["ws-path"]}
+			if headers := params["ws-headers"]; headers != "" {
+				headerMap := make(map[string]string)
+				for _, header := range strings.Split(headers, "|") {
+					key, value, found := strings.Cut(header, ":")
+					if found {
+						headerMap[strings.TrimSpace(key)] = strings.TrimSpace(value)
+					}
+				}
+				wsOptions["headers"] = headerMap
+			}
+			clashProxy["ws-opts"] = wsOptions
+		}
+	case "trojan":
+		clashProxy["type"] = "trojan"
+		clashProxy["password"] = params["password"]
+	case "http", "https":
+		clashProxy["type"] = "http"
+		clashProxy["username"] = username
+		clashProxy["password"] = password
+		clashProxy["tls"] = proxyType == "https"
+	case "socks5", "socks5-tls":
+		clashProxy["type"] = "socks5"
+		clashProxy["username"] = username
+		clashProxy["password"] = password
+		clashProxy["tls"] = proxyType == "socks5-tls"
+	case "hysteria2":
+		clashProxy["type"] = "hysteria2"
+		clashProxy["password"] = params["password"]
+		if download := params["download-bandwidth"]; download != "" {
+			clashProxy["down"] = download + " Mbps"
+		}
+	case "tuic", "tuic-v5":
+		clashProxy["type"] = "tuic"
+		clashProxy["uuid"] = params["uuid"]
+		clashProxy["password"] = params["password"]
+		if alpn := params["alpn"]; alpn != "" {
+			clashProxy["alpn"] = []string{alpn}
+		}
+	default:
+		return nil, E.New("unsupported type: ", proxyType)
+	}
+	var node yaml.Node
+	err := node.Encode(clashProxy)
+	if err != nil {
+		return nil, err
+	}
+	return &node, nil
+}
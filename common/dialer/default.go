@@ -7,7 +7,10 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/conntrack"
+	"github.com/sagernet/sing-box/common/mptcp"
+	"github.com/sagernet/sing-box/common/relay"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -78,6 +81,19 @@ func NewDefault(router adapter.Router, options option.DialerOptions) (*DefaultDi
 		dialer.Control = control.Append(dialer.Control, control.ProtectPath(options.ProtectPath))
 		listener.Control = control.Append(listener.Control, control.ProtectPath(options.ProtectPath))
 	}
+	var bufferOptions *option.BufferOptions
+	if router != nil {
+		bufferOptions = relay.MergeOptions(options.Buffer, router.DefaultBuffer())
+	} else {
+		bufferOptions = options.Buffer
+	}
+	err := relay.Validate(bufferOptions)
+	if err != nil {
+		return nil, err
+	}
+	bufferControl := relay.SocketControl(bufferOptions)
+	dialer.Control = control.Append(dialer.Control, bufferControl)
+	listener.Control = control.Append(listener.Control, bufferControl)
 	if options.ConnectTimeout != 0 {
 		dialer.Timeout = time.Duration(options.ConnectTimeout)
 	} else {
@@ -118,11 +134,21 @@ func NewDefault(router adapter.Router, options option.DialerOptions) (*DefaultDi
 		udpDialer6.LocalAddr = &net.UDPAddr{IP: bindAddr.AsSlice()}
 		udpAddr6 = M.SocksaddrFrom(bindAddr, 0).String()
 	}
-	if options.TCPMultiPath {
+	if options.TCPMultiPath || len(options.TCPMultiPathInterfaces) > 0 {
 		if !go121Available {
 			return nil, E.New("MultiPath TCP requires go1.21, please recompile your binary.")
 		}
 		setMultiPathTCP(&dialer4)
+		setMultiPathTCP(&dialer6)
+	}
+	if len(options.TCPMultiPathInterfaces) > 0 {
+		if router == nil {
+			return nil, E.New("missing router for MultiPath TCP interfaces")
+		}
+		err := router.RegisterMultipathTCPInterfaces(options.TCPMultiPathInterfaces, mptcp.FlagSubflow)
+		if err != nil {
+			return nil, err
+		}
 	}
 	if options.IsWireGuardListener {
 		for _, controlFn := range wgControlFns {
@@ -161,6 +187,9 @@ func (d *DefaultDialer) DialContext(ctx context.Context, network string, address
 			return trackConn(d.udpDialer6.DialContext(ctx, network, address.String()))
 		}
 	}
+	if log.IsTraceContext(ctx) {
+		return d.traceDial(ctx, network, address)
+	}
 	if !address.IsIPv6() {
 		return trackConn(DialSlowContext(&d.dialer4, ctx, network, address))
 	} else {
@@ -168,6 +197,21 @@ func (d *DefaultDialer) DialContext(ctx context.Context, network string, address
 	}
 }
 
+func (d *DefaultDialer) traceDial(ctx context.Context, network string, address M.Socksaddr) (net.Conn, error) {
+	dialer := &d.dialer4
+	if address.IsIPv6() {
+		dialer = &d.dialer6
+	}
+	start := time.Now()
+	conn, err := trackConn(DialSlowContext(dialer, ctx, network, address))
+	if err != nil {
+		log.TraceEvent(ctx, "dial ", network, " ", address, " failed after ", time.Since(start), ": ", err)
+		return nil, err
+	}
+	log.TraceEvent(ctx, "dial ", network, " ", conn.LocalAddr(), " => ", conn.RemoteAddr(), " in ", time.Since(start))
+	return conn, nil
+}
+
 func (d *DefaultDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	if destination.IsIPv6() {
 		return trackPacketConn(d.udpListener.ListenPacket(ctx, N.NetworkUDP, d.udpAddr6))
@@ -37,5 +37,11 @@ func New(router adapter.Router, options option.DialerOptions) (N.Dialer, error)
 			domainStrategy,
 			time.Duration(options.FallbackDelay))
 	}
-	return dialer, nil
+	if options.Knock != nil {
+		dialer, err = NewKnockDialer(dialer, *options.Knock)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return NewRelayDialer(dialer), nil
 }
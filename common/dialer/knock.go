@@ -0,0 +1,108 @@
+package dialer
+
+import (
+	"context"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/common/knock"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+// knockDelay gives the knock packet a head start over the connection it
+// unlocks.
+const knockDelay = 50 * time.Millisecond
+
+type KnockDialer struct {
+	dialer   N.Dialer
+	port     uint16
+	key      []byte
+	interval time.Duration
+	access   sync.Mutex
+	knocked  map[string]time.Time
+}
+
+func NewKnockDialer(dialer N.Dialer, options option.OutboundKnockOptions) (*KnockDialer, error) {
+	if options.ServerPort == 0 {
+		return nil, E.New("missing knock server_port")
+	}
+	if options.Key == "" {
+		return nil, E.New("missing knock key")
+	}
+	interval := time.Duration(options.Interval)
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	return &KnockDialer{
+		dialer:   dialer,
+		port:     options.ServerPort,
+		key:      []byte(options.Key),
+		interval: interval,
+		knocked:  make(map[string]time.Time),
+	}, nil
+}
+
+func (d *KnockDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	err := d.knock(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return d.dialer.DialContext(ctx, network, destination)
+}
+
+func (d *KnockDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	err := d.knock(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	return d.dialer.ListenPacket(ctx, destination)
+}
+
+func (d *KnockDialer) knock(ctx context.Context, destination M.Socksaddr) error {
+	host := destination.AddrString()
+	d.access.Lock()
+	lastKnock, loaded := d.knocked[host]
+	if loaded && time.Since(lastKnock) < d.interval {
+		d.access.Unlock()
+		return nil
+	}
+	d.knocked[host] = time.Now()
+	d.access.Unlock()
+	err := Knock(ctx, d.dialer, M.Socksaddr{Addr: destination.Addr, Fqdn: destination.Fqdn, Port: d.port}, d.key)
+	if err != nil {
+		d.access.Lock()
+		delete(d.knocked, host)
+		d.access.Unlock()
+		return E.Cause(err, "knock ", host)
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(knockDelay):
+	}
+	return nil
+}
+
+func (d *KnockDialer) Upstream() any {
+	return d.dialer
+}
+
+// Knock sends a single knock packet to destination.
+func Knock(ctx context.Context, dialer N.Dialer, destination M.Socksaddr, key []byte) error {
+	conn, err := dialer.DialContext(ctx, N.NetworkUDP, destination)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	_, err = conn.Write(knock.Packet(key, timeFunc()))
+	return err
+}
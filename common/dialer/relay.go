@@ -0,0 +1,48 @@
+package dialer
+
+import (
+	"context"
+	"net"
+
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type relayKey struct{}
+
+// ContextWithRelay makes the next dial through a dialer created by New go
+// through upstream instead of the network. A nil upstream clears it.
+func ContextWithRelay(ctx context.Context, upstream N.Dialer) context.Context {
+	return context.WithValue(ctx, relayKey{}, upstream)
+}
+
+func RelayFromContext(ctx context.Context) N.Dialer {
+	upstream, _ := ctx.Value(relayKey{}).(N.Dialer)
+	return upstream
+}
+
+type RelayDialer struct {
+	dialer N.Dialer
+}
+
+func NewRelayDialer(dialer N.Dialer) *RelayDialer {
+	return &RelayDialer{dialer}
+}
+
+func (d *RelayDialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if upstream := RelayFromContext(ctx); upstream != nil {
+		return upstream.DialContext(ContextWithRelay(ctx, nil), network, destination)
+	}
+	return d.dialer.DialContext(ctx, network, destination)
+}
+
+func (d *RelayDialer) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	if upstream := RelayFromContext(ctx); upstream != nil {
+		return upstream.ListenPacket(ContextWithRelay(ctx, nil), destination)
+	}
+	return d.dialer.ListenPacket(ctx, destination)
+}
+
+func (d *RelayDialer) Upstream() any {
+	return d.dialer
+}
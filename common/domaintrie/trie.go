@@ -0,0 +1,219 @@
+package domaintrie
+
+import (
+	"slices"
+	"sort"
+	"unicode/utf8"
+)
+
+const prefixLabel = '\r'
+
+// Trie is a succinct trie of reversed domains shared by many domain rule
+// items. Keys are built the same way as sing's domain.Matcher, and each key
+// carries the IDs of the items it was added by, so that one lookup reports
+// every matching item at once.
+type Trie struct {
+	leaves, labelBitmap []uint64
+	labels              []byte
+	ranks, selects      []int32
+	leafRanks           []int32
+	valueIndex          []uint32
+	values              []uint32
+}
+
+type Builder struct {
+	keys map[string][]uint32
+}
+
+func NewBuilder() *Builder {
+	return &Builder{keys: make(map[string][]uint32)}
+}
+
+// Add adds the domains and domain suffixes of the item id. Items must be
+// added in ascending order of their IDs.
+func (b *Builder) Add(id uint32, domains []string, domainSuffixes []string) {
+	for _, domain := range domainSuffixes {
+		if domain == "" {
+			continue
+		}
+		if domain[0] == '.' {
+			b.add(reverseDomainSuffix(domain), id)
+		} else {
+			b.add(reverseDomain(domain), id)
+			b.add(reverseRootDomainSuffix(domain), id)
+		}
+	}
+	for _, domain := range domains {
+		b.add(reverseDomain(domain), id)
+	}
+}
+
+// AddKeys adds keys of the item id dumped from another trie by Keys.
+func (b *Builder) AddKeys(id uint32, keys []string) {
+	for _, key := range keys {
+		b.add(key, id)
+	}
+}
+
+func (b *Builder) add(key string, id uint32) {
+	ids := b.keys[key]
+	if len(ids) > 0 && ids[len(ids)-1] == id {
+		return
+	}
+	b.keys[key] = append(ids, id)
+}
+
+func (b *Builder) Len() int {
+	return len(b.keys)
+}
+
+// Build returns the trie of all added keys, or nil if there are none.
+func (b *Builder) Build() *Trie {
+	if len(b.keys) == 0 {
+		return nil
+	}
+	keys := make([]string, 0, len(b.keys))
+	for key := range b.keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	t := &Trie{}
+	lIdx := 0
+	type qElt struct{ s, e, col int }
+	queue := []qElt{{0, len(keys), 0}}
+	for i := 0; i < len(queue); i++ {
+		elt := queue[i]
+		if elt.col == len(keys[elt.s]) {
+			// a leaf node, leaves are visited in order of their ranks
+			t.valueIndex = append(t.valueIndex, uint32(len(t.values)))
+			t.values = append(t.values, b.keys[keys[elt.s]]...)
+			elt.s++
+			setBit(&t.leaves, i, 1)
+		}
+		for j := elt.s; j < elt.e; {
+			frm := j
+			for ; j < elt.e && keys[j][elt.col] == keys[frm][elt.col]; j++ {
+			}
+			queue = append(queue, qElt{frm, j, elt.col + 1})
+			t.labels = append(t.labels, keys[frm][elt.col])
+			setBit(&t.labelBitmap, lIdx, 0)
+			lIdx++
+		}
+		setBit(&t.labelBitmap, lIdx, 1)
+		lIdx++
+	}
+	t.valueIndex = append(t.valueIndex, uint32(len(t.values)))
+	t.selects, t.ranks = indexSelect32R64(t.labelBitmap)
+	t.leafRanks = indexRank64(t.leaves, true)
+	return t
+}
+
+// Lookup returns the sorted IDs of all items matching domain.
+func (t *Trie) Lookup(domain string) []uint32 {
+	key := reverseDomain(domain)
+	var (
+		matches       []uint32
+		nodeId, bmIdx int
+	)
+	for i := 0; i < len(key); i++ {
+		currentChar := key[i]
+		for ; ; bmIdx++ {
+			if getBit(t.labelBitmap, bmIdx) != 0 {
+				return compactMatches(matches)
+			}
+			nextLabel := t.labels[bmIdx-nodeId]
+			if nextLabel == prefixLabel {
+				matches = t.appendValues(matches, countZeros(t.labelBitmap, t.ranks, bmIdx+1))
+				continue
+			}
+			if nextLabel == currentChar {
+				break
+			}
+		}
+		nodeId = countZeros(t.labelBitmap, t.ranks, bmIdx+1)
+		bmIdx = selectIthOne(t.labelBitmap, t.ranks, t.selects, nodeId-1) + 1
+	}
+	if getBit(t.leaves, nodeId) != 0 {
+		matches = t.appendValues(matches, nodeId)
+	}
+	for ; getBit(t.labelBitmap, bmIdx) == 0; bmIdx++ {
+		if t.labels[bmIdx-nodeId] == prefixLabel {
+			matches = t.appendValues(matches, countZeros(t.labelBitmap, t.ranks, bmIdx+1))
+		}
+	}
+	return compactMatches(matches)
+}
+
+// Keys returns the keys of the trie by the IDs of the items they were added
+// by, so that the items can be moved to a new trie.
+func (t *Trie) Keys() map[uint32][]string {
+	keys := make(map[uint32][]string)
+	t.walk(0, nil, keys)
+	return keys
+}
+
+func (t *Trie) walk(nodeId int, key []byte, keys map[uint32][]string) {
+	if getBit(t.leaves, nodeId) != 0 {
+		leafRank, _ := rank64(t.leaves, t.leafRanks, int32(nodeId))
+		for _, id := range t.values[t.valueIndex[leafRank]:t.valueIndex[leafRank+1]] {
+			keys[id] = append(keys[id], string(key))
+		}
+	}
+	var bmIdx int
+	if nodeId > 0 {
+		bmIdx = selectIthOne(t.labelBitmap, t.ranks, t.selects, nodeId-1) + 1
+	}
+	for ; getBit(t.labelBitmap, bmIdx) == 0; bmIdx++ {
+		t.walk(countZeros(t.labelBitmap, t.ranks, bmIdx+1), append(key, t.labels[bmIdx-nodeId]), keys)
+	}
+}
+
+func (t *Trie) appendValues(matches []uint32, nodeId int) []uint32 {
+	leafRank, _ := rank64(t.leaves, t.leafRanks, int32(nodeId))
+	return append(matches, t.values[t.valueIndex[leafRank]:t.valueIndex[leafRank+1]]...)
+}
+
+func compactMatches(matches []uint32) []uint32 {
+	if len(matches) > 1 {
+		slices.Sort(matches)
+		matches = slices.Compact(matches)
+	}
+	return matches
+}
+
+// Cache holds the lookup result of the last domain, so that items sharing
+// a trie walk it only once per connection.
+type Cache struct {
+	trie    *Trie
+	domain  string
+	matches []uint32
+}
+
+func (t *Trie) Match(cache *Cache, domain string, id uint32) bool {
+	if cache.trie != t || cache.domain != domain {
+		cache.trie = t
+		cache.domain = domain
+		cache.matches = t.Lookup(domain)
+	}
+	_, found := slices.BinarySearch(cache.matches, id)
+	return found
+}
+
+func reverseDomain(domain string) string {
+	l := len(domain)
+	b := make([]byte, l)
+	for i := 0; i < l; {
+		r, n := utf8.DecodeRuneInString(domain[i:])
+		i += n
+		utf8.EncodeRune(b[l-i:], r)
+	}
+	return string(b)
+}
+
+func reverseDomainSuffix(domain string) string {
+	return reverseDomain(domain) + string(prefixLabel)
+}
+
+func reverseRootDomainSuffix(domain string) string {
+	return reverseDomain(domain) + "." + string(prefixLabel)
+}
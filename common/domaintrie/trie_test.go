@@ -0,0 +1,56 @@
+package domaintrie
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing/common/domain"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrie(t *testing.T) {
+	t.Parallel()
+	items := []struct {
+		domains        []string
+		domainSuffixes []string
+	}{
+		{[]string{"example.com"}, []string{"google.com"}},
+		{nil, []string{".cn", "sagernet.org"}},
+		{[]string{"www.google.com", "example.org"}, []string{".google.com"}},
+	}
+	builder := NewBuilder()
+	matchers := make([]*domain.Matcher, len(items))
+	for i, item := range items {
+		builder.Add(uint32(i), item.domains, item.domainSuffixes)
+		matchers[i] = domain.NewMatcher(item.domains, item.domainSuffixes)
+	}
+	trie := builder.Build()
+	var cache Cache
+	for _, testDomain := range []string{
+		"example.com", "www.example.com", "google.com", "www.google.com", "mail.google.com",
+		"fakegoogle.com", "baidu.cn", "cn", "sagernet.org", "sing-box.sagernet.org", "example.org",
+		"com", "", "例子.cn",
+	} {
+		var expected []uint32
+		for i, matcher := range matchers {
+			if matcher.Match(testDomain) {
+				expected = append(expected, uint32(i))
+			}
+		}
+		require.Equal(t, expected, trie.Lookup(testDomain), testDomain)
+		for i := range items {
+			require.Equal(t, matchers[i].Match(testDomain), trie.Match(&cache, testDomain, uint32(i)), testDomain)
+		}
+	}
+	require.Nil(t, NewBuilder().Build())
+
+	keys := trie.Keys()
+	rebuilder := NewBuilder()
+	rebuilder.AddKeys(0, keys[2])
+	rebuilder.AddKeys(1, keys[0])
+	rebuilt := rebuilder.Build()
+	for _, testDomain := range []string{"example.com", "www.google.com", "mail.google.com", "google.com", "baidu.cn", "example.org"} {
+		require.Equal(t, matchers[2].Match(testDomain), rebuilt.Match(&cache, testDomain, 0), testDomain)
+		require.Equal(t, matchers[0].Match(testDomain), rebuilt.Match(&cache, testDomain, 1), testDomain)
+	}
+}
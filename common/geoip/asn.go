@@ -0,0 +1,47 @@
+package geoip
+
+import (
+	"net/netip"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/oschwald/maxminddb-golang"
+)
+
+// ASNReader looks up autonomous system numbers in MaxMind compatible ASN
+// databases, such as GeoLite2-ASN.
+type ASNReader struct {
+	reader *maxminddb.Reader
+}
+
+type asnRecord struct {
+	AutonomousSystemNumber uint32 `maxminddb:"autonomous_system_number"`
+}
+
+func OpenASN(path string) (*ASNReader, error) {
+	database, err := maxminddb.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.Contains(database.Metadata.DatabaseType, "ASN") {
+		database.Close()
+		return nil, E.New("incorrect database type, expected ASN, got ", database.Metadata.DatabaseType)
+	}
+	return &ASNReader{database}, nil
+}
+
+func (r *ASNReader) Type() string {
+	return r.reader.Metadata.DatabaseType
+}
+
+// Lookup returns the autonomous system number of addr, or zero if unknown.
+func (r *ASNReader) Lookup(addr netip.Addr) uint32 {
+	var record asnRecord
+	_ = r.reader.Lookup(addr.AsSlice(), &record)
+	return record.AutonomousSystemNumber
+}
+
+func (r *ASNReader) Close() error {
+	return r.reader.Close()
+}
@@ -2,6 +2,7 @@ package geoip
 
 import (
 	"net/netip"
+	"strings"
 
 	E "github.com/sagernet/sing/common/exceptions"
 
@@ -9,7 +10,14 @@ import (
 )
 
 type Reader struct {
-	reader *maxminddb.Reader
+	reader  *maxminddb.Reader
+	maxMind bool
+}
+
+type maxMindCountryRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
 }
 
 func Open(path string) (*Reader, []string, error) {
@@ -17,16 +25,47 @@ func Open(path string) (*Reader, []string, error) {
 	if err != nil {
 		return nil, nil, err
 	}
-	if database.Metadata.DatabaseType != "sing-geoip" {
+	return newReader(database)
+}
+
+// FromBytes opens the database from memory, so that the file can be
+// replaced while the reader is in use.
+func FromBytes(content []byte) (*Reader, []string, error) {
+	database, err := maxminddb.FromBytes(content)
+	if err != nil {
+		return nil, nil, err
+	}
+	return newReader(database)
+}
+
+// newReader accepts sing-geoip databases, which list their codes as
+// languages, and MaxMind compatible country or city databases.
+func newReader(database *maxminddb.Reader) (*Reader, []string, error) {
+	databaseType := database.Metadata.DatabaseType
+	switch {
+	case databaseType == "sing-geoip":
+		return &Reader{reader: database}, database.Metadata.Languages, nil
+	case strings.Contains(databaseType, "Country"), strings.Contains(databaseType, "City"):
+		return &Reader{reader: database, maxMind: true}, nil, nil
+	default:
 		database.Close()
-		return nil, nil, E.New("incorrect database type, expected sing-geoip, got ", database.Metadata.DatabaseType)
+		return nil, nil, E.New("incorrect database type, expected sing-geoip or country, got ", databaseType)
 	}
-	return &Reader{database}, database.Metadata.Languages, nil
+}
+
+func (r *Reader) Type() string {
+	return r.reader.Metadata.DatabaseType
 }
 
 func (r *Reader) Lookup(addr netip.Addr) string {
 	var code string
-	_ = r.reader.Lookup(addr.AsSlice(), &code)
+	if r.maxMind {
+		var record maxMindCountryRecord
+		_ = r.reader.Lookup(addr.AsSlice(), &record)
+		code = strings.ToLower(record.Country.ISOCode)
+	} else {
+		_ = r.reader.Lookup(addr.AsSlice(), &code)
+	}
 	if code != "" {
 		return code
 	}
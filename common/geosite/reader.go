@@ -4,22 +4,21 @@ import (
 	"bufio"
 	"encoding/binary"
 	"io"
-	"os"
-	"sync/atomic"
 
-	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing-box/common/mmap"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/varbin"
 )
 
 type Reader struct {
-	reader       io.ReadSeeker
+	reader       *mmap.File
+	dataOffset   int64
 	domainIndex  map[string]int
 	domainLength map[string]int
 }
 
 func Open(path string) (*Reader, []string, error) {
-	content, err := os.Open(path)
+	content, err := mmap.Open(path)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -45,7 +44,8 @@ type geositeMetadata struct {
 }
 
 func (r *Reader) readMetadata() error {
-	reader := bufio.NewReader(r.reader)
+	counter := &readCounter{Reader: r.reader.Reader()}
+	reader := bufio.NewReader(counter)
 	version, err := reader.ReadByte()
 	if err != nil {
 		return err
@@ -65,28 +65,27 @@ func (r *Reader) readMetadata() error {
 	}
 	r.domainIndex = domainIndex
 	r.domainLength = domainLength
-	if reader.Buffered() > 0 {
-		return common.Error(r.reader.Seek(int64(-reader.Buffered()), io.SeekCurrent))
-	}
+	r.dataOffset = counter.count - int64(reader.Buffered())
 	return nil
 }
 
+// Read decodes only the requested code, so the rest of the database is never
+// materialized in memory.
 func (r *Reader) Read(code string) ([]Item, error) {
 	index, exists := r.domainIndex[code]
 	if !exists {
 		return nil, E.New("code ", code, " not exists!")
 	}
-	_, err := r.reader.Seek(int64(index), io.SeekCurrent)
-	if err != nil {
-		return nil, err
-	}
-	counter := &readCounter{Reader: r.reader}
-	domain, err := varbin.ReadValue[[]Item](bufio.NewReader(counter), binary.BigEndian)
-	if err != nil {
-		return nil, err
+	offset := r.dataOffset + int64(index)
+	if offset >= r.reader.Size() {
+		return nil, E.New("code ", code, ": index out of range")
 	}
-	_, err = r.reader.Seek(int64(-index)-counter.count, io.SeekCurrent)
-	return domain, err
+	section := io.NewSectionReader(r.reader, offset, r.reader.Size()-offset)
+	return varbin.ReadValue[[]Item](bufio.NewReader(section), binary.BigEndian)
+}
+
+func (r *Reader) Close() error {
+	return r.reader.Close()
 }
 
 func (r *Reader) Upstream() any {
@@ -100,8 +99,6 @@ type readCounter struct {
 
 func (r *readCounter) Read(p []byte) (n int, err error) {
 	n, err = r.Reader.Read(p)
-	if n > 0 {
-		atomic.AddInt64(&r.count, int64(n))
-	}
+	r.count += int64(n)
 	return
 }
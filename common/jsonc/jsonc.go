@@ -0,0 +1,219 @@
+package jsonc
+
+import (
+	"bytes"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type tokenType uint8
+
+const (
+	tokenDelim tokenType = iota
+	tokenValue
+	tokenLineComment
+	tokenBlockComment
+)
+
+type token struct {
+	typ      tokenType
+	content  []byte
+	offset   int
+	newlines int
+}
+
+func (t token) isComment() bool {
+	return t.typ == tokenLineComment || t.typ == tokenBlockComment
+}
+
+func (t token) isDelim(delim byte) bool {
+	return t.typ == tokenDelim && t.content[0] == delim
+}
+
+func tokenize(content []byte) ([]token, error) {
+	var (
+		tokens   []token
+		newlines int
+	)
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case c == '\n':
+			newlines++
+			i++
+		case c == ' ' || c == '\t' || c == '\r':
+			i++
+		case c == '{' || c == '}' || c == '[' || c == ']' || c == ':' || c == ',':
+			tokens = append(tokens, token{tokenDelim, content[i : i+1], i, newlines})
+			newlines = 0
+			i++
+		case c == '"':
+			end := i + 1
+			for ; end < len(content) && content[end] != '"'; end++ {
+				if content[end] == '\\' {
+					end++
+				}
+			}
+			if end >= len(content) {
+				return nil, E.New("unterminated string at offset ", i)
+			}
+			tokens = append(tokens, token{tokenValue, content[i : end+1], i, newlines})
+			newlines = 0
+			i = end + 1
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			end := bytes.IndexByte(content[i:], '\n')
+			if end == -1 {
+				end = len(content)
+			} else {
+				end += i
+			}
+			tokens = append(tokens, token{tokenLineComment, bytes.TrimRight(content[i:end], " \t\r"), i, newlines})
+			newlines = 0
+			i = end
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := bytes.Index(content[i+2:], []byte("*/"))
+			if end == -1 {
+				return nil, E.New("unterminated comment at offset ", i)
+			}
+			end += i + 4
+			tokens = append(tokens, token{tokenBlockComment, content[i:end], i, newlines})
+			newlines = 0
+			i = end
+		default:
+			end := i
+			for end < len(content) && !bytes.ContainsRune([]byte(" \t\r\n{}[]:,/\""), rune(content[end])) {
+				end++
+			}
+			if end == i {
+				return nil, E.New("unexpected character at offset ", i)
+			}
+			tokens = append(tokens, token{tokenValue, content[i:end], i, newlines})
+			newlines = 0
+			i = end
+		}
+	}
+	return tokens, nil
+}
+
+// isTrailingComma reports whether the comma at index closes a list, ignoring comments.
+func isTrailingComma(tokens []token, index int) bool {
+	for _, next := range tokens[index+1:] {
+		if next.isComment() {
+			continue
+		}
+		return next.isDelim('}') || next.isDelim(']')
+	}
+	return true
+}
+
+// Standardize converts JSONC content to plain JSON by blanking out comments
+// and trailing commas. Byte offsets are kept, so decoding errors still point
+// to the right row and column.
+func Standardize(content []byte) ([]byte, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+	var result []byte
+	for index, current := range tokens {
+		if !current.isComment() && !(current.isDelim(',') && isTrailingComma(tokens, index)) {
+			continue
+		}
+		if result == nil {
+			result = bytes.Clone(content)
+		}
+		for i := current.offset; i < current.offset+len(current.content); i++ {
+			if result[i] != '\n' {
+				result[i] = ' '
+			}
+		}
+	}
+	if result == nil {
+		return content, nil
+	}
+	return result, nil
+}
+
+// Format re-indents JSONC content while keeping comments, key order and
+// single blank lines between entries. Trailing commas are removed.
+func Format(content []byte, indent string) ([]byte, error) {
+	tokens, err := tokenize(content)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		buffer         bytes.Buffer
+		depth          int
+		pendingNewline bool
+	)
+	writeNewline := func(blankLine bool) {
+		if buffer.Len() > 0 {
+			if blankLine {
+				buffer.WriteByte('\n')
+			}
+			buffer.WriteByte('\n')
+		}
+		for i := 0; i < depth; i++ {
+			buffer.WriteString(indent)
+		}
+	}
+	for index := 0; index < len(tokens); index++ {
+		current := tokens[index]
+		switch {
+		case current.isComment():
+			if current.newlines > 0 || buffer.Len() == 0 {
+				writeNewline(current.newlines > 1 && pendingNewline)
+			} else {
+				buffer.WriteByte(' ')
+			}
+			buffer.Write(current.content)
+			pendingNewline = pendingNewline || current.typ == tokenLineComment || current.newlines > 0
+			continue
+		case current.isDelim('{') || current.isDelim('['):
+			if pendingNewline {
+				writeNewline(current.newlines > 1)
+				pendingNewline = false
+			}
+			closeDelim := byte('}')
+			if current.content[0] == '[' {
+				closeDelim = ']'
+			}
+			if index+1 < len(tokens) && tokens[index+1].isDelim(closeDelim) {
+				buffer.Write(current.content)
+				buffer.WriteByte(closeDelim)
+				index++
+				continue
+			}
+			buffer.Write(current.content)
+			depth++
+			pendingNewline = true
+		case current.isDelim('}') || current.isDelim(']'):
+			if depth == 0 {
+				return nil, E.New("unexpected ", string(current.content), " at offset ", current.offset)
+			}
+			depth--
+			writeNewline(false)
+			buffer.Write(current.content)
+			pendingNewline = false
+		case current.isDelim(','):
+			if isTrailingComma(tokens, index) {
+				continue
+			}
+			buffer.WriteByte(',')
+			pendingNewline = true
+		case current.isDelim(':'):
+			buffer.WriteString(": ")
+		default:
+			if pendingNewline {
+				writeNewline(current.newlines > 1)
+				pendingNewline = false
+			}
+			buffer.Write(current.content)
+		}
+	}
+	if depth != 0 {
+		return nil, E.New("unexpected end of content")
+	}
+	buffer.WriteByte('\n')
+	return buffer.Bytes(), nil
+}
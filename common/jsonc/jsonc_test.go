@@ -0,0 +1,85 @@
+package jsonc
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStandardize(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		content string
+		result  string
+	}{
+		{"plain", `{"a": 1}`, `{"a": 1}`},
+		{"line comment", "{\"a\": 1 // one\n}", "{\"a\": 1       \n}"},
+		{"block comment", `{/* x */"a": 1}`, `{       "a": 1}`},
+		{"multiline block comment", "{/*\n*/\"a\": 1}", "{  \n  \"a\": 1}"},
+		{"trailing comma", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2 ], "b": 3 }`},
+		{"trailing comma before comment", "[1, // x\n]", "[1      \n]"},
+		{"comment in string", `{"a": "//b/*c*/"}`, `{"a": "//b/*c*/"}`},
+		{"escaped quote", `{"a": "\"//"}`, `{"a": "\"//"}`},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := Standardize([]byte(testCase.content))
+			require.NoError(t, err)
+			require.Equal(t, testCase.result, string(result))
+			require.Len(t, result, len(testCase.content))
+			require.True(t, json.Valid(result))
+		})
+	}
+}
+
+func TestStandardizeError(t *testing.T) {
+	t.Parallel()
+	for _, content := range []string{
+		`{"a": "b}`,
+		`{"a": 1 /* x }`,
+	} {
+		_, err := Standardize([]byte(content))
+		require.Error(t, err, content)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		content string
+		result  string
+	}{
+		{"empty", `{}`, "{}\n"},
+		{"key order", `{"b":1,"a":[]}`, "{\n  \"b\": 1,\n  \"a\": []\n}\n"},
+		{"trailing comma", `{"a":[1,2,],}`, "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n"},
+		{"line comment", "{\n// head\n\"a\": 1 // tail\n}", "{\n  // head\n  \"a\": 1 // tail\n}\n"},
+		{"blank line", "{\"a\": 1,\n\n\"b\": 2}", "{\n  \"a\": 1,\n\n  \"b\": 2\n}\n"},
+		{"collapse blank lines", "{\"a\": 1,\n\n\n\n\"b\": 2}", "{\n  \"a\": 1,\n\n  \"b\": 2\n}\n"},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			result, err := Format([]byte(testCase.content), "  ")
+			require.NoError(t, err)
+			require.Equal(t, testCase.result, string(result))
+			formatted, err := Format(result, "  ")
+			require.NoError(t, err)
+			require.Equal(t, string(result), string(formatted))
+		})
+	}
+}
+
+func TestFormatError(t *testing.T) {
+	t.Parallel()
+	for _, content := range []string{
+		`{"a": 1`,
+		`{"a": 1}}`,
+	} {
+		_, err := Format([]byte(content), "  ")
+		require.Error(t, err, content)
+	}
+}
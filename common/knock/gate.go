@@ -0,0 +1,145 @@
+package knock
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+// Gate listens for knock packets and tracks the source addresses allowed to
+// connect.
+type Gate struct {
+	ctx      context.Context
+	logger   log.ContextLogger
+	listen   M.Socksaddr
+	key      []byte
+	timeout  time.Duration
+	timeFunc func() time.Time
+	conn     net.PacketConn
+	access   sync.Mutex
+	allowed  map[netip.Addr]time.Time
+	nonces   map[[nonceSize]byte]time.Time
+	done     chan struct{}
+}
+
+func NewGate(ctx context.Context, logger log.ContextLogger, listen M.Socksaddr, key string, timeout time.Duration) (*Gate, error) {
+	if key == "" {
+		return nil, E.New("missing knock key")
+	}
+	if listen.Port == 0 {
+		return nil, E.New("missing knock listen port")
+	}
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	return &Gate{
+		ctx:      ctx,
+		logger:   logger,
+		listen:   listen,
+		key:      []byte(key),
+		timeout:  timeout,
+		timeFunc: timeFunc,
+		allowed:  make(map[netip.Addr]time.Time),
+		nonces:   make(map[[nonceSize]byte]time.Time),
+		done:     make(chan struct{}),
+	}, nil
+}
+
+func (g *Gate) Start() error {
+	var listenConfig net.ListenConfig
+	conn, err := listenConfig.ListenPacket(g.ctx, M.NetworkFromNetAddr(N.NetworkUDP, g.listen.Addr), g.listen.String())
+	if err != nil {
+		return E.Cause(err, "listen knock port")
+	}
+	g.conn = conn
+	g.logger.Info("knock server started at ", conn.LocalAddr())
+	go g.loopRead()
+	go g.loopCleanup()
+	return nil
+}
+
+func (g *Gate) Close() error {
+	select {
+	case <-g.done:
+		return nil
+	default:
+		close(g.done)
+	}
+	if g.conn != nil {
+		return g.conn.Close()
+	}
+	return nil
+}
+
+// Allowed reports whether addr sent a valid knock within the timeout.
+func (g *Gate) Allowed(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	g.access.Lock()
+	defer g.access.Unlock()
+	expiresAt, loaded := g.allowed[addr]
+	return loaded && g.timeFunc().Before(expiresAt)
+}
+
+func (g *Gate) loopRead() {
+	buffer := make([]byte, PacketSize+1)
+	for {
+		n, addr, err := g.conn.ReadFrom(buffer)
+		if err != nil {
+			return
+		}
+		source := M.SocksaddrFromNet(addr).Unwrap()
+		now := g.timeFunc()
+		nonce, err := Verify(g.key, buffer[:n], now)
+		if err != nil {
+			g.logger.Debug("drop knock from ", source, ": ", err)
+			continue
+		}
+		g.access.Lock()
+		if _, replayed := g.nonces[nonce]; replayed {
+			g.access.Unlock()
+			g.logger.Debug("drop knock from ", source, ": replayed")
+			continue
+		}
+		g.nonces[nonce] = now.Add(2 * MaxClockSkew)
+		g.allowed[source.Addr] = now.Add(g.timeout)
+		g.access.Unlock()
+		g.logger.Debug("accepted knock from ", source.Addr)
+	}
+}
+
+func (g *Gate) loopCleanup() {
+	ticker := time.NewTicker(time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-g.done:
+			return
+		case <-ticker.C:
+		}
+		now := g.timeFunc()
+		g.access.Lock()
+		for addr, expiresAt := range g.allowed {
+			if now.After(expiresAt) {
+				delete(g.allowed, addr)
+			}
+		}
+		for nonce, expiresAt := range g.nonces {
+			if now.After(expiresAt) {
+				delete(g.nonces, nonce)
+			}
+		}
+		g.access.Unlock()
+	}
+}
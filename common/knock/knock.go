@@ -0,0 +1,58 @@
+package knock
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	Version    = 1
+	nonceSize  = 16
+	headerSize = 1 + 8 + nonceSize
+	PacketSize = headerSize + sha256.Size
+
+	// MaxClockSkew is how far the timestamp of a knock packet may differ from
+	// the local clock.
+	MaxClockSkew = 30 * time.Second
+)
+
+// Packet builds a knock packet: version, unix timestamp and a random nonce,
+// followed by their HMAC-SHA256 under key.
+func Packet(key []byte, now time.Time) []byte {
+	packet := make([]byte, PacketSize)
+	packet[0] = Version
+	binary.BigEndian.PutUint64(packet[1:9], uint64(now.Unix()))
+	rand.Read(packet[9:headerSize])
+	mac := hmac.New(sha256.New, key)
+	mac.Write(packet[:headerSize])
+	copy(packet[headerSize:], mac.Sum(nil))
+	return packet
+}
+
+// Verify checks a knock packet and returns its nonce for replay detection.
+func Verify(key []byte, packet []byte, now time.Time) ([nonceSize]byte, error) {
+	var nonce [nonceSize]byte
+	if len(packet) != PacketSize {
+		return nonce, E.New("bad packet length: ", len(packet))
+	}
+	if packet[0] != Version {
+		return nonce, E.New("unknown version: ", packet[0])
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(packet[:headerSize])
+	if !hmac.Equal(mac.Sum(nil), packet[headerSize:]) {
+		return nonce, E.New("bad authentication")
+	}
+	timestamp := time.Unix(int64(binary.BigEndian.Uint64(packet[1:9])), 0)
+	skew := now.Sub(timestamp)
+	if skew > MaxClockSkew || skew < -MaxClockSkew {
+		return nonce, E.New("timestamp out of range: ", timestamp.Format(time.RFC3339))
+	}
+	copy(nonce[:], packet[9:headerSize])
+	return nonce, nil
+}
@@ -0,0 +1,153 @@
+package knock
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerify(t *testing.T) {
+	t.Parallel()
+	key := []byte("key")
+	now := time.Unix(1700000000, 0)
+	for _, testCase := range []struct {
+		name   string
+		packet func() []byte
+		now    time.Time
+		valid  bool
+	}{
+		{"valid", func() []byte { return Packet(key, now) }, now, true},
+		{"skew within range", func() []byte { return Packet(key, now.Add(-MaxClockSkew)) }, now, true},
+		{"expired", func() []byte { return Packet(key, now.Add(-MaxClockSkew-time.Second)) }, now, false},
+		{"future", func() []byte { return Packet(key, now.Add(MaxClockSkew+time.Second)) }, now, false},
+		{"wrong key", func() []byte { return Packet([]byte("other"), now) }, now, false},
+		{"short", func() []byte { return Packet(key, now)[:PacketSize-1] }, now, false},
+		{"unknown version", func() []byte {
+			packet := Packet(key, now)
+			packet[0] = Version + 1
+			return packet
+		}, now, false},
+		{"tampered", func() []byte {
+			packet := Packet(key, now)
+			packet[9] ^= 1
+			return packet
+		}, now, false},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			packet := testCase.packet()
+			nonce, err := Verify(key, packet, testCase.now)
+			if testCase.valid {
+				require.NoError(t, err)
+				require.Equal(t, packet[9:headerSize], nonce[:])
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestPacketNonce(t *testing.T) {
+	t.Parallel()
+	now := time.Now()
+	first, err := Verify([]byte("key"), Packet([]byte("key"), now), now)
+	require.NoError(t, err)
+	second, err := Verify([]byte("key"), Packet([]byte("key"), now), now)
+	require.NoError(t, err)
+	require.NotEqual(t, first, second)
+}
+
+func TestGateReplay(t *testing.T) {
+	t.Parallel()
+	gate, err := NewGate(context.Background(), log.NewNOPFactory().Logger(), M.ParseSocksaddrHostPort("127.0.0.1", 1), "key", time.Minute)
+	require.NoError(t, err)
+	gate.listen = M.ParseSocksaddrHostPort("127.0.0.1", 0)
+	require.NoError(t, gate.Start())
+	defer gate.Close()
+	gateAddr := gate.conn.LocalAddr()
+	source := netip.MustParseAddr("127.0.0.1")
+	send := func(conn net.PacketConn, packet []byte) {
+		_, err := conn.WriteTo(packet, gateAddr)
+		require.NoError(t, err)
+	}
+	client, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer client.Close()
+
+	send(client, Packet([]byte("other"), time.Now()))
+	time.Sleep(100 * time.Millisecond)
+	require.False(t, gate.Allowed(source))
+
+	packet := Packet([]byte("key"), time.Now())
+	send(client, packet)
+	require.Eventually(t, func() bool {
+		return gate.Allowed(source)
+	}, time.Second, 10*time.Millisecond)
+
+	// a replayed packet must not extend the allowance
+	gate.access.Lock()
+	gate.allowed[source] = time.Now().Add(-time.Second)
+	gate.access.Unlock()
+	send(client, packet)
+	time.Sleep(100 * time.Millisecond)
+	require.False(t, gate.Allowed(source))
+}
+
+func TestGateAllowed(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	gate := &Gate{
+		timeout:  time.Minute,
+		timeFunc: func() time.Time { return now },
+		allowed: map[netip.Addr]time.Time{
+			netip.MustParseAddr("192.0.2.1"): now.Add(time.Second),
+			netip.MustParseAddr("192.0.2.2"): now.Add(-time.Second),
+		},
+	}
+	for _, testCase := range []struct {
+		addr    string
+		allowed bool
+	}{
+		{"192.0.2.1", true},
+		{"::ffff:192.0.2.1", true},
+		{"192.0.2.2", false},
+		{"192.0.2.3", false},
+	} {
+		require.Equal(t, testCase.allowed, gate.Allowed(netip.MustParseAddr(testCase.addr)), testCase.addr)
+	}
+}
+
+func TestPacketConnAdmit(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	knocked := netip.MustParseAddr("192.0.2.1")
+	gate := &Gate{
+		timeout:  time.Minute,
+		timeFunc: func() time.Time { return now },
+		allowed:  map[netip.Addr]time.Time{knocked: now.Add(time.Minute)},
+	}
+	conn := gate.NewPacketConn(nil)
+	flow := netip.AddrPortFrom(knocked, 443)
+	require.True(t, conn.admit(flow))
+	require.False(t, conn.admit(netip.MustParseAddrPort("192.0.2.2:443")))
+
+	// the flow outlives the knock while it keeps sending
+	for i := 0; i < 5; i++ {
+		now = now.Add(30 * time.Second)
+		require.True(t, conn.admit(flow))
+	}
+	require.False(t, gate.Allowed(knocked))
+	require.False(t, conn.admit(netip.AddrPortFrom(knocked, 444)))
+
+	// and expires once idle for the knock timeout
+	now = now.Add(time.Minute + time.Second)
+	require.False(t, conn.admit(flow))
+}
@@ -0,0 +1,64 @@
+package knock
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+	"time"
+
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+// PacketConn drops packets from sources without a valid knock. A source stays
+// admitted while it keeps sending, so that QUIC connections established after
+// a knock outlive the knock timeout.
+type PacketConn struct {
+	net.PacketConn
+	gate        *Gate
+	access      sync.Mutex
+	admitted    map[netip.AddrPort]time.Time
+	lastCleanup time.Time
+}
+
+func (g *Gate) NewPacketConn(conn net.PacketConn) *PacketConn {
+	return &PacketConn{
+		PacketConn:  conn,
+		gate:        g,
+		admitted:    make(map[netip.AddrPort]time.Time),
+		lastCleanup: g.timeFunc(),
+	}
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	for {
+		n, addr, err = c.PacketConn.ReadFrom(p)
+		if err != nil || c.admit(M.AddrPortFromNet(addr)) {
+			return
+		}
+	}
+}
+
+func (c *PacketConn) admit(source netip.AddrPort) bool {
+	source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+	now := c.gate.timeFunc()
+	c.access.Lock()
+	defer c.access.Unlock()
+	if now.Sub(c.lastCleanup) > time.Minute {
+		for addrPort, expiresAt := range c.admitted {
+			if now.After(expiresAt) {
+				delete(c.admitted, addrPort)
+			}
+		}
+		c.lastCleanup = now
+	}
+	expiresAt, loaded := c.admitted[source]
+	if !(loaded && now.Before(expiresAt)) && !c.gate.Allowed(source.Addr()) {
+		return false
+	}
+	c.admitted[source] = now.Add(c.gate.timeout)
+	return true
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.PacketConn
+}
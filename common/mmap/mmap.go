@@ -0,0 +1,26 @@
+// Package mmap opens read-only database files by mapping them into memory, so
+// that only the pages that are actually decoded become resident.
+package mmap
+
+import (
+	"io"
+)
+
+type File struct {
+	io.ReaderAt
+	size   int64
+	closer io.Closer
+}
+
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// Reader returns a new reader of the whole file, independent of other readers.
+func (f *File) Reader() io.Reader {
+	return io.NewSectionReader(f.ReaderAt, 0, f.size)
+}
+
+func (f *File) Close() error {
+	return f.closer.Close()
+}
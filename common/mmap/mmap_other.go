@@ -0,0 +1,20 @@
+//go:build !unix
+
+package mmap
+
+import (
+	"os"
+)
+
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	return &File{file, stat.Size(), file}, nil
+}
@@ -0,0 +1,38 @@
+//go:build unix
+
+package mmap
+
+import (
+	"bytes"
+	"os"
+	"syscall"
+)
+
+type mappedContent []byte
+
+func (c mappedContent) Close() error {
+	return syscall.Munmap(c)
+}
+
+// Open falls back to reading through the file if it can not be mapped.
+func Open(path string) (*File, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	size := stat.Size()
+	if size == 0 || int64(int(size)) != size {
+		return &File{file, size, file}, nil
+	}
+	content, err := syscall.Mmap(int(file.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+	if err != nil {
+		return &File{file, size, file}, nil
+	}
+	file.Close()
+	return &File{bytes.NewReader(content), size, mappedContent(content)}, nil
+}
@@ -0,0 +1,171 @@
+package mptcp
+
+import (
+	"errors"
+	"net/netip"
+	"os"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/mdlayher/netlink"
+	"golang.org/x/sys/unix"
+)
+
+// from include/uapi/linux/mptcp.h
+const (
+	pmFamilyName    = "mptcp_pm"
+	pmFamilyVersion = 1
+
+	pmCommandAddAddr = 1
+	pmCommandDelAddr = 2
+	pmCommandGetAddr = 3
+
+	pmAttrAddr = 1
+
+	pmAddrAttrFamily = 1
+	pmAddrAttrID     = 2
+	pmAddrAttrAddr4  = 3
+	pmAddrAttrAddr6  = 4
+	pmAddrAttrFlags  = 6
+	pmAddrAttrIfIdx  = 7
+)
+
+// addEndpoint returns false if the address is already an endpoint, which is
+// then left alone.
+func addEndpoint(addr netip.Addr, index int, flags uint32) (uint8, bool, error) {
+	conn, family, err := dialPathManager()
+	if err != nil {
+		return 0, false, err
+	}
+	defer conn.Close()
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Nested(pmAttrAddr, func(encoder *netlink.AttributeEncoder) error {
+		if addr.Is4() {
+			encoder.Uint16(pmAddrAttrFamily, unix.AF_INET)
+			encoder.Bytes(pmAddrAttrAddr4, addr.AsSlice())
+		} else {
+			encoder.Uint16(pmAddrAttrFamily, unix.AF_INET6)
+			encoder.Bytes(pmAddrAttrAddr6, addr.AsSlice())
+		}
+		encoder.Int32(pmAddrAttrIfIdx, int32(index))
+		encoder.Uint32(pmAddrAttrFlags, flags)
+		return nil
+	})
+	_, err = execute(conn, family, pmCommandAddAddr, netlink.Acknowledge, encoder)
+	if err != nil {
+		if errors.Is(err, os.ErrExist) {
+			return 0, false, nil
+		}
+		return 0, false, err
+	}
+	// the kernel does not report the assigned id, look it up for deletion
+	messages, err := execute(conn, family, pmCommandGetAddr, netlink.Dump, nil)
+	if err != nil {
+		return 0, false, err
+	}
+	for _, message := range messages {
+		id, endpointAddr, err := parseEndpoint(message.Data)
+		if err != nil {
+			return 0, false, err
+		}
+		if endpointAddr == addr {
+			return id, true, nil
+		}
+	}
+	return 0, false, E.New("added endpoint not found")
+}
+
+func deleteEndpoint(id uint8) error {
+	conn, family, err := dialPathManager()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	encoder := netlink.NewAttributeEncoder()
+	encoder.Nested(pmAttrAddr, func(encoder *netlink.AttributeEncoder) error {
+		encoder.Uint8(pmAddrAttrID, id)
+		return nil
+	})
+	_, err = execute(conn, family, pmCommandDelAddr, netlink.Acknowledge, encoder)
+	return err
+}
+
+func dialPathManager() (*netlink.Conn, uint16, error) {
+	conn, err := netlink.Dial(unix.NETLINK_GENERIC, nil)
+	if err != nil {
+		return nil, 0, E.Cause(err, "dial generic netlink")
+	}
+	encoder := netlink.NewAttributeEncoder()
+	encoder.String(unix.CTRL_ATTR_FAMILY_NAME, pmFamilyName)
+	messages, err := execute(conn, unix.GENL_ID_CTRL, unix.CTRL_CMD_GETFAMILY, 0, encoder)
+	if err != nil {
+		conn.Close()
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, E.New("MPTCP is not supported by the kernel")
+		}
+		return nil, 0, E.Cause(err, "resolve ", pmFamilyName, " family")
+	}
+	for _, message := range messages {
+		decoder, err := netlink.NewAttributeDecoder(message.Data[unix.GENL_HDRLEN:])
+		if err != nil {
+			conn.Close()
+			return nil, 0, err
+		}
+		for decoder.Next() {
+			if decoder.Type() == unix.CTRL_ATTR_FAMILY_ID {
+				return conn, decoder.Uint16(), nil
+			}
+		}
+	}
+	conn.Close()
+	return nil, 0, E.New("missing ", pmFamilyName, " family id")
+}
+
+func execute(conn *netlink.Conn, family uint16, command uint8, flags netlink.HeaderFlags, encoder *netlink.AttributeEncoder) ([]netlink.Message, error) {
+	data := []byte{command, pmFamilyVersion, 0, 0}
+	if encoder != nil {
+		attributes, err := encoder.Encode()
+		if err != nil {
+			return nil, err
+		}
+		data = append(data, attributes...)
+	}
+	return conn.Execute(netlink.Message{
+		Header: netlink.Header{
+			Type:  netlink.HeaderType(family),
+			Flags: netlink.Request | flags,
+		},
+		Data: data,
+	})
+}
+
+func parseEndpoint(data []byte) (uint8, netip.Addr, error) {
+	if len(data) < unix.GENL_HDRLEN {
+		return 0, netip.Addr{}, E.New("short message")
+	}
+	decoder, err := netlink.NewAttributeDecoder(data[unix.GENL_HDRLEN:])
+	if err != nil {
+		return 0, netip.Addr{}, err
+	}
+	var (
+		id   uint8
+		addr netip.Addr
+	)
+	for decoder.Next() {
+		if decoder.Type() != pmAttrAddr {
+			continue
+		}
+		decoder.Nested(func(decoder *netlink.AttributeDecoder) error {
+			for decoder.Next() {
+				switch decoder.Type() {
+				case pmAddrAttrID:
+					id = decoder.Uint8()
+				case pmAddrAttrAddr4, pmAddrAttrAddr6:
+					addr, _ = netip.AddrFromSlice(decoder.Bytes())
+				}
+			}
+			return nil
+		})
+	}
+	return id, addr, decoder.Err()
+}
@@ -0,0 +1,17 @@
+//go:build !linux
+
+package mptcp
+
+import (
+	"net/netip"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func addEndpoint(addr netip.Addr, index int, flags uint32) (uint8, bool, error) {
+	return 0, false, E.New("MPTCP endpoints are only supported on Linux")
+}
+
+func deleteEndpoint(id uint8) error {
+	return E.New("MPTCP endpoints are only supported on Linux")
+}
@@ -0,0 +1,141 @@
+package mptcp
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const (
+	// FlagSignal announces the address to peers, for listeners.
+	FlagSignal uint32 = 1 << 0
+	// FlagSubflow opens additional subflows from the address, for dialers.
+	FlagSubflow uint32 = 1 << 1
+)
+
+type endpoint struct {
+	id    uint8
+	index int
+	flags uint32
+}
+
+// Manager installs the addresses of the registered interfaces as endpoints
+// of the kernel MPTCP path manager, so that connections can use every
+// interface at once. Endpoints are global to the network namespace, only
+// those added by the manager are removed again.
+type Manager struct {
+	logger     logger.Logger
+	access     sync.Mutex
+	interfaces map[string]uint32
+	endpoints  map[netip.Addr]endpoint
+	started    bool
+}
+
+func NewManager(logger logger.Logger) *Manager {
+	return &Manager{
+		logger:     logger,
+		interfaces: make(map[string]uint32),
+		endpoints:  make(map[netip.Addr]endpoint),
+	}
+}
+
+func (m *Manager) Register(interfaces []string, flags uint32) error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	for _, name := range interfaces {
+		m.interfaces[name] |= flags
+	}
+	if !m.started {
+		return nil
+	}
+	return m.update()
+}
+
+func (m *Manager) Start() error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.started = true
+	return m.update()
+}
+
+// Update follows address changes of the registered interfaces.
+func (m *Manager) Update() error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	if !m.started {
+		return nil
+	}
+	return m.update()
+}
+
+func (m *Manager) update() error {
+	var errors []error
+	wanted := make(map[netip.Addr]endpoint)
+	for name, flags := range m.interfaces {
+		netInterface, err := net.InterfaceByName(name)
+		if err != nil {
+			// the interface may come up later
+			m.logger.Debug("MPTCP interface ", name, ": ", err)
+			continue
+		}
+		addrs, err := netInterface.Addrs()
+		if err != nil {
+			errors = append(errors, E.Cause(err, "read addresses of ", name))
+			continue
+		}
+		for _, netAddr := range addrs {
+			addr := M.PrefixFromNet(netAddr).Addr()
+			if !addr.IsValid() || addr.IsLoopback() || addr.IsLinkLocalUnicast() {
+				continue
+			}
+			wanted[addr] = endpoint{index: netInterface.Index, flags: flags}
+		}
+	}
+	for addr, current := range m.endpoints {
+		if next, loaded := wanted[addr]; loaded && next.index == current.index && next.flags == current.flags {
+			delete(wanted, addr)
+			continue
+		}
+		err := deleteEndpoint(current.id)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "delete MPTCP endpoint ", addr))
+			continue
+		}
+		delete(m.endpoints, addr)
+		m.logger.Debug("deleted MPTCP endpoint ", addr)
+	}
+	for addr, next := range wanted {
+		id, owned, err := addEndpoint(addr, next.index, next.flags)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "add MPTCP endpoint ", addr))
+			continue
+		}
+		if !owned {
+			m.logger.Debug("MPTCP endpoint ", addr, " already exists")
+			continue
+		}
+		next.id = id
+		m.endpoints[addr] = next
+		m.logger.Info("added MPTCP endpoint ", addr)
+	}
+	return E.Errors(errors...)
+}
+
+func (m *Manager) Close() error {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.started = false
+	var errors []error
+	for addr, current := range m.endpoints {
+		err := deleteEndpoint(current.id)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "delete MPTCP endpoint ", addr))
+		}
+		delete(m.endpoints, addr)
+	}
+	return E.Errors(errors...)
+}
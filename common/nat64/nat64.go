@@ -0,0 +1,145 @@
+package nat64
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+var (
+	WellKnownPrefix = netip.MustParsePrefix("64:ff9b::/96")
+
+	// ipv4OnlyAddresses are the well-known addresses of ipv4only.arpa, see RFC 7050.
+	ipv4OnlyAddresses = []netip.Addr{
+		netip.AddrFrom4([4]byte{192, 0, 0, 170}),
+		netip.AddrFrom4([4]byte{192, 0, 0, 171}),
+	}
+	prefixLengths = []int{96, 64, 56, 48, 40, 32}
+)
+
+const PrefixAuto = "auto"
+
+func ParsePrefix(prefixString string) (netip.Prefix, error) {
+	prefix, err := netip.ParsePrefix(prefixString)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	if !prefix.Addr().Is6() {
+		return netip.Prefix{}, E.New("NAT64 prefix must be IPv6: ", prefixString)
+	}
+	switch prefix.Bits() {
+	case 32, 40, 48, 56, 64, 96:
+	default:
+		return netip.Prefix{}, E.New("invalid NAT64 prefix length: ", prefix.Bits())
+	}
+	return prefix.Masked(), nil
+}
+
+// Synthesize embeds an IPv4 address into prefix as described in RFC 6052,
+// skipping the reserved bits 64 to 71.
+func Synthesize(prefix netip.Prefix, address netip.Addr) netip.Addr {
+	ip6 := prefix.Addr().As16()
+	ip4 := address.Unmap().As4()
+	offset := prefix.Bits() / 8
+	for _, b := range ip4 {
+		if offset == 8 {
+			offset++
+		}
+		ip6[offset] = b
+		offset++
+	}
+	return netip.AddrFrom16(ip6)
+}
+
+// Extract returns the IPv4 address embedded in a synthesized address.
+func Extract(prefix netip.Prefix, address netip.Addr) (netip.Addr, bool) {
+	if !address.Is6() || address.Is4In6() || !prefix.Contains(address) {
+		return netip.Addr{}, false
+	}
+	ip6 := address.As16()
+	var ip4 [4]byte
+	offset := prefix.Bits() / 8
+	for i := range ip4 {
+		if offset == 8 {
+			offset++
+		}
+		ip4[i] = ip6[offset]
+		offset++
+	}
+	return netip.AddrFrom4(ip4), true
+}
+
+// Discover finds the NAT64 prefix of the current network by resolving
+// ipv4only.arpa through the DNS64 server, see RFC 7050.
+func Discover(ctx context.Context, router adapter.Router) (netip.Prefix, error) {
+	addresses, err := router.Lookup(ctx, "ipv4only.arpa", dns.DomainStrategyUseIPv6)
+	if err != nil {
+		return netip.Prefix{}, E.Cause(err, "lookup ipv4only.arpa")
+	}
+	for _, address := range addresses {
+		for _, bits := range prefixLengths {
+			prefix := netip.PrefixFrom(address, bits).Masked()
+			extracted, loaded := Extract(prefix, address)
+			if !loaded {
+				continue
+			}
+			for _, ipv4OnlyAddress := range ipv4OnlyAddresses {
+				if extracted == ipv4OnlyAddress {
+					return prefix, nil
+				}
+			}
+		}
+	}
+	return netip.Prefix{}, E.New("NAT64 prefix not found in ipv4only.arpa response")
+}
+
+// Resolver returns either a static NAT64 prefix or one discovered on first use.
+type Resolver struct {
+	router adapter.Router
+	auto   bool
+	access sync.Mutex
+	prefix netip.Prefix
+}
+
+func NewResolver(router adapter.Router, prefixString string) (*Resolver, error) {
+	if prefixString == PrefixAuto {
+		return &Resolver{router: router, auto: true}, nil
+	}
+	prefix, err := ParsePrefix(prefixString)
+	if err != nil {
+		return nil, err
+	}
+	return &Resolver{prefix: prefix}, nil
+}
+
+func (r *Resolver) Prefix(ctx context.Context) (netip.Prefix, error) {
+	if !r.auto {
+		return r.prefix, nil
+	}
+	r.access.Lock()
+	defer r.access.Unlock()
+	if r.prefix.IsValid() {
+		return r.prefix, nil
+	}
+	prefix, err := Discover(ctx, r.router)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	r.prefix = prefix
+	return prefix, nil
+}
+
+// Reset drops the discovered prefix, so that it is discovered again after a
+// network change.
+func (r *Resolver) Reset() {
+	if !r.auto {
+		return
+	}
+	r.access.Lock()
+	r.prefix = netip.Prefix{}
+	r.access.Unlock()
+}
@@ -0,0 +1,108 @@
+package nat64
+
+import (
+	"net/netip"
+	"testing"
+
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+// examples from RFC 6052 section 2.4
+var rfc6052Examples = []struct {
+	prefix      string
+	synthesized string
+}{
+	{"2001:db8::/32", "2001:db8:c000:221::"},
+	{"2001:db8:100::/40", "2001:db8:1c0:2:21::"},
+	{"2001:db8:122::/48", "2001:db8:122:c000:2:2100::"},
+	{"2001:db8:122:300::/56", "2001:db8:122:3c0:0:221::"},
+	{"2001:db8:122:344::/64", "2001:db8:122:344:c0:2:2100:0"},
+	{"2001:db8:122:344::/96", "2001:db8:122:344::192.0.2.33"},
+	{"64:ff9b::/96", "64:ff9b::192.0.2.33"},
+}
+
+func TestSynthesize(t *testing.T) {
+	t.Parallel()
+	address := netip.MustParseAddr("192.0.2.33")
+	for _, testCase := range rfc6052Examples {
+		prefix := netip.MustParsePrefix(testCase.prefix)
+		synthesized := netip.MustParseAddr(testCase.synthesized)
+		require.Equal(t, synthesized, Synthesize(prefix, address), testCase.prefix)
+		require.Equal(t, synthesized, Synthesize(prefix, netip.AddrFrom16(address.As16())), testCase.prefix)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	t.Parallel()
+	address := netip.MustParseAddr("192.0.2.33")
+	for _, testCase := range rfc6052Examples {
+		extracted, loaded := Extract(netip.MustParsePrefix(testCase.prefix), netip.MustParseAddr(testCase.synthesized))
+		require.True(t, loaded, testCase.prefix)
+		require.Equal(t, address, extracted, testCase.prefix)
+	}
+	for _, testCase := range []struct {
+		name    string
+		prefix  string
+		address string
+	}{
+		{"ipv4", "64:ff9b::/96", "192.0.2.33"},
+		{"ipv4 mapped", "::ffff:0:0/96", "::ffff:192.0.2.33"},
+		{"outside prefix", "64:ff9b::/96", "2001:db8::192.0.2.33"},
+	} {
+		_, loaded := Extract(netip.MustParsePrefix(testCase.prefix), netip.MustParseAddr(testCase.address))
+		require.False(t, loaded, testCase.name)
+	}
+}
+
+func TestParsePrefix(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		prefix string
+		result string
+	}{
+		{"64:ff9b::/96", "64:ff9b::/96"},
+		{"2001:db8:122:344::1/64", "2001:db8:122:344::/64"},
+		{"2001:db8::/32", "2001:db8::/32"},
+		{"2001:db8::/33", ""},
+		{"2001:db8::/128", ""},
+		{"192.0.2.0/24", ""},
+		{"invalid", ""},
+	} {
+		prefix, err := ParsePrefix(testCase.prefix)
+		if testCase.result == "" {
+			require.Error(t, err, testCase.prefix)
+			continue
+		}
+		require.NoError(t, err, testCase.prefix)
+		require.Equal(t, netip.MustParsePrefix(testCase.result), prefix)
+	}
+}
+
+func TestPacketConnAddress(t *testing.T) {
+	t.Parallel()
+	prefix := WellKnownPrefix
+	synthesized := M.ParseSocksaddr("[64:ff9b::192.0.2.33]:53")
+	extracted := M.ParseSocksaddr("192.0.2.33:53")
+	other := M.ParseSocksaddr("198.51.100.1:53")
+	for _, testCase := range []struct {
+		name          string
+		synthesizeAll bool
+		read          []M.Socksaddr
+		write         M.Socksaddr
+		result        M.Socksaddr
+	}{
+		{"extracted", false, []M.Socksaddr{synthesized}, extracted, synthesized},
+		{"not extracted", false, []M.Socksaddr{synthesized}, other, other},
+		{"synthesize all", true, nil, other, M.SocksaddrFrom(Synthesize(prefix, other.Addr), 53)},
+		{"ipv6", true, nil, synthesized, synthesized},
+		{"fqdn", true, nil, M.ParseSocksaddr("example.com:53"), M.ParseSocksaddr("example.com:53")},
+	} {
+		conn := NewPacketConn(nil, prefix, testCase.synthesizeAll)
+		for _, address := range testCase.read {
+			require.Equal(t, extracted, conn.extract(address), testCase.name)
+		}
+		require.Equal(t, testCase.result, conn.synthesize(testCase.write), testCase.name)
+	}
+}
@@ -0,0 +1,93 @@
+package nat64
+
+import (
+	"net"
+	"net/netip"
+	"sync"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// PacketConn reports synthesized source addresses as their IPv4 addresses and
+// synthesizes IPv4 destinations on write. Unless synthesizeAll is set, only
+// addresses previously extracted by the connection are synthesized again.
+type PacketConn struct {
+	N.NetPacketConn
+	prefix        netip.Prefix
+	synthesizeAll bool
+	access        sync.RWMutex
+	extracted     map[netip.Addr]struct{}
+}
+
+func NewPacketConn(conn N.NetPacketConn, prefix netip.Prefix, synthesizeAll bool) *PacketConn {
+	return &PacketConn{
+		NetPacketConn: conn,
+		prefix:        prefix,
+		synthesizeAll: synthesizeAll,
+		extracted:     make(map[netip.Addr]struct{}),
+	}
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	n, addr, err = c.NetPacketConn.ReadFrom(p)
+	if err == nil {
+		addr = c.extract(M.SocksaddrFromNet(addr)).UDPAddr()
+	}
+	return
+}
+
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	return c.NetPacketConn.WriteTo(p, c.synthesize(M.SocksaddrFromNet(addr)).UDPAddr())
+}
+
+func (c *PacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.NetPacketConn.ReadPacket(buffer)
+	if err != nil {
+		return
+	}
+	destination = c.extract(destination)
+	return
+}
+
+func (c *PacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	return c.NetPacketConn.WritePacket(buffer, c.synthesize(destination))
+}
+
+func (c *PacketConn) extract(address M.Socksaddr) M.Socksaddr {
+	extracted, loaded := Extract(c.prefix, address.Addr)
+	if !loaded {
+		return address
+	}
+	if !c.synthesizeAll {
+		c.access.RLock()
+		_, exists := c.extracted[extracted]
+		c.access.RUnlock()
+		if !exists {
+			c.access.Lock()
+			c.extracted[extracted] = struct{}{}
+			c.access.Unlock()
+		}
+	}
+	return M.SocksaddrFrom(extracted, address.Port)
+}
+
+func (c *PacketConn) synthesize(address M.Socksaddr) M.Socksaddr {
+	if !address.IsIPv4() {
+		return address
+	}
+	if !c.synthesizeAll {
+		c.access.RLock()
+		_, exists := c.extracted[address.Addr.Unmap()]
+		c.access.RUnlock()
+		if !exists {
+			return address
+		}
+	}
+	return M.SocksaddrFrom(Synthesize(c.prefix, address.Addr), address.Port)
+}
+
+func (c *PacketConn) Upstream() any {
+	return c.NetPacketConn
+}
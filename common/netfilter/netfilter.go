@@ -0,0 +1,71 @@
+package netfilter
+
+import (
+	"os/exec"
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// Command is an nft, iptables or ip command changing the rules of the host.
+type Command struct {
+	Name      string
+	Arguments []string
+}
+
+// Install removes rules left by an unclean exit with cleanup before running
+// setup, and removes the rules again if setup fails.
+func Install(setup func() error, cleanup func() error) error {
+	_ = cleanup()
+	err := setup()
+	if err != nil {
+		_ = cleanup()
+		return err
+	}
+	return nil
+}
+
+// Run runs command, writing input to its standard input if not empty.
+func Run(command Command, input string) error {
+	cmd := exec.Command(command.Name, command.Arguments...)
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		if message := strings.TrimSpace(string(output)); message != "" {
+			err = E.New(message)
+		}
+		return E.Cause(err, command.Name, " ", strings.Join(command.Arguments, " "))
+	}
+	return nil
+}
+
+// RunAll runs commands in order until one fails.
+func RunAll(commands []Command) error {
+	for _, command := range commands {
+		err := Run(command, "")
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RunEach runs all commands, even if some fail, for removing rules that may
+// not exist.
+func RunEach(commands []Command) error {
+	var errs []error
+	for _, command := range commands {
+		err := Run(command, "")
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return E.Errors(errs...)
+}
+
+func FormatMark(mark uint32) string {
+	return "0x" + strconv.FormatUint(uint64(mark), 16)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package netfilter
+
+import (
+	"testing"
+
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstall(t *testing.T) {
+	t.Parallel()
+	var calls []string
+	setup := func() error {
+		calls = append(calls, "setup")
+		return E.New("setup failed")
+	}
+	cleanup := func() error {
+		calls = append(calls, "cleanup")
+		return E.New("nothing to remove")
+	}
+	require.EqualError(t, Install(setup, cleanup), "setup failed")
+	require.Equal(t, []string{"cleanup", "setup", "cleanup"}, calls)
+}
+
+func TestRun(t *testing.T) {
+	t.Parallel()
+	require.NoError(t, Run(Command{Name: "sh", Arguments: []string{"-c", "read line && test \"$line\" = input"}}, "input\n"))
+	err := Run(Command{Name: "sh", Arguments: []string{"-c", "echo failed >&2; exit 1"}}, "")
+	require.EqualError(t, err, "sh -c echo failed >&2; exit 1: failed")
+	err = RunEach([]Command{
+		{Name: "sh", Arguments: []string{"-c", "exit 1"}},
+		{Name: "sh", Arguments: []string{"-c", "exit 2"}},
+	})
+	require.ErrorContains(t, err, "exit status 1")
+	require.ErrorContains(t, err, "exit status 2")
+	require.Error(t, RunAll([]Command{{Name: "sh", Arguments: []string{"-c", "exit 1"}}, {Name: "missing-command"}}))
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/outboundprovider/filter"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -34,9 +35,38 @@ type providerAction interface {
 	execute(ctx context.Context, router adapter.Router, logger log.ContextLogger, groupContext *ProviderActionGroupContext) (bool, error) // bool: continue(true)|break(false)
 }
 
-func NewProviderActionGroup(options []option.ProviderOutboundActionOptions) (*ProviderActionGroup, error) {
-	actions := make([]providerAction, 0, len(options))
-	for i, opt := range options {
+// NewProviderActionGroup creates the actions normalizing outbound tags by
+// the provider options, followed by the configured actions.
+func NewProviderActionGroup(options option.ProviderOutboundOptions) (*ProviderActionGroup, error) {
+	var actions []providerAction
+	if len(options.Filter) > 0 {
+		f, err := filter.NewOutboundFilter(option.OutboundFilterOptions{Rules: options.Filter})
+		if err != nil {
+			return nil, E.Cause(err, "parse filter")
+		}
+		actions = append(actions, &actionFilter{filter: f, keep: true})
+	}
+	if len(options.Exclude) > 0 {
+		f, err := filter.NewOutboundFilter(option.OutboundFilterOptions{Rules: options.Exclude})
+		if err != nil {
+			return nil, E.Cause(err, "parse exclude")
+		}
+		actions = append(actions, &actionFilter{filter: f})
+	}
+	for i, rename := range options.Rename {
+		action, err := newActionReplaceFromOptions(rename.Pattern, rename.Replacement)
+		if err != nil {
+			return nil, E.Cause(err, "parse rename[", i, "]")
+		}
+		actions = append(actions, action)
+	}
+	if options.RegionFlag {
+		actions = append(actions, &actionRegionFlag{})
+	}
+	if options.Override != nil {
+		actions = append(actions, &actionOverride{options: *options.Override})
+	}
+	for i, opt := range options.Actions {
 		creator, ok := actionCreatorMap[opt.Operate]
 		if !ok {
 			return nil, E.New("action[", i, "]: unknown operate [", opt.Operate, "]")
@@ -50,13 +80,16 @@ func NewProviderActionGroup(options []option.ProviderOutboundActionOptions) (*Pr
 	return &ProviderActionGroup{actions: actions}, nil
 }
 
-func (g *ProviderActionGroup) Execute(ctx context.Context, router adapter.Router, logger log.ContextLogger, outbounds []*option.Outbound) (*ProviderActionGroupContext, error) {
+func (g *ProviderActionGroup) Execute(ctx context.Context, router adapter.Router, logger log.ContextLogger, outbounds []*option.Outbound, groupOutbounds []*option.Outbound) (*ProviderActionGroupContext, error) {
 	groupContext := &ProviderActionGroupContext{
 		outbounds:        outbounds,
 		outboundMap:      make(map[string]*option.Outbound),
-		groupOutbounds:   make([]*option.Outbound, 0),
+		groupOutbounds:   append(make([]*option.Outbound, 0, len(groupOutbounds)), groupOutbounds...),
 		groupOutboundMap: make(map[string]*option.Outbound),
 	}
+	for _, outbound := range groupOutbounds {
+		groupContext.groupOutboundMap[outbound.Tag] = outbound
+	}
 	for i, action := range g.actions {
 		isContinue, err := action.execute(ctx, router, logger, groupContext)
 		if err != nil {
@@ -8,6 +8,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/json"
 )
 
@@ -23,6 +24,8 @@ type actionFilterOptions struct {
 
 type actionFilter struct {
 	filter *filter.OutboundFilter
+	// keep removes the outbounds not matching the filter instead
+	keep bool
 }
 
 func newActionFilter(b []byte) (providerAction, error) {
@@ -44,7 +47,7 @@ func (a *actionFilter) execute(_ context.Context, _ adapter.Router, logger log.C
 	removeOutboundTags := make(map[string]struct{})
 	newOutbounds := make([]*option.Outbound, 0, len(groupContext.outbounds))
 	for _, outbound := range groupContext.outbounds {
-		if !a.filter.MatchOutboundOptions(outbound) {
+		if a.filter.MatchOutboundOptions(outbound) == a.keep {
 			newOutbounds = append(newOutbounds, outbound)
 			continue
 		}
@@ -54,7 +57,19 @@ func (a *actionFilter) execute(_ context.Context, _ adapter.Router, logger log.C
 	}
 	groupContext.outbounds = make([]*option.Outbound, 0, len(newOutbounds))
 	groupContext.outbounds = append(groupContext.outbounds, newOutbounds...)
+	newGroupOutbounds := make([]*option.Outbound, 0, len(groupContext.groupOutbounds))
 	for _, outbound := range groupContext.groupOutbounds {
+		// a relay missing a hop is a different chain, so it is removed too
+		if outbound.Type == C.TypeRelay && common.Any(outbound.RelayOptions.Outbounds, func(it string) bool {
+			_, removed := removeOutboundTags[it]
+			return removed
+		}) {
+			delete(groupContext.groupOutboundMap, outbound.Tag)
+			removeOutboundTags[outbound.Tag] = struct{}{}
+			logger.Debug("action[filter]: group: [", outbound.Tag, "]")
+			continue
+		}
+		newGroupOutbounds = append(newGroupOutbounds, outbound)
 		switch outbound.Type {
 		case C.TypeSelector:
 			newOutbounds := make([]string, 0, len(outbound.SelectorOptions.Outbounds))
@@ -82,7 +97,28 @@ func (a *actionFilter) execute(_ context.Context, _ adapter.Router, logger log.C
 			}
 			outbound.URLTestOptions.Outbounds = make([]string, 0, len(newOutbounds))
 			outbound.URLTestOptions.Outbounds = append(outbound.URLTestOptions.Outbounds, newOutbounds...)
+		case C.TypeLoadBalance:
+			newOutbounds := make([]string, 0, len(outbound.LoadBalanceOptions.Outbounds))
+			for _, out := range outbound.LoadBalanceOptions.Outbounds {
+				_, ok := removeOutboundTags[out]
+				if !ok {
+					newOutbounds = append(newOutbounds, out)
+				}
+			}
+			outbound.LoadBalanceOptions.Outbounds = make([]string, 0, len(newOutbounds))
+			outbound.LoadBalanceOptions.Outbounds = append(outbound.LoadBalanceOptions.Outbounds, newOutbounds...)
+		case C.TypeFallback:
+			newOutbounds := make([]string, 0, len(outbound.FallbackOptions.Outbounds))
+			for _, out := range outbound.FallbackOptions.Outbounds {
+				_, ok := removeOutboundTags[out]
+				if !ok {
+					newOutbounds = append(newOutbounds, out)
+				}
+			}
+			outbound.FallbackOptions.Outbounds = make([]string, 0, len(newOutbounds))
+			outbound.FallbackOptions.Outbounds = append(outbound.FallbackOptions.Outbounds, newOutbounds...)
 		}
 	}
+	groupContext.groupOutbounds = newGroupOutbounds
 	return true, nil
 }
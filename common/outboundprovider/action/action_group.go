@@ -42,7 +42,7 @@ func newActionGroup(b []byte) (providerAction, error) {
 		return nil, E.New("missing tag")
 	}
 	switch options.Options.Type {
-	case C.TypeSelector, C.TypeURLTest:
+	case C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback:
 	case "":
 		return nil, E.New("missing type")
 	default:
@@ -81,6 +81,16 @@ func (a *actionGroup) execute(_ context.Context, _ adapter.Router, logger log.Co
 		outs = append(outs, newGroupOutbound.URLTestOptions.Outbounds...)
 		outs = append(outs, outbounds...)
 		newGroupOutbound.URLTestOptions.Outbounds = outs
+	case C.TypeLoadBalance:
+		outs := make([]string, 0, len(newGroupOutbound.LoadBalanceOptions.Outbounds)+len(outbounds))
+		outs = append(outs, newGroupOutbound.LoadBalanceOptions.Outbounds...)
+		outs = append(outs, outbounds...)
+		newGroupOutbound.LoadBalanceOptions.Outbounds = outs
+	case C.TypeFallback:
+		outs := make([]string, 0, len(newGroupOutbound.FallbackOptions.Outbounds)+len(outbounds))
+		outs = append(outs, newGroupOutbound.FallbackOptions.Outbounds...)
+		outs = append(outs, outbounds...)
+		newGroupOutbound.FallbackOptions.Outbounds = outs
 	}
 	groupContext.groupOutbounds = append(groupContext.groupOutbounds, &newGroupOutbound)
 	groupContext.groupOutboundMap[newGroupOutbound.Tag] = &newGroupOutbound
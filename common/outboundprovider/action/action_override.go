@@ -0,0 +1,102 @@
+package action
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/outboundprovider/filter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/json"
+)
+
+const actionOverrideOperate = "override"
+
+func init() {
+	actionCreatorMap[actionOverrideOperate] = newActionOverride
+}
+
+type actionOverrideOptions struct {
+	option.ProviderOutboundOverrideOptions
+	option.OutboundFilterOptions
+}
+
+// actionOverride applies dialer, TLS and multiplex settings to outbounds,
+// or to all of them if no filter rules are set.
+type actionOverride struct {
+	filter  *filter.OutboundFilter
+	options option.ProviderOutboundOverrideOptions
+}
+
+func newActionOverride(b []byte) (providerAction, error) {
+	var options actionOverrideOptions
+	err := json.Unmarshal(b, &options)
+	if err != nil {
+		return nil, err
+	}
+	a := &actionOverride{options: options.ProviderOutboundOverrideOptions}
+	if len(options.Rules) > 0 {
+		a.filter, err = filter.NewOutboundFilter(options.OutboundFilterOptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+func (a *actionOverride) execute(_ context.Context, _ adapter.Router, logger log.ContextLogger, groupContext *ProviderActionGroupContext) (bool, error) {
+	for _, outbound := range groupContext.outbounds {
+		if a.filter != nil && !a.filter.MatchOutboundOptions(outbound) {
+			continue
+		}
+		rawOptions, err := outbound.RawOptions()
+		if err != nil {
+			return false, err
+		}
+		if wrapper, isWrapper := rawOptions.(option.DialerOptionsWrapper); isWrapper {
+			dialerOptions := wrapper.TakeDialerOptions()
+			if a.options.Detour != "" {
+				dialerOptions.Detour = a.options.Detour
+			}
+			if a.options.DomainStrategy != option.DomainStrategy(0) {
+				dialerOptions.DomainStrategy = a.options.DomainStrategy
+			}
+			if a.options.TCPFastOpen != nil {
+				dialerOptions.TCPFastOpen = *a.options.TCPFastOpen
+			}
+			wrapper.ReplaceDialerOptions(dialerOptions)
+		}
+		if tlsOverride := a.options.TLS; tlsOverride != nil {
+			if wrapper, isWrapper := rawOptions.(option.OutboundTLSOptionsWrapper); isWrapper {
+				if tlsOptions := wrapper.TakeOutboundTLSOptions(); tlsOptions != nil && tlsOptions.Enabled {
+					if tlsOverride.Insecure != nil {
+						tlsOptions.Insecure = *tlsOverride.Insecure
+					}
+					if len(tlsOverride.ALPN) > 0 {
+						tlsOptions.ALPN = tlsOverride.ALPN
+					}
+					if tlsOverride.UTLS != nil {
+						tlsOptions.UTLS = common.Ptr(*tlsOverride.UTLS)
+					}
+				}
+			}
+		}
+		if a.options.Multiplex != nil {
+			multiplex := common.Ptr(*a.options.Multiplex)
+			switch outbound.Type {
+			case C.TypeShadowsocks:
+				outbound.ShadowsocksOptions.Multiplex = multiplex
+			case C.TypeTrojan:
+				outbound.TrojanOptions.Multiplex = multiplex
+			case C.TypeVMess:
+				outbound.VMessOptions.Multiplex = multiplex
+			case C.TypeVLESS:
+				outbound.VLESSOptions.Multiplex = multiplex
+			}
+		}
+		logger.Debug("action[override]: tag: [", outbound.Tag, "]")
+	}
+	return true, nil
+}
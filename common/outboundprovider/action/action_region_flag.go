@@ -0,0 +1,124 @@
+package action
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+)
+
+const actionRegionFlagOperate = "region_flag"
+
+func init() {
+	actionCreatorMap[actionRegionFlagOperate] = func([]byte) (providerAction, error) {
+		return &actionRegionFlag{}, nil
+	}
+}
+
+// actionRegionFlag prefixes outbound tags with the flag of the region named
+// in them, so that nodes can be grouped by country with tag filters.
+type actionRegionFlag struct{}
+
+type region struct {
+	code     string
+	keywords *regexp.Regexp
+}
+
+// regions are matched by ISO 3166-1 code in upper case, delimited by
+// non-letters, or by English or Chinese names of the region and its
+// common node locations.
+var regions = []region{
+	newRegion("HK", `hong\s?kong`, `香港|港`),
+	newRegion("MO", `maca[uo]`, `澳门|澳門`),
+	newRegion("TW", `taiwan|taipei`, `台湾|台灣|台北|新北|彰化`),
+	newRegion("JP", `japan|tokyo|osaka`, `日本|东京|東京|大阪|埼玉`),
+	newRegion("KR", `korea|seoul|chuncheon`, `韩国|韓國|韩|韓|首尔|首爾|春川`),
+	newRegion("SG", `singapore`, `新加坡|狮城|獅城`),
+	newRegion("US", `united\s?states|america|los\s?angeles|san\s?jose|silicon\s?valley|seattle|chicago|new\s?york|dallas|miami|ashburn`, `美国|美國|洛杉矶|洛杉磯|圣何塞|聖何塞|硅谷|西雅图|芝加哥|纽约|紐約|达拉斯|迈阿密`),
+	newRegion("CA", `canada|toronto|vancouver|montreal`, `加拿大|多伦多|温哥华|蒙特利尔`),
+	newRegion("GB", `united\s?kingdom|britain|england|london`, `英国|英國|伦敦|倫敦`),
+	newRegion("DE", `germany|frankfurt|berlin`, `德国|德國|法兰克福|柏林`),
+	newRegion("FR", `france|paris`, `法国|法國|巴黎`),
+	newRegion("NL", `netherlands|holland|amsterdam`, `荷兰|荷蘭|阿姆斯特丹`),
+	newRegion("IT", `italy|milan`, `意大利|義大利|米兰`),
+	newRegion("ES", `spain|madrid`, `西班牙|马德里`),
+	newRegion("CH", `switzerland|zurich`, `瑞士|苏黎世`),
+	newRegion("SE", `sweden|stockholm`, `瑞典|斯德哥尔摩`),
+	newRegion("IE", `ireland|dublin`, `爱尔兰|愛爾蘭|都柏林`),
+	newRegion("RU", `russia|moscow`, `俄罗斯|俄羅斯|莫斯科`),
+	newRegion("TR", `turkey|türkiye|istanbul`, `土耳其|伊斯坦布尔`),
+	newRegion("AE", `emirates|dubai`, `阿联酋|迪拜`),
+	newRegion("IN", `india|mumbai`, `印度(?:[^尼]|$)|孟买`),
+	newRegion("ID", `indonesia|jakarta`, `印尼|印度尼西亚|雅加达`),
+	newRegion("MY", `malaysia|kuala\s?lumpur`, `马来西亚|馬來西亞|吉隆坡`),
+	newRegion("TH", `thailand|bangkok`, `泰国|泰國|曼谷`),
+	newRegion("VN", `vietnam|hanoi`, `越南|河内`),
+	newRegion("PH", `philippines|manila`, `菲律宾|菲律賓|马尼拉`),
+	newRegion("AU", `australia|sydney|melbourne`, `澳大利亚|澳洲|悉尼|墨尔本`),
+	newRegion("BR", `brazil|são\s?paulo|sao\s?paulo`, `巴西|圣保罗`),
+	newRegion("AR", `argentina`, `阿根廷`),
+	newRegion("ZA", `south\s?africa|johannesburg`, `南非|约翰内斯堡`),
+	newRegion("CN", `china`, `中国|中國|回国|回國`),
+}
+
+func newRegion(code string, names string, chineseNames string) region {
+	return region{
+		code:     code,
+		keywords: regexp.MustCompile(`(?:^|[^A-Za-z])` + code + `(?:[^A-Za-z]|$)|(?i:` + names + `)|` + chineseNames),
+	}
+}
+
+// regionFlag returns the flag of the region named first in tag, or empty if
+// none is found.
+func regionFlag(tag string) string {
+	var (
+		flag  string
+		index = -1
+	)
+	for _, r := range regions {
+		loc := r.keywords.FindStringIndex(tag)
+		if loc != nil && (index == -1 || loc[0] < index) {
+			flag = flagEmoji(r.code)
+			index = loc[0]
+		}
+	}
+	return flag
+}
+
+func flagEmoji(code string) string {
+	var builder strings.Builder
+	for _, letter := range code {
+		builder.WriteRune(0x1F1E6 + letter - 'A')
+	}
+	return builder.String()
+}
+
+func hasFlag(tag string) bool {
+	for _, r := range tag {
+		return r >= 0x1F1E6 && r <= 0x1F1FF
+	}
+	return false
+}
+
+func (a *actionRegionFlag) execute(_ context.Context, _ adapter.Router, logger log.ContextLogger, groupContext *ProviderActionGroupContext) (bool, error) {
+	renameMap := make(map[string]string)
+	for _, outbound := range groupContext.outbounds {
+		if hasFlag(outbound.Tag) {
+			continue
+		}
+		flag := regionFlag(outbound.Tag)
+		if flag == "" {
+			continue
+		}
+		newTag := flag + " " + outbound.Tag
+		logger.Debug("action[region_flag]: outbound: ", outbound.Tag, " -> ", newTag)
+		renameMap[outbound.Tag] = newTag
+		delete(groupContext.outboundMap, outbound.Tag)
+		groupContext.outboundMap[newTag] = outbound
+		outbound.Tag = newTag
+	}
+	renameGroupMembers(groupContext, renameMap)
+	return true, nil
+}
@@ -63,6 +63,12 @@ func (a *actionRename) execute(_ context.Context, _ adapter.Router, logger log.C
 		groupContext.outboundMap[newTag] = outbound
 		outbound.Tag = newTag
 	}
+	renameGroupMembers(groupContext, renameMap)
+	return true, nil
+}
+
+// renameGroupMembers updates the group members renamed by renameMap.
+func renameGroupMembers(groupContext *ProviderActionGroupContext, renameMap map[string]string) {
 	for _, outbound := range groupContext.groupOutbounds {
 		switch outbound.Type {
 		case C.TypeSelector:
@@ -85,7 +91,27 @@ func (a *actionRename) execute(_ context.Context, _ adapter.Router, logger log.C
 					outbound.URLTestOptions.Outbounds[i] = newTag
 				}
 			}
+		case C.TypeLoadBalance:
+			for i, tag := range outbound.LoadBalanceOptions.Outbounds {
+				newTag, ok := renameMap[tag]
+				if ok {
+					outbound.LoadBalanceOptions.Outbounds[i] = newTag
+				}
+			}
+		case C.TypeFallback:
+			for i, tag := range outbound.FallbackOptions.Outbounds {
+				newTag, ok := renameMap[tag]
+				if ok {
+					outbound.FallbackOptions.Outbounds[i] = newTag
+				}
+			}
+		case C.TypeRelay:
+			for i, tag := range outbound.RelayOptions.Outbounds {
+				newTag, ok := renameMap[tag]
+				if ok {
+					outbound.RelayOptions.Outbounds[i] = newTag
+				}
+			}
 		}
 	}
-	return true, nil
 }
@@ -0,0 +1,69 @@
+package action
+
+import (
+	"context"
+	"regexp"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+)
+
+const actionReplaceOperate = "replace"
+
+func init() {
+	actionCreatorMap[actionReplaceOperate] = newActionReplace
+}
+
+type actionReplaceOptions struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
+// actionReplace renames outbounds by replacing the matches of a regular
+// expression in their tags.
+type actionReplace struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+func newActionReplace(b []byte) (providerAction, error) {
+	var options actionReplaceOptions
+	err := json.Unmarshal(b, &options)
+	if err != nil {
+		return nil, err
+	}
+	return newActionReplaceFromOptions(options.Pattern, options.Replacement)
+}
+
+func newActionReplaceFromOptions(pattern string, replacement string) (*actionReplace, error) {
+	if pattern == "" {
+		return nil, E.New("missing pattern")
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, E.Cause(err, "invalid pattern [", pattern, "]")
+	}
+	return &actionReplace{
+		pattern:     re,
+		replacement: replacement,
+	}, nil
+}
+
+func (a *actionReplace) execute(_ context.Context, _ adapter.Router, logger log.ContextLogger, groupContext *ProviderActionGroupContext) (bool, error) {
+	renameMap := make(map[string]string)
+	for _, outbound := range groupContext.outbounds {
+		newTag := a.pattern.ReplaceAllString(outbound.Tag, a.replacement)
+		if newTag == "" || newTag == outbound.Tag {
+			continue
+		}
+		logger.Debug("action[replace]: outbound: ", outbound.Tag, " -> ", newTag)
+		renameMap[outbound.Tag] = newTag
+		delete(groupContext.outboundMap, outbound.Tag)
+		groupContext.outboundMap[newTag] = outbound
+		outbound.Tag = newTag
+	}
+	renameGroupMembers(groupContext, renameMap)
+	return true, nil
+}
@@ -0,0 +1,88 @@
+package action
+
+import (
+	"context"
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionFlag(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		tag  string
+		flag string
+	}{
+		{"香港 01", "🇭🇰"},
+		{"HK-02", "🇭🇰"},
+		{"Hong Kong IPLC", "🇭🇰"},
+		{"US Los Angeles", "🇺🇸"},
+		{"日本 Tokyo", "🇯🇵"},
+		{"印度尼西亚 01", "🇮🇩"},
+		{"印度 01", "🇮🇳"},
+		{"台湾 -> 美国", "🇹🇼"},
+		{"RUSH 01", ""},
+		{"Remaining traffic", ""},
+	} {
+		require.Equal(t, testCase.flag, regionFlag(testCase.tag), testCase.tag)
+	}
+}
+
+func TestProviderActionGroup(t *testing.T) {
+	t.Parallel()
+	group, err := NewProviderActionGroup(option.ProviderOutboundOptions{
+		Filter:     []string{"HK", "JP"},
+		Exclude:    []string{"expire"},
+		Rename:     []option.ProviderOutboundRenameOptions{{Pattern: `\s*\|.*$`, Replacement: ""}},
+		RegionFlag: true,
+	})
+	require.NoError(t, err)
+	var outbounds []*option.Outbound
+	for _, tag := range []string{"HK 01 | 1x", "JP 01", "US 01", "HK expire 2026"} {
+		outbounds = append(outbounds, &option.Outbound{Type: C.TypeSOCKS, Tag: tag})
+	}
+	groupOutbounds := []*option.Outbound{
+		{Type: C.TypeRelay, Tag: "Chain", RelayOptions: option.RelayOutboundOptions{Outbounds: []string{"US 01", "JP 01"}}},
+		{Type: C.TypeSelector, Tag: "Proxy", SelectorOptions: option.SelectorOutboundOptions{Outbounds: []string{"HK 01 | 1x", "US 01", "Chain"}}},
+	}
+	groupContext, err := group.Execute(context.Background(), nil, log.NewNOPFactory().Logger(), outbounds, groupOutbounds)
+	require.NoError(t, err)
+	var tags []string
+	for _, outbound := range groupContext.Outbounds() {
+		tags = append(tags, outbound.Tag)
+	}
+	require.Equal(t, []string{"🇭🇰 HK 01", "🇯🇵 JP 01"}, tags)
+	require.Len(t, groupContext.GroupOutbounds(), 1)
+	require.Equal(t, []string{"🇭🇰 HK 01"}, groupContext.GroupOutbounds()[0].SelectorOptions.Outbounds)
+}
+
+func TestActionOverride(t *testing.T) {
+	t.Parallel()
+	group, err := NewProviderActionGroup(option.ProviderOutboundOptions{
+		Override: &option.ProviderOutboundOverrideOptions{
+			Detour:      "chain",
+			TCPFastOpen: common.Ptr(true),
+			TLS: &option.ProviderOutboundTLSOverrideOptions{
+				UTLS: &option.OutboundUTLSOptions{Enabled: true, Fingerprint: "chrome"},
+			},
+			Multiplex: &option.OutboundMultiplexOptions{Enabled: true},
+		},
+	})
+	require.NoError(t, err)
+	trojan := &option.Outbound{Type: C.TypeTrojan, Tag: "T"}
+	trojan.TrojanOptions.TLS = &option.OutboundTLSOptions{Enabled: true, ServerName: "example.com"}
+	socks := &option.Outbound{Type: C.TypeSOCKS, Tag: "S"}
+	_, err = group.Execute(context.Background(), nil, log.NewNOPFactory().Logger(), []*option.Outbound{trojan, socks}, nil)
+	require.NoError(t, err)
+	require.Equal(t, "chain", trojan.TrojanOptions.Detour)
+	require.True(t, trojan.TrojanOptions.TCPFastOpen)
+	require.Equal(t, "chrome", trojan.TrojanOptions.TLS.UTLS.Fingerprint)
+	require.Equal(t, "example.com", trojan.TrojanOptions.TLS.ServerName)
+	require.True(t, trojan.TrojanOptions.Multiplex.Enabled)
+	require.Equal(t, "chain", socks.SocksOptions.Detour)
+}
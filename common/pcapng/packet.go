@@ -0,0 +1,180 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"net/netip"
+	"sync"
+	"time"
+)
+
+const (
+	tcpFlagFIN = 0x01
+	tcpFlagSYN = 0x02
+	tcpFlagPSH = 0x08
+	tcpFlagACK = 0x10
+
+	maxSegmentSize = 65000
+)
+
+// Flow synthesizes raw IP packets for a proxied stream so that it can be
+// inspected as if it had been captured on the wire.
+type Flow struct {
+	access      sync.Mutex
+	writer      *Writer
+	source      netip.AddrPort
+	destination netip.AddrPort
+	clientSeq   uint32
+	serverSeq   uint32
+}
+
+func NewTCPFlow(writer *Writer, source netip.AddrPort, destination netip.AddrPort) *Flow {
+	source, destination = unifyAddrPort(source, destination)
+	flow := &Flow{
+		writer:      writer,
+		source:      source,
+		destination: destination,
+		clientSeq:   1,
+		serverSeq:   1,
+	}
+	now := time.Now()
+	flow.writeTCP(now, true, tcpFlagSYN, nil)
+	flow.writeTCP(now, false, tcpFlagSYN|tcpFlagACK, nil)
+	flow.writeTCP(now, true, tcpFlagACK, nil)
+	return flow
+}
+
+func NewUDPFlow(writer *Writer, source netip.AddrPort, destination netip.AddrPort) *Flow {
+	source, destination = unifyAddrPort(source, destination)
+	return &Flow{
+		writer:      writer,
+		source:      source,
+		destination: destination,
+	}
+}
+
+func (f *Flow) WriteTCP(fromClient bool, payload []byte) error {
+	f.access.Lock()
+	defer f.access.Unlock()
+	now := time.Now()
+	for len(payload) > 0 {
+		segment := payload
+		if len(segment) > maxSegmentSize {
+			segment = segment[:maxSegmentSize]
+		}
+		err := f.writeTCP(now, fromClient, tcpFlagPSH|tcpFlagACK, segment)
+		if err != nil {
+			return err
+		}
+		payload = payload[len(segment):]
+	}
+	return nil
+}
+
+func (f *Flow) CloseTCP() error {
+	f.access.Lock()
+	defer f.access.Unlock()
+	return f.writeTCP(time.Now(), true, tcpFlagFIN|tcpFlagACK, nil)
+}
+
+func (f *Flow) writeTCP(timestamp time.Time, fromClient bool, flags uint8, payload []byte) error {
+	var (
+		source      netip.AddrPort
+		destination netip.AddrPort
+		seq         *uint32
+		ack         uint32
+	)
+	if fromClient {
+		source, destination = f.source, f.destination
+		seq, ack = &f.clientSeq, f.serverSeq
+	} else {
+		source, destination = f.destination, f.source
+		seq, ack = &f.serverSeq, f.clientSeq
+	}
+	segment := make([]byte, 20+len(payload))
+	binary.BigEndian.PutUint16(segment[0:], source.Port())
+	binary.BigEndian.PutUint16(segment[2:], destination.Port())
+	binary.BigEndian.PutUint32(segment[4:], *seq)
+	if flags&tcpFlagACK != 0 {
+		binary.BigEndian.PutUint32(segment[8:], ack)
+	}
+	segment[12] = 5 << 4
+	segment[13] = flags
+	binary.BigEndian.PutUint16(segment[14:], 65535)
+	copy(segment[20:], payload)
+	*seq += uint32(len(payload))
+	if flags&(tcpFlagSYN|tcpFlagFIN) != 0 {
+		*seq++
+	}
+	return f.writer.WritePacket(timestamp, buildIPPacket(source.Addr(), destination.Addr(), 6, segment))
+}
+
+func (f *Flow) WriteUDP(fromClient bool, payload []byte) error {
+	source, destination := f.source, f.destination
+	if !fromClient {
+		source, destination = destination, source
+	}
+	datagram := make([]byte, 8+len(payload))
+	binary.BigEndian.PutUint16(datagram[0:], source.Port())
+	binary.BigEndian.PutUint16(datagram[2:], destination.Port())
+	binary.BigEndian.PutUint16(datagram[4:], uint16(len(datagram)))
+	copy(datagram[8:], payload)
+	return f.writer.WritePacket(time.Now(), buildIPPacket(source.Addr(), destination.Addr(), 17, datagram))
+}
+
+func buildIPPacket(source netip.Addr, destination netip.Addr, protocol uint8, payload []byte) []byte {
+	if source.Is4() {
+		packet := make([]byte, 20+len(payload))
+		packet[0] = 0x45
+		binary.BigEndian.PutUint16(packet[2:], uint16(len(packet)))
+		packet[8] = 64
+		packet[9] = protocol
+		sourceBytes := source.As4()
+		destinationBytes := destination.As4()
+		copy(packet[12:], sourceBytes[:])
+		copy(packet[16:], destinationBytes[:])
+		binary.BigEndian.PutUint16(packet[10:], ipv4Checksum(packet[:20]))
+		copy(packet[20:], payload)
+		return packet
+	}
+	packet := make([]byte, 40+len(payload))
+	packet[0] = 0x60
+	binary.BigEndian.PutUint16(packet[4:], uint16(len(payload)))
+	packet[6] = protocol
+	packet[7] = 64
+	sourceBytes := source.As16()
+	destinationBytes := destination.As16()
+	copy(packet[8:], sourceBytes[:])
+	copy(packet[24:], destinationBytes[:])
+	copy(packet[40:], payload)
+	return packet
+}
+
+func ipv4Checksum(header []byte) uint16 {
+	var sum uint32
+	for i := 0; i < len(header); i += 2 {
+		sum += uint32(binary.BigEndian.Uint16(header[i:]))
+	}
+	for sum > 0xffff {
+		sum = (sum >> 16) + (sum & 0xffff)
+	}
+	return ^uint16(sum)
+}
+
+func unifyAddrPort(source netip.AddrPort, destination netip.AddrPort) (netip.AddrPort, netip.AddrPort) {
+	sourceAddr, destinationAddr := source.Addr().Unmap(), destination.Addr().Unmap()
+	if !sourceAddr.IsValid() {
+		sourceAddr = netip.IPv4Unspecified()
+	}
+	if !destinationAddr.IsValid() {
+		if sourceAddr.Is4() {
+			destinationAddr = netip.IPv4Unspecified()
+		} else {
+			destinationAddr = netip.IPv6Unspecified()
+		}
+	}
+	if sourceAddr.Is4() != destinationAddr.Is4() {
+		sourceAddr = netip.AddrFrom16(sourceAddr.As16())
+		destinationAddr = netip.AddrFrom16(destinationAddr.As16())
+	}
+	return netip.AddrPortFrom(sourceAddr, source.Port()), netip.AddrPortFrom(destinationAddr, destination.Port())
+}
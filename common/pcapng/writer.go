@@ -0,0 +1,80 @@
+package pcapng
+
+import (
+	"encoding/binary"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	blockTypeSectionHeader         = 0x0A0D0D0A
+	blockTypeInterface             = 0x00000001
+	blockTypeEnhancedPacket        = 0x00000006
+	byteOrderMagic                 = 0x1A2B3C4D
+	LinkTypeRaw             uint16 = 101
+)
+
+type Writer struct {
+	access  sync.Mutex
+	writer  io.Writer
+	written int64
+}
+
+func NewWriter(writer io.Writer, linkType uint16) (*Writer, error) {
+	w := &Writer{writer: writer}
+	sectionHeader := make([]byte, 28)
+	binary.LittleEndian.PutUint32(sectionHeader[0:], blockTypeSectionHeader)
+	binary.LittleEndian.PutUint32(sectionHeader[4:], 28)
+	binary.LittleEndian.PutUint32(sectionHeader[8:], byteOrderMagic)
+	binary.LittleEndian.PutUint16(sectionHeader[12:], 1)
+	binary.LittleEndian.PutUint16(sectionHeader[14:], 0)
+	binary.LittleEndian.PutUint64(sectionHeader[16:], 0xFFFFFFFFFFFFFFFF)
+	binary.LittleEndian.PutUint32(sectionHeader[24:], 28)
+	err := w.write(sectionHeader)
+	if err != nil {
+		return nil, err
+	}
+	interfaceDescription := make([]byte, 20)
+	binary.LittleEndian.PutUint32(interfaceDescription[0:], blockTypeInterface)
+	binary.LittleEndian.PutUint32(interfaceDescription[4:], 20)
+	binary.LittleEndian.PutUint16(interfaceDescription[8:], linkType)
+	binary.LittleEndian.PutUint32(interfaceDescription[12:], 0)
+	binary.LittleEndian.PutUint32(interfaceDescription[16:], 20)
+	err = w.write(interfaceDescription)
+	if err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *Writer) write(content []byte) error {
+	n, err := w.writer.Write(content)
+	w.written += int64(n)
+	return err
+}
+
+func (w *Writer) WritePacket(timestamp time.Time, packet []byte) error {
+	padding := (4 - len(packet)%4) % 4
+	blockLength := 32 + len(packet) + padding
+	block := make([]byte, blockLength)
+	microseconds := uint64(timestamp.UnixMicro())
+	binary.LittleEndian.PutUint32(block[0:], blockTypeEnhancedPacket)
+	binary.LittleEndian.PutUint32(block[4:], uint32(blockLength))
+	binary.LittleEndian.PutUint32(block[8:], 0)
+	binary.LittleEndian.PutUint32(block[12:], uint32(microseconds>>32))
+	binary.LittleEndian.PutUint32(block[16:], uint32(microseconds))
+	binary.LittleEndian.PutUint32(block[20:], uint32(len(packet)))
+	binary.LittleEndian.PutUint32(block[24:], uint32(len(packet)))
+	copy(block[28:], packet)
+	binary.LittleEndian.PutUint32(block[blockLength-4:], uint32(blockLength))
+	w.access.Lock()
+	defer w.access.Unlock()
+	return w.write(block)
+}
+
+func (w *Writer) Written() int64 {
+	w.access.Lock()
+	defer w.access.Unlock()
+	return w.written
+}
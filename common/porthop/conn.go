@@ -0,0 +1,191 @@
+package porthop
+
+import (
+	"context"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const DefaultInterval = 30 * time.Second
+
+var _ N.Dialer = (*Dialer)(nil)
+
+// Dialer returns UDP connections that hop across ports of the destination
+// server, and dials TCP as is.
+type Dialer struct {
+	N.Dialer
+	ports    []uint16
+	interval time.Duration
+}
+
+func NewDialer(dialer N.Dialer, ports []uint16, interval time.Duration) *Dialer {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Dialer{dialer, ports, interval}
+}
+
+func (d *Dialer) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	if N.NetworkName(network) != N.NetworkUDP {
+		return d.Dialer.DialContext(ctx, network, destination)
+	}
+	return NewConn(ctx, d.Dialer, destination, d.ports, d.interval)
+}
+
+// Conn sends to a random port of the destination, switching to a new local
+// socket and port every interval. The previous socket keeps receiving until
+// the next hop so that packets in flight are not lost.
+type Conn struct {
+	ctx          context.Context
+	dialer       N.Dialer
+	destination  M.Socksaddr
+	ports        []uint16
+	remoteAddr   net.Addr
+	access       sync.Mutex
+	current      net.Conn
+	previous     net.Conn
+	packets      chan *buf.Buffer
+	readDeadline deadline
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+func NewConn(ctx context.Context, dialer N.Dialer, destination M.Socksaddr, ports []uint16, interval time.Duration) (*Conn, error) {
+	conn := &Conn{
+		ctx:          ctx,
+		dialer:       dialer,
+		destination:  destination,
+		ports:        ports,
+		packets:      make(chan *buf.Buffer, 256),
+		readDeadline: makeDeadline(),
+		done:         make(chan struct{}),
+	}
+	current, err := conn.dial()
+	if err != nil {
+		return nil, err
+	}
+	conn.current = current
+	conn.remoteAddr = current.RemoteAddr()
+	go conn.loopRead(current)
+	go conn.loopHop(interval)
+	return conn, nil
+}
+
+func (c *Conn) dial() (net.Conn, error) {
+	destination := c.destination
+	destination.Port = c.ports[rand.Intn(len(c.ports))]
+	return c.dialer.DialContext(c.ctx, N.NetworkUDP, destination)
+}
+
+func (c *Conn) loopHop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case <-ticker.C:
+		}
+		conn, err := c.dial()
+		if err != nil {
+			continue
+		}
+		c.access.Lock()
+		select {
+		case <-c.done:
+			c.access.Unlock()
+			conn.Close()
+			return
+		default:
+		}
+		if c.previous != nil {
+			c.previous.Close()
+		}
+		c.previous = c.current
+		c.current = conn
+		c.access.Unlock()
+		go c.loopRead(conn)
+	}
+}
+
+func (c *Conn) loopRead(conn net.Conn) {
+	for {
+		buffer := buf.NewPacket()
+		_, err := buffer.ReadOnceFrom(conn)
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		select {
+		case c.packets <- buffer:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *Conn) Read(p []byte) (n int, err error) {
+	select {
+	case buffer := <-c.packets:
+		n = copy(p, buffer.Bytes())
+		buffer.Release()
+		return
+	case <-c.done:
+		return 0, net.ErrClosed
+	case <-c.readDeadline.wait():
+		return 0, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *Conn) Write(p []byte) (n int, err error) {
+	c.access.Lock()
+	conn := c.current
+	c.access.Unlock()
+	return conn.Write(p)
+}
+
+func (c *Conn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+		c.access.Lock()
+		defer c.access.Unlock()
+		c.current.Close()
+		if c.previous != nil {
+			c.previous.Close()
+		}
+	})
+	return nil
+}
+
+func (c *Conn) LocalAddr() net.Addr {
+	c.access.Lock()
+	defer c.access.Unlock()
+	return c.current.LocalAddr()
+}
+
+// RemoteAddr stays the address of the first port, so that QUIC sees a single
+// path.
+func (c *Conn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *Conn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
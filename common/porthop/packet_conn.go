@@ -0,0 +1,139 @@
+package porthop
+
+import (
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+const routeTimeout = 5 * time.Minute
+
+type packet struct {
+	buffer *buf.Buffer
+	source net.Addr
+}
+
+type route struct {
+	conn     net.PacketConn
+	lastSeen time.Time
+}
+
+// PacketConn merges sockets listening on the ports a client hops across, and
+// replies to each client through the socket its last packet arrived on.
+type PacketConn struct {
+	conns        []net.PacketConn
+	access       sync.Mutex
+	routes       map[netip.AddrPort]route
+	packets      chan packet
+	readDeadline deadline
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+func NewPacketConn(conns []net.PacketConn) *PacketConn {
+	conn := &PacketConn{
+		conns:        conns,
+		routes:       make(map[netip.AddrPort]route),
+		packets:      make(chan packet, 256),
+		readDeadline: makeDeadline(),
+		done:         make(chan struct{}),
+	}
+	for _, packetConn := range conns {
+		go conn.loopRead(packetConn)
+	}
+	go conn.loopCleanup()
+	return conn
+}
+
+func (c *PacketConn) loopRead(conn net.PacketConn) {
+	for {
+		buffer := buf.NewPacket()
+		n, source, err := conn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		buffer.Truncate(n)
+		c.access.Lock()
+		c.routes[M.AddrPortFromNet(source)] = route{conn, time.Now()}
+		c.access.Unlock()
+		select {
+		case c.packets <- packet{buffer, source}:
+		case <-c.done:
+			buffer.Release()
+			return
+		}
+	}
+}
+
+func (c *PacketConn) loopCleanup() {
+	ticker := time.NewTicker(routeTimeout)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.done:
+			return
+		case now := <-ticker.C:
+			c.access.Lock()
+			for source, route := range c.routes {
+				if now.Sub(route.lastSeen) > routeTimeout {
+					delete(c.routes, source)
+				}
+			}
+			c.access.Unlock()
+		}
+	}
+}
+
+func (c *PacketConn) ReadFrom(p []byte) (n int, addr net.Addr, err error) {
+	select {
+	case packet := <-c.packets:
+		n = copy(p, packet.buffer.Bytes())
+		packet.buffer.Release()
+		return n, packet.source, nil
+	case <-c.done:
+		return 0, nil, net.ErrClosed
+	case <-c.readDeadline.wait():
+		return 0, nil, os.ErrDeadlineExceeded
+	}
+}
+
+func (c *PacketConn) WriteTo(p []byte, addr net.Addr) (n int, err error) {
+	c.access.Lock()
+	route, loaded := c.routes[M.AddrPortFromNet(addr)]
+	c.access.Unlock()
+	if !loaded {
+		return c.conns[0].WriteTo(p, addr)
+	}
+	return route.conn.WriteTo(p, addr)
+}
+
+func (c *PacketConn) Close() error {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	return common.Close(common.Map(c.conns, func(it net.PacketConn) any { return it })...)
+}
+
+func (c *PacketConn) LocalAddr() net.Addr {
+	return c.conns[0].LocalAddr()
+}
+
+func (c *PacketConn) SetDeadline(t time.Time) error {
+	return c.SetReadDeadline(t)
+}
+
+func (c *PacketConn) SetReadDeadline(t time.Time) error {
+	c.readDeadline.set(t)
+	return nil
+}
+
+func (c *PacketConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
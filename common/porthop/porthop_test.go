@@ -0,0 +1,89 @@
+package porthop
+
+import (
+	"context"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePorts(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		ports  []string
+		result []uint16
+	}{
+		{[]string{"443"}, []uint16{443}},
+		{[]string{"1000:1002", "443"}, []uint16{1000, 1001, 1002, 443}},
+		{[]string{"65535:65535"}, []uint16{65535}},
+		{[]string{"0"}, nil},
+		{[]string{"1002:1000"}, nil},
+		{[]string{"1000-1002"}, nil},
+		{[]string{"65536"}, nil},
+	} {
+		result, err := ParsePorts(testCase.ports)
+		if testCase.result == nil {
+			require.Error(t, err, testCase.ports)
+			continue
+		}
+		require.NoError(t, err, testCase.ports)
+		require.Equal(t, testCase.result, result)
+	}
+}
+
+func TestHop(t *testing.T) {
+	t.Parallel()
+	var (
+		serverConns []net.PacketConn
+		ports       []uint16
+	)
+	for i := 0; i < 3; i++ {
+		serverConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+		require.NoError(t, err)
+		serverConns = append(serverConns, serverConn)
+		ports = append(ports, M.SocksaddrFromNet(serverConn.LocalAddr()).Port)
+	}
+	server := NewPacketConn(serverConns)
+	defer server.Close()
+	sources := make(chan M.Socksaddr, 64)
+	go func() {
+		buffer := make([]byte, 64)
+		for {
+			n, source, err := server.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			sources <- M.SocksaddrFromNet(source)
+			server.WriteTo(buffer[:n], source)
+		}
+	}()
+
+	client, err := NewDialer(N.SystemDialer, ports, 50*time.Millisecond).DialContext(context.Background(), N.NetworkUDP, M.ParseSocksaddr("127.0.0.1:0"))
+	require.NoError(t, err)
+	defer client.Close()
+	remoteAddr := client.RemoteAddr()
+	seenSources := make(map[M.Socksaddr]bool)
+	buffer := make([]byte, 64)
+	for i := 0; i < 10; i++ {
+		_, err = client.Write([]byte("ping"))
+		require.NoError(t, err)
+		require.NoError(t, client.SetReadDeadline(time.Now().Add(time.Second)))
+		n, err := client.Read(buffer)
+		require.NoError(t, err)
+		require.Equal(t, "ping", string(buffer[:n]))
+		seenSources[<-sources] = true
+		time.Sleep(30 * time.Millisecond)
+	}
+	require.Equal(t, remoteAddr, client.RemoteAddr())
+	require.Greater(t, len(seenSources), 1)
+
+	require.NoError(t, client.SetReadDeadline(time.Now()))
+	_, err = client.Read(buffer)
+	require.ErrorIs(t, err, os.ErrDeadlineExceeded)
+}
@@ -0,0 +1,32 @@
+package porthop
+
+import (
+	"strconv"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// ParsePorts expands a list of ports and inclusive port ranges written as
+// "start:end".
+func ParsePorts(portList []string) ([]uint16, error) {
+	var ports []uint16
+	for _, portRange := range portList {
+		startString, endString, isRange := strings.Cut(portRange, ":")
+		start, err := strconv.ParseUint(startString, 10, 16)
+		if err != nil || start == 0 {
+			return nil, E.New("bad port range: ", portRange)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.ParseUint(endString, 10, 16)
+			if err != nil || end < start {
+				return nil, E.New("bad port range: ", portRange)
+			}
+		}
+		for port := start; port <= end; port++ {
+			ports = append(ports, uint16(port))
+		}
+	}
+	return ports, nil
+}
@@ -0,0 +1,39 @@
+package portmap
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"net/netip"
+	"os"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// defaultGateway reads the IPv4 default route from /proc/net/route.
+func defaultGateway() (netip.Addr, error) {
+	file, err := os.Open("/proc/net/route")
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer file.Close()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 3 || fields[1] != "00000000" {
+			continue
+		}
+		gatewayBytes, err := hex.DecodeString(fields[2])
+		if err != nil || len(gatewayBytes) != 4 {
+			continue
+		}
+		var gateway [4]byte
+		binary.LittleEndian.PutUint32(gateway[:], binary.BigEndian.Uint32(gatewayBytes))
+		if gateway == [4]byte{} {
+			continue
+		}
+		return netip.AddrFrom4(gateway), nil
+	}
+	return netip.Addr{}, E.New("default route not found")
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package portmap
+
+import (
+	"net/netip"
+	"os"
+)
+
+func defaultGateway() (netip.Addr, error) {
+	return netip.Addr{}, os.ErrInvalid
+}
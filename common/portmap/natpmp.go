@@ -0,0 +1,50 @@
+package portmap
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"time"
+
+	natpmp "github.com/jackpal/go-nat-pmp"
+)
+
+var _ client = (*natPMPClient)(nil)
+
+type natPMPClient struct {
+	client *natpmp.Client
+}
+
+func newNATPMPClient(gateway netip.Addr) *natPMPClient {
+	return &natPMPClient{
+		client: natpmp.NewClientWithTimeout(net.IP(gateway.AsSlice()), requestTimeout),
+	}
+}
+
+func (c *natPMPClient) Name() string {
+	return "NAT-PMP"
+}
+
+func (c *natPMPClient) Probe() error {
+	_, err := c.client.GetExternalAddress()
+	return err
+}
+
+func (c *natPMPClient) AddPortMapping(ctx context.Context, mapping Mapping, lifetime time.Duration) (netip.AddrPort, error) {
+	result, err := c.client.AddPortMapping(mapping.Network, int(mapping.InternalPort), int(mapping.ExternalPort), int(lifetime.Seconds()))
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	addressResult, err := c.client.GetExternalAddress()
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	return netip.AddrPortFrom(netip.AddrFrom4(addressResult.ExternalIPAddress), result.MappedExternalPort), nil
+}
+
+// DeletePortMapping requests a zero lifetime, as RFC 6886 defines for
+// removing a mapping.
+func (c *natPMPClient) DeletePortMapping(ctx context.Context, mapping Mapping) error {
+	_, err := c.client.AddPortMapping(mapping.Network, int(mapping.InternalPort), 0, 0)
+	return err
+}
@@ -0,0 +1,197 @@
+package portmap
+
+import (
+	"context"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const (
+	ProtocolUPnP   = "upnp"
+	ProtocolNATPMP = "nat-pmp"
+
+	defaultLifetime = time.Hour
+	retryInterval   = 30 * time.Second
+	requestTimeout  = 5 * time.Second
+)
+
+type Mapping struct {
+	Network      string
+	InternalPort uint16
+	ExternalPort uint16
+}
+
+type Options struct {
+	Context     context.Context
+	Logger      log.ContextLogger
+	Protocol    string
+	Gateway     netip.Addr
+	Mappings    []Mapping
+	Lifetime    time.Duration
+	Description string
+}
+
+type client interface {
+	Name() string
+	AddPortMapping(ctx context.Context, mapping Mapping, lifetime time.Duration) (netip.AddrPort, error)
+	DeletePortMapping(ctx context.Context, mapping Mapping) error
+}
+
+// Mapper requests port mappings from the local gateway and renews them at
+// half of their lifetime until closed.
+type Mapper struct {
+	ctx         context.Context
+	cancel      context.CancelFunc
+	logger      log.ContextLogger
+	protocol    string
+	gateway     netip.Addr
+	mappings    []Mapping
+	lifetime    time.Duration
+	description string
+	access      sync.Mutex
+	client      client
+	done        chan struct{}
+}
+
+func New(options Options) (*Mapper, error) {
+	switch options.Protocol {
+	case "", ProtocolUPnP, ProtocolNATPMP:
+	default:
+		return nil, E.New("unknown port mapping protocol: ", options.Protocol)
+	}
+	if options.Gateway.IsValid() && !options.Gateway.Is4() {
+		return nil, E.New("port mapping gateway must be IPv4")
+	}
+	lifetime := options.Lifetime
+	if lifetime <= 0 {
+		lifetime = defaultLifetime
+	}
+	mappings := make([]Mapping, 0, len(options.Mappings))
+	for _, mapping := range options.Mappings {
+		if mapping.ExternalPort == 0 {
+			mapping.ExternalPort = mapping.InternalPort
+		}
+		mappings = append(mappings, mapping)
+	}
+	ctx, cancel := context.WithCancel(options.Context)
+	return &Mapper{
+		ctx:         ctx,
+		cancel:      cancel,
+		logger:      options.Logger,
+		protocol:    options.Protocol,
+		gateway:     options.Gateway,
+		mappings:    mappings,
+		lifetime:    lifetime,
+		description: options.Description,
+		done:        make(chan struct{}),
+	}, nil
+}
+
+func (m *Mapper) Start() {
+	go m.loopMap()
+}
+
+func (m *Mapper) Close() error {
+	m.cancel()
+	<-m.done
+	m.access.Lock()
+	mappingClient := m.client
+	m.access.Unlock()
+	if mappingClient == nil {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	var errors []error
+	for _, mapping := range m.mappings {
+		err := mappingClient.DeletePortMapping(ctx, mapping)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "delete port mapping ", mapping.Network, "/", mapping.ExternalPort))
+		}
+	}
+	return E.Errors(errors...)
+}
+
+func (m *Mapper) loopMap() {
+	defer close(m.done)
+	for {
+		err := m.update()
+		interval := m.lifetime / 2
+		if err != nil {
+			m.logger.Error(E.Cause(err, "port mapping"))
+			interval = retryInterval
+			m.access.Lock()
+			m.client = nil
+			m.access.Unlock()
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+func (m *Mapper) update() error {
+	m.access.Lock()
+	mappingClient := m.client
+	m.access.Unlock()
+	if mappingClient == nil {
+		var err error
+		mappingClient, err = m.discover()
+		if err != nil {
+			return err
+		}
+		m.access.Lock()
+		m.client = mappingClient
+		m.access.Unlock()
+	}
+	for _, mapping := range m.mappings {
+		ctx, cancel := context.WithTimeout(m.ctx, requestTimeout)
+		externalAddr, err := mappingClient.AddPortMapping(ctx, mapping, m.lifetime)
+		cancel()
+		if err != nil {
+			return E.Cause(err, mappingClient.Name(), ": map ", mapping.Network, "/", mapping.InternalPort)
+		}
+		m.logger.Info(mappingClient.Name(), ": mapped ", mapping.Network, "/", mapping.InternalPort, " to ", externalAddr)
+	}
+	return nil
+}
+
+func (m *Mapper) discover() (client, error) {
+	var errors []error
+	if m.protocol == "" || m.protocol == ProtocolNATPMP {
+		gateway := m.gateway
+		if !gateway.IsValid() {
+			var err error
+			gateway, err = defaultGateway()
+			if err != nil {
+				errors = append(errors, E.Cause(err, "detect gateway"))
+			}
+		}
+		if gateway.IsValid() {
+			natPMPClient := newNATPMPClient(gateway)
+			err := natPMPClient.Probe()
+			if err == nil {
+				return natPMPClient, nil
+			}
+			errors = append(errors, E.Cause(err, natPMPClient.Name()))
+		}
+	}
+	if m.protocol == "" || m.protocol == ProtocolUPnP {
+		ctx, cancel := context.WithTimeout(m.ctx, requestTimeout)
+		upnpClient, err := discoverUPnP(ctx, m.gateway, m.description)
+		cancel()
+		if err == nil {
+			return upnpClient, nil
+		}
+		errors = append(errors, E.Cause(err, "UPnP"))
+	}
+	return nil, E.Errors(errors...)
+}
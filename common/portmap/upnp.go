@@ -0,0 +1,101 @@
+package portmap
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/huin/goupnp"
+	"github.com/huin/goupnp/dcps/internetgateway2"
+)
+
+var _ client = (*upnpClient)(nil)
+
+type upnpConnection interface {
+	GetServiceClient() *goupnp.ServiceClient
+	AddPortMappingCtx(ctx context.Context, NewRemoteHost string, NewExternalPort uint16, NewProtocol string, NewInternalPort uint16, NewInternalClient string, NewEnabled bool, NewPortMappingDescription string, NewLeaseDuration uint32) error
+	DeletePortMappingCtx(ctx context.Context, NewRemoteHost string, NewExternalPort uint16, NewProtocol string) error
+	GetExternalIPAddressCtx(ctx context.Context) (string, error)
+}
+
+type upnpClient struct {
+	connection  upnpConnection
+	description string
+}
+
+// discoverUPnP searches for an internet gateway device, preferring the one at
+// gateway if given.
+func discoverUPnP(ctx context.Context, gateway netip.Addr, description string) (*upnpClient, error) {
+	var connections []upnpConnection
+	ipConnections2, _, err := internetgateway2.NewWANIPConnection2ClientsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, connection := range ipConnections2 {
+		connections = append(connections, connection)
+	}
+	ipConnections1, _, err := internetgateway2.NewWANIPConnection1ClientsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, connection := range ipConnections1 {
+		connections = append(connections, connection)
+	}
+	pppConnections, _, err := internetgateway2.NewWANPPPConnection1ClientsCtx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, connection := range pppConnections {
+		connections = append(connections, connection)
+	}
+	if len(connections) == 0 {
+		return nil, E.New("no internet gateway device found")
+	}
+	if gateway.IsValid() {
+		for _, connection := range connections {
+			if M.ParseSocksaddr(connection.GetServiceClient().Location.Host).Addr == gateway {
+				return &upnpClient{connection, description}, nil
+			}
+		}
+		return nil, E.New("internet gateway device not found at ", gateway)
+	}
+	return &upnpClient{connections[0], description}, nil
+}
+
+func (c *upnpClient) Name() string {
+	return "UPnP"
+}
+
+func (c *upnpClient) AddPortMapping(ctx context.Context, mapping Mapping, lifetime time.Duration) (netip.AddrPort, error) {
+	localAddr := c.connection.GetServiceClient().LocalAddr()
+	if localAddr == nil {
+		return netip.AddrPort{}, E.New("unknown local address")
+	}
+	protocol := strings.ToUpper(mapping.Network)
+	err := c.connection.AddPortMappingCtx(ctx, "", mapping.ExternalPort, protocol, mapping.InternalPort, localAddr.String(), true, c.description, uint32(lifetime.Seconds()))
+	if err != nil {
+		// Some IGDv1 devices only accept permanent leases.
+		err = c.connection.AddPortMappingCtx(ctx, "", mapping.ExternalPort, protocol, mapping.InternalPort, localAddr.String(), true, c.description, 0)
+		if err != nil {
+			return netip.AddrPort{}, err
+		}
+	}
+	externalAddress, err := c.connection.GetExternalIPAddressCtx(ctx)
+	if err != nil {
+		return netip.AddrPort{}, err
+	}
+	externalIP := net.ParseIP(externalAddress)
+	if externalIP == nil {
+		return netip.AddrPort{}, E.New("invalid external address: ", externalAddress)
+	}
+	return netip.AddrPortFrom(M.AddrFromIP(externalIP), mapping.ExternalPort), nil
+}
+
+func (c *upnpClient) DeletePortMapping(ctx context.Context, mapping Mapping) error {
+	return c.connection.DeletePortMappingCtx(ctx, "", mapping.ExternalPort, strings.ToUpper(mapping.Network))
+}
@@ -20,15 +20,42 @@ var ErrNotFound = E.New("process not found")
 type Config struct {
 	Logger         log.ContextLogger
 	PackageManager tun.PackageManager
+	// SkipProcessPath resolves only the owning user where finding the
+	// process path is a separate search.
+	SkipProcessPath bool
 }
 
 type Info struct {
 	ProcessPath string
 	PackageName string
+	AppID       string
 	User        string
 	UserId      int32
 }
 
+// String returns the path, package name or app ID of the process, followed
+// by its user if known.
+func (i *Info) String() string {
+	var name string
+	if i.ProcessPath != "" {
+		name = i.ProcessPath
+	} else if i.PackageName != "" {
+		name = i.PackageName
+	} else if i.AppID != "" {
+		name = i.AppID
+	}
+	if name == "" {
+		if i.UserId != -1 {
+			name = F.ToString(i.UserId)
+		}
+	} else if i.User != "" {
+		name = F.ToString(name, " (", i.User, ")")
+	} else if i.UserId != -1 {
+		name = F.ToString(name, " (", i.UserId, ")")
+	}
+	return name
+}
+
 func FindProcessInfo(searcher Searcher, ctx context.Context, network string, source netip.AddrPort, destination netip.AddrPort) (*Info, error) {
 	info, err := searcher.FindProcessInfo(ctx, network, source, destination)
 	if err != nil {
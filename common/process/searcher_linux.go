@@ -12,11 +12,12 @@ import (
 var _ Searcher = (*linuxSearcher)(nil)
 
 type linuxSearcher struct {
-	logger log.ContextLogger
+	logger          log.ContextLogger
+	skipProcessPath bool
 }
 
 func NewSearcher(config Config) (Searcher, error) {
-	return &linuxSearcher{config.Logger}, nil
+	return &linuxSearcher{config.Logger, config.SkipProcessPath}, nil
 }
 
 func (s *linuxSearcher) FindProcessInfo(ctx context.Context, network string, source netip.AddrPort, destination netip.AddrPort) (*Info, error) {
@@ -24,9 +25,12 @@ func (s *linuxSearcher) FindProcessInfo(ctx context.Context, network string, sou
 	if err != nil {
 		return nil, err
 	}
-	processPath, err := resolveProcessNameByProcSearch(inode, uid)
-	if err != nil {
-		s.logger.DebugContext(ctx, "find process path: ", err)
+	var processPath string
+	if !s.skipProcessPath {
+		processPath, err = resolveProcessNameByProcSearch(inode, uid)
+		if err != nil {
+			s.logger.DebugContext(ctx, "find process path: ", err)
+		}
 	}
 	return &Info{
 		UserId:      int32(uid),
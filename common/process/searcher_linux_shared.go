@@ -187,7 +187,12 @@ func resolveProcessNameByProcSearch(inode, uid uint32) (string, error) {
 			}
 
 			if bytes.Equal(buffer[:n], socket) {
-				return os.Readlink(path.Join(processPath, "exe"))
+				executablePath, err := os.Readlink(path.Join(processPath, "exe"))
+				if err != nil {
+					return "", err
+				}
+				// the executable was replaced, e.g. by a package upgrade, while running
+				return strings.TrimSuffix(executablePath, " (deleted)"), nil
 			}
 		}
 	}
@@ -0,0 +1,140 @@
+package clash
+
+import (
+	"fmt"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+)
+
+type ClashProxyGroup struct {
+	Name      string   `yaml:"name"`
+	Type      string   `yaml:"type"`
+	Proxies   []string `yaml:"proxies"`
+	URL       string   `yaml:"url"`
+	Interval  int      `yaml:"interval"`
+	Tolerance uint16   `yaml:"tolerance"`
+	Strategy  string   `yaml:"strategy"`
+}
+
+// generateGroupOptions converts proxy groups with dependencies first, so
+// that nested groups are created before the groups using them. Members
+// that are neither a proxy nor a converted group, such as DIRECT or
+// proxies of proxy providers, are dropped, and so are groups left empty.
+func generateGroupOptions(groups []ClashProxyGroup, proxyTags map[string]bool) ([]option.Outbound, error) {
+	groupByName := make(map[string]*ClashProxyGroup, len(groups))
+	for i := range groups {
+		group := &groups[i]
+		if group.Name == "" {
+			return nil, fmt.Errorf("parse proxy group[%d] failed: missing name", i+1)
+		}
+		if proxyTags[group.Name] || groupByName[group.Name] != nil {
+			return nil, fmt.Errorf("parse proxy group `%s` failed: duplicate name", group.Name)
+		}
+		groupByName[group.Name] = group
+	}
+	var (
+		outbounds []option.Outbound
+		converted = make(map[string]bool)
+		visiting  = make(map[string]bool)
+		visited   = make(map[string]bool)
+		visit     func(group *ClashProxyGroup) error
+	)
+	visit = func(group *ClashProxyGroup) error {
+		if visited[group.Name] {
+			return nil
+		}
+		if visiting[group.Name] {
+			return fmt.Errorf("parse proxy group `%s` failed: loop detected", group.Name)
+		}
+		visiting[group.Name] = true
+		members := make([]string, 0, len(group.Proxies))
+		for _, member := range group.Proxies {
+			if memberGroup := groupByName[member]; memberGroup != nil {
+				err := visit(memberGroup)
+				if err != nil {
+					return err
+				}
+			}
+			if proxyTags[member] || converted[member] {
+				members = append(members, member)
+			}
+		}
+		visited[group.Name] = true
+		if len(members) == 0 {
+			return nil
+		}
+		outbound, loaded := groupOptions(group, members)
+		if loaded {
+			outbounds = append(outbounds, outbound)
+			converted[group.Name] = true
+		}
+		return nil
+	}
+	for i := range groups {
+		err := visit(&groups[i])
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outbounds, nil
+}
+
+func groupOptions(group *ClashProxyGroup, members []string) (option.Outbound, bool) {
+	interval := option.Duration(time.Duration(group.Interval) * time.Second)
+	switch group.Type {
+	case "select":
+		return option.Outbound{
+			Type: C.TypeSelector,
+			Tag:  group.Name,
+			SelectorOptions: option.SelectorOutboundOptions{
+				Outbounds: members,
+			},
+		}, true
+	case "url-test":
+		return option.Outbound{
+			Type: C.TypeURLTest,
+			Tag:  group.Name,
+			URLTestOptions: option.URLTestOutboundOptions{
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  interval,
+				Tolerance: group.Tolerance,
+			},
+		}, true
+	case "fallback":
+		return option.Outbound{
+			Type: C.TypeFallback,
+			Tag:  group.Name,
+			FallbackOptions: option.FallbackOutboundOptions{
+				Outbounds: members,
+				URL:       group.URL,
+				Interval:  interval,
+			},
+		}, true
+	case "load-balance":
+		strategy := C.LoadBalanceStrategyConsistentHashing
+		if group.Strategy == "round-robin" {
+			strategy = C.LoadBalanceStrategyRoundRobin
+		}
+		return option.Outbound{
+			Type: C.TypeLoadBalance,
+			Tag:  group.Name,
+			LoadBalanceOptions: option.LoadBalanceOutboundOptions{
+				Outbounds: members,
+				Strategy:  strategy,
+			},
+		}, true
+	case "relay":
+		return option.Outbound{
+			Type: C.TypeRelay,
+			Tag:  group.Name,
+			RelayOptions: option.RelayOutboundOptions{
+				Outbounds: members,
+			},
+		}, true
+	default:
+		return option.Outbound{}, false
+	}
+}
@@ -15,7 +15,8 @@ type ClashProxyInterface interface {
 }
 
 type ClashConfig struct {
-	Proxies []ClashProxy `yaml:"proxies"`
+	Proxies     []ClashProxy      `yaml:"proxies"`
+	ProxyGroups []ClashProxyGroup `yaml:"proxy-groups"`
 }
 
 const (
@@ -114,13 +115,19 @@ func ParseClashConfig(raw []byte) ([]option.Outbound, error) {
 	if config.Proxies == nil || len(config.Proxies) == 0 {
 		return nil, fmt.Errorf("no outbounds found in clash config")
 	}
-	m := make([]option.Outbound, 0, len(config.Proxies))
+	m := make([]option.Outbound, 0, len(config.Proxies)+len(config.ProxyGroups))
+	proxyTags := make(map[string]bool, len(config.Proxies))
 	for i, proxy := range config.Proxies {
 		options, err := proxy.Proxy.GenerateOptions()
 		if err != nil {
 			return nil, fmt.Errorf("parse outbound[%d], tag: `%s` failed: %s", i+1, proxy.Proxy.Tag(), err)
 		}
 		m = append(m, *options)
+		proxyTags[options.Tag] = true
 	}
-	return m, nil
+	groups, err := generateGroupOptions(config.ProxyGroups, proxyTags)
+	if err != nil {
+		return nil, err
+	}
+	return append(m, groups...), nil
 }
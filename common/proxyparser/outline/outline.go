@@ -0,0 +1,47 @@
+package outline
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/sagernet/sing-box/common/proxyparser/utils"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+)
+
+// Outline dynamic access key, as served for ssconf:// links.
+
+type AccessKey struct {
+	Server     string `json:"server"`
+	ServerPort uint16 `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+}
+
+func ParseOutlineConfig(raw []byte) ([]option.Outbound, error) {
+	var accessKey AccessKey
+	err := json.Unmarshal(raw, &accessKey)
+	if err != nil {
+		return nil, err
+	}
+	if accessKey.Server == "" || accessKey.ServerPort == 0 {
+		return nil, fmt.Errorf("no server found in Outline access key")
+	}
+	if !utils.CheckShadowsocksMethod(accessKey.Method) {
+		return nil, fmt.Errorf("invalid method: %s", accessKey.Method)
+	}
+	return []option.Outbound{{
+		Type: C.TypeShadowsocks,
+		Tag:  net.JoinHostPort(accessKey.Server, strconv.Itoa(int(accessKey.ServerPort))),
+		ShadowsocksOptions: option.ShadowsocksOutboundOptions{
+			ServerOptions: option.ServerOptions{
+				Server:     accessKey.Server,
+				ServerPort: accessKey.ServerPort,
+			},
+			Method:   accessKey.Method,
+			Password: accessKey.Password,
+		},
+	}}, nil
+}
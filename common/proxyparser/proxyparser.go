@@ -1,29 +1,46 @@
 package proxyparser
 
 import (
+	"mime"
+
 	"github.com/sagernet/sing-box/common/proxyparser/clash"
+	"github.com/sagernet/sing-box/common/proxyparser/outline"
 	"github.com/sagernet/sing-box/common/proxyparser/raw"
 	"github.com/sagernet/sing-box/common/proxyparser/singbox"
+	"github.com/sagernet/sing-box/common/proxyparser/sip008"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
-func ParseOutbound(content []byte) ([]option.Outbound, error) {
+// ParseOutbound detects the subscription format by payload shape. A JSON
+// content type limits detection to the JSON formats.
+func ParseOutbound(content []byte, contentType string) ([]option.Outbound, error) {
 	var (
-		outbounds        []option.Outbound
-		err1, err2, err3 error
+		outbounds                    []option.Outbound
+		err1, err2, err3, err4, err5 error
 	)
 	outbounds, err1 = singbox.ParseSingboxConfig(content)
 	if err1 == nil {
 		return outbounds, nil
 	}
-	outbounds, err2 = clash.ParseClashConfig(content)
+	outbounds, err2 = sip008.ParseSIP008Config(content)
 	if err2 == nil {
 		return outbounds, nil
 	}
-	outbounds, err3 = raw.ParseRawConfig(content)
+	outbounds, err3 = outline.ParseOutlineConfig(content)
 	if err3 == nil {
 		return outbounds, nil
 	}
-	return nil, E.New("parse config failed: sing-box: ", err1, " | clash: ", err2, " | raw: ", err3)
+	if mediaType, _, _ := mime.ParseMediaType(contentType); mediaType == "application/json" {
+		return nil, E.New("parse config failed: sing-box: ", err1, " | sip008: ", err2, " | outline: ", err3)
+	}
+	outbounds, err4 = clash.ParseClashConfig(content)
+	if err4 == nil {
+		return outbounds, nil
+	}
+	outbounds, err5 = raw.ParseRawConfig(content)
+	if err5 == nil {
+		return outbounds, nil
+	}
+	return nil, E.New("parse config failed: sing-box: ", err1, " | sip008: ", err2, " | outline: ", err3, " | clash: ", err4, " | raw: ", err5)
 }
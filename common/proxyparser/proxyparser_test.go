@@ -0,0 +1,113 @@
+package proxyparser
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseOutbound(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name        string
+		content     string
+		contentType string
+		tags        []string
+	}{
+		{
+			"sip008",
+			`{"version":1,"servers":[{"id":"1","remarks":"A","server":"192.0.2.1","server_port":8388,"password":"p","method":"aes-128-gcm"},{"server":"192.0.2.2","server_port":8389,"password":"p","method":"chacha20-ietf-poly1305","plugin":"obfs-local","plugin_opts":"obfs=http"}],"bytes_used":1}`,
+			"application/json; charset=utf-8",
+			[]string{"A", "192.0.2.2:8389"},
+		},
+		{
+			"outline",
+			`{"server":"192.0.2.1","server_port":443,"password":"p","method":"chacha20-ietf-poly1305","prefix":"\u0016\u0003\u0001"}`,
+			"text/plain",
+			[]string{"192.0.2.1:443"},
+		},
+		{
+			"sing-box",
+			`{"outbounds":[{"type":"socks","tag":"S","server":"192.0.2.1","server_port":1080}]}`,
+			"",
+			[]string{"S"},
+		},
+	} {
+		outbounds, err := ParseOutbound([]byte(testCase.content), testCase.contentType)
+		require.NoError(t, err, testCase.name)
+		var tags []string
+		for _, outbound := range outbounds {
+			tags = append(tags, outbound.Tag)
+		}
+		require.Equal(t, testCase.tags, tags, testCase.name)
+	}
+	outbounds, err := ParseOutbound([]byte(`{"version":1,"servers":[{"server":"192.0.2.2","server_port":8389,"password":"p","method":"chacha20-ietf-poly1305","plugin":"obfs-local","plugin_opts":"obfs=http"}]}`), "")
+	require.NoError(t, err)
+	require.Equal(t, C.TypeShadowsocks, outbounds[0].Type)
+	require.Equal(t, "obfs-local", outbounds[0].ShadowsocksOptions.Plugin)
+	require.Equal(t, "obfs=http", outbounds[0].ShadowsocksOptions.PluginOptions)
+}
+
+func TestParseOutboundError(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name        string
+		content     string
+		contentType string
+	}{
+		{"empty sip008", `{"version":1,"servers":[]}`, "application/json"},
+		{"invalid method", `{"server":"192.0.2.1","server_port":443,"password":"p","method":"none-such"}`, "application/json"},
+		{"json only", `proxies: [{name: A, type: socks5, server: 192.0.2.1, port: 1080}]`, "application/json"},
+	} {
+		_, err := ParseOutbound([]byte(testCase.content), testCase.contentType)
+		require.Error(t, err, testCase.name)
+	}
+	_, err := ParseOutbound([]byte(`proxies: [{name: A, type: socks5, server: 192.0.2.1, port: 1080}]`), "text/yaml")
+	require.NoError(t, err)
+}
+
+func TestParseClashProxyGroups(t *testing.T) {
+	t.Parallel()
+	content := `
+proxies:
+  - {name: A, type: socks5, server: 192.0.2.1, port: 1080}
+  - {name: B, type: socks5, server: 192.0.2.2, port: 1080}
+proxy-groups:
+  - {name: Proxy, type: select, proxies: [Auto, Backup, DIRECT, A]}
+  - {name: Auto, type: url-test, proxies: [A, B], url: "https://www.gstatic.com/generate_204", interval: 300, tolerance: 50}
+  - {name: Backup, type: fallback, proxies: [Chain, Missing]}
+  - {name: Chain, type: relay, proxies: [A, B]}
+  - {name: Balance, type: load-balance, proxies: [A, B], strategy: round-robin}
+  - {name: Empty, type: select, proxies: [DIRECT]}
+  - {name: Unknown, type: none-such, proxies: [A]}
+  - {name: Other, type: select, proxies: [Unknown, B]}
+`
+	outbounds, err := ParseOutbound([]byte(content), "")
+	require.NoError(t, err)
+	var tags []string
+	for _, outbound := range outbounds {
+		tags = append(tags, outbound.Tag)
+	}
+	require.Equal(t, []string{"A", "B", "Auto", "Chain", "Backup", "Proxy", "Balance", "Other"}, tags)
+	require.Equal(t, C.TypeURLTest, outbounds[2].Type)
+	require.Equal(t, []string{"A", "B"}, outbounds[2].URLTestOptions.Outbounds)
+	require.Equal(t, uint16(50), outbounds[2].URLTestOptions.Tolerance)
+	require.Equal(t, C.TypeRelay, outbounds[3].Type)
+	require.Equal(t, C.TypeFallback, outbounds[4].Type)
+	require.Equal(t, []string{"Chain"}, outbounds[4].FallbackOptions.Outbounds)
+	require.Equal(t, C.TypeSelector, outbounds[5].Type)
+	require.Equal(t, []string{"Auto", "Backup", "A"}, outbounds[5].SelectorOptions.Outbounds)
+	require.Equal(t, C.LoadBalanceStrategyRoundRobin, outbounds[6].LoadBalanceOptions.Strategy)
+	require.Equal(t, []string{"B"}, outbounds[7].SelectorOptions.Outbounds)
+
+	_, err = ParseOutbound([]byte(`
+proxies:
+  - {name: A, type: socks5, server: 192.0.2.1, port: 1080}
+proxy-groups:
+  - {name: X, type: select, proxies: [Y, A]}
+  - {name: Y, type: select, proxies: [X]}
+`), "")
+	require.Error(t, err)
+}
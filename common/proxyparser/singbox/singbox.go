@@ -25,7 +25,7 @@ func ParseSingboxConfig(raw []byte) ([]option.Outbound, error) {
 	for _, outboundOptions := range outboundConfig.Outbounds {
 		switch outboundOptions.Type {
 		// TODO: Remove Direct ???
-		case C.TypeBlock, C.TypeDNS, C.TypeURLTest, C.TypeSelector:
+		case C.TypeBlock, C.TypeDNS, C.TypeURLTest, C.TypeSelector, C.TypeLoadBalance, C.TypeFallback, C.TypeRelay:
 			continue
 		default:
 			// TODO: Remove Detour ???
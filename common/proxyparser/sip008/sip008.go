@@ -0,0 +1,72 @@
+package sip008
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+
+	"github.com/sagernet/sing-box/common/proxyparser/utils"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+)
+
+// SIP008 online configuration https://shadowsocks.org/doc/sip008.html
+
+type Config struct {
+	Version int      `json:"version"`
+	Servers []Server `json:"servers"`
+}
+
+type Server struct {
+	ID         string `json:"id"`
+	Remarks    string `json:"remarks"`
+	Server     string `json:"server"`
+	ServerPort uint16 `json:"server_port"`
+	Password   string `json:"password"`
+	Method     string `json:"method"`
+	Plugin     string `json:"plugin"`
+	PluginOpts string `json:"plugin_opts"`
+}
+
+func ParseSIP008Config(raw []byte) ([]option.Outbound, error) {
+	var config Config
+	err := json.Unmarshal(raw, &config)
+	if err != nil {
+		return nil, err
+	}
+	if config.Servers == nil {
+		return nil, fmt.Errorf("no servers found in SIP008 config")
+	}
+	var options []option.Outbound
+	for i, server := range config.Servers {
+		if server.Server == "" || server.ServerPort == 0 {
+			return nil, fmt.Errorf("parse server[%d] failed: invalid address", i)
+		}
+		if !utils.CheckShadowsocksMethod(server.Method) {
+			return nil, fmt.Errorf("parse server[%d] failed: invalid method: %s", i, server.Method)
+		}
+		outboundOptions := option.Outbound{
+			Type: C.TypeShadowsocks,
+			Tag:  server.Remarks,
+			ShadowsocksOptions: option.ShadowsocksOutboundOptions{
+				ServerOptions: option.ServerOptions{
+					Server:     server.Server,
+					ServerPort: server.ServerPort,
+				},
+				Method:        server.Method,
+				Password:      server.Password,
+				Plugin:        server.Plugin,
+				PluginOptions: server.PluginOpts,
+			},
+		}
+		if outboundOptions.Tag == "" {
+			outboundOptions.Tag = net.JoinHostPort(server.Server, strconv.Itoa(int(server.ServerPort)))
+		}
+		options = append(options, outboundOptions)
+	}
+	if len(options) == 0 {
+		return nil, fmt.Errorf("no servers found in SIP008 config")
+	}
+	return options, nil
+}
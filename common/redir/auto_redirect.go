@@ -0,0 +1,213 @@
+package redir
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+const (
+	DefaultTProxyTableIndex = 2024
+
+	autoRedirectTableRedirect = "sing-box-redirect"
+	autoRedirectTableTProxy   = "sing-box-tproxy"
+	autoRedirectChainRedirect = "SING_BOX_REDIRECT"
+	autoRedirectChainTProxy   = "SING_BOX_TPROXY"
+)
+
+var (
+	autoRedirectExcludeInet4 = []string{
+		"0.0.0.0/8",
+		"10.0.0.0/8",
+		"100.64.0.0/10",
+		"127.0.0.0/8",
+		"169.254.0.0/16",
+		"172.16.0.0/12",
+		"192.168.0.0/16",
+		"224.0.0.0/4",
+		"240.0.0.0/4",
+	}
+	autoRedirectExcludeInet6 = []string{
+		"::1/128",
+		"fc00::/7",
+		"fe80::/10",
+		"ff00::/8",
+	}
+)
+
+// AutoRedirectOptions describes the firewall rules that send forwarded and
+// local traffic to a redirect or tproxy inbound. Traffic to local and private
+// addresses is excluded, and local traffic with OutputMark, which is set on
+// connections of outbounds, is excluded to avoid loops.
+type AutoRedirectOptions struct {
+	TProxy          bool
+	Port            uint16
+	Inet4           bool
+	Inet6           bool
+	TCP             bool
+	UDP             bool
+	InputMark       uint32
+	OutputMark      uint32
+	TableIndex      int
+	DisableNFTables bool
+}
+
+func (o *AutoRedirectOptions) tableName() string {
+	if o.TProxy {
+		return autoRedirectTableTProxy
+	}
+	return autoRedirectTableRedirect
+}
+
+func (o *AutoRedirectOptions) protocols() []string {
+	var protocols []string
+	if o.TCP {
+		protocols = append(protocols, "tcp")
+	}
+	if o.UDP && o.TProxy {
+		protocols = append(protocols, "udp")
+	}
+	return protocols
+}
+
+// nftablesScript returns the script for `nft -f -` creating the table.
+func (o *AutoRedirectOptions) nftablesScript() string {
+	var (
+		builder   strings.Builder
+		protocols = "meta l4proto { " + strings.Join(o.protocols(), ", ") + " }"
+	)
+	writeExcludes := func() {
+		if !o.Inet4 {
+			builder.WriteString("\t\tmeta nfproto ipv4 return\n")
+		}
+		if !o.Inet6 {
+			builder.WriteString("\t\tmeta nfproto ipv6 return\n")
+		}
+		builder.WriteString("\t\tfib daddr type local return\n")
+		builder.WriteString("\t\tip daddr { " + strings.Join(autoRedirectExcludeInet4, ", ") + " } return\n")
+		builder.WriteString("\t\tip6 daddr { " + strings.Join(autoRedirectExcludeInet6, ", ") + " } return\n")
+	}
+	builder.WriteString("table inet " + o.tableName() + " {\n")
+	builder.WriteString("\tchain prerouting {\n")
+	if o.TProxy {
+		builder.WriteString("\t\ttype filter hook prerouting priority mangle; policy accept;\n")
+		writeExcludes()
+		builder.WriteString(F.ToString("\t\t", protocols, " tproxy to :", o.Port, " meta mark set ", netfilter.FormatMark(o.InputMark), " accept\n"))
+	} else {
+		builder.WriteString("\t\ttype nat hook prerouting priority dstnat; policy accept;\n")
+		writeExcludes()
+		builder.WriteString(F.ToString("\t\t", protocols, " redirect to :", o.Port, "\n"))
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("\tchain output {\n")
+	if o.TProxy {
+		builder.WriteString("\t\ttype route hook output priority mangle; policy accept;\n")
+	} else {
+		builder.WriteString("\t\ttype nat hook output priority -100; policy accept;\n")
+	}
+	builder.WriteString("\t\tmeta mark " + netfilter.FormatMark(o.OutputMark) + " return\n")
+	if o.TProxy {
+		builder.WriteString("\t\tct direction reply return\n")
+	}
+	writeExcludes()
+	if o.TProxy {
+		builder.WriteString("\t\t" + protocols + " meta mark set " + netfilter.FormatMark(o.InputMark) + "\n")
+	} else {
+		builder.WriteString(F.ToString("\t\t", protocols, " redirect to :", o.Port, "\n"))
+	}
+	builder.WriteString("\t}\n")
+	builder.WriteString("}\n")
+	return builder.String()
+}
+
+// iptablesCommands returns the commands creating the chains with iptables
+// and ip6tables, and the commands removing them.
+func (o *AutoRedirectOptions) iptablesCommands() (setup []netfilter.Command, cleanup []netfilter.Command) {
+	table, chain := "nat", autoRedirectChainRedirect
+	if o.TProxy {
+		table, chain = "mangle", autoRedirectChainTProxy
+	}
+	outputChain := chain + "_OUTPUT"
+	for _, family := range []struct {
+		enabled  bool
+		name     string
+		excludes []string
+	}{
+		{o.Inet4, "iptables", autoRedirectExcludeInet4},
+		{o.Inet6, "ip6tables", autoRedirectExcludeInet6},
+	} {
+		if !family.enabled {
+			continue
+		}
+		command := func(arguments ...string) netfilter.Command {
+			return netfilter.Command{Name: family.name, Arguments: append([]string{"-t", table}, arguments...)}
+		}
+		excludes := func(chain string) {
+			setup = append(setup, command("-A", chain, "-m", "addrtype", "--dst-type", "LOCAL", "-j", "RETURN"))
+			for _, prefix := range family.excludes {
+				setup = append(setup, command("-A", chain, "-d", prefix, "-j", "RETURN"))
+			}
+		}
+		setup = append(setup, command("-N", chain))
+		excludes(chain)
+		for _, protocol := range o.protocols() {
+			if o.TProxy {
+				setup = append(setup, command("-A", chain, "-p", protocol, "-j", "TPROXY", "--on-port", F.ToString(o.Port), "--tproxy-mark", netfilter.FormatMark(o.InputMark)))
+			} else {
+				setup = append(setup, command("-A", chain, "-p", protocol, "-j", "REDIRECT", "--to-ports", F.ToString(o.Port)))
+			}
+		}
+		setup = append(setup, command("-N", outputChain))
+		setup = append(setup, command("-A", outputChain, "-m", "mark", "--mark", netfilter.FormatMark(o.OutputMark), "-j", "RETURN"))
+		if o.TProxy {
+			setup = append(setup, command("-A", outputChain, "-m", "conntrack", "--ctdir", "REPLY", "-j", "RETURN"))
+			excludes(outputChain)
+			for _, protocol := range o.protocols() {
+				setup = append(setup, command("-A", outputChain, "-p", protocol, "-j", "MARK", "--set-mark", netfilter.FormatMark(o.InputMark)))
+			}
+		} else {
+			setup = append(setup, command("-A", outputChain, "-j", chain))
+		}
+		setup = append(setup, command("-A", "PREROUTING", "-j", chain))
+		setup = append(setup, command("-A", "OUTPUT", "-j", outputChain))
+		cleanup = append(cleanup,
+			command("-D", "PREROUTING", "-j", chain),
+			command("-D", "OUTPUT", "-j", outputChain),
+			command("-F", outputChain),
+			command("-X", outputChain),
+			command("-F", chain),
+			command("-X", chain),
+		)
+	}
+	return
+}
+
+// routeCommands returns the commands routing packets with the tproxy mark to
+// the local interface, and the commands removing them.
+func (o *AutoRedirectOptions) routeCommands() (setup []netfilter.Command, cleanup []netfilter.Command) {
+	if !o.TProxy {
+		return
+	}
+	tableIndex := F.ToString(o.TableIndex)
+	for _, family := range []struct {
+		enabled bool
+		flag    string
+	}{
+		{o.Inet4, "-4"},
+		{o.Inet6, "-6"},
+	} {
+		if !family.enabled {
+			continue
+		}
+		setup = append(setup,
+			netfilter.Command{Name: "ip", Arguments: []string{family.flag, "rule", "add", "fwmark", netfilter.FormatMark(o.InputMark), "table", tableIndex}},
+			netfilter.Command{Name: "ip", Arguments: []string{family.flag, "route", "add", "local", "default", "dev", "lo", "table", tableIndex}},
+		)
+		cleanup = append(cleanup,
+			netfilter.Command{Name: "ip", Arguments: []string{family.flag, "rule", "del", "fwmark", netfilter.FormatMark(o.InputMark), "table", tableIndex}},
+			netfilter.Command{Name: "ip", Arguments: []string{family.flag, "route", "del", "local", "default", "dev", "lo", "table", tableIndex}},
+		)
+	}
+	return
+}
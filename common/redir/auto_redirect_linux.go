@@ -0,0 +1,86 @@
+package redir
+
+import (
+	"os/exec"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type AutoRedirect struct {
+	options     AutoRedirectOptions
+	useNFTables bool
+	started     bool
+}
+
+func NewAutoRedirect(options AutoRedirectOptions) (*AutoRedirect, error) {
+	autoRedirect := &AutoRedirect{options: options}
+	if !options.DisableNFTables {
+		_, err := exec.LookPath("nft")
+		autoRedirect.useNFTables = err == nil
+	}
+	var requiredCommands []string
+	if !autoRedirect.useNFTables {
+		if options.Inet4 {
+			requiredCommands = append(requiredCommands, "iptables")
+		}
+		if options.Inet6 {
+			requiredCommands = append(requiredCommands, "ip6tables")
+		}
+	}
+	if options.TProxy {
+		requiredCommands = append(requiredCommands, "ip")
+	}
+	for _, name := range requiredCommands {
+		_, err := exec.LookPath(name)
+		if err != nil {
+			return nil, E.Cause(err, "find ", name)
+		}
+	}
+	return autoRedirect, nil
+}
+
+func (r *AutoRedirect) Start() error {
+	err := netfilter.Install(r.setup, r.cleanup)
+	if err != nil {
+		return err
+	}
+	r.started = true
+	return nil
+}
+
+func (r *AutoRedirect) Close() error {
+	if !r.started {
+		return nil
+	}
+	r.started = false
+	return r.cleanup()
+}
+
+func (r *AutoRedirect) setup() error {
+	if r.useNFTables {
+		err := netfilter.Run(netfilter.Command{Name: "nft", Arguments: []string{"-f", "-"}}, r.options.nftablesScript())
+		if err != nil {
+			return err
+		}
+	} else {
+		setup, _ := r.options.iptablesCommands()
+		err := netfilter.RunAll(setup)
+		if err != nil {
+			return err
+		}
+	}
+	setup, _ := r.options.routeCommands()
+	return netfilter.RunAll(setup)
+}
+
+func (r *AutoRedirect) cleanup() error {
+	var commands []netfilter.Command
+	if r.useNFTables {
+		commands = append(commands, netfilter.Command{Name: "nft", Arguments: []string{"delete", "table", "inet", r.options.tableName()}})
+	} else {
+		_, commands = r.options.iptablesCommands()
+	}
+	_, routeCleanup := r.options.routeCommands()
+	return netfilter.RunEach(append(commands, routeCleanup...))
+}
@@ -0,0 +1,23 @@
+//go:build !linux
+
+package redir
+
+import (
+	"os"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type AutoRedirect struct{}
+
+func NewAutoRedirect(options AutoRedirectOptions) (*AutoRedirect, error) {
+	return nil, E.New("only supported on Linux")
+}
+
+func (r *AutoRedirect) Start() error {
+	return os.ErrInvalid
+}
+
+func (r *AutoRedirect) Close() error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package redir
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/netfilter"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAutoRedirectNFTablesScript(t *testing.T) {
+	t.Parallel()
+	redirect := AutoRedirectOptions{Port: 7892, Inet4: true, TCP: true, UDP: true, OutputMark: 0x2024}
+	script := redirect.nftablesScript()
+	require.True(t, strings.HasPrefix(script, "table inet sing-box-redirect {\n"))
+	require.Contains(t, script, "type nat hook prerouting priority dstnat;")
+	require.Contains(t, script, "\t\tmeta nfproto ipv6 return\n")
+	require.Contains(t, script, "\t\tmeta mark 0x2024 return\n")
+	require.Contains(t, script, "\t\tmeta l4proto { tcp } redirect to :7892\n")
+	require.NotContains(t, script, "udp")
+
+	tproxy := AutoRedirectOptions{TProxy: true, Port: 7893, Inet4: true, Inet6: true, TCP: true, UDP: true, InputMark: 0x2023, OutputMark: 0x2024}
+	script = tproxy.nftablesScript()
+	require.True(t, strings.HasPrefix(script, "table inet sing-box-tproxy {\n"))
+	require.NotContains(t, script, "meta nfproto")
+	require.Contains(t, script, "\t\tmeta l4proto { tcp, udp } tproxy to :7893 meta mark set 0x2023 accept\n")
+	require.Contains(t, script, "\t\tct direction reply return\n")
+	require.Contains(t, script, "\t\tmeta l4proto { tcp, udp } meta mark set 0x2023\n")
+}
+
+func TestAutoRedirectIPTablesCommands(t *testing.T) {
+	t.Parallel()
+	tproxy := AutoRedirectOptions{TProxy: true, Port: 7893, Inet4: true, UDP: true, InputMark: 0x2023, OutputMark: 0x2024, TableIndex: 2024}
+	setup, cleanup := tproxy.iptablesCommands()
+	for _, command := range append(setup, cleanup...) {
+		require.Equal(t, "iptables", command.Name)
+		require.Equal(t, []string{"-t", "mangle"}, command.Arguments[:2])
+	}
+	require.Contains(t, setup, netfilter.Command{Name: "iptables", Arguments: []string{"-t", "mangle", "-A", "SING_BOX_TPROXY", "-p", "udp", "-j", "TPROXY", "--on-port", "7893", "--tproxy-mark", "0x2023"}})
+	require.Equal(t, netfilter.Command{Name: "iptables", Arguments: []string{"-t", "mangle", "-A", "OUTPUT", "-j", "SING_BOX_TPROXY_OUTPUT"}}, setup[len(setup)-1])
+	require.Len(t, cleanup, 6)
+	setup, cleanup = tproxy.routeCommands()
+	require.Equal(t, []netfilter.Command{
+		{Name: "ip", Arguments: []string{"-4", "rule", "add", "fwmark", "0x2023", "table", "2024"}},
+		{Name: "ip", Arguments: []string{"-4", "route", "add", "local", "default", "dev", "lo", "table", "2024"}},
+	}, setup)
+	require.Len(t, cleanup, 2)
+
+	redirect := AutoRedirectOptions{Port: 7892, Inet4: true, Inet6: true, TCP: true, OutputMark: 0x2024}
+	setup, cleanup = redirect.iptablesCommands()
+	require.Contains(t, setup, netfilter.Command{Name: "ip6tables", Arguments: []string{"-t", "nat", "-A", "SING_BOX_REDIRECT", "-p", "tcp", "-j", "REDIRECT", "--to-ports", "7892"}})
+	require.Len(t, cleanup, 12)
+	setup, _ = redirect.routeCommands()
+	require.Empty(t, setup)
+}
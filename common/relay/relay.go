@@ -0,0 +1,155 @@
+package relay
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/task"
+)
+
+const (
+	PoolShared    = "shared"
+	PoolDedicated = "dedicated"
+)
+
+func Validate(options *option.BufferOptions) error {
+	if options == nil {
+		return nil
+	}
+	switch options.RelayBufferPool {
+	case "", PoolShared, PoolDedicated:
+	default:
+		return E.New("unknown relay buffer pool: ", options.RelayBufferPool)
+	}
+	if options.RelayBufferSize > 0 && options.RelayBufferSize < 1024 {
+		return E.New("relay buffer size too small: ", uint64(options.RelayBufferSize))
+	}
+	return nil
+}
+
+// MergeOptions merges buffer options field by field, earlier options take precedence.
+func MergeOptions(optionsList ...*option.BufferOptions) *option.BufferOptions {
+	var merged option.BufferOptions
+	var loaded bool
+	for _, options := range optionsList {
+		if options == nil {
+			continue
+		}
+		loaded = true
+		if merged.ReadBufferSize == 0 {
+			merged.ReadBufferSize = options.ReadBufferSize
+		}
+		if merged.WriteBufferSize == 0 {
+			merged.WriteBufferSize = options.WriteBufferSize
+		}
+		if merged.RelayBufferSize == 0 {
+			merged.RelayBufferSize = options.RelayBufferSize
+		}
+		if merged.RelayBufferPool == "" {
+			merged.RelayBufferPool = options.RelayBufferPool
+		}
+	}
+	if !loaded {
+		return nil
+	}
+	return &merged
+}
+
+func CopyConn(ctx context.Context, source net.Conn, destination net.Conn, options *option.BufferOptions) error {
+	if options == nil || options.RelayBufferSize == 0 && options.RelayBufferPool != PoolDedicated {
+		return bufio.CopyConn(ctx, source, destination)
+	}
+	var group task.Group
+	if _, dstDuplex := common.Cast[N.WriteCloser](destination); dstDuplex {
+		group.Append("upload", func(ctx context.Context) error {
+			err := common.Error(Copy(destination, source, options))
+			if err == nil {
+				N.CloseWrite(destination)
+			} else {
+				common.Close(destination)
+			}
+			return err
+		})
+	} else {
+		group.Append("upload", func(ctx context.Context) error {
+			defer common.Close(destination)
+			return common.Error(Copy(destination, source, options))
+		})
+	}
+	if _, srcDuplex := common.Cast[N.WriteCloser](source); srcDuplex {
+		group.Append("download", func(ctx context.Context) error {
+			err := common.Error(Copy(source, destination, options))
+			if err == nil {
+				N.CloseWrite(source)
+			} else {
+				common.Close(source)
+			}
+			return err
+		})
+	} else {
+		group.Append("download", func(ctx context.Context) error {
+			defer common.Close(source)
+			return common.Error(Copy(source, destination, options))
+		})
+	}
+	group.Cleanup(func() {
+		common.Close(source, destination)
+	})
+	return group.Run(ctx)
+}
+
+func Copy(destination io.Writer, source io.Reader, options *option.BufferOptions) (n int64, err error) {
+	if canSplice(source, destination) {
+		return bufio.Copy(destination, source)
+	}
+	bufferSize := int(options.RelayBufferSize)
+	if bufferSize == 0 {
+		bufferSize = buf.BufferSize
+	}
+	dedicated := options.RelayBufferPool == PoolDedicated
+	var (
+		buffer       *buf.Buffer
+		notFirstTime bool
+	)
+	if dedicated {
+		buffer = buf.With(make([]byte, bufferSize))
+	}
+	for {
+		if dedicated {
+			buffer.Reset()
+		} else {
+			buffer = buf.NewSize(bufferSize)
+		}
+		_, err = buffer.ReadOnceFrom(source)
+		dataLen := buffer.Len()
+		if dataLen > 0 {
+			_, writeErr := destination.Write(buffer.Bytes())
+			if writeErr != nil {
+				err = writeErr
+				if !notFirstTime {
+					err = N.ReportHandshakeFailure(source, err)
+				}
+			} else {
+				n += int64(dataLen)
+				notFirstTime = true
+			}
+		}
+		if !dedicated {
+			buffer.Release()
+		}
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
+			return
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package relay
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidate(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		options *option.BufferOptions
+		valid   bool
+	}{
+		{"nil", nil, true},
+		{"empty", &option.BufferOptions{}, true},
+		{"shared pool", &option.BufferOptions{RelayBufferPool: PoolShared}, true},
+		{"dedicated pool", &option.BufferOptions{RelayBufferPool: PoolDedicated}, true},
+		{"unknown pool", &option.BufferOptions{RelayBufferPool: "global"}, false},
+		{"minimum size", &option.BufferOptions{RelayBufferSize: 1024}, true},
+		{"too small", &option.BufferOptions{RelayBufferSize: 512}, false},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			err := Validate(testCase.options)
+			if testCase.valid {
+				require.NoError(t, err)
+			} else {
+				require.Error(t, err)
+			}
+		})
+	}
+}
+
+func TestMergeOptions(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name     string
+		options  []*option.BufferOptions
+		expected *option.BufferOptions
+	}{
+		{"none", nil, nil},
+		{"all nil", []*option.BufferOptions{nil, nil}, nil},
+		{
+			"single",
+			[]*option.BufferOptions{{RelayBufferSize: 4096}},
+			&option.BufferOptions{RelayBufferSize: 4096},
+		},
+		{
+			"earlier wins",
+			[]*option.BufferOptions{{RelayBufferSize: 4096}, {RelayBufferSize: 8192, RelayBufferPool: PoolDedicated}},
+			&option.BufferOptions{RelayBufferSize: 4096, RelayBufferPool: PoolDedicated},
+		},
+		{
+			"field by field",
+			[]*option.BufferOptions{nil, {ReadBufferSize: 1 << 20}, {WriteBufferSize: 1 << 20, ReadBufferSize: 1024}},
+			&option.BufferOptions{ReadBufferSize: 1 << 20, WriteBufferSize: 1 << 20},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.expected, MergeOptions(testCase.options...))
+		})
+	}
+}
+
+func TestCopy(t *testing.T) {
+	t.Parallel()
+	payload := bytes.Repeat([]byte("sing-box"), 4096)
+	for _, testCase := range []struct {
+		name    string
+		options *option.BufferOptions
+	}{
+		{"shared", &option.BufferOptions{RelayBufferSize: 1024}},
+		{"dedicated", &option.BufferOptions{RelayBufferSize: 1024, RelayBufferPool: PoolDedicated}},
+		{"dedicated default size", &option.BufferOptions{RelayBufferPool: PoolDedicated}},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			var destination bytes.Buffer
+			n, err := Copy(&destination, io.MultiReader(bytes.NewReader(payload[:100]), bytes.NewReader(payload[100:])), testCase.options)
+			require.NoError(t, err)
+			require.Equal(t, int64(len(payload)), n)
+			require.Equal(t, payload, destination.Bytes())
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package relay
+
+import (
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/control"
+)
+
+func SocketControl(options *option.BufferOptions) control.Func {
+	if options == nil || options.ReadBufferSize == 0 && options.WriteBufferSize == 0 {
+		return nil
+	}
+	return setSocketBuffer(int(options.ReadBufferSize), int(options.WriteBufferSize))
+}
@@ -0,0 +1,9 @@
+//go:build !unix && !windows
+
+package relay
+
+import "github.com/sagernet/sing/common/control"
+
+func setSocketBuffer(readBufferSize int, writeBufferSize int) control.Func {
+	return nil
+}
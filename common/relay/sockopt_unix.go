@@ -0,0 +1,30 @@
+//go:build unix
+
+package relay
+
+import (
+	"syscall"
+
+	"github.com/sagernet/sing/common/control"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func setSocketBuffer(readBufferSize int, writeBufferSize int) control.Func {
+	return func(network, address string, conn syscall.RawConn) error {
+		return control.Raw(conn, func(fd uintptr) error {
+			if readBufferSize > 0 {
+				err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_RCVBUF, readBufferSize)
+				if err != nil {
+					return E.Cause(err, "set read buffer size")
+				}
+			}
+			if writeBufferSize > 0 {
+				err := syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_SNDBUF, writeBufferSize)
+				if err != nil {
+					return E.Cause(err, "set write buffer size")
+				}
+			}
+			return nil
+		})
+	}
+}
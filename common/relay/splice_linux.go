@@ -0,0 +1,31 @@
+package relay
+
+import (
+	"io"
+	"syscall"
+
+	"github.com/sagernet/sing/common"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// canSplice unwraps source and destination the same way bufio.Copy does, and
+// reports whether the copy will be handed to splice(2).
+func canSplice(source io.Reader, destination io.Writer) bool {
+	for {
+		source, _ = N.UnwrapCountReader(N.UnwrapReader(source), nil)
+		destination, _ = N.UnwrapCountWriter(N.UnwrapWriter(destination), nil)
+		if _, isCached := source.(N.CachedReader); isCached {
+			// bufio.Copy flushes the cached payload, after which the wrapper is replaceable.
+			if upstream, hasUpstream := source.(common.WithUpstream); hasUpstream {
+				if upstreamReader, isReader := upstream.Upstream().(io.Reader); isReader {
+					source = upstreamReader
+					continue
+				}
+			}
+		}
+		break
+	}
+	_, sourceIsSyscall := source.(syscall.Conn)
+	_, destinationIsSyscall := destination.(syscall.Conn)
+	return sourceIsSyscall && destinationIsSyscall
+}
@@ -0,0 +1,134 @@
+package relay
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+
+	"github.com/stretchr/testify/require"
+)
+
+func tcpPipe(t *testing.T) (net.Conn, net.Conn) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	accepted := make(chan net.Conn, 1)
+	go func() {
+		conn, _ := listener.Accept()
+		accepted <- conn
+	}()
+	client, err := net.Dial("tcp", listener.Addr().String())
+	require.NoError(t, err)
+	server := <-accepted
+	require.NotNil(t, server)
+	t.Cleanup(func() {
+		client.Close()
+		server.Close()
+	})
+	return client, server
+}
+
+func TestCanSplice(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name   string
+		wrap   func(source net.Conn, destination net.Conn) (io.Reader, io.Writer)
+		splice bool
+	}{
+		{
+			name: "raw",
+			wrap: func(source net.Conn, destination net.Conn) (io.Reader, io.Writer) {
+				return source, destination
+			},
+			splice: true,
+		},
+		{
+			name: "counter",
+			wrap: func(source net.Conn, destination net.Conn) (io.Reader, io.Writer) {
+				return bufio.NewInt64CounterConn(source, nil, nil), bufio.NewInt64CounterConn(destination, nil, nil)
+			},
+			splice: true,
+		},
+		{
+			name: "cached",
+			wrap: func(source net.Conn, destination net.Conn) (io.Reader, io.Writer) {
+				return bufio.NewCachedConn(source, buf.As([]byte("cached"))), destination
+			},
+			splice: true,
+		},
+		{
+			name: "counter over cached",
+			wrap: func(source net.Conn, destination net.Conn) (io.Reader, io.Writer) {
+				return bufio.NewInt64CounterConn(bufio.NewCachedConn(source, buf.As([]byte("cached"))), nil, nil), destination
+			},
+			splice: true,
+		},
+		{
+			name: "opaque",
+			wrap: func(source net.Conn, destination net.Conn) (io.Reader, io.Writer) {
+				return struct{ io.Reader }{source}, destination
+			},
+			splice: false,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			_, source := tcpPipe(t)
+			destination, _ := tcpPipe(t)
+			reader, writer := testCase.wrap(source, destination)
+			require.Equal(t, testCase.splice, canSplice(reader, writer))
+		})
+	}
+}
+
+// replaceableConn fails the test if it is read in userspace instead of being
+// unwrapped to the underlying socket.
+type replaceableConn struct {
+	net.Conn
+	t *testing.T
+}
+
+func (c *replaceableConn) Read(p []byte) (n int, err error) {
+	c.t.Error("relay read through the wrapper instead of splicing")
+	return c.Conn.Read(p)
+}
+
+func (c *replaceableConn) Upstream() any {
+	return c.Conn
+}
+
+func (c *replaceableConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *replaceableConn) WriterReplaceable() bool {
+	return true
+}
+
+func TestCopySplice(t *testing.T) {
+	t.Parallel()
+	sourceClient, source := tcpPipe(t)
+	destination, destinationServer := tcpPipe(t)
+	payload := bytes.Repeat([]byte("sing-box"), 64*1024)
+	reader := bufio.NewCachedConn(&replaceableConn{source, t}, buf.As(payload[:8]))
+	go func() {
+		sourceClient.Write(payload[8:])
+		sourceClient.Close()
+	}()
+	copied := make(chan error, 1)
+	go func() {
+		_, err := Copy(destination, reader, &option.BufferOptions{RelayBufferPool: PoolDedicated})
+		destination.Close()
+		copied <- err
+	}()
+	received, err := io.ReadAll(destinationServer)
+	require.NoError(t, err)
+	require.NoError(t, <-copied)
+	require.Equal(t, payload, received)
+}
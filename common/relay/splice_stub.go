@@ -0,0 +1,9 @@
+//go:build !linux
+
+package relay
+
+import "io"
+
+func canSplice(source io.Reader, destination io.Writer) bool {
+	return false
+}
@@ -71,3 +71,7 @@ func (p *AndroidSystemProxy) runAndroidShell(name string, args ...string) error
 		return shell.Exec("sh", p.rishPath, "-c", F.ToString(name, " ", strings.Join(args, " "))).Attach().Run()
 	}
 }
+
+func NewSystemProxyPAC(ctx context.Context, pacURL string) (*AndroidSystemProxy, error) {
+	return nil, E.New("PAC system proxy is unsupported on Android")
+}
@@ -20,6 +20,7 @@ type DarwinSystemProxy struct {
 	element       *list.Element[tun.DefaultInterfaceUpdateCallback]
 	serverAddr    M.Socksaddr
 	supportSOCKS  bool
+	pacURL        string
 	isEnabled     bool
 }
 
@@ -37,6 +38,15 @@ func NewSystemProxy(ctx context.Context, serverAddr M.Socksaddr, supportSOCKS bo
 	return proxy, nil
 }
 
+func NewSystemProxyPAC(ctx context.Context, pacURL string) (*DarwinSystemProxy, error) {
+	proxy, err := NewSystemProxy(ctx, M.Socksaddr{}, false)
+	if err != nil {
+		return nil, err
+	}
+	proxy.pacURL = pacURL
+	return proxy, nil
+}
+
 func (p *DarwinSystemProxy) IsEnabled() bool {
 	return p.isEnabled
 }
@@ -50,6 +60,13 @@ func (p *DarwinSystemProxy) Disable() error {
 	if err != nil {
 		return err
 	}
+	if p.pacURL != "" {
+		err = shell.Exec("networksetup", "-setautoproxystate", interfaceDisplayName, "off").Attach().Run()
+		if err == nil {
+			p.isEnabled = false
+		}
+		return err
+	}
 	if p.supportSOCKS {
 		err = shell.Exec("networksetup", "-setsocksfirewallproxystate", interfaceDisplayName, "off").Attach().Run()
 	}
@@ -88,6 +105,14 @@ func (p *DarwinSystemProxy) update0() error {
 	if err != nil {
 		return err
 	}
+	if p.pacURL != "" {
+		err = shell.Exec("networksetup", "-setautoproxyurl", interfaceDisplayName, p.pacURL).Attach().Run()
+		if err != nil {
+			return err
+		}
+		p.isEnabled = true
+		return nil
+	}
 	if p.supportSOCKS {
 		err = shell.Exec("networksetup", "-setsocksfirewallproxy", interfaceDisplayName, p.serverAddr.AddrString(), strconv.Itoa(int(p.serverAddr.Port))).Attach().Run()
 	}
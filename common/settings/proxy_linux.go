@@ -21,6 +21,7 @@ type LinuxSystemProxy struct {
 	sudoUser        string
 	serverAddr      M.Socksaddr
 	supportSOCKS    bool
+	pacURL          string
 	isEnabled       bool
 }
 
@@ -53,11 +54,23 @@ func NewSystemProxy(ctx context.Context, serverAddr M.Socksaddr, supportSOCKS bo
 	}, nil
 }
 
+func NewSystemProxyPAC(ctx context.Context, pacURL string) (*LinuxSystemProxy, error) {
+	proxy, err := NewSystemProxy(ctx, M.Socksaddr{}, false)
+	if err != nil {
+		return nil, err
+	}
+	proxy.pacURL = pacURL
+	return proxy, nil
+}
+
 func (p *LinuxSystemProxy) IsEnabled() bool {
 	return p.isEnabled
 }
 
 func (p *LinuxSystemProxy) Enable() error {
+	if p.pacURL != "" {
+		return p.enablePAC()
+	}
 	if p.hasGSettings {
 		err := p.runAsUser("gsettings", "set", "org.gnome.system.proxy.http", "enabled", "true")
 		if err != nil {
@@ -127,6 +140,35 @@ func (p *LinuxSystemProxy) Disable() error {
 	return nil
 }
 
+func (p *LinuxSystemProxy) enablePAC() error {
+	if p.hasGSettings {
+		err := p.runAsUser("gsettings", "set", "org.gnome.system.proxy", "autoconfig-url", p.pacURL)
+		if err != nil {
+			return err
+		}
+		err = p.runAsUser("gsettings", "set", "org.gnome.system.proxy", "mode", "auto")
+		if err != nil {
+			return err
+		}
+	}
+	if p.kWriteConfigCmd != "" {
+		err := p.runAsUser(p.kWriteConfigCmd, "--file", "kioslaverc", "--group", "Proxy Settings", "--key", "Proxy Config Script", p.pacURL)
+		if err != nil {
+			return err
+		}
+		err = p.runAsUser(p.kWriteConfigCmd, "--file", "kioslaverc", "--group", "Proxy Settings", "--key", "ProxyType", "2")
+		if err != nil {
+			return err
+		}
+		err = p.runAsUser("dbus-send", "--type=signal", "/KIO/Scheduler", "org.kde.KIO.Scheduler.reparseSlaveConfiguration", "string:''")
+		if err != nil {
+			return err
+		}
+	}
+	p.isEnabled = true
+	return nil
+}
+
 func (p *LinuxSystemProxy) runAsUser(name string, args ...string) error {
 	if os.Getuid() != 0 {
 		return shell.Exec(name, args...).Attach().Run()
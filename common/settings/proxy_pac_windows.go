@@ -0,0 +1,79 @@
+package settings
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	modwininet             = windows.NewLazySystemDLL("wininet.dll")
+	procInternetSetOptionW = modwininet.NewProc("InternetSetOptionW")
+)
+
+const (
+	internetOptionPerConnectionOption  = 75
+	internetOptionSettingsChanged      = 39
+	internetOptionRefresh              = 37
+	internetOptionProxySettingsChanged = 95
+
+	internetPerConnFlags         = 1
+	internetPerConnAutoconfigURL = 4
+
+	proxyTypeDirect       = 1
+	proxyTypeAutoProxyURL = 4
+)
+
+type internetPerConnOptionList struct {
+	dwSize        uint32
+	pszConnection uintptr
+	dwOptionCount uint32
+	dwOptionError uint32
+	pOptions      uintptr
+}
+
+type internetPerConnOption struct {
+	dwOption uint32
+	value    uint64
+}
+
+func internetSetOption(option uintptr, lpBuffer uintptr, dwBufferSize uintptr) error {
+	r0, _, err := syscall.SyscallN(procInternetSetOptionW.Addr(), 0, option, lpBuffer, dwBufferSize)
+	if r0 != 1 {
+		return err
+	}
+	return nil
+}
+
+// setAutoConfigURL points the WinINet proxy settings at a PAC file, since
+// sing/common/wininet only handles static proxies.
+func setAutoConfigURL(pacURL string) error {
+	options := make([]internetPerConnOption, 2)
+	options[0].dwOption = internetPerConnFlags
+	*((*uint32)(unsafe.Pointer(&options[0].value))) = proxyTypeAutoProxyURL | proxyTypeDirect
+	options[1].dwOption = internetPerConnAutoconfigURL
+	*((*uintptr)(unsafe.Pointer(&options[1].value))) = uintptr(unsafe.Pointer(windows.StringToUTF16Ptr(pacURL)))
+	var optionList internetPerConnOptionList
+	optionList.dwSize = uint32(unsafe.Sizeof(optionList))
+	optionList.dwOptionCount = uint32(len(options))
+	optionList.pOptions = uintptr(unsafe.Pointer(&options[0]))
+	err := internetSetOption(internetOptionPerConnectionOption, uintptr(unsafe.Pointer(&optionList)), uintptr(optionList.dwSize))
+	if err != nil {
+		return os.NewSyscallError("InternetSetOption(PerConnectionOption)", err)
+	}
+	err = internetSetOption(internetOptionSettingsChanged, 0, 0)
+	if err != nil {
+		return os.NewSyscallError("InternetSetOption(SettingsChanged)", err)
+	}
+	err = internetSetOption(internetOptionProxySettingsChanged, 0, 0)
+	if err != nil {
+		return os.NewSyscallError("InternetSetOption(ProxySettingsChanged)", err)
+	}
+	err = internetSetOption(internetOptionRefresh, 0, 0)
+	if err != nil {
+		return os.NewSyscallError("InternetSetOption(Refresh)", err)
+	}
+	return nil
+}
@@ -12,3 +12,7 @@ import (
 func NewSystemProxy(ctx context.Context, serverAddr M.Socksaddr, supportSOCKS bool) (SystemProxy, error) {
 	return nil, os.ErrInvalid
 }
+
+func NewSystemProxyPAC(ctx context.Context, pacURL string) (SystemProxy, error) {
+	return nil, os.ErrInvalid
+}
@@ -10,6 +10,7 @@ import (
 type WindowsSystemProxy struct {
 	serverAddr   M.Socksaddr
 	supportSOCKS bool
+	pacURL       string
 	isEnabled    bool
 }
 
@@ -20,12 +21,23 @@ func NewSystemProxy(ctx context.Context, serverAddr M.Socksaddr, supportSOCKS bo
 	}, nil
 }
 
+func NewSystemProxyPAC(ctx context.Context, pacURL string) (*WindowsSystemProxy, error) {
+	return &WindowsSystemProxy{
+		pacURL: pacURL,
+	}, nil
+}
+
 func (p *WindowsSystemProxy) IsEnabled() bool {
 	return p.isEnabled
 }
 
 func (p *WindowsSystemProxy) Enable() error {
-	err := wininet.SetSystemProxy("http://"+p.serverAddr.String(), "")
+	var err error
+	if p.pacURL != "" {
+		err = setAutoConfigURL(p.pacURL)
+	} else {
+		err = wininet.SetSystemProxy("http://"+p.serverAddr.String(), "")
+	}
 	if err != nil {
 		return err
 	}
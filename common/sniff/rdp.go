@@ -0,0 +1,33 @@
+package sniff
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+// RDP detects if the stream starts with an X.224 Connection Request PDU
+// in a TPKT header, which is the first message of an RDP connection.
+// For the RDP protocol specification, see https://learn.microsoft.com/en-us/openspecs/windows_protocols/ms-rdpbcgr/18a27ef9-6f9a-4501-b000-94b1fe3c2c10
+func RDP(_ context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	var header [11]byte
+	_, err := io.ReadFull(reader, header[:])
+	if err != nil {
+		return nil, err
+	}
+	// TPKT: version 3, reserved, total length
+	if header[0] != 3 || header[1] != 0 {
+		return nil, os.ErrInvalid
+	}
+	length := binary.BigEndian.Uint16(header[2:4])
+	// X.224: length indicator excluding itself, Connection Request code,
+	// zero destination reference, source reference and class 0
+	if length < 11 || int(header[4]) != int(length)-5 || header[5] != 0xE0 || binary.BigEndian.Uint16(header[6:8]) != 0 || header[10]&0xF0 != 0 {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolRDP}, nil
+}
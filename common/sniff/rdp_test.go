@@ -0,0 +1,35 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffRDP(t *testing.T) {
+	t.Parallel()
+
+	packets := []string{
+		"0300002b26e00000000000436f6f6b69653a206d737473686173683d757365720d0a010008000b000000",
+		"030000130ee000000000000100080003000000",
+	}
+
+	for _, pkt := range packets {
+		pkt, err := hex.DecodeString(pkt)
+		require.NoError(t, err)
+		metadata, err := sniff.RDP(context.TODO(), bytes.NewReader(pkt))
+		require.NoError(t, err)
+		require.Equal(t, C.ProtocolRDP, metadata.Protocol)
+	}
+
+	pkt, err := hex.DecodeString("16030100c4010000c00303")
+	require.NoError(t, err)
+	_, err = sniff.RDP(context.TODO(), bytes.NewReader(pkt))
+	require.Error(t, err)
+}
@@ -0,0 +1,25 @@
+package sniff
+
+import (
+	"context"
+	"io"
+	"os"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+)
+
+// SSH detects if the stream starts with an SSH identification string.
+// For the SSH protocol specification, see https://www.rfc-editor.org/rfc/rfc4253#section-4.2
+func SSH(_ context.Context, reader io.Reader) (*adapter.InboundContext, error) {
+	var version [8]byte
+	_, err := io.ReadFull(reader, version[:])
+	if err != nil {
+		return nil, err
+	}
+	// SSH-1.99 is announced by servers compatible with both versions
+	if string(version[:]) != "SSH-2.0-" && string(version[:]) != "SSH-1.99" {
+		return nil, os.ErrInvalid
+	}
+	return &adapter.InboundContext{Protocol: C.ProtocolSSH}, nil
+}
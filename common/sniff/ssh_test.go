@@ -0,0 +1,23 @@
+package sniff_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSniffSSH(t *testing.T) {
+	t.Parallel()
+
+	metadata, err := sniff.SSH(context.TODO(), bytes.NewReader([]byte("SSH-2.0-OpenSSH_9.6p1 Ubuntu-3ubuntu13.5\r\n")))
+	require.NoError(t, err)
+	require.Equal(t, C.ProtocolSSH, metadata.Protocol)
+
+	_, err = sniff.SSH(context.TODO(), bytes.NewReader([]byte("GET / HTTP/1.1\r\n")))
+	require.Error(t, err)
+}
@@ -8,9 +8,12 @@ import (
 )
 
 type Monitor struct {
-	logger  logger.Logger
-	timeout time.Duration
-	timer   *time.Timer
+	logger    logger.Logger
+	timeout   time.Duration
+	profile   bool
+	timer     *time.Timer
+	taskName  string
+	startedAt time.Time
 }
 
 func New(logger logger.Logger, timeout time.Duration) *Monitor {
@@ -20,7 +23,19 @@ func New(logger logger.Logger, timeout time.Duration) *Monitor {
 	}
 }
 
+// NewProfile returns a monitor that also reports how long each task took,
+// used to profile startup.
+func NewProfile(logger logger.Logger, timeout time.Duration) *Monitor {
+	return &Monitor{
+		logger:  logger,
+		timeout: timeout,
+		profile: true,
+	}
+}
+
 func (m *Monitor) Start(taskName ...any) {
+	m.taskName = F.ToString(taskName...)
+	m.startedAt = time.Now()
 	m.timer = time.AfterFunc(m.timeout, func() {
 		m.logger.Warn(F.ToString(taskName...), " take too much time to finish!")
 	})
@@ -28,4 +43,7 @@ func (m *Monitor) Start(taskName ...any) {
 
 func (m *Monitor) Finish() {
 	m.timer.Stop()
+	if m.profile {
+		m.logger.Debug(m.taskName, " finished (", F.Seconds(time.Since(m.startedAt).Seconds()), "s)")
+	}
 }
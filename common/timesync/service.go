@@ -0,0 +1,204 @@
+package timesync
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/logger"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/pause"
+)
+
+type Options struct {
+	Context       context.Context
+	Dialer        N.Dialer
+	Logger        logger.Logger
+	Servers       []M.Socksaddr
+	Interval      time.Duration
+	RetryInterval time.Duration
+	WriteToSystem bool
+}
+
+type Status struct {
+	Synced      bool          `json:"synced"`
+	Server      string        `json:"server,omitempty"`
+	Offset      time.Duration `json:"offset"`
+	LastUpdated time.Time     `json:"last_updated"`
+	LastError   string        `json:"last_error,omitempty"`
+}
+
+var _ ntp.TimeService = (*Service)(nil)
+
+// Service is a NTP client that fails over between servers in order and keeps
+// using the local clock until a server answers.
+type Service struct {
+	ctx           context.Context
+	cancel        common.ContextCancelCauseFunc
+	dialer        N.Dialer
+	logger        logger.Logger
+	servers       []M.Socksaddr
+	interval      time.Duration
+	retryInterval time.Duration
+	writeToSystem bool
+	timer         *time.Timer
+	pause         pause.Manager
+	access        sync.RWMutex
+	serverIndex   int
+	status        Status
+}
+
+func NewService(options Options) *Service {
+	ctx := options.Context
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := common.ContextWithCancelCause(ctx)
+	servers := common.Map(options.Servers, func(it M.Socksaddr) M.Socksaddr {
+		if it.Port == 0 {
+			it.Port = 123
+		}
+		return it
+	})
+	if len(servers) == 0 {
+		servers = []M.Socksaddr{{Fqdn: "time.apple.com", Port: 123}}
+	}
+	if options.Logger == nil {
+		options.Logger = logger.NOP()
+	}
+	interval := options.Interval
+	if interval <= 0 {
+		interval = 30 * time.Minute
+	}
+	retryInterval := options.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = time.Minute
+	}
+	if retryInterval > interval {
+		retryInterval = interval
+	}
+	dialer := options.Dialer
+	if dialer == nil {
+		dialer = N.SystemDialer
+	}
+	return &Service{
+		ctx:           ctx,
+		cancel:        cancel,
+		dialer:        dialer,
+		logger:        options.Logger,
+		servers:       servers,
+		interval:      interval,
+		retryInterval: retryInterval,
+		writeToSystem: options.WriteToSystem,
+		pause:         service.FromContext[pause.Manager](ctx),
+	}
+}
+
+func (s *Service) Start() error {
+	err := s.update()
+	if err != nil {
+		s.logger.Error(E.Cause(err, "initialize time, using local clock"))
+		s.timer = time.NewTimer(s.retryInterval)
+	} else {
+		s.logger.Info("updated time: ", s.TimeFunc()().Local().Format(ntp.TimeLayout))
+		s.timer = time.NewTimer(s.interval)
+	}
+	go s.loopUpdate()
+	return nil
+}
+
+func (s *Service) Close() error {
+	if s.timer != nil {
+		s.timer.Stop()
+	}
+	s.cancel(os.ErrClosed)
+	return nil
+}
+
+func (s *Service) TimeFunc() func() time.Time {
+	return func() time.Time {
+		s.access.RLock()
+		defer s.access.RUnlock()
+		return time.Now().Add(s.status.Offset)
+	}
+}
+
+func (s *Service) Status() Status {
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return s.status
+}
+
+func (s *Service) loopUpdate() {
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-s.timer.C:
+		}
+		if s.pause != nil {
+			s.pause.WaitActive()
+			select {
+			case <-s.ctx.Done():
+				return
+			default:
+			}
+		}
+		err := s.update()
+		if err == nil {
+			s.logger.Debug("updated time: ", s.TimeFunc()().Local().Format(ntp.TimeLayout))
+			s.timer.Reset(s.interval)
+		} else {
+			s.logger.Warn("update time: ", err)
+			s.timer.Reset(s.retryInterval)
+		}
+	}
+}
+
+// update queries the servers in order, starting from the last one that
+// answered.
+func (s *Service) update() error {
+	s.access.RLock()
+	startIndex := s.serverIndex
+	s.access.RUnlock()
+	var errors []error
+	for i := range s.servers {
+		index := (startIndex + i) % len(s.servers)
+		server := s.servers[index]
+		response, err := ntp.Exchange(s.ctx, s.dialer, server)
+		if err != nil {
+			if len(s.servers) > 1 {
+				s.logger.Debug("query ", server, ": ", err)
+			}
+			errors = append(errors, E.Cause(err, server))
+			continue
+		}
+		s.access.Lock()
+		s.serverIndex = index
+		s.status = Status{
+			Synced:      true,
+			Server:      server.String(),
+			Offset:      response.ClockOffset,
+			LastUpdated: time.Now(),
+		}
+		s.access.Unlock()
+		if s.writeToSystem {
+			writeErr := ntp.SetSystemTime(s.TimeFunc()())
+			if writeErr != nil {
+				s.logger.Warn("write time to system: ", writeErr)
+			}
+		}
+		return nil
+	}
+	err := E.Errors(errors...)
+	s.access.Lock()
+	s.status.LastError = err.Error()
+	s.access.Unlock()
+	return err
+}
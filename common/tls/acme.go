@@ -9,6 +9,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/acmedns"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -90,6 +91,47 @@ func startACME(ctx context.Context, options option.InboundACMEOptions) (*tls.Con
 			solver.DNSProvider = &cloudflare.Provider{
 				APIToken: dnsOptions.CloudflareOptions.APIToken,
 			}
+		case C.DNSProviderRoute53:
+			solver.DNSProvider = &acmedns.Route53{
+				AccessKeyID:     dnsOptions.Route53Options.AccessKeyID,
+				SecretAccessKey: dnsOptions.Route53Options.SecretAccessKey,
+				SessionToken:    dnsOptions.Route53Options.SessionToken,
+				HostedZoneID:    dnsOptions.Route53Options.HostedZoneID,
+			}
+		case C.DNSProviderGcore:
+			solver.DNSProvider = &acmedns.Gcore{
+				APIKey: dnsOptions.GcoreOptions.APIKey,
+			}
+		case C.DNSProviderDuckDNS:
+			solver.DNSProvider = &acmedns.DuckDNS{
+				Token: dnsOptions.DuckDNSOptions.Token,
+			}
+		case C.DNSProviderDeSEC:
+			solver.DNSProvider = &acmedns.DeSEC{
+				Token: dnsOptions.DeSECOptions.Token,
+			}
+		case C.DNSProviderRFC2136:
+			solver.DNSProvider = &acmedns.RFC2136{
+				Server:        dnsOptions.RFC2136Options.Server,
+				TSIGKeyName:   dnsOptions.RFC2136Options.TSIGKeyName,
+				TSIGSecret:    dnsOptions.RFC2136Options.TSIGSecret,
+				TSIGAlgorithm: dnsOptions.RFC2136Options.TSIGAlgorithm,
+			}
+		case C.DNSProviderExec:
+			if len(dnsOptions.ExecOptions.Command) == 0 {
+				return nil, nil, E.New("missing ACME DNS01 exec command")
+			}
+			solver.DNSProvider = &acmedns.Exec{
+				Command: dnsOptions.ExecOptions.Command,
+			}
+		case C.DNSProviderWebhook:
+			if dnsOptions.WebhookOptions.URL == "" {
+				return nil, nil, E.New("missing ACME DNS01 webhook URL")
+			}
+			solver.DNSProvider = &acmedns.Webhook{
+				URL:    dnsOptions.WebhookOptions.URL,
+				Header: dnsOptions.WebhookOptions.Headers.Build(),
+			}
 		default:
 			return nil, nil, E.New("unsupported ACME DNS01 provider type: " + dnsOptions.Provider)
 		}
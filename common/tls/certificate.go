@@ -0,0 +1,86 @@
+package tls
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// loadRootCAs returns the trust store configured in options, or nil to use
+// the system one.
+func loadRootCAs(options option.OutboundTLSOptions) (*x509.CertPool, error) {
+	var certificate []byte
+	if len(options.Certificate) > 0 {
+		certificate = []byte(strings.Join(options.Certificate, "\n"))
+	} else if options.CertificatePath != "" {
+		content, err := os.ReadFile(options.CertificatePath)
+		if err != nil {
+			return nil, E.Cause(err, "read certificate")
+		}
+		certificate = content
+	}
+	if len(certificate) == 0 && options.CertificateDirectoryPath == "" {
+		return nil, nil
+	}
+	certPool := x509.NewCertPool()
+	if len(certificate) > 0 && !certPool.AppendCertsFromPEM(certificate) {
+		return nil, E.New("failed to parse certificate:\n\n", certificate)
+	}
+	if options.CertificateDirectoryPath != "" {
+		entries, err := os.ReadDir(options.CertificateDirectoryPath)
+		if err != nil {
+			return nil, E.Cause(err, "read certificate directory")
+		}
+		var loaded bool
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+			content, err := os.ReadFile(filepath.Join(options.CertificateDirectoryPath, entry.Name()))
+			if err != nil {
+				return nil, E.Cause(err, "read certificate")
+			}
+			// trust store directories may contain other files, such as hash links
+			if certPool.AppendCertsFromPEM(content) {
+				loaded = true
+			}
+		}
+		if !loaded {
+			return nil, E.New("no certificates found in ", options.CertificateDirectoryPath)
+		}
+	}
+	return certPool, nil
+}
+
+func parsePublicKeyPins(pins []string) ([][]byte, error) {
+	var pinList [][]byte
+	for i, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(pin)
+		if err != nil || len(hash) != sha256.Size {
+			return nil, E.New("invalid certificate_public_key_sha256[", i, "]: ", pin)
+		}
+		pinList = append(pinList, hash)
+	}
+	return pinList, nil
+}
+
+// verifyPublicKeyPins checks that any certificate presented by the server has
+// a pinned SubjectPublicKeyInfo SHA-256 hash.
+func verifyPublicKeyPins(pins [][]byte, certificates []*x509.Certificate) error {
+	for _, certificate := range certificates {
+		hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+		for _, pin := range pins {
+			if bytes.Equal(hash[:], pin) {
+				return nil
+			}
+		}
+	}
+	return E.New("no certificate matches certificate_public_key_sha256")
+}
@@ -2,13 +2,17 @@ package tls
 
 import (
 	"context"
+	"crypto/tls"
 	"net"
 	"os"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/badtls"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	aTLS "github.com/sagernet/sing/common/tls"
@@ -29,6 +33,9 @@ func NewClient(ctx context.Context, serverAddress string, options option.Outboun
 	if !options.Enabled {
 		return nil, nil
 	}
+	if options.PQKeyExchange && options.ECH != nil && options.ECH.Enabled {
+		return nil, E.New("post-quantum key exchange is unavailable in ECH")
+	}
 	if options.ECH != nil && options.ECH.Enabled {
 		return NewECHClient(ctx, serverAddress, options)
 	} else if options.Reality != nil && options.Reality.Enabled {
@@ -43,10 +50,16 @@ func NewClient(ctx context.Context, serverAddress string, options option.Outboun
 func ClientHandshake(ctx context.Context, conn net.Conn, config Config) (Conn, error) {
 	ctx, cancel := context.WithTimeout(ctx, C.TCPTimeout)
 	defer cancel()
+	start := time.Now()
 	tlsConn, err := aTLS.ClientHandshake(ctx, conn, config)
 	if err != nil {
+		log.TraceEvent(ctx, "tls handshake with ", config.ServerName(), " failed after ", time.Since(start), ": ", err)
 		return nil, err
 	}
+	if log.IsTraceContext(ctx) {
+		state := tlsConn.ConnectionState()
+		log.TraceEvent(ctx, "tls handshake with ", config.ServerName(), " in ", time.Since(start), ": ", tls.VersionName(state.Version), ", ", tls.CipherSuiteName(state.CipherSuite), ", alpn: ", state.NegotiatedProtocol, ", resumed: ", state.DidResume)
+	}
 	readWaitConn, err := badtls.NewReadWaitConn(tlsConn)
 	if err == nil {
 		return readWaitConn, nil
@@ -109,6 +109,7 @@ func NewECHClient(ctx context.Context, serverAddress string, options option.Outb
 		tlsConfig.InsecureSkipVerify = true
 		tlsConfig.VerifyConnection = func(state cftls.ConnectionState) error {
 			verifyOptions := x509.VerifyOptions{
+				Roots:         tlsConfig.RootCAs,
 				DNSName:       serverName,
 				Intermediates: x509.NewCertPool(),
 			}
@@ -148,22 +149,28 @@ func NewECHClient(ctx context.Context, serverAddress string, options option.Outb
 			return nil, E.New("unknown cipher_suite: ", cipherSuite)
 		}
 	}
-	var certificate []byte
-	if len(options.Certificate) > 0 {
-		certificate = []byte(strings.Join(options.Certificate, "\n"))
-	} else if options.CertificatePath != "" {
-		content, err := os.ReadFile(options.CertificatePath)
+	rootCAs, err := loadRootCAs(options)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = rootCAs
+	if len(options.CertificatePublicKeySHA256) > 0 {
+		pins, err := parsePublicKeyPins(options.CertificatePublicKeySHA256)
 		if err != nil {
-			return nil, E.Cause(err, "read certificate")
+			return nil, err
 		}
-		certificate = content
-	}
-	if len(certificate) > 0 {
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(certificate) {
-			return nil, E.New("failed to parse certificate:\n\n", certificate)
+		// VerifyConnection, unlike VerifyPeerCertificate, also runs when a
+		// session is resumed.
+		verifyConnection := tlsConfig.VerifyConnection
+		tlsConfig.VerifyConnection = func(state cftls.ConnectionState) error {
+			if verifyConnection != nil {
+				err := verifyConnection(state)
+				if err != nil {
+					return err
+				}
+			}
+			return verifyPublicKeyPins(pins, state.PeerCertificates)
 		}
-		tlsConfig.RootCAs = certPool
 	}
 
 	// ECH Config
@@ -15,6 +15,20 @@ import (
 )
 
 func ECHKeygenDefault(serverName string, pqSignatureSchemesEnabled bool) (configPem string, keyPem string, err error) {
+	keyPairs, err := echKeygenDefault(serverName, pqSignatureSchemesEnabled, 0)
+	if err != nil {
+		return
+	}
+	var keyBuffer bytes.Buffer
+	for _, keyPair := range keyPairs {
+		keyBuffer.Write(keyPair.rawKey)
+	}
+	configPem = echConfigsPem(keyPairs)
+	keyPem = string(pem.EncodeToMemory(&pem.Block{Type: "ECH KEYS", Bytes: keyBuffer.Bytes()}))
+	return
+}
+
+func echKeygenDefault(serverName string, pqSignatureSchemesEnabled bool, configID uint8) ([]echKeyConfigPair, error) {
 	cipherSuites := []echCipherSuite{
 		{
 			kdf:  hpke.KDF_HKDF_SHA256,
@@ -26,17 +40,16 @@ func ECHKeygenDefault(serverName string, pqSignatureSchemesEnabled bool) (config
 	}
 
 	keyConfig := []myECHKeyConfig{
-		{id: 0, kem: hpke.KEM_X25519_HKDF_SHA256},
+		{id: configID, kem: hpke.KEM_X25519_HKDF_SHA256},
 	}
 	if pqSignatureSchemesEnabled {
-		keyConfig = append(keyConfig, myECHKeyConfig{id: 1, kem: hpke.KEM_X25519_KYBER768_DRAFT00})
+		keyConfig = append(keyConfig, myECHKeyConfig{id: configID + 1, kem: hpke.KEM_X25519_KYBER768_DRAFT00})
 	}
 
-	keyPairs, err := echKeygen(0xfe0d, serverName, keyConfig, cipherSuites)
-	if err != nil {
-		return
-	}
+	return echKeygen(0xfe0d, serverName, keyConfig, cipherSuites)
+}
 
+func echConfigsPem(keyPairs []echKeyConfigPair) string {
 	var configBuffer bytes.Buffer
 	var totalLen uint16
 	for _, keyPair := range keyPairs {
@@ -46,15 +59,7 @@ func ECHKeygenDefault(serverName string, pqSignatureSchemesEnabled bool) (config
 	for _, keyPair := range keyPairs {
 		configBuffer.Write(keyPair.rawConf)
 	}
-
-	var keyBuffer bytes.Buffer
-	for _, keyPair := range keyPairs {
-		keyBuffer.Write(keyPair.rawKey)
-	}
-
-	configPem = string(pem.EncodeToMemory(&pem.Block{Type: "ECH CONFIGS", Bytes: configBuffer.Bytes()}))
-	keyPem = string(pem.EncodeToMemory(&pem.Block{Type: "ECH KEYS", Bytes: keyBuffer.Bytes()}))
-	return
+	return string(pem.EncodeToMemory(&pem.Block{Type: "ECH CONFIGS", Bytes: configBuffer.Bytes()}))
 }
 
 type echKeyConfigPair struct {
@@ -8,12 +8,16 @@ import (
 	"encoding/pem"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"time"
 
 	cftls "github.com/sagernet/cloudflare-tls"
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	"github.com/sagernet/sing/common/ntp"
 )
@@ -26,7 +30,28 @@ type echServerConfig struct {
 	certificatePath string
 	keyPath         string
 	echKeyPath      string
+	keyPair         atomic.Pointer[cftls.Certificate]
 	watcher         *fswatch.Watcher
+
+	echProvider            *echKeySetProvider
+	echKeys                []cftls.EXP_ECHKey
+	echPublicName          string
+	echPQEnabled           bool
+	echKeyRotationInterval time.Duration
+	echConfigPath          string
+	echConfigs             []byte
+	echConfigID            uint8
+	echDone                chan struct{}
+}
+
+// echKeySetProvider allows replacing the key set while configs cloned from
+// the server config keep using it.
+type echKeySetProvider struct {
+	keySet atomic.Pointer[cftls.EXP_ECHKeySet]
+}
+
+func (p *echKeySetProvider) GetDecryptionContext(handle []byte, version uint16) cftls.ECHProviderResult {
+	return p.keySet.Load().GetDecryptionContext(handle, version)
 }
 
 func (c *echServerConfig) ServerName() string {
@@ -68,6 +93,72 @@ func (c *echServerConfig) Start() error {
 	if err != nil {
 		c.logger.Warn("create credentials watcher: ", err)
 	}
+	if c.echKeyRotationInterval > 0 {
+		err = c.writeECHConfigs()
+		if err != nil {
+			return err
+		}
+		c.echDone = make(chan struct{})
+		go c.loopRotateECHKeys()
+	}
+	return nil
+}
+
+func (c *echServerConfig) loopRotateECHKeys() {
+	ticker := time.NewTicker(c.echKeyRotationInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			err := c.rotateECHKeys()
+			if err == nil {
+				err = c.writeECHConfigs()
+			}
+			if err != nil {
+				c.logger.Error(E.Cause(err, "rotate ECH keys"))
+			} else {
+				c.logger.Info("rotated ECH keys")
+			}
+		case <-c.echDone:
+			return
+		}
+	}
+}
+
+// rotateECHKeys generates a new key generation. Keys of the previous
+// generation are kept for one more interval, so clients still holding the old
+// configs can connect.
+func (c *echServerConfig) rotateECHKeys() error {
+	keyPairs, err := echKeygenDefault(c.echPublicName, c.echPQEnabled, c.echConfigID)
+	if err != nil {
+		return E.Cause(err, "generate ECH keys")
+	}
+	// ids 0 and 1 are used by keys from ech-keypair
+	c.echConfigID += 2
+	if c.echConfigID < 2 {
+		c.echConfigID = 2
+	}
+	echKeys := common.Map(keyPairs, func(it echKeyConfigPair) cftls.EXP_ECHKey {
+		return it.key
+	})
+	echKeySet, err := cftls.EXP_NewECHKeySet(append(append([]cftls.EXP_ECHKey{}, echKeys...), c.echKeys...))
+	if err != nil {
+		return E.Cause(err, "create ECH key set")
+	}
+	c.echKeys = echKeys
+	c.echConfigs = []byte(echConfigsPem(keyPairs))
+	c.echProvider.keySet.Store(echKeySet)
+	return nil
+}
+
+func (c *echServerConfig) writeECHConfigs() error {
+	if c.echConfigPath == "" {
+		return nil
+	}
+	err := os.WriteFile(c.echConfigPath, c.echConfigs, 0o644)
+	if err != nil {
+		return E.Cause(err, "write ECH configs")
+	}
 	return nil
 }
 
@@ -97,30 +188,37 @@ func (c *echServerConfig) startWatcher() error {
 	if err != nil {
 		return err
 	}
+	err = watcher.Start()
+	if err != nil {
+		return err
+	}
 	c.watcher = watcher
 	return nil
 }
 
 func (c *echServerConfig) credentialsUpdated(path string) error {
 	if path == c.certificatePath || path == c.keyPath {
-		if path == c.certificatePath {
-			certificate, err := os.ReadFile(c.certificatePath)
+		certificate := c.certificate
+		if c.certificatePath != "" {
+			content, err := os.ReadFile(c.certificatePath)
 			if err != nil {
 				return err
 			}
-			c.certificate = certificate
-		} else {
-			key, err := os.ReadFile(c.keyPath)
+			certificate = content
+		}
+		key := c.key
+		if c.keyPath != "" {
+			content, err := os.ReadFile(c.keyPath)
 			if err != nil {
 				return err
 			}
-			c.key = key
+			key = content
 		}
-		keyPair, err := cftls.X509KeyPair(c.certificate, c.key)
+		keyPair, err := cftls.X509KeyPair(certificate, key)
 		if err != nil {
 			return E.Cause(err, "parse key pair")
 		}
-		c.config.Certificates = []cftls.Certificate{keyPair}
+		c.keyPair.Store(&keyPair)
 		c.logger.Info("reloaded TLS certificate")
 	} else {
 		echKeyContent, err := os.ReadFile(c.echKeyPath)
@@ -139,14 +237,22 @@ func (c *echServerConfig) credentialsUpdated(path string) error {
 		if err != nil {
 			return E.Cause(err, "create ECH key set")
 		}
-		c.config.ServerECHProvider = echKeySet
+		c.echKeys = echKeys
+		c.echProvider.keySet.Store(echKeySet)
 		c.logger.Info("reloaded ECH keys")
 	}
 	return nil
 }
 
+func (c *echServerConfig) getCertificate(*cftls.ClientHelloInfo) (*cftls.Certificate, error) {
+	return c.keyPair.Load(), nil
+}
+
 func (c *echServerConfig) Close() error {
 	var err error
+	if c.echDone != nil {
+		close(c.echDone)
+	}
 	if c.watcher != nil {
 		err = E.Append(err, c.watcher.Close(), func(err error) error {
 			return E.Cause(err, "close credentials watcher")
@@ -227,48 +333,80 @@ func NewECHServer(ctx context.Context, logger log.Logger, options option.Inbound
 	if err != nil {
 		return nil, E.Cause(err, "parse x509 key pair")
 	}
-	tlsConfig.Certificates = []cftls.Certificate{keyPair}
+
+	serverConfig := &echServerConfig{
+		config:                 &tlsConfig,
+		logger:                 logger,
+		certificate:            certificate,
+		key:                    key,
+		echProvider:            &echKeySetProvider{},
+		echPublicName:          options.ECH.PublicName,
+		echPQEnabled:           options.ECH.PQSignatureSchemesEnabled,
+		echKeyRotationInterval: time.Duration(options.ECH.KeyRotationInterval),
+		echConfigPath:          options.ECH.ConfigPath,
+		echConfigID:            2,
+	}
+	serverConfig.keyPair.Store(&keyPair)
+	tlsConfig.GetCertificate = serverConfig.getCertificate
+	if len(options.Certificate) == 0 && options.CertificatePath != "" {
+		serverConfig.certificatePath, _ = filepath.Abs(options.CertificatePath)
+	}
+	if len(options.Key) == 0 && options.KeyPath != "" {
+		serverConfig.keyPath, _ = filepath.Abs(options.KeyPath)
+	}
+	if len(options.ECH.Key) == 0 && options.ECH.KeyPath != "" {
+		serverConfig.echKeyPath, _ = filepath.Abs(options.ECH.KeyPath)
+	}
 
 	var echKey []byte
 	if len(options.ECH.Key) > 0 {
 		echKey = []byte(strings.Join(options.ECH.Key, "\n"))
-	} else if options.KeyPath != "" {
+	} else if options.ECH.KeyPath != "" {
 		content, err := os.ReadFile(options.ECH.KeyPath)
 		if err != nil {
 			return nil, E.Cause(err, "read ECH key")
 		}
 		echKey = content
-	} else {
+	} else if serverConfig.echKeyRotationInterval == 0 {
 		return nil, E.New("missing ECH key")
 	}
 
-	block, rest := pem.Decode(echKey)
-	if block == nil || block.Type != "ECH KEYS" || len(rest) > 0 {
-		return nil, E.New("invalid ECH keys pem")
-	}
+	if echKey != nil {
+		block, rest := pem.Decode(echKey)
+		if block == nil || block.Type != "ECH KEYS" || len(rest) > 0 {
+			return nil, E.New("invalid ECH keys pem")
+		}
 
-	echKeys, err := cftls.EXP_UnmarshalECHKeys(block.Bytes)
-	if err != nil {
-		return nil, E.Cause(err, "parse ECH keys")
+		echKeys, err := cftls.EXP_UnmarshalECHKeys(block.Bytes)
+		if err != nil {
+			return nil, E.Cause(err, "parse ECH keys")
+		}
+
+		echKeySet, err := cftls.EXP_NewECHKeySet(echKeys)
+		if err != nil {
+			return nil, E.Cause(err, "create ECH key set")
+		}
+		serverConfig.echKeys = echKeys
+		serverConfig.echProvider.keySet.Store(echKeySet)
 	}
 
-	echKeySet, err := cftls.EXP_NewECHKeySet(echKeys)
-	if err != nil {
-		return nil, E.Cause(err, "create ECH key set")
+	if serverConfig.echKeyRotationInterval > 0 {
+		if serverConfig.echPublicName == "" {
+			serverConfig.echPublicName = options.ServerName
+		}
+		if serverConfig.echPublicName == "" {
+			return nil, E.New("missing ECH public_name for key rotation")
+		}
+		err = serverConfig.rotateECHKeys()
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	tlsConfig.ECHEnabled = true
 	tlsConfig.PQSignatureSchemesEnabled = options.ECH.PQSignatureSchemesEnabled
 	tlsConfig.DynamicRecordSizingDisabled = options.ECH.DynamicRecordSizingDisabled
-	tlsConfig.ServerECHProvider = echKeySet
+	tlsConfig.ServerECHProvider = serverConfig.echProvider
 
-	return &echServerConfig{
-		config:          &tlsConfig,
-		logger:          logger,
-		certificate:     certificate,
-		key:             key,
-		certificatePath: options.CertificatePath,
-		keyPath:         options.KeyPath,
-		echKeyPath:      options.ECH.KeyPath,
-	}, nil
+	return serverConfig, nil
 }
@@ -0,0 +1,12 @@
+//go:build go1.24
+
+package tls
+
+import "crypto/tls"
+
+// setPQKeyExchange prefers the X25519+ML-KEM-768 hybrid group while keeping
+// classical groups for peers without support.
+func setPQKeyExchange(config *tls.Config) error {
+	config.CurvePreferences = []tls.CurveID{tls.X25519MLKEM768, tls.X25519, tls.CurveP256, tls.CurveP384}
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build !go1.24
+
+package tls
+
+import (
+	"crypto/tls"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func setPQKeyExchange(config *tls.Config) error {
+	return E.New("post-quantum key exchange requires sing-box built with Go 1.24 or later")
+}
@@ -49,6 +49,9 @@ func NewRealityClient(ctx context.Context, serverAddress string, options option.
 	if options.UTLS == nil || !options.UTLS.Enabled {
 		return nil, E.New("uTLS is required by reality client")
 	}
+	if len(options.CertificatePublicKeySHA256) > 0 {
+		return nil, E.New("certificate_public_key_sha256 is unavailable in reality")
+	}
 
 	uClient, err := NewUTLSClient(ctx, serverAddress, options)
 	if err != nil {
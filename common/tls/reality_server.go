@@ -3,9 +3,11 @@
 package tls
 
 import (
+	"bytes"
 	"context"
 	"crypto/tls"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"net"
 	"time"
@@ -13,8 +15,11 @@ import (
 	"github.com/sagernet/reality"
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
 	"github.com/sagernet/sing/common/debug"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
@@ -22,10 +27,18 @@ import (
 	"github.com/sagernet/sing/common/ntp"
 )
 
+const (
+	tlsRecordHeaderLength  = 5
+	tlsRecordTypeHandshake = 22
+)
+
 var _ ServerConfigCompat = (*RealityServerConfig)(nil)
 
 type RealityServerConfig struct {
 	config *reality.Config
+	// destinations maps client server names to configs with their own
+	// handshake target; unmatched clients use config.
+	destinations map[string]*reality.Config
 }
 
 func NewRealityServer(ctx context.Context, logger log.Logger, options option.InboundTLSOptions) (*RealityServerConfig, error) {
@@ -78,7 +91,15 @@ func NewRealityServer(ctx context.Context, logger log.Logger, options option.Inb
 	tlsConfig.Type = N.NetworkTCP
 	tlsConfig.Dest = options.Reality.Handshake.ServerOptions.Build().String()
 
-	tlsConfig.ServerNames = map[string]bool{options.ServerName: true}
+	tlsConfig.ServerNames = make(map[string]bool)
+	if options.ServerName != "" || len(options.Reality.Destinations) == 0 {
+		tlsConfig.ServerNames[options.ServerName] = true
+	}
+	for _, destination := range options.Reality.Destinations {
+		for _, serverName := range destination.ServerName {
+			tlsConfig.ServerNames[serverName] = true
+		}
+	}
 	privateKey, err := base64.RawURLEncoding.DecodeString(options.Reality.PrivateKey)
 	if err != nil {
 		return nil, E.Cause(err, "decode private key")
@@ -102,7 +123,8 @@ func NewRealityServer(ctx context.Context, logger log.Logger, options option.Inb
 		tlsConfig.ShortIds[shortID] = true
 	}
 
-	handshakeDialer, err := dialer.New(adapter.RouterFromContext(ctx), options.Reality.Handshake.DialerOptions)
+	router := adapter.RouterFromContext(ctx)
+	handshakeDialer, err := dialer.New(router, options.Reality.Handshake.DialerOptions)
 	if err != nil {
 		return nil, err
 	}
@@ -114,7 +136,37 @@ func NewRealityServer(ctx context.Context, logger log.Logger, options option.Inb
 		tlsConfig.Show = true
 	}
 
-	return &RealityServerConfig{&tlsConfig}, nil
+	serverConfig := &RealityServerConfig{config: &tlsConfig}
+	if len(options.Reality.Destinations) > 0 {
+		serverConfig.destinations = make(map[string]*reality.Config)
+	}
+	for i, destination := range options.Reality.Destinations {
+		if len(destination.ServerName) == 0 {
+			return nil, E.New("missing server_name in destinations[", i, "]")
+		}
+		if destination.Server == "" {
+			return nil, E.New("missing server in destinations[", i, "]")
+		}
+		destinationDialer, err := dialer.New(router, destination.DialerOptions)
+		if err != nil {
+			return nil, E.Cause(err, "destinations[", i, "]")
+		}
+		destinationConfig := tlsConfig.Clone()
+		destinationConfig.Dest = destination.ServerOptions.Build().String()
+		destinationConfig.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			return destinationDialer.DialContext(ctx, network, M.ParseSocksaddr(addr))
+		}
+		for _, serverName := range destination.ServerName {
+			serverConfig.destinations[serverName] = destinationConfig
+		}
+		// without handshake.server, unmatched clients are forwarded to the
+		// first destination like any failed authentication
+		if i == 0 && options.Reality.Handshake.Server == "" {
+			tlsConfig.Dest = destinationConfig.Dest
+			tlsConfig.DialContext = destinationConfig.DialContext
+		}
+	}
+	return serverConfig, nil
 }
 
 func (c *RealityServerConfig) ServerName() string {
@@ -131,6 +183,9 @@ func (c *RealityServerConfig) NextProtos() []string {
 
 func (c *RealityServerConfig) SetNextProtos(nextProto []string) {
 	c.config.NextProtos = nextProto
+	for _, destinationConfig := range c.destinations {
+		destinationConfig.NextProtos = nextProto
+	}
 }
 
 func (c *RealityServerConfig) Config() (*tls.Config, error) {
@@ -154,17 +209,84 @@ func (c *RealityServerConfig) Server(conn net.Conn) (Conn, error) {
 }
 
 func (c *RealityServerConfig) ServerHandshake(ctx context.Context, conn net.Conn) (Conn, error) {
-	tlsConn, err := reality.Server(ctx, conn, c.config)
+	config := c.config
+	if len(c.destinations) > 0 {
+		buffer := buf.NewPacket()
+		serverName, err := readClientHello(ctx, conn, buffer)
+		if err != nil {
+			buffer.Release()
+			return nil, E.Cause(err, "REALITY: read client hello")
+		}
+		if destinationConfig, loaded := c.destinations[serverName]; loaded {
+			config = destinationConfig
+		}
+		conn = bufio.NewCachedConn(conn, buffer)
+	}
+	tlsConn, err := reality.Server(ctx, conn, config)
 	if err != nil {
 		return nil, err
 	}
 	return &realityConnWrapper{Conn: tlsConn}, nil
 }
 
+// readClientHello reads the complete ClientHello into buffer, which may span
+// several records, until the handshake deadline. Non-TLS content is left for
+// REALITY to forward to the handshake destination.
+func readClientHello(ctx context.Context, conn net.Conn, buffer *buf.Buffer) (string, error) {
+	if deadline, loaded := ctx.Deadline(); loaded {
+		err := conn.SetReadDeadline(deadline)
+		if err != nil {
+			return "", err
+		}
+		defer conn.SetReadDeadline(time.Time{})
+	}
+	var handshake []byte
+	for {
+		_, err := buffer.ReadFullFrom(conn, tlsRecordHeaderLength)
+		if err != nil {
+			return "", err
+		}
+		header := buffer.Bytes()[buffer.Len()-tlsRecordHeaderLength:]
+		if header[0] != tlsRecordTypeHandshake {
+			return "", nil
+		}
+		start := buffer.Len()
+		_, err = buffer.ReadFullFrom(conn, int(binary.BigEndian.Uint16(header[3:])))
+		if err != nil {
+			return "", err
+		}
+		handshake = append(handshake, buffer.Bytes()[start:]...)
+		if len(handshake) >= 4 {
+			messageLength := int(handshake[1])<<16 | int(handshake[2])<<8 | int(handshake[3])
+			if len(handshake) >= 4+messageLength {
+				break
+			}
+		}
+	}
+	metadata, _ := sniff.TLSClientHello(ctx, bytes.NewReader(buffer.Bytes()))
+	if metadata == nil {
+		return "", nil
+	}
+	return metadata.Domain, nil
+}
+
 func (c *RealityServerConfig) Clone() Config {
-	return &RealityServerConfig{
+	config := &RealityServerConfig{
 		config: c.config.Clone(),
 	}
+	if c.destinations != nil {
+		config.destinations = make(map[string]*reality.Config)
+		clonedConfigs := make(map[*reality.Config]*reality.Config)
+		for serverName, destinationConfig := range c.destinations {
+			clonedConfig, loaded := clonedConfigs[destinationConfig]
+			if !loaded {
+				clonedConfig = destinationConfig.Clone()
+				clonedConfigs[destinationConfig] = clonedConfig
+			}
+			config.destinations[serverName] = clonedConfig
+		}
+	}
+	return config
 }
 
 var _ Conn = (*realityConnWrapper)(nil)
@@ -9,6 +9,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
 	aTLS "github.com/sagernet/sing/common/tls"
 )
 
@@ -16,6 +17,9 @@ func NewServer(ctx context.Context, logger log.Logger, options option.InboundTLS
 	if !options.Enabled {
 		return nil, nil
 	}
+	if options.PQKeyExchange && (options.ECH != nil && options.ECH.Enabled || options.Reality != nil && options.Reality.Enabled) {
+		return nil, E.New("post-quantum key exchange is unavailable in ECH and reality")
+	}
 	if options.ECH != nil && options.ECH.Enabled {
 		return NewECHServer(ctx, logger, options)
 	} else if options.Reality != nil && options.Reality.Enabled {
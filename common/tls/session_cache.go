@@ -0,0 +1,168 @@
+package tls
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"strconv"
+	"sync"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/cache"
+	"github.com/sagernet/sing/service"
+)
+
+const sessionCacheSize = 1024
+
+// SessionCache holds TLS resumption states shared by the outbound clients of
+// an instance that enable session_cache, and writes them through to the cache
+// file if enabled.
+type SessionCache struct {
+	ctx   context.Context
+	cache *cache.LruCache[string, []byte]
+}
+
+func NewSessionCache(ctx context.Context) *SessionCache {
+	return &SessionCache{
+		ctx:   ctx,
+		cache: cache.New[string, []byte](cache.WithSize[string, []byte](sessionCacheSize)),
+	}
+}
+
+func (c *SessionCache) cacheFile() adapter.CacheFile {
+	cacheFile := service.FromContext[adapter.CacheFile](c.ctx)
+	if cacheFile == nil || !cacheFile.StoreTLSSession() {
+		return nil
+	}
+	return cacheFile
+}
+
+func (c *SessionCache) load(key string) (ticket []byte, state []byte, loaded bool) {
+	content, loaded := c.cache.Load(key)
+	if !loaded {
+		cacheFile := c.cacheFile()
+		if cacheFile == nil {
+			return
+		}
+		content = cacheFile.LoadTLSSession(key)
+		if content == nil {
+			return
+		}
+		c.cache.Store(key, content)
+	}
+	if len(content) < 2 {
+		return
+	}
+	ticketLength := int(binary.BigEndian.Uint16(content))
+	if len(content) < 2+ticketLength {
+		return
+	}
+	return content[2 : 2+ticketLength], content[2+ticketLength:], true
+}
+
+func (c *SessionCache) store(key string, ticket []byte, state []byte) {
+	if len(ticket) > 0xffff {
+		return
+	}
+	content := make([]byte, 2+len(ticket)+len(state))
+	binary.BigEndian.PutUint16(content, uint16(len(ticket)))
+	copy(content[2:], ticket)
+	copy(content[2+len(ticket):], state)
+	c.cache.Store(key, content)
+	if cacheFile := c.cacheFile(); cacheFile != nil {
+		go cacheFile.SaveTLSSession(key, content)
+	}
+}
+
+func (c *SessionCache) delete(key string) {
+	c.cache.Delete(key)
+	if cacheFile := c.cacheFile(); cacheFile != nil {
+		go cacheFile.SaveTLSSession(key, nil)
+	}
+}
+
+var sessionCacheAccess sync.Mutex
+
+// sessionCacheFromContext returns the shared session cache, creating it on the
+// first client that enables session_cache.
+func sessionCacheFromContext(ctx context.Context) *SessionCache {
+	registry := service.RegistryFromContext(ctx)
+	if registry == nil {
+		return nil
+	}
+	sessionCacheAccess.Lock()
+	defer sessionCacheAccess.Unlock()
+	sessionCache := service.FromContext[*SessionCache](ctx)
+	if sessionCache == nil {
+		sessionCache = NewSessionCache(ctx)
+		service.MustRegister[*SessionCache](ctx, sessionCache)
+	}
+	return sessionCache
+}
+
+// sessionCachePrefix scopes cached sessions to the outbound and its trust
+// configuration, so that a session established under one set of roots, pins
+// or insecure is never resumed by a client that would not accept the server.
+func sessionCachePrefix(ctx context.Context, options option.OutboundTLSOptions) string {
+	var tag string
+	if metadata := adapter.ContextFrom(ctx); metadata != nil {
+		tag = metadata.Outbound
+	}
+	hash := sha256.New()
+	for _, field := range [][]string{
+		options.Certificate,
+		{options.CertificatePath, options.CertificateDirectoryPath},
+		options.CertificatePublicKeySHA256,
+		{strconv.FormatBool(options.Insecure)},
+	} {
+		for _, value := range field {
+			hash.Write([]byte(value))
+			hash.Write([]byte{0})
+		}
+		hash.Write([]byte{1})
+	}
+	return tag + ":" + hex.EncodeToString(hash.Sum(nil)[:8]) + ":"
+}
+
+type stdSessionCache struct {
+	*SessionCache
+	prefix string
+}
+
+func (c *stdSessionCache) Get(sessionKey string) (*tls.ClientSessionState, bool) {
+	sessionKey = c.prefix + sessionKey
+	ticket, stateBytes, loaded := c.load(sessionKey)
+	if !loaded {
+		return nil, false
+	}
+	state, err := tls.ParseSessionState(stateBytes)
+	if err != nil {
+		c.delete(sessionKey)
+		return nil, false
+	}
+	session, err := tls.NewResumptionState(ticket, state)
+	if err != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+func (c *stdSessionCache) Put(sessionKey string, session *tls.ClientSessionState) {
+	sessionKey = c.prefix + sessionKey
+	if session == nil {
+		c.delete(sessionKey)
+		return
+	}
+	ticket, state, err := session.ResumptionState()
+	if err != nil || state == nil {
+		return
+	}
+	stateBytes, err := state.Bytes()
+	if err != nil {
+		return
+	}
+	c.store(sessionKey, ticket, stateBytes)
+}
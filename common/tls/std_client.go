@@ -6,8 +6,6 @@ import (
 	"crypto/x509"
 	"net"
 	"net/netip"
-	"os"
-	"strings"
 
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -72,6 +70,7 @@ func NewSTDClient(ctx context.Context, serverAddress string, options option.Outb
 		tlsConfig.InsecureSkipVerify = true
 		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
 			verifyOptions := x509.VerifyOptions{
+				Roots:         tlsConfig.RootCAs,
 				DNSName:       serverName,
 				Intermediates: x509.NewCertPool(),
 			}
@@ -111,22 +110,39 @@ func NewSTDClient(ctx context.Context, serverAddress string, options option.Outb
 			return nil, E.New("unknown cipher_suite: ", cipherSuite)
 		}
 	}
-	var certificate []byte
-	if len(options.Certificate) > 0 {
-		certificate = []byte(strings.Join(options.Certificate, "\n"))
-	} else if options.CertificatePath != "" {
-		content, err := os.ReadFile(options.CertificatePath)
+	if options.PQKeyExchange {
+		err := setPQKeyExchange(&tlsConfig)
 		if err != nil {
-			return nil, E.Cause(err, "read certificate")
+			return nil, err
 		}
-		certificate = content
 	}
-	if len(certificate) > 0 {
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(certificate) {
-			return nil, E.New("failed to parse certificate:\n\n", certificate)
+	rootCAs, err := loadRootCAs(options)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = rootCAs
+	if len(options.CertificatePublicKeySHA256) > 0 {
+		pins, err := parsePublicKeyPins(options.CertificatePublicKeySHA256)
+		if err != nil {
+			return nil, err
+		}
+		// VerifyConnection, unlike VerifyPeerCertificate, also runs when a
+		// session is resumed.
+		verifyConnection := tlsConfig.VerifyConnection
+		tlsConfig.VerifyConnection = func(state tls.ConnectionState) error {
+			if verifyConnection != nil {
+				err := verifyConnection(state)
+				if err != nil {
+					return err
+				}
+			}
+			return verifyPublicKeyPins(pins, state.PeerCertificates)
+		}
+	}
+	if options.SessionCache {
+		if sessionCache := sessionCacheFromContext(ctx); sessionCache != nil {
+			tlsConfig.ClientSessionCache = &stdSessionCache{sessionCache, sessionCachePrefix(ctx, options)}
 		}
-		tlsConfig.RootCAs = certPool
 	}
 	return &STDClientConfig{&tlsConfig}, nil
 }
@@ -5,7 +5,9 @@ import (
 	"crypto/tls"
 	"net"
 	"os"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
@@ -26,6 +28,7 @@ type STDServerConfig struct {
 	key             []byte
 	certificatePath string
 	keyPath         string
+	keyPair         atomic.Pointer[tls.Certificate]
 	watcher         *fswatch.Watcher
 }
 
@@ -106,33 +109,46 @@ func (c *STDServerConfig) startWatcher() error {
 	if err != nil {
 		return err
 	}
+	err = watcher.Start()
+	if err != nil {
+		return err
+	}
 	c.watcher = watcher
 	return nil
 }
 
+// certificateUpdated reloads both files, since renewal tools usually replace
+// the certificate and the key one after another.
 func (c *STDServerConfig) certificateUpdated(path string) error {
-	if path == c.certificatePath {
-		certificate, err := os.ReadFile(c.certificatePath)
+	certificate := c.certificate
+	if c.certificatePath != "" {
+		content, err := os.ReadFile(c.certificatePath)
 		if err != nil {
 			return E.Cause(err, "reload certificate from ", c.certificatePath)
 		}
-		c.certificate = certificate
-	} else if path == c.keyPath {
-		key, err := os.ReadFile(c.keyPath)
+		certificate = content
+	}
+	key := c.key
+	if c.keyPath != "" {
+		content, err := os.ReadFile(c.keyPath)
 		if err != nil {
 			return E.Cause(err, "reload key from ", c.keyPath)
 		}
-		c.key = key
+		key = content
 	}
-	keyPair, err := tls.X509KeyPair(c.certificate, c.key)
+	keyPair, err := tls.X509KeyPair(certificate, key)
 	if err != nil {
-		return E.Cause(err, "reload key pair")
+		return E.Cause(err, "reload key pair after ", path, " changed")
 	}
-	c.config.Certificates = []tls.Certificate{keyPair}
+	c.keyPair.Store(&keyPair)
 	c.logger.Info("reloaded TLS certificate")
 	return nil
 }
 
+func (c *STDServerConfig) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.keyPair.Load(), nil
+}
+
 func (c *STDServerConfig) Close() error {
 	if c.acmeService != nil {
 		return c.acmeService.Close()
@@ -195,8 +211,15 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 			return nil, E.New("unknown cipher_suite: ", cipherSuite)
 		}
 	}
+	if options.PQKeyExchange {
+		err := setPQKeyExchange(tlsConfig)
+		if err != nil {
+			return nil, err
+		}
+	}
 	var certificate []byte
 	var key []byte
+	var keyPair *tls.Certificate
 	if acmeService == nil {
 		if len(options.Certificate) > 0 {
 			certificate = []byte(strings.Join(options.Certificate, "\n"))
@@ -227,20 +250,33 @@ func NewSTDServer(ctx context.Context, logger log.Logger, options option.Inbound
 				return nil, E.New("missing key")
 			}
 
-			keyPair, err := tls.X509KeyPair(certificate, key)
+			loadedKeyPair, err := tls.X509KeyPair(certificate, key)
 			if err != nil {
 				return nil, E.Cause(err, "parse x509 key pair")
 			}
-			tlsConfig.Certificates = []tls.Certificate{keyPair}
+			keyPair = &loadedKeyPair
 		}
 	}
-	return &STDServerConfig{
-		config:          tlsConfig,
-		logger:          logger,
-		acmeService:     acmeService,
-		certificate:     certificate,
-		key:             key,
-		certificatePath: options.CertificatePath,
-		keyPath:         options.KeyPath,
-	}, nil
+	serverConfig := &STDServerConfig{
+		config:      tlsConfig,
+		logger:      logger,
+		acmeService: acmeService,
+		certificate: certificate,
+		key:         key,
+	}
+	if keyPair != nil {
+		if len(options.Certificate) == 0 && options.CertificatePath != "" {
+			serverConfig.certificatePath, _ = filepath.Abs(options.CertificatePath)
+		}
+		if len(options.Key) == 0 && options.KeyPath != "" {
+			serverConfig.keyPath, _ = filepath.Abs(options.KeyPath)
+		}
+		if serverConfig.certificatePath != "" || serverConfig.keyPath != "" {
+			serverConfig.keyPair.Store(keyPair)
+			tlsConfig.GetCertificate = serverConfig.getCertificate
+		} else {
+			tlsConfig.Certificates = []tls.Certificate{*keyPair}
+		}
+	}
+	return serverConfig, nil
 }
@@ -5,12 +5,9 @@ package tls
 import (
 	"context"
 	"crypto/tls"
-	"crypto/x509"
 	"math/rand"
 	"net"
 	"net/netip"
-	"os"
-	"strings"
 
 	"github.com/sagernet/sing-box/option"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -168,30 +165,88 @@ func NewUTLSClient(ctx context.Context, serverAddress string, options option.Out
 			return nil, E.New("unknown cipher_suite: ", cipherSuite)
 		}
 	}
-	var certificate []byte
-	if len(options.Certificate) > 0 {
-		certificate = []byte(strings.Join(options.Certificate, "\n"))
-	} else if options.CertificatePath != "" {
-		content, err := os.ReadFile(options.CertificatePath)
+	rootCAs, err := loadRootCAs(options)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig.RootCAs = rootCAs
+	if len(options.CertificatePublicKeySHA256) > 0 {
+		pins, err := parsePublicKeyPins(options.CertificatePublicKeySHA256)
 		if err != nil {
-			return nil, E.Cause(err, "read certificate")
+			return nil, err
+		}
+		// VerifyConnection, unlike VerifyPeerCertificate, also runs when a
+		// session is resumed.
+		verifyConnection := tlsConfig.VerifyConnection
+		tlsConfig.VerifyConnection = func(state utls.ConnectionState) error {
+			if verifyConnection != nil {
+				err := verifyConnection(state)
+				if err != nil {
+					return err
+				}
+			}
+			return verifyPublicKeyPins(pins, state.PeerCertificates)
 		}
-		certificate = content
 	}
-	if len(certificate) > 0 {
-		certPool := x509.NewCertPool()
-		if !certPool.AppendCertsFromPEM(certificate) {
-			return nil, E.New("failed to parse certificate:\n\n", certificate)
+	if options.SessionCache {
+		if sessionCache := sessionCacheFromContext(ctx); sessionCache != nil {
+			tlsConfig.ClientSessionCache = &utlsSessionCache{sessionCache, "utls:" + sessionCachePrefix(ctx, options)}
+			tlsConfig.OmitEmptyPsk = true
 		}
-		tlsConfig.RootCAs = certPool
 	}
 	id, err := uTLSClientHelloID(options.UTLS.Fingerprint)
 	if err != nil {
 		return nil, err
 	}
+	if options.PQKeyExchange {
+		id, err = uTLSPQClientHelloID(id)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return &UTLSClientConfig{&tlsConfig, id}, nil
 }
 
+type utlsSessionCache struct {
+	*SessionCache
+	prefix string
+}
+
+func (c *utlsSessionCache) Get(sessionKey string) (*utls.ClientSessionState, bool) {
+	sessionKey = c.prefix + sessionKey
+	ticket, stateBytes, loaded := c.load(sessionKey)
+	if !loaded {
+		return nil, false
+	}
+	state, err := utls.ParseSessionState(stateBytes)
+	if err != nil {
+		c.delete(sessionKey)
+		return nil, false
+	}
+	session, err := utls.NewResumptionState(ticket, state)
+	if err != nil {
+		return nil, false
+	}
+	return session, true
+}
+
+func (c *utlsSessionCache) Put(sessionKey string, session *utls.ClientSessionState) {
+	sessionKey = c.prefix + sessionKey
+	if session == nil {
+		c.delete(sessionKey)
+		return
+	}
+	ticket, state, err := session.ResumptionState()
+	if err != nil || state == nil {
+		return
+	}
+	stateBytes, err := state.Bytes()
+	if err != nil {
+		return
+	}
+	c.store(sessionKey, ticket, stateBytes)
+}
+
 var (
 	randomFingerprint     utls.ClientHelloID
 	randomizedFingerprint utls.ClientHelloID
@@ -215,6 +270,19 @@ func init() {
 	randomizedFingerprint.Weights = &weights
 }
 
+// uTLSPQClientHelloID maps Chrome fingerprints to their variants sending the
+// X25519Kyber768Draft00 key share.
+func uTLSPQClientHelloID(id utls.ClientHelloID) (utls.ClientHelloID, error) {
+	switch id {
+	case utls.HelloChrome_Auto, utls.HelloChrome_115_PQ:
+		return utls.HelloChrome_115_PQ, nil
+	case utls.HelloChrome_100_PSK, utls.HelloChrome_112_PSK_Shuf, utls.HelloChrome_114_Padding_PSK_Shuf, utls.HelloChrome_115_PQ_PSK:
+		return utls.HelloChrome_115_PQ_PSK, nil
+	default:
+		return utls.ClientHelloID{}, E.New("post-quantum key exchange is unavailable with uTLS fingerprint ", id.Str())
+	}
+}
+
 func uTLSClientHelloID(name string) (utls.ClientHelloID, error) {
 	switch name {
 	case "chrome", "":
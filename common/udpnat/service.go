@@ -0,0 +1,236 @@
+package udpnat
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/canceler"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const flowBacklog = 64
+
+type Handler interface {
+	N.UDPConnectionHandler
+	E.Handler
+}
+
+type Service[K comparable] struct {
+	handler           Handler
+	timeout           time.Duration
+	access            sync.RWMutex
+	flows             map[K]*conn
+	maxFlows          int
+	maxFlowsPerSource int
+	sourceFlows       map[netip.Addr]int
+}
+
+func New[K comparable](timeout time.Duration, handler Handler) *Service[K] {
+	return &Service[K]{
+		handler:     handler,
+		timeout:     timeout,
+		flows:       make(map[K]*conn),
+		sourceFlows: make(map[netip.Addr]int),
+	}
+}
+
+// SetLimit limits the number of flows in total and for each source address,
+// zero means unlimited. Packets of new flows over the limit are dropped.
+func (s *Service[T]) SetLimit(maxFlows int, maxFlowsPerSource int) {
+	s.access.Lock()
+	s.maxFlows = maxFlows
+	s.maxFlowsPerSource = maxFlowsPerSource
+	s.access.Unlock()
+}
+
+func (s *Service[T]) WriteIsThreadUnsafe() {
+}
+
+// NewContextPacket sends buffer to the flow of key, and returns false if the
+// packet is dropped by the flow limit.
+func (s *Service[T]) NewContextPacket(ctx context.Context, key T, buffer *buf.Buffer, metadata M.Metadata, init func(natConn N.PacketConn) (context.Context, N.PacketWriter)) bool {
+	s.access.RLock()
+	c, loaded := s.flows[key]
+	s.access.RUnlock()
+	if !loaded {
+		s.access.Lock()
+		c, loaded = s.flows[key]
+		if !loaded {
+			source := metadata.Source.Addr.Unmap()
+			if s.maxFlows > 0 && len(s.flows) >= s.maxFlows || s.maxFlowsPerSource > 0 && s.sourceFlows[source] >= s.maxFlowsPerSource {
+				s.access.Unlock()
+				buffer.Release()
+				return false
+			}
+			c = &conn{
+				data:       make(chan packet, flowBacklog),
+				sourceAddr: source,
+				localAddr:  metadata.Source,
+				remoteAddr: metadata.Destination,
+			}
+			c.ctx, c.cancel = common.ContextWithCancelCause(ctx)
+			c.timer = defaultWheel.Add(s.timeout, func() {
+				s.delete(key, c)
+				c.Close()
+			})
+			s.flows[key] = c
+			s.sourceFlows[source]++
+		}
+		s.access.Unlock()
+		if !loaded {
+			var handlerCtx context.Context
+			handlerCtx, c.source = init(c)
+			defaultWorkers.Go(func() {
+				err := s.handler.NewPacketConnection(handlerCtx, c, metadata)
+				if err != nil {
+					s.handler.NewError(handlerCtx, err)
+				}
+				c.Close()
+				s.delete(key, c)
+			})
+		}
+	}
+	if common.Done(c.ctx) {
+		s.delete(key, c)
+		if !common.Done(ctx) {
+			return s.NewContextPacket(ctx, key, buffer, metadata, init)
+		}
+		buffer.Release()
+		return true
+	}
+	c.timer.Refresh()
+	select {
+	case c.data <- packet{data: buffer, destination: metadata.Destination}:
+	default:
+		// drop instead of blocking the shared inbound loop on a slow flow
+		buffer.Release()
+	}
+	return true
+}
+
+func (s *Service[T]) delete(key T, c *conn) {
+	s.access.Lock()
+	if s.flows[key] == c {
+		delete(s.flows, key)
+		s.sourceFlows[c.sourceAddr]--
+		if s.sourceFlows[c.sourceAddr] == 0 {
+			delete(s.sourceFlows, c.sourceAddr)
+		}
+	}
+	s.access.Unlock()
+	c.timer.Remove()
+}
+
+type DirectBackWriter struct {
+	Source N.PacketConn
+	Nat    N.PacketConn
+}
+
+func (w *DirectBackWriter) WritePacket(buffer *buf.Buffer, addr M.Socksaddr) error {
+	return w.Source.WritePacket(buffer, M.SocksaddrFromNet(w.Nat.LocalAddr()))
+}
+
+func (w *DirectBackWriter) Upstream() any {
+	return w.Source
+}
+
+type packet struct {
+	data        *buf.Buffer
+	destination M.Socksaddr
+}
+
+var _ canceler.PacketConn = (*conn)(nil)
+
+type conn struct {
+	ctx        context.Context
+	cancel     common.ContextCancelCauseFunc
+	timer      *timerEntry
+	data       chan packet
+	sourceAddr netip.Addr
+	localAddr  M.Socksaddr
+	remoteAddr M.Socksaddr
+	source     N.PacketWriter
+}
+
+func (c *conn) ReadPacket(buffer *buf.Buffer) (addr M.Socksaddr, err error) {
+	select {
+	case p := <-c.data:
+		_, err = buffer.ReadOnceFrom(p.data)
+		p.data.Release()
+		return p.destination, err
+	case <-c.ctx.Done():
+		return M.Socksaddr{}, io.ErrClosedPipe
+	}
+}
+
+func (c *conn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.timer.Refresh()
+	return c.source.WritePacket(buffer, destination)
+}
+
+func (c *conn) Close() error {
+	select {
+	case <-c.ctx.Done():
+		return nil
+	default:
+		c.cancel(net.ErrClosed)
+	}
+	for {
+		select {
+		case p := <-c.data:
+			p.data.Release()
+			continue
+		default:
+		}
+		break
+	}
+	if sourceCloser, sourceIsCloser := c.source.(io.Closer); sourceIsCloser {
+		return sourceCloser.Close()
+	}
+	return nil
+}
+
+func (c *conn) Timeout() time.Duration {
+	return c.timer.Timeout()
+}
+
+func (c *conn) SetTimeout(timeout time.Duration) {
+	c.timer.SetTimeout(timeout)
+}
+
+func (c *conn) LocalAddr() net.Addr {
+	return c.localAddr
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	return c.remoteAddr
+}
+
+func (c *conn) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *conn) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *conn) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *conn) NeedAdditionalReadDeadline() bool {
+	return true
+}
+
+func (c *conn) Upstream() any {
+	return c.source
+}
@@ -0,0 +1,69 @@
+package udpnat
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testHandler struct {
+	conns chan N.PacketConn
+}
+
+func (h *testHandler) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	h.conns <- conn
+	for {
+		buffer := buf.NewPacket()
+		_, err := conn.ReadPacket(buffer)
+		buffer.Release()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func (h *testHandler) NewError(ctx context.Context, err error) {
+}
+
+type testPacketWriter struct{}
+
+func (w *testPacketWriter) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	buffer.Release()
+	return nil
+}
+
+func TestServiceLimit(t *testing.T) {
+	t.Parallel()
+	handler := &testHandler{conns: make(chan N.PacketConn, 8)}
+	service := New[netip.AddrPort](time.Minute, handler)
+	service.SetLimit(3, 2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	newPacket := func(source string) bool {
+		metadata := M.Metadata{
+			Source:      M.ParseSocksaddr(source),
+			Destination: M.ParseSocksaddr("1.1.1.1:53"),
+		}
+		return service.NewContextPacket(ctx, metadata.Source.AddrPort(), buf.As([]byte("ping")), metadata, func(natConn N.PacketConn) (context.Context, N.PacketWriter) {
+			return ctx, &testPacketWriter{}
+		})
+	}
+	require.True(t, newPacket("192.168.1.2:1000"))
+	require.True(t, newPacket("192.168.1.2:1001"))
+	require.True(t, newPacket("192.168.1.2:1000"))
+	require.False(t, newPacket("192.168.1.2:1002"))
+	require.True(t, newPacket("192.168.1.3:1000"))
+	require.False(t, newPacket("192.168.1.4:1000"))
+	conn := <-handler.conns
+	require.NoError(t, conn.Close())
+	require.Eventually(t, func() bool {
+		return newPacket("192.168.1.4:1000")
+	}, time.Second, 10*time.Millisecond)
+}
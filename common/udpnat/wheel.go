@@ -0,0 +1,145 @@
+package udpnat
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	wheelTick  = time.Second
+	wheelSlots = 64
+)
+
+// timerWheel expires idle flows of all NAT services from a single goroutine.
+// Entries are refreshed lock-free by storing their last active time, and are
+// only rescheduled when their slot comes up.
+type timerWheel struct {
+	access  sync.Mutex
+	slots   [wheelSlots][]*timerEntry
+	current int
+	running bool
+}
+
+type timerEntry struct {
+	wheel      *timerWheel
+	timeout    time.Duration
+	lastActive atomic.Int64
+	removed    atomic.Bool
+	slot       int
+	rounds     int
+	onExpire   func()
+}
+
+var defaultWheel timerWheel
+
+func (w *timerWheel) Add(timeout time.Duration, onExpire func()) *timerEntry {
+	entry := &timerEntry{
+		wheel:    w,
+		timeout:  timeout,
+		onExpire: onExpire,
+	}
+	entry.lastActive.Store(time.Now().UnixNano())
+	w.access.Lock()
+	w.schedule(entry, timeout)
+	if !w.running {
+		w.running = true
+		go w.loop()
+	}
+	w.access.Unlock()
+	return entry
+}
+
+func (w *timerWheel) schedule(entry *timerEntry, after time.Duration) {
+	ticks := int((after + wheelTick - 1) / wheelTick)
+	if ticks < 1 {
+		ticks = 1
+	}
+	entry.rounds = (ticks - 1) / wheelSlots
+	index := (w.current + ticks) % wheelSlots
+	entry.slot = index
+	w.slots[index] = append(w.slots[index], entry)
+}
+
+func (w *timerWheel) loop() {
+	ticker := time.NewTicker(wheelTick)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !w.tick() {
+			return
+		}
+	}
+}
+
+func (w *timerWheel) tick() bool {
+	now := time.Now().UnixNano()
+	var expired []*timerEntry
+	w.access.Lock()
+	w.current = (w.current + 1) % wheelSlots
+	entries := w.slots[w.current]
+	w.slots[w.current] = nil
+	var pending int
+	for _, entry := range entries {
+		if entry.removed.Load() {
+			continue
+		}
+		if entry.rounds > 0 {
+			entry.rounds--
+			entry.slot = w.current
+			w.slots[w.current] = append(w.slots[w.current], entry)
+			continue
+		}
+		idle := time.Duration(now - entry.lastActive.Load())
+		if idle >= entry.timeout {
+			expired = append(expired, entry)
+			continue
+		}
+		w.schedule(entry, entry.timeout-idle)
+	}
+	for _, slot := range w.slots {
+		pending += len(slot)
+	}
+	if pending == 0 {
+		w.running = false
+	}
+	w.access.Unlock()
+	for _, entry := range expired {
+		entry.onExpire()
+	}
+	return pending > 0
+}
+
+func (e *timerEntry) Refresh() {
+	e.lastActive.Store(time.Now().UnixNano())
+}
+
+func (e *timerEntry) Timeout() time.Duration {
+	e.wheel.access.Lock()
+	defer e.wheel.access.Unlock()
+	return e.timeout
+}
+
+// SetTimeout changes the idle timeout and reschedules the entry, so that a
+// shorter timeout takes effect without waiting for the old slot.
+func (e *timerEntry) SetTimeout(timeout time.Duration) {
+	w := e.wheel
+	w.access.Lock()
+	defer w.access.Unlock()
+	if e.timeout == timeout {
+		return
+	}
+	e.timeout = timeout
+	slot := w.slots[e.slot]
+	for i, entry := range slot {
+		if entry == e {
+			w.slots[e.slot] = append(slot[:i], slot[i+1:]...)
+			idle := time.Duration(time.Now().UnixNano() - e.lastActive.Load())
+			w.schedule(e, timeout-idle)
+			return
+		}
+	}
+}
+
+func (e *timerEntry) Remove() {
+	e.removed.Store(true)
+}
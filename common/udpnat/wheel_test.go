@@ -0,0 +1,143 @@
+package udpnat
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTimerWheelSchedule(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		current int
+		after   time.Duration
+		index   int
+		rounds  int
+	}{
+		{"immediate", 0, 0, 1, 0},
+		{"sub tick", 0, time.Millisecond, 1, 0},
+		{"one tick", 0, wheelTick, 1, 0},
+		{"round up", 0, wheelTick + time.Millisecond, 2, 0},
+		{"wrap", wheelSlots - 1, 2 * wheelTick, 1, 0},
+		{"full round", 0, wheelSlots * wheelTick, 0, 0},
+		{"multiple rounds", 3, (2*wheelSlots + 5) * wheelTick, 8, 2},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			wheel := &timerWheel{current: testCase.current}
+			entry := &timerEntry{}
+			wheel.schedule(entry, testCase.after)
+			require.Equal(t, testCase.rounds, entry.rounds)
+			require.Len(t, wheel.slots[testCase.index], 1)
+		})
+	}
+}
+
+func TestTimerWheelTick(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		timeout time.Duration
+		idle    time.Duration
+		removed bool
+		expired bool
+	}{
+		{"idle", time.Minute, 2 * time.Minute, false, true},
+		{"active", time.Minute, 0, false, false},
+		{"removed", time.Minute, 2 * time.Minute, true, false},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			wheel := &timerWheel{}
+			var expired atomic.Bool
+			entry := &timerEntry{
+				timeout:  testCase.timeout,
+				onExpire: func() { expired.Store(true) },
+			}
+			entry.lastActive.Store(time.Now().Add(-testCase.idle).UnixNano())
+			if testCase.removed {
+				entry.Remove()
+			}
+			wheel.schedule(entry, wheelTick)
+			pending := wheel.tick()
+			require.Equal(t, testCase.expired, expired.Load())
+			require.Equal(t, !testCase.expired && !testCase.removed, pending)
+		})
+	}
+}
+
+func TestTimerWheelRounds(t *testing.T) {
+	t.Parallel()
+	wheel := &timerWheel{}
+	var expired atomic.Bool
+	entry := &timerEntry{
+		timeout:  (wheelSlots + 1) * wheelTick,
+		onExpire: func() { expired.Store(true) },
+	}
+	entry.lastActive.Store(time.Now().Add(-time.Hour).UnixNano())
+	wheel.schedule(entry, entry.timeout)
+	for i := 0; i < wheelSlots; i++ {
+		require.True(t, wheel.tick())
+		require.False(t, expired.Load())
+	}
+	require.False(t, wheel.tick())
+	require.True(t, expired.Load())
+}
+
+func TestTimerEntrySetTimeout(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		timeout time.Duration
+		updated time.Duration
+		idle    time.Duration
+		index   int
+	}{
+		{"shorten", time.Minute, 10 * time.Second, 0, 10},
+		{"shorten idle", time.Minute, 10 * time.Second, 20 * time.Second, 1},
+		{"extend", time.Minute, 10 * time.Minute, 0, 600 % wheelSlots},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			wheel := &timerWheel{}
+			entry := &timerEntry{
+				wheel:   wheel,
+				timeout: testCase.timeout,
+			}
+			entry.lastActive.Store(time.Now().Add(-testCase.idle).UnixNano())
+			wheel.schedule(entry, testCase.timeout)
+			entry.SetTimeout(testCase.updated)
+			require.Equal(t, testCase.updated, entry.Timeout())
+			var scheduled int
+			for _, slot := range wheel.slots {
+				scheduled += len(slot)
+			}
+			require.Equal(t, 1, scheduled)
+			require.Len(t, wheel.slots[testCase.index], 1)
+		})
+	}
+}
+
+func TestWorkerPoolReuse(t *testing.T) {
+	t.Parallel()
+	pool := &workerPool{jobs: make(chan func())}
+	done := make(chan struct{})
+	pool.Go(func() { done <- struct{}{} })
+	<-done
+	// the first worker is now idle and takes the next job
+	require.Eventually(t, func() bool {
+		select {
+		case pool.jobs <- func() { done <- struct{}{} }:
+			return true
+		default:
+			return false
+		}
+	}, time.Second, time.Millisecond)
+	<-done
+}
@@ -0,0 +1,47 @@
+package udpnat
+
+import (
+	"time"
+)
+
+const workerIdleTimeout = 30 * time.Second
+
+// workerPool runs flow handlers of all NAT services on shared goroutines. A
+// worker that finished a flow waits for the next one instead of exiting, so
+// short-lived flows such as DNS queries reuse workers rather than creating a
+// goroutine each.
+type workerPool struct {
+	jobs chan func()
+}
+
+var defaultWorkers = workerPool{
+	jobs: make(chan func()),
+}
+
+func (p *workerPool) Go(job func()) {
+	select {
+	case p.jobs <- job:
+	default:
+		go p.work(job)
+	}
+}
+
+func (p *workerPool) work(job func()) {
+	timer := time.NewTimer(workerIdleTimeout)
+	defer timer.Stop()
+	for {
+		job()
+		if !timer.Stop() {
+			select {
+			case <-timer.C:
+			default:
+			}
+		}
+		timer.Reset(workerIdleTimeout)
+		select {
+		case job = <-p.jobs:
+		case <-timer.C:
+			return
+		}
+	}
+}
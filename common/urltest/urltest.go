@@ -8,25 +8,42 @@ import (
 	"sync"
 	"time"
 
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing/common"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 )
 
+// HistoryLimit is the number of latency samples kept per outbound.
+const HistoryLimit = 10
+
 type History struct {
 	Time  time.Time `json:"time"`
 	Delay uint16    `json:"delay"`
 }
 
+// HistoryCache persists latency samples across restarts.
+type HistoryCache interface {
+	LoadURLTestHistories() map[string][]*History
+	// SaveURLTestHistories replaces all saved samples with histories.
+	SaveURLTestHistories(histories map[string][]*History) error
+}
+
 type HistoryStorage struct {
-	access       sync.RWMutex
-	delayHistory map[string]*History
-	updateHook   chan<- struct{}
+	access         sync.RWMutex
+	saveAccess     sync.Mutex
+	delayHistory   map[string]*History
+	histories      map[string][]*History
+	cache          HistoryCache
+	outboundExists func(tag string) bool
+	saveTimer      *time.Timer
+	updateHook     chan<- struct{}
 }
 
 func NewHistoryStorage() *HistoryStorage {
 	return &HistoryStorage{
 		delayHistory: make(map[string]*History),
+		histories:    make(map[string][]*History),
 	}
 }
 
@@ -34,6 +51,32 @@ func (s *HistoryStorage) SetHook(hook chan<- struct{}) {
 	s.updateHook = hook
 }
 
+// SetCache restores samples saved in cache, keeping samples already taken,
+// and saves further samples to it in the background. Samples of tags
+// outboundExists reports removed are dropped when saving. Only the first
+// cache set is used.
+func (s *HistoryStorage) SetCache(cache HistoryCache, outboundExists func(tag string) bool) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if s.cache != nil {
+		return
+	}
+	s.cache = cache
+	s.outboundExists = outboundExists
+	for tag, histories := range cache.LoadURLTestHistories() {
+		if len(histories) == 0 {
+			continue
+		}
+		if _, loaded := s.histories[tag]; loaded {
+			continue
+		}
+		s.histories[tag] = histories
+		if last := histories[len(histories)-1]; last.Delay > 0 {
+			s.delayHistory[tag] = last
+		}
+	}
+}
+
 func (s *HistoryStorage) LoadURLTestHistory(tag string) *History {
 	if s == nil {
 		return nil
@@ -43,10 +86,22 @@ func (s *HistoryStorage) LoadURLTestHistory(tag string) *History {
 	return s.delayHistory[tag]
 }
 
+// LoadURLTestHistories returns the latest samples of tag, oldest first.
+// Failed tests are recorded with zero delay.
+func (s *HistoryStorage) LoadURLTestHistories(tag string) []*History {
+	if s == nil {
+		return nil
+	}
+	s.access.RLock()
+	defer s.access.RUnlock()
+	return append([]*History(nil), s.histories[tag]...)
+}
+
 func (s *HistoryStorage) DeleteURLTestHistory(tag string) {
 	s.access.Lock()
 	delete(s.delayHistory, tag)
 	s.access.Unlock()
+	s.appendHistory(tag, &History{Time: time.Now()})
 	s.notifyUpdated()
 }
 
@@ -54,9 +109,52 @@ func (s *HistoryStorage) StoreURLTestHistory(tag string, history *History) {
 	s.access.Lock()
 	s.delayHistory[tag] = history
 	s.access.Unlock()
+	s.appendHistory(tag, history)
 	s.notifyUpdated()
 }
 
+func (s *HistoryStorage) appendHistory(tag string, history *History) {
+	s.access.Lock()
+	histories := append(s.histories[tag], history)
+	if len(histories) > HistoryLimit {
+		histories = append([]*History(nil), histories[len(histories)-HistoryLimit:]...)
+	}
+	s.histories[tag] = histories
+	if s.cache != nil && s.saveTimer == nil {
+		s.saveTimer = time.AfterFunc(C.URLTestHistorySaveInterval, s.saveHistories)
+	}
+	s.access.Unlock()
+}
+
+// Flush saves samples not saved yet immediately.
+func (s *HistoryStorage) Flush() {
+	s.access.Lock()
+	saveTimer := s.saveTimer
+	s.access.Unlock()
+	if saveTimer != nil && saveTimer.Stop() {
+		s.saveHistories()
+	}
+}
+
+func (s *HistoryStorage) saveHistories() {
+	s.saveAccess.Lock()
+	defer s.saveAccess.Unlock()
+	s.access.Lock()
+	s.saveTimer = nil
+	histories := make(map[string][]*History, len(s.histories))
+	for tag, tagHistories := range s.histories {
+		if s.outboundExists != nil && !s.outboundExists(tag) {
+			delete(s.histories, tag)
+			delete(s.delayHistory, tag)
+			continue
+		}
+		histories[tag] = tagHistories
+	}
+	cache := s.cache
+	s.access.Unlock()
+	_ = cache.SaveURLTestHistories(histories)
+}
+
 func (s *HistoryStorage) notifyUpdated() {
 	updateHook := s.updateHook
 	if updateHook != nil {
@@ -69,6 +167,7 @@ func (s *HistoryStorage) notifyUpdated() {
 
 func (s *HistoryStorage) Close() error {
 	s.updateHook = nil
+	s.Flush()
 	return nil
 }
 
@@ -0,0 +1,73 @@
+package urltest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type memoryHistoryCache map[string][]*History
+
+func (c memoryHistoryCache) LoadURLTestHistories() map[string][]*History {
+	return c
+}
+
+func (c memoryHistoryCache) SaveURLTestHistories(histories map[string][]*History) error {
+	for tag := range c {
+		delete(c, tag)
+	}
+	for tag, tagHistories := range histories {
+		c[tag] = tagHistories
+	}
+	return nil
+}
+
+func TestHistoryStorageWindow(t *testing.T) {
+	t.Parallel()
+	storage := NewHistoryStorage()
+	for i := 1; i <= HistoryLimit+2; i++ {
+		storage.StoreURLTestHistory("a", &History{Delay: uint16(i)})
+	}
+	histories := storage.LoadURLTestHistories("a")
+	require.Len(t, histories, HistoryLimit)
+	require.Equal(t, uint16(3), histories[0].Delay)
+	require.Equal(t, uint16(HistoryLimit+2), storage.LoadURLTestHistory("a").Delay)
+
+	storage.DeleteURLTestHistory("a")
+	require.Nil(t, storage.LoadURLTestHistory("a"))
+	histories = storage.LoadURLTestHistories("a")
+	require.Len(t, histories, HistoryLimit)
+	require.Zero(t, histories[HistoryLimit-1].Delay)
+}
+
+func TestHistoryStorageCache(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	cache := memoryHistoryCache{
+		"available":   {{Time: now, Delay: 0}, {Time: now, Delay: 100}},
+		"unavailable": {{Time: now, Delay: 100}, {Time: now, Delay: 0}},
+		"tested":      {{Time: now, Delay: 100}},
+	}
+	storage := NewHistoryStorage()
+	storage.StoreURLTestHistory("tested", &History{Time: now, Delay: 50})
+	storage.SetCache(cache, func(tag string) bool {
+		return tag != "unavailable"
+	})
+
+	require.Equal(t, uint16(100), storage.LoadURLTestHistory("available").Delay)
+	require.Len(t, storage.LoadURLTestHistories("available"), 2)
+	require.Nil(t, storage.LoadURLTestHistory("unavailable"))
+	require.Len(t, storage.LoadURLTestHistories("unavailable"), 2)
+	require.Equal(t, uint16(50), storage.LoadURLTestHistory("tested").Delay)
+
+	storage.StoreURLTestHistory("new", &History{Time: now, Delay: 10})
+	require.NotContains(t, cache, "new")
+	storage.Flush()
+	require.Len(t, cache["new"], 1)
+	require.Len(t, cache["tested"], 1)
+	require.NotContains(t, cache, "unavailable")
+	require.Empty(t, storage.LoadURLTestHistories("unavailable"))
+	storage.SetCache(memoryHistoryCache{"other": {{Time: now, Delay: 10}}}, nil)
+	require.Empty(t, storage.LoadURLTestHistories("other"))
+}
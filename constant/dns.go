@@ -3,4 +3,28 @@ package constant
 const (
 	DNSProviderAliDNS     = "alidns"
 	DNSProviderCloudflare = "cloudflare"
+	DNSProviderRoute53    = "route53"
+	DNSProviderGcore      = "gcore"
+	DNSProviderDuckDNS    = "duckdns"
+	DNSProviderDeSEC      = "desec"
+	DNSProviderRFC2136    = "rfc2136"
+	DNSProviderExec       = "exec"
+	DNSProviderWebhook    = "webhook"
+)
+
+const (
+	DNSInboundProtocolTLS   = "tls"
+	DNSInboundProtocolHTTPS = "https"
+)
+
+const DNSServerAddressRace = "race"
+
+const (
+	DNSSECModeAD   = "ad"
+	DNSSECModeFull = "full"
+)
+
+const (
+	DNSAdBlockResponseNXDomain = "nxdomain"
+	DNSAdBlockResponseZero     = "zero"
 )
@@ -26,12 +26,27 @@ const (
 )
 
 const (
-	TypeSelector = "selector"
-	TypeURLTest  = "urltest"
+	TypeSelector    = "selector"
+	TypeURLTest     = "urltest"
+	TypeLoadBalance = "loadbalance"
+	TypeFallback    = "fallback"
+	TypeRelay       = "relay"
+)
+
+const (
+	LoadBalanceStrategyRoundRobin        = "round_robin"
+	LoadBalanceStrategyLeastConnections  = "least_connections"
+	LoadBalanceStrategyConsistentHashing = "consistent_hashing"
 )
 
 const TypeProvider = "provider"
 
+const (
+	ProviderSourceHTTP = "http"
+	ProviderSourceFile = "file"
+	ProviderSourceExec = "exec"
+)
+
 func ProxyDisplayName(proxyType string) string {
 	switch proxyType {
 	case TypeTun:
@@ -82,6 +97,12 @@ func ProxyDisplayName(proxyType string) string {
 		return "Selector"
 	case TypeURLTest:
 		return "URLTest"
+	case TypeLoadBalance:
+		return "LoadBalance"
+	case TypeFallback:
+		return "Fallback"
+	case TypeRelay:
+		return "Relay"
 	default:
 		return "Unknown"
 	}
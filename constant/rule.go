@@ -11,10 +11,45 @@ const (
 )
 
 const (
-	RuleSetTypeInline   = "inline"
-	RuleSetTypeLocal    = "local"
-	RuleSetTypeRemote   = "remote"
-	RuleSetVersion1     = 1
-	RuleSetFormatSource = "source"
-	RuleSetFormatBinary = "binary"
+	RuleSetTypeInline    = "inline"
+	RuleSetTypeLocal     = "local"
+	RuleSetTypeRemote    = "remote"
+	RuleSetTypeComposite = "composite"
+	RuleSetVersion1      = 1
+	RuleSetFormatSource  = "source"
+	RuleSetFormatBinary  = "binary"
+	RuleSetFormatClash   = "clash"
+	RuleSetFormatAdGuard = "adguard"
+	RuleSetFormatHosts   = "hosts"
+)
+
+const (
+	RuleSetCompositeModeUnion     = "union"
+	RuleSetCompositeModeIntersect = "intersect"
+	RuleSetCompositeModeExclude   = "exclude"
+)
+
+const (
+	ClashRuleSetBehaviorDomain    = "domain"
+	ClashRuleSetBehaviorIPCIDR    = "ipcidr"
+	ClashRuleSetBehaviorClassical = "classical"
+)
+
+const (
+	RuleActionTypeRoute     = "route"
+	RuleActionTypeReject    = "reject"
+	RuleActionTypeDrop      = "drop"
+	RuleActionTypeHijackDNS = "hijack-dns"
+	RuleActionTypeResolve   = "resolve"
+	RuleActionTypeSniff     = "sniff"
+)
+
+const (
+	DNSRuleActionTypeRoute  = "route"
+	DNSRuleActionTypeStatic = "static"
+)
+
+const (
+	RuleActionRejectMethodDefault = "default"
+	RuleActionRejectMethodDrop    = "drop"
 )
@@ -0,0 +1,10 @@
+package constant
+
+const (
+	TaskActionUpdateProviders = "update_providers"
+	TaskActionURLTest         = "url_test"
+	TaskActionClearDNSCache   = "clear_dns_cache"
+	TaskActionClearFakeIP     = "clear_fakeip"
+	TaskActionRotateLogs      = "rotate_logs"
+	TaskActionResetSelector   = "reset_selector"
+)
@@ -17,4 +17,13 @@ const (
 	StopTimeout                = 5 * time.Second
 	FatalStopTimeout           = 10 * time.Second
 	FakeIPMetadataSaveInterval = 10 * time.Second
+	URLTestHistorySaveInterval = 10 * time.Second
 )
+
+const DefaultStartConcurrency = 5
+
+var ProtocolTimeouts = map[string]time.Duration{
+	ProtocolDNS:  DNSTimeout,
+	ProtocolQUIC: QUICTimeout,
+	ProtocolSTUN: STUNTimeout,
+}
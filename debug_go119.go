@@ -33,4 +33,5 @@ func applyDebugOptions(options option.DebugOptions) {
 	if options.OOMKiller != nil {
 		conntrack.KillerEnabled = *options.OOMKiller
 	}
+	conntrack.MaxConnections = options.MaxConnections
 }
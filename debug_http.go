@@ -5,6 +5,7 @@ import (
 	"net/http/pprof"
 	"runtime"
 	"runtime/debug"
+	"strconv"
 	"strings"
 
 	"github.com/sagernet/sing-box/common/humanize"
@@ -33,6 +34,21 @@ func applyDebugListenOption(options option.DebugOptions) {
 			writer.WriteHeader(http.StatusNoContent)
 			go debug.FreeOSMemory()
 		})
+		r.Post("/profile_rate", func(writer http.ResponseWriter, request *http.Request) {
+			blockRate, err := strconv.Atoi(request.URL.Query().Get("block"))
+			if err != nil {
+				http.Error(writer, "invalid block profile rate", http.StatusBadRequest)
+				return
+			}
+			mutexFraction, err := strconv.Atoi(request.URL.Query().Get("mutex"))
+			if err != nil {
+				http.Error(writer, "invalid mutex profile fraction", http.StatusBadRequest)
+				return
+			}
+			runtime.SetBlockProfileRate(blockRate)
+			runtime.SetMutexProfileFraction(mutexFraction)
+			writer.WriteHeader(http.StatusNoContent)
+		})
 		r.Get("/memory", func(writer http.ResponseWriter, request *http.Request) {
 			var memStats runtime.MemStats
 			runtime.ReadMemStats(&memStats)
@@ -46,7 +62,7 @@ func applyDebugListenOption(options option.DebugOptions) {
 
 			encoder := json.NewEncoder(writer)
 			encoder.SetIndent("", "  ")
-			encoder.Encode(memObject)
+			encoder.Encode(&memObject)
 		})
 		r.Route("/pprof", func(r chi.Router) {
 			r.HandleFunc("/", func(writer http.ResponseWriter, request *http.Request) {
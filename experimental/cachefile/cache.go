@@ -12,6 +12,7 @@ import (
 	"github.com/sagernet/bbolt"
 	bboltErrors "github.com/sagernet/bbolt/errors"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/compress"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -19,19 +20,24 @@ import (
 )
 
 var (
-	bucketSelected = []byte("selected")
-	bucketExpand   = []byte("group_expand")
-	bucketMode     = []byte("clash_mode")
-	bucketRuleSet  = []byte("rule_set")
+	bucketSelected         = []byte("selected")
+	bucketSelectedIdentity = []byte("selected_identity")
+	bucketExpand           = []byte("group_expand")
+	bucketMode             = []byte("clash_mode")
+	bucketRuleSet          = []byte("rule_set")
 	//
 	bucketOutboundProviderInfo = []byte("outbound_provider_info")
 
 	bucketNameList = []string{
 		string(bucketSelected),
+		string(bucketSelectedIdentity),
 		string(bucketExpand),
 		string(bucketMode),
 		string(bucketRuleSet),
 		string(bucketRDRC),
+		string(bucketTLSSession),
+		string(bucketURLTestHistory),
+		string(bucketDNSCache),
 		//
 		string(bucketOutboundProviderInfo),
 	}
@@ -47,6 +53,8 @@ type CacheFile struct {
 	cacheID           []byte
 	storeFakeIP       bool
 	storeRDRC         bool
+	storeTLSSession   bool
+	storeDNS          bool
 	rdrcTimeout       time.Duration
 	DB                *bbolt.DB
 	saveMetadataTimer *time.Timer
@@ -84,16 +92,18 @@ func New(ctx context.Context, options option.CacheFileOptions) *CacheFile {
 		}
 	}
 	return &CacheFile{
-		ctx:          ctx,
-		path:         filemanager.BasePath(ctx, path),
-		cacheID:      cacheIDBytes,
-		storeFakeIP:  options.StoreFakeIP,
-		storeRDRC:    options.StoreRDRC,
-		rdrcTimeout:  rdrcTimeout,
-		saveDomain:   make(map[netip.Addr]string),
-		saveAddress4: make(map[string]netip.Addr),
-		saveAddress6: make(map[string]netip.Addr),
-		saveRDRC:     make(map[saveRDRCCacheKey]bool),
+		ctx:             ctx,
+		path:            filemanager.BasePath(ctx, path),
+		cacheID:         cacheIDBytes,
+		storeFakeIP:     options.StoreFakeIP,
+		storeRDRC:       options.StoreRDRC,
+		storeTLSSession: options.StoreTLSSession,
+		storeDNS:        options.StoreDNS,
+		rdrcTimeout:     rdrcTimeout,
+		saveDomain:      make(map[netip.Addr]string),
+		saveAddress4:    make(map[string]netip.Addr),
+		saveAddress6:    make(map[string]netip.Addr),
+		saveRDRC:        make(map[saveRDRCCacheKey]bool),
 	}
 }
 
@@ -255,6 +265,32 @@ func (c *CacheFile) StoreSelected(group, selected string) error {
 	})
 }
 
+func (c *CacheFile) LoadSelectedIdentity(group string) string {
+	var identity string
+	c.DB.View(func(t *bbolt.Tx) error {
+		bucket := c.bucket(t, bucketSelectedIdentity)
+		if bucket == nil {
+			return nil
+		}
+		identity = string(bucket.Get([]byte(group)))
+		return nil
+	})
+	return identity
+}
+
+func (c *CacheFile) StoreSelectedIdentity(group, identity string) error {
+	return c.DB.Batch(func(t *bbolt.Tx) error {
+		bucket, err := c.createBucket(t, bucketSelectedIdentity)
+		if err != nil {
+			return err
+		}
+		if identity == "" {
+			return bucket.Delete([]byte(group))
+		}
+		return bucket.Put([]byte(group), []byte(identity))
+	})
+}
+
 func (c *CacheFile) LoadGroupExpand(group string) (isExpand bool, loaded bool) {
 	c.DB.View(func(t *bbolt.Tx) error {
 		bucket := c.bucket(t, bucketExpand)
@@ -292,7 +328,10 @@ func (c *CacheFile) LoadRuleSet(tag string) *adapter.SavedRuleSet {
 		if bucket == nil {
 			return os.ErrNotExist
 		}
-		setBinary := bucket.Get([]byte(tag))
+		setBinary, err := compress.Decompress(bucket.Get([]byte(tag)))
+		if err != nil {
+			return err
+		}
 		if len(setBinary) == 0 {
 			return os.ErrInvalid
 		}
@@ -314,6 +353,10 @@ func (c *CacheFile) SaveRuleSet(tag string, set *adapter.SavedRuleSet) error {
 		if err != nil {
 			return err
 		}
+		setBinary, err = compress.Compress(setBinary)
+		if err != nil {
+			return err
+		}
 		return bucket.Put([]byte(tag), setBinary)
 	})
 }
@@ -325,7 +368,10 @@ func (c *CacheFile) LoadOutboundProviderInfo(tag string) *adapter.OutboundProvid
 		if bucket == nil {
 			return os.ErrNotExist
 		}
-		infoBinary := bucket.Get([]byte(tag))
+		infoBinary, err := compress.Decompress(bucket.Get([]byte(tag)))
+		if err != nil {
+			return err
+		}
 		if len(infoBinary) == 0 {
 			return os.ErrInvalid
 		}
@@ -347,6 +393,10 @@ func (c *CacheFile) SaveOutboundProviderInfo(tag string, info *adapter.OutboundP
 		if err != nil {
 			return err
 		}
+		infoBinary, err = compress.Compress(infoBinary)
+		if err != nil {
+			return err
+		}
 		return bucket.Put([]byte(tag), infoBinary)
 	})
 }
@@ -0,0 +1,100 @@
+package cachefile
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"time"
+
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/varbin"
+)
+
+var bucketDNSCache = []byte("dns_cache")
+
+func (c *CacheFile) StoreDNS() bool {
+	return c.storeDNS
+}
+
+func (c *CacheFile) LoadDNSCache() []adapter.SavedDNSResponse {
+	var responses []adapter.SavedDNSResponse
+	c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketDNSCache)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			response, err := readSavedDNSResponse(bytes.NewReader(v))
+			if err == nil {
+				responses = append(responses, response)
+			}
+			return nil
+		})
+	})
+	return responses
+}
+
+// SaveDNSCache replaces the stored DNS cache, keeping the order of responses.
+func (c *CacheFile) SaveDNSCache(responses []adapter.SavedDNSResponse) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketDNSCache)
+		if err != nil {
+			return err
+		}
+		cursor := bucket.Cursor()
+		for key, _ := cursor.First(); key != nil; key, _ = cursor.First() {
+			err = cursor.Delete()
+			if err != nil {
+				return err
+			}
+		}
+		key := make([]byte, 4)
+		for i, response := range responses {
+			var buffer bytes.Buffer
+			err = writeSavedDNSResponse(&buffer, response)
+			if err != nil {
+				return err
+			}
+			binary.BigEndian.PutUint32(key, uint32(i))
+			err = bucket.Put(key, buffer.Bytes())
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func writeSavedDNSResponse(writer io.Writer, response adapter.SavedDNSResponse) error {
+	var expireAt int64
+	if !response.ExpireAt.IsZero() {
+		expireAt = response.ExpireAt.Unix()
+	}
+	err := binary.Write(writer, binary.BigEndian, expireAt)
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(writer, binary.BigEndian, response.Transport)
+	if err != nil {
+		return err
+	}
+	return varbin.Write(writer, binary.BigEndian, response.Message)
+}
+
+func readSavedDNSResponse(reader io.Reader) (response adapter.SavedDNSResponse, err error) {
+	var expireAt int64
+	err = binary.Read(reader, binary.BigEndian, &expireAt)
+	if err != nil {
+		return
+	}
+	if expireAt != 0 {
+		response.ExpireAt = time.Unix(expireAt, 0)
+	}
+	response.Transport, err = varbin.ReadValue[string](reader, binary.BigEndian)
+	if err != nil {
+		return
+	}
+	response.Message, err = varbin.ReadValue[[]byte](reader, binary.BigEndian)
+	return
+}
@@ -1,6 +1,7 @@
 package cachefile
 
 import (
+	"bytes"
 	"net/netip"
 	"os"
 	"time"
@@ -179,6 +180,47 @@ func (c *CacheFile) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bo
 	return address, address.IsValid()
 }
 
+func (c *CacheFile) FakeIPLoadAll() map[netip.Addr]string {
+	mappings := make(map[netip.Addr]string)
+	_ = c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketFakeIP)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			address := M.AddrFromIP(k)
+			if address.IsValid() && len(v) > 0 {
+				mappings[address] = string(v)
+			}
+			return nil
+		})
+	})
+	return mappings
+}
+
+// FakeIPDelete removes the mapping of address if it still points to domain.
+func (c *CacheFile) FakeIPDelete(address netip.Addr, domain string) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket := tx.Bucket(bucketFakeIP)
+		if bucket == nil || string(bucket.Get(address.AsSlice())) != domain {
+			return nil
+		}
+		err := bucket.Delete(address.AsSlice())
+		if err != nil {
+			return err
+		}
+		if address.Is4() {
+			bucket = tx.Bucket(bucketFakeIPDomain4)
+		} else {
+			bucket = tx.Bucket(bucketFakeIPDomain6)
+		}
+		if bucket == nil || !bytes.Equal(bucket.Get([]byte(domain)), address.AsSlice()) {
+			return nil
+		}
+		return bucket.Delete([]byte(domain))
+	})
+}
+
 func (c *CacheFile) FakeIPReset() error {
 	return c.DB.Batch(func(tx *bbolt.Tx) error {
 		err := tx.DeleteBucket(bucketFakeIP)
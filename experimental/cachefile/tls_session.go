@@ -0,0 +1,39 @@
+package cachefile
+
+import (
+	"bytes"
+
+	"github.com/sagernet/bbolt"
+)
+
+var bucketTLSSession = []byte("tls_session")
+
+func (c *CacheFile) StoreTLSSession() bool {
+	return c.storeTLSSession
+}
+
+func (c *CacheFile) LoadTLSSession(key string) []byte {
+	var content []byte
+	c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketTLSSession)
+		if bucket == nil {
+			return nil
+		}
+		content = bytes.Clone(bucket.Get([]byte(key)))
+		return nil
+	})
+	return content
+}
+
+func (c *CacheFile) SaveTLSSession(key string, session []byte) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketTLSSession)
+		if err != nil {
+			return err
+		}
+		if session == nil {
+			return bucket.Delete([]byte(key))
+		}
+		return bucket.Put([]byte(key), session)
+	})
+}
@@ -0,0 +1,63 @@
+package cachefile
+
+import (
+	"github.com/sagernet/bbolt"
+	"github.com/sagernet/sing-box/common/urltest"
+	"github.com/sagernet/sing/common/json"
+)
+
+var bucketURLTestHistory = []byte("urltest_history")
+
+func (c *CacheFile) LoadURLTestHistories() map[string][]*urltest.History {
+	historiesMap := make(map[string][]*urltest.History)
+	c.DB.View(func(tx *bbolt.Tx) error {
+		bucket := c.bucket(tx, bucketURLTestHistory)
+		if bucket == nil {
+			return nil
+		}
+		return bucket.ForEach(func(k, v []byte) error {
+			var histories []*urltest.History
+			if json.Unmarshal(v, &histories) == nil {
+				historiesMap[string(k)] = histories
+			}
+			return nil
+		})
+	})
+	return historiesMap
+}
+
+func (c *CacheFile) SaveURLTestHistories(historiesMap map[string][]*urltest.History) error {
+	return c.DB.Batch(func(tx *bbolt.Tx) error {
+		bucket, err := c.createBucket(tx, bucketURLTestHistory)
+		if err != nil {
+			return err
+		}
+		var staleTags [][]byte
+		err = bucket.ForEach(func(k, v []byte) error {
+			if _, loaded := historiesMap[string(k)]; !loaded {
+				staleTags = append(staleTags, append([]byte(nil), k...))
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, tag := range staleTags {
+			err = bucket.Delete(tag)
+			if err != nil {
+				return err
+			}
+		}
+		for tag, histories := range historiesMap {
+			content, err := json.Marshal(histories)
+			if err != nil {
+				return err
+			}
+			err = bucket.Put([]byte(tag), content)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,251 @@
+package clashapi
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/pcapng"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultCaptureDuration = 30 * time.Second
+	maxCaptureDuration     = 10 * time.Minute
+	defaultCaptureSize     = 16 * 1024 * 1024
+)
+
+var _ adapter.ConnectionTracker = (*captureManager)(nil)
+
+type captureManager struct {
+	access   sync.RWMutex
+	captures []*packetCapture
+}
+
+func (m *captureManager) add(capture *packetCapture) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	m.captures = append(m.captures, capture)
+}
+
+func (m *captureManager) remove(capture *packetCapture) {
+	m.access.Lock()
+	defer m.access.Unlock()
+	newCaptures := make([]*packetCapture, 0, len(m.captures))
+	for _, it := range m.captures {
+		if it != capture {
+			newCaptures = append(newCaptures, it)
+		}
+	}
+	m.captures = newCaptures
+}
+
+func (m *captureManager) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	m.access.RLock()
+	captures := m.captures
+	m.access.RUnlock()
+	for _, capture := range captures {
+		if !capture.filter.Match(&metadata, adapter.OutboundTag(matchOutbound)) {
+			continue
+		}
+		flow := capture.newFlow(metadata, true)
+		if flow != nil {
+			conn = &captureConn{conn, capture, flow}
+		}
+	}
+	return conn
+}
+
+func (m *captureManager) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	m.access.RLock()
+	captures := m.captures
+	m.access.RUnlock()
+	for _, capture := range captures {
+		if !capture.filter.Match(&metadata, adapter.OutboundTag(matchOutbound)) {
+			continue
+		}
+		flow := capture.newFlow(metadata, false)
+		if flow != nil {
+			conn = &capturePacketConn{conn, capture, flow}
+		}
+	}
+	return conn
+}
+
+type packetCapture struct {
+	filter  connectionFilter
+	access  sync.Mutex
+	writer  *pcapng.Writer
+	flusher http.Flusher
+	maxSize int64
+	closed  bool
+	done    chan struct{}
+}
+
+func (c *packetCapture) do(action func() error) {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if c.closed {
+		return
+	}
+	err := action()
+	if c.flusher != nil {
+		c.flusher.Flush()
+	}
+	if err != nil || c.writer.Written() >= c.maxSize {
+		c.closeLocked()
+	}
+}
+
+func (c *packetCapture) close() {
+	c.access.Lock()
+	defer c.access.Unlock()
+	if !c.closed {
+		c.closeLocked()
+	}
+}
+
+func (c *packetCapture) closeLocked() {
+	c.closed = true
+	close(c.done)
+}
+
+func (c *packetCapture) newFlow(metadata adapter.InboundContext, isTCP bool) *pcapng.Flow {
+	destination := metadata.Destination
+	if metadata.OriginDestination.IsValid() {
+		destination = metadata.OriginDestination
+	} else if !destination.IsIP() && len(metadata.DestinationAddresses) > 0 {
+		destination = M.SocksaddrFrom(metadata.DestinationAddresses[0], destination.Port)
+	}
+	var flow *pcapng.Flow
+	c.do(func() error {
+		if isTCP {
+			flow = pcapng.NewTCPFlow(c.writer, metadata.Source.AddrPort(), destination.AddrPort())
+		} else {
+			flow = pcapng.NewUDPFlow(c.writer, metadata.Source.AddrPort(), destination.AddrPort())
+		}
+		return nil
+	})
+	return flow
+}
+
+type captureConn struct {
+	net.Conn
+	capture *packetCapture
+	flow    *pcapng.Flow
+}
+
+func (c *captureConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n > 0 {
+		c.capture.do(func() error {
+			return c.flow.WriteTCP(true, p[:n])
+		})
+	}
+	return
+}
+
+func (c *captureConn) Write(p []byte) (n int, err error) {
+	n, err = c.Conn.Write(p)
+	if n > 0 {
+		c.capture.do(func() error {
+			return c.flow.WriteTCP(false, p[:n])
+		})
+	}
+	return
+}
+
+func (c *captureConn) Close() error {
+	c.capture.do(c.flow.CloseTCP)
+	return c.Conn.Close()
+}
+
+type capturePacketConn struct {
+	N.PacketConn
+	capture *packetCapture
+	flow    *pcapng.Flow
+}
+
+func (c *capturePacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err == nil {
+		c.capture.do(func() error {
+			return c.flow.WriteUDP(true, buffer.Bytes())
+		})
+	}
+	return
+}
+
+func (c *capturePacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.capture.do(func() error {
+		return c.flow.WriteUDP(false, buffer.Bytes())
+	})
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func capturePackets(manager *captureManager) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter, err := parseConnectionFilter(query)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		duration := defaultCaptureDuration
+		if durationString := query.Get("duration"); durationString != "" {
+			parsedDuration, err := option.ParseDuration(durationString)
+			if err != nil || parsedDuration <= 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+			duration = time.Duration(parsedDuration)
+		}
+		if duration > maxCaptureDuration {
+			duration = maxCaptureDuration
+		}
+		maxSize := int64(defaultCaptureSize)
+		if sizeString := query.Get("max_size"); sizeString != "" {
+			maxSize, err = strconv.ParseInt(sizeString, 10, 64)
+			if err != nil || maxSize <= 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+		}
+		w.Header().Set("Content-Type", "application/x-pcapng")
+		w.Header().Set("Content-Disposition", "attachment; filename=\"capture.pcapng\"")
+		w.WriteHeader(http.StatusOK)
+		writer, err := pcapng.NewWriter(w, pcapng.LinkTypeRaw)
+		if err != nil {
+			return
+		}
+		capture := &packetCapture{
+			filter:  filter,
+			writer:  writer,
+			maxSize: maxSize,
+			done:    make(chan struct{}),
+		}
+		capture.flusher, _ = w.(http.Flusher)
+		manager.add(capture)
+		defer manager.remove(capture)
+		timer := time.NewTimer(duration)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-capture.done:
+		case <-r.Context().Done():
+		}
+		capture.close()
+	}
+}
@@ -2,8 +2,11 @@ package clashapi
 
 import (
 	"net/http"
+	"strings"
 
+	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/log"
+	E "github.com/sagernet/sing/common/exceptions"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -39,11 +42,18 @@ func getConfigs(server *Server, logFactory log.Factory) func(w http.ResponseWrit
 		} else if logLevel < log.LevelError {
 			logLevel = log.LevelError
 		}
-		render.JSON(w, r, &configSchema{
+		config := &configSchema{
 			Mode:        server.mode,
 			BindAddress: "*",
 			LogLevel:    log.FormatLevel(logLevel),
-		})
+		}
+		if inbounds := tunInbounds(server.router); len(inbounds) > 0 {
+			config.Tun = map[string]any{
+				"enable": true,
+				"stack":  inbounds[0].Stack(),
+			}
+		}
+		render.JSON(w, r, config)
 	}
 }
 
@@ -59,10 +69,52 @@ func patchConfigs(server *Server) func(w http.ResponseWriter, r *http.Request) {
 		if newConfig.Mode != "" {
 			server.SetMode(newConfig.Mode)
 		}
+		if stack, loaded := newConfig.Tun["stack"].(string); loaded {
+			inbounds := tunInbounds(server.router)
+			if len(inbounds) == 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError("tun inbound not found"))
+				return
+			}
+			err = setTunStack(inbounds, strings.ToLower(stack))
+			if err != nil {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, newError(err.Error()))
+				return
+			}
+		}
 		render.NoContent(w, r)
 	}
 }
 
+// setTunStack switches all tun inbounds to stack, and switches back those
+// already switched if one fails.
+func setTunStack(inbounds []adapter.TunInbound, stack string) error {
+	previousStacks := make([]string, 0, len(inbounds))
+	for _, inbound := range inbounds {
+		previousStack := inbound.Stack()
+		err := inbound.SetStack(stack)
+		if err != nil {
+			for i, previousStack := range previousStacks {
+				err = E.Errors(err, inbounds[i].SetStack(previousStack))
+			}
+			return err
+		}
+		previousStacks = append(previousStacks, previousStack)
+	}
+	return nil
+}
+
+func tunInbounds(router adapter.Router) []adapter.TunInbound {
+	var inbounds []adapter.TunInbound
+	for _, inbound := range router.Inbounds() {
+		if tunInbound, isTun := inbound.(adapter.TunInbound); isTun {
+			inbounds = append(inbounds, tunInbound)
+		}
+	}
+	return inbounds
+}
+
 func updateConfigs(w http.ResponseWriter, r *http.Request) {
 	render.NoContent(w, r)
 }
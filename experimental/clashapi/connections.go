@@ -7,29 +7,79 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/clashapi/trafficontrol"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
 	"github.com/sagernet/ws"
 	"github.com/sagernet/ws/wsutil"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
-	"github.com/gofrs/uuid/v5"
 )
 
 func connectionRouter(router adapter.Router, trafficManager *trafficontrol.Manager) http.Handler {
 	r := chi.NewRouter()
-	r.Get("/", getConnections(trafficManager))
-	r.Delete("/", closeAllConnections(router, trafficManager))
-	r.Delete("/{id}", closeConnection(trafficManager))
+	r.Get("/", getConnections(router, trafficManager))
+	r.Get("/closed", getClosedConnections(router))
+	r.Delete("/", closeAllConnections(router))
+	r.Delete("/{id}", closeConnection(router))
 	return r
 }
 
-func getConnections(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+// connectionsSnapshot lists the connections tracked by the router in the
+// format of trafficontrol.Snapshot.
+func connectionsSnapshot(router adapter.Router, trafficManager *trafficontrol.Manager) render.M {
+	snapshot := trafficManager.Snapshot()
+	connections := make([]render.M, 0)
+	for _, connection := range router.Connections() {
+		if connection.OutboundType == C.TypeDNS {
+			continue
+		}
+		connections = append(connections, connectionJSON(connection))
+	}
+	return render.M{
+		"downloadTotal": snapshot.Download,
+		"uploadTotal":   snapshot.Upload,
+		"connections":   connections,
+		"memory":        snapshot.Memory,
+	}
+}
+
+func connectionJSON(connection adapter.TrackedConnection) render.M {
+	inbound := connection.InboundType
+	if connection.Inbound != "" {
+		inbound += "/" + connection.Inbound
+	}
+	source := M.ParseSocksaddr(connection.Source)
+	destination := M.ParseSocksaddr(connection.Destination)
+	return render.M{
+		"id": connection.ID,
+		"metadata": render.M{
+			"network":         connection.Network,
+			"type":            inbound,
+			"sourceIP":        source.Addr,
+			"destinationIP":   destination.Addr,
+			"sourcePort":      F.ToString(source.Port),
+			"destinationPort": F.ToString(destination.Port),
+			"host":            connection.Domain,
+			"dnsMode":         "normal",
+			"processPath":     connection.ProcessPath,
+		},
+		"upload":      connection.Upload,
+		"download":    connection.Download,
+		"start":       connection.CreatedAt,
+		"chains":      connection.Chains,
+		"rule":        connection.Rule,
+		"rulePayload": "",
+	}
+}
+
+func getConnections(router adapter.Router, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Header.Get("Upgrade") != "websocket" {
-			snapshot := trafficManager.Snapshot()
-			render.JSON(w, r, snapshot)
+			render.JSON(w, r, connectionsSnapshot(router, trafficManager))
 			return
 		}
 
@@ -54,8 +104,7 @@ func getConnections(trafficManager *trafficontrol.Manager) func(w http.ResponseW
 		buf := &bytes.Buffer{}
 		sendSnapshot := func() error {
 			buf.Reset()
-			snapshot := trafficManager.Snapshot()
-			if err := json.NewEncoder(buf).Encode(snapshot); err != nil {
+			if err := json.NewEncoder(buf).Encode(connectionsSnapshot(router, trafficManager)); err != nil {
 				return err
 			}
 			return wsutil.WriteServerText(conn, buf.Bytes())
@@ -75,25 +124,23 @@ func getConnections(trafficManager *trafficontrol.Manager) func(w http.ResponseW
 	}
 }
 
-func closeConnection(trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+func getClosedConnections(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		id := uuid.FromStringOrNil(chi.URLParam(r, "id"))
-		snapshot := trafficManager.Snapshot()
-		for _, c := range snapshot.Connections {
-			if id == c.Metadata().ID {
-				c.Close()
-				break
-			}
-		}
+		render.JSON(w, r, render.M{"connections": router.ClosedConnections()})
+	}
+}
+
+func closeConnection(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		router.CloseConnection(chi.URLParam(r, "id"))
 		render.NoContent(w, r)
 	}
 }
 
-func closeAllConnections(router adapter.Router, trafficManager *trafficontrol.Manager) func(w http.ResponseWriter, r *http.Request) {
+func closeAllConnections(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
-		snapshot := trafficManager.Snapshot()
-		for _, c := range snapshot.Connections {
-			c.Close()
+		for _, connection := range router.Connections() {
+			router.CloseConnection(connection.ID)
 		}
 		router.ResetNetwork()
 		render.NoContent(w, r)
@@ -0,0 +1,14 @@
+package clashapi
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+)
+
+func debugRouter(server *Server) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/capture", capturePackets(server.captureManager))
+	r.Get("/trace", traceConnection(server.router))
+	return r
+}
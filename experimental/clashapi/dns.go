@@ -16,9 +16,78 @@ import (
 func dnsRouter(router adapter.Router) http.Handler {
 	r := chi.NewRouter()
 	r.Get("/query", queryDNS(router))
+	r.Route("/adblock", func(r chi.Router) {
+		r.Get("/", getAdBlock(router))
+		r.Patch("/", patchAdBlock(router))
+		r.Delete("/statistics", resetAdBlockStatistics(router))
+	})
 	return r
 }
 
+func getAdBlock(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adBlocker := router.DNSAdBlocker()
+		if adBlocker == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		var queries, blocked uint64
+		clients := make([]render.M, 0)
+		for _, statistics := range adBlocker.Statistics() {
+			queries += statistics.Queries
+			blocked += statistics.Blocked
+			clients = append(clients, render.M{
+				"client":      statistics.Client.String(),
+				"queries":     statistics.Queries,
+				"blocked":     statistics.Blocked,
+				"lastBlocked": statistics.LastBlocked,
+			})
+		}
+		render.JSON(w, r, render.M{
+			"enabled": adBlocker.Enabled(),
+			"queries": queries,
+			"blocked": blocked,
+			"clients": clients,
+		})
+	}
+}
+
+func patchAdBlock(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adBlocker := router.DNSAdBlocker()
+		if adBlocker == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		var request struct {
+			Enabled *bool `json:"enabled"`
+		}
+		err := render.DecodeJSON(r.Body, &request)
+		if err != nil || request.Enabled == nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, ErrBadRequest)
+			return
+		}
+		adBlocker.SetEnabled(*request.Enabled)
+		render.NoContent(w, r)
+	}
+}
+
+func resetAdBlockStatistics(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		adBlocker := router.DNSAdBlocker()
+		if adBlocker == nil {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		adBlocker.ResetStatistics()
+		render.NoContent(w, r)
+	}
+}
+
 func queryDNS(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		name := r.URL.Query().Get("name")
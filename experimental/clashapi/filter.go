@@ -0,0 +1,89 @@
+package clashapi
+
+import (
+	"net/netip"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type connectionFilter struct {
+	network  string
+	inbound  string
+	outbound string
+	domain   string
+	prefix   netip.Prefix
+	port     uint16
+}
+
+func parseConnectionFilter(query url.Values) (connectionFilter, error) {
+	filter := connectionFilter{
+		network:  query.Get("network"),
+		inbound:  query.Get("inbound"),
+		outbound: query.Get("outbound"),
+		domain:   strings.TrimPrefix(strings.ToLower(query.Get("domain")), "."),
+	}
+	switch filter.network {
+	case "", N.NetworkTCP, N.NetworkUDP:
+	default:
+		return connectionFilter{}, E.New("unknown network: ", filter.network)
+	}
+	if ipString := query.Get("ip"); ipString != "" {
+		prefix, err := netip.ParsePrefix(ipString)
+		if err != nil {
+			addr, addrErr := netip.ParseAddr(ipString)
+			if addrErr != nil {
+				return connectionFilter{}, E.Cause(err, "parse ip")
+			}
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+		filter.prefix = prefix
+	}
+	if portString := query.Get("port"); portString != "" {
+		port, err := strconv.ParseUint(portString, 10, 16)
+		if err != nil {
+			return connectionFilter{}, E.Cause(err, "parse port")
+		}
+		filter.port = uint16(port)
+	}
+	return filter, nil
+}
+
+// Match checks the filter against the connection, the outbound condition is skipped if outbound is empty.
+func (f *connectionFilter) Match(metadata *adapter.InboundContext, outbound string) bool {
+	if f.network != "" && f.network != metadata.Network {
+		return false
+	}
+	if f.inbound != "" && f.inbound != metadata.Inbound {
+		return false
+	}
+	if f.outbound != "" && outbound != "" && f.outbound != outbound {
+		return false
+	}
+	if f.port != 0 && f.port != metadata.Destination.Port {
+		return false
+	}
+	if f.prefix.IsValid() {
+		addressMatched := f.prefix.Contains(metadata.Source.Addr.Unmap()) ||
+			f.prefix.Contains(metadata.Destination.Addr.Unmap()) ||
+			f.prefix.Contains(metadata.OriginDestination.Addr.Unmap())
+		if !addressMatched {
+			return false
+		}
+	}
+	if f.domain != "" {
+		if !matchDomainSuffix(metadata.Domain, f.domain) && !matchDomainSuffix(metadata.Destination.Fqdn, f.domain) {
+			return false
+		}
+	}
+	return true
+}
+
+func matchDomainSuffix(domain string, suffix string) bool {
+	domain = strings.ToLower(domain)
+	return domain == suffix || strings.HasSuffix(domain, "."+suffix)
+}
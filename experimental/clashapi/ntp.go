@@ -0,0 +1,31 @@
+package clashapi
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/sagernet/sing-box/common/timesync"
+	"github.com/sagernet/sing/common/ntp"
+	"github.com/sagernet/sing/service"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+func ntpRouter(ctx context.Context) http.Handler {
+	r := chi.NewRouter()
+	r.Get("/", getNTPStatus(ctx))
+	return r
+}
+
+func getNTPStatus(ctx context.Context) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		timeService, isTimeSync := service.FromContext[ntp.TimeService](ctx).(*timesync.Service)
+		if !isTimeSync {
+			render.Status(r, http.StatusNotFound)
+			render.JSON(w, r, ErrNotFound)
+			return
+		}
+		render.JSON(w, r, timeService.Status())
+	}
+}
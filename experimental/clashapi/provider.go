@@ -40,7 +40,7 @@ func getProviders(server *Server, router adapter.Router) func(w http.ResponseWri
 func getProvider(server *Server) func(w http.ResponseWriter, r *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
 		provider := r.Context().Value(CtxKeyProvider).(adapter.OutboundProvider)
-		render.JSON(w, r, proxyInfo(server, provider))
+		render.JSON(w, r, proxyProviderInfo(server, provider))
 	}
 }
 
@@ -87,14 +87,19 @@ func proxyProviderInfo(server *Server, provider adapter.OutboundProvider) *badjs
 	info.Put("name", provider.Tag())
 	info.Put("type", "Proxy")
 	info.Put("vehicleType", "HTTP")
-	subscriptionInfo := render.M{}
-	providerInfo := provider.ProviderInfo()
-	subscriptionInfo["Download"] = providerInfo.Download
-	subscriptionInfo["Upload"] = providerInfo.Upload
-	subscriptionInfo["Total"] = providerInfo.Total
-	subscriptionInfo["Expire"] = providerInfo.Expired.Unix()
-	info.Put("subscriptionInfo", subscriptionInfo)
-	info.Put("updatedAt", providerInfo.LastUpdated)
+	if providerInfo := provider.ProviderInfo(); providerInfo != nil {
+		subscriptionInfo := render.M{}
+		subscriptionInfo["Download"] = providerInfo.Download
+		subscriptionInfo["Upload"] = providerInfo.Upload
+		subscriptionInfo["Total"] = providerInfo.Total
+		var expire int64
+		if !providerInfo.Expired.IsZero() {
+			expire = providerInfo.Expired.Unix()
+		}
+		subscriptionInfo["Expire"] = expire
+		info.Put("subscriptionInfo", subscriptionInfo)
+		info.Put("updatedAt", providerInfo.LastUpdated)
+	}
 	outbounds := provider.BasicOutbounds()
 	proxies := make([]*badjson.JSONObject, 0, len(outbounds))
 	for _, out := range outbounds {
@@ -72,12 +72,11 @@ func proxyInfo(server *Server, detour adapter.Outbound) *badjson.JSONObject {
 	info.Put("type", clashType)
 	info.Put("name", detour.Tag())
 	info.Put("udp", common.Contains(detour.Network(), N.NetworkUDP))
-	delayHistory := server.urlTestHistory.LoadURLTestHistory(adapter.OutboundTag(detour))
-	if delayHistory != nil {
-		info.Put("history", []*urltest.History{delayHistory})
-	} else {
-		info.Put("history", []*urltest.History{})
+	delayHistory := server.urlTestHistory.LoadURLTestHistories(adapter.OutboundTag(detour))
+	if delayHistory == nil {
+		delayHistory = []*urltest.History{}
 	}
+	info.Put("history", delayHistory)
 	if group, isGroup := detour.(adapter.OutboundGroup); isGroup {
 		info.Put("now", group.Now())
 		info.Put("all", group.All())
@@ -48,7 +48,12 @@ func getRuleProvider(w http.ResponseWriter, r *http.Request) {
 
 func updateRuleProvider(w http.ResponseWriter, r *http.Request) {
 	ruleSet := r.Context().Value(CtxKeyProvider).(adapter.RuleSet)
-	ruleSet.Update(r.Context())
+	err := ruleSet.Update(r.Context())
+	if err != nil {
+		render.Status(r, http.StatusInternalServerError)
+		render.JSON(w, r, newError(err.Error()))
+		return
+	}
 	render.NoContent(w, r)
 }
 
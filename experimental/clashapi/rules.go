@@ -4,6 +4,7 @@ import (
 	"net/http"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/render"
@@ -27,10 +28,14 @@ func getRules(router adapter.Router) func(w http.ResponseWriter, r *http.Request
 
 		var rules []Rule
 		for _, rule := range rawRules {
+			proxy := rule.Outbound()
+			if action := rule.Action(); action.Action != C.RuleActionTypeRoute {
+				proxy = action.Action
+			}
 			rules = append(rules, Rule{
 				Type:    rule.Type(),
 				Payload: rule.String(),
-				Proxy:   rule.Outbound(),
+				Proxy:   proxy,
 			})
 		}
 
@@ -45,6 +45,7 @@ type Server struct {
 	logger         log.Logger
 	httpServer     *http.Server
 	trafficManager *trafficontrol.Manager
+	captureManager *captureManager
 	urlTestHistory *urltest.HistoryStorage
 	mode           string
 	modeList       []string
@@ -68,6 +69,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 			Handler: chiRouter,
 		},
 		trafficManager:           trafficManager,
+		captureManager:           &captureManager{},
 		modeList:                 options.ModeList,
 		externalController:       options.ExternalController != "",
 		externalUIDownloadURL:    options.ExternalUIDownloadURL,
@@ -85,6 +87,7 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		server.modeList = append([]string{defaultMode}, server.modeList...)
 	}
 	server.mode = defaultMode
+	router.AppendTracker(server.captureManager)
 	//goland:noinspection GoDeprecation
 	//nolint:staticcheck
 	if options.StoreMode || options.StoreSelected || options.StoreFakeIP || options.CacheFile != "" || options.CacheID != "" {
@@ -113,6 +116,8 @@ func NewServer(ctx context.Context, router adapter.Router, logFactory log.Observ
 		r.Mount("/profile", profileRouter())
 		r.Mount("/cache", cacheRouter(ctx))
 		r.Mount("/dns", dnsRouter(router))
+		r.Mount("/ntp", ntpRouter(ctx))
+		r.Mount("/debug", debugRouter(server))
 
 		server.setupMetaAPI(r)
 	})
@@ -138,6 +143,10 @@ func (s *Server) PreStart() error {
 		}) {
 			s.mode = mode
 		}
+		s.urlTestHistory.SetCache(cacheFile, func(tag string) bool {
+			_, loaded := s.router.Outbound(tag)
+			return loaded
+		})
 	}
 	return nil
 }
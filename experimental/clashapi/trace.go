@@ -0,0 +1,56 @@
+package clashapi
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/go-chi/render"
+)
+
+const (
+	defaultTraceTimeout = time.Minute
+	maxTraceTimeout     = 10 * time.Minute
+)
+
+func traceConnection(router adapter.Router) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		filter, err := parseConnectionFilter(query)
+		if err != nil {
+			render.Status(r, http.StatusBadRequest)
+			render.JSON(w, r, newError(err.Error()))
+			return
+		}
+		timeout := defaultTraceTimeout
+		if timeoutString := query.Get("timeout"); timeoutString != "" {
+			parsedTimeout, err := option.ParseDuration(timeoutString)
+			if err != nil || parsedTimeout <= 0 {
+				render.Status(r, http.StatusBadRequest)
+				render.JSON(w, r, ErrBadRequest)
+				return
+			}
+			timeout = time.Duration(parsedTimeout)
+		}
+		if timeout > maxTraceTimeout {
+			timeout = maxTraceTimeout
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), timeout)
+		defer cancel()
+		connCtx, err := router.TraceNextConnection(ctx, filter.Match)
+		if err != nil {
+			render.Status(r, http.StatusRequestTimeout)
+			render.JSON(w, r, ErrRequestTimeout)
+			return
+		}
+		id, _ := log.IDFromContext(connCtx)
+		render.JSON(w, r, render.M{
+			"id":    id.ID,
+			"start": id.CreatedAt,
+		})
+	}
+}
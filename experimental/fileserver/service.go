@@ -0,0 +1,166 @@
+package fileserver
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	aTLS "github.com/sagernet/sing/common/tls"
+)
+
+var _ adapter.Service = (*Service)(nil)
+
+type Service struct {
+	logger        log.ContextLogger
+	listen        M.Socksaddr
+	path          string
+	directory     string
+	listing       bool
+	authenticator *auth.Authenticator
+	tlsConfig     tls.ServerConfig
+	httpServer    *http.Server
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, options option.FileServerOptions) (*Service, error) {
+	if options.Listen == "" {
+		return nil, E.New("missing listen address")
+	}
+	listen := M.ParseSocksaddr(options.Listen)
+	if !listen.IsValid() || listen.Port == 0 {
+		return nil, E.New("invalid listen address: ", options.Listen)
+	}
+	if options.Directory == "" {
+		return nil, E.New("missing directory")
+	}
+	directoryInfo, err := os.Stat(options.Directory)
+	if err != nil {
+		return nil, E.Cause(err, "read directory")
+	}
+	if !directoryInfo.IsDir() {
+		return nil, E.New("not a directory: ", options.Directory)
+	}
+	servePath := options.Path
+	if servePath == "" {
+		servePath = "/"
+	} else {
+		if servePath[0] != '/' {
+			servePath = "/" + servePath
+		}
+		if !strings.HasSuffix(servePath, "/") {
+			servePath += "/"
+		}
+	}
+	service := &Service{
+		logger:    logger,
+		listen:    listen,
+		path:      servePath,
+		directory: options.Directory,
+		listing:   options.DirectoryListing,
+	}
+	if len(options.Users) > 0 {
+		service.authenticator = auth.NewAuthenticator(options.Users)
+	}
+	if options.TLS != nil && options.TLS.Enabled {
+		service.tlsConfig, err = tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return service, nil
+}
+
+func (s *Service) Start() error {
+	var handler http.Handler = http.FileServer(fileSystem{http.Dir(s.directory), s.listing})
+	if s.path != "/" {
+		handler = http.StripPrefix(strings.TrimSuffix(s.path, "/"), handler)
+	}
+	mux := http.NewServeMux()
+	mux.Handle(s.path, s.authorize(handler))
+	s.httpServer = &http.Server{Handler: mux}
+	listener, err := net.Listen("tcp", s.listen.String())
+	if err != nil {
+		return E.Cause(err, "listen file server")
+	}
+	if s.tlsConfig != nil {
+		err = s.tlsConfig.Start()
+		if err != nil {
+			listener.Close()
+			return E.Cause(err, "create TLS config")
+		}
+		if len(s.tlsConfig.NextProtos()) == 0 {
+			s.tlsConfig.SetNextProtos([]string{"http/1.1"})
+		}
+		listener = aTLS.NewListener(listener, s.tlsConfig)
+	}
+	s.logger.Info("file server listening at ", listener.Addr(), s.path)
+	go func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serve files: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Service) authorize(handler http.Handler) http.Handler {
+	if s.authenticator == nil {
+		return handler
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, loaded := r.BasicAuth()
+		if !loaded || !s.authenticator.Verify(username, password) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="sing-box"`)
+			http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+			return
+		}
+		handler.ServeHTTP(w, r)
+	})
+}
+
+func (s *Service) Close() error {
+	return common.Close(
+		common.PtrOrNil(s.httpServer),
+		s.tlsConfig,
+	)
+}
+
+// fileSystem hides directories without an index page unless listing is
+// enabled, so that a camouflage site does not expose its layout.
+type fileSystem struct {
+	http.FileSystem
+	listing bool
+}
+
+func (f fileSystem) Open(name string) (http.File, error) {
+	file, err := f.FileSystem.Open(name)
+	if err != nil || f.listing {
+		return file, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+	if info.IsDir() {
+		index, err := f.FileSystem.Open(path.Join(name, "index.html"))
+		if err != nil {
+			file.Close()
+			return nil, fs.ErrNotExist
+		}
+		index.Close()
+	}
+	return file, nil
+}
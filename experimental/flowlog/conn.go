@@ -0,0 +1,102 @@
+package flowlog
+
+import (
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+)
+
+type flowMetadata struct {
+	service       *Service
+	metadata      adapter.InboundContext
+	matchedRule   adapter.Rule
+	matchOutbound adapter.Outbound
+	createdAt     time.Time
+	upload        atomic.Int64
+	download      atomic.Int64
+	closeOnce     sync.Once
+	element       *list.Element[*flowMetadata]
+}
+
+func (m *flowMetadata) entry(now time.Time, endReason uint8) *Entry {
+	return newEntry(m.metadata, m.matchedRule, m.matchOutbound, m.createdAt, now, endReason, m.upload.Load(), m.download.Load())
+}
+
+func (m *flowMetadata) finish() {
+	m.closeOnce.Do(func() {
+		m.service.untrack(m)
+		m.service.emit(m.entry(time.Now(), FlowEndReasonEndOfFlow))
+	})
+}
+
+type flowConn struct {
+	N.ExtendedConn
+	*flowMetadata
+}
+
+func newFlowConn(conn net.Conn, metadata *flowMetadata) *flowConn {
+	return &flowConn{
+		ExtendedConn: bufio.NewCounterConn(conn, []N.CountFunc{func(n int64) {
+			metadata.upload.Add(n)
+		}}, []N.CountFunc{func(n int64) {
+			metadata.download.Add(n)
+		}}),
+		flowMetadata: metadata,
+	}
+}
+
+func (c *flowConn) Close() error {
+	c.finish()
+	return c.ExtendedConn.Close()
+}
+
+func (c *flowConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+func (c *flowConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *flowConn) WriterReplaceable() bool {
+	return true
+}
+
+type flowPacketConn struct {
+	N.PacketConn
+	*flowMetadata
+}
+
+func newFlowPacketConn(conn N.PacketConn, metadata *flowMetadata) *flowPacketConn {
+	return &flowPacketConn{
+		PacketConn: bufio.NewCounterPacketConn(conn, []N.CountFunc{func(n int64) {
+			metadata.upload.Add(n)
+		}}, []N.CountFunc{func(n int64) {
+			metadata.download.Add(n)
+		}}),
+		flowMetadata: metadata,
+	}
+}
+
+func (c *flowPacketConn) Close() error {
+	c.finish()
+	return c.PacketConn.Close()
+}
+
+func (c *flowPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+func (c *flowPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *flowPacketConn) WriterReplaceable() bool {
+	return true
+}
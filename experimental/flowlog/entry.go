@@ -0,0 +1,88 @@
+package flowlog
+
+import (
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	FlowEndReasonActiveTimeout uint8 = 2
+	FlowEndReasonEndOfFlow     uint8 = 3
+)
+
+// Entry uses IPFIX information element names where one exists.
+type Entry struct {
+	FlowStart                int64  `json:"flowStartMilliseconds"`
+	FlowEnd                  int64  `json:"flowEndMilliseconds"`
+	FlowDuration             int64  `json:"flowDurationMilliseconds"`
+	FlowEndReason            uint8  `json:"flowEndReason"`
+	Snapshot                 bool   `json:"snapshot,omitempty"`
+	ProtocolIdentifier       uint8  `json:"protocolIdentifier"`
+	SourceIPv4Address        string `json:"sourceIPv4Address,omitempty"`
+	SourceIPv6Address        string `json:"sourceIPv6Address,omitempty"`
+	SourceTransportPort      uint16 `json:"sourceTransportPort"`
+	DestinationIPv4Address   string `json:"destinationIPv4Address,omitempty"`
+	DestinationIPv6Address   string `json:"destinationIPv6Address,omitempty"`
+	DestinationTransportPort uint16 `json:"destinationTransportPort"`
+	InitiatorOctets          int64  `json:"initiatorOctets"`
+	ResponderOctets          int64  `json:"responderOctets"`
+	Domain                   string `json:"domain,omitempty"`
+	Protocol                 string `json:"protocol,omitempty"`
+	Inbound                  string `json:"inbound,omitempty"`
+	InboundType              string `json:"inboundType,omitempty"`
+	User                     string `json:"user,omitempty"`
+	Rule                     string `json:"rule"`
+	Outbound                 string `json:"outbound"`
+}
+
+func newEntry(metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound, createdAt time.Time, closedAt time.Time, endReason uint8, upload int64, download int64) *Entry {
+	entry := &Entry{
+		FlowStart:                createdAt.UnixMilli(),
+		FlowEnd:                  closedAt.UnixMilli(),
+		FlowDuration:             closedAt.Sub(createdAt).Milliseconds(),
+		FlowEndReason:            endReason,
+		Snapshot:                 endReason == FlowEndReasonActiveTimeout,
+		SourceTransportPort:      metadata.Source.Port,
+		DestinationTransportPort: metadata.Destination.Port,
+		InitiatorOctets:          upload,
+		ResponderOctets:          download,
+		Protocol:                 metadata.Protocol,
+		Inbound:                  metadata.Inbound,
+		InboundType:              metadata.InboundType,
+		User:                     metadata.User,
+		Outbound:                 adapter.OutboundTag(matchOutbound),
+	}
+	switch metadata.Network {
+	case N.NetworkTCP:
+		entry.ProtocolIdentifier = 6
+	case N.NetworkUDP:
+		entry.ProtocolIdentifier = 17
+	}
+	if metadata.Source.IsIPv4() {
+		entry.SourceIPv4Address = metadata.Source.Addr.String()
+	} else if metadata.Source.IsIPv6() {
+		entry.SourceIPv6Address = metadata.Source.Addr.String()
+	}
+	destination := metadata.Destination
+	if metadata.OriginDestination.IsValid() {
+		destination = metadata.OriginDestination
+	}
+	if destination.IsIPv4() {
+		entry.DestinationIPv4Address = destination.Addr.String()
+	} else if destination.IsIPv6() {
+		entry.DestinationIPv6Address = destination.Addr.String()
+	}
+	if metadata.Domain != "" {
+		entry.Domain = metadata.Domain
+	} else {
+		entry.Domain = metadata.Destination.Fqdn
+	}
+	if matchedRule != nil {
+		entry.Rule = matchedRule.String()
+	} else {
+		entry.Rule = "final"
+	}
+	return entry
+}
@@ -0,0 +1,264 @@
+package flowlog
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+var (
+	_ adapter.Service           = (*Service)(nil)
+	_ adapter.ConnectionTracker = (*Service)(nil)
+)
+
+type Service struct {
+	ctx              context.Context
+	logger           log.ContextLogger
+	path             string
+	snapshotPath     string
+	collector        M.Socksaddr
+	collectorURL     string
+	httpClient       *http.Client
+	snapshotInterval time.Duration
+	file             *os.File
+	snapshotFile     *os.File
+	posts            chan []byte
+	conn             net.Conn
+	entries          chan *Entry
+	snapshots        chan []*Entry
+	activeAccess     sync.Mutex
+	activeFlows      list.List[*flowMetadata]
+	done             chan struct{}
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, options option.FlowLogOptions) (*Service, error) {
+	if options.Path == "" && options.Collector == "" {
+		return nil, E.New("missing path or collector")
+	}
+	service := &Service{
+		ctx:              ctx,
+		logger:           logger,
+		path:             options.Path,
+		snapshotPath:     options.SnapshotPath,
+		entries:          make(chan *Entry, 1024),
+		snapshots:        make(chan []*Entry, 1),
+		snapshotInterval: time.Duration(options.SnapshotInterval),
+		done:             make(chan struct{}),
+	}
+	if collectorURL, err := url.Parse(options.Collector); err == nil && (collectorURL.Scheme == "http" || collectorURL.Scheme == "https") {
+		service.collectorURL = options.Collector
+		service.httpClient = &http.Client{Timeout: C.TCPTimeout}
+		service.posts = make(chan []byte, 16)
+	} else if options.Collector != "" {
+		service.collector = M.ParseSocksaddr(options.Collector)
+		if !service.collector.IsValid() || service.collector.Port == 0 {
+			return nil, E.New("invalid collector address: ", options.Collector)
+		}
+	}
+	if service.snapshotInterval > 0 && service.snapshotInterval < time.Second {
+		return nil, E.New("snapshot interval must be at least 1s")
+	}
+	if service.snapshotPath != "" && service.snapshotInterval == 0 {
+		return nil, E.New("snapshot_path requires snapshot_interval")
+	}
+	return service, nil
+}
+
+func (s *Service) Start() error {
+	if s.path != "" {
+		file, err := filemanager.OpenFile(s.ctx, s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return E.Cause(err, "open flow log file")
+		}
+		s.file = file
+	}
+	if s.snapshotPath != "" {
+		file, err := filemanager.OpenFile(s.ctx, s.snapshotPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return E.Cause(err, "open flow snapshot file")
+		}
+		s.snapshotFile = file
+	}
+	if s.collector.IsValid() {
+		conn, err := net.DialTimeout(N.NetworkUDP, s.collector.String(), C.TCPTimeout)
+		if err != nil {
+			return E.Cause(err, "connect to flow collector")
+		}
+		s.conn = conn
+	}
+	go s.loopWrite()
+	if s.httpClient != nil {
+		go s.loopPost()
+	}
+	if s.snapshotInterval > 0 {
+		go s.loopSnapshot()
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	select {
+	case <-s.done:
+		return os.ErrClosed
+	default:
+	}
+	close(s.done)
+	return common.Close(
+		common.PtrOrNil(s.file),
+		common.PtrOrNil(s.snapshotFile),
+		s.conn,
+	)
+}
+
+func (s *Service) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	return newFlowConn(conn, s.newMetadata(metadata, matchedRule, matchOutbound))
+}
+
+func (s *Service) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	return newFlowPacketConn(conn, s.newMetadata(metadata, matchedRule, matchOutbound))
+}
+
+func (s *Service) newMetadata(metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) *flowMetadata {
+	flow := &flowMetadata{
+		service:       s,
+		metadata:      metadata,
+		matchedRule:   matchedRule,
+		matchOutbound: matchOutbound,
+		createdAt:     time.Now(),
+	}
+	if s.snapshotInterval > 0 {
+		s.activeAccess.Lock()
+		flow.element = s.activeFlows.PushBack(flow)
+		s.activeAccess.Unlock()
+	}
+	return flow
+}
+
+func (s *Service) untrack(flow *flowMetadata) {
+	if flow.element == nil {
+		return
+	}
+	s.activeAccess.Lock()
+	s.activeFlows.Remove(flow.element)
+	s.activeAccess.Unlock()
+}
+
+func (s *Service) loopSnapshot() {
+	ticker := time.NewTicker(s.snapshotInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.activeAccess.Lock()
+			snapshot := make([]*Entry, 0, s.activeFlows.Len())
+			for element := s.activeFlows.Front(); element != nil; element = element.Next() {
+				snapshot = append(snapshot, element.Value.entry(now, FlowEndReasonActiveTimeout))
+			}
+			s.activeAccess.Unlock()
+			if len(snapshot) == 0 {
+				continue
+			}
+			select {
+			case s.snapshots <- snapshot:
+			default:
+				s.logger.Warn("flow log snapshot skipped: previous snapshot still exporting")
+			}
+		}
+	}
+}
+
+func (s *Service) emit(entry *Entry) {
+	select {
+	case s.entries <- entry:
+	default:
+		s.logger.Warn("flow log queue full, entry dropped")
+	}
+}
+
+func (s *Service) loopWrite() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case entry := <-s.entries:
+			s.write([]*Entry{entry}, s.file)
+		case snapshot := <-s.snapshots:
+			file := s.file
+			if s.snapshotFile != nil {
+				file = s.snapshotFile
+			}
+			s.write(snapshot, file)
+		}
+	}
+}
+
+// write exports entries to file and the collector. Snapshot entries are
+// marked with the snapshot field so consumers can tell them from final records.
+func (s *Service) write(entries []*Entry, file *os.File) {
+	var buffer bytes.Buffer
+	for _, entry := range entries {
+		content, err := json.Marshal(entry)
+		if err != nil {
+			continue
+		}
+		if s.conn != nil {
+			_, err = s.conn.Write(content)
+			if err != nil {
+				s.logger.Debug("write flow to collector: ", err)
+			}
+		}
+		buffer.Write(content)
+		buffer.WriteByte('\n')
+	}
+	if file != nil {
+		_, err := file.Write(buffer.Bytes())
+		if err != nil {
+			s.logger.Error("write flow log: ", err)
+		}
+	}
+	if s.posts != nil && buffer.Len() > 0 {
+		select {
+		case s.posts <- buffer.Bytes():
+		default:
+			s.logger.Warn("flow collector queue full, ", len(entries), " entries dropped")
+		}
+	}
+}
+
+func (s *Service) loopPost() {
+	for {
+		select {
+		case <-s.done:
+			return
+		case content := <-s.posts:
+			response, err := s.httpClient.Post(s.collectorURL, "application/x-ndjson", bytes.NewReader(content))
+			if err != nil {
+				s.logger.Debug("post flows to collector: ", err)
+				continue
+			}
+			response.Body.Close()
+			if response.StatusCode/100 != 2 {
+				s.logger.Debug("post flows to collector: unexpected status: ", response.Status)
+			}
+		}
+	}
+}
@@ -16,4 +16,8 @@ const (
 	CommandSetSystemProxyEnabled
 	CommandConnections
 	CommandCloseConnection
+	CommandGetOutboundProviders
+	CommandUpdateOutboundProvider
+	CommandGetRuleProviders
+	CommandUpdateRuleProvider
 )
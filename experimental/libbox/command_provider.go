@@ -0,0 +1,226 @@
+package libbox
+
+import (
+	"encoding/binary"
+	"net"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/urltest"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/varbin"
+	"github.com/sagernet/sing/service"
+)
+
+type OutboundProvider struct {
+	Tag         string
+	Type        string
+	LastUpdated int64
+	Expired     int64
+	Total       int64
+	Download    int64
+	Upload      int64
+	ItemList    []*OutboundGroupItem
+}
+
+func (p *OutboundProvider) GetItems() OutboundGroupItemIterator {
+	return newIterator(p.ItemList)
+}
+
+type OutboundProviderIterator interface {
+	Next() *OutboundProvider
+	HasNext() bool
+}
+
+type RuleProvider struct {
+	Tag         string
+	Type        string
+	Format      string
+	RuleCount   int32
+	LastUpdated int64
+}
+
+type RuleProviderIterator interface {
+	Next() *RuleProvider
+	HasNext() bool
+}
+
+func (c *CommandClient) GetOutboundProviders() (OutboundProviderIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandGetOutboundProviders))
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := varbin.ReadValue[[]*OutboundProvider](conn, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(providers), nil
+}
+
+func (s *CommandServer) handleGetOutboundProviders(conn net.Conn) error {
+	serviceNow := s.service
+	if serviceNow == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	historyStorage := service.PtrFromContext[urltest.HistoryStorage](serviceNow.ctx)
+	var providers []OutboundProvider
+	for _, iProvider := range serviceNow.instance.Router().OutboundProviders() {
+		var provider OutboundProvider
+		provider.Tag = iProvider.Tag()
+		provider.Type = iProvider.Type()
+		if info := iProvider.ProviderInfo(); info != nil {
+			if !info.LastUpdated.IsZero() {
+				provider.LastUpdated = info.LastUpdated.Unix()
+			}
+			if !info.Expired.IsZero() {
+				provider.Expired = info.Expired.Unix()
+			}
+			provider.Total = int64(info.Total)
+			provider.Download = int64(info.Download)
+			provider.Upload = int64(info.Upload)
+		}
+		for _, itemTag := range iProvider.All() {
+			itemOutbound, isLoaded := iProvider.Outbound(itemTag)
+			if !isLoaded {
+				continue
+			}
+			var item OutboundGroupItem
+			item.Tag = itemTag
+			item.Type = itemOutbound.Type()
+			if historyStorage != nil {
+				if history := historyStorage.LoadURLTestHistory(adapter.OutboundTag(itemOutbound)); history != nil {
+					item.URLTestTime = history.Time.Unix()
+					item.URLTestDelay = int32(history.Delay)
+				}
+			}
+			provider.ItemList = append(provider.ItemList, &item)
+		}
+		providers = append(providers, provider)
+	}
+	err := writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return varbin.Write(conn, binary.BigEndian, providers)
+}
+
+func (c *CommandClient) UpdateOutboundProvider(providerTag string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandUpdateOutboundProvider))
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, providerTag)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleUpdateOutboundProvider(conn net.Conn) error {
+	providerTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	serviceNow := s.service
+	if serviceNow == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	provider, isLoaded := serviceNow.instance.Router().OutboundProvider(providerTag)
+	if !isLoaded {
+		return writeError(conn, E.New("outbound provider not found: ", providerTag))
+	}
+	return writeError(conn, provider.Update(serviceNow.ctx))
+}
+
+func (c *CommandClient) GetRuleProviders() (RuleProviderIterator, error) {
+	conn, err := c.directConnect()
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandGetRuleProviders))
+	if err != nil {
+		return nil, err
+	}
+	err = readError(conn)
+	if err != nil {
+		return nil, err
+	}
+	providers, err := varbin.ReadValue[[]*RuleProvider](conn, binary.BigEndian)
+	if err != nil {
+		return nil, err
+	}
+	return newIterator(providers), nil
+}
+
+func (s *CommandServer) handleGetRuleProviders(conn net.Conn) error {
+	serviceNow := s.service
+	if serviceNow == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	var providers []RuleProvider
+	for _, ruleSet := range serviceNow.instance.Router().RuleSets() {
+		metadata := ruleSet.Metadata()
+		provider := RuleProvider{
+			Tag:       ruleSet.Name(),
+			Type:      ruleSet.Type(),
+			Format:    metadata.Format,
+			RuleCount: int32(metadata.RuleNum),
+		}
+		if !metadata.LastUpdated.IsZero() {
+			provider.LastUpdated = metadata.LastUpdated.Unix()
+		}
+		providers = append(providers, provider)
+	}
+	err := writeError(conn, nil)
+	if err != nil {
+		return err
+	}
+	return varbin.Write(conn, binary.BigEndian, providers)
+}
+
+func (c *CommandClient) UpdateRuleProvider(providerTag string) error {
+	conn, err := c.directConnect()
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	err = binary.Write(conn, binary.BigEndian, uint8(CommandUpdateRuleProvider))
+	if err != nil {
+		return err
+	}
+	err = varbin.Write(conn, binary.BigEndian, providerTag)
+	if err != nil {
+		return err
+	}
+	return readError(conn)
+}
+
+func (s *CommandServer) handleUpdateRuleProvider(conn net.Conn) error {
+	providerTag, err := varbin.ReadValue[string](conn, binary.BigEndian)
+	if err != nil {
+		return err
+	}
+	serviceNow := s.service
+	if serviceNow == nil {
+		return writeError(conn, E.New("service not ready"))
+	}
+	ruleSet, isLoaded := serviceNow.instance.Router().RuleSet(providerTag)
+	if !isLoaded {
+		return writeError(conn, E.New("rule provider not found: ", providerTag))
+	}
+	return writeError(conn, ruleSet.Update(serviceNow.ctx))
+}
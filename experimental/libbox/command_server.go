@@ -174,6 +174,14 @@ func (s *CommandServer) handleConnection(conn net.Conn) error {
 		return s.handleConnectionsConn(conn)
 	case CommandCloseConnection:
 		return s.handleCloseConnection(conn)
+	case CommandGetOutboundProviders:
+		return s.handleGetOutboundProviders(conn)
+	case CommandUpdateOutboundProvider:
+		return s.handleUpdateOutboundProvider(conn)
+	case CommandGetRuleProviders:
+		return s.handleGetRuleProviders(conn)
+	case CommandUpdateRuleProvider:
+		return s.handleUpdateRuleProvider(conn)
 	default:
 		return E.New("unknown command: ", command)
 	}
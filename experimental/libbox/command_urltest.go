@@ -50,6 +50,9 @@ func (s *CommandServer) handleURLTest(conn net.Conn) error {
 		return writeError(conn, E.New("outbound is not a group: ", groupTag))
 	}
 	urlTest, isURLTest := abstractOutboundGroup.(*outbound.URLTest)
+	if fallback, isFallback := abstractOutboundGroup.(*outbound.Fallback); isFallback {
+		urlTest, isURLTest = fallback.URLTest, true
+	}
 	if isURLTest {
 		go urlTest.CheckOutbounds()
 	} else {
@@ -11,6 +11,7 @@ import (
 
 	"github.com/sagernet/sing-box"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/jsonc"
 	"github.com/sagernet/sing-box/common/process"
 	"github.com/sagernet/sing-box/common/urltest"
 	C "github.com/sagernet/sing-box/constant"
@@ -22,6 +23,7 @@ import (
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/logger"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
@@ -90,6 +92,23 @@ func (s *BoxService) Close() error {
 	return s.instance.Close()
 }
 
+// AddOutbound adds an outbound to the running service from its JSON options.
+func (s *BoxService) AddOutbound(outboundContent string) error {
+	content, err := jsonc.Standardize([]byte(outboundContent))
+	if err != nil {
+		return E.Cause(err, "decode outbound")
+	}
+	options, err := json.UnmarshalExtended[option.Outbound](content)
+	if err != nil {
+		return E.Cause(err, "decode outbound")
+	}
+	return s.instance.AddOutbound(options)
+}
+
+func (s *BoxService) RemoveOutbound(tag string) error {
+	return s.instance.RemoveOutbound(tag)
+}
+
 func (s *BoxService) NeedWIFIState() bool {
 	return s.instance.Router().NeedWIFIState()
 }
@@ -203,27 +222,34 @@ func (w *platformInterfaceWrapper) ReadWIFIState() adapter.WIFIState {
 }
 
 func (w *platformInterfaceWrapper) FindProcessInfo(ctx context.Context, network string, source netip.AddrPort, destination netip.AddrPort) (*process.Info, error) {
-	var uid int32
 	if w.useProcFS {
-		uid = procfs.ResolveSocketByProcSearch(network, source, destination)
+		uid := procfs.ResolveSocketByProcSearch(network, source, destination)
 		if uid == -1 {
 			return nil, E.New("procfs: not found")
 		}
-	} else {
-		var ipProtocol int32
-		switch N.NetworkName(network) {
-		case N.NetworkTCP:
-			ipProtocol = syscall.IPPROTO_TCP
-		case N.NetworkUDP:
-			ipProtocol = syscall.IPPROTO_UDP
-		default:
-			return nil, E.New("unknown network: ", network)
-		}
-		var err error
-		uid, err = w.iif.FindConnectionOwner(ipProtocol, source.Addr().String(), int32(source.Port()), destination.Addr().String(), int32(destination.Port()))
+		packageName, _ := w.iif.PackageNameByUid(uid)
+		return &process.Info{UserId: uid, PackageName: packageName}, nil
+	}
+	var ipProtocol int32
+	switch N.NetworkName(network) {
+	case N.NetworkTCP:
+		ipProtocol = syscall.IPPROTO_TCP
+	case N.NetworkUDP:
+		ipProtocol = syscall.IPPROTO_UDP
+	default:
+		return nil, E.New("unknown network: ", network)
+	}
+	if C.IsDarwin {
+		// Apple platforms identify connection owners by bundle identifier instead of user id
+		appID, err := w.iif.FindConnectionAppID(ipProtocol, source.Addr().String(), int32(source.Port()), destination.Addr().String(), int32(destination.Port()))
 		if err != nil {
 			return nil, err
 		}
+		return &process.Info{UserId: -1, AppID: appID}, nil
+	}
+	uid, err := w.iif.FindConnectionOwner(ipProtocol, source.Addr().String(), int32(source.Port()), destination.Addr().String(), int32(destination.Port()))
+	if err != nil {
+		return nil, err
 	}
 	packageName, _ := w.iif.PackageNameByUid(uid)
 	return &process.Info{UserId: uid, PackageName: packageName}, nil
@@ -0,0 +1,97 @@
+package nativeapi
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/render"
+)
+
+var _ adapter.Service = (*Server)(nil)
+
+type Server struct {
+	router     adapter.Router
+	logger     log.Logger
+	listen     string
+	secret     string
+	httpServer *http.Server
+}
+
+func NewServer(router adapter.Router, logger log.Logger, options option.NativeAPIOptions) (*Server, error) {
+	if options.Listen == "" {
+		return nil, E.New("missing listen address")
+	}
+	return &Server{
+		router: router,
+		logger: logger,
+		listen: options.Listen,
+		secret: options.Secret,
+	}, nil
+}
+
+func (s *Server) Start() error {
+	chiRouter := chi.NewRouter()
+	chiRouter.Group(func(r chi.Router) {
+		r.Use(s.authenticate)
+		r.Get("/connections", s.getConnections)
+		r.Get("/connections/closed", s.getClosedConnections)
+		r.Delete("/connections/{id}", s.closeConnection)
+	})
+	s.httpServer = &http.Server{Handler: chiRouter}
+	listener, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return E.Cause(err, "listen native API")
+	}
+	s.logger.Info("native API listening at ", listener.Addr())
+	go func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serve native API: ", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) Close() error {
+	return common.Close(common.PtrOrNil(s.httpServer))
+}
+
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.secret != "" {
+			bearer, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+			if bearer != "Bearer" || !found || token != s.secret {
+				render.Status(r, http.StatusUnauthorized)
+				render.JSON(w, r, render.M{"error": "unauthorized"})
+				return
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (s *Server) getConnections(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, render.M{"connections": s.router.Connections()})
+}
+
+func (s *Server) getClosedConnections(w http.ResponseWriter, r *http.Request) {
+	render.JSON(w, r, render.M{"connections": s.router.ClosedConnections()})
+}
+
+func (s *Server) closeConnection(w http.ResponseWriter, r *http.Request) {
+	if !s.router.CloseConnection(chi.URLParam(r, "id")) {
+		render.Status(r, http.StatusNotFound)
+		render.JSON(w, r, render.M{"error": "connection not found"})
+		return
+	}
+	render.NoContent(w, r)
+}
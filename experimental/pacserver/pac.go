@@ -0,0 +1,219 @@
+package pacserver
+
+import (
+	"bytes"
+	"net/netip"
+	"strings"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
+)
+
+var privateIPv4Prefixes = []string{
+	"0.0.0.0/32",
+	"10.0.0.0/8",
+	"127.0.0.0/8",
+	"169.254.0.0/16",
+	"172.16.0.0/12",
+	"192.168.0.0/16",
+	"224.0.0.0/4",
+}
+
+type pacRule struct {
+	Domain        []string    `json:"domain,omitempty"`
+	DomainSuffix  []string    `json:"domain_suffix,omitempty"`
+	DomainKeyword []string    `json:"domain_keyword,omitempty"`
+	DomainRegex   []string    `json:"domain_regex,omitempty"`
+	IPCIDR        [][2]string `json:"ip_cidr,omitempty"`
+	Direct        bool        `json:"direct"`
+}
+
+const pacScript = `
+function matchDomain(rule, host) {
+	var i;
+	if (rule.domain) {
+		for (i = 0; i < rule.domain.length; i++) {
+			if (host == rule.domain[i]) return true;
+		}
+	}
+	if (rule.domain_suffix) {
+		for (i = 0; i < rule.domain_suffix.length; i++) {
+			var suffix = rule.domain_suffix[i];
+			if (suffix.charAt(0) != ".") {
+				if (host == suffix) return true;
+				suffix = "." + suffix;
+			}
+			if (host.length > suffix.length && host.substring(host.length - suffix.length) == suffix) return true;
+		}
+	}
+	if (rule.domain_keyword) {
+		for (i = 0; i < rule.domain_keyword.length; i++) {
+			if (host.indexOf(rule.domain_keyword[i]) >= 0) return true;
+		}
+	}
+	if (rule.domain_regex) {
+		for (i = 0; i < rule.domain_regex.length; i++) {
+			if (new RegExp(rule.domain_regex[i]).test(host)) return true;
+		}
+	}
+	return false;
+}
+
+function matchIP(rule, host) {
+	if (!/^\d+\.\d+\.\d+\.\d+$/.test(host)) return false;
+	for (var i = 0; i < rule.ip_cidr.length; i++) {
+		if (isInNet(host, rule.ip_cidr[i][0], rule.ip_cidr[i][1])) return true;
+	}
+	return false;
+}
+
+function FindProxyForURL(url, host) {
+	for (var i = 0; i < rules.length; i++) {
+		var rule = rules[i];
+		var hasDomain = rule.domain || rule.domain_suffix || rule.domain_keyword || rule.domain_regex;
+		if (hasDomain && !matchDomain(rule, host)) continue;
+		if (rule.ip_cidr && !matchIP(rule, host)) continue;
+		return rule.direct ? "DIRECT" : proxy;
+	}
+	return final_direct ? "DIRECT" : proxy;
+}
+`
+
+// generatePAC translates route rules into a PAC file. Only rules built from
+// domain and IPv4 destination items can be expressed; once a rule that cannot
+// be translated routes to a non-direct outbound, all later direct results are
+// dropped, so that traffic the router would proxy never bypasses it.
+func generatePAC(proxy string, routeOptions option.RouteOptions, outbounds []option.Outbound) ([]byte, error) {
+	outboundTypes := make(map[string]string)
+	for i, outbound := range outbounds {
+		tag := outbound.Tag
+		if tag == "" {
+			tag = F.ToString(i)
+		}
+		outboundTypes[tag] = outbound.Type
+	}
+	isDirect := func(tag string) bool {
+		return outboundTypes[tag] == C.TypeDirect
+	}
+	rules := make([]pacRule, 0, len(routeOptions.Rules))
+	var untranslated bool
+	for _, rule := range routeOptions.Rules {
+		if rule.Type != "" && rule.Type != C.RuleTypeDefault {
+			untranslated = untranslated || !isDirect(rule.LogicalOptions.Outbound)
+			continue
+		}
+		translated, loaded := translateRule(rule.DefaultOptions)
+		if !loaded {
+			untranslated = untranslated || !isDirect(rule.DefaultOptions.Outbound)
+			continue
+		}
+		translated.Direct = isDirect(rule.DefaultOptions.Outbound)
+		if translated.Direct && untranslated {
+			continue
+		}
+		rules = append(rules, translated)
+	}
+	finalTag := routeOptions.Final
+	if finalTag == "" {
+		if len(outbounds) > 0 {
+			finalTag = outbounds[0].Tag
+			if finalTag == "" {
+				finalTag = "0"
+			}
+		}
+	}
+	finalDirect := !untranslated && (len(outbounds) == 0 || isDirect(finalTag))
+	rulesContent, err := json.Marshal(rules)
+	if err != nil {
+		return nil, err
+	}
+	proxyContent, err := json.Marshal(proxy)
+	if err != nil {
+		return nil, err
+	}
+	buffer := new(bytes.Buffer)
+	buffer.WriteString("var proxy = ")
+	buffer.Write(proxyContent)
+	buffer.WriteString(";\nvar final_direct = ")
+	if finalDirect {
+		buffer.WriteString("true")
+	} else {
+		buffer.WriteString("false")
+	}
+	buffer.WriteString(";\nvar rules = ")
+	buffer.Write(rulesContent)
+	buffer.WriteString(";\n")
+	buffer.WriteString(pacScript)
+	return buffer.Bytes(), nil
+}
+
+func translateRule(options option.DefaultRule) (pacRule, bool) {
+	if options.Invert ||
+		len(options.Inbound) > 0 ||
+		options.IPVersion != 0 ||
+		len(options.Network) > 0 ||
+		len(options.AuthUser) > 0 ||
+		len(options.Protocol) > 0 ||
+		len(options.Geosite) > 0 ||
+		len(options.SourceGeoIP) > 0 ||
+		len(options.GeoIP) > 0 ||
+		len(options.SourceIPCIDR) > 0 ||
+		options.SourceIPIsPrivate ||
+		len(options.SourcePort) > 0 ||
+		len(options.SourcePortRange) > 0 ||
+		len(options.Port) > 0 ||
+		len(options.PortRange) > 0 ||
+		len(options.ProcessName) > 0 ||
+		len(options.ProcessPath) > 0 ||
+		len(options.PackageName) > 0 ||
+		len(options.AppID) > 0 ||
+		len(options.User) > 0 ||
+		len(options.UserID) > 0 ||
+		options.ClashMode != "" ||
+		len(options.WIFISSID) > 0 ||
+		len(options.WIFIBSSID) > 0 ||
+		len(options.RuleSet) > 0 {
+		return pacRule{}, false
+	}
+	rule := pacRule{
+		Domain:        options.Domain,
+		DomainSuffix:  options.DomainSuffix,
+		DomainKeyword: options.DomainKeyword,
+		DomainRegex:   options.DomainRegex,
+	}
+	prefixes := append([]string(nil), options.IPCIDR...)
+	if options.IPIsPrivate {
+		prefixes = append(prefixes, privateIPv4Prefixes...)
+	}
+	for _, prefixString := range prefixes {
+		var prefix netip.Prefix
+		var err error
+		if strings.Contains(prefixString, "/") {
+			prefix, err = netip.ParsePrefix(prefixString)
+		} else {
+			var addr netip.Addr
+			addr, err = netip.ParseAddr(prefixString)
+			if err == nil {
+				prefix = netip.PrefixFrom(addr, addr.BitLen())
+			}
+		}
+		if err != nil || !prefix.Addr().Is4() {
+			return pacRule{}, false
+		}
+		rule.IPCIDR = append(rule.IPCIDR, [2]string{prefix.Masked().Addr().String(), prefixMask(prefix.Bits())})
+	}
+	if len(rule.Domain) == 0 && len(rule.DomainSuffix) == 0 && len(rule.DomainKeyword) == 0 && len(rule.DomainRegex) == 0 && len(rule.IPCIDR) == 0 {
+		return pacRule{}, false
+	}
+	return rule, true
+}
+
+func prefixMask(bits int) string {
+	var mask [4]byte
+	for i := 0; i < bits; i++ {
+		mask[i/8] |= 0x80 >> (i % 8)
+	}
+	return netip.AddrFrom4(mask).String()
+}
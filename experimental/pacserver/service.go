@@ -0,0 +1,154 @@
+package pacserver
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/settings"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var _ adapter.Service = (*Service)(nil)
+
+type Service struct {
+	ctx            context.Context
+	logger         log.ContextLogger
+	listen         M.Socksaddr
+	path           string
+	content        []byte
+	setSystemProxy bool
+	httpServer     *http.Server
+	systemProxy    settings.SystemProxy
+}
+
+func NewService(ctx context.Context, logger log.ContextLogger, options option.PACServerOptions, inbounds []option.Inbound, outbounds []option.Outbound, routeOptions option.RouteOptions) (*Service, error) {
+	if options.Listen == "" {
+		return nil, E.New("missing listen address")
+	}
+	listen := M.ParseSocksaddr(options.Listen)
+	if !listen.IsValid() || listen.Port == 0 {
+		return nil, E.New("invalid listen address: ", options.Listen)
+	}
+	path := options.Path
+	if path == "" {
+		path = "/proxy.pac"
+	} else if path[0] != '/' {
+		path = "/" + path
+	}
+	proxy, err := proxyFromInbound(options.Inbound, inbounds)
+	if err != nil {
+		return nil, err
+	}
+	content, err := generatePAC(proxy, routeOptions, outbounds)
+	if err != nil {
+		return nil, E.Cause(err, "generate PAC file")
+	}
+	return &Service{
+		ctx:            ctx,
+		logger:         logger,
+		listen:         listen,
+		path:           path,
+		content:        content,
+		setSystemProxy: options.SetSystemProxy,
+	}, nil
+}
+
+// proxyFromInbound returns the PAC proxy directive for the named inbound, or
+// for the first HTTP, mixed or SOCKS inbound if no tag is given.
+func proxyFromInbound(tag string, inbounds []option.Inbound) (string, error) {
+	for _, inbound := range inbounds {
+		if tag != "" && inbound.Tag != tag {
+			continue
+		}
+		var listenOptions option.ListenOptions
+		switch inbound.Type {
+		case C.TypeHTTP:
+			listenOptions = inbound.HTTPOptions.ListenOptions
+		case C.TypeMixed:
+			listenOptions = inbound.MixedOptions.ListenOptions
+		case C.TypeSOCKS:
+			listenOptions = inbound.SocksOptions.ListenOptions
+		default:
+			if tag != "" {
+				return "", E.New("inbound ", tag, " is not a http, mixed or socks inbound")
+			}
+			continue
+		}
+		addr := netip.IPv4Unspecified()
+		if listenOptions.Listen != nil {
+			addr = listenOptions.Listen.Build()
+		}
+		if addr.IsUnspecified() {
+			addr = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+		}
+		serverAddr := M.SocksaddrFrom(addr, listenOptions.ListenPort).String()
+		if inbound.Type == C.TypeSOCKS {
+			return "SOCKS5 " + serverAddr + "; SOCKS " + serverAddr, nil
+		}
+		return "PROXY " + serverAddr, nil
+	}
+	if tag != "" {
+		return "", E.New("inbound not found: ", tag)
+	}
+	return "", E.New("missing http, mixed or socks inbound for PAC file")
+}
+
+func (s *Service) Start() error {
+	listener, err := net.Listen("tcp", s.listen.String())
+	if err != nil {
+		return E.Cause(err, "listen PAC server")
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(s.path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+		w.Write(s.content)
+	})
+	s.httpServer = &http.Server{Handler: mux}
+	s.logger.Info("PAC server listening at ", listener.Addr(), s.path)
+	go func() {
+		err := s.httpServer.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.logger.Error("serve PAC file: ", err)
+		}
+	}()
+	if s.setSystemProxy {
+		pacAddr := s.listen
+		if pacAddr.Addr.IsUnspecified() {
+			pacAddr.Addr = netip.AddrFrom4([4]byte{127, 0, 0, 1})
+		}
+		systemProxy, err := settings.NewSystemProxyPAC(s.ctx, "http://"+pacAddr.String()+s.path)
+		if err != nil {
+			return E.Cause(err, "initialize system proxy")
+		}
+		err = systemProxy.Enable()
+		if err != nil {
+			return E.Cause(err, "set system proxy")
+		}
+		s.systemProxy = systemProxy
+	}
+	return nil
+}
+
+func (s *Service) Close() error {
+	var err error
+	if s.systemProxy != nil && s.systemProxy.IsEnabled() {
+		err = s.systemProxy.Disable()
+		if err != nil {
+			err = E.Cause(err, "unset system proxy")
+		}
+	}
+	if s.httpServer != nil {
+		err = E.Append(err, s.httpServer.Close(), func(err error) error {
+			return E.Cause(err, "close PAC server")
+		})
+	}
+	return err
+}
@@ -0,0 +1,179 @@
+package tasks
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-box/outbound"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+
+	"github.com/robfig/cron/v3"
+)
+
+var _ adapter.Service = (*Service)(nil)
+
+type Service struct {
+	ctx        context.Context
+	router     adapter.Router
+	logger     log.ContextLogger
+	logFactory log.Factory
+	cron       *cron.Cron
+}
+
+type task struct {
+	tag     string
+	action  string
+	targets []string
+}
+
+func NewService(ctx context.Context, router adapter.Router, logFactory log.Factory, options []option.TaskOptions) (*Service, error) {
+	s := &Service{
+		ctx:        ctx,
+		router:     router,
+		logger:     logFactory.NewLogger("tasks"),
+		logFactory: logFactory,
+		cron:       cron.New(),
+	}
+	for i, taskOptions := range options {
+		tag := taskOptions.Tag
+		if tag == "" {
+			tag = F.ToString(i)
+		}
+		switch taskOptions.Action {
+		case C.TaskActionUpdateProviders, C.TaskActionURLTest, C.TaskActionClearDNSCache, C.TaskActionClearFakeIP, C.TaskActionRotateLogs, C.TaskActionResetSelector:
+		case "":
+			return nil, E.New("task[", tag, "]: missing action")
+		default:
+			return nil, E.New("task[", tag, "]: unknown action: ", taskOptions.Action)
+		}
+		t := &task{
+			tag:     tag,
+			action:  taskOptions.Action,
+			targets: taskOptions.Targets,
+		}
+		_, err := s.cron.AddFunc(taskOptions.Schedule, func() {
+			s.run(t)
+		})
+		if err != nil {
+			return nil, E.Cause(err, "task[", tag, "]: parse schedule")
+		}
+	}
+	return s, nil
+}
+
+func (s *Service) Start() error {
+	s.cron.Start()
+	return nil
+}
+
+func (s *Service) Close() error {
+	<-s.cron.Stop().Done()
+	return nil
+}
+
+func (s *Service) run(t *task) {
+	s.logger.Debug("run task[", t.tag, "]: ", t.action)
+	var err error
+	switch t.action {
+	case C.TaskActionUpdateProviders:
+		err = s.updateProviders(t.targets)
+	case C.TaskActionURLTest:
+		err = s.urlTest(t.targets)
+	case C.TaskActionClearDNSCache:
+		s.router.ClearDNSCache()
+	case C.TaskActionClearFakeIP:
+		fakeIPStore := s.router.FakeIPStore()
+		if fakeIPStore == nil {
+			err = E.New("fakeip is not enabled")
+		} else {
+			err = fakeIPStore.Reset()
+		}
+	case C.TaskActionRotateLogs:
+		rotatableFactory, isRotatable := s.logFactory.(log.RotatableFactory)
+		if !isRotatable {
+			err = E.New("log output is not a file")
+		} else {
+			err = rotatableFactory.Rotate()
+		}
+	case C.TaskActionResetSelector:
+		err = s.resetSelectors(t.targets)
+	}
+	if err != nil {
+		s.logger.Error("task[", t.tag, "]: ", err)
+	} else {
+		s.logger.Info("task[", t.tag, "]: ", t.action, " finished")
+	}
+}
+
+func (s *Service) updateProviders(targets []string) error {
+	var errors []error
+	for _, provider := range s.router.OutboundProviders() {
+		if len(targets) > 0 && !common.Contains(targets, provider.Tag()) {
+			continue
+		}
+		err := provider.Update(s.ctx)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "update outbound provider ", provider.Tag()))
+		}
+	}
+	for _, ruleSet := range s.router.RuleSets() {
+		if len(targets) > 0 && !common.Contains(targets, ruleSet.Name()) {
+			continue
+		}
+		if len(targets) == 0 && ruleSet.Type() != C.RuleSetTypeRemote {
+			continue
+		}
+		err := ruleSet.Update(s.ctx)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "update rule-set ", ruleSet.Name()))
+		}
+	}
+	return E.Errors(errors...)
+}
+
+func (s *Service) groups(targets []string) []adapter.Outbound {
+	var groups []adapter.Outbound
+	for _, it := range s.router.Outbounds() {
+		if _, isGroup := it.(adapter.OutboundGroup); isGroup {
+			groups = append(groups, it)
+		}
+	}
+	for _, provider := range s.router.OutboundProviders() {
+		for _, group := range provider.GroupOutbounds() {
+			groups = append(groups, group)
+		}
+	}
+	if len(targets) > 0 {
+		groups = common.Filter(groups, func(it adapter.Outbound) bool {
+			return common.Contains(targets, it.Tag())
+		})
+	}
+	return groups
+}
+
+func (s *Service) urlTest(targets []string) error {
+	for _, group := range s.groups(targets) {
+		if urlTest, isURLTest := group.(*outbound.URLTest); isURLTest {
+			urlTest.CheckOutbounds()
+		} else if len(targets) > 0 {
+			return E.New("outbound ", group.Tag(), " is not a urltest group")
+		}
+	}
+	return nil
+}
+
+func (s *Service) resetSelectors(targets []string) error {
+	for _, group := range s.groups(targets) {
+		if selector, isSelector := group.(*outbound.Selector); isSelector {
+			selector.SelectDefault()
+		} else if len(targets) > 0 {
+			return E.New("outbound ", group.Tag(), " is not a selector")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,35 @@
+package inbound
+
+import (
+	"os"
+	"strconv"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/redir"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+// newAutoRedirect creates the firewall rules of redirect and tproxy inbounds,
+// for the address families of the listen address. The output mark is
+// registered by the router, so that dialers created before inbounds use it.
+func newAutoRedirect(router adapter.Router, listenOptions option.ListenOptions, options redir.AutoRedirectOptions) (*redir.AutoRedirect, error) {
+	listenAddr := listenOptions.Listen.Build()
+	if !listenAddr.IsUnspecified() {
+		return nil, E.New("unspecified `listen` address is required by `auto_redirect`")
+	}
+	if listenOptions.ListenPort == 0 {
+		return nil, E.New("`listen_port` is required by `auto_redirect`")
+	}
+	options.Port = listenOptions.ListenPort
+	options.Inet4 = true
+	options.Inet6 = listenAddr.Is6()
+	options.OutputMark = router.AutoRedirectOutputMark()
+	disableNFTables, dErr := strconv.ParseBool(os.Getenv("DISABLE_NFTABLES"))
+	options.DisableNFTables = dErr == nil && disableNFTables
+	autoRedirect, err := redir.NewAutoRedirect(options)
+	if err != nil {
+		return nil, E.Cause(err, "initialize auto-redirect")
+	}
+	return autoRedirect, nil
+}
@@ -15,6 +15,7 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 	if options.Type == "" {
 		return nil, E.New("missing inbound type")
 	}
+	ctx = adapter.ContextWithRouter(ctx, router)
 	switch options.Type {
 	case C.TypeTun:
 		return NewTun(ctx, router, logger, tag, options.TunOptions, platformInterface)
@@ -48,6 +49,8 @@ func New(ctx context.Context, router adapter.Router, logger log.ContextLogger, t
 		return NewTUIC(ctx, router, logger, tag, options.TUICOptions)
 	case C.TypeHysteria2:
 		return NewHysteria2(ctx, router, logger, tag, options.Hysteria2Options)
+	case C.TypeDNS:
+		return NewDNS(ctx, router, logger, tag, options.DNSOptions)
 	default:
 		return nil, E.New("unknown inbound type: ", options.Type)
 	}
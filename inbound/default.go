@@ -3,14 +3,21 @@ package inbound
 import (
 	"context"
 	"net"
+	"net/netip"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/knock"
+	"github.com/sagernet/sing-box/common/porthop"
+	"github.com/sagernet/sing-box/common/portmap"
+	"github.com/sagernet/sing-box/common/relay"
 	"github.com/sagernet/sing-box/common/settings"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -36,11 +43,20 @@ type myInboundAdapter struct {
 	setSystemProxy bool
 	systemProxy    settings.SystemProxy
 
+	knockGate  *knock.Gate
+	portMapper *portmap.Mapper
+
+	// hysteria2
+
+	udpHopPorts []uint16
+
 	// internal
 
 	tcpListener          net.Listener
+	tcpListeners         []net.Listener
 	udpConn              *net.UDPConn
 	udpAddr              M.Socksaddr
+	udpHopConn           *porthop.PacketConn
 	packetOutboundClosed chan struct{}
 	packetOutbound       chan *myInboundPacket
 
@@ -60,13 +76,26 @@ func (a *myInboundAdapter) Network() []string {
 }
 
 func (a *myInboundAdapter) Start() error {
-	var err error
+	err := a.startKnock()
+	if err != nil {
+		return err
+	}
 	if common.Contains(a.network, N.NetworkTCP) {
-		_, err = a.ListenTCP()
-		if err != nil {
-			return err
+		if a.listenOptions.ReusePortListeners > 1 {
+			err = a.listenTCPShards()
+			if err != nil {
+				return err
+			}
+			for i, tcpListener := range a.tcpListeners {
+				go a.loopTCPShardIn(tcpListener, i)
+			}
+		} else {
+			_, err = a.ListenTCP()
+			if err != nil {
+				return err
+			}
+			go a.loopTCPIn(a.tcpListener)
 		}
-		go a.loopTCPIn()
 	}
 	if common.Contains(a.network, N.NetworkUDP) {
 		_, err = a.ListenUDP()
@@ -110,19 +139,109 @@ func (a *myInboundAdapter) Start() error {
 		}
 		a.systemProxy = systemProxy
 	}
+	if a.listenOptions.PortMapping != nil {
+		err = a.startPortMapping()
+		if err != nil {
+			return E.Cause(err, "initialize port mapping")
+		}
+	}
+	return nil
+}
+
+func (a *myInboundAdapter) startPortMapping() error {
+	mappingOptions := a.listenOptions.PortMapping
+	var mappings []portmap.Mapping
+	if a.tcpListener != nil {
+		mappings = append(mappings, portmap.Mapping{
+			Network:      N.NetworkTCP,
+			InternalPort: M.SocksaddrFromNet(a.tcpListener.Addr()).Port,
+			ExternalPort: mappingOptions.ExternalPort,
+		})
+	} else if len(a.tcpListeners) > 0 {
+		mappings = append(mappings, portmap.Mapping{
+			Network:      N.NetworkTCP,
+			InternalPort: M.SocksaddrFromNet(a.tcpListeners[0].Addr()).Port,
+			ExternalPort: mappingOptions.ExternalPort,
+		})
+	}
+	if a.udpConn != nil {
+		mappings = append(mappings, portmap.Mapping{
+			Network:      N.NetworkUDP,
+			InternalPort: a.udpAddr.Port,
+			ExternalPort: mappingOptions.ExternalPort,
+		})
+	}
+	if a.listenOptions.Knock != nil {
+		mappings = append(mappings, portmap.Mapping{
+			Network:      N.NetworkUDP,
+			InternalPort: a.listenOptions.Knock.ListenPort,
+		})
+	}
+	var gateway netip.Addr
+	if mappingOptions.Gateway != nil {
+		gateway = mappingOptions.Gateway.Build()
+	}
+	portMapper, err := portmap.New(portmap.Options{
+		Context:     a.ctx,
+		Logger:      a.logger,
+		Protocol:    mappingOptions.Protocol,
+		Gateway:     gateway,
+		Mappings:    mappings,
+		Lifetime:    time.Duration(mappingOptions.Lifetime),
+		Description: "sing-box " + a.protocol,
+	})
+	if err != nil {
+		return err
+	}
+	portMapper.Start()
+	a.portMapper = portMapper
 	return nil
 }
 
 func (a *myInboundAdapter) Close() error {
 	a.inShutdown.Store(true)
 	var err error
+	if a.portMapper != nil {
+		err = a.portMapper.Close()
+	}
 	if a.systemProxy != nil && a.systemProxy.IsEnabled() {
-		err = a.systemProxy.Disable()
+		err = E.Errors(err, a.systemProxy.Disable())
+	}
+	if len(a.tcpListeners) > 0 {
+		for _, tcpListener := range a.tcpListeners {
+			err = E.Errors(err, tcpListener.Close())
+		}
+	} else {
+		err = E.Errors(err, common.Close(a.tcpListener))
+	}
+	udpConn := common.PtrOrNil(a.udpConn)
+	if a.udpHopConn != nil {
+		udpConn = a.udpHopConn
 	}
-	return E.Errors(err, common.Close(
-		a.tcpListener,
-		common.PtrOrNil(a.udpConn),
-	))
+	return E.Errors(err, common.Close(udpConn, common.PtrOrNil(a.knockGate)))
+}
+
+// startKnock starts the knock gate once, whether the inbound uses the default
+// listeners or listens by itself.
+func (a *myInboundAdapter) startKnock() error {
+	if a.listenOptions.Knock == nil || a.knockGate != nil {
+		return nil
+	}
+	knockOptions := a.listenOptions.Knock
+	knockGate, err := knock.NewGate(a.ctx, a.logger, M.SocksaddrFrom(a.listenOptions.Listen.Build(), knockOptions.ListenPort), knockOptions.Key, time.Duration(knockOptions.Timeout))
+	if err != nil {
+		return err
+	}
+	err = knockGate.Start()
+	if err != nil {
+		return err
+	}
+	a.knockGate = knockGate
+	return nil
+}
+
+func (a *myInboundAdapter) knockAllowed(addr netip.Addr) bool {
+	return a.knockGate == nil || a.knockGate.Allowed(addr)
 }
 
 func (a *myInboundAdapter) upstreamHandler(metadata adapter.InboundContext) adapter.UpstreamHandlerAdapter {
@@ -150,6 +269,19 @@ func (a *myInboundAdapter) newPacketConnection(ctx context.Context, conn N.Packe
 	return a.router.RoutePacketConnection(ctx, conn, metadata)
 }
 
+func (a *myInboundAdapter) bufferControl() (control.Func, error) {
+	bufferOptions := a.listenOptions.Buffer
+	router := adapter.RouterFromContext(a.ctx)
+	if router != nil {
+		bufferOptions = relay.MergeOptions(bufferOptions, router.DefaultBuffer())
+	}
+	err := relay.Validate(bufferOptions)
+	if err != nil {
+		return nil, err
+	}
+	return relay.SocketControl(bufferOptions), nil
+}
+
 func (a *myInboundAdapter) createMetadata(conn net.Conn, metadata adapter.InboundContext) adapter.InboundContext {
 	metadata.Inbound = a.tag
 	metadata.InboundType = a.protocol
@@ -3,8 +3,10 @@ package inbound
 import (
 	"context"
 	"net"
+	"runtime"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/mptcp"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing/common/control"
@@ -14,19 +16,73 @@ import (
 )
 
 func (a *myInboundAdapter) ListenTCP() (net.Listener, error) {
-	var err error
+	tcpListener, err := a.listenTCP(nil)
+	if err == nil {
+		a.logger.Info("tcp server started at ", tcpListener.Addr())
+	}
+	if a.listenOptions.ProxyProtocol || a.listenOptions.ProxyProtocolAcceptNoHeader {
+		return nil, E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
+	}
+	a.tcpListener = tcpListener
+	return tcpListener, err
+}
+
+func (a *myInboundAdapter) listenTCPShards() error {
+	if a.listenOptions.ProxyProtocol || a.listenOptions.ProxyProtocolAcceptNoHeader {
+		return E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
+	}
+	if !reusePortAvailable {
+		return E.New("reuse_port_listeners is only supported on Linux")
+	}
+	for i := 0; i < a.listenOptions.ReusePortListeners; i++ {
+		shardControl := control.ReuseAddr()
+		if a.listenOptions.ReusePortCPUAffinity {
+			shardControl = control.Append(shardControl, setIncomingCPU(i%runtime.NumCPU()))
+		}
+		tcpListener, err := a.listenTCP(shardControl)
+		if err != nil {
+			for _, shardListener := range a.tcpListeners {
+				shardListener.Close()
+			}
+			a.tcpListeners = nil
+			return err
+		}
+		a.tcpListeners = append(a.tcpListeners, tcpListener)
+	}
+	a.tcpListener = a.tcpListeners[0]
+	a.logger.Info("tcp server started at ", a.tcpListener.Addr(), " with ", len(a.tcpListeners), " listeners")
+	return nil
+}
+
+func (a *myInboundAdapter) listenTCP(extraControl control.Func) (net.Listener, error) {
 	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), a.listenOptions.ListenPort)
 	var tcpListener net.Listener
 	var listenConfig net.ListenConfig
 	// TODO: Add an option to customize the keep alive period
 	listenConfig.KeepAlive = C.TCPKeepAliveInitial
 	listenConfig.Control = control.Append(listenConfig.Control, control.SetKeepAlivePeriod(C.TCPKeepAliveInitial, C.TCPKeepAliveInterval))
-	if a.listenOptions.TCPMultiPath {
+	bufferControl, err := a.bufferControl()
+	if err != nil {
+		return nil, err
+	}
+	listenConfig.Control = control.Append(listenConfig.Control, bufferControl)
+	listenConfig.Control = control.Append(listenConfig.Control, extraControl)
+	if a.listenOptions.TCPMultiPath || len(a.listenOptions.TCPMultiPathInterfaces) > 0 {
 		if !go121Available {
 			return nil, E.New("MultiPath TCP requires go1.21, please recompile your binary.")
 		}
 		setMultiPathTCP(&listenConfig)
 	}
+	if len(a.listenOptions.TCPMultiPathInterfaces) > 0 {
+		router := adapter.RouterFromContext(a.ctx)
+		if router == nil {
+			return nil, E.New("missing router for MultiPath TCP interfaces")
+		}
+		err = router.RegisterMultipathTCPInterfaces(a.listenOptions.TCPMultiPathInterfaces, mptcp.FlagSignal)
+		if err != nil {
+			return nil, err
+		}
+	}
 	if a.listenOptions.TCPFastOpen {
 		if !go120Available {
 			return nil, E.New("TCP Fast Open requires go1.20, please recompile your binary.")
@@ -35,18 +91,21 @@ func (a *myInboundAdapter) ListenTCP() (net.Listener, error) {
 	} else {
 		tcpListener, err = listenConfig.Listen(a.ctx, M.NetworkFromNetAddr(N.NetworkTCP, bindAddr.Addr), bindAddr.String())
 	}
-	if err == nil {
-		a.logger.Info("tcp server started at ", tcpListener.Addr())
-	}
-	if a.listenOptions.ProxyProtocol || a.listenOptions.ProxyProtocolAcceptNoHeader {
-		return nil, E.New("Proxy Protocol is deprecated and removed in sing-box 1.6.0")
-	}
-	a.tcpListener = tcpListener
 	return tcpListener, err
 }
 
-func (a *myInboundAdapter) loopTCPIn() {
-	tcpListener := a.tcpListener
+func (a *myInboundAdapter) loopTCPShardIn(tcpListener net.Listener, index int) {
+	if a.listenOptions.ReusePortCPUAffinity {
+		runtime.LockOSThread()
+		err := setThreadAffinity(index % runtime.NumCPU())
+		if err != nil {
+			a.logger.Warn("set cpu affinity for listener ", index, ": ", err)
+		}
+	}
+	a.loopTCPIn(tcpListener)
+}
+
+func (a *myInboundAdapter) loopTCPIn(tcpListener net.Listener) {
 	for {
 		conn, err := tcpListener.Accept()
 		if err != nil {
@@ -59,10 +118,15 @@ func (a *myInboundAdapter) loopTCPIn() {
 			if a.inShutdown.Load() && E.IsClosed(err) {
 				return
 			}
-			a.tcpListener.Close()
+			tcpListener.Close()
 			a.logger.Error("serve error: ", err)
 			continue
 		}
+		if !a.knockAllowed(M.AddrFromNet(conn.RemoteAddr())) {
+			a.logger.Debug("reject connection from ", conn.RemoteAddr(), ": missing knock")
+			conn.Close()
+			continue
+		}
 		go a.injectTCP(conn, adapter.InboundContext{})
 	}
 }
@@ -0,0 +1,25 @@
+package inbound
+
+import (
+	"syscall"
+
+	"github.com/sagernet/sing/common/control"
+
+	"golang.org/x/sys/unix"
+)
+
+const reusePortAvailable = true
+
+func setIncomingCPU(cpu int) control.Func {
+	return func(network, address string, conn syscall.RawConn) error {
+		return control.Raw(conn, func(fd uintptr) error {
+			return unix.SetsockoptInt(int(fd), unix.SOL_SOCKET, unix.SO_INCOMING_CPU, cpu)
+		})
+	}
+}
+
+func setThreadAffinity(cpu int) error {
+	var cpuSet unix.CPUSet
+	cpuSet.Set(cpu)
+	return unix.SchedSetaffinity(0, &cpuSet)
+}
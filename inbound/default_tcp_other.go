@@ -0,0 +1,19 @@
+//go:build !linux
+
+package inbound
+
+import (
+	"os"
+
+	"github.com/sagernet/sing/common/control"
+)
+
+const reusePortAvailable = false
+
+func setIncomingCPU(cpu int) control.Func {
+	return nil
+}
+
+func setThreadAffinity(cpu int) error {
+	return os.ErrInvalid
+}
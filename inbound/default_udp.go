@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/porthop"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
@@ -16,6 +17,44 @@ import (
 
 func (a *myInboundAdapter) ListenUDP() (net.PacketConn, error) {
 	bindAddr := M.SocksaddrFrom(a.listenOptions.Listen.Build(), a.listenOptions.ListenPort)
+	udpConn, err := a.listenUDP(bindAddr)
+	if err != nil {
+		return nil, err
+	}
+	a.udpConn = udpConn.(*net.UDPConn)
+	a.udpAddr = bindAddr
+	a.logger.Info("udp server started at ", udpConn.LocalAddr())
+	if len(a.udpHopPorts) > 0 {
+		hopConns := []net.PacketConn{udpConn}
+		for _, port := range a.udpHopPorts {
+			if port == bindAddr.Port {
+				continue
+			}
+			hopConn, err := a.listenUDP(M.SocksaddrFrom(bindAddr.Addr, port))
+			if err != nil {
+				common.Close(common.Map(hopConns, func(it net.PacketConn) any { return it })...)
+				return nil, E.Cause(err, "listen hop port ", port)
+			}
+			hopConns = append(hopConns, hopConn)
+		}
+		a.udpHopConn = porthop.NewPacketConn(hopConns)
+		udpConn = a.udpHopConn
+		a.logger.Info("udp server hopping across ", len(hopConns), " ports")
+	}
+	// inbounds serving QUIC read from the returned conn instead of the
+	// default loops, which check knocks themselves
+	err = a.startKnock()
+	if err != nil {
+		common.Close(udpConn)
+		return nil, err
+	}
+	if a.knockGate != nil {
+		return a.knockGate.NewPacketConn(udpConn), nil
+	}
+	return udpConn, nil
+}
+
+func (a *myInboundAdapter) listenUDP(bindAddr M.Socksaddr) (net.PacketConn, error) {
 	var lc net.ListenConfig
 	var udpFragment bool
 	if a.listenOptions.UDPFragment != nil {
@@ -26,14 +65,12 @@ func (a *myInboundAdapter) ListenUDP() (net.PacketConn, error) {
 	if !udpFragment {
 		lc.Control = control.Append(lc.Control, control.DisableUDPFragment())
 	}
-	udpConn, err := lc.ListenPacket(a.ctx, M.NetworkFromNetAddr(N.NetworkUDP, bindAddr.Addr), bindAddr.String())
+	bufferControl, err := a.bufferControl()
 	if err != nil {
 		return nil, err
 	}
-	a.udpConn = udpConn.(*net.UDPConn)
-	a.udpAddr = bindAddr
-	a.logger.Info("udp server started at ", udpConn.LocalAddr())
-	return udpConn, err
+	lc.Control = control.Append(lc.Control, bufferControl)
+	return lc.ListenPacket(a.ctx, M.NetworkFromNetAddr(N.NetworkUDP, bindAddr.Addr), bindAddr.String())
 }
 
 func (a *myInboundAdapter) loopUDPIn() {
@@ -49,6 +86,9 @@ func (a *myInboundAdapter) loopUDPIn() {
 		if err != nil {
 			return
 		}
+		if !a.knockAllowed(addr.Addr()) {
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -77,6 +117,9 @@ func (a *myInboundAdapter) loopUDPOOBIn() {
 		if err != nil {
 			return
 		}
+		if !a.knockAllowed(addr.Addr()) {
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -101,6 +144,10 @@ func (a *myInboundAdapter) loopUDPInThreadSafe() {
 			buffer.Release()
 			return
 		}
+		if !a.knockAllowed(addr.Addr()) {
+			buffer.Release()
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -127,6 +174,10 @@ func (a *myInboundAdapter) loopUDPOOBInThreadSafe() {
 			buffer.Release()
 			return
 		}
+		if !a.knockAllowed(addr.Addr()) {
+			buffer.Release()
+			continue
+		}
 		buffer.Truncate(n)
 		var metadata adapter.InboundContext
 		metadata.Inbound = a.tag
@@ -179,12 +230,17 @@ func (a *myInboundAdapter) writePacket(buffer *buf.Buffer, destination M.Socksad
 type myInboundPacketAdapter myInboundAdapter
 
 func (s *myInboundPacketAdapter) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
-	n, addr, err := s.udpConn.ReadFromUDPAddrPort(buffer.FreeBytes())
-	if err != nil {
-		return M.Socksaddr{}, err
+	for {
+		n, addr, err := s.udpConn.ReadFromUDPAddrPort(buffer.FreeBytes())
+		if err != nil {
+			return M.Socksaddr{}, err
+		}
+		if !(*myInboundAdapter)(s).knockAllowed(addr.Addr()) {
+			continue
+		}
+		buffer.Truncate(n)
+		return M.SocksaddrFromNetIP(addr), nil
 	}
-	buffer.Truncate(n)
-	return M.SocksaddrFromNetIP(addr), nil
 }
 
 func (s *myInboundPacketAdapter) WriteIsThreadUnsafe() {
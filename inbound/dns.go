@@ -0,0 +1,308 @@
+package inbound
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/tls"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ adapter.Inbound = (*DNS)(nil)
+
+// DNS serves queries from clients through the DNS router, over plain UDP
+// and TCP, TLS, or HTTPS and HTTP/3.
+type DNS struct {
+	myInboundAdapter
+	dnsRouter   adapter.Router
+	dnsProtocol string
+	path        string
+	tlsConfig   tls.ServerConfig
+	httpServer  *http.Server
+	h3Server    any
+}
+
+func NewDNS(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.DNSInboundOptions) (*DNS, error) {
+	inbound := &DNS{
+		myInboundAdapter: myInboundAdapter{
+			protocol:      C.TypeDNS,
+			network:       options.Network.Build(),
+			ctx:           ctx,
+			router:        router,
+			logger:        logger,
+			tag:           tag,
+			listenOptions: options.ListenOptions,
+		},
+		dnsRouter:   router,
+		dnsProtocol: options.Protocol,
+		path:        options.Path,
+	}
+	if inbound.path == "" {
+		inbound.path = "/dns-query"
+	}
+	tlsEnabled := options.TLS != nil && options.TLS.Enabled
+	switch options.Protocol {
+	case "":
+		if tlsEnabled {
+			return nil, E.New("TLS is only allowed for the tls and https protocols")
+		}
+	case C.DNSInboundProtocolTLS:
+		if !tlsEnabled {
+			return nil, E.New("TLS is required for DNS over TLS")
+		}
+		if options.Network == "" {
+			inbound.network = []string{N.NetworkTCP}
+		} else if common.Contains(inbound.network, N.NetworkUDP) {
+			return nil, E.New("DNS over TLS does not support UDP")
+		}
+	case C.DNSInboundProtocolHTTPS:
+		if common.Contains(inbound.network, N.NetworkUDP) && !tlsEnabled {
+			if options.Network != "" {
+				return nil, E.New("TLS is required for HTTP3 server")
+			}
+			inbound.network = []string{N.NetworkTCP}
+		}
+	default:
+		return nil, E.New("unknown DNS inbound protocol: ", options.Protocol)
+	}
+	if tlsEnabled {
+		tlsConfig, err := tls.NewServer(ctx, logger, common.PtrValueOrDefault(options.TLS))
+		if err != nil {
+			return nil, err
+		}
+		inbound.tlsConfig = tlsConfig
+	}
+	inbound.connHandler = inbound
+	inbound.packetHandler = inbound
+	return inbound, nil
+}
+
+func (d *DNS) Start() error {
+	if d.tlsConfig != nil {
+		err := d.tlsConfig.Start()
+		if err != nil {
+			return E.Cause(err, "create TLS config")
+		}
+	}
+	if d.dnsProtocol != C.DNSInboundProtocolHTTPS {
+		return d.myInboundAdapter.Start()
+	}
+	if common.Contains(d.network, N.NetworkTCP) {
+		var tlsConfig *tls.STDConfig
+		if d.tlsConfig != nil {
+			var err error
+			tlsConfig, err = d.tlsConfig.Config()
+			if err != nil {
+				return err
+			}
+		}
+		tcpListener, err := d.ListenTCP()
+		if err != nil {
+			return err
+		}
+		d.httpServer = &http.Server{
+			Handler:   d,
+			TLSConfig: tlsConfig,
+			BaseContext: func(listener net.Listener) context.Context {
+				return d.ctx
+			},
+		}
+		go func() {
+			var sErr error
+			if tlsConfig != nil {
+				sErr = d.httpServer.ServeTLS(tcpListener, "", "")
+			} else {
+				sErr = d.httpServer.Serve(tcpListener)
+			}
+			if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+				d.logger.Error("http server serve error: ", sErr)
+			}
+		}()
+	}
+	if common.Contains(d.network, N.NetworkUDP) {
+		err := d.configureHTTP3Listener()
+		if !C.WithQUIC && len(d.network) > 1 {
+			d.logger.Warn(E.Cause(err, "DNS over HTTP3 disabled"))
+		} else if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *DNS) Close() error {
+	return common.Close(
+		&d.myInboundAdapter,
+		common.PtrOrNil(d.httpServer),
+		d.h3Server,
+		d.tlsConfig,
+	)
+}
+
+func (d *DNS) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	if d.tlsConfig != nil {
+		tlsConn, err := tls.ServerHandshake(ctx, conn, d.tlsConfig)
+		if err != nil {
+			return E.Cause(err, "TLS handshake")
+		}
+		conn = tlsConn
+	}
+	defer conn.Close()
+	var writeAccess sync.Mutex
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(C.DNSTimeout))
+		if err != nil {
+			return err
+		}
+		var queryLength uint16
+		err = binary.Read(conn, binary.BigEndian, &queryLength)
+		if err != nil {
+			if errors.Is(err, io.EOF) || E.IsTimeout(err) {
+				return nil
+			}
+			return err
+		}
+		if queryLength == 0 {
+			return dns.RCodeFormatError
+		}
+		buffer := buf.NewSize(int(queryLength))
+		_, err = buffer.ReadFullFrom(conn, int(queryLength))
+		if err != nil {
+			buffer.Release()
+			return err
+		}
+		var message mDNS.Msg
+		err = message.Unpack(buffer.Bytes())
+		buffer.Release()
+		if err != nil {
+			return err
+		}
+		go func() {
+			response := d.exchange(ctx, metadata, &message)
+			responseBuffer := buf.NewPacket()
+			defer responseBuffer.Release()
+			responseBuffer.Resize(2, 0)
+			rawResponse, err := response.PackBuffer(responseBuffer.FreeBytes())
+			if err != nil {
+				d.logger.ErrorContext(ctx, E.Cause(err, "pack response"))
+				return
+			}
+			responseBuffer.Truncate(len(rawResponse))
+			binary.BigEndian.PutUint16(responseBuffer.ExtendHeader(2), uint16(len(rawResponse)))
+			writeAccess.Lock()
+			_, err = conn.Write(responseBuffer.Bytes())
+			writeAccess.Unlock()
+			if err != nil {
+				d.logger.DebugContext(ctx, E.Cause(err, "write response"))
+			}
+		}()
+	}
+}
+
+func (d *DNS) NewPacket(ctx context.Context, conn N.PacketConn, buffer *buf.Buffer, metadata adapter.InboundContext) error {
+	var message mDNS.Msg
+	err := message.Unpack(buffer.Bytes())
+	if err != nil {
+		return err
+	}
+	ctx = log.ContextWithNewID(ctx)
+	go func() {
+		response := d.exchange(ctx, metadata, &message)
+		responseBuffer, err := dns.TruncateDNSMessage(&message, response, 1024)
+		if err != nil {
+			d.logger.ErrorContext(ctx, E.Cause(err, "pack response"))
+			return
+		}
+		err = conn.WritePacket(responseBuffer, metadata.Source)
+		if err != nil {
+			d.logger.DebugContext(ctx, E.Cause(err, "write response"))
+		}
+	}()
+	return nil
+}
+
+func (d *DNS) ServeHTTP(writer http.ResponseWriter, request *http.Request) {
+	ctx := log.ContextWithNewID(request.Context())
+	if request.URL.Path != d.path {
+		rejectHTTP(writer, http.StatusNotFound)
+		return
+	}
+	var (
+		rawMessage []byte
+		err        error
+	)
+	switch request.Method {
+	case http.MethodGet:
+		rawMessage, err = base64.RawURLEncoding.DecodeString(request.URL.Query().Get("dns"))
+	case http.MethodPost:
+		if request.Header.Get("Content-Type") != dns.MimeType {
+			rejectHTTP(writer, http.StatusUnsupportedMediaType)
+			return
+		}
+		rawMessage, err = io.ReadAll(io.LimitReader(request.Body, mDNS.MaxMsgSize))
+	default:
+		rejectHTTP(writer, http.StatusMethodNotAllowed)
+		return
+	}
+	var message mDNS.Msg
+	if err == nil {
+		err = message.Unpack(rawMessage)
+	}
+	if err != nil {
+		rejectHTTP(writer, http.StatusBadRequest)
+		d.logger.DebugContext(ctx, E.Cause(err, "process DNS request from ", request.RemoteAddr))
+		return
+	}
+	var metadata adapter.InboundContext
+	metadata.Inbound = d.tag
+	metadata.InboundType = d.protocol
+	metadata.InboundOptions = d.listenOptions.InboundOptions
+	metadata.Source = M.ParseSocksaddr(request.RemoteAddr).Unwrap()
+	// the message ID of DoH queries is zero, see RFC 8484 section 4.1
+	messageID := message.Id
+	response := d.exchange(ctx, metadata, &message)
+	response.Id = messageID
+	rawResponse, err := response.Pack()
+	if err != nil {
+		rejectHTTP(writer, http.StatusInternalServerError)
+		d.logger.ErrorContext(ctx, E.Cause(err, "pack response"))
+		return
+	}
+	writer.Header().Set("Content-Type", dns.MimeType)
+	writer.Write(rawResponse)
+}
+
+// exchange answers the query through the DNS router, replying with an
+// error code if it fails, so that clients do not wait for timeouts.
+func (d *DNS) exchange(ctx context.Context, metadata adapter.InboundContext, message *mDNS.Msg) *mDNS.Msg {
+	response, err := d.dnsRouter.Exchange(adapter.WithContext(ctx, &metadata), message)
+	if err == nil {
+		return response
+	}
+	rcode := mDNS.RcodeServerFailure
+	var rcodeError dns.RCodeError
+	if errors.As(err, &rcodeError) {
+		rcode = int(rcodeError)
+	}
+	response = new(mDNS.Msg)
+	response.SetRcode(message, rcode)
+	return response
+}
@@ -0,0 +1,46 @@
+//go:build with_quic
+
+package inbound
+
+import (
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-quic"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func (d *DNS) configureHTTP3Listener() error {
+	err := qtls.ConfigureHTTP3(d.tlsConfig)
+	if err != nil {
+		return err
+	}
+
+	udpConn, err := d.ListenUDP()
+	if err != nil {
+		return err
+	}
+
+	quicListener, err := qtls.ListenEarly(udpConn, d.tlsConfig, &quic.Config{
+		Allow0RTT: true,
+	})
+	if err != nil {
+		udpConn.Close()
+		return err
+	}
+
+	h3Server := &http3.Server{
+		Port:    int(d.listenOptions.ListenPort),
+		Handler: d,
+	}
+
+	go func() {
+		sErr := h3Server.ServeListener(quicListener)
+		udpConn.Close()
+		if sErr != nil && !E.IsClosedOrCanceled(sErr) {
+			d.logger.Error("http3 server serve error: ", sErr)
+		}
+	}()
+
+	d.h3Server = h3Server
+	return nil
+}
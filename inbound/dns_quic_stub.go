@@ -0,0 +1,11 @@
+//go:build !with_quic
+
+package inbound
+
+import (
+	C "github.com/sagernet/sing-box/constant"
+)
+
+func (d *DNS) configureHTTP3Listener() error {
+	return C.ErrQUICNotIncluded
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/porthop"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -76,6 +77,10 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			return nil, E.New("unknown masquerade URL scheme: ", masqueradeURL.Scheme)
 		}
 	}
+	hopPorts, err := porthop.ParsePorts(options.HopPorts)
+	if err != nil {
+		return nil, E.Cause(err, "parse hop_ports")
+	}
 	inbound := &Hysteria2{
 		myInboundAdapter: myInboundAdapter{
 			protocol:      C.TypeHysteria2,
@@ -85,6 +90,7 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			logger:        logger,
 			tag:           tag,
 			listenOptions: options.ListenOptions,
+			udpHopPorts:   hopPorts,
 		},
 		tlsConfig: tlsConfig,
 	}
@@ -14,7 +14,6 @@ import (
 	"github.com/sagernet/sing/common/auth"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/protocol/http"
-	"github.com/sagernet/sing/protocol/socks"
 	"github.com/sagernet/sing/protocol/socks/socks4"
 	"github.com/sagernet/sing/protocol/socks/socks5"
 )
@@ -27,6 +26,8 @@ var (
 type Mixed struct {
 	myInboundAdapter
 	authenticator *auth.Authenticator
+	udpNetwork    option.NetworkList
+	udpServer     *socksUDPServer
 }
 
 func NewMixed(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.HTTPMixedInboundOptions) *Mixed {
@@ -42,11 +43,22 @@ func NewMixed(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			setSystemProxy: options.SetSystemProxy,
 		},
 		auth.NewAuthenticator(options.Users),
+		options.Network,
+		nil,
 	}
 	inbound.connHandler = inbound
 	return inbound
 }
 
+func (h *Mixed) Start() error {
+	udpServer, err := h.listenSocksUDP(h.udpNetwork)
+	if err != nil {
+		return err
+	}
+	h.udpServer = udpServer
+	return h.myInboundAdapter.Start()
+}
+
 func (h *Mixed) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	reader := std_bufio.NewReader(conn)
 	headerBytes, err := reader.Peek(1)
@@ -55,7 +67,7 @@ func (h *Mixed) NewConnection(ctx context.Context, conn net.Conn, metadata adapt
 	}
 	switch headerBytes[0] {
 	case socks4.Version, socks5.Version:
-		return socks.HandleConnection0(ctx, conn, reader, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+		return handleSocksConnection(ctx, conn, reader, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata), h.udpServer)
 	default:
 		return http.HandleConnection(ctx, conn, reader, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
 	}
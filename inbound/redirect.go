@@ -17,6 +17,7 @@ import (
 
 type Redirect struct {
 	myInboundAdapter
+	autoRedirect *redir.AutoRedirect
 
 	// Script
 	scripts []*script.Script
@@ -35,6 +36,15 @@ func NewRedirect(ctx context.Context, router adapter.Router, logger log.ContextL
 		},
 	}
 	redirect.connHandler = redirect
+	if options.AutoRedirect {
+		var err error
+		redirect.autoRedirect, err = newAutoRedirect(router, options.ListenOptions, redir.AutoRedirectOptions{
+			TCP: true,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	// Script
 	if len(options.Scripts) > 0 {
@@ -79,6 +89,12 @@ func (r *Redirect) Start() error {
 			}
 		}
 	}()
+	if r.autoRedirect != nil {
+		err = r.autoRedirect.Start()
+		if err != nil {
+			return E.Cause(err, "start auto-redirect")
+		}
+	}
 	if len(r.scripts) > 0 {
 		// Script
 		for i, s := range r.scripts {
@@ -98,7 +114,11 @@ func (r *Redirect) Close() error {
 			s.CallWithEvent(context.Background(), script.EventBeforeClose)
 		}
 	}
-	err := r.myInboundAdapter.Close()
+	var err error
+	if r.autoRedirect != nil {
+		err = r.autoRedirect.Close()
+	}
+	err = E.Errors(err, r.myInboundAdapter.Close())
 	if len(r.scripts) > 0 {
 		// Script
 		for _, s := range r.scripts {
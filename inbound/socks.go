@@ -1,6 +1,7 @@
 package inbound
 
 import (
+	std_bufio "bufio"
 	"context"
 	"net"
 	"os"
@@ -12,7 +13,6 @@ import (
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common/auth"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/protocol/socks"
 )
 
 var (
@@ -23,6 +23,8 @@ var (
 type Socks struct {
 	myInboundAdapter
 	authenticator *auth.Authenticator
+	udpNetwork    option.NetworkList
+	udpServer     *socksUDPServer
 }
 
 func NewSocks(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SocksInboundOptions) *Socks {
@@ -37,13 +39,24 @@ func NewSocks(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			listenOptions: options.ListenOptions,
 		},
 		auth.NewAuthenticator(options.Users),
+		options.Network,
+		nil,
 	}
 	inbound.connHandler = inbound
 	return inbound
 }
 
+func (h *Socks) Start() error {
+	udpServer, err := h.listenSocksUDP(h.udpNetwork)
+	if err != nil {
+		return err
+	}
+	h.udpServer = udpServer
+	return h.myInboundAdapter.Start()
+}
+
 func (h *Socks) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
-	return socks.HandleConnection(ctx, conn, h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata))
+	return handleSocksConnection(ctx, conn, std_bufio.NewReader(conn), h.authenticator, h.upstreamUserHandler(metadata), adapter.UpstreamMetadata(metadata), h.udpServer)
 }
 
 func (h *Socks) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
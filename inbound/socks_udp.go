@@ -0,0 +1,366 @@
+package inbound
+
+import (
+	std_bufio "bufio"
+	"context"
+	"io"
+	"net"
+	"net/netip"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+	"github.com/sagernet/sing/protocol/socks/socks5"
+)
+
+const socksUDPHeaderLength = 3
+
+// handleSocksConnection serves a socks connection, relaying UDP ASSOCIATE
+// requests through udpServer instead of a new socket for each association.
+func handleSocksConnection(ctx context.Context, conn net.Conn, reader *std_bufio.Reader, authenticator *auth.Authenticator, handler socks.Handler, metadata M.Metadata, udpServer *socksUDPServer) error {
+	version, err := reader.Peek(1)
+	if err != nil {
+		return err
+	}
+	if version[0] != socks5.Version || udpServer == nil {
+		return socks.HandleConnection0(ctx, conn, reader, authenticator, handler, metadata)
+	}
+	common.Must1(reader.Discard(1))
+	authRequest, err := socks5.ReadAuthRequest0(reader)
+	if err != nil {
+		return err
+	}
+	authMethod := socks5.AuthTypeNotRequired
+	if authenticator != nil {
+		if !common.Contains(authRequest.Methods, socks5.AuthTypeUsernamePassword) {
+			err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+				Method: socks5.AuthTypeNoAcceptedMethods,
+			})
+			if err != nil {
+				return err
+			}
+			return E.New("socks5: no accepted auth methods")
+		}
+		authMethod = socks5.AuthTypeUsernamePassword
+	}
+	err = socks5.WriteAuthResponse(conn, socks5.AuthResponse{
+		Method: authMethod,
+	})
+	if err != nil {
+		return err
+	}
+	if authMethod == socks5.AuthTypeUsernamePassword {
+		usernamePasswordAuthRequest, err := socks5.ReadUsernamePasswordAuthRequest(reader)
+		if err != nil {
+			return err
+		}
+		ctx = auth.ContextWithUser(ctx, usernamePasswordAuthRequest.Username)
+		response := socks5.UsernamePasswordAuthResponse{
+			Status: socks5.UsernamePasswordStatusSuccess,
+		}
+		if !authenticator.Verify(usernamePasswordAuthRequest.Username, usernamePasswordAuthRequest.Password) {
+			response.Status = socks5.UsernamePasswordStatusFailure
+		}
+		err = socks5.WriteUsernamePasswordAuthResponse(conn, response)
+		if err != nil {
+			return err
+		}
+		if response.Status != socks5.UsernamePasswordStatusSuccess {
+			return E.New("socks5: authentication failed, username=", usernamePasswordAuthRequest.Username)
+		}
+	}
+	request, err := socks5.ReadRequest(reader)
+	if err != nil {
+		return err
+	}
+	metadata.Protocol = "socks5"
+	metadata.Destination = request.Destination
+	switch request.Command {
+	case socks5.CommandConnect:
+		err = socks5.WriteResponse(conn, socks5.Response{
+			ReplyCode: socks5.ReplyCodeSuccess,
+			Bind:      M.SocksaddrFromNet(conn.LocalAddr()),
+		})
+		if err != nil {
+			return err
+		}
+		return handler.NewConnection(ctx, conn, metadata)
+	case socks5.CommandUDPAssociate:
+		association := udpServer.newAssociation(M.AddrFromNet(conn.RemoteAddr()).Unmap(), request.Destination.Port)
+		err = socks5.WriteResponse(conn, socks5.Response{
+			ReplyCode: socks5.ReplyCodeSuccess,
+			Bind:      M.SocksaddrFrom(M.AddrFromNet(conn.LocalAddr()).Unmap(), udpServer.port),
+		})
+		if err != nil {
+			association.Close()
+			return err
+		}
+		var innerError error
+		done := make(chan struct{})
+		go func() {
+			innerError = handler.NewPacketConnection(ctx, association, metadata)
+			conn.Close()
+			close(done)
+		}()
+		_, err = io.Copy(io.Discard, conn)
+		if E.IsClosedOrCanceled(err) {
+			err = nil
+		}
+		association.Close()
+		<-done
+		return E.Errors(innerError, err)
+	default:
+		err = socks5.WriteResponse(conn, socks5.Response{
+			ReplyCode: socks5.ReplyCodeUnsupported,
+		})
+		if err != nil {
+			return err
+		}
+		return E.New("socks5: unsupported command ", request.Command)
+	}
+}
+
+// listenSocksUDP listens for UDP on the listen port before connections are
+// accepted, so that UDP ASSOCIATE requests are relayed through it. Unless
+// network is set, a socket is opened for each association instead if the
+// port is not available.
+func (a *myInboundAdapter) listenSocksUDP(network option.NetworkList) (*socksUDPServer, error) {
+	if !common.Contains(network.Build(), N.NetworkUDP) {
+		return nil, nil
+	}
+	packetConn, err := a.ListenUDP()
+	if err != nil {
+		if network != "" {
+			return nil, err
+		}
+		a.logger.Warn(E.Cause(err, "listen udp, fallback to a socket for each association"))
+		return nil, nil
+	}
+	udpServer := newSocksUDPServer(packetConn)
+	go udpServer.loopIn()
+	return udpServer, nil
+}
+
+// socksUDPServer relays the datagrams of all UDP associations of an inbound
+// through one socket. An association is bound to the first client address
+// sending from the IP of its TCP connection, and packets from any remote
+// address are written back to the client (full cone).
+type socksUDPServer struct {
+	conn         net.PacketConn
+	port         uint16
+	access       sync.Mutex
+	pending      []*socksAssociation
+	associations map[netip.AddrPort]*socksAssociation
+}
+
+type socksPacket struct {
+	data        *buf.Buffer
+	destination M.Socksaddr
+}
+
+func newSocksUDPServer(conn net.PacketConn) *socksUDPServer {
+	return &socksUDPServer{
+		conn:         conn,
+		port:         M.SocksaddrFromNet(conn.LocalAddr()).Port,
+		associations: make(map[netip.AddrPort]*socksAssociation),
+	}
+}
+
+func (s *socksUDPServer) newAssociation(clientAddr netip.Addr, clientPort uint16) *socksAssociation {
+	ctx, cancel := context.WithCancel(context.Background())
+	association := &socksAssociation{
+		ctx:        ctx,
+		cancel:     cancel,
+		server:     s,
+		clientAddr: clientAddr,
+		clientPort: clientPort,
+		data:       make(chan socksPacket, 64),
+	}
+	s.access.Lock()
+	s.pending = append(s.pending, association)
+	s.access.Unlock()
+	return association
+}
+
+func (s *socksUDPServer) loopIn() {
+	for {
+		buffer := buf.NewPacket()
+		n, addr, err := s.conn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			buffer.Release()
+			return
+		}
+		buffer.Truncate(n)
+		s.handlePacket(buffer, M.AddrPortFromNet(addr))
+	}
+}
+
+func (s *socksUDPServer) handlePacket(buffer *buf.Buffer, source netip.AddrPort) {
+	destination, err := readSocksUDPHeader(buffer)
+	if err != nil {
+		buffer.Release()
+		return
+	}
+	source = netip.AddrPortFrom(source.Addr().Unmap(), source.Port())
+	association := s.lookup(source)
+	if association == nil {
+		buffer.Release()
+		return
+	}
+	select {
+	case association.data <- socksPacket{buffer, destination}:
+	default:
+		buffer.Release()
+	}
+}
+
+// lookup returns the association bound to source, or binds a pending
+// association of the same IP, preferring the one announcing the same port.
+func (s *socksUDPServer) lookup(source netip.AddrPort) *socksAssociation {
+	s.access.Lock()
+	defer s.access.Unlock()
+	association := s.associations[source]
+	if association != nil {
+		return association
+	}
+	index := -1
+	for i, pending := range s.pending {
+		if pending.clientAddr != source.Addr() {
+			continue
+		}
+		if pending.clientPort == source.Port() {
+			index = i
+			break
+		}
+		if index == -1 {
+			index = i
+		}
+	}
+	if index == -1 {
+		return nil
+	}
+	association = s.pending[index]
+	s.pending = append(s.pending[:index], s.pending[index+1:]...)
+	association.client = source
+	s.associations[source] = association
+	return association
+}
+
+func (s *socksUDPServer) remove(association *socksAssociation) {
+	s.access.Lock()
+	defer s.access.Unlock()
+	if association.client.IsValid() {
+		delete(s.associations, association.client)
+		return
+	}
+	for i, pending := range s.pending {
+		if pending == association {
+			s.pending = append(s.pending[:i], s.pending[i+1:]...)
+			return
+		}
+	}
+}
+
+// readSocksUDPHeader strips the RSV, FRAG and DST fields of a UDP request.
+// Fragmented datagrams are not supported.
+func readSocksUDPHeader(buffer *buf.Buffer) (M.Socksaddr, error) {
+	if buffer.Len() < socksUDPHeaderLength {
+		return M.Socksaddr{}, io.ErrUnexpectedEOF
+	}
+	if buffer.Byte(2) != 0 {
+		return M.Socksaddr{}, E.New("socks5: fragmented packet")
+	}
+	buffer.Advance(socksUDPHeaderLength)
+	return M.SocksaddrSerializer.ReadAddrPort(buffer)
+}
+
+var _ N.PacketConn = (*socksAssociation)(nil)
+
+type socksAssociation struct {
+	ctx        context.Context
+	cancel     context.CancelFunc
+	server     *socksUDPServer
+	clientAddr netip.Addr
+	clientPort uint16
+	client     netip.AddrPort
+	data       chan socksPacket
+	closeOnce  sync.Once
+}
+
+func (c *socksAssociation) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	select {
+	case packet := <-c.data:
+		_, err = buffer.ReadOnceFrom(packet.data)
+		packet.data.Release()
+		return packet.destination, err
+	case <-c.ctx.Done():
+		return M.Socksaddr{}, io.ErrClosedPipe
+	}
+}
+
+func (c *socksAssociation) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.server.access.Lock()
+	client := c.client
+	c.server.access.Unlock()
+	if !client.IsValid() {
+		buffer.Release()
+		return nil
+	}
+	headerLength := socksUDPHeaderLength + M.SocksaddrSerializer.AddrPortLen(destination)
+	if buffer.Start() < headerLength {
+		newBuffer := buf.NewSize(headerLength + buffer.Len())
+		newBuffer.Resize(headerLength, 0)
+		common.Must1(newBuffer.Write(buffer.Bytes()))
+		buffer.Release()
+		buffer = newBuffer
+	}
+	defer buffer.Release()
+	header := buf.With(buffer.ExtendHeader(headerLength))
+	common.Must(header.WriteZeroN(socksUDPHeaderLength))
+	err := M.SocksaddrSerializer.WriteAddrPort(header, destination)
+	if err != nil {
+		return err
+	}
+	_, err = c.server.conn.WriteTo(buffer.Bytes(), net.UDPAddrFromAddrPort(client))
+	return err
+}
+
+func (c *socksAssociation) FrontHeadroom() int {
+	return socksUDPHeaderLength + M.MaxSocksaddrLength
+}
+
+func (c *socksAssociation) Close() error {
+	c.closeOnce.Do(func() {
+		c.cancel()
+		c.server.remove(c)
+	})
+	return nil
+}
+
+func (c *socksAssociation) LocalAddr() net.Addr {
+	return c.server.conn.LocalAddr()
+}
+
+func (c *socksAssociation) SetDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *socksAssociation) SetReadDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *socksAssociation) SetWriteDeadline(t time.Time) error {
+	return os.ErrInvalid
+}
+
+func (c *socksAssociation) NeedAdditionalReadDeadline() bool {
+	return true
+}
@@ -0,0 +1,176 @@
+package inbound
+
+import (
+	std_bufio "bufio"
+	"context"
+	"net"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing/common/auth"
+	"github.com/sagernet/sing/common/buf"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/protocol/socks"
+	"github.com/sagernet/sing/protocol/socks/socks5"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testSocksHandler struct {
+	connections chan M.Metadata
+	closed      chan struct{}
+}
+
+func (h *testSocksHandler) NewConnection(ctx context.Context, conn net.Conn, metadata M.Metadata) error {
+	h.connections <- metadata
+	return conn.Close()
+}
+
+// NewPacketConnection echoes packets, and replies from another address too.
+func (h *testSocksHandler) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata M.Metadata) error {
+	defer close(h.closed)
+	for {
+		buffer := buf.NewPacket()
+		destination, err := conn.ReadPacket(buffer)
+		if err != nil {
+			buffer.Release()
+			return nil
+		}
+		reply := buf.As(append([]byte(nil), buffer.Bytes()...))
+		err = conn.WritePacket(buffer, destination)
+		if err != nil {
+			return err
+		}
+		err = conn.WritePacket(reply, M.ParseSocksaddrHostPort("198.51.100.1", 53))
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func startTestSocksServer(t *testing.T, authenticator *auth.Authenticator) (*testSocksHandler, *socksUDPServer, M.Socksaddr, chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		listener.Close()
+		packetConn.Close()
+	})
+	handler := &testSocksHandler{
+		connections: make(chan M.Metadata, 1),
+		closed:      make(chan struct{}),
+	}
+	udpServer := newSocksUDPServer(packetConn)
+	go udpServer.loopIn()
+	errors := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		errors <- handleSocksConnection(context.Background(), conn, std_bufio.NewReader(conn), authenticator, handler, M.Metadata{}, udpServer)
+	}()
+	return handler, udpServer, M.SocksaddrFromNet(listener.Addr()), errors
+}
+
+func TestSocksAuthentication(t *testing.T) {
+	t.Parallel()
+	authenticator := auth.NewAuthenticator([]auth.User{{Username: "user", Password: "pass"}})
+	destination := M.ParseSocksaddrHostPort("example.org", 443)
+	for _, testCase := range []struct {
+		name     string
+		username string
+		password string
+		success  bool
+	}{
+		{"no method", "", "", false},
+		{"wrong password", "user", "wrong", false},
+		{"success", "user", "pass", true},
+	} {
+		handler, _, serverAddr, errors := startTestSocksServer(t, authenticator)
+		conn, err := net.Dial("tcp", serverAddr.String())
+		require.NoError(t, err, testCase.name)
+		response, err := socks.ClientHandshake5(conn, socks5.CommandConnect, destination, testCase.username, testCase.password)
+		if !testCase.success {
+			require.Error(t, err, testCase.name)
+			require.Error(t, <-errors, testCase.name)
+			conn.Close()
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, serverAddr, response.Bind, testCase.name)
+		metadata := <-handler.connections
+		require.Equal(t, "socks5", metadata.Protocol)
+		require.Equal(t, destination, metadata.Destination)
+		require.NoError(t, <-errors)
+		conn.Close()
+	}
+}
+
+func TestSocksUDPAssociate(t *testing.T) {
+	t.Parallel()
+	handler, udpServer, serverAddr, errors := startTestSocksServer(t, nil)
+	conn, err := net.Dial("tcp", serverAddr.String())
+	require.NoError(t, err)
+	defer conn.Close()
+	response, err := socks.ClientHandshake5(conn, socks5.CommandUDPAssociate, M.SocksaddrFrom(netip.IPv4Unspecified(), 0), "", "")
+	require.NoError(t, err)
+	require.Equal(t, M.SocksaddrFromNet(udpServer.conn.LocalAddr()), response.Bind)
+
+	packetConn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer packetConn.Close()
+	destination := M.ParseSocksaddrHostPort("203.0.113.1", 443)
+	request := buf.NewPacket()
+	defer request.Release()
+	request.Write([]byte{0, 0, 0})
+	require.NoError(t, M.SocksaddrSerializer.WriteAddrPort(request, destination))
+	request.WriteString("hello")
+	_, err = packetConn.WriteTo(request.Bytes(), response.Bind.UDPAddr())
+	require.NoError(t, err)
+	require.NoError(t, packetConn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	for _, expected := range []M.Socksaddr{destination, M.ParseSocksaddrHostPort("198.51.100.1", 53)} {
+		buffer := buf.NewPacket()
+		n, _, err := packetConn.ReadFrom(buffer.FreeBytes())
+		require.NoError(t, err)
+		buffer.Truncate(n)
+		source, err := readSocksUDPHeader(buffer)
+		require.NoError(t, err)
+		require.Equal(t, expected, source)
+		require.Equal(t, []byte("hello"), buffer.Bytes())
+		buffer.Release()
+	}
+
+	conn.Close()
+	select {
+	case <-handler.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("association not closed with the TCP connection")
+	}
+	require.NoError(t, <-errors)
+	udpServer.access.Lock()
+	require.Empty(t, udpServer.associations)
+	require.Empty(t, udpServer.pending)
+	udpServer.access.Unlock()
+}
+
+func TestSocksUDPAssociationBinding(t *testing.T) {
+	t.Parallel()
+	server := &socksUDPServer{associations: make(map[netip.AddrPort]*socksAssociation)}
+	client := netip.MustParseAddr("10.0.0.1")
+	unannounced := server.newAssociation(client, 0)
+	announced := server.newAssociation(client, 5000)
+	require.Nil(t, server.lookup(netip.MustParseAddrPort("10.0.0.2:5000")))
+	require.Equal(t, announced, server.lookup(netip.AddrPortFrom(client, 5000)))
+	require.Equal(t, unannounced, server.lookup(netip.AddrPortFrom(client, 6000)))
+	require.Equal(t, unannounced, server.lookup(netip.AddrPortFrom(client, 6000)))
+	require.Nil(t, server.lookup(netip.AddrPortFrom(client, 7000)))
+	unannounced.Close()
+	require.Nil(t, server.lookup(netip.AddrPortFrom(client, 6000)))
+	require.Equal(t, announced, server.lookup(netip.AddrPortFrom(client, 5000)))
+	announced.Close()
+	require.Empty(t, server.associations)
+}
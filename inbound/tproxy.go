@@ -4,27 +4,35 @@ import (
 	"context"
 	"net"
 	"net/netip"
+	"sync"
 	"syscall"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/redir"
 	"github.com/sagernet/sing-box/common/script"
+	"github.com/sagernet/sing-box/common/udpnat"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	tun "github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/buf"
 	"github.com/sagernet/sing/common/control"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
-	"github.com/sagernet/sing/common/udpnat"
 )
 
+// tproxyMaxWriteBackConns limits transparent sockets kept for each UDP
+// session, packets from further peers are written with one-shot sockets.
+const tproxyMaxWriteBackConns = 16
+
 type TProxy struct {
 	myInboundAdapter
-	udpNat *udpnat.Service[netip.AddrPort]
+	udpNat       *udpnat.Service[netip.AddrPort]
+	outputMark   uint32
+	autoRedirect *redir.AutoRedirect
 
 	// Script
 	scripts []*script.Script
@@ -50,7 +58,30 @@ func NewTProxy(ctx context.Context, router adapter.Router, logger log.ContextLog
 	}
 	tproxy.connHandler = tproxy
 	tproxy.oobPacketHandler = tproxy
-	tproxy.udpNat = udpnat.New[netip.AddrPort](int64(udpTimeout.Seconds()), tproxy.upstreamContextHandler())
+	tproxy.udpNat = udpnat.New[netip.AddrPort](udpTimeout, tproxy.upstreamContextHandler())
+	tproxy.udpNat.SetLimit(options.UDPMaxSessions, options.UDPMaxSessionsPerSource)
+	tproxy.outputMark = router.AutoRedirectOutputMark()
+	if options.AutoRedirect {
+		inputMark := uint32(options.AutoRedirectInputMark)
+		if inputMark == 0 {
+			inputMark = tun.DefaultAutoRedirectInputMark
+		}
+		tableIndex := options.IPRoute2TableIndex
+		if tableIndex == 0 {
+			tableIndex = redir.DefaultTProxyTableIndex
+		}
+		var err error
+		tproxy.autoRedirect, err = newAutoRedirect(router, options.ListenOptions, redir.AutoRedirectOptions{
+			TProxy:     true,
+			TCP:        common.Contains(tproxy.network, N.NetworkTCP),
+			UDP:        common.Contains(tproxy.network, N.NetworkUDP),
+			InputMark:  inputMark,
+			TableIndex: tableIndex,
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
 	tproxy.packetUpstream = tproxy.udpNat
 
 	// Script
@@ -105,9 +136,13 @@ func (t *TProxy) Start() error {
 			}
 		}
 	}
-	if t.tcpListener != nil {
-		err = control.Conn(common.MustCast[syscall.Conn](t.tcpListener), func(fd uintptr) error {
-			return redir.TProxy(fd, M.SocksaddrFromNet(t.tcpListener.Addr()).Addr.Is6())
+	tcpListeners := t.tcpListeners
+	if len(tcpListeners) == 0 && t.tcpListener != nil {
+		tcpListeners = []net.Listener{t.tcpListener}
+	}
+	for _, tcpListener := range tcpListeners {
+		err = control.Conn(common.MustCast[syscall.Conn](tcpListener), func(fd uintptr) error {
+			return redir.TProxy(fd, M.SocksaddrFromNet(tcpListener.Addr()).Addr.Is6())
 		})
 		if err != nil {
 			return E.Cause(err, "configure tproxy TCP listener")
@@ -121,6 +156,12 @@ func (t *TProxy) Start() error {
 			return E.Cause(err, "configure tproxy UDP listener")
 		}
 	}
+	if t.autoRedirect != nil {
+		err = t.autoRedirect.Start()
+		if err != nil {
+			return E.Cause(err, "start auto-redirect")
+		}
+	}
 	return nil
 }
 
@@ -131,7 +172,11 @@ func (t *TProxy) Close() error {
 			s.CallWithEvent(context.Background(), script.EventBeforeClose)
 		}
 	}
-	err := t.myInboundAdapter.Close()
+	var err error
+	if t.autoRedirect != nil {
+		err = t.autoRedirect.Close()
+	}
+	err = E.Errors(err, t.myInboundAdapter.Close())
 	if len(t.scripts) > 0 {
 		// Script
 		for _, s := range t.scripts {
@@ -153,45 +198,62 @@ func (t *TProxy) NewPacket(ctx context.Context, conn N.PacketConn, buffer *buf.B
 		return E.Cause(err, "get tproxy destination")
 	}
 	metadata.Destination = M.SocksaddrFromNetIP(destination).Unwrap()
-	t.udpNat.NewContextPacket(ctx, metadata.Source.AddrPort(), buffer, adapter.UpstreamMetadata(metadata), func(natConn N.PacketConn) (context.Context, N.PacketWriter) {
-		return adapter.WithContext(log.ContextWithNewID(ctx), &metadata), &tproxyPacketWriter{ctx: ctx, source: natConn, destination: metadata.Destination}
-	})
+	if !t.udpNat.NewContextPacket(ctx, metadata.Source.AddrPort(), buffer, adapter.UpstreamMetadata(metadata), func(natConn N.PacketConn) (context.Context, N.PacketWriter) {
+		return adapter.WithContext(log.ContextWithNewID(ctx), &metadata), &tproxyPacketWriter{ctx: ctx, source: natConn, mark: t.outputMark, conns: make(map[M.Socksaddr]*net.UDPConn)}
+	}) {
+		t.logger.DebugContext(ctx, "drop packet from ", metadata.Source, ": too many UDP sessions")
+	}
 	return nil
 }
 
+// tproxyPacketWriter writes packets back to the source from the address of
+// each remote peer. Transparent sockets of the peers are kept with the NAT
+// session, so that all peers reach the source through the same mapping.
 type tproxyPacketWriter struct {
-	ctx         context.Context
-	source      N.PacketConn
-	destination M.Socksaddr
-	conn        *net.UDPConn
+	ctx    context.Context
+	source N.PacketConn
+	mark   uint32
+	access sync.Mutex
+	conns  map[M.Socksaddr]*net.UDPConn
 }
 
 func (w *tproxyPacketWriter) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
 	defer buffer.Release()
-	conn := w.conn
-	if w.destination == destination && conn != nil {
-		_, err := conn.WriteToUDPAddrPort(buffer.Bytes(), M.AddrPortFromNet(w.source.LocalAddr()))
+	w.access.Lock()
+	defer w.access.Unlock()
+	conn, loaded := w.conns[destination]
+	if !loaded {
+		var listener net.ListenConfig
+		listener.Control = control.Append(listener.Control, control.ReuseAddr())
+		listener.Control = control.Append(listener.Control, redir.TProxyWriteBack())
+		if w.mark > 0 {
+			listener.Control = control.Append(listener.Control, control.RoutingMark(w.mark))
+		}
+		packetConn, err := listener.ListenPacket(w.ctx, "udp", destination.String())
 		if err != nil {
-			w.conn = nil
+			return err
+		}
+		conn = packetConn.(*net.UDPConn)
+		if w.conns != nil && len(w.conns) < tproxyMaxWriteBackConns {
+			w.conns[destination] = conn
+		} else {
+			defer conn.Close()
 		}
-		return err
-	}
-	var listener net.ListenConfig
-	listener.Control = control.Append(listener.Control, control.ReuseAddr())
-	listener.Control = control.Append(listener.Control, redir.TProxyWriteBack())
-	packetConn, err := listener.ListenPacket(w.ctx, "udp", destination.String())
-	if err != nil {
-		return err
 	}
-	udpConn := packetConn.(*net.UDPConn)
-	if w.destination == destination {
-		w.conn = udpConn
-	} else {
-		defer udpConn.Close()
+	_, err := conn.WriteToUDPAddrPort(buffer.Bytes(), M.AddrPortFromNet(w.source.LocalAddr()))
+	if err != nil && loaded {
+		delete(w.conns, destination)
+		conn.Close()
 	}
-	return common.Error(udpConn.WriteToUDPAddrPort(buffer.Bytes(), M.AddrPortFromNet(w.source.LocalAddr())))
+	return err
 }
 
 func (w *tproxyPacketWriter) Close() error {
-	return common.Close(common.PtrOrNil(w.conn))
+	w.access.Lock()
+	defer w.access.Unlock()
+	for _, conn := range w.conns {
+		conn.Close()
+	}
+	w.conns = nil
+	return nil
 }
@@ -8,9 +8,12 @@ import (
 	"runtime"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/cgroup"
+	"github.com/sagernet/sing-box/common/nat64"
 	"github.com/sagernet/sing-box/common/script"
 	"github.com/sagernet/sing-box/common/taskmonitor"
 	C "github.com/sagernet/sing-box/constant"
@@ -19,6 +22,7 @@ import (
 	"github.com/sagernet/sing-box/option"
 	tun "github.com/sagernet/sing-tun"
 	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -28,7 +32,7 @@ import (
 	"go4.org/netipx"
 )
 
-var _ adapter.Inbound = (*Tun)(nil)
+var _ adapter.TunInbound = (*Tun)(nil)
 
 type Tun struct {
 	tag                         string
@@ -39,18 +43,25 @@ type Tun struct {
 	tunOptions                  tun.Options
 	endpointIndependentNat      bool
 	udpTimeout                  int64
+	access                      sync.Mutex
 	stack                       string
 	tunIf                       tun.Tun
 	tunStack                    tun.Stack
+	detachStack                 func()
+	started                     bool
+	closed                      bool
 	platformInterface           platform.Interface
 	platformOptions             option.TunPlatformOptions
 	autoRedirect                tun.AutoRedirect
+	cgroupBypass                *cgroup.Bypass
+	networkUpdateCallback       *list.Element[tun.NetworkUpdateCallback]
 	routeRuleSet                []adapter.RuleSet
 	routeRuleSetCallback        []*list.Element[adapter.RuleSetUpdateCallback]
 	routeExcludeRuleSet         []adapter.RuleSet
 	routeExcludeRuleSetCallback []*list.Element[adapter.RuleSetUpdateCallback]
 	routeAddressSet             []*netipx.IPSet
 	routeExcludeAddressSet      []*netipx.IPSet
+	nat64                       *nat64.Resolver
 
 	// Script
 	scripts []*script.Script
@@ -241,6 +252,31 @@ func NewTun(ctx context.Context, router adapter.Router, logger log.ContextLogger
 		}
 	}
 
+	if len(options.IncludeCgroup) > 0 || len(options.ExcludeCgroup) > 0 {
+		if !options.AutoRoute {
+			return nil, E.New("`auto_route` is required by `include_cgroup` and `exclude_cgroup`")
+		}
+		if len(options.IncludeCgroup) > 0 && len(options.ExcludeCgroup) > 0 {
+			return nil, E.New("`include_cgroup` and `exclude_cgroup` cannot be used together")
+		}
+		inbound.cgroupBypass, err = cgroup.NewBypass(cgroup.BypassOptions{
+			TunOptions: &inbound.tunOptions,
+			Include:    options.IncludeCgroup,
+			Exclude:    options.ExcludeCgroup,
+			Mark:       cgroup.DefaultMark,
+		})
+		if err != nil {
+			return nil, E.Cause(err, "initialize cgroup rules")
+		}
+	}
+
+	if options.NAT64Prefix != "" {
+		inbound.nat64, err = nat64.NewResolver(router, options.NAT64Prefix)
+		if err != nil {
+			return nil, E.Cause(err, "parse nat64_prefix")
+		}
+	}
+
 	// Script
 	if len(options.Scripts) > 0 {
 		inbound.scripts = make([]*script.Script, 0, len(options.Scripts))
@@ -264,10 +300,10 @@ func uidToRange(uidList option.Listable[uint32]) []ranges.Range[uint32] {
 
 func parseRange(uidRanges []ranges.Range[uint32], rangeList []string) ([]ranges.Range[uint32], error) {
 	for _, uidRange := range rangeList {
-		if !strings.Contains(uidRange, ":") {
-			return nil, E.New("missing ':' in range: ", uidRange)
+		subIndex := strings.IndexAny(uidRange, ":-")
+		if subIndex == -1 {
+			return nil, E.New("missing ':' or '-' in range: ", uidRange)
 		}
-		subIndex := strings.Index(uidRange, ":")
 		if subIndex == 0 {
 			return nil, E.New("missing range start: ", uidRange)
 		} else if subIndex == len(uidRange)-1 {
@@ -351,7 +387,25 @@ func (t *Tun) Start() error {
 		}
 	}
 	t.logger.Trace("creating stack")
+	t.access.Lock()
 	t.tunIf = tunInterface
+	err = t.startStack(t.stack)
+	t.started = err == nil
+	t.access.Unlock()
+	if err != nil {
+		return err
+	}
+	if t.cgroupBypass != nil {
+		err = t.cgroupBypass.Start()
+		if err != nil {
+			return E.Cause(err, "start cgroup rules")
+		}
+	}
+	t.logger.Info("started at ", t.tunOptions.Name)
+	return nil
+}
+
+func (t *Tun) startStack(stack string) error {
 	var (
 		forwarderBindInterface bool
 		includeAllNetworks     bool
@@ -360,10 +414,11 @@ func (t *Tun) Start() error {
 		forwarderBindInterface = true
 		includeAllNetworks = t.platformInterface.IncludeAllNetworks()
 	}
-	var tunStack tun.Stack
-	tunStack, err = tun.NewStack(t.stack, tun.StackOptions{
+	var stackInterface tun.Tun
+	stackInterface, t.detachStack = stackTun(t.tunIf, stack)
+	tunStack, err := tun.NewStack(stack, tun.StackOptions{
 		Context:                t.ctx,
-		Tun:                    tunInterface,
+		Tun:                    stackInterface,
 		TunOptions:             t.tunOptions,
 		EndpointIndependentNat: t.endpointIndependentNat,
 		UDPTimeout:             t.udpTimeout,
@@ -376,19 +431,142 @@ func (t *Tun) Start() error {
 	if err != nil {
 		return err
 	}
+	monitor := taskmonitor.New(t.logger, C.StartTimeout)
 	monitor.Start("initiating tun stack")
 	err = tunStack.Start()
 	monitor.Finish()
 	t.tunStack = tunStack
+	return err
+}
+
+func (t *Tun) Stack() string {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return t.stack
+}
+
+// SetStack replaces the TCP/IP stack at runtime. The interface is reopened,
+// since the system stack reads from it until it is closed, so existing
+// connections are interrupted.
+func (t *Tun) SetStack(stack string) error {
+	switch stack {
+	case "", "system", "gvisor", "mixed":
+	default:
+		return E.New("unknown stack: ", stack)
+	}
+	if t.platformInterface != nil {
+		return E.New("switching stack is not supported by the platform interface")
+	}
+	t.access.Lock()
+	defer t.access.Unlock()
+	if t.closed {
+		return E.New("tun inbound is closed")
+	}
+	if !t.started {
+		return E.New("tun interface is not started")
+	}
+	if stack == t.stack && t.tunIf != nil {
+		return nil
+	}
+	err := t.reopen(stack)
 	if err != nil {
 		return err
 	}
-	t.logger.Info("started at ", t.tunOptions.Name)
+	t.stack = stack
+	t.logger.Info("switched to ", stackName(stack), " stack")
+	return nil
+}
+
+// reopen closes the stack and the interface, and opens them again with
+// stack, which also sets up routes of the interface again. If that fails,
+// the interface is opened again with the current stack.
+func (t *Tun) reopen(stack string) error {
+	t.closeInterface()
+	err := t.openInterface(stack)
+	if err == nil {
+		return nil
+	}
+	restoreErr := t.openInterface(t.stack)
+	if restoreErr != nil {
+		return E.Errors(err, E.Cause(restoreErr, "restore ", stackName(t.stack), " stack"))
+	}
+	return err
+}
+
+func (t *Tun) openInterface(stack string) error {
+	tunInterface, err := tun.New(t.tunOptions)
+	if err != nil {
+		return E.Cause(err, "configure tun interface")
+	}
+	t.tunIf = tunInterface
+	err = t.startStack(stack)
+	if err != nil {
+		t.closeInterface()
+		return E.Cause(err, "start ", stackName(stack), " stack")
+	}
 	return nil
 }
 
+func (t *Tun) closeInterface() {
+	if t.tunIf == nil {
+		return
+	}
+	err := common.Close(t.tunStack)
+	if t.detachStack != nil {
+		t.detachStack()
+	}
+	err = E.Errors(err, t.tunIf.Close())
+	t.tunStack = nil
+	t.tunIf = nil
+	if err != nil {
+		// routes may have been removed already
+		t.logger.Debug(E.Cause(err, "close tun interface"))
+	}
+}
+
+// refreshRoutes reopens the interface if routes set up by auto_route are
+// removed when the network changes.
+func (t *Tun) refreshRoutes() {
+	t.access.Lock()
+	defer t.access.Unlock()
+	if t.closed {
+		return
+	}
+	// the interface is missing if reopening it failed before
+	if t.tunIf != nil {
+		err := checkTunRoutes(&t.tunOptions)
+		if err == nil {
+			return
+		}
+		t.logger.Warn("reconfigure tun interface: ", err)
+	}
+	err := t.reopen(t.stack)
+	if err != nil {
+		t.logger.Error(E.Cause(err, "reconfigure tun interface"))
+		return
+	}
+	if t.cgroupBypass != nil {
+		err = t.cgroupBypass.Start()
+		if err != nil {
+			t.logger.Error(E.Cause(err, "restart cgroup rules"))
+		}
+	}
+}
+
+func stackName(stack string) string {
+	if stack == "" {
+		return "default"
+	}
+	return stack
+}
+
 func (t *Tun) PostStart() error {
 	monitor := taskmonitor.New(t.logger, C.StartTimeout)
+	if C.IsLinux && t.platformInterface == nil && t.tunOptions.AutoRoute {
+		if networkMonitor := t.router.NetworkMonitor(); networkMonitor != nil {
+			t.networkUpdateCallback = networkMonitor.RegisterCallback(t.refreshRoutes)
+		}
+	}
 	if t.autoRedirect != nil {
 		t.routeAddressSet = common.FlatMap(t.routeRuleSet, adapter.RuleSet.ExtractIPSet)
 		for _, routeRuleSet := range t.routeRuleSet {
@@ -447,11 +625,24 @@ func (t *Tun) Close() error {
 			s.CallWithEvent(context.Background(), script.EventBeforeClose)
 		}
 	}
-	err := common.Close(
-		t.tunStack,
+	if t.networkUpdateCallback != nil {
+		t.router.NetworkMonitor().UnregisterCallback(t.networkUpdateCallback)
+	}
+	t.access.Lock()
+	err := common.Close(t.tunStack)
+	if t.detachStack != nil {
+		t.detachStack()
+	}
+	err = E.Errors(err, common.Close(
 		t.tunIf,
 		t.autoRedirect,
-	)
+		common.PtrOrNil(t.cgroupBypass),
+	))
+	// a network update being handled must not reopen the interface
+	t.closed = true
+	t.tunStack = nil
+	t.tunIf = nil
+	t.access.Unlock()
 	if len(t.scripts) > 0 {
 		// Script
 		for _, s := range t.scripts {
@@ -476,6 +667,9 @@ func (t *Tun) NewConnection(ctx context.Context, conn net.Conn, upstreamMetadata
 		t.logger.InfoContext(ctx, "inbound connection from ", metadata.Source)
 	}
 	t.logger.InfoContext(ctx, "inbound connection to ", metadata.Destination)
+	if prefix, loaded := t.nat64Prefix(ctx, metadata.Destination); loaded {
+		metadata.Destination.Addr, _ = nat64.Extract(prefix, metadata.Destination.Addr)
+	}
 	err := t.router.RouteConnection(ctx, conn, metadata)
 	if err != nil {
 		t.NewError(ctx, err)
@@ -493,6 +687,10 @@ func (t *Tun) NewPacketConnection(ctx context.Context, conn N.PacketConn, upstre
 	metadata.InboundOptions = t.inboundOptions
 	t.logger.InfoContext(ctx, "inbound packet connection from ", metadata.Source)
 	t.logger.InfoContext(ctx, "inbound packet connection to ", metadata.Destination)
+	if prefix, loaded := t.nat64Prefix(ctx, metadata.Destination); loaded {
+		metadata.Destination.Addr, _ = nat64.Extract(prefix, metadata.Destination.Addr)
+		conn = nat64.NewPacketConn(bufio.NewNetPacketConn(conn), prefix, false)
+	}
 	err := t.router.RoutePacketConnection(ctx, conn, metadata)
 	if err != nil {
 		t.NewError(ctx, err)
@@ -500,6 +698,20 @@ func (t *Tun) NewPacketConnection(ctx context.Context, conn N.PacketConn, upstre
 	return nil
 }
 
+// nat64Prefix returns the NAT64 prefix if destination is a synthesized
+// address, so that it can be routed as the IPv4 address it stands for.
+func (t *Tun) nat64Prefix(ctx context.Context, destination M.Socksaddr) (netip.Prefix, bool) {
+	if t.nat64 == nil || !destination.IsIPv6() {
+		return netip.Prefix{}, false
+	}
+	prefix, err := t.nat64.Prefix(ctx)
+	if err != nil {
+		t.logger.DebugContext(ctx, "discover NAT64 prefix: ", err)
+		return netip.Prefix{}, false
+	}
+	return prefix, prefix.Contains(destination.Addr)
+}
+
 func (t *Tun) NewError(ctx context.Context, err error) {
 	NewError(t.logger, ctx, err)
 }
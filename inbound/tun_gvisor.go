@@ -0,0 +1,43 @@
+//go:build with_gvisor
+
+package inbound
+
+import (
+	"github.com/sagernet/gvisor/pkg/tcpip/stack"
+	"github.com/sagernet/sing-tun"
+)
+
+// gVisorTun records the link endpoint created by the gVisor stack. Closing
+// the stack does not stop the endpoint from reading the interface, since the
+// nil dispatcher is not passed through the filter of sing-tun.
+type gVisorTun struct {
+	tun.GVisorTun
+	endpoint stack.LinkEndpoint
+}
+
+func (t *gVisorTun) NewEndpoint() (stack.LinkEndpoint, error) {
+	endpoint, err := t.GVisorTun.NewEndpoint()
+	if err != nil {
+		return nil, err
+	}
+	t.endpoint = endpoint
+	return endpoint, nil
+}
+
+func (t *gVisorTun) detach() {
+	if t.endpoint != nil {
+		t.endpoint.Attach(nil)
+	}
+}
+
+// stackTun returns the interface for the stack, and the function stopping
+// the reads of the stack after it is closed.
+func stackTun(tunInterface tun.Tun, stack string) (tun.Tun, func()) {
+	if stack == "gvisor" {
+		if gTun, isGTun := tunInterface.(tun.GVisorTun); isGTun {
+			wrapper := &gVisorTun{GVisorTun: gTun}
+			return wrapper, wrapper.detach
+		}
+	}
+	return tunInterface, func() {}
+}
@@ -0,0 +1,9 @@
+//go:build !with_gvisor
+
+package inbound
+
+import "github.com/sagernet/sing-tun"
+
+func stackTun(tunInterface tun.Tun, stack string) (tun.Tun, func()) {
+	return tunInterface, func() {}
+}
@@ -0,0 +1,64 @@
+package inbound
+
+import (
+	"net"
+
+	"github.com/sagernet/netlink"
+	"github.com/sagernet/sing-tun"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	"golang.org/x/sys/unix"
+)
+
+// checkTunRoutes returns an error if the address, rules or routes set up by
+// auto_route are missing, as network managers may flush them when the
+// network changes.
+func checkTunRoutes(options *tun.Options) error {
+	link, err := netlink.LinkByName(options.Name)
+	if err != nil {
+		return E.Cause(err, "find interface")
+	}
+	if link.Attrs().Flags&net.FlagUp == 0 {
+		return E.New("interface is down")
+	}
+	ruleList, err := netlink.RuleList(netlink.FAMILY_ALL)
+	if err != nil {
+		return err
+	}
+	for _, family := range []struct {
+		family    int
+		addresses int
+	}{
+		{unix.AF_INET, len(options.Inet4Address)},
+		{unix.AF_INET6, len(options.Inet6Address)},
+	} {
+		if family.addresses == 0 {
+			continue
+		}
+		addresses, err := netlink.AddrList(link, family.family)
+		if err != nil {
+			return err
+		}
+		if len(addresses) == 0 {
+			return E.New("missing address")
+		}
+		var hasRule bool
+		for _, rule := range ruleList {
+			if rule.Family == family.family && rule.Priority >= options.IPRoute2RuleIndex && rule.Priority <= options.IPRoute2RuleIndex+10 {
+				hasRule = true
+				break
+			}
+		}
+		if !hasRule {
+			return E.New("missing rules")
+		}
+		routes, err := netlink.RouteListFiltered(family.family, &netlink.Route{Table: options.IPRoute2TableIndex}, netlink.RT_FILTER_TABLE)
+		if err != nil {
+			return err
+		}
+		if len(routes) == 0 {
+			return E.New("missing routes")
+		}
+	}
+	return nil
+}
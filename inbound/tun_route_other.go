@@ -0,0 +1,9 @@
+//go:build !linux
+
+package inbound
+
+import "github.com/sagernet/sing-tun"
+
+func checkTunRoutes(options *tun.Options) error {
+	return nil
+}
@@ -3,6 +3,6 @@
 package include
 
 import (
+	_ "github.com/sagernet/sing-box/transport/dnsquic"
 	_ "github.com/sagernet/sing-box/transport/v2rayquic"
-	_ "github.com/sagernet/sing-dns/quic"
 )
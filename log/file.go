@@ -0,0 +1,61 @@
+package log
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/service/filemanager"
+)
+
+// RotatableFactory is implemented by factories that can reopen their log
+// file after moving the current one aside.
+type RotatableFactory interface {
+	Rotate() error
+}
+
+type fileWriter struct {
+	ctx    context.Context
+	path   string
+	access sync.Mutex
+	file   *os.File
+}
+
+func openFileWriter(ctx context.Context, path string) (*fileWriter, error) {
+	file, err := filemanager.OpenFile(ctx, path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileWriter{ctx: ctx, path: path, file: file}, nil
+}
+
+func (w *fileWriter) Write(p []byte) (n int, err error) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	return w.file.Write(p)
+}
+
+func (w *fileWriter) Rotate() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	rotatedPath := w.path + "." + time.Now().Format("20060102-150405")
+	err := os.Rename(w.path, rotatedPath)
+	if err != nil {
+		return E.Cause(err, "rename log file")
+	}
+	file, err := filemanager.OpenFile(w.ctx, w.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return E.Cause(err, "reopen log file")
+	}
+	w.file.Close()
+	w.file = file
+	return nil
+}
+
+func (w *fileWriter) Close() error {
+	w.access.Lock()
+	defer w.access.Unlock()
+	return w.file.Close()
+}
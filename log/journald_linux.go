@@ -0,0 +1,76 @@
+package log
+
+import (
+	"bytes"
+	"encoding/binary"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+const journaldSocketPath = "/run/systemd/journal/socket"
+
+var _ Sink = (*journaldSink)(nil)
+
+type journaldSink struct {
+	identifier string
+	access     sync.Mutex
+	conn       *net.UnixConn
+}
+
+func NewJournaldSink(options option.JournaldLogOptions) (Sink, error) {
+	sink := &journaldSink{
+		identifier: options.Identifier,
+	}
+	if sink.identifier == "" {
+		sink.identifier = "sing-box"
+	}
+	return sink, nil
+}
+
+func (s *journaldSink) Start() error {
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocketPath, Net: "unixgram"})
+	if err != nil {
+		return E.Cause(err, "connect to journald")
+	}
+	s.conn = conn
+	return nil
+}
+
+func (s *journaldSink) Close() error {
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+func (s *journaldSink) WriteMessage(level Level, message string) {
+	if s.conn == nil {
+		return
+	}
+	var buffer bytes.Buffer
+	appendJournaldField(&buffer, "PRIORITY", strconv.Itoa(int(syslogSeverity(level))))
+	appendJournaldField(&buffer, "SYSLOG_IDENTIFIER", s.identifier)
+	appendJournaldField(&buffer, "MESSAGE", message)
+	s.access.Lock()
+	s.conn.Write(buffer.Bytes())
+	s.access.Unlock()
+}
+
+func appendJournaldField(buffer *bytes.Buffer, key string, value string) {
+	buffer.WriteString(key)
+	if !strings.Contains(value, "\n") {
+		buffer.WriteByte('=')
+		buffer.WriteString(value)
+	} else {
+		// multi-line values use the length-prefixed binary form of the native protocol
+		buffer.WriteByte('\n')
+		binary.Write(buffer, binary.LittleEndian, uint64(len(value)))
+		buffer.WriteString(value)
+	}
+	buffer.WriteByte('\n')
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package log
+
+import (
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func NewJournaldSink(options option.JournaldLogOptions) (Sink, error) {
+	return nil, E.New("journald is only supported on Linux")
+}
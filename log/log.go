@@ -49,7 +49,7 @@ func New(options Options) (Factory, error) {
 		FullTimestamp:    logOptions.Timestamp,
 		TimestampFormat:  "-0700 2006-01-02 15:04:05",
 	}
-	factory := NewDefaultFactory(
+	factory := newDefaultFactory(
 		options.Context,
 		logFormatter,
 		logWriter,
@@ -57,6 +57,27 @@ func New(options Options) (Factory, error) {
 		options.PlatformWriter,
 		options.Observable,
 	)
+	if len(logOptions.ModuleLevels) > 0 {
+		moduleLevels, err := parseModuleLevels(logOptions.ModuleLevels)
+		if err != nil {
+			return nil, E.Cause(err, "parse module levels")
+		}
+		factory.moduleLevels = moduleLevels
+	}
+	if logOptions.Syslog != nil && logOptions.Syslog.Enabled {
+		sink, err := NewSyslogSink(*logOptions.Syslog)
+		if err != nil {
+			return nil, E.Cause(err, "create syslog sink")
+		}
+		factory.sinks = append(factory.sinks, sink)
+	}
+	if logOptions.Journald != nil && logOptions.Journald.Enabled {
+		sink, err := NewJournaldSink(*logOptions.Journald)
+		if err != nil {
+			return nil, E.Cause(err, "create journald sink")
+		}
+		factory.sinks = append(factory.sinks, sink)
+	}
 	if logOptions.Level != "" {
 		logLevel, err := ParseLevel(logOptions.Level)
 		if err != nil {
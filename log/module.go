@@ -0,0 +1,60 @@
+package log
+
+import (
+	"sort"
+	"strings"
+
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+type moduleLevel struct {
+	pattern  string
+	wildcard bool
+	level    Level
+}
+
+// parseModuleLevels sorts patterns by length so that the most specific one wins.
+func parseModuleLevels(levels map[string]string) ([]moduleLevel, error) {
+	moduleLevels := make([]moduleLevel, 0, len(levels))
+	for pattern, levelString := range levels {
+		level, err := ParseLevel(levelString)
+		if err != nil {
+			return nil, E.Cause(err, "parse level for module ", pattern)
+		}
+		var wildcard bool
+		if strings.HasSuffix(pattern, "*") {
+			pattern = strings.TrimSuffix(pattern, "*")
+			wildcard = true
+		}
+		moduleLevels = append(moduleLevels, moduleLevel{pattern, wildcard, level})
+	}
+	sort.Slice(moduleLevels, func(i, j int) bool {
+		if len(moduleLevels[i].pattern) != len(moduleLevels[j].pattern) {
+			return len(moduleLevels[i].pattern) > len(moduleLevels[j].pattern)
+		}
+		return !moduleLevels[i].wildcard && moduleLevels[j].wildcard
+	})
+	return moduleLevels, nil
+}
+
+func matchModuleLevel(moduleLevels []moduleLevel, tag string) (Level, bool) {
+	for _, module := range moduleLevels {
+		if module.wildcard {
+			if strings.HasPrefix(tag, module.pattern) {
+				return module.level, true
+			}
+			continue
+		}
+		if !strings.HasPrefix(tag, module.pattern) {
+			continue
+		}
+		if len(tag) == len(module.pattern) {
+			return module.level, true
+		}
+		switch tag[len(module.pattern)] {
+		case '[', '/':
+			return module.level, true
+		}
+	}
+	return 0, false
+}
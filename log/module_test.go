@@ -0,0 +1,33 @@
+package log
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestModuleLevel(t *testing.T) {
+	t.Parallel()
+	moduleLevels, err := parseModuleLevels(map[string]string{
+		"dns":                   "debug",
+		"inbound/tun":           "warn",
+		"outbound/*":            "info",
+		"outbound/vmess[proxy]": "trace",
+	})
+	require.NoError(t, err)
+	for tag, expected := range map[string]Level{
+		"dns":                   LevelDebug,
+		"dns/transport[local]":  LevelDebug,
+		"inbound/tun[tun-in]":   LevelWarn,
+		"outbound/direct[out]":  LevelInfo,
+		"outbound/vmess[proxy]": LevelTrace,
+	} {
+		level, loaded := matchModuleLevel(moduleLevels, tag)
+		require.True(t, loaded, tag)
+		require.Equal(t, expected, level, tag)
+	}
+	for _, tag := range []string{"router", "dnsx", "inbound/tunnel[in]"} {
+		_, loaded := matchModuleLevel(moduleLevels, tag)
+		require.False(t, loaded, tag)
+	}
+}
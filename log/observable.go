@@ -7,23 +7,29 @@ import (
 	"time"
 
 	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/observable"
-	"github.com/sagernet/sing/service/filemanager"
 )
 
-var _ Factory = (*defaultFactory)(nil)
+var (
+	_ Factory          = (*defaultFactory)(nil)
+	_ RotatableFactory = (*defaultFactory)(nil)
+)
 
 type defaultFactory struct {
 	ctx               context.Context
 	formatter         Formatter
 	platformFormatter Formatter
 	writer            io.Writer
-	file              *os.File
+	file              *fileWriter
 	filePath          string
 	platformWriter    PlatformWriter
+	sinkFormatter     Formatter
+	sinks             []Sink
 	needObservable    bool
 	level             Level
+	moduleLevels      []moduleLevel
 	subscriber        *observable.Subscriber[Entry]
 	observer          *observable.Observer[Entry]
 }
@@ -36,6 +42,17 @@ func NewDefaultFactory(
 	platformWriter PlatformWriter,
 	needObservable bool,
 ) ObservableFactory {
+	return newDefaultFactory(ctx, formatter, writer, filePath, platformWriter, needObservable)
+}
+
+func newDefaultFactory(
+	ctx context.Context,
+	formatter Formatter,
+	writer io.Writer,
+	filePath string,
+	platformWriter PlatformWriter,
+	needObservable bool,
+) *defaultFactory {
 	factory := &defaultFactory{
 		ctx:       ctx,
 		formatter: formatter,
@@ -43,6 +60,12 @@ func NewDefaultFactory(
 			BaseTime:         formatter.BaseTime,
 			DisableLineBreak: true,
 		},
+		sinkFormatter: Formatter{
+			BaseTime:         formatter.BaseTime,
+			DisableColors:    true,
+			DisableTimestamp: true,
+			DisableLineBreak: true,
+		},
 		writer:         writer,
 		filePath:       filePath,
 		platformWriter: platformWriter,
@@ -61,23 +84,39 @@ func NewDefaultFactory(
 
 func (f *defaultFactory) Start() error {
 	if f.filePath != "" {
-		logFile, err := filemanager.OpenFile(f.ctx, f.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+		logFile, err := openFileWriter(f.ctx, f.filePath)
 		if err != nil {
 			return err
 		}
 		f.writer = logFile
 		f.file = logFile
 	}
+	for _, sink := range f.sinks {
+		err := sink.Start()
+		if err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (f *defaultFactory) Close() error {
+	for _, sink := range f.sinks {
+		sink.Close()
+	}
 	return common.Close(
 		common.PtrOrNil(f.file),
 		f.subscriber,
 	)
 }
 
+func (f *defaultFactory) Rotate() error {
+	if f.file == nil {
+		return E.New("log output is not a file")
+	}
+	return f.file.Rotate()
+}
+
 func (f *defaultFactory) Level() Level {
 	return f.level
 }
@@ -91,7 +130,9 @@ func (f *defaultFactory) Logger() ContextLogger {
 }
 
 func (f *defaultFactory) NewLogger(tag string) ContextLogger {
-	return &observableLogger{f, tag}
+	logger := &observableLogger{defaultFactory: f, tag: tag}
+	logger.moduleLevel, logger.hasModuleLevel = matchModuleLevel(f.moduleLevels, tag)
+	return logger
 }
 
 func (f *defaultFactory) Subscribe() (subscription observable.Subscription[Entry], done <-chan struct{}, err error) {
@@ -106,12 +147,18 @@ var _ ContextLogger = (*observableLogger)(nil)
 
 type observableLogger struct {
 	*defaultFactory
-	tag string
+	tag            string
+	moduleLevel    Level
+	hasModuleLevel bool
 }
 
 func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	level = OverrideLevelFromContext(level, ctx)
-	if level > l.level {
+	maxLevel := l.level
+	if l.hasModuleLevel {
+		maxLevel = l.moduleLevel
+	}
+	if level > maxLevel && !IsTraceContext(ctx) {
 		return
 	}
 	nowTime := time.Now()
@@ -138,6 +185,12 @@ func (l *observableLogger) Log(ctx context.Context, level Level, args []any) {
 	if l.platformWriter != nil {
 		l.platformWriter.WriteMessage(level, l.platformFormatter.Format(ctx, level, l.tag, F.ToString(args...), nowTime))
 	}
+	if len(l.sinks) > 0 {
+		message := l.sinkFormatter.Format(ctx, level, l.tag, F.ToString(args...), nowTime)
+		for _, sink := range l.sinks {
+			sink.WriteMessage(level, message)
+		}
+	}
 }
 
 func (l *observableLogger) Trace(args ...any) {
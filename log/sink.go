@@ -0,0 +1,7 @@
+package log
+
+type Sink interface {
+	Start() error
+	Close() error
+	WriteMessage(level Level, message string)
+}
@@ -0,0 +1,272 @@
+package log
+
+import (
+	"crypto/tls"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+)
+
+var _ Sink = (*syslogSink)(nil)
+
+const syslogQueueSize = 1024
+
+type syslogSink struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	facility  uint8
+	hostname  string
+	tag       string
+	pid       string
+	access    sync.Mutex
+	conn      net.Conn
+	messages  chan string
+	done      chan struct{}
+}
+
+func NewSyslogSink(options option.SyslogLogOptions) (Sink, error) {
+	sink := &syslogSink{
+		network:  options.Network,
+		hostname: options.Hostname,
+		tag:      options.Tag,
+		pid:      strconv.Itoa(os.Getpid()),
+		messages: make(chan string, syslogQueueSize),
+		done:     make(chan struct{}),
+	}
+	var defaultPort uint16
+	switch sink.network {
+	case "", "udp":
+		sink.network = "udp"
+		defaultPort = 514
+	case "tcp":
+		defaultPort = 514
+	case "tls":
+		defaultPort = 6514
+	default:
+		return nil, E.New("unknown syslog network: ", options.Network)
+	}
+	if options.Address == "" {
+		return nil, E.New("missing syslog address")
+	}
+	serverAddress := M.ParseSocksaddr(options.Address)
+	if serverAddress.Port == 0 {
+		serverAddress.Port = defaultPort
+	}
+	sink.address = serverAddress.String()
+	if sink.network == "tls" {
+		serverName := options.ServerName
+		if serverName == "" {
+			serverName = serverAddress.AddrString()
+		}
+		sink.tlsConfig = &tls.Config{
+			ServerName:         serverName,
+			InsecureSkipVerify: options.Insecure,
+		}
+	}
+	if options.Facility != "" {
+		facility, err := ParseSyslogFacility(options.Facility)
+		if err != nil {
+			return nil, err
+		}
+		sink.facility = facility
+	} else {
+		sink.facility = syslogFacilityDaemon
+	}
+	if sink.hostname == "" {
+		sink.hostname, _ = os.Hostname()
+		if sink.hostname == "" {
+			sink.hostname = "-"
+		}
+	}
+	if sink.tag == "" {
+		sink.tag = "sing-box"
+	}
+	return sink, nil
+}
+
+func (s *syslogSink) Start() error {
+	conn, err := s.dial()
+	if err != nil {
+		return err
+	}
+	s.access.Lock()
+	s.conn = conn
+	s.access.Unlock()
+	go s.loopWrite()
+	return nil
+}
+
+func (s *syslogSink) dial() (net.Conn, error) {
+	dialer := net.Dialer{Timeout: C.TCPTimeout}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.tlsConfig != nil {
+		conn, err = tls.DialWithDialer(&dialer, "tcp", s.address, s.tlsConfig)
+	} else {
+		conn, err = dialer.Dial(s.network, s.address)
+	}
+	if err != nil {
+		return nil, E.Cause(err, "connect to syslog server")
+	}
+	return conn, nil
+}
+
+// loopWrite writes queued messages, so that a stalled server does not block
+// logging. After a write fails, the server is dialed again with exponential
+// backoff, and messages are dropped until it succeeds.
+func (s *syslogSink) loopWrite() {
+	var (
+		delay    = time.Second
+		nextDial time.Time
+	)
+	for {
+		var content string
+		select {
+		case <-s.done:
+			return
+		case content = <-s.messages:
+		}
+		s.access.Lock()
+		conn := s.conn
+		s.access.Unlock()
+		if conn == nil {
+			if time.Now().Before(nextDial) {
+				continue
+			}
+			var err error
+			conn, err = s.dial()
+			if err != nil {
+				nextDial = time.Now().Add(delay)
+				delay *= 2
+				if delay > time.Minute {
+					delay = time.Minute
+				}
+				continue
+			}
+			s.access.Lock()
+			select {
+			case <-s.done:
+				s.access.Unlock()
+				conn.Close()
+				return
+			default:
+			}
+			s.conn = conn
+			s.access.Unlock()
+			delay = time.Second
+		}
+		conn.SetWriteDeadline(time.Now().Add(C.TCPTimeout))
+		_, err := conn.Write([]byte(content))
+		if err != nil {
+			s.access.Lock()
+			if s.conn == conn {
+				s.conn = nil
+			}
+			s.access.Unlock()
+			conn.Close()
+		}
+	}
+}
+
+func (s *syslogSink) Close() error {
+	s.access.Lock()
+	defer s.access.Unlock()
+	select {
+	case <-s.done:
+	default:
+		close(s.done)
+	}
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+func (s *syslogSink) WriteMessage(level Level, message string) {
+	content := s.format(level, message, time.Now())
+	if s.network != "udp" {
+		// RFC 6587 octet-counting framing
+		content = strconv.Itoa(len(content)) + " " + content
+	}
+	select {
+	case s.messages <- content:
+	default:
+		// the queue is full while the server is stalled
+	}
+}
+
+func (s *syslogSink) format(level Level, message string, timestamp time.Time) string {
+	priority := int(s.facility)<<3 | int(syslogSeverity(level))
+	var builder strings.Builder
+	builder.WriteString("<")
+	builder.WriteString(strconv.Itoa(priority))
+	builder.WriteString(">1 ")
+	builder.WriteString(timestamp.Format("2006-01-02T15:04:05.000000Z07:00"))
+	builder.WriteString(" ")
+	builder.WriteString(s.hostname)
+	builder.WriteString(" ")
+	builder.WriteString(s.tag)
+	builder.WriteString(" ")
+	builder.WriteString(s.pid)
+	builder.WriteString(" - - ")
+	builder.WriteString(message)
+	return builder.String()
+}
+
+const (
+	syslogSeverityEmergency uint8 = iota
+	syslogSeverityAlert
+	syslogSeverityCritical
+	syslogSeverityError
+	syslogSeverityWarning
+	syslogSeverityNotice
+	syslogSeverityInfo
+	syslogSeverityDebug
+)
+
+func syslogSeverity(level Level) uint8 {
+	switch level {
+	case LevelPanic:
+		return syslogSeverityEmergency
+	case LevelFatal:
+		return syslogSeverityCritical
+	case LevelError:
+		return syslogSeverityError
+	case LevelWarn:
+		return syslogSeverityWarning
+	case LevelInfo:
+		return syslogSeverityInfo
+	default:
+		return syslogSeverityDebug
+	}
+}
+
+const syslogFacilityDaemon uint8 = 3
+
+var syslogFacilities = []string{
+	"kern", "user", "mail", "daemon", "auth", "syslog", "lpr", "news",
+	"uucp", "cron", "authpriv", "ftp", "ntp", "security", "console", "solaris-cron",
+	"local0", "local1", "local2", "local3", "local4", "local5", "local6", "local7",
+}
+
+func ParseSyslogFacility(facility string) (uint8, error) {
+	for index, name := range syslogFacilities {
+		if name == facility {
+			return uint8(index), nil
+		}
+	}
+	return 0, E.New("unknown syslog facility: ", facility)
+}
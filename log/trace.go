@@ -0,0 +1,26 @@
+package log
+
+import (
+	"context"
+)
+
+type traceKey struct{}
+
+// ContextWithTrace marks the context so that all messages logged with it are
+// written regardless of the configured level. Layers without a logger of their
+// own report trace events to logger through TraceEvent.
+func ContextWithTrace(ctx context.Context, logger ContextLogger) context.Context {
+	return context.WithValue(ctx, (*traceKey)(nil), logger)
+}
+
+func IsTraceContext(ctx context.Context) bool {
+	return ctx.Value((*traceKey)(nil)) != nil
+}
+
+// TraceEvent logs to the trace logger if ctx is traced.
+func TraceEvent(ctx context.Context, args ...any) {
+	logger, traced := ctx.Value((*traceKey)(nil)).(ContextLogger)
+	if traced {
+		logger.InfoContext(ctx, args...)
+	}
+}
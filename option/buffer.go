@@ -0,0 +1,8 @@
+package option
+
+type BufferOptions struct {
+	ReadBufferSize  MemoryBytes `json:"read_buffer_size,omitempty"`
+	WriteBufferSize MemoryBytes `json:"write_buffer_size,omitempty"`
+	RelayBufferSize MemoryBytes `json:"relay_buffer_size,omitempty"`
+	RelayBufferPool string      `json:"relay_buffer_pool,omitempty"`
+}
@@ -33,9 +33,28 @@ func (o *Options) UnmarshalJSON(content []byte) error {
 }
 
 type LogOptions struct {
-	Disabled     bool   `json:"disabled,omitempty"`
-	Level        string `json:"level,omitempty"`
-	Output       string `json:"output,omitempty"`
-	Timestamp    bool   `json:"timestamp,omitempty"`
-	DisableColor bool   `json:"-"`
+	Disabled     bool                `json:"disabled,omitempty"`
+	Level        string              `json:"level,omitempty"`
+	Output       string              `json:"output,omitempty"`
+	ModuleLevels map[string]string   `json:"module_levels,omitempty"`
+	Timestamp    bool                `json:"timestamp,omitempty"`
+	Syslog       *SyslogLogOptions   `json:"syslog,omitempty"`
+	Journald     *JournaldLogOptions `json:"journald,omitempty"`
+	DisableColor bool                `json:"-"`
+}
+
+type SyslogLogOptions struct {
+	Enabled    bool   `json:"enabled,omitempty"`
+	Network    string `json:"network,omitempty"`
+	Address    string `json:"address,omitempty"`
+	Facility   string `json:"facility,omitempty"`
+	Tag        string `json:"tag,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	ServerName string `json:"server_name,omitempty"`
+	Insecure   bool   `json:"insecure,omitempty"`
+}
+
+type JournaldLogOptions struct {
+	Enabled    bool   `json:"enabled,omitempty"`
+	Identifier string `json:"identifier,omitempty"`
 }
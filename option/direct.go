@@ -12,4 +12,5 @@ type DirectOutboundOptions struct {
 	OverrideAddress string `json:"override_address,omitempty"`
 	OverridePort    uint16 `json:"override_port,omitempty"`
 	ProxyProtocol   uint8  `json:"proxy_protocol,omitempty"`
+	NAT64Prefix     string `json:"nat64_prefix,omitempty"`
 }
@@ -7,19 +7,24 @@ type DNSOptions struct {
 	Rules          []DNSRule          `json:"rules,omitempty"`
 	Final          string             `json:"final,omitempty"`
 	ReverseMapping bool               `json:"reverse_mapping,omitempty"`
+	LANResolve     bool               `json:"lan_resolve,omitempty"`
 	FakeIP         *DNSFakeIPOptions  `json:"fakeip,omitempty"`
+	AdBlock        *DNSAdBlockOptions `json:"adblock,omitempty"`
 	DNSClientOptions
 }
 
 type DNSServerOptions struct {
-	Tag                  string         `json:"tag,omitempty"`
-	Address              string         `json:"address"`
-	AddressResolver      string         `json:"address_resolver,omitempty"`
-	AddressStrategy      DomainStrategy `json:"address_strategy,omitempty"`
-	AddressFallbackDelay Duration       `json:"address_fallback_delay,omitempty"`
-	Strategy             DomainStrategy `json:"strategy,omitempty"`
-	Detour               string         `json:"detour,omitempty"`
-	ClientSubnet         *AddrPrefix    `json:"client_subnet,omitempty"`
+	Tag                  string           `json:"tag,omitempty"`
+	Address              string           `json:"address"`
+	AddressResolver      string           `json:"address_resolver,omitempty"`
+	AddressStrategy      DomainStrategy   `json:"address_strategy,omitempty"`
+	AddressFallbackDelay Duration         `json:"address_fallback_delay,omitempty"`
+	Strategy             DomainStrategy   `json:"strategy,omitempty"`
+	Detour               string           `json:"detour,omitempty"`
+	ClientSubnet         *AddrPrefix      `json:"client_subnet,omitempty"`
+	Servers              Listable[string] `json:"servers,omitempty"`
+	GeoIP                Listable[string] `json:"geoip,omitempty"`
+	DNSSEC               string           `json:"dnssec,omitempty"`
 }
 
 type DNSClientOptions struct {
@@ -27,6 +32,7 @@ type DNSClientOptions struct {
 	DisableCache     bool           `json:"disable_cache,omitempty"`
 	DisableExpire    bool           `json:"disable_expire,omitempty"`
 	IndependentCache bool           `json:"independent_cache,omitempty"`
+	CacheCapacity    uint32         `json:"cache_capacity,omitempty"`
 	ClientSubnet     *AddrPrefix    `json:"client_subnet,omitempty"`
 }
 
@@ -34,4 +40,19 @@ type DNSFakeIPOptions struct {
 	Enabled    bool          `json:"enabled,omitempty"`
 	Inet4Range *netip.Prefix `json:"inet4_range,omitempty"`
 	Inet6Range *netip.Prefix `json:"inet6_range,omitempty"`
+	MaxEntries uint32        `json:"max_entries,omitempty"`
+}
+
+type DNSAdBlockOptions struct {
+	Enabled  bool             `json:"enabled,omitempty"`
+	RuleSet  Listable[string] `json:"rule_set,omitempty"`
+	Response string           `json:"response,omitempty"`
+}
+
+type DNSInboundOptions struct {
+	ListenOptions
+	Network  NetworkList `json:"network,omitempty"`
+	Protocol string      `json:"protocol,omitempty"`
+	Path     string      `json:"path,omitempty"`
+	InboundTLSOptionsContainer
 }
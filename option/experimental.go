@@ -1,19 +1,28 @@
 package option
 
+import "github.com/sagernet/sing/common/auth"
+
 type ExperimentalOptions struct {
-	CacheFile *CacheFileOptions `json:"cache_file,omitempty"`
-	ClashAPI  *ClashAPIOptions  `json:"clash_api,omitempty"`
-	V2RayAPI  *V2RayAPIOptions  `json:"v2ray_api,omitempty"`
-	Debug     *DebugOptions     `json:"debug,omitempty"`
+	CacheFile  *CacheFileOptions  `json:"cache_file,omitempty"`
+	ClashAPI   *ClashAPIOptions   `json:"clash_api,omitempty"`
+	V2RayAPI   *V2RayAPIOptions   `json:"v2ray_api,omitempty"`
+	NativeAPI  *NativeAPIOptions  `json:"native_api,omitempty"`
+	Debug      *DebugOptions      `json:"debug,omitempty"`
+	FlowLog    *FlowLogOptions    `json:"flow_log,omitempty"`
+	PACServer  *PACServerOptions  `json:"pac_server,omitempty"`
+	FileServer *FileServerOptions `json:"file_server,omitempty"`
+	Tasks      []TaskOptions      `json:"tasks,omitempty"`
 }
 
 type CacheFileOptions struct {
-	Enabled     bool     `json:"enabled,omitempty"`
-	Path        string   `json:"path,omitempty"`
-	CacheID     string   `json:"cache_id,omitempty"`
-	StoreFakeIP bool     `json:"store_fakeip,omitempty"`
-	StoreRDRC   bool     `json:"store_rdrc,omitempty"`
-	RDRCTimeout Duration `json:"rdrc_timeout,omitempty"`
+	Enabled         bool     `json:"enabled,omitempty"`
+	Path            string   `json:"path,omitempty"`
+	CacheID         string   `json:"cache_id,omitempty"`
+	StoreFakeIP     bool     `json:"store_fakeip,omitempty"`
+	StoreRDRC       bool     `json:"store_rdrc,omitempty"`
+	StoreTLSSession bool     `json:"store_tls_session,omitempty"`
+	StoreDNS        bool     `json:"store_dns,omitempty"`
+	RDRCTimeout     Duration `json:"rdrc_timeout,omitempty"`
 }
 
 type ClashAPIOptions struct {
@@ -48,3 +57,41 @@ type V2RayStatsServiceOptions struct {
 	Outbounds []string `json:"outbounds,omitempty"`
 	Users     []string `json:"users,omitempty"`
 }
+
+type NativeAPIOptions struct {
+	Listen string `json:"listen,omitempty"`
+	Secret string `json:"secret,omitempty"`
+}
+
+type FlowLogOptions struct {
+	Enabled          bool     `json:"enabled,omitempty"`
+	Path             string   `json:"path,omitempty"`
+	Collector        string   `json:"collector,omitempty"`
+	SnapshotInterval Duration `json:"snapshot_interval,omitempty"`
+	SnapshotPath     string   `json:"snapshot_path,omitempty"`
+}
+
+type PACServerOptions struct {
+	Enabled        bool   `json:"enabled,omitempty"`
+	Listen         string `json:"listen,omitempty"`
+	Path           string `json:"path,omitempty"`
+	Inbound        string `json:"inbound,omitempty"`
+	SetSystemProxy bool   `json:"set_system_proxy,omitempty"`
+}
+
+type FileServerOptions struct {
+	Enabled          bool               `json:"enabled,omitempty"`
+	Listen           string             `json:"listen,omitempty"`
+	Directory        string             `json:"directory,omitempty"`
+	Path             string             `json:"path,omitempty"`
+	DirectoryListing bool               `json:"directory_listing,omitempty"`
+	Users            []auth.User        `json:"users,omitempty"`
+	TLS              *InboundTLSOptions `json:"tls,omitempty"`
+}
+
+type TaskOptions struct {
+	Tag      string           `json:"tag,omitempty"`
+	Schedule string           `json:"schedule"`
+	Action   string           `json:"action"`
+	Targets  Listable[string] `json:"targets,omitempty"`
+}
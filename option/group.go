@@ -16,3 +16,23 @@ type URLTestOutboundOptions struct {
 	InterruptExistConnections bool                                   `json:"interrupt_exist_connections,omitempty"`
 	Providers                 Listable[ProviderGroupOutboundOptions] `json:"providers,omitempty"`
 }
+
+type FallbackOutboundOptions struct {
+	Outbounds                 []string                               `json:"outbounds"`
+	URL                       string                                 `json:"url,omitempty"`
+	Interval                  Duration                               `json:"interval,omitempty"`
+	Tolerance                 uint16                                 `json:"tolerance,omitempty"`
+	IdleTimeout               Duration                               `json:"idle_timeout,omitempty"`
+	InterruptExistConnections bool                                   `json:"interrupt_exist_connections,omitempty"`
+	Providers                 Listable[ProviderGroupOutboundOptions] `json:"providers,omitempty"`
+}
+
+type RelayOutboundOptions struct {
+	Outbounds []string `json:"outbounds"`
+}
+
+type LoadBalanceOutboundOptions struct {
+	Outbounds []string                               `json:"outbounds"`
+	Strategy  string                                 `json:"strategy,omitempty"`
+	Providers Listable[ProviderGroupOutboundOptions] `json:"providers,omitempty"`
+}
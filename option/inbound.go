@@ -27,6 +27,7 @@ type _Inbound struct {
 	VLESSOptions       VLESSInboundOptions       `json:"-"`
 	TUICOptions        TUICInboundOptions        `json:"-"`
 	Hysteria2Options   Hysteria2InboundOptions   `json:"-"`
+	DNSOptions         DNSInboundOptions         `json:"-"`
 }
 
 type Inbound _Inbound
@@ -66,6 +67,8 @@ func (h *Inbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.TUICOptions
 	case C.TypeHysteria2:
 		rawOptionsPtr = &h.Hysteria2Options
+	case C.TypeDNS:
+		rawOptionsPtr = &h.DNSOptions
 	case "":
 		return nil, E.New("missing inbound type")
 	default:
@@ -99,27 +102,48 @@ func (h *Inbound) UnmarshalJSON(bytes []byte) error {
 }
 
 type InboundOptions struct {
-	SniffEnabled              bool           `json:"sniff,omitempty"`
-	SniffOverrideDestination  bool           `json:"sniff_override_destination,omitempty"`
-	SniffTimeout              Duration       `json:"sniff_timeout,omitempty"`
-	DomainStrategy            DomainStrategy `json:"domain_strategy,omitempty"`
-	UDPDisableDomainUnmapping bool           `json:"udp_disable_domain_unmapping,omitempty"`
+	SniffEnabled              bool             `json:"sniff,omitempty"`
+	SniffOverrideDestination  bool             `json:"sniff_override_destination,omitempty"`
+	SniffTimeout              Duration         `json:"sniff_timeout,omitempty"`
+	SniffPorts                Listable[uint16] `json:"sniff_ports,omitempty"`
+	SniffBufferSize           int              `json:"sniff_buffer_size,omitempty"`
+	DomainStrategy            DomainStrategy   `json:"domain_strategy,omitempty"`
+	UDPDisableDomainUnmapping bool             `json:"udp_disable_domain_unmapping,omitempty"`
+	Buffer                    *BufferOptions   `json:"buffer,omitempty"`
 }
 
 type ListenOptions struct {
-	Listen                      *ListenAddress   `json:"listen,omitempty"`
-	ListenPort                  uint16           `json:"listen_port,omitempty"`
-	TCPFastOpen                 bool             `json:"tcp_fast_open,omitempty"`
-	TCPMultiPath                bool             `json:"tcp_multi_path,omitempty"`
-	UDPFragment                 *bool            `json:"udp_fragment,omitempty"`
-	UDPFragmentDefault          bool             `json:"-"`
-	UDPTimeout                  UDPTimeoutCompat `json:"udp_timeout,omitempty"`
-	ProxyProtocol               bool             `json:"proxy_protocol,omitempty"`
-	ProxyProtocolAcceptNoHeader bool             `json:"proxy_protocol_accept_no_header,omitempty"`
-	Detour                      string           `json:"detour,omitempty"`
+	Listen                      *ListenAddress       `json:"listen,omitempty"`
+	ListenPort                  uint16               `json:"listen_port,omitempty"`
+	TCPFastOpen                 bool                 `json:"tcp_fast_open,omitempty"`
+	TCPMultiPath                bool                 `json:"tcp_multi_path,omitempty"`
+	TCPMultiPathInterfaces      Listable[string]     `json:"tcp_multi_path_interfaces,omitempty"`
+	ReusePortListeners          int                  `json:"reuse_port_listeners,omitempty"`
+	ReusePortCPUAffinity        bool                 `json:"reuse_port_cpu_affinity,omitempty"`
+	UDPFragment                 *bool                `json:"udp_fragment,omitempty"`
+	UDPFragmentDefault          bool                 `json:"-"`
+	UDPTimeout                  UDPTimeoutCompat     `json:"udp_timeout,omitempty"`
+	ProxyProtocol               bool                 `json:"proxy_protocol,omitempty"`
+	ProxyProtocolAcceptNoHeader bool                 `json:"proxy_protocol_accept_no_header,omitempty"`
+	Detour                      string               `json:"detour,omitempty"`
+	Knock                       *InboundKnockOptions `json:"knock,omitempty"`
+	PortMapping                 *PortMappingOptions  `json:"port_mapping,omitempty"`
 	InboundOptions
 }
 
+type InboundKnockOptions struct {
+	ListenPort uint16   `json:"listen_port"`
+	Key        string   `json:"key"`
+	Timeout    Duration `json:"timeout,omitempty"`
+}
+
+type PortMappingOptions struct {
+	Protocol     string         `json:"protocol,omitempty"`
+	Gateway      *ListenAddress `json:"gateway,omitempty"`
+	ExternalPort uint16         `json:"external_port,omitempty"`
+	Lifetime     Duration       `json:"lifetime,omitempty"`
+}
+
 type UDPTimeoutCompat Duration
 
 func (c UDPTimeoutCompat) MarshalJSON() ([]byte, error) {
@@ -1,9 +1,11 @@
 package option
 
 type NTPOptions struct {
-	Enabled       bool     `json:"enabled,omitempty"`
-	Interval      Duration `json:"interval,omitempty"`
-	WriteToSystem bool     `json:"write_to_system,omitempty"`
+	Enabled       bool             `json:"enabled,omitempty"`
+	Interval      Duration         `json:"interval,omitempty"`
+	WriteToSystem bool             `json:"write_to_system,omitempty"`
+	Servers       Listable[string] `json:"servers,omitempty"`
+	RetryInterval Duration         `json:"retry_interval,omitempty"`
 	ServerOptions
 	DialerOptions
 }
@@ -27,6 +27,9 @@ type _Outbound struct {
 	Hysteria2Options    Hysteria2OutboundOptions    `json:"-"`
 	SelectorOptions     SelectorOutboundOptions     `json:"-"`
 	URLTestOptions      URLTestOutboundOptions      `json:"-"`
+	LoadBalanceOptions  LoadBalanceOutboundOptions  `json:"-"`
+	FallbackOptions     FallbackOutboundOptions     `json:"-"`
+	RelayOptions        RelayOutboundOptions        `json:"-"`
 	//
 	ProviderOptions ProviderOutboundOptions `json:"-"`
 }
@@ -72,6 +75,12 @@ func (h *Outbound) RawOptions() (any, error) {
 		rawOptionsPtr = &h.SelectorOptions
 	case C.TypeURLTest:
 		rawOptionsPtr = &h.URLTestOptions
+	case C.TypeLoadBalance:
+		rawOptionsPtr = &h.LoadBalanceOptions
+	case C.TypeFallback:
+		rawOptionsPtr = &h.FallbackOptions
+	case C.TypeRelay:
+		rawOptionsPtr = &h.RelayOptions
 	case C.TypeProvider:
 		rawOptionsPtr = &h.ProviderOptions
 	case "":
@@ -112,21 +121,30 @@ type DialerOptionsWrapper interface {
 }
 
 type DialerOptions struct {
-	Detour              string         `json:"detour,omitempty"`
-	BindInterface       string         `json:"bind_interface,omitempty"`
-	Inet4BindAddress    *ListenAddress `json:"inet4_bind_address,omitempty"`
-	Inet6BindAddress    *ListenAddress `json:"inet6_bind_address,omitempty"`
-	ProtectPath         string         `json:"protect_path,omitempty"`
-	RoutingMark         uint32         `json:"routing_mark,omitempty"`
-	ReuseAddr           bool           `json:"reuse_addr,omitempty"`
-	ConnectTimeout      Duration       `json:"connect_timeout,omitempty"`
-	TCPFastOpen         bool           `json:"tcp_fast_open,omitempty"`
-	TCPMultiPath        bool           `json:"tcp_multi_path,omitempty"`
-	UDPFragment         *bool          `json:"udp_fragment,omitempty"`
-	UDPFragmentDefault  bool           `json:"-"`
-	DomainStrategy      DomainStrategy `json:"domain_strategy,omitempty"`
-	FallbackDelay       Duration       `json:"fallback_delay,omitempty"`
-	IsWireGuardListener bool           `json:"-"`
+	Detour                 string                `json:"detour,omitempty"`
+	BindInterface          string                `json:"bind_interface,omitempty"`
+	Inet4BindAddress       *ListenAddress        `json:"inet4_bind_address,omitempty"`
+	Inet6BindAddress       *ListenAddress        `json:"inet6_bind_address,omitempty"`
+	ProtectPath            string                `json:"protect_path,omitempty"`
+	RoutingMark            uint32                `json:"routing_mark,omitempty"`
+	ReuseAddr              bool                  `json:"reuse_addr,omitempty"`
+	ConnectTimeout         Duration              `json:"connect_timeout,omitempty"`
+	TCPFastOpen            bool                  `json:"tcp_fast_open,omitempty"`
+	TCPMultiPath           bool                  `json:"tcp_multi_path,omitempty"`
+	TCPMultiPathInterfaces Listable[string]      `json:"tcp_multi_path_interfaces,omitempty"`
+	UDPFragment            *bool                 `json:"udp_fragment,omitempty"`
+	UDPFragmentDefault     bool                  `json:"-"`
+	DomainStrategy         DomainStrategy        `json:"domain_strategy,omitempty"`
+	FallbackDelay          Duration              `json:"fallback_delay,omitempty"`
+	Buffer                 *BufferOptions        `json:"buffer,omitempty"`
+	Knock                  *OutboundKnockOptions `json:"knock,omitempty"`
+	IsWireGuardListener    bool                  `json:"-"`
+}
+
+type OutboundKnockOptions struct {
+	ServerPort uint16   `json:"server_port"`
+	Key        string   `json:"key"`
+	Interval   Duration `json:"interval,omitempty"`
 }
 
 func (o *DialerOptions) TakeDialerOptions() DialerOptions {
@@ -6,17 +6,44 @@ import (
 )
 
 type ProviderOutboundOptions struct {
-	URL             string                                  `json:"url"`
+	Source          string                                  `json:"source,omitempty"`
+	URL             string                                  `json:"url,omitempty"`
+	Path            string                                  `json:"path,omitempty"`
+	Command         Listable[string]                        `json:"command,omitempty"`
 	CacheTag        string                                  `json:"cache_tag,omitempty"`
 	UpdateInterval  Duration                                `json:"update_interval,omitempty"`
 	RequestTimeout  Duration                                `json:"request_timeout,omitempty"`
 	HTTP3           bool                                    `json:"http3,omitempty"`
 	Headers         map[string]string                       `json:"headers,omitempty"`
 	SelectorOptions SelectorOutboundOptions                 `json:"selector,omitempty"`
+	Filter          Listable[string]                        `json:"filter,omitempty"`
+	Exclude         Listable[string]                        `json:"exclude,omitempty"`
+	Rename          []ProviderOutboundRenameOptions         `json:"rename,omitempty"`
+	RegionFlag      bool                                    `json:"region_flag,omitempty"`
+	Override        *ProviderOutboundOverrideOptions        `json:"override,omitempty"`
 	Actions         Listable[ProviderOutboundActionOptions] `json:"actions,omitempty"`
 	ProviderDialer  DialerOptions                           `json:"dialer,omitempty"`
 }
 
+type ProviderOutboundOverrideOptions struct {
+	Detour         string                              `json:"detour,omitempty"`
+	DomainStrategy DomainStrategy                      `json:"domain_strategy,omitempty"`
+	TCPFastOpen    *bool                               `json:"tcp_fast_open,omitempty"`
+	TLS            *ProviderOutboundTLSOverrideOptions `json:"tls,omitempty"`
+	Multiplex      *OutboundMultiplexOptions           `json:"multiplex,omitempty"`
+}
+
+type ProviderOutboundTLSOverrideOptions struct {
+	Insecure *bool                `json:"insecure,omitempty"`
+	ALPN     Listable[string]     `json:"alpn,omitempty"`
+	UTLS     *OutboundUTLSOptions `json:"utls,omitempty"`
+}
+
+type ProviderOutboundRenameOptions struct {
+	Pattern     string `json:"pattern"`
+	Replacement string `json:"replacement"`
+}
+
 type ProviderOutboundActionOptions struct {
 	Operate    string          `json:"operate"`
 	RawMessage json.RawMessage `json:"-"`
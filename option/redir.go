@@ -2,6 +2,8 @@ package option
 
 type RedirectInboundOptions struct {
 	ListenOptions
+	AutoRedirect           bool   `json:"auto_redirect,omitempty"`
+	AutoRedirectOutputMark FwMark `json:"auto_redirect_output_mark,omitempty"`
 
 	// Script
 	Scripts Listable[ScriptOptions] `json:"scripts,omitempty"`
@@ -9,7 +11,13 @@ type RedirectInboundOptions struct {
 
 type TProxyInboundOptions struct {
 	ListenOptions
-	Network NetworkList `json:"network,omitempty"`
+	Network                 NetworkList `json:"network,omitempty"`
+	UDPMaxSessions          int         `json:"udp_max_sessions,omitempty"`
+	UDPMaxSessionsPerSource int         `json:"udp_max_sessions_per_source,omitempty"`
+	AutoRedirect            bool        `json:"auto_redirect,omitempty"`
+	AutoRedirectInputMark   FwMark      `json:"auto_redirect_input_mark,omitempty"`
+	AutoRedirectOutputMark  FwMark      `json:"auto_redirect_output_mark,omitempty"`
+	IPRoute2TableIndex      int         `json:"iproute2_table_index,omitempty"`
 
 	// Script
 	Scripts Listable[ScriptOptions] `json:"scripts,omitempty"`
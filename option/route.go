@@ -1,26 +1,43 @@
 package option
 
 type RouteOptions struct {
-	GeoIP               *GeoIPOptions   `json:"geoip,omitempty"`
-	Geosite             *GeositeOptions `json:"geosite,omitempty"`
-	Rules               []Rule          `json:"rules,omitempty"`
-	RuleSet             []RuleSet       `json:"rule_set,omitempty"`
-	Final               string          `json:"final,omitempty"`
-	FindProcess         bool            `json:"find_process,omitempty"`
-	AutoDetectInterface bool            `json:"auto_detect_interface,omitempty"`
-	OverrideAndroidVPN  bool            `json:"override_android_vpn,omitempty"`
-	DefaultInterface    string          `json:"default_interface,omitempty"`
-	DefaultMark         uint32          `json:"default_mark,omitempty"`
+	GeoIP               *GeoIPOptions       `json:"geoip,omitempty"`
+	Geosite             *GeositeOptions     `json:"geosite,omitempty"`
+	Rules               []Rule              `json:"rules,omitempty"`
+	RuleSet             []RuleSet           `json:"rule_set,omitempty"`
+	Final               string              `json:"final,omitempty"`
+	FindProcess         bool                `json:"find_process,omitempty"`
+	AutoDetectInterface bool                `json:"auto_detect_interface,omitempty"`
+	OverrideAndroidVPN  bool                `json:"override_android_vpn,omitempty"`
+	DefaultInterface    string              `json:"default_interface,omitempty"`
+	DefaultMark         uint32              `json:"default_mark,omitempty"`
+	DefaultBuffer       *BufferOptions      `json:"default_buffer,omitempty"`
+	StartConcurrency    int                 `json:"start_concurrency,omitempty"`
+	UDPTimeouts         map[string]Duration `json:"udp_timeouts,omitempty"`
+	Mirrors             []MirrorOptions     `json:"mirrors,omitempty"`
+}
+
+type MirrorOptions struct {
+	Tag        string      `json:"tag"`
+	Network    string      `json:"network,omitempty"`
+	Address    string      `json:"address"`
+	SampleRate float64     `json:"sample_rate,omitempty"`
+	MaxBytes   MemoryBytes `json:"max_bytes,omitempty"`
 }
 
 type GeoIPOptions struct {
-	Path           string `json:"path,omitempty"`
-	DownloadURL    string `json:"download_url,omitempty"`
-	DownloadDetour string `json:"download_detour,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	DownloadURL    string   `json:"download_url,omitempty"`
+	DownloadDetour string   `json:"download_detour,omitempty"`
+	ChecksumURL    string   `json:"checksum_url,omitempty"`
+	UpdateInterval Duration `json:"update_interval,omitempty"`
+	ASNPath        string   `json:"asn_path,omitempty"`
 }
 
 type GeositeOptions struct {
-	Path           string `json:"path,omitempty"`
-	DownloadURL    string `json:"download_url,omitempty"`
-	DownloadDetour string `json:"download_detour,omitempty"`
+	Path           string   `json:"path,omitempty"`
+	DownloadURL    string   `json:"download_url,omitempty"`
+	DownloadDetour string   `json:"download_detour,omitempty"`
+	ChecksumURL    string   `json:"checksum_url,omitempty"`
+	UpdateInterval Duration `json:"update_interval,omitempty"`
 }
@@ -77,6 +77,8 @@ type _DefaultRule struct {
 	Geosite                  Listable[string] `json:"geosite,omitempty"`
 	SourceGeoIP              Listable[string] `json:"source_geoip,omitempty"`
 	GeoIP                    Listable[string] `json:"geoip,omitempty"`
+	SourceASN                Listable[uint32] `json:"source_asn,omitempty"`
+	ASN                      Listable[uint32] `json:"asn,omitempty"`
 	SourceIPCIDR             Listable[string] `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool             `json:"source_ip_is_private,omitempty"`
 	IPCIDR                   Listable[string] `json:"ip_cidr,omitempty"`
@@ -88,15 +90,21 @@ type _DefaultRule struct {
 	ProcessName              Listable[string] `json:"process_name,omitempty"`
 	ProcessPath              Listable[string] `json:"process_path,omitempty"`
 	PackageName              Listable[string] `json:"package_name,omitempty"`
+	AppID                    Listable[string] `json:"app_id,omitempty"`
 	User                     Listable[string] `json:"user,omitempty"`
 	UserID                   Listable[int32]  `json:"user_id,omitempty"`
 	ClashMode                string           `json:"clash_mode,omitempty"`
 	WIFISSID                 Listable[string] `json:"wifi_ssid,omitempty"`
 	WIFIBSSID                Listable[string] `json:"wifi_bssid,omitempty"`
+	TimeRange                Listable[string] `json:"time_range,omitempty"`
+	Weekday                  Listable[string] `json:"weekday,omitempty"`
 	RuleSet                  Listable[string] `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool             `json:"rule_set_ip_cidr_match_source,omitempty"`
 	Invert                   bool             `json:"invert,omitempty"`
 	Outbound                 string           `json:"outbound,omitempty"`
+	UDPTimeout               Duration         `json:"udp_timeout,omitempty"`
+	Mirror                   string           `json:"mirror,omitempty"`
+	RuleAction
 
 	// Deprecated: renamed to rule_set_ip_cidr_match_source
 	Deprecated_RulesetIPCIDRMatchSource bool `json:"rule_set_ipcidr_match_source,omitempty"`
@@ -122,14 +130,20 @@ func (r *DefaultRule) IsValid() bool {
 	var defaultValue DefaultRule
 	defaultValue.Invert = r.Invert
 	defaultValue.Outbound = r.Outbound
+	defaultValue.UDPTimeout = r.UDPTimeout
+	defaultValue.Mirror = r.Mirror
+	defaultValue.RuleAction = r.RuleAction
 	return !reflect.DeepEqual(r, defaultValue)
 }
 
 type LogicalRule struct {
-	Mode     string `json:"mode"`
-	Rules    []Rule `json:"rules,omitempty"`
-	Invert   bool   `json:"invert,omitempty"`
-	Outbound string `json:"outbound,omitempty"`
+	Mode       string   `json:"mode"`
+	Rules      []Rule   `json:"rules,omitempty"`
+	Invert     bool     `json:"invert,omitempty"`
+	Outbound   string   `json:"outbound,omitempty"`
+	UDPTimeout Duration `json:"udp_timeout,omitempty"`
+	Mirror     string   `json:"mirror,omitempty"`
+	RuleAction
 }
 
 func (r LogicalRule) IsValid() bool {
@@ -0,0 +1,14 @@
+package option
+
+type RuleAction struct {
+	Action     string           `json:"action,omitempty"`
+	Method     string           `json:"method,omitempty"`
+	Sniffer    Listable[string] `json:"sniffer,omitempty"`
+	Timeout    Duration         `json:"timeout,omitempty"`
+	BufferSize int              `json:"buffer_size,omitempty"`
+	Strategy   DomainStrategy   `json:"strategy,omitempty"`
+	Server     string           `json:"server,omitempty"`
+
+	OverrideAddress string `json:"override_address,omitempty"`
+	OverridePort    uint16 `json:"override_port,omitempty"`
+}
@@ -78,8 +78,11 @@ type _DefaultDNSRule struct {
 	Geosite                  Listable[string]       `json:"geosite,omitempty"`
 	SourceGeoIP              Listable[string]       `json:"source_geoip,omitempty"`
 	GeoIP                    Listable[string]       `json:"geoip,omitempty"`
+	SourceASN                Listable[uint32]       `json:"source_asn,omitempty"`
+	ASN                      Listable[uint32]       `json:"asn,omitempty"`
 	IPCIDR                   Listable[string]       `json:"ip_cidr,omitempty"`
 	IPIsPrivate              bool                   `json:"ip_is_private,omitempty"`
+	DNSSECValidated          bool                   `json:"dnssec_validated,omitempty"`
 	SourceIPCIDR             Listable[string]       `json:"source_ip_cidr,omitempty"`
 	SourceIPIsPrivate        bool                   `json:"source_ip_is_private,omitempty"`
 	SourcePort               Listable[uint16]       `json:"source_port,omitempty"`
@@ -89,20 +92,31 @@ type _DefaultDNSRule struct {
 	ProcessName              Listable[string]       `json:"process_name,omitempty"`
 	ProcessPath              Listable[string]       `json:"process_path,omitempty"`
 	PackageName              Listable[string]       `json:"package_name,omitempty"`
+	AppID                    Listable[string]       `json:"app_id,omitempty"`
 	User                     Listable[string]       `json:"user,omitempty"`
 	UserID                   Listable[int32]        `json:"user_id,omitempty"`
 	Outbound                 Listable[string]       `json:"outbound,omitempty"`
 	ClashMode                string                 `json:"clash_mode,omitempty"`
 	WIFISSID                 Listable[string]       `json:"wifi_ssid,omitempty"`
 	WIFIBSSID                Listable[string]       `json:"wifi_bssid,omitempty"`
+	TimeRange                Listable[string]       `json:"time_range,omitempty"`
+	Weekday                  Listable[string]       `json:"weekday,omitempty"`
 	RuleSet                  Listable[string]       `json:"rule_set,omitempty"`
 	RuleSetIPCIDRMatchSource bool                   `json:"rule_set_ip_cidr_match_source,omitempty"`
 	RuleSetIPCIDRAcceptEmpty bool                   `json:"rule_set_ip_cidr_accept_empty,omitempty"`
 	Invert                   bool                   `json:"invert,omitempty"`
+	Action                   string                 `json:"action,omitempty"`
 	Server                   string                 `json:"server,omitempty"`
+	Detour                   string                 `json:"detour,omitempty"`
+	Answer                   Listable[string]       `json:"answer,omitempty"`
+	HostsPath                Listable[string]       `json:"hosts_path,omitempty"`
 	DisableCache             bool                   `json:"disable_cache,omitempty"`
 	RewriteTTL               *uint32                `json:"rewrite_ttl,omitempty"`
 	ClientSubnet             *AddrPrefix            `json:"client_subnet,omitempty"`
+	FakeIP                   *bool                  `json:"fakeip,omitempty"`
+	BlockIPv6                bool                   `json:"block_ipv6,omitempty"`
+	BlockIPCIDR              Listable[string]       `json:"block_ip_cidr,omitempty"`
+	RewriteRCode             string                 `json:"rewrite_rcode,omitempty"`
 
 	// Deprecated: renamed to rule_set_ip_cidr_match_source
 	Deprecated_RulesetIPCIDRMatchSource bool `json:"rule_set_ipcidr_match_source,omitempty"`
@@ -127,21 +141,37 @@ func (r *DefaultDNSRule) UnmarshalJSON(bytes []byte) error {
 func (r *DefaultDNSRule) IsValid() bool {
 	var defaultValue DefaultDNSRule
 	defaultValue.Invert = r.Invert
+	defaultValue.Action = r.Action
 	defaultValue.Server = r.Server
+	defaultValue.Detour = r.Detour
+	defaultValue.Answer = r.Answer
+	defaultValue.HostsPath = r.HostsPath
 	defaultValue.DisableCache = r.DisableCache
 	defaultValue.RewriteTTL = r.RewriteTTL
 	defaultValue.ClientSubnet = r.ClientSubnet
+	defaultValue.FakeIP = r.FakeIP
+	defaultValue.BlockIPv6 = r.BlockIPv6
+	defaultValue.BlockIPCIDR = r.BlockIPCIDR
+	defaultValue.RewriteRCode = r.RewriteRCode
 	return !reflect.DeepEqual(r, defaultValue)
 }
 
 type LogicalDNSRule struct {
-	Mode         string      `json:"mode"`
-	Rules        []DNSRule   `json:"rules,omitempty"`
-	Invert       bool        `json:"invert,omitempty"`
-	Server       string      `json:"server,omitempty"`
-	DisableCache bool        `json:"disable_cache,omitempty"`
-	RewriteTTL   *uint32     `json:"rewrite_ttl,omitempty"`
-	ClientSubnet *AddrPrefix `json:"client_subnet,omitempty"`
+	Mode         string           `json:"mode"`
+	Rules        []DNSRule        `json:"rules,omitempty"`
+	Invert       bool             `json:"invert,omitempty"`
+	Action       string           `json:"action,omitempty"`
+	Server       string           `json:"server,omitempty"`
+	Detour       string           `json:"detour,omitempty"`
+	Answer       Listable[string] `json:"answer,omitempty"`
+	HostsPath    Listable[string] `json:"hosts_path,omitempty"`
+	DisableCache bool             `json:"disable_cache,omitempty"`
+	RewriteTTL   *uint32          `json:"rewrite_ttl,omitempty"`
+	ClientSubnet *AddrPrefix      `json:"client_subnet,omitempty"`
+	FakeIP       *bool            `json:"fakeip,omitempty"`
+	BlockIPv6    bool             `json:"block_ipv6,omitempty"`
+	BlockIPCIDR  Listable[string] `json:"block_ip_cidr,omitempty"`
+	RewriteRCode string           `json:"rewrite_rcode,omitempty"`
 }
 
 func (r LogicalDNSRule) IsValid() bool {
@@ -14,12 +14,14 @@ import (
 )
 
 type _RuleSet struct {
-	Type          string        `json:"type"`
-	Tag           string        `json:"tag"`
-	Format        string        `json:"format"`
-	InlineOptions PlainRuleSet  `json:"-"`
-	LocalOptions  LocalRuleSet  `json:"-"`
-	RemoteOptions RemoteRuleSet `json:"-"`
+	Type             string           `json:"type"`
+	Tag              string           `json:"tag"`
+	Format           string           `json:"format"`
+	Behavior         string           `json:"behavior,omitempty"`
+	InlineOptions    PlainRuleSet     `json:"-"`
+	LocalOptions     LocalRuleSet     `json:"-"`
+	RemoteOptions    RemoteRuleSet    `json:"-"`
+	CompositeOptions CompositeRuleSet `json:"-"`
 }
 
 type RuleSet _RuleSet
@@ -34,6 +36,8 @@ func (r RuleSet) MarshalJSON() ([]byte, error) {
 		v = r.LocalOptions
 	case C.RuleSetTypeRemote:
 		v = r.RemoteOptions
+	case C.RuleSetTypeComposite:
+		v = r.CompositeOptions
 	default:
 		return nil, E.New("unknown rule-set type: " + r.Type)
 	}
@@ -48,16 +52,28 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 	if r.Tag == "" {
 		return E.New("missing tag")
 	}
-	if r.Type != C.RuleSetTypeInline {
+	if r.Type != C.RuleSetTypeInline && r.Type != C.RuleSetTypeComposite {
 		switch r.Format {
 		case "":
 			return E.New("missing format")
-		case C.RuleSetFormatSource, C.RuleSetFormatBinary:
+		case C.RuleSetFormatSource, C.RuleSetFormatBinary, C.RuleSetFormatAdGuard, C.RuleSetFormatHosts:
+		case C.RuleSetFormatClash:
+			switch r.Behavior {
+			case "":
+				return E.New("missing behavior")
+			case C.ClashRuleSetBehaviorDomain, C.ClashRuleSetBehaviorIPCIDR, C.ClashRuleSetBehaviorClassical:
+			default:
+				return E.New("unknown clash rule-set behavior: " + r.Behavior)
+			}
 		default:
 			return E.New("unknown rule-set format: " + r.Format)
 		}
+		if r.Format != C.RuleSetFormatClash {
+			r.Behavior = ""
+		}
 	} else {
 		r.Format = ""
+		r.Behavior = ""
 	}
 	var v any
 	switch r.Type {
@@ -68,6 +84,8 @@ func (r *RuleSet) UnmarshalJSON(bytes []byte) error {
 		v = &r.LocalOptions
 	case C.RuleSetTypeRemote:
 		v = &r.RemoteOptions
+	case C.RuleSetTypeComposite:
+		v = &r.CompositeOptions
 	default:
 		return E.New("unknown rule-set type: " + r.Type)
 	}
@@ -86,6 +104,12 @@ type RemoteRuleSet struct {
 	URL            string   `json:"url"`
 	DownloadDetour string   `json:"download_detour,omitempty"`
 	UpdateInterval Duration `json:"update_interval,omitempty"`
+	Path           string   `json:"path,omitempty"`
+}
+
+type CompositeRuleSet struct {
+	Mode    string           `json:"mode,omitempty"`
+	RuleSet Listable[string] `json:"rule_set"`
 }
 
 type _HeadlessRule struct {
@@ -4,12 +4,14 @@ import "github.com/sagernet/sing/common/auth"
 
 type SocksInboundOptions struct {
 	ListenOptions
-	Users []auth.User `json:"users,omitempty"`
+	Users   []auth.User `json:"users,omitempty"`
+	Network NetworkList `json:"network,omitempty"`
 }
 
 type HTTPMixedInboundOptions struct {
 	ListenOptions
 	Users          []auth.User `json:"users,omitempty"`
+	Network        NetworkList `json:"network,omitempty"`
 	SetSystemProxy bool        `json:"set_system_proxy,omitempty"`
 	InboundTLSOptionsContainer
 }
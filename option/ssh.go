@@ -11,4 +11,6 @@ type SSHOutboundOptions struct {
 	HostKey              Listable[string] `json:"host_key,omitempty"`
 	HostKeyAlgorithms    Listable[string] `json:"host_key_algorithms,omitempty"`
 	ClientVersion        string           `json:"client_version,omitempty"`
+	MaxChannels          int              `json:"max_channels,omitempty"`
+	KeepaliveInterval    Duration         `json:"keepalive_interval,omitempty"`
 }
@@ -12,6 +12,7 @@ type InboundTLSOptions struct {
 	CertificatePath string                 `json:"certificate_path,omitempty"`
 	Key             Listable[string]       `json:"key,omitempty"`
 	KeyPath         string                 `json:"key_path,omitempty"`
+	PQKeyExchange   bool                   `json:"pq_key_exchange,omitempty"`
 	ACME            *InboundACMEOptions    `json:"acme,omitempty"`
 	ECH             *InboundECHOptions     `json:"ech,omitempty"`
 	Reality         *InboundRealityOptions `json:"reality,omitempty"`
@@ -35,19 +36,23 @@ func (o *InboundTLSOptionsContainer) ReplaceInboundTLSOptions(options *InboundTL
 }
 
 type OutboundTLSOptions struct {
-	Enabled         bool                    `json:"enabled,omitempty"`
-	DisableSNI      bool                    `json:"disable_sni,omitempty"`
-	ServerName      string                  `json:"server_name,omitempty"`
-	Insecure        bool                    `json:"insecure,omitempty"`
-	ALPN            Listable[string]        `json:"alpn,omitempty"`
-	MinVersion      string                  `json:"min_version,omitempty"`
-	MaxVersion      string                  `json:"max_version,omitempty"`
-	CipherSuites    Listable[string]        `json:"cipher_suites,omitempty"`
-	Certificate     Listable[string]        `json:"certificate,omitempty"`
-	CertificatePath string                  `json:"certificate_path,omitempty"`
-	ECH             *OutboundECHOptions     `json:"ech,omitempty"`
-	UTLS            *OutboundUTLSOptions    `json:"utls,omitempty"`
-	Reality         *OutboundRealityOptions `json:"reality,omitempty"`
+	Enabled                    bool                    `json:"enabled,omitempty"`
+	DisableSNI                 bool                    `json:"disable_sni,omitempty"`
+	ServerName                 string                  `json:"server_name,omitempty"`
+	Insecure                   bool                    `json:"insecure,omitempty"`
+	ALPN                       Listable[string]        `json:"alpn,omitempty"`
+	MinVersion                 string                  `json:"min_version,omitempty"`
+	MaxVersion                 string                  `json:"max_version,omitempty"`
+	CipherSuites               Listable[string]        `json:"cipher_suites,omitempty"`
+	Certificate                Listable[string]        `json:"certificate,omitempty"`
+	CertificatePath            string                  `json:"certificate_path,omitempty"`
+	CertificateDirectoryPath   string                  `json:"certificate_directory_path,omitempty"`
+	CertificatePublicKeySHA256 Listable[string]        `json:"certificate_public_key_sha256,omitempty"`
+	PQKeyExchange              bool                    `json:"pq_key_exchange,omitempty"`
+	SessionCache               bool                    `json:"session_cache,omitempty"`
+	ECH                        *OutboundECHOptions     `json:"ech,omitempty"`
+	UTLS                       *OutboundUTLSOptions    `json:"utls,omitempty"`
+	Reality                    *OutboundRealityOptions `json:"reality,omitempty"`
 }
 
 type OutboundTLSOptionsContainer struct {
@@ -68,11 +73,12 @@ func (o *OutboundTLSOptionsContainer) ReplaceOutboundTLSOptions(options *Outboun
 }
 
 type InboundRealityOptions struct {
-	Enabled           bool                           `json:"enabled,omitempty"`
-	Handshake         InboundRealityHandshakeOptions `json:"handshake,omitempty"`
-	PrivateKey        string                         `json:"private_key,omitempty"`
-	ShortID           Listable[string]               `json:"short_id,omitempty"`
-	MaxTimeDifference Duration                       `json:"max_time_difference,omitempty"`
+	Enabled           bool                               `json:"enabled,omitempty"`
+	Handshake         InboundRealityHandshakeOptions     `json:"handshake,omitempty"`
+	Destinations      []InboundRealityDestinationOptions `json:"destinations,omitempty"`
+	PrivateKey        string                             `json:"private_key,omitempty"`
+	ShortID           Listable[string]                   `json:"short_id,omitempty"`
+	MaxTimeDifference Duration                           `json:"max_time_difference,omitempty"`
 }
 
 type InboundRealityHandshakeOptions struct {
@@ -80,12 +86,20 @@ type InboundRealityHandshakeOptions struct {
 	DialerOptions
 }
 
+type InboundRealityDestinationOptions struct {
+	ServerName Listable[string] `json:"server_name,omitempty"`
+	InboundRealityHandshakeOptions
+}
+
 type InboundECHOptions struct {
 	Enabled                     bool             `json:"enabled,omitempty"`
 	PQSignatureSchemesEnabled   bool             `json:"pq_signature_schemes_enabled,omitempty"`
 	DynamicRecordSizingDisabled bool             `json:"dynamic_record_sizing_disabled,omitempty"`
 	Key                         Listable[string] `json:"key,omitempty"`
 	KeyPath                     string           `json:"key_path,omitempty"`
+	KeyRotationInterval         Duration         `json:"key_rotation_interval,omitempty"`
+	PublicName                  string           `json:"public_name,omitempty"`
+	ConfigPath                  string           `json:"config_path,omitempty"`
 }
 
 type OutboundECHOptions struct {
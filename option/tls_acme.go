@@ -29,6 +29,13 @@ type _ACMEDNS01ChallengeOptions struct {
 	Provider          string                     `json:"provider,omitempty"`
 	AliDNSOptions     ACMEDNS01AliDNSOptions     `json:"-"`
 	CloudflareOptions ACMEDNS01CloudflareOptions `json:"-"`
+	Route53Options    ACMEDNS01Route53Options    `json:"-"`
+	GcoreOptions      ACMEDNS01GcoreOptions      `json:"-"`
+	DuckDNSOptions    ACMEDNS01DuckDNSOptions    `json:"-"`
+	DeSECOptions      ACMEDNS01DeSECOptions      `json:"-"`
+	RFC2136Options    ACMEDNS01RFC2136Options    `json:"-"`
+	ExecOptions       ACMEDNS01ExecOptions       `json:"-"`
+	WebhookOptions    ACMEDNS01WebhookOptions    `json:"-"`
 }
 
 type ACMEDNS01ChallengeOptions _ACMEDNS01ChallengeOptions
@@ -40,6 +47,20 @@ func (o ACMEDNS01ChallengeOptions) MarshalJSON() ([]byte, error) {
 		v = o.AliDNSOptions
 	case C.DNSProviderCloudflare:
 		v = o.CloudflareOptions
+	case C.DNSProviderRoute53:
+		v = o.Route53Options
+	case C.DNSProviderGcore:
+		v = o.GcoreOptions
+	case C.DNSProviderDuckDNS:
+		v = o.DuckDNSOptions
+	case C.DNSProviderDeSEC:
+		v = o.DeSECOptions
+	case C.DNSProviderRFC2136:
+		v = o.RFC2136Options
+	case C.DNSProviderExec:
+		v = o.ExecOptions
+	case C.DNSProviderWebhook:
+		v = o.WebhookOptions
 	case "":
 		return nil, E.New("missing provider type")
 	default:
@@ -59,6 +80,20 @@ func (o *ACMEDNS01ChallengeOptions) UnmarshalJSON(bytes []byte) error {
 		v = &o.AliDNSOptions
 	case C.DNSProviderCloudflare:
 		v = &o.CloudflareOptions
+	case C.DNSProviderRoute53:
+		v = &o.Route53Options
+	case C.DNSProviderGcore:
+		v = &o.GcoreOptions
+	case C.DNSProviderDuckDNS:
+		v = &o.DuckDNSOptions
+	case C.DNSProviderDeSEC:
+		v = &o.DeSECOptions
+	case C.DNSProviderRFC2136:
+		v = &o.RFC2136Options
+	case C.DNSProviderExec:
+		v = &o.ExecOptions
+	case C.DNSProviderWebhook:
+		v = &o.WebhookOptions
 	default:
 		return E.New("unknown provider type: " + o.Provider)
 	}
@@ -78,3 +113,38 @@ type ACMEDNS01AliDNSOptions struct {
 type ACMEDNS01CloudflareOptions struct {
 	APIToken string `json:"api_token,omitempty"`
 }
+
+type ACMEDNS01Route53Options struct {
+	AccessKeyID     string `json:"access_key_id,omitempty"`
+	SecretAccessKey string `json:"secret_access_key,omitempty"`
+	SessionToken    string `json:"session_token,omitempty"`
+	HostedZoneID    string `json:"hosted_zone_id,omitempty"`
+}
+
+type ACMEDNS01GcoreOptions struct {
+	APIKey string `json:"api_key,omitempty"`
+}
+
+type ACMEDNS01DuckDNSOptions struct {
+	Token string `json:"token,omitempty"`
+}
+
+type ACMEDNS01DeSECOptions struct {
+	Token string `json:"token,omitempty"`
+}
+
+type ACMEDNS01RFC2136Options struct {
+	Server        string `json:"server,omitempty"`
+	TSIGKeyName   string `json:"tsig_key_name,omitempty"`
+	TSIGSecret    string `json:"tsig_secret,omitempty"`
+	TSIGAlgorithm string `json:"tsig_algorithm,omitempty"`
+}
+
+type ACMEDNS01ExecOptions struct {
+	Command Listable[string] `json:"command,omitempty"`
+}
+
+type ACMEDNS01WebhookOptions struct {
+	URL     string     `json:"url,omitempty"`
+	Headers HTTPHeader `json:"headers,omitempty"`
+}
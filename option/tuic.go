@@ -19,13 +19,14 @@ type TUICUser struct {
 type TUICOutboundOptions struct {
 	DialerOptions
 	ServerOptions
-	UUID              string      `json:"uuid,omitempty"`
-	Password          string      `json:"password,omitempty"`
-	CongestionControl string      `json:"congestion_control,omitempty"`
-	UDPRelayMode      string      `json:"udp_relay_mode,omitempty"`
-	UDPOverStream     bool        `json:"udp_over_stream,omitempty"`
-	ZeroRTTHandshake  bool        `json:"zero_rtt_handshake,omitempty"`
-	Heartbeat         Duration    `json:"heartbeat,omitempty"`
-	Network           NetworkList `json:"network,omitempty"`
+	UUID                 string            `json:"uuid,omitempty"`
+	Password             string            `json:"password,omitempty"`
+	CongestionControl    string            `json:"congestion_control,omitempty"`
+	CongestionControlMap map[string]string `json:"congestion_control_map,omitempty"`
+	UDPRelayMode         string            `json:"udp_relay_mode,omitempty"`
+	UDPOverStream        bool              `json:"udp_over_stream,omitempty"`
+	ZeroRTTHandshake     bool              `json:"zero_rtt_handshake,omitempty"`
+	Heartbeat            Duration          `json:"heartbeat,omitempty"`
+	Network              NetworkList       `json:"network,omitempty"`
 	OutboundTLSOptionsContainer
 }
@@ -31,12 +31,15 @@ type TunInboundOptions struct {
 	IncludeUIDRange        Listable[string]       `json:"include_uid_range,omitempty"`
 	ExcludeUID             Listable[uint32]       `json:"exclude_uid,omitempty"`
 	ExcludeUIDRange        Listable[string]       `json:"exclude_uid_range,omitempty"`
+	IncludeCgroup          Listable[string]       `json:"include_cgroup,omitempty"`
+	ExcludeCgroup          Listable[string]       `json:"exclude_cgroup,omitempty"`
 	IncludeAndroidUser     Listable[int]          `json:"include_android_user,omitempty"`
 	IncludePackage         Listable[string]       `json:"include_package,omitempty"`
 	ExcludePackage         Listable[string]       `json:"exclude_package,omitempty"`
 	EndpointIndependentNat bool                   `json:"endpoint_independent_nat,omitempty"`
 	UDPTimeout             UDPTimeoutCompat       `json:"udp_timeout,omitempty"`
 	Stack                  string                 `json:"stack,omitempty"`
+	NAT64Prefix            string                 `json:"nat64_prefix,omitempty"`
 	Platform               *TunPlatformOptions    `json:"platform,omitempty"`
 	InboundOptions
 
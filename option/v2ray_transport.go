@@ -81,7 +81,10 @@ type V2RayWebsocketOptions struct {
 	EarlyDataHeaderName string     `json:"early_data_header_name,omitempty"`
 }
 
-type V2RayQUICOptions struct{}
+type V2RayQUICOptions struct {
+	CongestionControl       string `json:"congestion_control,omitempty"`
+	InitialCongestionWindow uint32 `json:"initial_congestion_window,omitempty"`
+}
 
 type V2RayGRPCOptions struct {
 	ServiceName         string   `json:"service_name,omitempty"`
@@ -59,6 +59,12 @@ func New(ctx context.Context, router adapter.Router, logFactory log.Factory, log
 		return NewSelector(ctx, router, logger, tag, options.SelectorOptions)
 	case C.TypeURLTest:
 		return NewURLTest(ctx, router, logger, tag, options.URLTestOptions)
+	case C.TypeLoadBalance:
+		return NewLoadBalance(ctx, router, logger, tag, options.LoadBalanceOptions)
+	case C.TypeFallback:
+		return NewFallback(ctx, router, logger, tag, options.FallbackOptions)
+	case C.TypeRelay:
+		return NewRelay(router, logger, tag, options.RelayOptions)
 	case C.TypeProvider:
 		return NewProvider(ctx, router, logFactory, logger, tag, options.ProviderOptions)
 	default:
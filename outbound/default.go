@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/relay"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
@@ -28,6 +29,7 @@ type myOutboundAdapter struct {
 	logger       log.ContextLogger
 	tag          string
 	dependencies []string
+	buffer       *option.BufferOptions
 }
 
 func (a *myOutboundAdapter) Type() string {
@@ -50,6 +52,17 @@ func (a *myOutboundAdapter) NewError(ctx context.Context, err error) {
 	NewError(a.logger, ctx, err)
 }
 
+func (a *myOutboundAdapter) relayBuffer() *option.BufferOptions {
+	return a.buffer
+}
+
+func (a *myOutboundAdapter) defaultBuffer() *option.BufferOptions {
+	if a.router == nil {
+		return nil
+	}
+	return a.router.DefaultBuffer()
+}
+
 func withDialerDependency(options option.DialerOptions) []string {
 	if options.Detour != "" {
 		return []string{options.Detour}
@@ -74,7 +87,7 @@ func NewConnection(ctx context.Context, this N.Dialer, conn net.Conn, metadata a
 		outConn.Close()
 		return err
 	}
-	return CopyEarlyConn(ctx, conn, outConn)
+	return CopyEarlyConn(ctx, conn, outConn, relayBufferOptions(this, metadata))
 }
 
 func NewDirectConnection(ctx context.Context, router adapter.Router, this N.Dialer, conn net.Conn, metadata adapter.InboundContext, domainStrategy dns.DomainStrategy) error {
@@ -101,7 +114,7 @@ func NewDirectConnection(ctx context.Context, router adapter.Router, this N.Dial
 		outConn.Close()
 		return err
 	}
-	return CopyEarlyConn(ctx, conn, outConn)
+	return CopyEarlyConn(ctx, conn, outConn, relayBufferOptions(this, metadata))
 }
 
 func NewPacketConnection(ctx context.Context, this N.Dialer, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -134,13 +147,8 @@ func NewPacketConnection(ctx context.Context, this N.Dialer, conn N.PacketConn,
 			natConn.UpdateDestination(destinationAddress)
 		}
 	}
-	switch metadata.Protocol {
-	case C.ProtocolSTUN:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.STUNTimeout)
-	case C.ProtocolQUIC:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.QUICTimeout)
-	case C.ProtocolDNS:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.DNSTimeout)
+	if metadata.UDPTimeout > 0 {
+		ctx, conn = withUDPTimeout(ctx, conn, metadata.UDPTimeout)
 	}
 	return bufio.CopyPacketConn(ctx, conn, bufio.NewPacketConn(outConn))
 }
@@ -178,18 +186,38 @@ func NewDirectPacketConnection(ctx context.Context, router adapter.Router, this
 			natConn.UpdateDestination(destinationAddress)
 		}
 	}
-	switch metadata.Protocol {
-	case C.ProtocolSTUN:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.STUNTimeout)
-	case C.ProtocolQUIC:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.QUICTimeout)
-	case C.ProtocolDNS:
-		ctx, conn = canceler.NewPacketConn(ctx, conn, C.DNSTimeout)
+	if metadata.UDPTimeout > 0 {
+		ctx, conn = withUDPTimeout(ctx, conn, metadata.UDPTimeout)
 	}
 	return bufio.CopyPacketConn(ctx, conn, bufio.NewPacketConn(outConn))
 }
 
-func CopyEarlyConn(ctx context.Context, conn net.Conn, serverConn net.Conn) error {
+// withUDPTimeout applies the per-flow timeout to the NAT entry of the inbound
+// if it supports it, so that the timeout may also exceed the inbound
+// udp_timeout. Otherwise the flow can only be closed earlier.
+func withUDPTimeout(ctx context.Context, conn N.PacketConn, timeout time.Duration) (context.Context, N.PacketConn) {
+	if timeoutConn, isTimeoutConn := common.Cast[canceler.PacketConn](conn); isTimeoutConn {
+		timeoutConn.SetTimeout(timeout)
+		return ctx, conn
+	}
+	return canceler.NewPacketConn(ctx, conn, timeout)
+}
+
+type relayBufferOutbound interface {
+	relayBuffer() *option.BufferOptions
+	defaultBuffer() *option.BufferOptions
+}
+
+// relayBufferOptions merges the outbound, inbound and route.default_buffer
+// options, in that order of precedence.
+func relayBufferOptions(this N.Dialer, metadata adapter.InboundContext) *option.BufferOptions {
+	if outbound, isOutbound := this.(relayBufferOutbound); isOutbound {
+		return relay.MergeOptions(outbound.relayBuffer(), metadata.InboundOptions.Buffer, outbound.defaultBuffer())
+	}
+	return metadata.InboundOptions.Buffer
+}
+
+func CopyEarlyConn(ctx context.Context, conn net.Conn, serverConn net.Conn, bufferOptions *option.BufferOptions) error {
 	if cachedReader, isCached := conn.(N.CachedReader); isCached {
 		payload := cachedReader.ReadCached()
 		if payload != nil && !payload.IsEmpty() {
@@ -199,7 +227,7 @@ func CopyEarlyConn(ctx context.Context, conn net.Conn, serverConn net.Conn) erro
 				serverConn.Close()
 				return err
 			}
-			return bufio.CopyConn(ctx, conn, serverConn)
+			return relay.CopyConn(ctx, conn, serverConn, bufferOptions)
 		}
 	}
 	if earlyConn, isEarlyConn := common.Cast[N.EarlyConn](serverConn); isEarlyConn && earlyConn.NeedHandshake() {
@@ -231,7 +259,7 @@ func CopyEarlyConn(ctx context.Context, conn net.Conn, serverConn net.Conn) erro
 			return N.ReportHandshakeFailure(conn, err)
 		}
 	}
-	return bufio.CopyConn(ctx, conn, serverConn)
+	return relay.CopyConn(ctx, conn, serverConn, bufferOptions)
 }
 
 func NewError(logger log.ContextLogger, ctx context.Context, err error) {
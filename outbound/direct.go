@@ -8,10 +8,12 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/nat64"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
@@ -19,8 +21,9 @@ import (
 )
 
 var (
-	_ adapter.Outbound = (*Direct)(nil)
-	_ N.ParallelDialer = (*Direct)(nil)
+	_ adapter.Outbound                = (*Direct)(nil)
+	_ N.ParallelDialer                = (*Direct)(nil)
+	_ adapter.InterfaceUpdateListener = (*Direct)(nil)
 )
 
 type Direct struct {
@@ -31,6 +34,7 @@ type Direct struct {
 	overrideOption      int
 	overrideDestination M.Socksaddr
 	loopBack            *loopBackDetector
+	nat64               *nat64.Resolver
 }
 
 func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, options option.DirectOutboundOptions) (*Direct, error) {
@@ -47,6 +51,7 @@ func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, opti
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		domainStrategy: dns.DomainStrategy(options.DomainStrategy),
 		fallbackDelay:  time.Duration(options.FallbackDelay),
@@ -66,6 +71,12 @@ func NewDirect(router adapter.Router, logger log.ContextLogger, tag string, opti
 		outbound.overrideOption = 3
 		outbound.overrideDestination = M.Socksaddr{Port: options.OverridePort}
 	}
+	if options.NAT64Prefix != "" {
+		outbound.nat64, err = nat64.NewResolver(router, options.NAT64Prefix)
+		if err != nil {
+			return nil, E.Cause(err, "parse nat64_prefix")
+		}
+	}
 	return outbound, nil
 }
 
@@ -90,6 +101,13 @@ func (h *Direct) DialContext(ctx context.Context, network string, destination M.
 	case N.NetworkUDP:
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	}
+	if h.nat64 != nil && destination.IsIPv4() {
+		prefix, err := h.nat64.Prefix(ctx)
+		if err != nil {
+			return nil, E.Cause(err, "discover NAT64 prefix")
+		}
+		destination = M.SocksaddrFrom(nat64.Synthesize(prefix, destination.Addr), destination.Port)
+	}
 	conn, err := h.dialer.DialContext(ctx, network, destination)
 	if err != nil {
 		return nil, err
@@ -121,6 +139,18 @@ func (h *Direct) DialParallel(ctx context.Context, network string, destination M
 	} else {
 		domainStrategy = dns.DomainStrategy(metadata.InboundOptions.DomainStrategy)
 	}
+	if h.nat64 != nil {
+		prefix, err := h.nat64.Prefix(ctx)
+		if err != nil {
+			return nil, E.Cause(err, "discover NAT64 prefix")
+		}
+		destinationAddresses = common.Map(destinationAddresses, func(it netip.Addr) netip.Addr {
+			if it.Is4() || it.Is4In6() {
+				return nat64.Synthesize(prefix, it)
+			}
+			return it
+		})
+	}
 	return N.DialParallel(ctx, h.dialer, network, destination, destinationAddresses, domainStrategy == dns.DomainStrategyPreferIPv6, h.fallbackDelay)
 }
 
@@ -144,10 +174,25 @@ func (h *Direct) ListenPacket(ctx context.Context, destination M.Socksaddr) (net
 	} else {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	}
-	conn, err := h.dialer.ListenPacket(ctx, destination)
+	var nat64Prefix netip.Prefix
+	dialDestination := destination
+	if h.nat64 != nil {
+		var err error
+		nat64Prefix, err = h.nat64.Prefix(ctx)
+		if err != nil {
+			return nil, E.Cause(err, "discover NAT64 prefix")
+		}
+		if destination.IsIPv4() {
+			dialDestination = M.SocksaddrFrom(nat64.Synthesize(nat64Prefix, destination.Addr), destination.Port)
+		}
+	}
+	conn, err := h.dialer.ListenPacket(ctx, dialDestination)
 	if err != nil {
 		return nil, err
 	}
+	if nat64Prefix.IsValid() {
+		conn = nat64.NewPacketConn(bufio.NewPacketConn(conn), nat64Prefix, true)
+	}
 	conn = h.loopBack.NewPacketConn(bufio.NewPacketConn(conn), destination)
 	if originDestination != destination {
 		conn = bufio.NewNATPacketConn(bufio.NewPacketConn(conn), destination, originDestination)
@@ -155,6 +200,12 @@ func (h *Direct) ListenPacket(ctx context.Context, destination M.Socksaddr) (net
 	return conn, nil
 }
 
+func (h *Direct) InterfaceUpdated() {
+	if h.nat64 != nil {
+		h.nat64.Reset()
+	}
+}
+
 func (h *Direct) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	if h.loopBack.CheckConn(metadata.Source.AddrPort(), M.AddrPortFromNet(conn.LocalAddr())) {
 		return E.New("reject loopback connection to ", metadata.Destination)
@@ -0,0 +1,32 @@
+package outbound
+
+import (
+	"context"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+)
+
+var (
+	_ adapter.Outbound                = (*Fallback)(nil)
+	_ adapter.OutboundGroup           = (*Fallback)(nil)
+	_ adapter.InterfaceUpdateListener = (*Fallback)(nil)
+)
+
+// Fallback is a URLTest group that uses the first available outbound in
+// configured order instead of the fastest one.
+type Fallback struct {
+	*URLTest
+}
+
+func NewFallback(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.FallbackOutboundOptions) (*Fallback, error) {
+	urlTest, err := NewURLTest(ctx, router, logger, tag, option.URLTestOutboundOptions(options))
+	if err != nil {
+		return nil, err
+	}
+	urlTest.protocol = C.TypeFallback
+	urlTest.fallback = true
+	return &Fallback{urlTest}, nil
+}
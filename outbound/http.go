@@ -41,6 +41,7 @@ func NewHTTP(ctx context.Context, router adapter.Router, logger log.ContextLogge
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		sHTTP.NewClient(sHTTP.Options{
 			Dialer:   detour,
@@ -96,6 +96,7 @@ func NewHysteria(ctx context.Context, router adapter.Router, logger log.ContextL
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		client: client,
 	}, nil
@@ -6,9 +6,11 @@ import (
 	"context"
 	"net"
 	"os"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/porthop"
 	"github.com/sagernet/sing-box/common/tls"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -53,10 +55,29 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			return nil, E.New("unknown obfs type: ", options.Obfs.Type)
 		}
 	}
+	sendBPS := uint64(options.UpMbps * hysteria.MbpsToBps)
+	switch options.CongestionControl {
+	case "":
+	case "bbr":
+		sendBPS = 0
+	case "brutal":
+		if sendBPS == 0 {
+			return nil, E.New("missing up_mbps for brutal congestion control")
+		}
+	default:
+		return nil, E.New("unsupported congestion control algorithm: ", options.CongestionControl)
+	}
 	outboundDialer, err := dialer.New(router, options.DialerOptions)
 	if err != nil {
 		return nil, err
 	}
+	if len(options.HopPorts) > 0 {
+		hopPorts, err := porthop.ParsePorts(options.HopPorts)
+		if err != nil {
+			return nil, E.Cause(err, "parse hop_ports")
+		}
+		outboundDialer = porthop.NewDialer(outboundDialer, hopPorts, time.Duration(options.HopInterval))
+	}
 	networkList := options.Network.Build()
 	client, err := hysteria2.NewClient(hysteria2.ClientOptions{
 		Context:            ctx,
@@ -64,7 +85,7 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 		Logger:             logger,
 		BrutalDebug:        options.BrutalDebug,
 		ServerAddress:      options.ServerOptions.Build(),
-		SendBPS:            uint64(options.UpMbps * hysteria.MbpsToBps),
+		SendBPS:            sendBPS,
 		ReceiveBPS:         uint64(options.DownMbps * hysteria.MbpsToBps),
 		SalamanderPassword: salamanderPassword,
 		Password:           options.Password,
@@ -82,6 +103,7 @@ func NewHysteria2(ctx context.Context, router adapter.Router, logger log.Context
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		client: client,
 	}, nil
@@ -0,0 +1,358 @@
+package outbound
+
+import (
+	"context"
+	"hash/fnv"
+	"net"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/outboundprovider/filter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound                       = (*LoadBalance)(nil)
+	_ adapter.OutboundGroup                  = (*LoadBalance)(nil)
+	_ adapter.OutboundProviderUpdateListener = (*LoadBalance)(nil)
+)
+
+// loadBalanceVirtualNodes is the number of points each member takes on the
+// consistent hash ring.
+const loadBalanceVirtualNodes = 64
+
+type LoadBalance struct {
+	myOutboundAdapter
+	ctx       context.Context
+	tags      []string
+	providers []providerOutbound
+	strategy  string
+	members   atomic.Pointer[loadBalanceMembers]
+	next      atomic.Uint32
+}
+
+// loadBalanceMembers is replaced as a whole when a provider updates, so a
+// connection always picks from a consistent set.
+type loadBalanceMembers struct {
+	outbounds    []adapter.Outbound
+	outboundTags []string
+	connections  []atomic.Int32
+	ring         []loadBalanceNode
+	last         atomic.Int32
+}
+
+type loadBalanceNode struct {
+	hash  uint32
+	index int
+}
+
+func NewLoadBalance(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.LoadBalanceOutboundOptions) (*LoadBalance, error) {
+	outbound := &LoadBalance{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeLoadBalance,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		ctx:      ctx,
+		tags:     options.Outbounds,
+		strategy: options.Strategy,
+	}
+	switch outbound.strategy {
+	case "":
+		outbound.strategy = C.LoadBalanceStrategyRoundRobin
+	case C.LoadBalanceStrategyRoundRobin, C.LoadBalanceStrategyLeastConnections, C.LoadBalanceStrategyConsistentHashing:
+	default:
+		return nil, E.New("unknown load balance strategy: ", options.Strategy)
+	}
+	if len(options.Providers) > 0 {
+		outbound.providers = make([]providerOutbound, 0, len(options.Providers))
+		for i, provider := range options.Providers {
+			if provider.Tag == "" {
+				return nil, E.New("missing provider tag[", i, "]")
+			}
+			f, err := filter.NewOutboundFilter(provider.OutboundFilterOptions)
+			if err != nil {
+				return nil, E.Cause(err, "parse filter[", i, "]")
+			}
+			outbound.providers = append(outbound.providers, providerOutbound{
+				providerTag: provider.Tag,
+				filter:      f,
+			})
+		}
+	}
+	if len(outbound.tags) == 0 && len(outbound.providers) == 0 {
+		return nil, E.New("missing tags and providers")
+	}
+	return outbound, nil
+}
+
+func (s *LoadBalance) Dependencies() []string {
+	dependencies := make([]string, 0, len(s.tags)+len(s.providers))
+	dependencies = append(dependencies, s.dependencies...)
+	for _, provider := range s.providers {
+		dependencies = append(dependencies, provider.providerTag)
+	}
+	return dependencies
+}
+
+func (s *LoadBalance) Start() error {
+	members, err := s.resolveMembers()
+	if err != nil {
+		return err
+	}
+	s.members.Store(members)
+	return nil
+}
+
+func (s *LoadBalance) resolveMembers() (*loadBalanceMembers, error) {
+	members := &loadBalanceMembers{}
+	outboundByTag := make(map[string]bool)
+	addOutbound := func(detour adapter.Outbound) error {
+		if outboundByTag[detour.Tag()] {
+			return E.New("duplicate outbound: ", detour.Tag())
+		}
+		outboundByTag[detour.Tag()] = true
+		members.outbounds = append(members.outbounds, detour)
+		members.outboundTags = append(members.outboundTags, detour.Tag())
+		return nil
+	}
+	for i, tag := range s.tags {
+		detour, loaded := s.router.Outbound(tag)
+		if !loaded {
+			return nil, E.New("outbound ", i, " not found: ", tag)
+		}
+		err := addOutbound(detour)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i, p := range s.providers {
+		provider, loaded := s.router.OutboundProvider(p.providerTag)
+		if !loaded {
+			return nil, E.New("outbound provider[", i, "] provider not found: ", p.providerTag)
+		}
+		for _, detour := range provider.BasicOutbounds() {
+			if p.filter.MatchOutbound(detour) {
+				err := addOutbound(detour)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+		for _, detour := range provider.GroupOutbounds() {
+			if p.filter.MatchOutbound(detour) {
+				err := addOutbound(detour)
+				if err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if len(members.outbounds) == 0 {
+		return nil, E.New("missing outbounds")
+	}
+	members.connections = make([]atomic.Int32, len(members.outbounds))
+	if s.strategy == C.LoadBalanceStrategyConsistentHashing {
+		members.ring = newLoadBalanceRing(members.outboundTags)
+	}
+	return members, nil
+}
+
+// OutboundProviderUpdated replaces the members taken from the provider.
+// Counters of connections picked before are not carried over.
+func (s *LoadBalance) OutboundProviderUpdated(tag string) error {
+	if !common.Any(s.providers, func(it providerOutbound) bool {
+		return it.providerTag == tag
+	}) {
+		return nil
+	}
+	members, err := s.resolveMembers()
+	if err != nil {
+		return err
+	}
+	s.members.Store(members)
+	return nil
+}
+
+func newLoadBalanceRing(tags []string) []loadBalanceNode {
+	ring := make([]loadBalanceNode, 0, len(tags)*loadBalanceVirtualNodes)
+	for index, tag := range tags {
+		for i := 0; i < loadBalanceVirtualNodes; i++ {
+			ring = append(ring, loadBalanceNode{
+				hash:  loadBalanceHash(tag + "#" + strconv.Itoa(i)),
+				index: index,
+			})
+		}
+	}
+	sort.Slice(ring, func(i, j int) bool {
+		return ring[i].hash < ring[j].hash
+	})
+	return ring
+}
+
+func loadBalanceHash(key string) uint32 {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	return hash.Sum32()
+}
+
+func (s *LoadBalance) Now() string {
+	members := s.members.Load()
+	return members.outboundTags[members.last.Load()]
+}
+
+func (s *LoadBalance) All() []string {
+	return s.members.Load().outboundTags
+}
+
+// pick selects the member for a connection to destination over network,
+// skipping members that do not support the network.
+func (s *LoadBalance) pick(members *loadBalanceMembers, network string, destination M.Socksaddr) (int, error) {
+	supported := func(index int) bool {
+		return common.Contains(members.outbounds[index].Network(), network)
+	}
+	selected := -1
+	switch s.strategy {
+	case C.LoadBalanceStrategyRoundRobin:
+		start := int((s.next.Add(1) - 1) % uint32(len(members.outbounds)))
+		for i := 0; i < len(members.outbounds); i++ {
+			index := (start + i) % len(members.outbounds)
+			if supported(index) {
+				selected = index
+				break
+			}
+		}
+	case C.LoadBalanceStrategyLeastConnections:
+		var least int32
+		for index := range members.outbounds {
+			if !supported(index) {
+				continue
+			}
+			connections := members.connections[index].Load()
+			if selected == -1 || connections < least {
+				selected = index
+				least = connections
+			}
+		}
+	case C.LoadBalanceStrategyConsistentHashing:
+		var key string
+		if destination.IsFqdn() {
+			key = destination.Fqdn
+		} else {
+			key = destination.Addr.Unmap().String()
+		}
+		hash := loadBalanceHash(key)
+		start := sort.Search(len(members.ring), func(i int) bool {
+			return members.ring[i].hash >= hash
+		})
+		for i := 0; i < len(members.ring); i++ {
+			node := members.ring[(start+i)%len(members.ring)]
+			if supported(node.index) {
+				selected = node.index
+				break
+			}
+		}
+	}
+	if selected == -1 {
+		return -1, E.New("missing supported outbound for network: ", network)
+	}
+	members.last.Store(int32(selected))
+	return selected, nil
+}
+
+func (s *LoadBalance) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	members := s.members.Load()
+	index, err := s.pick(members, N.NetworkName(network), destination)
+	if err != nil {
+		return nil, err
+	}
+	members.connections[index].Add(1)
+	conn, err := members.outbounds[index].DialContext(ctx, network, destination)
+	if err != nil {
+		members.connections[index].Add(-1)
+		return nil, err
+	}
+	return &loadBalanceConn{Conn: conn, connections: &members.connections[index]}, nil
+}
+
+func (s *LoadBalance) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	members := s.members.Load()
+	index, err := s.pick(members, N.NetworkUDP, destination)
+	if err != nil {
+		return nil, err
+	}
+	members.connections[index].Add(1)
+	conn, err := members.outbounds[index].ListenPacket(ctx, destination)
+	if err != nil {
+		members.connections[index].Add(-1)
+		return nil, err
+	}
+	return &loadBalancePacketConn{PacketConn: conn, connections: &members.connections[index]}, nil
+}
+
+func (s *LoadBalance) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	members := s.members.Load()
+	index, err := s.pick(members, N.NetworkTCP, metadata.Destination)
+	if err != nil {
+		return err
+	}
+	members.connections[index].Add(1)
+	defer members.connections[index].Add(-1)
+	return members.outbounds[index].NewConnection(ctx, conn, metadata)
+}
+
+func (s *LoadBalance) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	members := s.members.Load()
+	index, err := s.pick(members, N.NetworkUDP, metadata.Destination)
+	if err != nil {
+		return err
+	}
+	members.connections[index].Add(1)
+	defer members.connections[index].Add(-1)
+	return members.outbounds[index].NewPacketConnection(ctx, conn, metadata)
+}
+
+type loadBalanceConn struct {
+	net.Conn
+	connections *atomic.Int32
+	closed      atomic.Bool
+}
+
+func (c *loadBalanceConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.connections.Add(-1)
+	}
+	return c.Conn.Close()
+}
+
+func (c *loadBalanceConn) Upstream() any {
+	return c.Conn
+}
+
+type loadBalancePacketConn struct {
+	net.PacketConn
+	connections *atomic.Int32
+	closed      atomic.Bool
+}
+
+func (c *loadBalancePacketConn) Close() error {
+	if c.closed.CompareAndSwap(false, true) {
+		c.connections.Add(-1)
+	}
+	return c.PacketConn.Close()
+}
+
+func (c *loadBalancePacketConn) Upstream() any {
+	return c.PacketConn
+}
@@ -5,21 +5,30 @@ package outbound
 import (
 	"bytes"
 	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
-	"regexp"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/sagernet/fswatch"
 	"github.com/sagernet/quic-go"
 	"github.com/sagernet/quic-go/http3"
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/compress"
 	"github.com/sagernet/sing-box/common/dialer"
+	"github.com/sagernet/sing-box/common/humanize"
 	"github.com/sagernet/sing-box/common/outboundprovider/action"
 	"github.com/sagernet/sing-box/common/proxyparser"
 	"github.com/sagernet/sing-box/common/taskmonitor"
@@ -30,6 +39,7 @@ import (
 	"github.com/sagernet/sing/common/bufio"
 	E "github.com/sagernet/sing/common/exceptions"
 	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/json"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/service"
@@ -39,11 +49,7 @@ const (
 	defaultRequestTimeout = 30 * time.Second
 )
 
-var (
-	defaultUserAgent = ""
-	regTraffic       *regexp.Regexp
-	regExpire        *regexp.Regexp
-)
+var defaultUserAgent = ""
 
 func init() {
 	defaultUserAgent = fmt.Sprintf(
@@ -55,8 +61,6 @@ func init() {
 		C.Version,
 		C.Version,
 	)
-	regTraffic = regexp.MustCompile(`upload=(\d+); download=(\d+); total=(\d+)`)
-	regExpire = regexp.MustCompile(`expire=(\d+)`)
 }
 
 var (
@@ -71,7 +75,11 @@ type Provider struct {
 	logFactory       log.Factory
 	logger           log.ContextLogger
 	tag              string
+	source           string
 	url              string
+	path             string
+	command          []string
+	watcher          *fswatch.Watcher
 	cache_tag        string
 	http3            bool
 	updateInterval   time.Duration
@@ -79,6 +87,7 @@ type Provider struct {
 	headers          http.Header
 	dialer           N.Dialer
 	dialerDetour     string
+	overrideDetour   string
 	httpClient       *http.Client
 	httpTransport    http.RoundTripper
 	selectorOptions  option.SelectorOutboundOptions
@@ -89,8 +98,10 @@ type Provider struct {
 	outboundMap      map[string]adapter.Outbound
 	groupOutbounds   []adapter.OutboundGroup
 	groupOutboundMap map[string]adapter.OutboundGroup
-	globalOutbound   *Selector
-	providerInfo     *adapter.OutboundProviderInfo
+	identities       map[string]string
+	outboundOptions  map[string]option.Outbound
+	globalOutbound   atomic.Pointer[Selector]
+	providerInfo     atomic.Pointer[adapter.OutboundProviderInfo]
 	loopUpdateCancel context.CancelFunc
 	startOnce        sync.Once
 	startErr         error
@@ -105,10 +116,28 @@ func NewProvider(ctx context.Context, router adapter.Router, logFactory log.Fact
 		tag:        tag,
 		cache_tag:  tag,
 	}
-	if options.URL == "" {
-		return nil, E.New("missing url")
+	switch options.Source {
+	case "", C.ProviderSourceHTTP:
+		if options.URL == "" {
+			return nil, E.New("missing url")
+		}
+		outbound.source = C.ProviderSourceHTTP
+		outbound.url = options.URL
+	case C.ProviderSourceFile:
+		if options.Path == "" {
+			return nil, E.New("missing path")
+		}
+		outbound.source = C.ProviderSourceFile
+		outbound.path, _ = filepath.Abs(options.Path)
+	case C.ProviderSourceExec:
+		if len(options.Command) == 0 {
+			return nil, E.New("missing command")
+		}
+		outbound.source = C.ProviderSourceExec
+		outbound.command = options.Command
+	default:
+		return nil, E.New("unknown provider source: ", options.Source)
 	}
-	outbound.url = options.URL
 	if options.CacheTag != "" {
 		outbound.cache_tag = options.CacheTag
 	}
@@ -133,12 +162,12 @@ func NewProvider(ctx context.Context, router adapter.Router, logFactory log.Fact
 		outbound.headers.Set(k, v)
 	}
 	outbound.selectorOptions = options.SelectorOptions
-	if len(options.Actions) > 0 {
-		group, err := action.NewProviderActionGroup(options.Actions)
-		if err != nil {
-			return nil, err
-		}
-		outbound.actionGroup = group
+	outbound.actionGroup, err = action.NewProviderActionGroup(options)
+	if err != nil {
+		return nil, err
+	}
+	if options.Override != nil {
+		outbound.overrideDetour = options.Override.Detour
 	}
 	err = router.RegisterOutboundProvider(tag, outbound)
 	if err != nil {
@@ -183,25 +212,63 @@ func (p *Provider) requestHTTP(ctx context.Context) (http.Header, []byte, error)
 			p.httpTransport = tr
 		}
 	}
+	compress.SetAcceptEncoding(req)
 	resp, err := httpClient.Do(req)
 	if err != nil {
 		return nil, nil, err
 	}
 	defer resp.Body.Close()
+	body, err := compress.DecodeResponse(resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
 	buffer := bytes.NewBuffer(nil)
-	_, err = io.Copy(buffer, resp.Body)
+	_, err = io.Copy(buffer, body)
 	if err != nil {
 		return nil, nil, err
 	}
 	return resp.Header, buffer.Bytes(), nil
 }
 
+// runCommand returns the stdout of the configured command.
+func (p *Provider) runCommand(ctx context.Context) ([]byte, error) {
+	if p.requestTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.requestTimeout)
+		defer cancel()
+	}
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.command[0], p.command[1:]...)
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, E.Cause(err, "run command: ", message)
+		}
+		return nil, E.Cause(err, "run command")
+	}
+	return output, nil
+}
+
 func (p *Provider) fetch(ctx context.Context) (*adapter.OutboundProviderInfo, error) {
-	headers, data, err := p.requestHTTP(ctx)
+	var (
+		headers = make(http.Header)
+		data    []byte
+		err     error
+	)
+	switch p.source {
+	case C.ProviderSourceFile:
+		data, err = os.ReadFile(p.path)
+	case C.ProviderSourceExec:
+		data, err = p.runCommand(ctx)
+	default:
+		headers, data, err = p.requestHTTP(ctx)
+	}
 	if err != nil {
 		return nil, err
 	}
-	outbounds, err := proxyparser.ParseOutbound(data)
+	outbounds, err := proxyparser.ParseOutbound(data, headers.Get("Content-Type"))
 	if err != nil {
 		return nil, err
 	}
@@ -211,36 +278,61 @@ func (p *Provider) fetch(ctx context.Context) (*adapter.OutboundProviderInfo, er
 	}
 	subscriptionUserInfo := headers.Get("subscription-userinfo")
 	if subscriptionUserInfo != "" {
-		subscriptionUserInfo = strings.ToLower(subscriptionUserInfo)
-		matchTraffic := regTraffic.FindStringSubmatch(subscriptionUserInfo)
-		if len(matchTraffic) == 4 {
-			uploadUint64, err := strconv.ParseUint(matchTraffic[1], 10, 64)
-			if err == nil {
-				info.Upload = uploadUint64
-			}
-			downloadUint64, err := strconv.ParseUint(matchTraffic[2], 10, 64)
-			if err == nil {
-				info.Download = downloadUint64
-			}
-			totalUint64, err := strconv.ParseUint(matchTraffic[3], 10, 64)
-			if err == nil {
-				info.Total = totalUint64
-			}
+		parseSubscriptionUserInfo(subscriptionUserInfo, info)
+		p.logger.Info("subscription: ", formatSubscriptionUserInfo(info))
+	}
+	return info, nil
+}
+
+// parseSubscriptionUserInfo reads the traffic and expiry of a subscription
+// from the `upload=...; download=...; total=...; expire=...` header, whose
+// fields may come in any order or be missing.
+func parseSubscriptionUserInfo(header string, info *adapter.OutboundProviderInfo) {
+	for _, field := range strings.Split(header, ";") {
+		key, value, found := strings.Cut(field, "=")
+		if !found {
+			continue
+		}
+		// some providers send fractional byte counts
+		number, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || number < 0 {
+			continue
 		}
-		matchExpire := regExpire.FindStringSubmatch(subscriptionUserInfo)
-		if len(matchExpire) == 2 {
-			expireUint64, err := strconv.ParseUint(matchExpire[1], 10, 64)
-			if err == nil {
-				info.Expired = time.Unix(int64(expireUint64), 0)
+		switch strings.ToLower(strings.TrimSpace(key)) {
+		case "upload":
+			info.Upload = uint64(number)
+		case "download":
+			info.Download = uint64(number)
+		case "total":
+			info.Total = uint64(number)
+		case "expire":
+			if number > 0 {
+				info.Expired = time.Unix(int64(number), 0)
 			}
 		}
 	}
-	return info, nil
+}
+
+func formatSubscriptionUserInfo(info *adapter.OutboundProviderInfo) string {
+	message := F.ToString("upload ", humanize.IBytes(info.Upload), ", download ", humanize.IBytes(info.Download))
+	if info.Total > 0 {
+		used := info.Upload + info.Download
+		var remaining uint64
+		if used < info.Total {
+			remaining = info.Total - used
+		}
+		message += F.ToString(", total ", humanize.IBytes(info.Total), ", remaining ", humanize.IBytes(remaining))
+	}
+	if !info.Expired.IsZero() {
+		message += ", expire " + info.Expired.Format(time.DateOnly)
+	}
+	return message
 }
 
 func (p *Provider) loadOrfetchInfo(ctx context.Context) (*adapter.OutboundProviderInfo, error) {
 	cacheFile := service.FromContext[adapter.CacheFile](p.ctx)
-	if cacheFile != nil {
+	// a local file is always read again, as it may have changed while stopped
+	if cacheFile != nil && p.source != C.ProviderSourceFile {
 		info := cacheFile.LoadOutboundProviderInfo(p.cache_tag)
 		if info != nil && (p.updateInterval == 0 || time.Since(info.LastUpdated) < p.updateInterval) {
 			return info, nil
@@ -262,12 +354,19 @@ func (p *Provider) update(ctx context.Context) error {
 	info, err := p.loadOrfetchInfo(ctx)
 	if err != nil {
 		p.logger.Error("failed to update outbound info: ", err)
-	} else {
-		p.logger.Info("outbound info updated")
-		info.Outbounds = nil
-		p.providerInfo = info
+		return err
 	}
-	return err
+	if p.globalOutbound.Load() != nil {
+		err = p.reload(info)
+		if err != nil {
+			p.logger.Error("failed to update outbounds: ", err)
+			return err
+		}
+	}
+	p.logger.Info("outbound info updated")
+	info.Outbounds = nil
+	p.providerInfo.Store(info)
+	return nil
 }
 
 func (p *Provider) loopUpdate(ctx context.Context, interval time.Duration) {
@@ -291,7 +390,7 @@ func (p *Provider) Update(ctx context.Context) error {
 }
 
 func (p *Provider) ProviderInfo() *adapter.OutboundProviderInfo {
-	return p.providerInfo
+	return p.providerInfo.Load()
 }
 
 func (p *Provider) start() error {
@@ -304,31 +403,19 @@ func (p *Provider) start() error {
 	}
 	p.logger.Debug("outbound info loaded")
 
-	outboundPtrs := make([]*option.Outbound, 0, len(info.Outbounds))
-	for i := range info.Outbounds {
-		outboundPtrs = append(outboundPtrs, &info.Outbounds[i])
-	}
-	var (
-		outboundOptions      []*option.Outbound
-		groupOutboundOptions []*option.Outbound
-	)
-	if p.actionGroup != nil {
-		p.logger.Debug("execute outbound actions")
-		groupContext, err := p.actionGroup.Execute(p.ctx, p.router, p.logger, outboundPtrs)
-		if err != nil {
-			return err
-		}
-		p.logger.Debug("outbound actions executed")
-		outboundOptions = groupContext.Outbounds()
-		groupOutboundOptions = groupContext.GroupOutbounds()
-	} else {
-		outboundOptions = outboundPtrs
+	outboundOptions, groupOutboundOptions, identities, err := p.prepareOutbounds(info)
+	if err != nil {
+		return err
 	}
 
 	// Create Outbound
 	globalOutboundTags := make([]string, 0, len(outboundOptions)+len(groupOutboundOptions))
 	p.outbounds = make([]adapter.Outbound, 0, len(outboundOptions))
 	p.outboundMap = make(map[string]adapter.Outbound)
+	p.outboundOptions = make(map[string]option.Outbound, len(outboundOptions)+len(groupOutboundOptions))
+	p.locker.Lock()
+	p.identities = identities
+	p.locker.Unlock()
 	for i, opt := range outboundOptions {
 		out, err := New(p.ctx, p.router, p.logFactory, p.logFactory.NewLogger(F.ToString("outbound/", opt.Type, "[", opt.Tag, "]")), opt.Tag, *opt)
 		if err != nil {
@@ -336,6 +423,7 @@ func (p *Provider) start() error {
 		}
 		p.outbounds = append(p.outbounds, out)
 		p.outboundMap[out.Tag()] = out
+		p.outboundOptions[out.Tag()] = *opt
 		globalOutboundTags = append(globalOutboundTags, out.Tag())
 	}
 	if len(groupOutboundOptions) > 0 {
@@ -348,14 +436,13 @@ func (p *Provider) start() error {
 			}
 			p.groupOutbounds = append(p.groupOutbounds, out.(adapter.OutboundGroup))
 			p.groupOutboundMap[out.Tag()] = out.(adapter.OutboundGroup)
+			p.outboundOptions[out.Tag()] = *opt
 			globalOutboundTags = append(globalOutboundTags, out.Tag())
 		}
 	}
-	globalOutboundOptions := p.selectorOptions
-	globalOutboundOptions.Outbounds = append(globalOutboundOptions.Outbounds, globalOutboundTags...)
-	p.globalOutbound, err = NewSelector(p.ctx, p.router, p.logger, p.tag, globalOutboundOptions)
+	globalOutbound, err := p.newGlobalOutbound(p.router, globalOutboundTags)
 	if err != nil {
-		return E.Cause(err, "parse global outbound")
+		return err
 	}
 	err = p.router.CheckOutboundProvider(p.tag)
 	if err != nil {
@@ -400,18 +487,255 @@ func (p *Provider) start() error {
 			}
 		}(out)
 	}
-	err = p.globalOutbound.Start()
+	err = globalOutbound.Start()
 	if err != nil {
 		failedTag = true
 		return E.Cause(err, "initialize global outbound")
 	}
+	p.globalOutbound.Store(globalOutbound)
 
 	info.Outbounds = nil
-	p.providerInfo = info
+	p.providerInfo.Store(info)
+
+	return nil
+}
+
+// prepareOutbounds splits the parsed outbounds into basic and group ones and
+// applies the configured actions.
+func (p *Provider) prepareOutbounds(info *adapter.OutboundProviderInfo) ([]*option.Outbound, []*option.Outbound, map[string]string, error) {
+	var (
+		outboundPtrs      []*option.Outbound
+		groupOutboundPtrs []*option.Outbound
+	)
+	identityByOptions := make(map[*option.Outbound]string, len(info.Outbounds))
+	for i := range info.Outbounds {
+		switch info.Outbounds[i].Type {
+		case C.TypeSelector, C.TypeURLTest, C.TypeLoadBalance, C.TypeFallback, C.TypeRelay:
+			groupOutboundPtrs = append(groupOutboundPtrs, &info.Outbounds[i])
+		default:
+			outboundPtrs = append(outboundPtrs, &info.Outbounds[i])
+			identityByOptions[&info.Outbounds[i]] = outboundIdentity(&info.Outbounds[i])
+		}
+	}
+	p.logger.Debug("execute outbound actions")
+	groupContext, err := p.actionGroup.Execute(p.ctx, p.router, p.logger, outboundPtrs, groupOutboundPtrs)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	p.logger.Debug("outbound actions executed")
+	outboundOptions := groupContext.Outbounds()
+	groupOutboundOptions := groupContext.GroupOutbounds()
+	identities := make(map[string]string, len(outboundOptions))
+	for _, opt := range outboundOptions {
+		identities[opt.Tag] = identityByOptions[opt]
+	}
+	return outboundOptions, groupOutboundOptions, identities, nil
+}
+
+func (p *Provider) newGlobalOutbound(router adapter.Router, outboundTags []string) (*Selector, error) {
+	globalOutboundOptions := p.selectorOptions
+	globalOutboundOptions.Outbounds = append(slices.Clone(globalOutboundOptions.Outbounds), outboundTags...)
+	globalOutbound, err := NewSelector(p.ctx, router, p.logger, p.tag, globalOutboundOptions)
+	if err != nil {
+		return nil, E.Cause(err, "parse global outbound")
+	}
+	return globalOutbound, nil
+}
+
+// reload applies updated outbound info to the started provider. Only the
+// outbounds whose options changed, along with groups depending on them, are
+// replaced; unchanged ones and their connections are kept.
+func (p *Provider) reload(info *adapter.OutboundProviderInfo) error {
+	if len(info.Outbounds) == 0 {
+		return E.New("missing outbound")
+	}
+	outboundOptions, groupOutboundOptions, identities, err := p.prepareOutbounds(info)
+	if err != nil {
+		return err
+	}
 
+	p.locker.RLock()
+	currentOutbounds := make(map[string]adapter.Outbound, len(p.outbounds)+len(p.groupOutbounds))
+	for _, out := range p.outbounds {
+		currentOutbounds[out.Tag()] = out
+	}
+	for _, out := range p.groupOutbounds {
+		currentOutbounds[out.Tag()] = out
+	}
+	currentOutboundOptions := p.outboundOptions
+	currentIdentities := p.identities
+	p.locker.RUnlock()
+
+	replaced := make(map[string]bool)
+	for tag := range currentOutbounds {
+		replaced[tag] = true
+	}
+	newOutboundOptions := make(map[string]option.Outbound, len(outboundOptions)+len(groupOutboundOptions))
+	for _, opt := range append(slices.Clone(outboundOptions), groupOutboundOptions...) {
+		if _, exists := newOutboundOptions[opt.Tag]; exists {
+			return E.New("duplicate outbound: ", opt.Tag)
+		}
+		newOutboundOptions[opt.Tag] = *opt
+		if current, loaded := currentOutboundOptions[opt.Tag]; loaded && outboundOptionsEqual(&current, opt) {
+			delete(replaced, opt.Tag)
+		} else if !loaded {
+			if _, loaded = p.router.Outbound(opt.Tag); loaded {
+				return E.New("duplicate outbound: ", opt.Tag)
+			}
+		}
+	}
+	// groups resolve their members when started, so they must be recreated
+	// along with them
+	for {
+		var changed bool
+		for tag, current := range currentOutbounds {
+			if replaced[tag] {
+				continue
+			}
+			if common.Any(current.Dependencies(), func(dependency string) bool {
+				return replaced[dependency]
+			}) {
+				replaced[tag] = true
+				changed = true
+			}
+		}
+		if !changed {
+			break
+		}
+	}
+
+	stagingRouter := &providerRouter{
+		Router:        p.router,
+		outboundByTag: make(map[string]adapter.Outbound),
+	}
+	var createdOutbounds []adapter.Outbound
+	newOutbound := func(opt *option.Outbound) (adapter.Outbound, error) {
+		if current, loaded := currentOutbounds[opt.Tag]; loaded && !replaced[opt.Tag] {
+			stagingRouter.outboundByTag[opt.Tag] = current
+			return current, nil
+		}
+		out, err := New(p.ctx, stagingRouter, p.logFactory, p.logFactory.NewLogger(F.ToString("outbound/", opt.Type, "[", opt.Tag, "]")), opt.Tag, *opt)
+		if err != nil {
+			return nil, err
+		}
+		createdOutbounds = append(createdOutbounds, out)
+		stagingRouter.outboundByTag[opt.Tag] = out
+		return out, nil
+	}
+	globalOutboundTags := make([]string, 0, len(outboundOptions)+len(groupOutboundOptions))
+	outbounds := make([]adapter.Outbound, 0, len(outboundOptions))
+	outboundMap := make(map[string]adapter.Outbound, len(outboundOptions))
+	for i, opt := range outboundOptions {
+		out, err := newOutbound(opt)
+		if err != nil {
+			closeOutbounds(createdOutbounds)
+			return E.Cause(err, "parse outbound[", i, "] [", opt.Tag, "]")
+		}
+		outbounds = append(outbounds, out)
+		outboundMap[out.Tag()] = out
+		globalOutboundTags = append(globalOutboundTags, out.Tag())
+	}
+	groupOutbounds := make([]adapter.OutboundGroup, 0, len(groupOutboundOptions))
+	groupOutboundMap := make(map[string]adapter.OutboundGroup, len(groupOutboundOptions))
+	for i, opt := range groupOutboundOptions {
+		out, err := newOutbound(opt)
+		if err != nil {
+			closeOutbounds(createdOutbounds)
+			return E.Cause(err, "parse group outbound[", i, "] [", opt.Tag, "]")
+		}
+		groupOutbounds = append(groupOutbounds, out.(adapter.OutboundGroup))
+		groupOutboundMap[out.Tag()] = out.(adapter.OutboundGroup)
+		globalOutboundTags = append(globalOutboundTags, out.Tag())
+	}
+	globalOutbound, err := p.newGlobalOutbound(stagingRouter, globalOutboundTags)
+	if err != nil {
+		closeOutbounds(createdOutbounds)
+		return err
+	}
+	for _, out := range createdOutbounds {
+		if starter, isStarter := out.(common.Starter); isStarter {
+			err = starter.Start()
+			if err != nil {
+				closeOutbounds(createdOutbounds)
+				return E.Cause(err, "initialize outbound/", out.Type(), "[", out.Tag(), "]")
+			}
+		}
+	}
+	// the global selector restores its selection by the identities of the
+	// new outbounds
+	p.locker.Lock()
+	p.identities = identities
+	p.locker.Unlock()
+	err = globalOutbound.Start()
+	if err != nil {
+		p.locker.Lock()
+		p.identities = currentIdentities
+		p.locker.Unlock()
+		closeOutbounds(createdOutbounds)
+		return E.Cause(err, "initialize global outbound")
+	}
+	for _, out := range createdOutbounds {
+		if postStarter, isPostStarter := out.(adapter.PostStarter); isPostStarter {
+			err = postStarter.PostStart()
+			if err != nil {
+				p.logger.Error(E.Cause(err, "post-start outbound/", out.Type(), "[", out.Tag(), "]"))
+			}
+		}
+	}
+
+	p.locker.Lock()
+	p.outbounds = outbounds
+	p.outboundMap = outboundMap
+	p.groupOutbounds = groupOutbounds
+	p.groupOutboundMap = groupOutboundMap
+	p.outboundOptions = newOutboundOptions
+	p.locker.Unlock()
+	p.globalOutbound.Store(globalOutbound)
+	p.router.NotifyOutboundProviderUpdated(p.tag)
+
+	for tag := range replaced {
+		out := currentOutbounds[tag]
+		err = common.Close(out)
+		if err != nil {
+			p.logger.Error(E.Cause(err, "close outbound/", out.Type(), "[", tag, "]"))
+		}
+	}
+	p.logger.Info("outbounds updated: ", len(createdOutbounds), " created, ", len(replaced), " closed")
 	return nil
 }
 
+func outboundOptionsEqual(current *option.Outbound, next *option.Outbound) bool {
+	currentContent, err := json.Marshal(current)
+	if err != nil {
+		return false
+	}
+	nextContent, err := json.Marshal(next)
+	if err != nil {
+		return false
+	}
+	return bytes.Equal(currentContent, nextContent)
+}
+
+func closeOutbounds(outbounds []adapter.Outbound) {
+	for _, out := range outbounds {
+		common.Close(out)
+	}
+}
+
+// providerRouter lets outbounds created by an update find each other before
+// the provider publishes them.
+type providerRouter struct {
+	adapter.Router
+	outboundByTag map[string]adapter.Outbound
+}
+
+func (r *providerRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	if outbound, loaded := r.outboundByTag[tag]; loaded {
+		return outbound, true
+	}
+	return r.Router.Outbound(tag)
+}
+
 func (p *Provider) lazyStart() error {
 	p.startOnce.Do(func() {
 		p.startErr = p.start()
@@ -450,6 +774,22 @@ func (p *Provider) PostStart() error {
 		loopUpdateCtx, p.loopUpdateCancel = context.WithCancel(p.ctx)
 		go p.loopUpdate(loopUpdateCtx, p.updateInterval)
 	}
+	if p.source == C.ProviderSourceFile {
+		watcher, err := fswatch.NewWatcher(fswatch.Options{
+			Path: []string{p.path},
+			Callback: func(path string) {
+				p.update(p.ctx)
+			},
+		})
+		if err != nil {
+			return err
+		}
+		err = watcher.Start()
+		if err != nil {
+			p.logger.Error(E.Cause(err, "watch outbound provider file"))
+		}
+		p.watcher = watcher
+	}
 
 	return nil
 }
@@ -459,6 +799,10 @@ func (p *Provider) Close() error {
 		p.loopUpdateCancel()
 		p.loopUpdateCancel = nil
 	}
+	if p.watcher != nil {
+		p.watcher.Close()
+		p.watcher = nil
+	}
 	httpTr, ok := p.httpTransport.(*http.Transport)
 	if ok {
 		httpTr.CloseIdleConnections()
@@ -469,6 +813,8 @@ func (p *Provider) Close() error {
 	}
 
 	// Close Outbounds
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 	common.Close(p.groupOutbounds)
 	common.Close(p.outbounds)
 
@@ -486,18 +832,21 @@ func (p *Provider) Type() string {
 }
 
 func (p *Provider) Network() []string {
-	if p.globalOutbound != nil {
-		return p.globalOutbound.Network()
+	if globalOutbound := p.globalOutbound.Load(); globalOutbound != nil {
+		return globalOutbound.Network()
 	}
 	return []string{N.NetworkTCP, N.NetworkUDP}
 }
 
 func (p *Provider) Dependencies() []string {
+	dependencies := []string{}
 	if p.dialerDetour != "" {
-		return []string{p.dialerDetour}
-	} else {
-		return []string{}
+		dependencies = append(dependencies, p.dialerDetour)
 	}
+	if p.overrideDetour != "" && p.overrideDetour != p.dialerDetour {
+		dependencies = append(dependencies, p.overrideDetour)
+	}
+	return dependencies
 }
 
 func (p *Provider) DialContext(ctx context.Context, network string, address M.Socksaddr) (net.Conn, error) {
@@ -505,7 +854,7 @@ func (p *Provider) DialContext(ctx context.Context, network string, address M.So
 	if err != nil {
 		return nil, E.Cause(err, "failed to lazy start")
 	}
-	return p.globalOutbound.DialContext(ctx, network, address)
+	return p.globalOutbound.Load().DialContext(ctx, network, address)
 }
 
 func (p *Provider) ListenPacket(ctx context.Context, address M.Socksaddr) (net.PacketConn, error) {
@@ -513,7 +862,7 @@ func (p *Provider) ListenPacket(ctx context.Context, address M.Socksaddr) (net.P
 	if err != nil {
 		return nil, E.Cause(err, "failed to lazy start")
 	}
-	return p.globalOutbound.ListenPacket(ctx, address)
+	return p.globalOutbound.Load().ListenPacket(ctx, address)
 }
 
 func (p *Provider) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
@@ -521,7 +870,7 @@ func (p *Provider) NewConnection(ctx context.Context, conn net.Conn, metadata ad
 	if err != nil {
 		return E.Cause(err, "failed to lazy start")
 	}
-	return p.globalOutbound.NewConnection(ctx, conn, metadata)
+	return p.globalOutbound.Load().NewConnection(ctx, conn, metadata)
 }
 
 func (p *Provider) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
@@ -529,10 +878,12 @@ func (p *Provider) NewPacketConnection(ctx context.Context, conn N.PacketConn, m
 	if err != nil {
 		return E.Cause(err, "failed to lazy start")
 	}
-	return p.globalOutbound.NewPacketConnection(ctx, conn, metadata)
+	return p.globalOutbound.Load().NewPacketConnection(ctx, conn, metadata)
 }
 
 func (p *Provider) InterfaceUpdated() {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 	for _, outbound := range p.outbounds {
 		listener, ok := outbound.(adapter.InterfaceUpdateListener)
 		if ok {
@@ -554,7 +905,7 @@ func (p *Provider) All() []string {
 	if err != nil {
 		return []string{}
 	}
-	return p.globalOutbound.All()
+	return p.globalOutbound.Load().All()
 }
 
 func (p *Provider) Now() string {
@@ -562,12 +913,14 @@ func (p *Provider) Now() string {
 	if err != nil {
 		return ""
 	}
-	return p.globalOutbound.Now()
+	return p.globalOutbound.Load().Now()
 }
 
 // OutboundProvider
 
 func (p *Provider) Outbound(tag string) (adapter.Outbound, bool) {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 	outbound, loaded := p.outboundMap[tag]
 	if !loaded && p.groupOutboundMap != nil && len(p.groupOutboundMap) > 0 {
 		outbound, loaded = p.groupOutboundMap[tag]
@@ -575,10 +928,34 @@ func (p *Provider) Outbound(tag string) (adapter.Outbound, bool) {
 	return outbound, loaded
 }
 
+func (p *Provider) OutboundIdentity(tag string) string {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
+	return p.identities[tag]
+}
+
+// outboundIdentity identifies a subscription node by its original name and
+// server, which unlike its position and final tag are kept across updates.
+func outboundIdentity(options *option.Outbound) string {
+	var serverOptions option.ServerOptions
+	rawOptions, err := options.RawOptions()
+	if err == nil {
+		if wrapper, isWrapper := rawOptions.(option.ServerOptionsWrapper); isWrapper {
+			serverOptions = wrapper.TakeServerOptions()
+		}
+	}
+	hash := sha256.Sum256([]byte(options.Tag + "\x00" + serverOptions.Build().String()))
+	return hex.EncodeToString(hash[:8])
+}
+
 func (p *Provider) BasicOutbounds() []adapter.Outbound {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 	return p.outbounds
 }
 
 func (p *Provider) GroupOutbounds() []adapter.OutboundGroup {
+	p.locker.RLock()
+	defer p.locker.RUnlock()
 	return p.groupOutbounds
 }
@@ -0,0 +1,138 @@
+package outbound
+
+import (
+	"context"
+	"net"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	_ adapter.Outbound      = (*Relay)(nil)
+	_ adapter.OutboundGroup = (*Relay)(nil)
+)
+
+type Relay struct {
+	myOutboundAdapter
+	tags      []string
+	outbounds []adapter.Outbound
+}
+
+func NewRelay(router adapter.Router, logger log.ContextLogger, tag string, options option.RelayOutboundOptions) (*Relay, error) {
+	if len(options.Outbounds) == 0 {
+		return nil, E.New("missing outbounds")
+	}
+	return &Relay{
+		myOutboundAdapter: myOutboundAdapter{
+			protocol:     C.TypeRelay,
+			network:      []string{N.NetworkTCP, N.NetworkUDP},
+			router:       router,
+			logger:       logger,
+			tag:          tag,
+			dependencies: options.Outbounds,
+		},
+		tags: options.Outbounds,
+	}, nil
+}
+
+func (h *Relay) Start() error {
+	for i, tag := range h.tags {
+		detour, loaded := h.router.Outbound(tag)
+		if !loaded {
+			return E.New("outbound ", i, " not found: ", tag)
+		}
+		if i < len(h.tags)-1 && !common.Contains(detour.Network(), N.NetworkTCP) {
+			return E.New("outbound ", i, " does not support TCP: ", tag)
+		}
+		h.outbounds = append(h.outbounds, detour)
+	}
+	h.network = h.outbounds[len(h.outbounds)-1].Network()
+	return nil
+}
+
+func (h *Relay) Now() string {
+	return h.tags[len(h.tags)-1]
+}
+
+func (h *Relay) All() []string {
+	return h.tags
+}
+
+// chain returns a context that makes the last outbound dial through all
+// previous ones in order, and a function reporting whether every hop was
+// actually dialed through.
+func (h *Relay) chain(ctx context.Context) (context.Context, func() bool) {
+	hops := make([]*relayHop, 0, len(h.outbounds)-1)
+	var upstream N.Dialer
+	for _, detour := range h.outbounds[:len(h.outbounds)-1] {
+		hop := &relayHop{outbound: detour, upstream: upstream}
+		hops = append(hops, hop)
+		upstream = hop
+	}
+	return dialer.ContextWithRelay(ctx, upstream), func() bool {
+		return common.All(hops, func(it *relayHop) bool {
+			return it.used.Load()
+		})
+	}
+}
+
+func (h *Relay) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	ctx, relayed := h.chain(ctx)
+	detour := h.outbounds[len(h.outbounds)-1]
+	conn, err := detour.DialContext(ctx, network, destination)
+	if err != nil {
+		return nil, err
+	}
+	if !relayed() {
+		conn.Close()
+		return nil, E.New("outbound ", detour.Tag(), " does not support relay")
+	}
+	return conn, nil
+}
+
+func (h *Relay) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	ctx, relayed := h.chain(ctx)
+	detour := h.outbounds[len(h.outbounds)-1]
+	conn, err := detour.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	if !relayed() {
+		conn.Close()
+		return nil, E.New("outbound ", detour.Tag(), " does not support relay")
+	}
+	return conn, nil
+}
+
+func (h *Relay) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
+	return NewConnection(ctx, h, conn, metadata)
+}
+
+func (h *Relay) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
+	return NewPacketConnection(ctx, h, conn, metadata)
+}
+
+type relayHop struct {
+	outbound adapter.Outbound
+	upstream N.Dialer
+	used     atomic.Bool
+}
+
+func (h *relayHop) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
+	h.used.Store(true)
+	return h.outbound.DialContext(dialer.ContextWithRelay(ctx, h.upstream), network, destination)
+}
+
+func (h *relayHop) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
+	h.used.Store(true)
+	return h.outbound.ListenPacket(dialer.ContextWithRelay(ctx, h.upstream), destination)
+}
@@ -3,6 +3,8 @@ package outbound
 import (
 	"context"
 	"net"
+	"sync"
+	"sync/atomic"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/interrupt"
@@ -10,6 +12,7 @@ import (
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -17,8 +20,9 @@ import (
 )
 
 var (
-	_ adapter.Outbound      = (*Selector)(nil)
-	_ adapter.OutboundGroup = (*Selector)(nil)
+	_ adapter.Outbound                       = (*Selector)(nil)
+	_ adapter.OutboundGroup                  = (*Selector)(nil)
+	_ adapter.OutboundProviderUpdateListener = (*Selector)(nil)
 )
 
 type Selector struct {
@@ -27,11 +31,18 @@ type Selector struct {
 	tags                         []string
 	providers                    []providerOutbound
 	defaultTag                   string
-	outbounds                    map[string]adapter.Outbound
-	outboundTags                 []string
-	selected                     adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	access                       sync.Mutex
+	state                        atomic.Pointer[selectorState]
+}
+
+// selectorState is replaced as a whole when the selection or the members
+// change, so that connections never see a partial update.
+type selectorState struct {
+	outbounds    map[string]adapter.Outbound
+	outboundTags []string
+	selected     adapter.Outbound
 }
 
 func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SelectorOutboundOptions) (*Selector, error) {
@@ -46,7 +57,6 @@ func NewSelector(ctx context.Context, router adapter.Router, logger log.ContextL
 		ctx:                          ctx,
 		tags:                         options.Outbounds,
 		defaultTag:                   options.Default,
-		outbounds:                    make(map[string]adapter.Outbound),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: options.InterruptExistConnections,
 	}
@@ -82,103 +92,169 @@ func (s *Selector) Dependencies() []string {
 }
 
 func (s *Selector) Network() []string {
-	if s.selected == nil {
+	state := s.state.Load()
+	if state == nil {
 		return []string{N.NetworkTCP, N.NetworkUDP}
 	}
-	return s.selected.Network()
+	return state.selected.Network()
 }
 
 func (s *Selector) Start() error {
+	outbounds, outboundTags, err := s.resolveOutbounds()
+	if err != nil {
+		return err
+	}
+	selected, err := s.loadSelected(outbounds, outboundTags)
+	if err != nil {
+		return err
+	}
+	s.state.Store(&selectorState{outbounds, outboundTags, selected})
+	return nil
+}
+
+func (s *Selector) resolveOutbounds() (map[string]adapter.Outbound, []string, error) {
+	outbounds := make(map[string]adapter.Outbound)
 	outboundTags := make([]string, 0, len(s.tags))
 	for i, tag := range s.tags {
 		detour, loaded := s.router.Outbound(tag)
 		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
+			return nil, nil, E.New("outbound ", i, " not found: ", tag)
 		}
-		s.outbounds[tag] = detour
+		outbounds[tag] = detour
 		outboundTags = append(outboundTags, tag)
 	}
 
 	for i, p := range s.providers {
 		provider, loaded := s.router.OutboundProvider(p.providerTag)
 		if !loaded {
-			return E.New("outbound provider[", i, "] provider not found: ", p.providerTag)
+			return nil, nil, E.New("outbound provider[", i, "] provider not found: ", p.providerTag)
 		}
 		for _, outbound := range provider.BasicOutbounds() {
 			if p.filter.MatchOutbound(outbound) {
-				_, loaded := s.outbounds[outbound.Tag()]
+				_, loaded := outbounds[outbound.Tag()]
 				if loaded {
-					return E.New("duplicate outbound: ", outbound.Tag())
+					return nil, nil, E.New("duplicate outbound: ", outbound.Tag())
 				}
-				s.outbounds[outbound.Tag()] = outbound
+				outbounds[outbound.Tag()] = outbound
 				outboundTags = append(outboundTags, outbound.Tag())
 			}
 		}
 		for _, outbound := range provider.GroupOutbounds() {
 			if p.filter.MatchOutbound(outbound) {
-				_, loaded := s.outbounds[outbound.Tag()]
+				_, loaded := outbounds[outbound.Tag()]
 				if loaded {
-					return E.New("duplicate outbound: ", outbound.Tag())
+					return nil, nil, E.New("duplicate outbound: ", outbound.Tag())
 				}
-				s.outbounds[outbound.Tag()] = outbound
+				outbounds[outbound.Tag()] = outbound
 				outboundTags = append(outboundTags, outbound.Tag())
 			}
 		}
 	}
-	if len(s.outbounds) == 0 {
-		return E.New("missing outbounds")
+	if len(outbounds) == 0 {
+		return nil, nil, E.New("missing outbounds")
 	}
-	s.outboundTags = make([]string, len(s.outbounds))
-	copy(s.outboundTags, outboundTags)
+	return outbounds, outboundTags, nil
+}
 
+func (s *Selector) loadSelected(outbounds map[string]adapter.Outbound, outboundTags []string) (adapter.Outbound, error) {
 	if s.tag != "" {
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
+			if identity := cacheFile.LoadSelectedIdentity(s.tag); identity != "" {
+				for _, tag := range outboundTags {
+					if s.outboundIdentity(tag) == identity {
+						return outbounds[tag], nil
+					}
+				}
+			}
 			selected := cacheFile.LoadSelected(s.tag)
 			if selected != "" {
-				detour, loaded := s.outbounds[selected]
+				detour, loaded := outbounds[selected]
 				if loaded {
-					s.selected = detour
-					return nil
+					return detour, nil
 				}
 			}
 		}
 	}
 
 	if s.defaultTag != "" {
-		detour, loaded := s.outbounds[s.defaultTag]
+		detour, loaded := outbounds[s.defaultTag]
 		if !loaded {
-			return E.New("default outbound not found: ", s.defaultTag)
+			return nil, E.New("default outbound not found: ", s.defaultTag)
 		}
-		s.selected = detour
-		return nil
+		return detour, nil
 	}
 
-	s.selected = s.outbounds[s.outboundTags[0]]
+	return outbounds[outboundTags[0]], nil
+}
+
+// OutboundProviderUpdated refreshes the members taken from the provider,
+// keeping the selected tag if it is still provided.
+func (s *Selector) OutboundProviderUpdated(tag string) error {
+	if !common.Any(s.providers, func(it providerOutbound) bool {
+		return it.providerTag == tag
+	}) {
+		return nil
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	outbounds, outboundTags, err := s.resolveOutbounds()
+	if err != nil {
+		return err
+	}
+	oldState := s.state.Load()
+	selected, loaded := outbounds[oldState.selected.Tag()]
+	if !loaded {
+		selected, err = s.loadSelected(outbounds, outboundTags)
+		if err != nil {
+			selected = outbounds[outboundTags[0]]
+		}
+	}
+	s.state.Store(&selectorState{outbounds, outboundTags, selected})
+	if oldState.selected != selected {
+		s.interruptGroup.Interrupt(s.interruptExternalConnections)
+	}
 	return nil
 }
 
+// outboundIdentity returns the provider identity of a member, so that the
+// selection follows a provided outbound even if an update changes its tag.
+func (s *Selector) outboundIdentity(tag string) string {
+	for _, provider := range s.router.OutboundProviders() {
+		if identity := provider.OutboundIdentity(tag); identity != "" {
+			return identity
+		}
+	}
+	return ""
+}
+
 func (s *Selector) Now() string {
-	return s.selected.Tag()
+	return s.state.Load().selected.Tag()
 }
 
 func (s *Selector) All() []string {
-	return s.outboundTags
+	return s.state.Load().outboundTags
 }
 
 func (s *Selector) SelectOutbound(tag string) bool {
-	detour, loaded := s.outbounds[tag]
+	s.access.Lock()
+	defer s.access.Unlock()
+	state := s.state.Load()
+	detour, loaded := state.outbounds[tag]
 	if !loaded {
 		return false
 	}
-	if s.selected == detour {
+	if state.selected == detour {
 		return true
 	}
-	s.selected = detour
+	s.state.Store(&selectorState{state.outbounds, state.outboundTags, detour})
 	if s.tag != "" {
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
 			err := cacheFile.StoreSelected(s.tag, tag)
+			if err == nil {
+				err = cacheFile.StoreSelectedIdentity(s.tag, s.outboundIdentity(tag))
+			}
 			if err != nil {
 				s.logger.Error("store selected: ", err)
 			}
@@ -188,8 +264,17 @@ func (s *Selector) SelectOutbound(tag string) bool {
 	return true
 }
 
+// SelectDefault switches back to the configured default outbound, or to the
+// first one if no default is set.
+func (s *Selector) SelectDefault() bool {
+	if s.defaultTag != "" {
+		return s.SelectOutbound(s.defaultTag)
+	}
+	return s.SelectOutbound(s.state.Load().outboundTags[0])
+}
+
 func (s *Selector) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	conn, err := s.selected.DialContext(ctx, network, destination)
+	conn, err := s.state.Load().selected.DialContext(ctx, network, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -197,7 +282,7 @@ func (s *Selector) DialContext(ctx context.Context, network string, destination
 }
 
 func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	conn, err := s.selected.ListenPacket(ctx, destination)
+	conn, err := s.state.Load().selected.ListenPacket(ctx, destination)
 	if err != nil {
 		return nil, err
 	}
@@ -206,12 +291,12 @@ func (s *Selector) ListenPacket(ctx context.Context, destination M.Socksaddr) (n
 
 func (s *Selector) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewConnection(ctx, conn, metadata)
+	return s.state.Load().selected.NewConnection(ctx, conn, metadata)
 }
 
 func (s *Selector) NewPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext) error {
 	ctx = interrupt.ContextWithIsExternalConnection(ctx)
-	return s.selected.NewPacketConnection(ctx, conn, metadata)
+	return s.state.Load().selected.NewPacketConnection(ctx, conn, metadata)
 }
 
 func RealTag(detour adapter.Outbound) string {
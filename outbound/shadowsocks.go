@@ -51,6 +51,7 @@ func NewShadowsocks(ctx context.Context, router adapter.Router, logger log.Conte
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		dialer:     outboundDialer,
 		method:     method,
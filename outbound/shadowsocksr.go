@@ -49,6 +49,7 @@ func NewShadowsocksR(ctx context.Context, router adapter.Router, logger log.Cont
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		dialer:     outboundDialer,
 		serverAddr: options.ServerOptions.Build(),
@@ -33,6 +33,7 @@ func NewShadowTLS(ctx context.Context, router adapter.Router, logger log.Context
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 	}
 	if options.TLS == nil || !options.TLS.Enabled {
@@ -4,12 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/base64"
+	"errors"
 	"math/rand"
 	"net"
 	"os"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/common/dialer"
@@ -39,9 +41,19 @@ type SSH struct {
 	hostKeyAlgorithms []string
 	clientVersion     string
 	authMethod        []ssh.AuthMethod
-	clientAccess      sync.Mutex
-	clientConn        net.Conn
-	client            *ssh.Client
+	maxChannels       int
+	keepaliveInterval time.Duration
+	sessionAccess     sync.Mutex
+	sessions          []*sshSession
+}
+
+type sshSession struct {
+	conn     net.Conn
+	client   *ssh.Client
+	channels int
+	// ready is closed once the session is connected, or failed with err
+	ready chan struct{}
+	err   error
 }
 
 func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.SSHOutboundOptions) (*SSH, error) {
@@ -57,6 +69,7 @@ func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		ctx:               ctx,
 		dialer:            outboundDialer,
@@ -64,6 +77,8 @@ func NewSSH(ctx context.Context, router adapter.Router, logger log.ContextLogger
 		user:              options.User,
 		hostKeyAlgorithms: options.HostKeyAlgorithms,
 		clientVersion:     options.ClientVersion,
+		maxChannels:       options.MaxChannels,
+		keepaliveInterval: time.Duration(options.KeepaliveInterval),
 	}
 	if outbound.serverAddr.Port == 0 {
 		outbound.serverAddr.Port = 22
@@ -122,21 +137,51 @@ func randomVersion() string {
 	return version
 }
 
-func (s *SSH) connect() (*ssh.Client, error) {
-	if s.client != nil {
-		return s.client, nil
+// acquire returns a session with a free channel slot, connecting a new one
+// once all sessions carry max_channels channels.
+func (s *SSH) acquire() (*sshSession, error) {
+	s.sessionAccess.Lock()
+	for _, session := range s.sessions {
+		if s.maxChannels == 0 || session.channels < s.maxChannels {
+			session.channels++
+			s.sessionAccess.Unlock()
+			<-session.ready
+			if session.err != nil {
+				return nil, session.err
+			}
+			return session, nil
+		}
 	}
-
-	s.clientAccess.Lock()
-	defer s.clientAccess.Unlock()
-
-	if s.client != nil {
-		return s.client, nil
+	// the session is connected without holding the lock, other connections
+	// wait for it as a pending session
+	session := &sshSession{
+		channels: 1,
+		ready:    make(chan struct{}),
+	}
+	s.sessions = append(s.sessions, session)
+	s.sessionAccess.Unlock()
+	err := s.connect(session)
+	if err != nil {
+		session.err = err
+		s.removeSession(session)
+	}
+	close(session.ready)
+	if err != nil {
+		return nil, err
 	}
+	return session, nil
+}
+
+func (s *SSH) release(session *sshSession) {
+	s.sessionAccess.Lock()
+	defer s.sessionAccess.Unlock()
+	session.channels--
+}
 
+func (s *SSH) connect(session *sshSession) error {
 	conn, err := s.dialer.DialContext(s.ctx, N.NetworkTCP, s.serverAddr)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	config := &ssh.ClientConfig{
 		User:              s.user,
@@ -159,41 +204,123 @@ func (s *SSH) connect() (*ssh.Client, error) {
 	clientConn, chans, reqs, err := ssh.NewClientConn(conn, s.serverAddr.Addr.String(), config)
 	if err != nil {
 		conn.Close()
-		return nil, E.Cause(err, "connect to ssh server")
+		return E.Cause(err, "connect to ssh server")
 	}
-
-	client := ssh.NewClient(clientConn, chans, reqs)
-
-	s.clientConn = conn
-	s.client = client
-
+	s.sessionAccess.Lock()
+	if !common.Contains(s.sessions, session) {
+		s.sessionAccess.Unlock()
+		clientConn.Close()
+		return E.New("ssh session closed")
+	}
+	session.conn = conn
+	session.client = ssh.NewClient(clientConn, chans, reqs)
+	s.sessionAccess.Unlock()
 	go func() {
-		client.Wait()
+		session.client.Wait()
 		conn.Close()
-		s.clientAccess.Lock()
-		s.client = nil
-		s.clientConn = nil
-		s.clientAccess.Unlock()
+		s.removeSession(session)
 	}()
+	if s.keepaliveInterval > 0 {
+		go s.loopKeepalive(session)
+	}
+	return nil
+}
 
-	return client, nil
+func (s *SSH) removeSession(session *sshSession) {
+	s.sessionAccess.Lock()
+	defer s.sessionAccess.Unlock()
+	s.sessions = common.Filter(s.sessions, func(it *sshSession) bool {
+		return it != session
+	})
+}
+
+// loopKeepalive closes the session once the server stops answering
+// keepalive requests within the interval, so that the next connection
+// dials a new one.
+func (s *SSH) loopKeepalive(session *sshSession) {
+	ticker := time.NewTicker(s.keepaliveInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		done := make(chan error, 1)
+		go func() {
+			_, _, err := session.client.SendRequest("keepalive@openssh.com", true, nil)
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err == nil {
+				continue
+			}
+			s.logger.Debug("ssh session closed: ", err)
+		case <-time.After(s.keepaliveInterval):
+			s.logger.Debug("ssh keepalive timed out")
+		}
+		session.conn.Close()
+		return
+	}
+}
+
+func (s *SSH) closeSessions() error {
+	s.sessionAccess.Lock()
+	sessions := s.sessions
+	s.sessions = nil
+	s.sessionAccess.Unlock()
+	var err error
+	for _, session := range sessions {
+		// pending sessions are closed once connected
+		if session.conn != nil {
+			err = E.Errors(err, session.conn.Close())
+		}
+	}
+	return err
 }
 
 func (s *SSH) InterfaceUpdated() {
-	common.Close(s.clientConn)
-	return
+	s.closeSessions()
 }
 
 func (s *SSH) Close() error {
-	return common.Close(s.clientConn)
+	return s.closeSessions()
 }
 
 func (s *SSH) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	client, err := s.connect()
-	if err != nil {
-		return nil, err
+	for attempt := 0; ; attempt++ {
+		session, err := s.acquire()
+		if err != nil {
+			return nil, err
+		}
+		conn, err := session.client.Dial(network, destination.String())
+		if err == nil {
+			return &sshChannelConn{Conn: conn, outbound: s, session: session}, nil
+		}
+		s.release(session)
+		var openChannelErr *ssh.OpenChannelError
+		if errors.As(err, &openChannelErr) || attempt > 0 {
+			return nil, err
+		}
+		// the session is broken, re-dial once
+		s.logger.DebugContext(ctx, "ssh session failed, reconnecting: ", err)
+		session.conn.Close()
+		s.removeSession(session)
 	}
-	return client.Dial(network, destination.String())
+}
+
+type sshChannelConn struct {
+	net.Conn
+	outbound  *SSH
+	session   *sshSession
+	closeOnce sync.Once
+}
+
+func (c *sshChannelConn) Close() error {
+	c.closeOnce.Do(func() {
+		c.outbound.release(c.session)
+	})
+	return c.Conn.Close()
+}
+
+func (c *sshChannelConn) Upstream() any {
+	return c.Conn
 }
 
 func (s *SSH) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
@@ -82,6 +82,7 @@ func NewTor(ctx context.Context, router adapter.Router, logger log.ContextLogger
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		ctx:       ctx,
 		proxy:     NewProxyListener(ctx, logger, outboundDialer),
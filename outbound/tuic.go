@@ -32,8 +32,10 @@ var (
 
 type TUIC struct {
 	myOutboundAdapter
-	client    *tuic.Client
-	udpStream bool
+	client          *tuic.Client
+	protocolClients map[string]*tuic.Client
+	clients         []*tuic.Client
+	udpStream       bool
 }
 
 func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.TUICOutboundOptions) (*TUIC, error) {
@@ -62,21 +64,51 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 	if err != nil {
 		return nil, err
 	}
-	client, err := tuic.NewClient(tuic.ClientOptions{
-		Context:           ctx,
-		Dialer:            outboundDialer,
-		ServerAddress:     options.ServerOptions.Build(),
-		TLSConfig:         tlsConfig,
-		UUID:              userUUID,
-		Password:          options.Password,
-		CongestionControl: options.CongestionControl,
-		UDPStream:         tuicUDPStream,
-		ZeroRTTHandshake:  options.ZeroRTTHandshake,
-		Heartbeat:         time.Duration(options.Heartbeat),
-	})
+	newClient := func(congestionControl string) (*tuic.Client, error) {
+		return tuic.NewClient(tuic.ClientOptions{
+			Context:           ctx,
+			Dialer:            outboundDialer,
+			ServerAddress:     options.ServerOptions.Build(),
+			TLSConfig:         tlsConfig,
+			UUID:              userUUID,
+			Password:          options.Password,
+			CongestionControl: congestionControl,
+			UDPStream:         tuicUDPStream,
+			ZeroRTTHandshake:  options.ZeroRTTHandshake,
+			Heartbeat:         time.Duration(options.Heartbeat),
+		})
+	}
+	client, err := newClient(options.CongestionControl)
 	if err != nil {
 		return nil, err
 	}
+	defaultCongestionControl := options.CongestionControl
+	if defaultCongestionControl == "" {
+		defaultCongestionControl = "cubic"
+	}
+	// connections of protocols mapped to another algorithm use a separate
+	// QUIC session per algorithm
+	protocolClients := make(map[string]*tuic.Client)
+	algorithmClients := make(map[string]*tuic.Client)
+	clients := []*tuic.Client{client}
+	for protocol, congestionControl := range options.CongestionControlMap {
+		if congestionControl == "" {
+			congestionControl = "cubic"
+		}
+		if congestionControl == defaultCongestionControl {
+			continue
+		}
+		algorithmClient, loaded := algorithmClients[congestionControl]
+		if !loaded {
+			algorithmClient, err = newClient(congestionControl)
+			if err != nil {
+				return nil, E.Cause(err, "congestion_control_map[", protocol, "]")
+			}
+			algorithmClients[congestionControl] = algorithmClient
+			clients = append(clients, algorithmClient)
+		}
+		protocolClients[protocol] = algorithmClient
+	}
 	return &TUIC{
 		myOutboundAdapter: myOutboundAdapter{
 			protocol:     C.TypeTUIC,
@@ -85,9 +117,12 @@ func NewTUIC(ctx context.Context, router adapter.Router, logger log.ContextLogge
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
-		client:    client,
-		udpStream: options.UDPOverStream,
+		client:          client,
+		protocolClients: protocolClients,
+		clients:         clients,
+		udpStream:       options.UDPOverStream,
 	}, nil
 }
 
@@ -95,11 +130,11 @@ func (h *TUIC) DialContext(ctx context.Context, network string, destination M.So
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
 		h.logger.InfoContext(ctx, "outbound connection to ", destination)
-		return h.client.DialConn(ctx, destination)
+		return h.clientFor(ctx).DialConn(ctx, destination)
 	case N.NetworkUDP:
 		if h.udpStream {
 			h.logger.InfoContext(ctx, "outbound stream packet connection to ", destination)
-			streamConn, err := h.client.DialConn(ctx, uot.RequestDestination(uot.Version))
+			streamConn, err := h.clientFor(ctx).DialConn(ctx, uot.RequestDestination(uot.Version))
 			if err != nil {
 				return nil, err
 			}
@@ -122,7 +157,7 @@ func (h *TUIC) DialContext(ctx context.Context, network string, destination M.So
 func (h *TUIC) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	if h.udpStream {
 		h.logger.InfoContext(ctx, "outbound stream packet connection to ", destination)
-		streamConn, err := h.client.DialConn(ctx, uot.RequestDestination(uot.Version))
+		streamConn, err := h.clientFor(ctx).DialConn(ctx, uot.RequestDestination(uot.Version))
 		if err != nil {
 			return nil, err
 		}
@@ -132,7 +167,7 @@ func (h *TUIC) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.P
 		}), nil
 	} else {
 		h.logger.InfoContext(ctx, "outbound packet connection to ", destination)
-		return h.client.ListenPacket(ctx)
+		return h.clientFor(ctx).ListenPacket(ctx)
 	}
 }
 
@@ -144,10 +179,25 @@ func (h *TUIC) NewPacketConnection(ctx context.Context, conn N.PacketConn, metad
 	return NewPacketConnection(ctx, h, conn, metadata)
 }
 
+func (h *TUIC) clientFor(ctx context.Context) *tuic.Client {
+	if metadata := adapter.ContextFrom(ctx); metadata != nil && metadata.Protocol != "" {
+		if client, loaded := h.protocolClients[metadata.Protocol]; loaded {
+			return client
+		}
+	}
+	return h.client
+}
+
 func (h *TUIC) InterfaceUpdated() {
-	_ = h.client.CloseWithError(E.New("network changed"))
+	for _, client := range h.clients {
+		_ = client.CloseWithError(E.New("network changed"))
+	}
 }
 
 func (h *TUIC) Close() error {
-	return h.client.CloseWithError(os.ErrClosed)
+	var err error
+	for _, client := range h.clients {
+		err = E.Errors(err, client.CloseWithError(os.ErrClosed))
+	}
+	return err
 }
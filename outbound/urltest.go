@@ -24,9 +24,10 @@ import (
 )
 
 var (
-	_ adapter.Outbound                = (*URLTest)(nil)
-	_ adapter.OutboundGroup           = (*URLTest)(nil)
-	_ adapter.InterfaceUpdateListener = (*URLTest)(nil)
+	_ adapter.Outbound                       = (*URLTest)(nil)
+	_ adapter.OutboundGroup                  = (*URLTest)(nil)
+	_ adapter.InterfaceUpdateListener        = (*URLTest)(nil)
+	_ adapter.OutboundProviderUpdateListener = (*URLTest)(nil)
 )
 
 type URLTest struct {
@@ -34,13 +35,20 @@ type URLTest struct {
 	ctx                          context.Context
 	tags                         []string
 	providers                    []providerOutbound
-	outboundTags                 []string
 	link                         string
 	interval                     time.Duration
 	tolerance                    uint16
 	idleTimeout                  time.Duration
-	group                        *URLTestGroup
 	interruptExternalConnections bool
+	fallback                     bool
+	access                       sync.Mutex
+	state                        atomic.Pointer[urlTestState]
+}
+
+// urlTestState is replaced as a whole when a provider updates.
+type urlTestState struct {
+	group        *URLTestGroup
+	outboundTags []string
 }
 
 func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLogger, tag string, options option.URLTestOutboundOptions) (*URLTest, error) {
@@ -84,6 +92,19 @@ func NewURLTest(ctx context.Context, router adapter.Router, logger log.ContextLo
 }
 
 func (s *URLTest) Start() error {
+	outbounds, err := s.resolveOutbounds()
+	if err != nil {
+		return err
+	}
+	group, err := s.newGroup(outbounds)
+	if err != nil {
+		return err
+	}
+	s.state.Store(&urlTestState{group, outboundTags(outbounds)})
+	return nil
+}
+
+func (s *URLTest) resolveOutbounds() ([]adapter.Outbound, error) {
 	outboundMap := make(map[string]struct{})
 	outbounds := make([]adapter.Outbound, 0, len(s.tags))
 	for i, tag := range s.tags {
@@ -92,7 +113,7 @@ func (s *URLTest) Start() error {
 		}
 		detour, loaded := s.router.Outbound(tag)
 		if !loaded {
-			return E.New("outbound ", i, " not found: ", tag)
+			return nil, E.New("outbound ", i, " not found: ", tag)
 		}
 		outbounds = append(outbounds, detour)
 		outboundMap[tag] = struct{}{}
@@ -100,13 +121,13 @@ func (s *URLTest) Start() error {
 	for i, p := range s.providers {
 		provider, loaded := s.router.OutboundProvider(p.providerTag)
 		if !loaded {
-			return E.New("outbound provider[", i, "] provider not found: ", p.providerTag)
+			return nil, E.New("outbound provider[", i, "] provider not found: ", p.providerTag)
 		}
 		for _, outbound := range provider.BasicOutbounds() {
 			if p.filter.MatchOutbound(outbound) {
 				_, loaded := outboundMap[outbound.Tag()]
 				if loaded {
-					return E.New("duplicate outbound: ", outbound.Tag())
+					return nil, E.New("duplicate outbound: ", outbound.Tag())
 				}
 				outboundMap[outbound.Tag()] = struct{}{}
 				outbounds = append(outbounds, outbound)
@@ -116,7 +137,7 @@ func (s *URLTest) Start() error {
 			if p.filter.MatchOutbound(outbound) {
 				_, loaded := outboundMap[outbound.Tag()]
 				if loaded {
-					return E.New("duplicate outbound: ", outbound.Tag())
+					return nil, E.New("duplicate outbound: ", outbound.Tag())
 				}
 				outboundMap[outbound.Tag()] = struct{}{}
 				outbounds = append(outbounds, outbound)
@@ -124,16 +145,42 @@ func (s *URLTest) Start() error {
 		}
 	}
 	if len(outbounds) == 0 {
-		return E.New("missing outbounds")
+		return nil, E.New("missing outbounds")
 	}
-	newOutbounds := make([]adapter.Outbound, len(outbounds))
-	s.outboundTags = make([]string, 0, len(outbounds))
-	copy(newOutbounds, outbounds)
-	for _, outbound := range outbounds {
-		s.outboundTags = append(s.outboundTags, outbound.Tag())
+	return outbounds, nil
+}
+
+// OutboundProviderUpdated replaces the group with one testing the members
+// currently taken from the provider.
+func (s *URLTest) OutboundProviderUpdated(tag string) error {
+	if !common.Any(s.providers, func(it providerOutbound) bool {
+		return it.providerTag == tag
+	}) {
+		return nil
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	outbounds, err := s.resolveOutbounds()
+	if err != nil {
+		return err
+	}
+	group, err := s.newGroup(outbounds)
+	if err != nil {
+		return err
 	}
-	outbounds = newOutbounds
-	group, err := NewURLTestGroup(
+	group.PostStart()
+	oldState := s.state.Swap(&urlTestState{group, outboundTags(outbounds)})
+	return oldState.group.Close()
+}
+
+// newGroup creates the group testing outbounds. In fallback mode the
+// tolerance is the maximum delay of an available outbound.
+func (s *URLTest) newGroup(outbounds []adapter.Outbound) (*URLTestGroup, error) {
+	var maxDelay uint16
+	if s.fallback {
+		maxDelay = s.tolerance
+	}
+	return NewURLTestGroup(
 		s.ctx,
 		s.router,
 		s.logger,
@@ -143,12 +190,17 @@ func (s *URLTest) Start() error {
 		s.tolerance,
 		s.idleTimeout,
 		s.interruptExternalConnections,
+		s.fallback,
+		maxDelay,
 	)
-	if err != nil {
-		return err
+}
+
+func outboundTags(outbounds []adapter.Outbound) []string {
+	tags := make([]string, 0, len(outbounds))
+	for _, outbound := range outbounds {
+		tags = append(tags, outbound.Tag())
 	}
-	s.group = group
-	return nil
+	return tags
 }
 
 func (s *URLTest) Dependencies() []string {
@@ -161,78 +213,85 @@ func (s *URLTest) Dependencies() []string {
 }
 
 func (s *URLTest) PostStart() error {
-	s.group.PostStart()
+	s.state.Load().group.PostStart()
 	return nil
 }
 
 func (s *URLTest) Close() error {
-	return common.Close(
-		common.PtrOrNil(s.group),
-	)
+	s.access.Lock()
+	defer s.access.Unlock()
+	state := s.state.Load()
+	if state == nil {
+		return nil
+	}
+	return state.group.Close()
 }
 
 func (s *URLTest) Now() string {
-	if s.group.selectedOutboundTCP != nil {
-		return s.group.selectedOutboundTCP.Tag()
-	} else if s.group.selectedOutboundUDP != nil {
-		return s.group.selectedOutboundUDP.Tag()
+	group := s.state.Load().group
+	if group.selectedOutboundTCP != nil {
+		return group.selectedOutboundTCP.Tag()
+	} else if group.selectedOutboundUDP != nil {
+		return group.selectedOutboundUDP.Tag()
 	}
 	return ""
 }
 
 func (s *URLTest) All() []string {
-	return s.outboundTags
+	return s.state.Load().outboundTags
 }
 
 func (s *URLTest) URLTest(ctx context.Context) (map[string]uint16, error) {
-	return s.group.URLTest(ctx)
+	return s.state.Load().group.URLTest(ctx)
 }
 
 func (s *URLTest) CheckOutbounds() {
-	s.group.CheckOutbounds(true)
+	s.state.Load().group.CheckOutbounds(true)
 }
 
 func (s *URLTest) DialContext(ctx context.Context, network string, destination M.Socksaddr) (net.Conn, error) {
-	s.group.Touch()
+	group := s.state.Load().group
+	group.Touch()
 	var outbound adapter.Outbound
 	switch N.NetworkName(network) {
 	case N.NetworkTCP:
-		outbound = s.group.selectedOutboundTCP
+		outbound = group.selectedOutboundTCP
 	case N.NetworkUDP:
-		outbound = s.group.selectedOutboundUDP
+		outbound = group.selectedOutboundUDP
 	default:
 		return nil, E.Extend(N.ErrUnknownNetwork, network)
 	}
 	if outbound == nil {
-		outbound, _ = s.group.Select(network)
+		outbound, _ = group.Select(network)
 	}
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
 	conn, err := outbound.DialContext(ctx, network, destination)
 	if err == nil {
-		return s.group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
+		return group.interruptGroup.NewConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	group.unavailable(outbound)
 	return nil, err
 }
 
 func (s *URLTest) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
-	s.group.Touch()
-	outbound := s.group.selectedOutboundUDP
+	group := s.state.Load().group
+	group.Touch()
+	outbound := group.selectedOutboundUDP
 	if outbound == nil {
-		outbound, _ = s.group.Select(N.NetworkUDP)
+		outbound, _ = group.Select(N.NetworkUDP)
 	}
 	if outbound == nil {
 		return nil, E.New("missing supported outbound")
 	}
 	conn, err := outbound.ListenPacket(ctx, destination)
 	if err == nil {
-		return s.group.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
+		return group.interruptGroup.NewPacketConn(conn, interrupt.IsExternalConnectionFromContext(ctx)), nil
 	}
 	s.logger.ErrorContext(ctx, err)
-	s.group.history.DeleteURLTestHistory(outbound.Tag())
+	group.unavailable(outbound)
 	return nil, err
 }
 
@@ -247,7 +306,7 @@ func (s *URLTest) NewPacketConnection(ctx context.Context, conn N.PacketConn, me
 }
 
 func (s *URLTest) InterfaceUpdated() {
-	go s.group.CheckOutbounds(true)
+	go s.state.Load().group.CheckOutbounds(true)
 	return
 }
 
@@ -267,6 +326,8 @@ type URLTestGroup struct {
 	selectedOutboundUDP          adapter.Outbound
 	interruptGroup               *interrupt.Group
 	interruptExternalConnections bool
+	fallback                     bool
+	maxDelay                     uint16
 
 	access     sync.Mutex
 	ticker     *time.Ticker
@@ -285,6 +346,8 @@ func NewURLTestGroup(
 	tolerance uint16,
 	idleTimeout time.Duration,
 	interruptExternalConnections bool,
+	fallback bool,
+	maxDelay uint16,
 ) (*URLTestGroup, error) {
 	if interval == 0 {
 		interval = C.DefaultURLTestInterval
@@ -319,10 +382,18 @@ func NewURLTestGroup(
 		pauseManager:                 service.FromContext[pause.Manager](ctx),
 		interruptGroup:               interrupt.NewGroup(),
 		interruptExternalConnections: interruptExternalConnections,
+		fallback:                     fallback,
+		maxDelay:                     maxDelay,
 	}, nil
 }
 
 func (g *URLTestGroup) PostStart() {
+	if cacheFile := service.FromContext[adapter.CacheFile](g.ctx); cacheFile != nil {
+		g.history.SetCache(cacheFile, func(tag string) bool {
+			_, loaded := g.router.Outbound(tag)
+			return loaded
+		})
+	}
 	g.started = true
 	g.lastActive.Store(time.Now())
 	go g.CheckOutbounds(false)
@@ -346,6 +417,7 @@ func (g *URLTestGroup) Touch() {
 }
 
 func (g *URLTestGroup) Close() error {
+	g.history.Flush()
 	if g.ticker == nil {
 		return nil
 	}
@@ -355,6 +427,9 @@ func (g *URLTestGroup) Close() error {
 }
 
 func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
+	if g.fallback {
+		return g.selectFallback(network)
+	}
 	var minDelay uint16
 	var minOutbound adapter.Outbound
 	switch network {
@@ -398,6 +473,36 @@ func (g *URLTestGroup) Select(network string) (adapter.Outbound, bool) {
 	return minOutbound, true
 }
 
+// selectFallback returns the first available outbound in configured order
+// whose delay is within maxDelay, so that a recovered outbound is preferred
+// again over the ones after it.
+func (g *URLTestGroup) selectFallback(network string) (adapter.Outbound, bool) {
+	var firstOutbound adapter.Outbound
+	for _, detour := range g.outbounds {
+		if !common.Contains(detour.Network(), network) {
+			continue
+		}
+		if firstOutbound == nil {
+			firstOutbound = detour
+		}
+		history := g.history.LoadURLTestHistory(RealTag(detour))
+		if history == nil || g.maxDelay > 0 && history.Delay > g.maxDelay {
+			continue
+		}
+		return detour, true
+	}
+	return firstOutbound, false
+}
+
+// unavailable drops the test history of a failed outbound, failing over
+// immediately in fallback mode.
+func (g *URLTestGroup) unavailable(outbound adapter.Outbound) {
+	g.history.DeleteURLTestHistory(outbound.Tag())
+	if g.fallback {
+		g.performUpdateCheck()
+	}
+}
+
 func (g *URLTestGroup) loopCheck() {
 	if time.Now().Sub(g.lastActive.Load()) > g.interval {
 		g.lastActive.Store(time.Now())
@@ -48,6 +48,7 @@ func NewVLESS(ctx context.Context, router adapter.Router, logger log.ContextLogg
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		dialer:     outboundDialer,
 		serverAddr: options.ServerOptions.Build(),
@@ -60,6 +60,7 @@ func NewWireGuard(ctx context.Context, router adapter.Router, logger log.Context
 			logger:       logger,
 			tag:          tag,
 			dependencies: withDialerDependency(options.DialerOptions),
+			buffer:       options.Buffer,
 		},
 		ctx:          ctx,
 		workers:      options.Workers,
@@ -150,7 +151,13 @@ func (w *WireGuard) start() error {
 			connectAddr = w.peers[0].Endpoint
 			reserved = w.peers[0].Reserved
 		}
-		bind = wireguard.NewClientBind(w.ctx, w, w.listener, isConnect, connectAddr, reserved)
+		clientBind := wireguard.NewClientBind(w.ctx, w, w.listener, isConnect, connectAddr, reserved)
+		if !isConnect {
+			for _, peer := range w.peers {
+				clientBind.SetReservedForEndpoint(peer.Endpoint, peer.Reserved)
+			}
+		}
+		bind = clientBind
 	}
 	wgDevice := device.NewDevice(w.tunDevice, bind, &device.Logger{
 		Verbosef: func(format string, args ...interface{}) {
@@ -206,19 +213,23 @@ func (w *WireGuard) DialContext(ctx context.Context, network string, destination
 		w.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	}
 	if destination.IsFqdn() {
-		destinationAddresses, err := w.router.LookupDefault(ctx, destination.Fqdn)
+		destinationAddresses, err := w.lookup(ctx, destination.Fqdn)
 		if err != nil {
 			return nil, err
 		}
 		return N.DialSerial(ctx, w.tunDevice, network, destination, destinationAddresses)
 	}
+	err := w.selectPeer(ctx, destination.Addr)
+	if err != nil {
+		return nil, err
+	}
 	return w.tunDevice.DialContext(ctx, network, destination)
 }
 
 func (w *WireGuard) ListenPacket(ctx context.Context, destination M.Socksaddr) (net.PacketConn, error) {
 	w.logger.InfoContext(ctx, "outbound packet connection to ", destination)
 	if destination.IsFqdn() {
-		destinationAddresses, err := w.router.LookupDefault(ctx, destination.Fqdn)
+		destinationAddresses, err := w.lookup(ctx, destination.Fqdn)
 		if err != nil {
 			return nil, err
 		}
@@ -228,9 +239,43 @@ func (w *WireGuard) ListenPacket(ctx context.Context, destination M.Socksaddr) (
 		}
 		return packetConn, err
 	}
+	if destination.IsIP() {
+		err := w.selectPeer(ctx, destination.Addr)
+		if err != nil {
+			return nil, err
+		}
+	}
 	return w.tunDevice.ListenPacket(ctx, destination)
 }
 
+// lookup resolves domain to the addresses routed by any peer, so that
+// connections are not attempted to addresses WireGuard would drop.
+func (w *WireGuard) lookup(ctx context.Context, domain string) ([]netip.Addr, error) {
+	destinationAddresses, err := w.router.LookupDefault(ctx, domain)
+	if err != nil {
+		return nil, err
+	}
+	routedAddresses := common.Filter(destinationAddresses, func(it netip.Addr) bool {
+		_, loaded := wireguard.SelectPeer(w.peers, it)
+		return loaded
+	})
+	if len(routedAddresses) == 0 {
+		return nil, E.New("no peer allows any address of ", domain, ": ", destinationAddresses)
+	}
+	return routedAddresses, nil
+}
+
+func (w *WireGuard) selectPeer(ctx context.Context, destination netip.Addr) error {
+	peerIndex, loaded := wireguard.SelectPeer(w.peers, destination)
+	if !loaded {
+		return E.New("no peer allows destination ", destination)
+	}
+	if len(w.peers) > 1 {
+		w.logger.DebugContext(ctx, "selected peer ", peerIndex, " (", w.peers[peerIndex].Endpoint, ") for ", destination)
+	}
+	return nil
+}
+
 func (w *WireGuard) NewConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext) error {
 	return NewDirectConnection(ctx, w.router, w, conn, metadata, dns.DomainStrategyAsIS)
 }
@@ -0,0 +1,226 @@
+package route
+
+import (
+	"context"
+	"io"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	"github.com/sagernet/sing/common/bufio"
+	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
+	"github.com/sagernet/sing/common/x/list"
+
+	"github.com/gofrs/uuid/v5"
+)
+
+const closedConnectionsLimit = 1000
+
+var _ adapter.ConnectionTracker = (*connectionTracker)(nil)
+
+// connectionTracker records routed connections for the connection query
+// API, and is only appended to the trackers of the router if an API using
+// it is enabled.
+type connectionTracker struct {
+	router            adapter.Router
+	timeFunc          func() time.Time
+	access            sync.Mutex
+	connections       map[string]*trackedConnection
+	closedConnections list.List[adapter.TrackedConnection]
+}
+
+func newConnectionTracker(router adapter.Router) *connectionTracker {
+	return &connectionTracker{
+		router:      router,
+		timeFunc:    time.Now,
+		connections: make(map[string]*trackedConnection),
+	}
+}
+
+type trackedConnection struct {
+	tracker  *connectionTracker
+	info     adapter.TrackedConnection
+	upload   atomic.Int64
+	download atomic.Int64
+	closer   io.Closer
+}
+
+func (t *connectionTracker) join(metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) *trackedConnection {
+	id, _ := uuid.NewV4()
+	domain := metadata.Domain
+	if domain == "" {
+		domain = metadata.Destination.Fqdn
+	}
+	var processPath string
+	if metadata.ProcessInfo != nil {
+		processPath = metadata.ProcessInfo.String()
+	}
+	var rule string
+	if matchedRule != nil {
+		rule = F.ToString(matchedRule, " => ", matchedRule.Outbound())
+	} else {
+		rule = "final"
+	}
+	var chains []string
+	outbound := matchOutbound
+	for {
+		chains = append(chains, outbound.Tag())
+		group, isGroup := outbound.(adapter.OutboundGroup)
+		if !isGroup {
+			break
+		}
+		next, loaded := t.router.Outbound(group.Now())
+		if !loaded {
+			break
+		}
+		outbound = next
+	}
+	connection := &trackedConnection{
+		tracker: t,
+		info: adapter.TrackedConnection{
+			ID:           id.String(),
+			Network:      metadata.Network,
+			Inbound:      metadata.Inbound,
+			InboundType:  metadata.InboundType,
+			Source:       metadata.Source.String(),
+			Destination:  metadata.Destination.String(),
+			Protocol:     metadata.Protocol,
+			Domain:       domain,
+			ProcessPath:  processPath,
+			Rule:         rule,
+			Outbound:     matchOutbound.Tag(),
+			Chains:       common.Reverse(chains),
+			OutboundType: outbound.Type(),
+			CreatedAt:    t.timeFunc(),
+		},
+	}
+	t.access.Lock()
+	t.connections[connection.info.ID] = connection
+	t.access.Unlock()
+	return connection
+}
+
+func (t *connectionTracker) RoutedConnection(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) net.Conn {
+	connection := t.join(metadata, matchedRule, matchOutbound)
+	trackedConn := &trackedConn{
+		ExtendedConn:      bufio.NewInt64CounterConn(conn, []*atomic.Int64{&connection.upload}, []*atomic.Int64{&connection.download}),
+		trackedConnection: connection,
+	}
+	connection.closer = trackedConn
+	return trackedConn
+}
+
+func (t *connectionTracker) RoutedPacketConnection(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, matchedRule adapter.Rule, matchOutbound adapter.Outbound) N.PacketConn {
+	connection := t.join(metadata, matchedRule, matchOutbound)
+	trackedConn := &trackedPacketConn{
+		PacketConn:        bufio.NewInt64CounterPacketConn(conn, []*atomic.Int64{&connection.upload}, []*atomic.Int64{&connection.download}),
+		trackedConnection: connection,
+	}
+	connection.closer = trackedConn
+	return trackedConn
+}
+
+type trackedConn struct {
+	N.ExtendedConn
+	*trackedConnection
+}
+
+func (c *trackedConn) Close() error {
+	c.Leave()
+	return c.ExtendedConn.Close()
+}
+
+func (c *trackedConn) Upstream() any {
+	return c.ExtendedConn
+}
+
+func (c *trackedConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackedConn) WriterReplaceable() bool {
+	return true
+}
+
+type trackedPacketConn struct {
+	N.PacketConn
+	*trackedConnection
+}
+
+func (c *trackedPacketConn) Close() error {
+	c.Leave()
+	return c.PacketConn.Close()
+}
+
+func (c *trackedPacketConn) Upstream() any {
+	return c.PacketConn
+}
+
+func (c *trackedPacketConn) ReaderReplaceable() bool {
+	return true
+}
+
+func (c *trackedPacketConn) WriterReplaceable() bool {
+	return true
+}
+
+func (c *trackedConnection) snapshot(now time.Time) adapter.TrackedConnection {
+	info := c.info
+	info.Upload = c.upload.Load()
+	info.Download = c.download.Load()
+	info.Duration = now.Sub(info.CreatedAt).Round(time.Millisecond).String()
+	return info
+}
+
+func (c *trackedConnection) Leave() {
+	t := c.tracker
+	t.access.Lock()
+	defer t.access.Unlock()
+	if _, loaded := t.connections[c.info.ID]; !loaded {
+		return
+	}
+	delete(t.connections, c.info.ID)
+	closedAt := t.timeFunc()
+	info := c.snapshot(closedAt)
+	info.ClosedAt = &closedAt
+	if t.closedConnections.Len() >= closedConnectionsLimit {
+		t.closedConnections.PopFront()
+	}
+	t.closedConnections.PushBack(info)
+}
+
+func (t *connectionTracker) Connections() []adapter.TrackedConnection {
+	now := t.timeFunc()
+	t.access.Lock()
+	defer t.access.Unlock()
+	connections := make([]adapter.TrackedConnection, 0, len(t.connections))
+	for _, connection := range t.connections {
+		connections = append(connections, connection.snapshot(now))
+	}
+	sort.Slice(connections, func(i, j int) bool {
+		return connections[i].CreatedAt.Before(connections[j].CreatedAt)
+	})
+	return connections
+}
+
+func (t *connectionTracker) ClosedConnections() []adapter.TrackedConnection {
+	t.access.Lock()
+	defer t.access.Unlock()
+	return t.closedConnections.Array()
+}
+
+func (t *connectionTracker) CloseConnection(id string) bool {
+	t.access.Lock()
+	connection, loaded := t.connections[id]
+	t.access.Unlock()
+	if !loaded {
+		return false
+	}
+	connection.closer.Close()
+	return true
+}
@@ -0,0 +1,130 @@
+package route
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testOutbound struct {
+	adapter.Outbound
+	tag string
+}
+
+func (o *testOutbound) Tag() string {
+	return o.tag
+}
+
+func (o *testOutbound) Type() string {
+	return "test"
+}
+
+type testOutboundGroup struct {
+	testOutbound
+	now string
+}
+
+func (o *testOutboundGroup) Now() string {
+	return o.now
+}
+
+func (o *testOutboundGroup) All() []string {
+	return []string{o.now}
+}
+
+type testRouter struct {
+	adapter.Router
+	outbounds map[string]adapter.Outbound
+}
+
+func (r *testRouter) Outbound(tag string) (adapter.Outbound, bool) {
+	outbound, loaded := r.outbounds[tag]
+	return outbound, loaded
+}
+
+func TestConnectionTracker(t *testing.T) {
+	t.Parallel()
+	now := time.Unix(1700000000, 0)
+	proxy := &testOutbound{tag: "proxy"}
+	tracker := newConnectionTracker(&testRouter{outbounds: map[string]adapter.Outbound{"proxy": proxy}})
+	tracker.timeFunc = func() time.Time { return now }
+	metadata := adapter.InboundContext{
+		Network:     N.NetworkTCP,
+		Inbound:     "mixed-in",
+		InboundType: "mixed",
+		Source:      M.ParseSocksaddr("192.0.2.1:40000"),
+		Destination: M.ParseSocksaddr("example.com:443"),
+		Protocol:    "tls",
+	}
+	client, server := net.Pipe()
+	defer server.Close()
+	conn := tracker.RoutedConnection(context.Background(), client, metadata, nil, &testOutboundGroup{testOutbound{tag: "select"}, "proxy"})
+	go func() {
+		buffer := make([]byte, 5)
+		server.Read(buffer)
+		server.Write([]byte("world!"))
+	}()
+	_, err := conn.Write([]byte("hello"))
+	require.NoError(t, err)
+	_, err = conn.Read(make([]byte, 6))
+	require.NoError(t, err)
+
+	now = now.Add(1500 * time.Millisecond)
+	connections := tracker.Connections()
+	require.Len(t, connections, 1)
+	id := connections[0].ID
+	require.Equal(t, adapter.TrackedConnection{
+		ID:           id,
+		Network:      N.NetworkTCP,
+		Inbound:      "mixed-in",
+		InboundType:  "mixed",
+		Source:       "192.0.2.1:40000",
+		Destination:  "example.com:443",
+		Protocol:     "tls",
+		Domain:       "example.com",
+		Rule:         "final",
+		Outbound:     "select",
+		Chains:       []string{"proxy", "select"},
+		OutboundType: "test",
+		Upload:       6,
+		Download:     5,
+		CreatedAt:    now.Add(-1500 * time.Millisecond),
+		Duration:     "1.5s",
+	}, connections[0])
+
+	require.False(t, tracker.CloseConnection("unknown"))
+	require.True(t, tracker.CloseConnection(id))
+	_, err = conn.Write([]byte("closed"))
+	require.Error(t, err)
+	require.Empty(t, tracker.Connections())
+	closedConnections := tracker.ClosedConnections()
+	require.Len(t, closedConnections, 1)
+	require.Equal(t, now, *closedConnections[0].ClosedAt)
+	require.False(t, tracker.CloseConnection(id))
+
+	conn.(adapter.Tracker).Leave()
+	require.Len(t, tracker.ClosedConnections(), 1)
+}
+
+func TestConnectionTrackerClosedLimit(t *testing.T) {
+	t.Parallel()
+	tracker := newConnectionTracker(nil)
+	var first string
+	for i := 0; i < closedConnectionsLimit+1; i++ {
+		conn := tracker.RoutedConnection(context.Background(), nil, adapter.InboundContext{}, nil, &testOutbound{})
+		if i == 0 {
+			first = tracker.Connections()[0].ID
+		}
+		conn.(adapter.Tracker).Leave()
+	}
+	closedConnections := tracker.ClosedConnections()
+	require.Len(t, closedConnections, closedConnectionsLimit)
+	require.NotEqual(t, first, closedConnections[0].ID)
+}
@@ -0,0 +1,133 @@
+package route
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const dnsAdBlockTTL = 10
+
+var _ adapter.DNSAdBlocker = (*dnsAdBlocker)(nil)
+
+// dnsAdBlocker blocks queries for domains matched by the adblock rule-sets,
+// before the DNS cache and rules, so that toggling it applies immediately.
+type dnsAdBlocker struct {
+	ruleSet      *RuleSetItem
+	zeroResponse bool
+	enabled      atomic.Bool
+	access       sync.Mutex
+	clients      map[netip.Addr]*adapter.DNSAdBlockStatistics
+}
+
+func newDNSAdBlocker(router adapter.Router, options option.DNSAdBlockOptions) (*dnsAdBlocker, error) {
+	if len(options.RuleSet) == 0 {
+		return nil, E.New("missing rule_set")
+	}
+	blocker := &dnsAdBlocker{
+		ruleSet: NewRuleSetItem(router, options.RuleSet, false, false),
+		clients: make(map[netip.Addr]*adapter.DNSAdBlockStatistics),
+	}
+	switch options.Response {
+	case "", C.DNSAdBlockResponseNXDomain:
+	case C.DNSAdBlockResponseZero:
+		blocker.zeroResponse = true
+	default:
+		return nil, E.New("unknown response: ", options.Response)
+	}
+	blocker.enabled.Store(options.Enabled)
+	return blocker, nil
+}
+
+func (b *dnsAdBlocker) Start() error {
+	return b.ruleSet.Start()
+}
+
+func (b *dnsAdBlocker) Enabled() bool {
+	return b.enabled.Load()
+}
+
+func (b *dnsAdBlocker) SetEnabled(enabled bool) {
+	b.enabled.Store(enabled)
+}
+
+// Block reports whether queries for domain should be blocked, and records
+// the query in the statistics of the client.
+func (b *dnsAdBlocker) Block(ctx context.Context, domain string) bool {
+	_, metadata := adapter.ExtendContext(ctx)
+	blocked := b.enabled.Load()
+	if blocked {
+		metadata.Destination = M.Socksaddr{}
+		metadata.DestinationAddresses = nil
+		metadata.Domain = domain
+		metadata.ResetRuleCache()
+		blocked = b.ruleSet.Match(metadata)
+	}
+	if client := metadata.Source.Addr; client.IsValid() {
+		client = client.Unmap()
+		b.access.Lock()
+		statistics := b.clients[client]
+		if statistics == nil {
+			statistics = &adapter.DNSAdBlockStatistics{Client: client}
+			b.clients[client] = statistics
+		}
+		statistics.Queries++
+		if blocked {
+			statistics.Blocked++
+			statistics.LastBlocked = domain
+		}
+		b.access.Unlock()
+	}
+	return blocked
+}
+
+// Response returns the reply to a blocked query, NXDOMAIN or unspecified
+// addresses for A and AAAA queries with the zero response.
+func (b *dnsAdBlocker) Response(message *mDNS.Msg) *mDNS.Msg {
+	response := new(mDNS.Msg)
+	if !b.zeroResponse {
+		response.SetRcode(message, mDNS.RcodeNameError)
+		return response
+	}
+	response.SetReply(message)
+	for _, question := range message.Question {
+		header := mDNS.RR_Header{Name: question.Name, Rrtype: question.Qtype, Class: mDNS.ClassINET, Ttl: dnsAdBlockTTL}
+		switch question.Qtype {
+		case mDNS.TypeA:
+			response.Answer = append(response.Answer, &mDNS.A{Hdr: header, A: net.IPv4zero})
+		case mDNS.TypeAAAA:
+			response.Answer = append(response.Answer, &mDNS.AAAA{Hdr: header, AAAA: net.IPv6zero})
+		}
+	}
+	return response
+}
+
+func (b *dnsAdBlocker) Statistics() []adapter.DNSAdBlockStatistics {
+	b.access.Lock()
+	statistics := make([]adapter.DNSAdBlockStatistics, 0, len(b.clients))
+	for _, client := range b.clients {
+		statistics = append(statistics, *client)
+	}
+	b.access.Unlock()
+	sort.Slice(statistics, func(i, j int) bool {
+		return statistics[i].Client.Less(statistics[j].Client)
+	})
+	return statistics
+}
+
+func (b *dnsAdBlocker) ResetStatistics() {
+	b.access.Lock()
+	b.clients = make(map[netip.Addr]*adapter.DNSAdBlockStatistics)
+	b.access.Unlock()
+}
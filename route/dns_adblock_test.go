@@ -0,0 +1,61 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDNSAdBlocker(t *testing.T) {
+	t.Parallel()
+	router := &testRuleSetRouter{ruleSets: map[string]adapter.RuleSet{
+		"ads": newTestInlineRuleSet(t, "ads", option.DefaultHeadlessRule{
+			DomainSuffix: []string{"ads.example.com"},
+		}),
+	}}
+	_, err := newDNSAdBlocker(router, option.DNSAdBlockOptions{Enabled: true})
+	require.Error(t, err)
+	_, err = newDNSAdBlocker(router, option.DNSAdBlockOptions{RuleSet: []string{"ads"}, Response: "refused"})
+	require.Error(t, err)
+	blocker, err := newDNSAdBlocker(router, option.DNSAdBlockOptions{
+		Enabled:  true,
+		RuleSet:  []string{"ads"},
+		Response: C.DNSAdBlockResponseZero,
+	})
+	require.NoError(t, err)
+	require.NoError(t, blocker.Start())
+	client := netip.MustParseAddr("192.168.1.2")
+	ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{
+		Source: M.SocksaddrFrom(netip.AddrFrom16(client.As16()), 53),
+	})
+	require.True(t, blocker.Block(ctx, "www.ads.example.com"))
+	require.False(t, blocker.Block(ctx, "example.com"))
+	require.True(t, blocker.Block(context.Background(), "ads.example.com"))
+	blocker.SetEnabled(false)
+	require.False(t, blocker.Block(ctx, "ads.example.com"))
+	require.Equal(t, []adapter.DNSAdBlockStatistics{{
+		Client:      client,
+		Queries:     3,
+		Blocked:     1,
+		LastBlocked: "www.ads.example.com",
+	}}, blocker.Statistics())
+	blocker.ResetStatistics()
+	require.Empty(t, blocker.Statistics())
+
+	response := blocker.Response(newTestQuery("ads.example.com.", mDNS.TypeAAAA))
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.Len(t, response.Answer, 1)
+	require.Equal(t, "ads.example.com.\t10\tIN\tAAAA\t::", response.Answer[0].String())
+	blocker.zeroResponse = false
+	response = blocker.Response(newTestQuery("ads.example.com.", mDNS.TypeA))
+	require.Equal(t, mDNS.RcodeNameError, response.Rcode)
+	require.Empty(t, response.Answer)
+}
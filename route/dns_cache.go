@@ -0,0 +1,286 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/cache"
+
+	mDNS "github.com/miekg/dns"
+)
+
+type dnsCacheKey struct {
+	mDNS.Question
+	transportName string
+}
+
+type dnsLookupKey struct {
+	domain        string
+	strategy      dns.DomainStrategy
+	transportName string
+}
+
+type dnsCache struct {
+	independent   bool
+	disableExpire bool
+	exchangeCache *cache.LruCache[dnsCacheKey, *mDNS.Msg]
+	lookupCache   *cache.LruCache[dnsLookupKey, []netip.Addr]
+}
+
+func newDNSCache(capacity int, independent bool, disableExpire bool) *dnsCache {
+	return &dnsCache{
+		independent:   independent,
+		disableExpire: disableExpire,
+		exchangeCache: cache.New[dnsCacheKey, *mDNS.Msg](cache.WithSize[dnsCacheKey, *mDNS.Msg](capacity)),
+		lookupCache:   cache.New[dnsLookupKey, []netip.Addr](cache.WithSize[dnsLookupKey, []netip.Addr](capacity)),
+	}
+}
+
+func (c *dnsCache) Wrap(transport dns.Transport) dns.Transport {
+	if _, isFakeIP := transport.(adapter.FakeIPTransport); isFakeIP {
+		return transport
+	}
+	return &cachedDNSTransport{transport, c}
+}
+
+func (c *dnsCache) Clear() {
+	c.exchangeCache.Clear()
+	c.lookupCache.Clear()
+}
+
+// LookupCache and ExchangeCache are the fast paths of dns.Client, which are
+// lost when the client cache is replaced by this one.
+func (c *dnsCache) LookupCache(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, bool) {
+	if c.independent || dns.DisableCacheFromContext(ctx) {
+		return nil, false
+	}
+	domain = strings.TrimSuffix(domain, ".")
+	if addresses, loaded := c.loadLookup(dnsLookupKey{domain, strategy, ""}); loaded {
+		return addresses, true
+	}
+	var response4, response6 []netip.Addr
+	if strategy != dns.DomainStrategyUseIPv6 {
+		response4 = c.loadAddresses(mDNS.Fqdn(domain), mDNS.TypeA)
+	}
+	if strategy != dns.DomainStrategyUseIPv4 {
+		response6 = c.loadAddresses(mDNS.Fqdn(domain), mDNS.TypeAAAA)
+	}
+	if len(response4) == 0 && len(response6) == 0 {
+		return nil, false
+	}
+	if strategy == dns.DomainStrategyPreferIPv6 {
+		return append(response6, response4...), true
+	}
+	return append(response4, response6...), true
+}
+
+func (c *dnsCache) ExchangeCache(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, bool) {
+	if c.independent || len(message.Question) != 1 || len(message.Ns) > 0 || len(message.Extra) > 0 || dns.DisableCacheFromContext(ctx) {
+		return nil, false
+	}
+	response, loaded := c.load(dnsCacheKey{message.Question[0], ""})
+	if !loaded {
+		return nil, false
+	}
+	response.Id = message.Id
+	return response, true
+}
+
+func (c *dnsCache) loadAddresses(name string, queryType uint16) []netip.Addr {
+	response, loaded := c.load(dnsCacheKey{mDNS.Question{Name: name, Qtype: queryType, Qclass: mDNS.ClassINET}, ""})
+	if !loaded {
+		return nil
+	}
+	addresses, _ := dns.MessageToAddresses(response)
+	return addresses
+}
+
+func (c *dnsCache) loadLookup(key dnsLookupKey) ([]netip.Addr, bool) {
+	addresses, expireAt, loaded := c.lookupCache.LoadWithExpire(key)
+	if !loaded {
+		return nil, false
+	}
+	if !c.disableExpire && !time.Now().Before(expireAt) {
+		c.lookupCache.Delete(key)
+		return nil, false
+	}
+	return addresses, true
+}
+
+func (c *dnsCache) transportName(transport dns.Transport) string {
+	if !c.independent {
+		return ""
+	}
+	return transport.Name()
+}
+
+func (c *dnsCache) store(key dnsCacheKey, response *mDNS.Msg) {
+	if response.Rcode != mDNS.RcodeSuccess && response.Rcode != mDNS.RcodeNameError {
+		return
+	}
+	timeToLive := messageTTL(response)
+	if timeToLive == 0 {
+		return
+	}
+	if c.disableExpire {
+		c.exchangeCache.Store(key, response.Copy())
+	} else {
+		c.exchangeCache.StoreWithExpire(key, response.Copy(), time.Now().Add(time.Duration(timeToLive)*time.Second))
+	}
+}
+
+func (c *dnsCache) load(key dnsCacheKey) (*mDNS.Msg, bool) {
+	response, expireAt, loaded := c.exchangeCache.LoadWithExpire(key)
+	if !loaded {
+		return nil, false
+	}
+	response = response.Copy()
+	if c.disableExpire {
+		return response, true
+	}
+	timeNow := time.Now()
+	if timeNow.After(expireAt) {
+		c.exchangeCache.Delete(key)
+		return nil, false
+	}
+	nowTTL := uint32(expireAt.Sub(timeNow).Seconds())
+	for _, recordList := range [][]mDNS.RR{response.Answer, response.Ns, response.Extra} {
+		for _, record := range recordList {
+			if record.Header().Ttl > nowTTL {
+				record.Header().Ttl = nowTTL
+			}
+		}
+	}
+	return response, true
+}
+
+func messageTTL(message *mDNS.Msg) uint32 {
+	var timeToLive uint32
+	for _, recordList := range [][]mDNS.RR{message.Answer, message.Ns, message.Extra} {
+		for _, record := range recordList {
+			if record.Header().Rrtype == mDNS.TypeOPT {
+				continue
+			}
+			if timeToLive == 0 || record.Header().Ttl < timeToLive {
+				timeToLive = record.Header().Ttl
+			}
+		}
+	}
+	if timeToLive == 0 && len(message.Answer) == 0 && len(message.Ns) == 0 {
+		timeToLive = dns.DefaultTTL
+	}
+	return timeToLive
+}
+
+type cachedDNSTransport struct {
+	dns.Transport
+	cache *dnsCache
+}
+
+func (t *cachedDNSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(message.Question) != 1 || len(message.Ns) > 0 || len(message.Extra) > 0 || dns.DisableCacheFromContext(ctx) {
+		return t.Transport.Exchange(ctx, message)
+	}
+	key := dnsCacheKey{message.Question[0], t.cache.transportName(t.Transport)}
+	if response, loaded := t.cache.load(key); loaded {
+		response.Id = message.Id
+		return response, nil
+	}
+	response, err := t.Transport.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	t.cache.store(key, response)
+	return response, nil
+}
+
+func (t *cachedDNSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	if dns.DisableCacheFromContext(ctx) {
+		return t.Transport.Lookup(ctx, domain, strategy)
+	}
+	key := dnsLookupKey{strings.TrimSuffix(domain, "."), strategy, t.cache.transportName(t.Transport)}
+	if addresses, loaded := t.cache.loadLookup(key); loaded {
+		return addresses, nil
+	}
+	addresses, err := t.Transport.Lookup(ctx, domain, strategy)
+	if err != nil {
+		return nil, err
+	}
+	if len(addresses) > 0 {
+		if t.cache.disableExpire {
+			t.cache.lookupCache.Store(key, addresses)
+		} else {
+			// transports without raw queries do not report TTLs, so use the same
+			// TTL as dns.Client does for them
+			timeToLive, loaded := dns.RewriteTTLFromContext(ctx)
+			if !loaded {
+				timeToLive = dns.DefaultTTL
+			}
+			t.cache.lookupCache.StoreWithExpire(key, addresses, time.Now().Add(time.Duration(timeToLive)*time.Second))
+		}
+	}
+	return addresses, nil
+}
+
+// Dump returns the unexpired responses of the exchange cache from the least
+// to the most recently used.
+func (c *dnsCache) Dump() []adapter.SavedDNSResponse {
+	var keys []dnsCacheKey
+	c.exchangeCache.Range(func(key dnsCacheKey, _ *mDNS.Msg) {
+		keys = append(keys, key)
+	})
+	timeNow := time.Now()
+	responses := make([]adapter.SavedDNSResponse, 0, len(keys))
+	for _, key := range keys {
+		response, expireAt, loaded := c.exchangeCache.LoadWithExpire(key)
+		if !loaded {
+			continue
+		}
+		if c.disableExpire {
+			expireAt = time.Time{}
+		} else if !timeNow.Before(expireAt) {
+			continue
+		}
+		message, err := response.Pack()
+		if err != nil {
+			continue
+		}
+		responses = append(responses, adapter.SavedDNSResponse{
+			Transport: key.transportName,
+			Message:   message,
+			ExpireAt:  expireAt,
+		})
+	}
+	return responses
+}
+
+// Restore loads responses saved by Dump, and returns the number of loaded ones.
+func (c *dnsCache) Restore(responses []adapter.SavedDNSResponse) int {
+	timeNow := time.Now()
+	var loaded int
+	for _, saved := range responses {
+		if !c.disableExpire && !timeNow.Before(saved.ExpireAt) {
+			continue
+		}
+		if c.independent != (saved.Transport != "") {
+			continue
+		}
+		var response mDNS.Msg
+		err := response.Unpack(saved.Message)
+		if err != nil || len(response.Question) != 1 {
+			continue
+		}
+		key := dnsCacheKey{response.Question[0], saved.Transport}
+		if c.disableExpire {
+			c.exchangeCache.Store(key, &response)
+		} else {
+			c.exchangeCache.StoreWithExpire(key, &response, saved.ExpireAt)
+		}
+		loaded++
+	}
+	return loaded
+}
@@ -0,0 +1,226 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testDNSTransport struct {
+	dns.Transport
+	name      string
+	exchanges int
+	lookups   int
+	ttl       uint32
+	addresses []netip.Addr
+}
+
+func (t *testDNSTransport) Name() string {
+	return t.name
+}
+
+func (t *testDNSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	t.exchanges++
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	question := message.Question[0]
+	for _, address := range t.addresses {
+		if address.Is4() && question.Qtype == mDNS.TypeA {
+			response.Answer = append(response.Answer, &mDNS.A{
+				Hdr: mDNS.RR_Header{Name: question.Name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: t.ttl},
+				A:   address.AsSlice(),
+			})
+		} else if address.Is6() && question.Qtype == mDNS.TypeAAAA {
+			response.Answer = append(response.Answer, &mDNS.AAAA{
+				Hdr:  mDNS.RR_Header{Name: question.Name, Rrtype: mDNS.TypeAAAA, Class: mDNS.ClassINET, Ttl: t.ttl},
+				AAAA: address.AsSlice(),
+			})
+		}
+	}
+	return response, nil
+}
+
+func (t *testDNSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	t.lookups++
+	return t.addresses, nil
+}
+
+func newTestQuery(name string, queryType uint16) *mDNS.Msg {
+	message := new(mDNS.Msg)
+	message.SetQuestion(mDNS.Fqdn(name), queryType)
+	return message
+}
+
+func TestMessageTTL(t *testing.T) {
+	t.Parallel()
+	header := func(ttl uint32) mDNS.RR_Header {
+		return mDNS.RR_Header{Name: "example.com.", Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: ttl}
+	}
+	for _, testCase := range []struct {
+		name     string
+		message  *mDNS.Msg
+		expected uint32
+	}{
+		{"empty", &mDNS.Msg{}, dns.DefaultTTL},
+		{"single", &mDNS.Msg{Answer: []mDNS.RR{&mDNS.A{Hdr: header(300)}}}, 300},
+		{"minimum", &mDNS.Msg{Answer: []mDNS.RR{&mDNS.A{Hdr: header(300)}, &mDNS.A{Hdr: header(60)}}}, 60},
+		{"authority", &mDNS.Msg{Ns: []mDNS.RR{&mDNS.A{Hdr: header(30)}}}, 30},
+		{"ignore opt", &mDNS.Msg{Answer: []mDNS.RR{&mDNS.A{Hdr: header(120)}}, Extra: []mDNS.RR{&mDNS.OPT{Hdr: mDNS.RR_Header{Rrtype: mDNS.TypeOPT}}}}, 120},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, testCase.expected, messageTTL(testCase.message))
+		})
+	}
+}
+
+func TestDNSCacheExchange(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name          string
+		ttl           uint32
+		disableExpire bool
+		expire        bool
+		exchanges     int
+	}{
+		{"cached", 60, false, false, 1},
+		{"expired", 60, false, true, 2},
+		{"disable expire", 60, true, true, 1},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			cache := newDNSCache(16, false, testCase.disableExpire)
+			transport := &testDNSTransport{ttl: testCase.ttl, addresses: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}
+			wrapped := cache.Wrap(transport)
+			_, err := wrapped.Exchange(context.Background(), newTestQuery("example.com", mDNS.TypeA))
+			require.NoError(t, err)
+			if testCase.expire {
+				key := dnsCacheKey{newTestQuery("example.com", mDNS.TypeA).Question[0], ""}
+				response, _ := cache.exchangeCache.Load(key)
+				cache.exchangeCache.StoreWithExpire(key, response, time.Now().Add(-time.Second))
+			}
+			response, err := wrapped.Exchange(context.Background(), newTestQuery("example.com", mDNS.TypeA))
+			require.NoError(t, err)
+			require.Len(t, response.Answer, 1)
+			require.LessOrEqual(t, response.Answer[0].Header().Ttl, testCase.ttl)
+			require.Equal(t, testCase.exchanges, transport.exchanges)
+		})
+	}
+}
+
+func TestDNSCacheLookupCache(t *testing.T) {
+	t.Parallel()
+	address4 := netip.MustParseAddr("1.1.1.1")
+	address6 := netip.MustParseAddr("2606:4700:4700::1111")
+	for _, testCase := range []struct {
+		name        string
+		independent bool
+		strategy    dns.DomainStrategy
+		expected    []netip.Addr
+		cached      bool
+	}{
+		{"prefer ipv4", false, dns.DomainStrategyPreferIPv4, []netip.Addr{address4, address6}, true},
+		{"prefer ipv6", false, dns.DomainStrategyPreferIPv6, []netip.Addr{address6, address4}, true},
+		{"ipv4 only", false, dns.DomainStrategyUseIPv4, []netip.Addr{address4}, true},
+		{"ipv6 only", false, dns.DomainStrategyUseIPv6, []netip.Addr{address6}, true},
+		{"independent", true, dns.DomainStrategyPreferIPv4, nil, false},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			cache := newDNSCache(16, testCase.independent, false)
+			wrapped := cache.Wrap(&testDNSTransport{name: "test", ttl: 60, addresses: []netip.Addr{address4, address6}})
+			for _, queryType := range []uint16{mDNS.TypeA, mDNS.TypeAAAA} {
+				_, err := wrapped.Exchange(context.Background(), newTestQuery("example.com", queryType))
+				require.NoError(t, err)
+			}
+			addresses, cached := cache.LookupCache(context.Background(), "example.com.", testCase.strategy)
+			require.Equal(t, testCase.cached, cached)
+			require.Equal(t, testCase.expected, addresses)
+			response, cached := cache.ExchangeCache(context.Background(), newTestQuery("example.com", mDNS.TypeA))
+			require.Equal(t, testCase.cached, cached)
+			if cached {
+				require.Len(t, response.Answer, 1)
+			}
+		})
+	}
+}
+
+func TestDNSCacheLookupTTL(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name    string
+		ctx     context.Context
+		timeout time.Duration
+	}{
+		{"default", context.Background(), dns.DefaultTTL * time.Second},
+		{"rewrite", dns.ContextWithRewriteTTL(context.Background(), 10), 10 * time.Second},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			cache := newDNSCache(16, false, false)
+			transport := &testDNSTransport{addresses: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}
+			wrapped := cache.Wrap(transport)
+			for i := 0; i < 2; i++ {
+				addresses, err := wrapped.Lookup(testCase.ctx, "example.com", dns.DomainStrategyAsIS)
+				require.NoError(t, err)
+				require.Equal(t, transport.addresses, addresses)
+			}
+			require.Equal(t, 1, transport.lookups)
+			_, expireAt, loaded := cache.lookupCache.LoadWithExpire(dnsLookupKey{"example.com", dns.DomainStrategyAsIS, ""})
+			require.True(t, loaded)
+			require.WithinDuration(t, time.Now().Add(testCase.timeout), expireAt, 5*time.Second)
+		})
+	}
+}
+
+func TestDNSCacheCapacity(t *testing.T) {
+	t.Parallel()
+	cache := newDNSCache(2, false, false)
+	transport := &testDNSTransport{ttl: 60, addresses: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}
+	wrapped := cache.Wrap(transport)
+	for _, name := range []string{"a.com", "b.com", "c.com", "a.com"} {
+		_, err := wrapped.Exchange(context.Background(), newTestQuery(name, mDNS.TypeA))
+		require.NoError(t, err)
+	}
+	require.Equal(t, 4, transport.exchanges)
+	_, cached := cache.ExchangeCache(context.Background(), newTestQuery("c.com", mDNS.TypeA))
+	require.True(t, cached)
+	_, cached = cache.ExchangeCache(context.Background(), newTestQuery("b.com", mDNS.TypeA))
+	require.False(t, cached)
+}
+
+func TestDNSCacheRestore(t *testing.T) {
+	t.Parallel()
+	cache := newDNSCache(2, false, false)
+	transport := &testDNSTransport{ttl: 60, addresses: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}
+	wrapped := cache.Wrap(transport)
+	for _, name := range []string{"a.com", "b.com", "a.com"} {
+		_, err := wrapped.Exchange(context.Background(), newTestQuery(name, mDNS.TypeA))
+		require.NoError(t, err)
+	}
+	responses := cache.Dump()
+	require.Len(t, responses, 2)
+	restored := newDNSCache(2, false, false)
+	require.Equal(t, 2, restored.Restore(responses))
+	response, cached := restored.ExchangeCache(context.Background(), newTestQuery("a.com", mDNS.TypeA))
+	require.True(t, cached)
+	require.Len(t, response.Answer, 1)
+	require.LessOrEqual(t, response.Answer[0].Header().Ttl, uint32(60))
+	_, err := restored.Wrap(transport).Exchange(context.Background(), newTestQuery("c.com", mDNS.TypeA))
+	require.NoError(t, err)
+	_, cached = restored.ExchangeCache(context.Background(), newTestQuery("b.com", mDNS.TypeA))
+	require.False(t, cached)
+	responses[0].ExpireAt = time.Now().Add(-time.Second)
+	require.Equal(t, 1, newDNSCache(2, false, false).Restore(responses))
+	require.Zero(t, newDNSCache(2, true, false).Restore(responses))
+}
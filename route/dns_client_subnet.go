@@ -0,0 +1,34 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// wrapClientSubnet appends the server or global client subnet to queries of
+// transport. It replaces the wrapper of dns.CreateTransport, which hides
+// FakeIP transports from type assertions.
+func wrapClientSubnet(transport dns.Transport, clientSubnet netip.Prefix) dns.Transport {
+	if !clientSubnet.IsValid() {
+		return transport
+	}
+	if _, isFakeIP := transport.(adapter.FakeIPTransport); isFakeIP {
+		return transport
+	}
+	return &clientSubnetTransport{transport, clientSubnet}
+}
+
+type clientSubnetTransport struct {
+	dns.Transport
+	clientSubnet netip.Prefix
+}
+
+func (t *clientSubnetTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	dns.SetClientSubnet(message, t.clientSubnet, false)
+	return t.Transport.Exchange(ctx, message)
+}
@@ -0,0 +1,50 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWrapClientSubnet(t *testing.T) {
+	t.Parallel()
+	clientSubnet := netip.MustParsePrefix("203.0.113.0/24")
+	fakeTransport := &testFakeIPTransport{testDNSTransport{name: "fake"}}
+	_, isFakeIP := wrapClientSubnet(fakeTransport, clientSubnet).(adapter.FakeIPTransport)
+	require.True(t, isFakeIP)
+	upstream := &testDNSTransport{name: "remote"}
+	require.Equal(t, upstream, wrapClientSubnet(upstream, netip.Prefix{}))
+	wrapped := wrapClientSubnet(upstream, clientSubnet)
+	for _, testCase := range []struct {
+		name   string
+		subnet netip.Prefix
+		expect netip.Prefix
+	}{
+		{"server", netip.Prefix{}, clientSubnet},
+		{"query", netip.MustParsePrefix("198.51.100.0/24"), netip.MustParsePrefix("198.51.100.0/24")},
+	} {
+		query := newTestQuery("example.com", mDNS.TypeA)
+		if testCase.subnet.IsValid() {
+			query.SetEdns0(1232, false)
+			query.IsEdns0().Option = append(query.IsEdns0().Option, &mDNS.EDNS0_SUBNET{
+				Code:          mDNS.EDNS0SUBNET,
+				Family:        1,
+				SourceNetmask: uint8(testCase.subnet.Bits()),
+				Address:       testCase.subnet.Addr().AsSlice(),
+			})
+		}
+		_, err := wrapped.Exchange(context.Background(), query)
+		require.NoError(t, err)
+		opt := query.IsEdns0()
+		require.NotNil(t, opt, testCase.name)
+		require.Len(t, opt.Option, 1, testCase.name)
+		subnet := opt.Option[0].(*mDNS.EDNS0_SUBNET)
+		address, _ := netip.AddrFromSlice(subnet.Address)
+		require.Equal(t, testCase.expect, netip.PrefixFrom(address.Unmap(), int(subnet.SourceNetmask)), testCase.name)
+	}
+}
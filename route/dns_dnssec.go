@@ -0,0 +1,454 @@
+package route
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+// rootTrustAnchors are the DS records of the root zone KSKs.
+var rootTrustAnchors = []*mDNS.DS{
+	{KeyTag: 20326, Algorithm: mDNS.RSASHA256, DigestType: mDNS.SHA256, Digest: "E06D44B80B8F1D39A95C0B0D7C65D08458E880409BBC683457104237C7F8EC8D"},
+	{KeyTag: 38696, Algorithm: mDNS.RSASHA256, DigestType: mDNS.SHA256, Digest: "683D2D0ACB8C9B712A1948B27F741219298D0A450D612C483AF444A4C0FB2B16"},
+}
+
+const (
+	dnssecMaxDepth = 32
+	dnssecUDPSize  = 1232
+)
+
+// dnssecTransport requests DNSSEC records from the upstream and reports
+// whether responses are validated, with the AD bit and in the metadata of the
+// context. In full mode, signatures are verified up to the root trust anchors
+// and bogus responses are rejected, otherwise the AD bit of the upstream is
+// trusted.
+type dnssecTransport struct {
+	dns.Transport
+	full         bool
+	trustAnchors []*mDNS.DS
+	access       sync.Mutex
+	zoneKeys     map[string]dnssecZoneKeys
+}
+
+type dnssecResultKey struct{}
+
+// dnssecResult collects whether all responses of a query are validated.
+type dnssecResult struct {
+	exchanged atomic.Bool
+	insecure  atomic.Bool
+}
+
+func contextWithDNSSECResult(ctx context.Context) (context.Context, *dnssecResult) {
+	result := new(dnssecResult)
+	return context.WithValue(ctx, (*dnssecResultKey)(nil), result), result
+}
+
+func dnssecResultFromContext(ctx context.Context) *dnssecResult {
+	result, _ := ctx.Value((*dnssecResultKey)(nil)).(*dnssecResult)
+	return result
+}
+
+func (r *dnssecResult) report(validated bool) {
+	r.exchanged.Store(true)
+	if !validated {
+		r.insecure.Store(true)
+	}
+}
+
+func (r *dnssecResult) Validated() bool {
+	return r.exchanged.Load() && !r.insecure.Load()
+}
+
+type dnssecZoneKeys struct {
+	keys     []*mDNS.DNSKEY
+	expireAt time.Time
+}
+
+func wrapDNSSEC(transport dns.Transport, mode string) (dns.Transport, error) {
+	switch mode {
+	case "":
+		return transport, nil
+	case C.DNSSECModeAD, C.DNSSECModeFull:
+	default:
+		return nil, E.New("unknown dnssec mode: ", mode)
+	}
+	if _, isFakeIP := transport.(adapter.FakeIPTransport); isFakeIP || !transport.Raw() {
+		return nil, E.New("dnssec requires a server with raw queries")
+	}
+	return &dnssecTransport{
+		Transport:    transport,
+		full:         mode == C.DNSSECModeFull,
+		trustAnchors: rootTrustAnchors,
+		zoneKeys:     make(map[string]dnssecZoneKeys),
+	}, nil
+}
+
+func (t *dnssecTransport) Reset() {
+	t.access.Lock()
+	t.zoneKeys = make(map[string]dnssecZoneKeys)
+	t.access.Unlock()
+	t.Transport.Reset()
+}
+
+func (t *dnssecTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	query := message.Copy()
+	var requestDNSSEC bool
+	if opt := query.IsEdns0(); opt != nil {
+		requestDNSSEC = opt.Do()
+		opt.SetDo()
+	} else {
+		query.SetEdns0(dnssecUDPSize, true)
+	}
+	response, err := t.Transport.Exchange(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	var validated bool
+	if t.full {
+		validated, err = t.validate(ctx, response)
+		if err != nil {
+			return nil, E.Cause(err, "dnssec validation")
+		}
+	} else {
+		validated = response.AuthenticatedData
+	}
+	response.Id = message.Id
+	response.AuthenticatedData = validated
+	if !requestDNSSEC {
+		response.Answer = common.Filter(response.Answer, isNotDNSSECRecord)
+		response.Ns = common.Filter(response.Ns, isNotDNSSECRecord)
+	}
+	if result := dnssecResultFromContext(ctx); result != nil {
+		result.report(validated)
+	}
+	return response, nil
+}
+
+func isNotDNSSECRecord(record mDNS.RR) bool {
+	switch record.Header().Rrtype {
+	case mDNS.TypeRRSIG, mDNS.TypeNSEC, mDNS.TypeNSEC3:
+		return false
+	default:
+		return true
+	}
+}
+
+// validate verifies the signatures of response. Only positive responses with
+// all answers signed by secure zones are validated, since denial of existence
+// proofs are not checked.
+func (t *dnssecTransport) validate(ctx context.Context, response *mDNS.Msg) (bool, error) {
+	switch response.Rcode {
+	case mDNS.RcodeSuccess:
+		if len(response.Answer) > 0 {
+			return t.verifySection(ctx, response.Answer, 0)
+		}
+		fallthrough
+	case mDNS.RcodeNameError:
+		_, err := t.verifySection(ctx, response.Ns, 0)
+		return false, err
+	default:
+		return false, nil
+	}
+}
+
+func (t *dnssecTransport) verifySection(ctx context.Context, records []mDNS.RR, depth int) (bool, error) {
+	type rrsetKey struct {
+		name      string
+		queryType uint16
+	}
+	rrsets := make(map[rrsetKey][]mDNS.RR)
+	signatures := make(map[rrsetKey][]*mDNS.RRSIG)
+	for _, record := range records {
+		header := record.Header()
+		if signature, isSignature := record.(*mDNS.RRSIG); isSignature {
+			key := rrsetKey{mDNS.CanonicalName(header.Name), signature.TypeCovered}
+			signatures[key] = append(signatures[key], signature)
+		} else if header.Rrtype != mDNS.TypeOPT {
+			key := rrsetKey{mDNS.CanonicalName(header.Name), header.Rrtype}
+			rrsets[key] = append(rrsets[key], record)
+		}
+	}
+	secure := len(rrsets) > 0
+	for key, rrset := range rrsets {
+		rrsetSecure, err := t.verifyRRSet(ctx, key.name, rrset, signatures[key], depth)
+		if err != nil {
+			return false, err
+		}
+		if !rrsetSecure {
+			secure = false
+		}
+	}
+	return secure, nil
+}
+
+// verifyRRSet returns false if rrset is in or signed by an insecure zone, and
+// an error if signatures are bogus or missing in a secure zone.
+func (t *dnssecTransport) verifyRRSet(ctx context.Context, name string, rrset []mDNS.RR, signatures []*mDNS.RRSIG, depth int) (bool, error) {
+	if len(signatures) == 0 {
+		secure, err := t.zoneSecure(ctx, name, depth)
+		if err != nil {
+			return false, err
+		}
+		if secure {
+			return false, E.New("missing signature of ", name, " ", mDNS.TypeToString[rrset[0].Header().Rrtype])
+		}
+		return false, nil
+	}
+	var lastErr error
+	for _, signature := range signatures {
+		if !mDNS.IsSubDomain(signature.SignerName, name) {
+			lastErr = E.New("signer ", signature.SignerName, " is not a parent")
+			continue
+		}
+		if !signature.ValidityPeriod(time.Now()) {
+			lastErr = E.New("signature expired")
+			continue
+		}
+		keys, err := t.loadZoneKeys(ctx, signature.SignerName, depth+1)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if keys == nil {
+			return false, nil
+		}
+		for _, key := range keys {
+			if key.KeyTag() != signature.KeyTag || key.Algorithm != signature.Algorithm {
+				continue
+			}
+			err = signature.Verify(key, rrset)
+			if err == nil {
+				return true, nil
+			}
+			lastErr = err
+		}
+		if lastErr == nil {
+			lastErr = E.New("missing key ", signature.KeyTag)
+		}
+	}
+	return false, E.Cause(lastErr, "verify ", name, " ", mDNS.TypeToString[rrset[0].Header().Rrtype])
+}
+
+// loadZoneKeys returns the verified DNSKEYs of zone, or nil if the zone is
+// insecure.
+func (t *dnssecTransport) loadZoneKeys(ctx context.Context, zone string, depth int) ([]*mDNS.DNSKEY, error) {
+	zone = mDNS.CanonicalName(zone)
+	t.access.Lock()
+	zoneKeys, loaded := t.zoneKeys[zone]
+	t.access.Unlock()
+	if loaded && time.Now().Before(zoneKeys.expireAt) {
+		return zoneKeys.keys, nil
+	}
+	if depth > dnssecMaxDepth {
+		return nil, E.New("too deep chain of trust")
+	}
+	var delegations []*mDNS.DS
+	if zone == "." {
+		delegations = t.trustAnchors
+	} else {
+		response, err := t.query(ctx, zone, mDNS.TypeDS)
+		if err != nil {
+			return nil, err
+		}
+		delegations = common.Map(common.Filter(response.Answer, func(it mDNS.RR) bool {
+			_, isDS := it.(*mDNS.DS)
+			return isDS
+		}), func(it mDNS.RR) *mDNS.DS {
+			return it.(*mDNS.DS)
+		})
+		var secure bool
+		if len(delegations) > 0 {
+			secure, err = t.verifySection(ctx, response.Answer, depth)
+		} else {
+			err = t.verifyNoDelegation(ctx, zone, response, depth)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !secure {
+			t.storeZoneKeys(zone, nil, dns.DefaultTTL)
+			return nil, nil
+		}
+	}
+	response, err := t.query(ctx, zone, mDNS.TypeDNSKEY)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		keyRecords []mDNS.RR
+		keys       []*mDNS.DNSKEY
+		signatures []*mDNS.RRSIG
+	)
+	for _, record := range response.Answer {
+		switch answer := record.(type) {
+		case *mDNS.DNSKEY:
+			keyRecords = append(keyRecords, answer)
+			keys = append(keys, answer)
+		case *mDNS.RRSIG:
+			if answer.TypeCovered == mDNS.TypeDNSKEY {
+				signatures = append(signatures, answer)
+			}
+		}
+	}
+	for _, key := range keys {
+		if !common.Any(delegations, func(delegation *mDNS.DS) bool {
+			return matchDelegation(key, delegation)
+		}) {
+			continue
+		}
+		for _, signature := range signatures {
+			if signature.KeyTag != key.KeyTag() || !signature.ValidityPeriod(time.Now()) {
+				continue
+			}
+			if signature.Verify(key, keyRecords) == nil {
+				t.storeZoneKeys(zone, keys, keyRecords[0].Header().Ttl)
+				return keys, nil
+			}
+		}
+	}
+	return nil, E.New("no trusted DNSKEY for ", zone)
+}
+
+// verifyNoDelegation checks the response to the DS query of zone without DS
+// records. The zone is insecure if its parent zone is insecure, or if a
+// signed NSEC or NSEC3 record of the parent denies the DS records.
+func (t *dnssecTransport) verifyNoDelegation(ctx context.Context, zone string, response *mDNS.Msg, depth int) error {
+	if response.Rcode != mDNS.RcodeSuccess {
+		return E.New("query DS of ", zone, ": ", mDNS.RcodeToString[response.Rcode])
+	}
+	var parent string
+	for _, record := range response.Ns {
+		if record.Header().Rrtype != mDNS.TypeSOA {
+			continue
+		}
+		name := mDNS.CanonicalName(record.Header().Name)
+		if name != zone && mDNS.IsSubDomain(name, zone) {
+			parent = name
+			break
+		}
+	}
+	if parent == "" {
+		return E.New("missing parent zone of ", zone)
+	}
+	keys, err := t.loadZoneKeys(ctx, parent, depth+1)
+	if err != nil || keys == nil {
+		return err
+	}
+	secure, err := t.verifySection(ctx, response.Ns, depth)
+	if err != nil {
+		return err
+	}
+	if secure {
+		for _, record := range response.Ns {
+			if denyDelegation(record, zone, parent) {
+				return nil
+			}
+		}
+	}
+	return E.New("missing denial of DS for ", zone)
+}
+
+// denyDelegation reports whether record proves that zone is delegated from
+// parent without DS records. NSEC3 opt-out is accepted when covering zone or
+// an empty non-terminal between zone and parent.
+func denyDelegation(record mDNS.RR, zone string, parent string) bool {
+	switch denial := record.(type) {
+	case *mDNS.NSEC:
+		return mDNS.CanonicalName(denial.Hdr.Name) == zone && !common.Contains(denial.TypeBitMap, mDNS.TypeDS) && !common.Contains(denial.TypeBitMap, mDNS.TypeSOA)
+	case *mDNS.NSEC3:
+		if denial.Match(zone) {
+			return !common.Contains(denial.TypeBitMap, mDNS.TypeDS) && !common.Contains(denial.TypeBitMap, mDNS.TypeSOA)
+		}
+		if denial.Flags&1 == 0 {
+			return false
+		}
+		for name := zone; name != parent; name = parentName(name) {
+			if denial.Cover(name) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// zoneSecure reports whether the zone containing name has verified keys. The
+// zone is found with the SOA record returned for name or its parents, unless
+// a parent zone is already known to be insecure.
+func (t *dnssecTransport) zoneSecure(ctx context.Context, name string, depth int) (bool, error) {
+	name = mDNS.CanonicalName(name)
+	now := time.Now()
+	t.access.Lock()
+	for parent := name; ; parent = parentName(parent) {
+		zoneKeys, loaded := t.zoneKeys[parent]
+		if loaded && zoneKeys.keys == nil && now.Before(zoneKeys.expireAt) {
+			t.access.Unlock()
+			return false, nil
+		}
+		if parent == "." {
+			break
+		}
+	}
+	t.access.Unlock()
+	for parent := name; ; parent = parentName(parent) {
+		response, err := t.query(ctx, parent, mDNS.TypeSOA)
+		if err != nil {
+			return false, err
+		}
+		for _, record := range append(response.Answer, response.Ns...) {
+			if record.Header().Rrtype != mDNS.TypeSOA {
+				continue
+			}
+			zone := mDNS.CanonicalName(record.Header().Name)
+			if mDNS.IsSubDomain(zone, name) {
+				keys, err := t.loadZoneKeys(ctx, zone, depth+1)
+				return keys != nil, err
+			}
+		}
+		if parent == "." {
+			return false, E.New("missing zone of ", name)
+		}
+	}
+}
+
+func parentName(name string) string {
+	offset, end := mDNS.NextLabel(name, 0)
+	if end {
+		return "."
+	}
+	return name[offset:]
+}
+
+func matchDelegation(key *mDNS.DNSKEY, delegation *mDNS.DS) bool {
+	if key.KeyTag() != delegation.KeyTag || key.Algorithm != delegation.Algorithm {
+		return false
+	}
+	keyDelegation := key.ToDS(delegation.DigestType)
+	return keyDelegation != nil && strings.EqualFold(keyDelegation.Digest, delegation.Digest)
+}
+
+func (t *dnssecTransport) storeZoneKeys(zone string, keys []*mDNS.DNSKEY, ttl uint32) {
+	t.access.Lock()
+	t.zoneKeys[zone] = dnssecZoneKeys{keys, time.Now().Add(time.Duration(ttl) * time.Second)}
+	t.access.Unlock()
+}
+
+func (t *dnssecTransport) query(ctx context.Context, name string, queryType uint16) (*mDNS.Msg, error) {
+	query := new(mDNS.Msg)
+	query.SetQuestion(name, queryType)
+	query.SetEdns0(dnssecUDPSize, true)
+	response, err := t.Transport.Exchange(ctx, query)
+	if err != nil {
+		return nil, E.Cause(err, "query ", mDNS.TypeToString[queryType], " of ", name)
+	}
+	return response, nil
+}
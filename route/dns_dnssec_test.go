@@ -0,0 +1,167 @@
+package route
+
+import (
+	"context"
+	"crypto"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testDNSSECUpstream struct {
+	dns.Transport
+	records     map[string][]mDNS.RR
+	authorities map[string][]mDNS.RR
+	ad          bool
+}
+
+func (t *testDNSSECUpstream) Name() string {
+	return "upstream"
+}
+
+func (t *testDNSSECUpstream) Raw() bool {
+	return true
+}
+
+func (t *testDNSSECUpstream) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.AuthenticatedData = t.ad
+	question := message.Question[0]
+	response.Answer = t.records[question.Name+" "+mDNS.TypeToString[question.Qtype]]
+	response.Ns = t.authorities[question.Name+" "+mDNS.TypeToString[question.Qtype]]
+	return response, nil
+}
+
+type testDNSSECZone struct {
+	key        *mDNS.DNSKEY
+	privateKey crypto.PrivateKey
+}
+
+func newTestDNSSECZone(t *testing.T, name string) *testDNSSECZone {
+	key := &mDNS.DNSKEY{
+		Hdr:       mDNS.RR_Header{Name: name, Rrtype: mDNS.TypeDNSKEY, Class: mDNS.ClassINET, Ttl: 3600},
+		Flags:     257,
+		Protocol:  3,
+		Algorithm: mDNS.ECDSAP256SHA256,
+	}
+	privateKey, err := key.Generate(256)
+	require.NoError(t, err)
+	return &testDNSSECZone{key, privateKey}
+}
+
+func (z *testDNSSECZone) sign(t *testing.T, rrset ...mDNS.RR) []mDNS.RR {
+	signature := &mDNS.RRSIG{
+		Hdr:        mDNS.RR_Header{Name: rrset[0].Header().Name, Rrtype: mDNS.TypeRRSIG, Class: mDNS.ClassINET, Ttl: rrset[0].Header().Ttl},
+		Algorithm:  z.key.Algorithm,
+		SignerName: z.key.Hdr.Name,
+		KeyTag:     z.key.KeyTag(),
+		Inception:  uint32(time.Now().Add(-time.Hour).Unix()),
+		Expiration: uint32(time.Now().Add(time.Hour).Unix()),
+	}
+	require.NoError(t, signature.Sign(z.privateKey.(crypto.Signer), rrset))
+	return append(rrset, signature)
+}
+
+func newTestA(name string, address string) *mDNS.A {
+	return &mDNS.A{
+		Hdr: mDNS.RR_Header{Name: name, Rrtype: mDNS.TypeA, Class: mDNS.ClassINET, Ttl: 60},
+		A:   net.ParseIP(address),
+	}
+}
+
+func newTestSOA(name string) *mDNS.SOA {
+	return &mDNS.SOA{
+		Hdr:     mDNS.RR_Header{Name: name, Rrtype: mDNS.TypeSOA, Class: mDNS.ClassINET, Ttl: 60},
+		Ns:      "ns." + strings.TrimPrefix(name, "."),
+		Mbox:    "hostmaster." + strings.TrimPrefix(name, "."),
+		Refresh: 3600,
+		Retry:   600,
+		Expire:  86400,
+		Minttl:  60,
+	}
+}
+
+func newTestNSEC(name string, nextDomain string, types ...uint16) *mDNS.NSEC {
+	return &mDNS.NSEC{
+		Hdr:        mDNS.RR_Header{Name: name, Rrtype: mDNS.TypeNSEC, Class: mDNS.ClassINET, Ttl: 60},
+		NextDomain: nextDomain,
+		TypeBitMap: types,
+	}
+}
+
+func TestDNSSECTransport(t *testing.T) {
+	t.Parallel()
+	root := newTestDNSSECZone(t, ".")
+	example := newTestDNSSECZone(t, "example.")
+	insecure := newTestDNSSECZone(t, "insecure.")
+	unproven := newTestDNSSECZone(t, "unproven.")
+	badRecords := example.sign(t, newTestA("bad.example.", "1.2.3.4"))
+	badRecords[0] = newTestA("bad.example.", "5.6.7.8")
+	upstream := &testDNSSECUpstream{records: map[string][]mDNS.RR{
+		". DNSKEY":          root.sign(t, root.key),
+		"example. DS":       root.sign(t, example.key.ToDS(mDNS.SHA256)),
+		"example. DNSKEY":   example.sign(t, example.key),
+		"www.example. A":    example.sign(t, newTestA("www.example.", "1.2.3.4")),
+		"bad.example. A":    badRecords,
+		"plain.example. A":  {newTestA("plain.example.", "1.2.3.4")},
+		"insecure. DNSKEY":  insecure.sign(t, insecure.key),
+		"www.insecure. A":   insecure.sign(t, newTestA("www.insecure.", "1.2.3.4")),
+		"wrong.example. A":  insecure.sign(t, newTestA("wrong.example.", "1.2.3.4")),
+		"plain.insecure. A": {newTestA("plain.insecure.", "1.2.3.4")},
+		"unproven. DNSKEY":  unproven.sign(t, unproven.key),
+		"www.unproven. A":   unproven.sign(t, newTestA("www.unproven.", "1.2.3.4")),
+	}, authorities: map[string][]mDNS.RR{
+		"plain.example. SOA":  example.sign(t, newTestSOA("example.")),
+		"insecure. DS":        append(root.sign(t, newTestSOA(".")), root.sign(t, newTestNSEC("insecure.", "unproven.", mDNS.TypeNS, mDNS.TypeRRSIG, mDNS.TypeNSEC))...),
+		"plain.insecure. SOA": {newTestSOA("insecure.")},
+		"unproven. DS":        root.sign(t, newTestSOA(".")),
+	}}
+	transport := &dnssecTransport{
+		Transport:    upstream,
+		full:         true,
+		trustAnchors: []*mDNS.DS{root.key.ToDS(mDNS.SHA256)},
+		zoneKeys:     make(map[string]dnssecZoneKeys),
+	}
+	for _, testCase := range []struct {
+		name      string
+		validated bool
+		bogus     bool
+	}{
+		{"www.example.", true, false},
+		{"bad.example.", false, true},
+		{"plain.example.", false, true},
+		{"www.insecure.", false, false},
+		{"plain.insecure.", false, false},
+		{"www.unproven.", false, true},
+		{"wrong.example.", false, true},
+	} {
+		ctx, result := contextWithDNSSECResult(context.Background())
+		query := newTestQuery(testCase.name, mDNS.TypeA)
+		response, err := transport.Exchange(ctx, query)
+		if testCase.bogus {
+			require.Error(t, err, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, testCase.validated, response.AuthenticatedData, testCase.name)
+		require.Equal(t, testCase.validated, result.Validated(), testCase.name)
+		for _, record := range response.Answer {
+			require.NotEqual(t, mDNS.TypeRRSIG, record.Header().Rrtype, testCase.name)
+		}
+	}
+	_, err := wrapDNSSEC(upstream, "strict")
+	require.Error(t, err)
+	adTransport, err := wrapDNSSEC(upstream, "ad")
+	require.NoError(t, err)
+	upstream.ad = true
+	response, err := adTransport.Exchange(context.Background(), newTestQuery("plain.example.", mDNS.TypeA))
+	require.NoError(t, err)
+	require.True(t, response.AuthenticatedData)
+}
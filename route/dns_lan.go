@@ -0,0 +1,138 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+const lanDNSTimeout = time.Second
+
+var (
+	mDNSAddress  = M.ParseSocksaddrHostPort("224.0.0.251", 5353)
+	llmnrAddress = M.ParseSocksaddrHostPort("224.0.0.252", 5355)
+)
+
+var _ dns.Transport = (*lanDNSTransport)(nil)
+
+// lanDNSTransport resolves .local names with mDNS and single-label names with
+// LLMNR, by one-shot multicast queries from the default interface. Responders
+// answer such queries with unicast responses to the source port.
+type lanDNSTransport struct {
+	dialer       N.Dialer
+	mDNSAddress  M.Socksaddr
+	llmnrAddress M.Socksaddr
+}
+
+func newLANDNSTransport(dialer N.Dialer) *lanDNSTransport {
+	return &lanDNSTransport{
+		dialer:       dialer,
+		mDNSAddress:  mDNSAddress,
+		llmnrAddress: llmnrAddress,
+	}
+}
+
+// isLANHostname reports whether domain should be resolved on the LAN.
+func isLANHostname(domain string) bool {
+	domain = strings.ToLower(strings.TrimSuffix(domain, "."))
+	if domain == "" || domain == "localhost" {
+		return false
+	}
+	return !strings.Contains(domain, ".") || strings.HasSuffix(domain, ".local")
+}
+
+func (t *lanDNSTransport) Name() string {
+	return "lan"
+}
+
+func (t *lanDNSTransport) Start() error {
+	return nil
+}
+
+func (t *lanDNSTransport) Reset() {
+}
+
+func (t *lanDNSTransport) Close() error {
+	return nil
+}
+
+func (t *lanDNSTransport) Raw() bool {
+	return true
+}
+
+func (t *lanDNSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(message.Question) != 1 {
+		return nil, E.New("bad question count: ", len(message.Question))
+	}
+	destination := t.llmnrAddress
+	if strings.Contains(strings.TrimSuffix(message.Question[0].Name, "."), ".") {
+		destination = t.mDNSAddress
+	}
+	conn, err := t.dialer.ListenPacket(ctx, destination)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	query := message.Copy()
+	query.RecursionDesired = false
+	query.Extra = nil
+	rawQuery, err := query.Pack()
+	if err != nil {
+		return nil, err
+	}
+	_, err = conn.WriteTo(rawQuery, destination.UDPAddr())
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().Add(lanDNSTimeout)
+	if ctxDeadline, loaded := ctx.Deadline(); loaded && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	err = conn.SetReadDeadline(deadline)
+	if err != nil {
+		return nil, err
+	}
+	buffer := buf.NewPacket()
+	defer buffer.Release()
+	for {
+		buffer.Reset()
+		n, _, err := conn.ReadFrom(buffer.FreeBytes())
+		if err != nil {
+			if E.IsTimeout(err) {
+				response := new(mDNS.Msg)
+				response.SetRcode(message, mDNS.RcodeNameError)
+				return response, nil
+			}
+			return nil, err
+		}
+		var response mDNS.Msg
+		err = response.Unpack(buffer.FreeBytes()[:n])
+		if err != nil || !response.Response || response.Id != message.Id {
+			continue
+		}
+		if len(response.Question) > 0 && !strings.EqualFold(response.Question[0].Name, message.Question[0].Name) {
+			continue
+		}
+		// clear the cache-flush bit of mDNS records
+		for _, record := range response.Answer {
+			record.Header().Class &^= 1 << 15
+		}
+		response.Question = message.Question
+		response.RecursionAvailable = true
+		return &response, nil
+	}
+}
+
+func (t *lanDNSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
@@ -0,0 +1,71 @@
+package route
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsLANHostname(t *testing.T) {
+	t.Parallel()
+	for domain, expected := range map[string]bool{
+		"printer":         true,
+		"nas.":            true,
+		"printer.local":   true,
+		"Printer.LOCAL.":  true,
+		"localhost":       false,
+		"example.com":     false,
+		"local.example":   false,
+		"":                false,
+		"sub.host.local.": true,
+	} {
+		require.Equal(t, expected, isLANHostname(domain), domain)
+	}
+}
+
+func TestLANDNSTransport(t *testing.T) {
+	t.Parallel()
+	responder, err := net.ListenPacket("udp4", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer responder.Close()
+	go func() {
+		buffer := make([]byte, 512)
+		for {
+			n, source, err := responder.ReadFrom(buffer)
+			if err != nil {
+				return
+			}
+			var query mDNS.Msg
+			if query.Unpack(buffer[:n]) != nil {
+				continue
+			}
+			response := new(mDNS.Msg)
+			response.SetReply(&query)
+			response.Authoritative = true
+			record, _ := mDNS.NewRR(query.Question[0].Name + " 120 IN A 192.168.1.2")
+			record.Header().Class |= 1 << 15
+			response.Answer = append(response.Answer, record)
+			rawResponse, _ := response.Pack()
+			_, _ = responder.WriteTo(rawResponse, source)
+		}
+	}()
+	transport := newLANDNSTransport(N.SystemDialer)
+	transport.mDNSAddress = M.SocksaddrFromNet(responder.LocalAddr())
+	transport.llmnrAddress = M.ParseSocksaddrHostPort("127.0.0.1", 9)
+	query := newTestQuery("printer.local.", mDNS.TypeA)
+	response, err := transport.Exchange(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+	require.Len(t, response.Answer, 1)
+	require.Equal(t, "printer.local.\t120\tIN\tA\t192.168.1.2", response.Answer[0].String())
+	query = newTestQuery("printer.", mDNS.TypeA)
+	response, err = transport.Exchange(context.Background(), query)
+	require.NoError(t, err)
+	require.Equal(t, mDNS.RcodeNameError, response.Rcode)
+}
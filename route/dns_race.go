@@ -0,0 +1,143 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"os"
+
+	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ dns.Transport = (*raceDNSTransport)(nil)
+
+// raceDNSTransport queries all upstreams concurrently and answers with the
+// first valid response. If geoip codes are set, responses with addresses
+// outside of them are only used when no other valid response arrives.
+type raceDNSTransport struct {
+	name       string
+	upstreams  []dns.Transport
+	geoIPCodes map[string]bool
+	geoReader  func() *geoip.Reader
+}
+
+type raceDNSResult struct {
+	response *mDNS.Msg
+	err      error
+}
+
+func newRaceDNSTransport(name string, upstreams []dns.Transport, geoIPCodes []string, geoReader func() *geoip.Reader) (*raceDNSTransport, error) {
+	if len(upstreams) == 0 {
+		return nil, E.New("missing servers")
+	}
+	for _, upstream := range upstreams {
+		if !upstream.Raw() {
+			return nil, E.New("server ", upstream.Name(), " does not support raw queries")
+		}
+	}
+	transport := &raceDNSTransport{
+		name:      name,
+		upstreams: upstreams,
+		geoReader: geoReader,
+	}
+	if len(geoIPCodes) > 0 {
+		transport.geoIPCodes = make(map[string]bool)
+		for _, code := range geoIPCodes {
+			transport.geoIPCodes[code] = true
+		}
+	}
+	return transport, nil
+}
+
+func (t *raceDNSTransport) Name() string {
+	return t.name
+}
+
+func (t *raceDNSTransport) Start() error {
+	return nil
+}
+
+func (t *raceDNSTransport) Reset() {
+}
+
+func (t *raceDNSTransport) Close() error {
+	return nil
+}
+
+func (t *raceDNSTransport) Raw() bool {
+	return true
+}
+
+func (t *raceDNSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	results := make(chan raceDNSResult, len(t.upstreams))
+	for _, upstream := range t.upstreams {
+		go func(upstream dns.Transport) {
+			response, err := upstream.Exchange(ctx, message.Copy())
+			if err == nil && response.Rcode != mDNS.RcodeSuccess && response.Rcode != mDNS.RcodeNameError {
+				err = dns.RCodeError(response.Rcode)
+			}
+			if err != nil {
+				err = E.Cause(err, upstream.Name())
+			}
+			results <- raceDNSResult{response, err}
+		}(upstream)
+	}
+	var (
+		fallback *mDNS.Msg
+		errs     []error
+	)
+	for range t.upstreams {
+		result := <-results
+		if result.err != nil {
+			errs = append(errs, result.err)
+			continue
+		}
+		response := result.response
+		response.Id = message.Id
+		if t.matchGeoIP(response) {
+			return response, nil
+		}
+		if fallback == nil {
+			fallback = response
+		}
+	}
+	if fallback != nil {
+		return fallback, nil
+	}
+	return nil, E.Errors(errs...)
+}
+
+// matchGeoIP reports whether any address of response is in the geoip codes.
+// Responses without addresses always match.
+func (t *raceDNSTransport) matchGeoIP(response *mDNS.Msg) bool {
+	if t.geoIPCodes == nil {
+		return true
+	}
+	addresses, _ := dns.MessageToAddresses(response)
+	if len(addresses) == 0 {
+		return true
+	}
+	geoReader := t.geoReader()
+	for _, address := range addresses {
+		var code string
+		if !N.IsPublicAddr(address) {
+			code = "private"
+		} else if geoReader != nil {
+			code = geoReader.Lookup(address)
+		}
+		if t.geoIPCodes[code] {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *raceDNSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
@@ -0,0 +1,84 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/common/geoip"
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testRaceUpstream struct {
+	testDNSTransport
+	delay time.Duration
+	rcode int
+}
+
+type testLookupTransport struct {
+	testDNSTransport
+}
+
+func (t *testLookupTransport) Raw() bool {
+	return false
+}
+
+func (t *testRaceUpstream) Raw() bool {
+	return true
+}
+
+func (t *testRaceUpstream) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	select {
+	case <-time.After(t.delay):
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	response, err := t.testDNSTransport.Exchange(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	response.Rcode = t.rcode
+	return response, nil
+}
+
+func TestRaceDNSTransport(t *testing.T) {
+	t.Parallel()
+	noGeoReader := func() *geoip.Reader { return nil }
+	_, err := newRaceDNSTransport("race", nil, nil, noGeoReader)
+	require.Error(t, err)
+	_, err = newRaceDNSTransport("race", []dns.Transport{&testLookupTransport{testDNSTransport{name: "local"}}}, nil, noGeoReader)
+	require.Error(t, err)
+	fast := &testRaceUpstream{testDNSTransport: testDNSTransport{name: "fast", addresses: []netip.Addr{netip.MustParseAddr("1.1.1.1")}}}
+	failed := &testRaceUpstream{testDNSTransport: testDNSTransport{name: "failed"}, rcode: mDNS.RcodeServerFailure}
+	private := &testRaceUpstream{testDNSTransport: testDNSTransport{name: "private", addresses: []netip.Addr{netip.MustParseAddr("192.168.1.1")}}, delay: 50 * time.Millisecond}
+	for _, testCase := range []struct {
+		name      string
+		upstreams []dns.Transport
+		geoIP     []string
+		address   string
+	}{
+		{"first valid", []dns.Transport{failed, private, fast}, nil, "1.1.1.1"},
+		{"skip failed", []dns.Transport{failed, private}, nil, "192.168.1.1"},
+		{"prefer geoip", []dns.Transport{fast, private}, []string{"private"}, "192.168.1.1"},
+		{"geoip fallback", []dns.Transport{failed, fast}, []string{"private"}, "1.1.1.1"},
+		{"all failed", []dns.Transport{failed}, nil, ""},
+	} {
+		transport, err := newRaceDNSTransport("race", testCase.upstreams, testCase.geoIP, noGeoReader)
+		require.NoError(t, err, testCase.name)
+		query := newTestQuery("example.com.", mDNS.TypeA)
+		response, err := transport.Exchange(context.Background(), query)
+		if testCase.address == "" {
+			require.Error(t, err, testCase.name)
+			continue
+		}
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, query.Id, response.Id, testCase.name)
+		addresses, err := dns.MessageToAddresses(response)
+		require.NoError(t, err, testCase.name)
+		require.Equal(t, []netip.Addr{netip.MustParseAddr(testCase.address)}, addresses, testCase.name)
+	}
+}
@@ -0,0 +1,147 @@
+package route
+
+import (
+	"net/netip"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+
+	mDNS "github.com/miekg/dns"
+	"go4.org/netipx"
+)
+
+var _ adapter.DNSResponseFilter = (*dnsResponseFilter)(nil)
+
+type dnsResponseFilter struct {
+	blockIPv6    bool
+	blockIPSet   *netipx.IPSet
+	rewrite      bool
+	rewriteRCode dns.RCodeError
+}
+
+func newDNSResponseFilter(blockIPv6 bool, blockIPCIDR []string, rewriteRCode string) (*dnsResponseFilter, error) {
+	if !blockIPv6 && len(blockIPCIDR) == 0 && rewriteRCode == "" {
+		return nil, nil
+	}
+	filter := &dnsResponseFilter{
+		blockIPv6: blockIPv6,
+	}
+	if len(blockIPCIDR) > 0 {
+		item, err := NewIPCIDRItem(false, blockIPCIDR)
+		if err != nil {
+			return nil, E.Cause(err, "block_ip_cidr")
+		}
+		filter.blockIPSet = item.ipSet
+	}
+	if rewriteRCode != "" {
+		rCode, loaded := parseRCode(rewriteRCode)
+		if !loaded {
+			return nil, E.New("unknown rewrite_rcode: ", rewriteRCode)
+		}
+		filter.rewrite = true
+		filter.rewriteRCode = rCode
+	}
+	return filter, nil
+}
+
+// parseRCode accepts the names of rcode:// servers.
+func parseRCode(name string) (dns.RCodeError, bool) {
+	switch name {
+	case "success":
+		return dns.RCodeSuccess, true
+	case "format_error":
+		return dns.RCodeFormatError, true
+	case "server_failure":
+		return dns.RCodeServerFailure, true
+	case "name_error":
+		return dns.RCodeNameError, true
+	case "not_implemented":
+		return dns.RCodeNotImplemented, true
+	case "refused":
+		return dns.RCodeRefused, true
+	default:
+		return 0, false
+	}
+}
+
+// cacheResponse reports whether responses can be cached before filtering,
+// since cached responses may be served to other rules unfiltered.
+func (f *dnsResponseFilter) cacheResponse() bool {
+	return f.blockIPSet == nil && !f.rewrite
+}
+
+// rewriteAll reports whether rewrite_rcode applies to all responses, rather
+// than those emptied by filters.
+func (f *dnsResponseFilter) rewriteAll() bool {
+	return f.rewrite && !f.blockIPv6 && f.blockIPSet == nil
+}
+
+func (f *dnsResponseFilter) FilterQuery(message *mDNS.Msg) *mDNS.Msg {
+	if len(message.Question) != 1 {
+		return nil
+	}
+	if !f.rewriteAll() && !(f.blockIPv6 && message.Question[0].Qtype == mDNS.TypeAAAA) {
+		return nil
+	}
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.RecursionAvailable = true
+	if f.rewrite {
+		response.Rcode = int(f.rewriteRCode)
+	}
+	return response
+}
+
+func (f *dnsResponseFilter) FilterResponse(response *mDNS.Msg) *mDNS.Msg {
+	if response.Rcode != mDNS.RcodeSuccess {
+		return response
+	}
+	answer := common.Filter(response.Answer, f.keepRecord)
+	if len(answer) != len(response.Answer) {
+		response = response.Copy()
+		response.Answer = answer
+	}
+	if f.rewrite && len(response.Question) == 1 && !common.Any(response.Answer, func(record mDNS.RR) bool {
+		return record.Header().Rrtype == response.Question[0].Qtype
+	}) {
+		rewritten := new(mDNS.Msg)
+		rewritten.SetReply(response)
+		rewritten.RecursionAvailable = response.RecursionAvailable
+		rewritten.Rcode = int(f.rewriteRCode)
+		return rewritten
+	}
+	return response
+}
+
+func (f *dnsResponseFilter) keepRecord(record mDNS.RR) bool {
+	switch answer := record.(type) {
+	case *mDNS.A:
+		return f.keepAddress(M.AddrFromIP(answer.A).Unmap())
+	case *mDNS.AAAA:
+		return f.keepAddress(M.AddrFromIP(answer.AAAA))
+	default:
+		return true
+	}
+}
+
+func (f *dnsResponseFilter) keepAddress(address netip.Addr) bool {
+	if f.blockIPv6 && address.Is6() {
+		return false
+	}
+	return f.blockIPSet == nil || !f.blockIPSet.Contains(address)
+}
+
+func (f *dnsResponseFilter) FilterAddresses(addresses []netip.Addr) ([]netip.Addr, error) {
+	if f.rewriteAll() {
+		addresses = nil
+	} else {
+		addresses = common.Filter(addresses, f.keepAddress)
+	}
+	if len(addresses) == 0 && f.rewrite && f.rewriteRCode != dns.RCodeSuccess {
+		return nil, f.rewriteRCode
+	}
+	return addresses, nil
+}
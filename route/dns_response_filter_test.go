@@ -0,0 +1,141 @@
+package route
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-dns"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewDNSResponseFilter(t *testing.T) {
+	t.Parallel()
+	filter, err := newDNSResponseFilter(false, nil, "")
+	require.NoError(t, err)
+	require.Nil(t, filter)
+	_, err = newDNSResponseFilter(false, []string{"not-a-prefix"}, "")
+	require.Error(t, err)
+	_, err = newDNSResponseFilter(false, nil, "nxdomain")
+	require.Error(t, err)
+	filter, err = newDNSResponseFilter(true, nil, "")
+	require.NoError(t, err)
+	require.True(t, filter.cacheResponse())
+	filter, err = newDNSResponseFilter(false, []string{"10.0.0.0/8"}, "")
+	require.NoError(t, err)
+	require.False(t, filter.cacheResponse())
+}
+
+func TestDNSResponseFilter(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		name         string
+		blockIPv6    bool
+		blockIPCIDR  []string
+		rewriteRCode string
+		queryType    uint16
+		answer       []string
+		filtered     bool
+		rcode        int
+		result       []string
+	}{
+		{
+			name:      "block ipv6 query",
+			blockIPv6: true,
+			queryType: mDNS.TypeAAAA,
+			filtered:  true,
+		},
+		{
+			name:         "block ipv6 query with rcode",
+			blockIPv6:    true,
+			rewriteRCode: "name_error",
+			queryType:    mDNS.TypeAAAA,
+			filtered:     true,
+			rcode:        mDNS.RcodeNameError,
+		},
+		{
+			name:      "block ipv6 keeps ipv4",
+			blockIPv6: true,
+			queryType: mDNS.TypeA,
+			answer:    []string{"example.com.\t60\tIN\tA\t1.1.1.1"},
+			result:    []string{"example.com.\t60\tIN\tA\t1.1.1.1"},
+		},
+		{
+			name:        "block ip cidr",
+			blockIPCIDR: []string{"10.0.0.0/8"},
+			queryType:   mDNS.TypeA,
+			answer: []string{
+				"example.com.\t60\tIN\tCNAME\tcdn.example.com.",
+				"cdn.example.com.\t60\tIN\tA\t10.0.0.1",
+				"cdn.example.com.\t60\tIN\tA\t1.1.1.1",
+			},
+			result: []string{
+				"example.com.\t60\tIN\tCNAME\tcdn.example.com.",
+				"cdn.example.com.\t60\tIN\tA\t1.1.1.1",
+			},
+		},
+		{
+			name:         "block ip cidr with rcode",
+			blockIPCIDR:  []string{"10.0.0.0/8"},
+			rewriteRCode: "refused",
+			queryType:    mDNS.TypeA,
+			answer:       []string{"example.com.\t60\tIN\tA\t10.0.0.1"},
+			rcode:        mDNS.RcodeRefused,
+		},
+		{
+			name:         "rewrite rcode",
+			rewriteRCode: "name_error",
+			queryType:    mDNS.TypeA,
+			filtered:     true,
+			rcode:        mDNS.RcodeNameError,
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			filter, err := newDNSResponseFilter(testCase.blockIPv6, testCase.blockIPCIDR, testCase.rewriteRCode)
+			require.NoError(t, err)
+			query := new(mDNS.Msg)
+			query.SetQuestion("example.com.", testCase.queryType)
+			response := filter.FilterQuery(query)
+			if testCase.filtered {
+				require.NotNil(t, response)
+			} else {
+				require.Nil(t, response)
+				response = new(mDNS.Msg)
+				response.SetReply(query)
+				for _, answer := range testCase.answer {
+					record, err := mDNS.NewRR(answer)
+					require.NoError(t, err)
+					response.Answer = append(response.Answer, record)
+				}
+				response = filter.FilterResponse(response)
+			}
+			require.Equal(t, testCase.rcode, response.Rcode)
+			var result []string
+			for _, record := range response.Answer {
+				result = append(result, record.String())
+			}
+			require.Equal(t, testCase.result, result)
+		})
+	}
+}
+
+func TestDNSResponseFilterAddresses(t *testing.T) {
+	t.Parallel()
+	addresses := []netip.Addr{netip.MustParseAddr("10.0.0.1"), netip.MustParseAddr("1.1.1.1"), netip.MustParseAddr("2606:4700::1111")}
+	filter, err := newDNSResponseFilter(true, []string{"10.0.0.0/8"}, "")
+	require.NoError(t, err)
+	result, err := filter.FilterAddresses(addresses)
+	require.NoError(t, err)
+	require.Equal(t, []netip.Addr{netip.MustParseAddr("1.1.1.1")}, result)
+	filter, err = newDNSResponseFilter(false, []string{"0.0.0.0/0"}, "refused")
+	require.NoError(t, err)
+	_, err = filter.FilterAddresses(addresses[:2])
+	require.ErrorIs(t, err, dns.RCodeRefused)
+	filter, err = newDNSResponseFilter(false, nil, "name_error")
+	require.NoError(t, err)
+	_, err = filter.FilterAddresses(addresses)
+	require.ErrorIs(t, err, dns.RCodeNameError)
+}
@@ -0,0 +1,262 @@
+package route
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/sagernet/fswatch"
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ adapter.DNSStaticTransport = (*staticDNSTransport)(nil)
+
+// staticDNSTransport answers queries from records configured in the rule and
+// hosts files, which are reloaded on change. Records named @ answer any
+// query name.
+type staticDNSTransport struct {
+	logger     log.ContextLogger
+	records    map[string][]mDNS.RR
+	anyRecords []mDNS.RR
+	hosts      []*dnsHostsFile
+	watcher    *fswatch.Watcher
+}
+
+func newStaticDNSTransport(logger log.ContextLogger, answers []string, hostsPaths []string) (*staticDNSTransport, error) {
+	transport := &staticDNSTransport{
+		logger:  logger,
+		records: make(map[string][]mDNS.RR),
+	}
+	for i, answer := range answers {
+		record, err := mDNS.NewRR(answer)
+		if err != nil {
+			return nil, E.Cause(err, "parse answer[", i, "]")
+		}
+		if record == nil {
+			return nil, E.New("parse answer[", i, "]: empty record")
+		}
+		name := strings.ToLower(record.Header().Name)
+		if name == "." {
+			transport.anyRecords = append(transport.anyRecords, record)
+		} else {
+			transport.records[name] = append(transport.records[name], record)
+		}
+	}
+	for _, hostsPath := range hostsPaths {
+		hostsPath, _ = filepath.Abs(hostsPath)
+		hostsFile := &dnsHostsFile{path: hostsPath}
+		err := hostsFile.reload()
+		if err != nil {
+			return nil, E.Cause(err, "read hosts file")
+		}
+		transport.hosts = append(transport.hosts, hostsFile)
+	}
+	if len(transport.hosts) > 0 {
+		watcher, err := fswatch.NewWatcher(fswatch.Options{
+			Path: common.Map(transport.hosts, func(it *dnsHostsFile) string {
+				return it.path
+			}),
+			Callback: transport.reloadHosts,
+		})
+		if err != nil {
+			return nil, err
+		}
+		transport.watcher = watcher
+	}
+	return transport, nil
+}
+
+func (t *staticDNSTransport) reloadHosts(path string) {
+	for _, hostsFile := range t.hosts {
+		if hostsFile.path != path {
+			continue
+		}
+		err := hostsFile.reload()
+		if err != nil {
+			t.logger.Error(E.Cause(err, "reload hosts file ", path))
+		} else {
+			t.logger.Info("reloaded hosts file ", path)
+		}
+	}
+}
+
+func (t *staticDNSTransport) Name() string {
+	return "static"
+}
+
+func (t *staticDNSTransport) Start() error {
+	if t.watcher != nil {
+		err := t.watcher.Start()
+		if err != nil {
+			t.logger.Error(E.Cause(err, "watch hosts file"))
+		}
+	}
+	return nil
+}
+
+func (t *staticDNSTransport) Reset() {
+}
+
+func (t *staticDNSTransport) Close() error {
+	if t.watcher != nil {
+		return t.watcher.Close()
+	}
+	return nil
+}
+
+func (t *staticDNSTransport) Raw() bool {
+	return true
+}
+
+func (t *staticDNSTransport) Contains(domain string) bool {
+	if len(t.anyRecords) > 0 {
+		return true
+	}
+	name := strings.ToLower(mDNS.Fqdn(domain))
+	if len(t.records[name]) > 0 {
+		return true
+	}
+	for _, hostsFile := range t.hosts {
+		if _, loaded := hostsFile.lookup(name); loaded {
+			return true
+		}
+	}
+	return false
+}
+
+func (t *staticDNSTransport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	if len(message.Question) != 1 {
+		return nil, E.New("bad question count: ", len(message.Question))
+	}
+	question := message.Question[0]
+	response := new(mDNS.Msg)
+	response.SetReply(message)
+	response.Authoritative = true
+	response.RecursionAvailable = true
+	response.Answer = t.answer(question)
+	return response, nil
+}
+
+func (t *staticDNSTransport) answer(question mDNS.Question) []mDNS.RR {
+	var answer []mDNS.RR
+	name := strings.ToLower(question.Name)
+	for i := 0; i < 8; i++ {
+		var (
+			records = t.records[name]
+			target  string
+		)
+		if len(records) == 0 && name == strings.ToLower(question.Name) {
+			records = t.anyRecords
+		}
+		for _, record := range records {
+			header := record.Header()
+			if header.Rrtype == question.Qtype || question.Qtype == mDNS.TypeANY {
+				answer = append(answer, withName(record, name))
+			} else if cname, isCNAME := record.(*mDNS.CNAME); isCNAME {
+				answer = append(answer, withName(record, name))
+				target = strings.ToLower(cname.Target)
+			}
+		}
+		if len(records) == 0 {
+			answer = append(answer, t.lookupHosts(name, question.Qtype)...)
+		}
+		if target == "" {
+			break
+		}
+		name = target
+	}
+	return answer
+}
+
+func (t *staticDNSTransport) lookupHosts(name string, queryType uint16) []mDNS.RR {
+	var answer []mDNS.RR
+	for _, hostsFile := range t.hosts {
+		addresses, loaded := hostsFile.lookup(name)
+		if !loaded {
+			continue
+		}
+		for _, address := range addresses {
+			header := mDNS.RR_Header{Name: name, Class: mDNS.ClassINET, Ttl: dns.DefaultTTL}
+			if address.Is4() && (queryType == mDNS.TypeA || queryType == mDNS.TypeANY) {
+				header.Rrtype = mDNS.TypeA
+				answer = append(answer, &mDNS.A{Hdr: header, A: address.AsSlice()})
+			} else if address.Is6() && (queryType == mDNS.TypeAAAA || queryType == mDNS.TypeANY) {
+				header.Rrtype = mDNS.TypeAAAA
+				answer = append(answer, &mDNS.AAAA{Hdr: header, AAAA: address.AsSlice()})
+			}
+		}
+		break
+	}
+	return answer
+}
+
+func withName(record mDNS.RR, name string) mDNS.RR {
+	record = mDNS.Copy(record)
+	record.Header().Name = name
+	return record
+}
+
+func (t *staticDNSTransport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
+
+type dnsHostsFile struct {
+	path    string
+	access  sync.RWMutex
+	entries map[string][]netip.Addr
+}
+
+func (f *dnsHostsFile) reload() error {
+	content, err := os.ReadFile(f.path)
+	if err != nil {
+		return err
+	}
+	entries := parseHosts(content)
+	f.access.Lock()
+	f.entries = entries
+	f.access.Unlock()
+	return nil
+}
+
+func (f *dnsHostsFile) lookup(name string) ([]netip.Addr, bool) {
+	f.access.RLock()
+	defer f.access.RUnlock()
+	addresses, loaded := f.entries[name]
+	return addresses, loaded
+}
+
+// parseHosts reads a hosts file, keyed by lower-cased FQDN.
+func parseHosts(content []byte) map[string][]netip.Addr {
+	entries := make(map[string][]netip.Addr)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		address, err := netip.ParseAddr(fields[0])
+		if err != nil {
+			continue
+		}
+		address = address.WithZone("").Unmap()
+		for _, name := range fields[1:] {
+			name = strings.ToLower(mDNS.Fqdn(name))
+			if !common.Contains(entries[name], address) {
+				entries[name] = append(entries[name], address)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,88 @@
+package route
+
+import (
+	"context"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/sagernet/sing-box/log"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseHosts(t *testing.T) {
+	t.Parallel()
+	entries := parseHosts([]byte(`# comment
+127.0.0.1 localhost
+::1       localhost ip6-localhost # trailing comment
+192.168.1.2 NAS.lan nas
+192.168.1.2 nas.lan
+fe80::1%lo0 link.local
+bad line
+`))
+	require.Equal(t, map[string][]netip.Addr{
+		"localhost.":     {netip.MustParseAddr("127.0.0.1"), netip.MustParseAddr("::1")},
+		"ip6-localhost.": {netip.MustParseAddr("::1")},
+		"nas.lan.":       {netip.MustParseAddr("192.168.1.2")},
+		"nas.":           {netip.MustParseAddr("192.168.1.2")},
+		"link.local.":    {netip.MustParseAddr("fe80::1")},
+	}, entries)
+}
+
+func TestStaticDNSTransport(t *testing.T) {
+	t.Parallel()
+	hostsPath := filepath.Join(t.TempDir(), "hosts")
+	require.NoError(t, os.WriteFile(hostsPath, []byte("10.0.0.1 router.lan\nfd00::1 router.lan\n"), 0o644))
+	transport, err := newStaticDNSTransport(log.NewNOPFactory().Logger(), []string{
+		"nas.lan. 60 IN A 192.168.1.2",
+		"www.lan. IN CNAME nas.lan.",
+		"gw.lan. IN CNAME router.lan.",
+		`nas.lan. IN TXT "hello world"`,
+	}, []string{hostsPath})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		name      string
+		queryType uint16
+		answer    []string
+	}{
+		{"nas.lan.", mDNS.TypeA, []string{"nas.lan.\t60\tIN\tA\t192.168.1.2"}},
+		{"NAS.lan.", mDNS.TypeTXT, []string{"nas.lan.\t3600\tIN\tTXT\t\"hello world\""}},
+		{"nas.lan.", mDNS.TypeAAAA, nil},
+		{"www.lan.", mDNS.TypeA, []string{"www.lan.\t3600\tIN\tCNAME\tnas.lan.", "nas.lan.\t60\tIN\tA\t192.168.1.2"}},
+		{"gw.lan.", mDNS.TypeAAAA, []string{"gw.lan.\t3600\tIN\tCNAME\trouter.lan.", "router.lan.\t600\tIN\tAAAA\tfd00::1"}},
+		{"router.lan.", mDNS.TypeA, []string{"router.lan.\t600\tIN\tA\t10.0.0.1"}},
+	} {
+		require.True(t, transport.Contains(testCase.name), testCase.name)
+		query := new(mDNS.Msg)
+		query.SetQuestion(testCase.name, testCase.queryType)
+		response, err := transport.Exchange(context.Background(), query)
+		require.NoError(t, err)
+		require.Equal(t, mDNS.RcodeSuccess, response.Rcode)
+		var answer []string
+		for _, record := range response.Answer {
+			answer = append(answer, record.String())
+		}
+		require.Equal(t, testCase.answer, answer, testCase.name)
+	}
+	require.False(t, transport.Contains("example.com"))
+	require.NoError(t, os.WriteFile(hostsPath, []byte("10.0.0.2 switch.lan\n"), 0o644))
+	transport.reloadHosts(hostsPath)
+	require.False(t, transport.Contains("router.lan"))
+	require.True(t, transport.Contains("switch.lan"))
+
+	transport, err = newStaticDNSTransport(log.NewNOPFactory().Logger(), []string{"@ 60 IN A 0.0.0.0"}, nil)
+	require.NoError(t, err)
+	require.True(t, transport.Contains("ads.example.com"))
+	query := new(mDNS.Msg)
+	query.SetQuestion("ads.example.com.", mDNS.TypeA)
+	response, err := transport.Exchange(context.Background(), query)
+	require.NoError(t, err)
+	require.Len(t, response.Answer, 1)
+	require.Equal(t, "ads.example.com.\t60\tIN\tA\t0.0.0.0", response.Answer[0].String())
+
+	_, err = newStaticDNSTransport(log.NewNOPFactory().Logger(), []string{"nas.lan IN A not-an-address"}, nil)
+	require.Error(t, err)
+}
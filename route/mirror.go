@@ -0,0 +1,275 @@
+package route
+
+import (
+	"context"
+	"encoding/binary"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/buf"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+const (
+	mirrorDirectionUpload   = 0
+	mirrorDirectionDownload = 1
+
+	mirrorQueueSize = 64
+)
+
+// mirrorSink copies the traffic of matched connections to an analysis socket.
+// Each mirrored connection opens its own sink connection, which starts with a
+// JSON line describing the connection, followed by frames of direction (0 from
+// client, 1 to client), big-endian uint32 length and payload. UDP connections
+// send one frame per packet.
+type mirrorSink struct {
+	logger     log.ContextLogger
+	tag        string
+	network    string
+	address    string
+	sampleRate float64
+	maxBytes   int64
+}
+
+type mirrorHeader struct {
+	ID          uint32    `json:"id"`
+	Time        time.Time `json:"time"`
+	Network     string    `json:"network"`
+	Inbound     string    `json:"inbound,omitempty"`
+	InboundType string    `json:"inbound_type"`
+	User        string    `json:"user,omitempty"`
+	Source      string    `json:"source"`
+	Destination string    `json:"destination"`
+	Domain      string    `json:"domain,omitempty"`
+	Protocol    string    `json:"protocol,omitempty"`
+	Outbound    string    `json:"outbound"`
+}
+
+func newMirrorSink(logger log.ContextLogger, options option.MirrorOptions) (*mirrorSink, error) {
+	if options.Tag == "" {
+		return nil, E.New("missing tag")
+	}
+	network := options.Network
+	switch network {
+	case "":
+		network = N.NetworkTCP
+	case N.NetworkTCP, "unix":
+	default:
+		return nil, E.New("unknown network: ", network)
+	}
+	if options.Address == "" {
+		return nil, E.New("missing address")
+	}
+	sampleRate := options.SampleRate
+	if sampleRate == 0 {
+		sampleRate = 1
+	} else if sampleRate < 0 || sampleRate > 1 {
+		return nil, E.New("sample_rate must be between 0 and 1")
+	}
+	return &mirrorSink{
+		logger:     logger,
+		tag:        options.Tag,
+		network:    network,
+		address:    options.Address,
+		sampleRate: sampleRate,
+		maxBytes:   int64(options.MaxBytes),
+	}, nil
+}
+
+// NewConn returns conn unchanged if the connection is not sampled. The sink is
+// dialed in the background, so that mirroring never delays or breaks the
+// mirrored traffic.
+func (s *mirrorSink) NewConn(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, outbound string) net.Conn {
+	writer := s.newWriter(ctx, N.NetworkTCP, metadata, outbound)
+	if writer == nil {
+		return conn
+	}
+	return &mirrorConn{Conn: conn, mirrorWriter: writer}
+}
+
+func (s *mirrorSink) NewPacketConn(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, outbound string) N.PacketConn {
+	writer := s.newWriter(ctx, N.NetworkUDP, metadata, outbound)
+	if writer == nil {
+		return conn
+	}
+	return &mirrorPacketConn{PacketConn: conn, mirrorWriter: writer}
+}
+
+func (s *mirrorSink) newWriter(ctx context.Context, network string, metadata adapter.InboundContext, outbound string) *mirrorWriter {
+	if s.sampleRate < 1 && rand.Float64() >= s.sampleRate {
+		return nil
+	}
+	id, _ := log.IDFromContext(ctx)
+	header, err := json.Marshal(mirrorHeader{
+		ID:          id.ID,
+		Time:        time.Now(),
+		Network:     network,
+		Inbound:     metadata.Inbound,
+		InboundType: metadata.InboundType,
+		User:        metadata.User,
+		Source:      metadata.Source.String(),
+		Destination: metadata.Destination.String(),
+		Domain:      metadata.Domain,
+		Protocol:    metadata.Protocol,
+		Outbound:    outbound,
+	})
+	if err != nil {
+		s.logger.WarnContext(ctx, "mirror[", s.tag, "]: ", err)
+		return nil
+	}
+	s.logger.DebugContext(ctx, "mirror connection to ", s.tag)
+	writer := &mirrorWriter{
+		sink:      s,
+		ctx:       ctx,
+		remaining: s.maxBytes,
+		queue:     make(chan *buf.Buffer, mirrorQueueSize),
+	}
+	go writer.loopWrite(append(header, '\n'))
+	return writer
+}
+
+type mirrorWriter struct {
+	sink      *mirrorSink
+	ctx       context.Context
+	access    sync.Mutex
+	closed    bool
+	remaining int64
+	queue     chan *buf.Buffer
+}
+
+// mirror queues a frame without blocking; frames are dropped when the sink
+// falls behind or is still being dialed with a full queue.
+func (w *mirrorWriter) mirror(direction byte, p []byte) {
+	w.access.Lock()
+	defer w.access.Unlock()
+	if w.closed {
+		return
+	}
+	if w.remaining > 0 {
+		if int64(len(p)) > w.remaining {
+			p = p[:w.remaining]
+		}
+		w.remaining -= int64(len(p))
+		if w.remaining == 0 {
+			defer w.closeSink()
+		}
+	}
+	frame := buf.NewSize(5 + len(p))
+	header := frame.Extend(5)
+	header[0] = direction
+	binary.BigEndian.PutUint32(header[1:], uint32(len(p)))
+	frame.Write(p)
+	select {
+	case w.queue <- frame:
+	default:
+		frame.Release()
+	}
+}
+
+func (w *mirrorWriter) loopWrite(header []byte) {
+	sinkConn, err := net.DialTimeout(w.sink.network, w.sink.address, C.TCPTimeout)
+	if err == nil {
+		_, err = sinkConn.Write(header)
+	}
+	if err == nil {
+		defer sinkConn.Close()
+		for frame := range w.queue {
+			_, err = sinkConn.Write(frame.Bytes())
+			frame.Release()
+			if err != nil {
+				break
+			}
+		}
+		if err == nil {
+			return
+		}
+	} else if sinkConn != nil {
+		sinkConn.Close()
+	}
+	w.sink.logger.WarnContext(w.ctx, "mirror[", w.sink.tag, "]: ", err)
+	w.access.Lock()
+	w.closeSink()
+	w.access.Unlock()
+	for frame := range w.queue {
+		frame.Release()
+	}
+}
+
+func (w *mirrorWriter) closeSink() {
+	if !w.closed {
+		w.closed = true
+		close(w.queue)
+	}
+}
+
+func (w *mirrorWriter) close() {
+	w.access.Lock()
+	w.closeSink()
+	w.access.Unlock()
+}
+
+type mirrorConn struct {
+	net.Conn
+	*mirrorWriter
+}
+
+func (c *mirrorConn) Read(p []byte) (n int, err error) {
+	n, err = c.Conn.Read(p)
+	if n > 0 {
+		c.mirror(mirrorDirectionUpload, p[:n])
+	}
+	return
+}
+
+func (c *mirrorConn) Write(p []byte) (n int, err error) {
+	n, err = c.Conn.Write(p)
+	if n > 0 {
+		c.mirror(mirrorDirectionDownload, p[:n])
+	}
+	return
+}
+
+func (c *mirrorConn) Close() error {
+	c.close()
+	return c.Conn.Close()
+}
+
+func (c *mirrorConn) Upstream() any {
+	return c.Conn
+}
+
+type mirrorPacketConn struct {
+	N.PacketConn
+	*mirrorWriter
+}
+
+func (c *mirrorPacketConn) ReadPacket(buffer *buf.Buffer) (destination M.Socksaddr, err error) {
+	destination, err = c.PacketConn.ReadPacket(buffer)
+	if err == nil {
+		c.mirror(mirrorDirectionUpload, buffer.Bytes())
+	}
+	return
+}
+
+func (c *mirrorPacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	c.mirror(mirrorDirectionDownload, buffer.Bytes())
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *mirrorPacketConn) Close() error {
+	c.close()
+	return c.PacketConn.Close()
+}
+
+func (c *mirrorPacketConn) Upstream() any {
+	return c.PacketConn
+}
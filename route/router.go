@@ -7,10 +7,10 @@ import (
 	"net/netip"
 	"net/url"
 	"os"
-	"os/user"
 	"runtime"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
@@ -18,9 +18,12 @@ import (
 	"github.com/sagernet/sing-box/common/dialer"
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
+	"github.com/sagernet/sing-box/common/mptcp"
 	"github.com/sagernet/sing-box/common/process"
+	"github.com/sagernet/sing-box/common/relay"
 	"github.com/sagernet/sing-box/common/sniff"
 	"github.com/sagernet/sing-box/common/taskmonitor"
+	"github.com/sagernet/sing-box/common/timesync"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/experimental/libbox/platform"
 	"github.com/sagernet/sing-box/log"
@@ -51,59 +54,75 @@ import (
 var _ adapter.Router = (*Router)(nil)
 
 type Router struct {
-	ctx                                context.Context
-	logger                             log.ContextLogger
-	dnsLogger                          log.ContextLogger
-	inboundByTag                       map[string]adapter.Inbound
-	outbounds                          []adapter.Outbound
-	outboundByTag                      map[string]adapter.Outbound
-	outboundProviders                  []adapter.OutboundProvider
-	outboundProviderByTag              map[string]adapter.OutboundProvider
-	cacheAllOutbounds                  []adapter.Outbound
-	cacheAllOutboundByTag              map[string]adapter.Outbound
-	cacheAllOutboundByTagLocker        sync.Mutex
-	rules                              []adapter.Rule
-	defaultDetour                      string
-	defaultOutboundForConnection       adapter.Outbound
-	defaultOutboundForPacketConnection adapter.Outbound
-	needGeoIPDatabase                  bool
-	needGeositeDatabase                bool
-	geoIPOptions                       option.GeoIPOptions
-	geositeOptions                     option.GeositeOptions
-	geoIPReader                        *geoip.Reader
-	geositeReader                      *geosite.Reader
-	geositeCache                       map[string]adapter.Rule
-	needFindProcess                    bool
-	dnsClient                          *dns.Client
-	defaultDomainStrategy              dns.DomainStrategy
-	dnsRules                           []adapter.DNSRule
-	ruleSets                           []adapter.RuleSet
-	ruleSetMap                         map[string]adapter.RuleSet
-	defaultTransport                   dns.Transport
-	transports                         []dns.Transport
-	transportMap                       map[string]dns.Transport
-	transportDomainStrategy            map[dns.Transport]dns.DomainStrategy
-	dnsReverseMapping                  *DNSReverseMapping
-	fakeIPStore                        adapter.FakeIPStore
-	interfaceFinder                    *control.DefaultInterfaceFinder
-	autoDetectInterface                bool
-	defaultInterface                   string
-	defaultMark                        uint32
-	autoRedirectOutputMark             uint32
-	networkMonitor                     tun.NetworkUpdateMonitor
-	interfaceMonitor                   tun.DefaultInterfaceMonitor
-	packageManager                     tun.PackageManager
-	powerListener                      winpowrprof.EventListener
-	processSearcher                    process.Searcher
-	timeService                        *ntp.Service
-	pauseManager                       pause.Manager
-	clashServer                        adapter.ClashServer
-	v2rayServer                        adapter.V2RayServer
-	platformInterface                  platform.Interface
-	needWIFIState                      bool
-	needPackageManager                 bool
-	wifiState                          adapter.WIFIState
-	started                            bool
+	ctx                     context.Context
+	logger                  log.ContextLogger
+	dnsLogger               log.ContextLogger
+	state                   atomic.Pointer[routingState]
+	outboundProviders       []adapter.OutboundProvider
+	outboundProviderByTag   map[string]adapter.OutboundProvider
+	outboundSnapshot        atomic.Pointer[outboundSnapshot]
+	needGeoIPDatabase       bool
+	needGeositeDatabase     bool
+	needASNDatabase         bool
+	geoIPOptions            option.GeoIPOptions
+	geositeOptions          option.GeositeOptions
+	geoIPReader             atomic.Pointer[geoip.Reader]
+	geoIPPath               string
+	asnReader               *geoip.ASNReader
+	geositePath             string
+	geoUpdateCancel         context.CancelFunc
+	geositeReader           *geosite.Reader
+	geositeCache            map[string]adapter.Rule
+	geositeCompileCache     map[string]option.DefaultRule
+	compileDuration         time.Duration
+	needFindProcess         bool
+	needProcessPath         bool
+	dnsClient               *dns.Client
+	dnsCache                *dnsCache
+	defaultDomainStrategy   dns.DomainStrategy
+	dnsRules                []adapter.DNSRule
+	ruleSets                []adapter.RuleSet
+	ruleSetMap              map[string]adapter.RuleSet
+	defaultTransport        dns.Transport
+	transports              []dns.Transport
+	transportMap            map[string]dns.Transport
+	dnsDetourTransports     map[dnsDetourKey]dns.Transport
+	lanDNSTransport         dns.Transport
+	dnsAdBlocker            *dnsAdBlocker
+	transportDomainStrategy map[dns.Transport]dns.DomainStrategy
+	dnsReverseMapping       *DNSReverseMapping
+	fakeIPStore             adapter.FakeIPStore
+	interfaceFinder         *control.DefaultInterfaceFinder
+	autoDetectInterface     bool
+	defaultInterface        string
+	defaultMark             uint32
+	defaultBuffer           *option.BufferOptions
+	autoRedirectOutputMark  uint32
+	mptcpManager            *mptcp.Manager
+	networkMonitor          tun.NetworkUpdateMonitor
+	interfaceMonitor        tun.DefaultInterfaceMonitor
+	packageManager          tun.PackageManager
+	powerListener           winpowrprof.EventListener
+	processSearcher         process.Searcher
+	timeService             *timesync.Service
+	pauseManager            pause.Manager
+	clashServer             adapter.ClashServer
+	v2rayServer             adapter.V2RayServer
+	trackers                []adapter.ConnectionTracker
+	connections             *connectionTracker
+	domainTrieAccess        sync.Mutex
+	traceAccess             sync.Mutex
+	traceRequests           []*traceRequest
+	traceRequestCount       atomic.Int32
+	startConcurrency        int
+	udpTimeouts             map[string]time.Duration
+	mirrors                 map[string]*mirrorSink
+	dnsHijack               *outbound.DNS
+	platformInterface       platform.Interface
+	needWIFIState           bool
+	needPackageManager      bool
+	wifiState               atomic.Pointer[adapter.WIFIState]
+	started                 bool
 }
 
 func NewRouter(
@@ -119,24 +138,24 @@ func NewRouter(
 		ctx:                   ctx,
 		logger:                logFactory.NewLogger("router"),
 		dnsLogger:             logFactory.NewLogger("dns"),
-		outboundByTag:         make(map[string]adapter.Outbound),
 		outboundProviderByTag: make(map[string]adapter.OutboundProvider),
-		cacheAllOutboundByTag: make(map[string]adapter.Outbound),
-		rules:                 make([]adapter.Rule, 0, len(options.Rules)),
 		dnsRules:              make([]adapter.DNSRule, 0, len(dnsOptions.Rules)),
 		ruleSetMap:            make(map[string]adapter.RuleSet),
 		needGeoIPDatabase:     hasRule(options.Rules, isGeoIPRule) || hasDNSRule(dnsOptions.Rules, isGeoIPDNSRule),
 		needGeositeDatabase:   hasRule(options.Rules, isGeositeRule) || hasDNSRule(dnsOptions.Rules, isGeositeDNSRule),
+		needASNDatabase:       hasRule(options.Rules, isASNRule) || hasDNSRule(dnsOptions.Rules, isASNDNSRule),
 		geoIPOptions:          common.PtrValueOrDefault(options.GeoIP),
 		geositeOptions:        common.PtrValueOrDefault(options.Geosite),
 		geositeCache:          make(map[string]adapter.Rule),
+		geositeCompileCache:   make(map[string]option.DefaultRule),
 		needFindProcess:       hasRule(options.Rules, isProcessRule) || hasDNSRule(dnsOptions.Rules, isProcessDNSRule) || options.FindProcess,
-		defaultDetour:         options.Final,
+		needProcessPath:       hasRule(options.Rules, isProcessPathRule) || hasDNSRule(dnsOptions.Rules, isProcessPathDNSRule) || options.FindProcess,
 		defaultDomainStrategy: dns.DomainStrategy(dnsOptions.Strategy),
 		interfaceFinder:       control.NewDefaultInterfaceFinder(),
 		autoDetectInterface:   options.AutoDetectInterface,
 		defaultInterface:      options.DefaultInterface,
 		defaultMark:           options.DefaultMark,
+		defaultBuffer:         options.DefaultBuffer,
 		pauseManager:          service.FromContext[pause.Manager](ctx),
 		platformInterface:     platformInterface,
 		needWIFIState:         hasRule(options.Rules, isWIFIRule) || hasDNSRule(dnsOptions.Rules, isWIFIDNSRule),
@@ -144,8 +163,47 @@ func NewRouter(
 			return len(inbound.TunOptions.IncludePackage) > 0 || len(inbound.TunOptions.ExcludePackage) > 0
 		}),
 	}
+	router.mptcpManager = mptcp.NewManager(router.logger)
+	if options.StartConcurrency > 0 {
+		router.startConcurrency = options.StartConcurrency
+	} else {
+		router.startConcurrency = C.DefaultStartConcurrency
+	}
+	router.udpTimeouts = make(map[string]time.Duration)
+	for protocol, timeout := range C.ProtocolTimeouts {
+		router.udpTimeouts[protocol] = timeout
+	}
+	for protocol, timeout := range options.UDPTimeouts {
+		router.udpTimeouts[protocol] = time.Duration(timeout)
+	}
+	for _, inbound := range inbounds {
+		var outputMark uint32
+		switch {
+		case inbound.Type == C.TypeRedirect && inbound.RedirectOptions.AutoRedirect:
+			outputMark = uint32(inbound.RedirectOptions.AutoRedirectOutputMark)
+		case inbound.Type == C.TypeTProxy && inbound.TProxyOptions.AutoRedirect:
+			outputMark = uint32(inbound.TProxyOptions.AutoRedirectOutputMark)
+		default:
+			continue
+		}
+		if outputMark == 0 {
+			outputMark = tun.DefaultAutoRedirectOutputMark
+		}
+		// dialers created from now on exclude their connections from redirect rules
+		if router.autoRedirectOutputMark > 0 && router.autoRedirectOutputMark != outputMark {
+			return nil, E.New("conflicting auto_redirect_output_mark of inbounds")
+		}
+		router.autoRedirectOutputMark = outputMark
+	}
+	err := relay.Validate(options.DefaultBuffer)
+	if err != nil {
+		return nil, E.Cause(err, "parse default buffer")
+	}
+	if dnsOptions.CacheCapacity > 0 && !dnsOptions.DisableCache {
+		router.dnsCache = newDNSCache(int(dnsOptions.CacheCapacity), dnsOptions.IndependentCache, dnsOptions.DisableExpire)
+	}
 	router.dnsClient = dns.NewClient(dns.ClientOptions{
-		DisableCache:     dnsOptions.DNSClientOptions.DisableCache,
+		DisableCache:     dnsOptions.DNSClientOptions.DisableCache || router.dnsCache != nil,
 		DisableExpire:    dnsOptions.DNSClientOptions.DisableExpire,
 		IndependentCache: dnsOptions.DNSClientOptions.IndependentCache,
 		RDRC: func() dns.RDRCStore {
@@ -160,13 +218,24 @@ func NewRouter(
 		},
 		Logger: router.dnsLogger,
 	})
-	for i, ruleOptions := range options.Rules {
-		routeRule, err := NewRule(router, router.logger, ruleOptions, true)
+	compileStartedAt := time.Now()
+	router.mirrors = make(map[string]*mirrorSink)
+	router.dnsHijack = outbound.NewDNS(router, "")
+	for i, mirrorOptions := range options.Mirrors {
+		sink, err := newMirrorSink(router.logger, mirrorOptions)
 		if err != nil {
-			return nil, E.Cause(err, "parse rule[", i, "]")
+			return nil, E.Cause(err, "parse mirror[", i, "]")
+		}
+		if _, exists := router.mirrors[sink.tag]; exists {
+			return nil, E.New("duplicate mirror tag: ", sink.tag)
 		}
-		router.rules = append(router.rules, routeRule)
+		router.mirrors[sink.tag] = sink
 	}
+	rules, err := router.newRules(options.Rules)
+	if err != nil {
+		return nil, err
+	}
+	router.state.Store(&routingState{rules: rules, defaultDetour: options.Final})
 	for i, dnsRuleOptions := range dnsOptions.Rules {
 		dnsRule, err := NewDNSRule(router, router.logger, dnsRuleOptions, true)
 		if err != nil {
@@ -185,6 +254,7 @@ func NewRouter(
 		router.ruleSets = append(router.ruleSets, ruleSet)
 		router.ruleSetMap[ruleSetOptions.Tag] = ruleSet
 	}
+	router.compileDuration = time.Since(compileStartedAt)
 
 	transports := make([]dns.Transport, len(dnsOptions.Servers))
 	dummyTransportMap := make(map[string]dns.Transport)
@@ -213,54 +283,90 @@ func NewRouter(
 			if _, exists := dummyTransportMap[tag]; exists {
 				continue
 			}
-			var detour N.Dialer
-			if server.Detour == "" {
-				detour = dialer.NewRouter(router)
+			var (
+				transport dns.Transport
+				err       error
+			)
+			if server.Address == C.DNSServerAddressRace {
+				upstreams := make([]dns.Transport, 0, len(server.Servers))
+				for _, upstreamTag := range server.Servers {
+					if !transportTagMap[upstreamTag] {
+						return nil, E.New("parse dns server[", tag, "]: server not found: ", upstreamTag)
+					}
+					if upstream, exists := dummyTransportMap[upstreamTag]; exists {
+						upstreams = append(upstreams, upstream)
+					}
+				}
+				if len(upstreams) < len(server.Servers) {
+					continue
+				}
+				transport, err = newRaceDNSTransport(tag, upstreams, server.GeoIP, router.GeoIPReader)
+				if err != nil {
+					return nil, E.Cause(err, "parse dns server[", tag, "]")
+				}
+				if len(server.GeoIP) > 0 {
+					router.needGeoIPDatabase = true
+				}
 			} else {
-				detour = dialer.NewDetour(router, server.Detour)
-			}
-			switch server.Address {
-			case "local":
-			default:
-				serverURL, _ := url.Parse(server.Address)
-				var serverAddress string
-				if serverURL != nil {
-					serverAddress = serverURL.Hostname()
+				if len(server.Servers) > 0 || len(server.GeoIP) > 0 {
+					return nil, E.New("parse dns server[", tag, "]: servers and geoip are only allowed for race servers")
 				}
-				if serverAddress == "" {
-					serverAddress = server.Address
+				var detour N.Dialer
+				if server.Detour == "" {
+					detour = dialer.NewRouter(router)
+				} else {
+					detour = dialer.NewDetour(router, server.Detour)
 				}
-				notIpAddress := !M.ParseSocksaddr(serverAddress).Addr.IsValid()
-				if server.AddressResolver != "" {
-					if !transportTagMap[server.AddressResolver] {
-						return nil, E.New("parse dns server[", tag, "]: address resolver not found: ", server.AddressResolver)
+				switch server.Address {
+				case "local":
+				default:
+					serverURL, _ := url.Parse(server.Address)
+					var serverAddress string
+					if serverURL != nil {
+						serverAddress = serverURL.Hostname()
+					}
+					if serverAddress == "" {
+						serverAddress = server.Address
 					}
-					if upstream, exists := dummyTransportMap[server.AddressResolver]; exists {
-						detour = dns.NewDialerWrapper(detour, router.dnsClient, upstream, dns.DomainStrategy(server.AddressStrategy), time.Duration(server.AddressFallbackDelay))
-					} else {
-						continue
+					notIpAddress := !M.ParseSocksaddr(serverAddress).Addr.IsValid()
+					if server.AddressResolver != "" {
+						if !transportTagMap[server.AddressResolver] {
+							return nil, E.New("parse dns server[", tag, "]: address resolver not found: ", server.AddressResolver)
+						}
+						if upstream, exists := dummyTransportMap[server.AddressResolver]; exists {
+							detour = dns.NewDialerWrapper(detour, router.dnsClient, upstream, dns.DomainStrategy(server.AddressStrategy), time.Duration(server.AddressFallbackDelay))
+						} else {
+							continue
+						}
+					} else if notIpAddress && strings.Contains(server.Address, ".") {
+						return nil, E.New("parse dns server[", tag, "]: missing address_resolver")
 					}
-				} else if notIpAddress && strings.Contains(server.Address, ".") {
-					return nil, E.New("parse dns server[", tag, "]: missing address_resolver")
 				}
+				var clientSubnet netip.Prefix
+				if server.ClientSubnet != nil {
+					clientSubnet = server.ClientSubnet.Build()
+				} else if dnsOptions.ClientSubnet != nil {
+					clientSubnet = dnsOptions.ClientSubnet.Build()
+				}
+				transport, err = dns.CreateTransport(dns.TransportOptions{
+					Context: ctx,
+					Logger:  logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")),
+					Name:    tag,
+					Dialer:  detour,
+					Address: server.Address,
+				})
+				if err != nil {
+					return nil, E.Cause(err, "parse dns server[", tag, "]")
+				}
+				transport = wrapClientSubnet(transport, clientSubnet)
 			}
-			var clientSubnet netip.Prefix
-			if server.ClientSubnet != nil {
-				clientSubnet = server.ClientSubnet.Build()
-			} else if dnsOptions.ClientSubnet != nil {
-				clientSubnet = dnsOptions.ClientSubnet.Build()
-			}
-			transport, err := dns.CreateTransport(dns.TransportOptions{
-				Context:      ctx,
-				Logger:       logFactory.NewLogger(F.ToString("dns/transport[", tag, "]")),
-				Name:         tag,
-				Dialer:       detour,
-				Address:      server.Address,
-				ClientSubnet: clientSubnet,
-			})
+			transport, err = wrapDNSSEC(transport, server.DNSSEC)
 			if err != nil {
 				return nil, E.Cause(err, "parse dns server[", tag, "]")
 			}
+			if router.dnsCache != nil {
+				transport = router.dnsCache.Wrap(transport)
+			}
 			transports[i] = transport
 			dummyTransportMap[tag] = transport
 			if server.Tag != "" {
@@ -309,9 +415,50 @@ func NewRouter(
 	if _, isFakeIP := defaultTransport.(adapter.FakeIPTransport); isFakeIP {
 		return nil, E.New("default DNS server cannot be fakeip")
 	}
+	dnsDetourTransports := make(map[dnsDetourKey]dns.Transport)
+	for i, rule := range router.dnsRules {
+		key := dnsDetourKey{rule.Outbound(), rule.Detour()}
+		if key.detour == "" {
+			continue
+		}
+		if _, loaded := dnsDetourTransports[key]; loaded {
+			continue
+		}
+		serverIndex := common.Index(dnsOptions.Servers, func(it option.DNSServerOptions) bool {
+			return it.Tag == key.server
+		})
+		if serverIndex == -1 {
+			return nil, E.New("parse dns rule[", i, "]: server not found: ", key.server)
+		}
+		server := dnsOptions.Servers[serverIndex]
+		transport, err := router.newDNSDetourTransport(ctx, logFactory, server, dnsOptions, dummyTransportMap[server.AddressResolver], key.detour)
+		if err != nil {
+			return nil, E.Cause(err, "parse dns rule[", i, "]")
+		}
+		transports = append(transports, transport)
+		dnsDetourTransports[key] = transport
+		strategy := dns.DomainStrategy(server.Strategy)
+		if strategy != dns.DomainStrategyAsIS {
+			transportDomainStrategy[transport] = strategy
+		}
+	}
+	if dnsOptions.LANResolve {
+		lanDialer, err := dialer.NewDefault(router, option.DialerOptions{})
+		if err != nil {
+			return nil, E.Cause(err, "create lan dns dialer")
+		}
+		router.lanDNSTransport = newLANDNSTransport(lanDialer)
+	}
+	if dnsOptions.AdBlock != nil {
+		router.dnsAdBlocker, err = newDNSAdBlocker(router, *dnsOptions.AdBlock)
+		if err != nil {
+			return nil, E.Cause(err, "parse dns adblock")
+		}
+	}
 	router.defaultTransport = defaultTransport
 	router.transports = transports
 	router.transportMap = transportMap
+	router.dnsDetourTransports = dnsDetourTransports
 	router.transportDomainStrategy = transportDomainStrategy
 
 	if dnsOptions.ReverseMapping {
@@ -327,7 +474,7 @@ func NewRouter(
 		if fakeIPOptions.Inet6Range != nil {
 			inet6Range = *fakeIPOptions.Inet6Range
 		}
-		router.fakeIPStore = fakeip.NewStore(ctx, router.logger, inet4Range, inet6Range)
+		router.fakeIPStore = fakeip.NewStore(ctx, router.logger, inet4Range, inet6Range, int(fakeIPOptions.MaxEntries))
 	}
 
 	usePlatformDefaultInterfaceMonitor := platformInterface != nil && platformInterface.UsePlatformDefaultInterfaceMonitor()
@@ -344,6 +491,10 @@ func NewRouter(
 			router.networkMonitor = networkMonitor
 			networkMonitor.RegisterCallback(func() {
 				_ = router.interfaceFinder.Update()
+				err := router.mptcpManager.Update()
+				if err != nil {
+					router.logger.Error(err)
+				}
 			})
 			interfaceMonitor, err := tun.NewDefaultInterfaceMonitor(router.networkMonitor, router.logger, tun.DefaultInterfaceMonitorOptions{
 				OverrideAndroidVPN:    options.OverrideAndroidVPN,
@@ -366,12 +517,24 @@ func NewRouter(
 		if err != nil {
 			return nil, E.Cause(err, "create NTP service")
 		}
-		timeService := ntp.NewService(ntp.Options{
+		var ntpServers []M.Socksaddr
+		if ntpOptions.Server != "" {
+			ntpServers = append(ntpServers, ntpOptions.ServerOptions.Build())
+		}
+		for _, server := range ntpOptions.Servers {
+			serverAddr := M.ParseSocksaddr(server)
+			if !serverAddr.IsValid() {
+				return nil, E.New("invalid NTP server: ", server)
+			}
+			ntpServers = append(ntpServers, serverAddr)
+		}
+		timeService := timesync.NewService(timesync.Options{
 			Context:       ctx,
 			Dialer:        ntpDialer,
 			Logger:        logFactory.NewLogger("ntp"),
-			Server:        ntpOptions.ServerOptions.Build(),
+			Servers:       ntpServers,
 			Interval:      time.Duration(ntpOptions.Interval),
+			RetryInterval: time.Duration(ntpOptions.RetryInterval),
 			WriteToSystem: ntpOptions.WriteToSystem,
 		})
 		service.MustRegister[ntp.TimeService](ctx, timeService)
@@ -381,75 +544,29 @@ func NewRouter(
 }
 
 func (r *Router) Initialize(inbounds []adapter.Inbound, outbounds []adapter.Outbound, defaultOutbound func() adapter.Outbound) error {
-	inboundByTag := make(map[string]adapter.Inbound)
-	for _, inbound := range inbounds {
-		inboundByTag[inbound.Tag()] = inbound
-	}
-	outboundByTag := make(map[string]adapter.Outbound)
-	for _, detour := range outbounds {
-		outboundByTag[detour.Tag()] = detour
-	}
-	var defaultOutboundForConnection adapter.Outbound
-	var defaultOutboundForPacketConnection adapter.Outbound
-	if r.defaultDetour != "" {
-		detour, loaded := outboundByTag[r.defaultDetour]
-		if !loaded {
-			return E.New("default detour not found: ", r.defaultDetour)
-		}
-		if common.Contains(detour.Network(), N.NetworkTCP) {
-			defaultOutboundForConnection = detour
-		}
-		if common.Contains(detour.Network(), N.NetworkUDP) {
-			defaultOutboundForPacketConnection = detour
-		}
-	}
-	if defaultOutboundForConnection == nil {
-		for _, detour := range outbounds {
-			if common.Contains(detour.Network(), N.NetworkTCP) {
-				defaultOutboundForConnection = detour
-				break
-			}
-		}
-	}
-	if defaultOutboundForPacketConnection == nil {
-		for _, detour := range outbounds {
-			if common.Contains(detour.Network(), N.NetworkUDP) {
-				defaultOutboundForPacketConnection = detour
-				break
-			}
-		}
-	}
-	if defaultOutboundForConnection == nil || defaultOutboundForPacketConnection == nil {
-		detour := defaultOutbound()
-		if defaultOutboundForConnection == nil {
-			defaultOutboundForConnection = detour
-		}
-		if defaultOutboundForPacketConnection == nil {
-			defaultOutboundForPacketConnection = detour
-		}
-		outbounds = append(outbounds, detour)
-		outboundByTag[detour.Tag()] = detour
-	}
-	r.inboundByTag = inboundByTag
-	r.outbounds = outbounds
-	r.defaultOutboundForConnection = defaultOutboundForConnection
-	r.defaultOutboundForPacketConnection = defaultOutboundForPacketConnection
-	r.outboundByTag = outboundByTag
-	for i, rule := range r.rules {
-		if _, loaded := outboundByTag[rule.Outbound()]; !loaded {
-			return E.New("outbound not found for rule[", i, "]: ", rule.Outbound())
-		}
+	initialState := r.loadState()
+	state, err := newRoutingState(inbounds, outbounds, initialState.rules, initialState.defaultDetour, defaultOutbound)
+	if err != nil {
+		return err
 	}
+	r.state.Store(state)
+	r.outboundSnapshot.Store(&outboundSnapshot{outboundByTag: state.outboundByTag})
 	return nil
 }
 
+func (r *Router) Inbounds() []adapter.Inbound {
+	return r.loadState().inbounds
+}
+
 func (r *Router) Outbounds() []adapter.Outbound {
 	if !r.started {
 		return nil
 	}
-	if len(r.cacheAllOutbounds) == 0 {
-		cacheAllOutbounds := make([]adapter.Outbound, 0, len(r.outbounds))
-		cacheAllOutbounds = append(cacheAllOutbounds, r.outbounds...)
+	snapshot := r.loadOutboundSnapshot()
+	if len(snapshot.outbounds) == 0 {
+		state := r.loadState()
+		cacheAllOutbounds := make([]adapter.Outbound, 0, len(state.outbounds))
+		cacheAllOutbounds = append(cacheAllOutbounds, state.outbounds...)
 		if len(r.outboundProviders) > 0 {
 			for _, provider := range r.outboundProviders {
 				basicOutbounds := provider.BasicOutbounds()
@@ -464,9 +581,10 @@ func (r *Router) Outbounds() []adapter.Outbound {
 				}
 			}
 		}
-		r.cacheAllOutbounds = cacheAllOutbounds
+		r.cacheOutboundSnapshot(snapshot, snapshot.withOutbounds(cacheAllOutbounds))
+		return cacheAllOutbounds
 	}
-	return r.cacheAllOutbounds
+	return snapshot.outbounds
 }
 
 func (r *Router) OutboundProvider(tag string) (adapter.OutboundProvider, bool) {
@@ -488,13 +606,30 @@ func (r *Router) RegisterOutboundProvider(tag string, provider adapter.OutboundP
 	return nil
 }
 
+// NotifyOutboundProviderUpdated drops the provided outbounds cached by the
+// router and lets groups using the provider refresh their members.
+func (r *Router) NotifyOutboundProviderUpdated(tag string) {
+	r.outboundSnapshot.Store(&outboundSnapshot{outboundByTag: r.loadState().outboundByTag})
+	for _, detour := range r.Outbounds() {
+		listener, isListener := detour.(adapter.OutboundProviderUpdateListener)
+		if !isListener {
+			continue
+		}
+		err := listener.OutboundProviderUpdated(tag)
+		if err != nil {
+			r.logger.Error(E.Cause(err, "update outbound/", detour.Type(), "[", detour.Tag(), "]"))
+		}
+	}
+}
+
 func (r *Router) CheckOutboundProvider(tag string) error {
 	provider, loaded := r.OutboundProvider(tag)
 	if !loaded {
 		return E.New("outbound provider not found: ", tag)
 	}
+	outboundByTag := r.loadState().outboundByTag
 	for _, outbound := range provider.BasicOutbounds() {
-		_, loaded = r.outboundByTag[outbound.Tag()]
+		_, loaded = outboundByTag[outbound.Tag()]
 		if loaded {
 			return E.New("duplicate outbound: ", outbound.Tag())
 		}
@@ -511,7 +646,7 @@ func (r *Router) CheckOutboundProvider(tag string) error {
 	groupOutbounds := provider.GroupOutbounds()
 	if len(groupOutbounds) > 0 {
 		for _, outbound := range groupOutbounds {
-			_, loaded = r.outboundByTag[outbound.Tag()]
+			_, loaded = outboundByTag[outbound.Tag()]
 			if loaded {
 				return E.New("duplicate outbound: ", outbound.Tag())
 			}
@@ -530,7 +665,7 @@ func (r *Router) CheckOutboundProvider(tag string) error {
 }
 
 func (r *Router) PreStart() error {
-	monitor := taskmonitor.New(r.logger, C.StartTimeout)
+	monitor := taskmonitor.NewProfile(r.logger, C.StartTimeout)
 	if r.interfaceMonitor != nil {
 		monitor.Start("initialize interface monitor")
 		err := r.interfaceMonitor.Start()
@@ -559,7 +694,8 @@ func (r *Router) PreStart() error {
 }
 
 func (r *Router) Start() error {
-	monitor := taskmonitor.New(r.logger, C.StartTimeout)
+	r.logger.Debug("compiled ", len(r.loadState().rules), " rules, ", len(r.dnsRules), " DNS rules and ", len(r.ruleSets), " rule-sets (", F.Seconds(r.compileDuration.Seconds()), "s)")
+	monitor := taskmonitor.NewProfile(r.logger, C.StartTimeout)
 	if r.needGeoIPDatabase {
 		monitor.Start("initialize geoip database")
 		err := r.prepareGeoIPDatabase()
@@ -568,6 +704,14 @@ func (r *Router) Start() error {
 			return err
 		}
 	}
+	if r.needASNDatabase {
+		monitor.Start("initialize asn database")
+		err := r.prepareASNDatabase()
+		monitor.Finish()
+		if err != nil {
+			return err
+		}
+	}
 	if r.needGeositeDatabase {
 		monitor.Start("initialize geosite database")
 		err := r.prepareGeositeDatabase()
@@ -577,25 +721,14 @@ func (r *Router) Start() error {
 		}
 	}
 	if r.needGeositeDatabase {
-		for _, rule := range r.rules {
-			err := rule.UpdateGeosite()
-			if err != nil {
-				r.logger.Error("failed to initialize geosite: ", err)
-			}
-		}
-		for _, rule := range r.dnsRules {
-			err := rule.UpdateGeosite()
-			if err != nil {
-				r.logger.Error("failed to initialize geosite: ", err)
-			}
-		}
-		err := common.Close(r.geositeReader)
+		monitor.Start("compile geosite rules")
+		err := r.compileGeositeRules()
+		monitor.Finish()
 		if err != nil {
 			return err
 		}
-		r.geositeCache = nil
-		r.geositeReader = nil
 	}
+	r.startGeoUpdate()
 
 	if runtime.GOOS == "windows" {
 		powerListener, err := winpowrprof.NewEventListener(r.notifyWindowsPowerEvent)
@@ -617,6 +750,7 @@ func (r *Router) Start() error {
 
 	monitor.Start("initialize DNS client")
 	r.dnsClient.Start()
+	r.loadDNSCache()
 	monitor.Finish()
 
 	if r.needPackageManager && r.platformInterface == nil {
@@ -662,13 +796,19 @@ func (r *Router) Start() error {
 			return E.Cause(err, "initialize time service")
 		}
 	}
+	monitor.Start("initialize MPTCP endpoints")
+	err := r.mptcpManager.Start()
+	monitor.Finish()
+	if err != nil {
+		return E.Cause(err, "initialize MPTCP endpoints")
+	}
 	return nil
 }
 
 func (r *Router) Close() error {
 	monitor := taskmonitor.New(r.logger, C.StopTimeout)
 	var err error
-	for i, rule := range r.rules {
+	for i, rule := range r.loadState().rules {
 		monitor.Start("close rule[", i, "]")
 		err = E.Append(err, rule.Close(), func(err error) error {
 			return E.Cause(err, "close rule[", i, "]")
@@ -689,13 +829,28 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
-	if r.geoIPReader != nil {
+	monitor.Start("save DNS cache")
+	err = E.Append(err, r.saveDNSCache(), func(err error) error {
+		return E.Cause(err, "save DNS cache")
+	})
+	monitor.Finish()
+	if r.geoUpdateCancel != nil {
+		r.geoUpdateCancel()
+	}
+	if geoIPReader := r.geoIPReader.Load(); geoIPReader != nil {
 		monitor.Start("close geoip reader")
-		err = E.Append(err, r.geoIPReader.Close(), func(err error) error {
+		err = E.Append(err, geoIPReader.Close(), func(err error) error {
 			return E.Cause(err, "close geoip reader")
 		})
 		monitor.Finish()
 	}
+	if r.asnReader != nil {
+		monitor.Start("close asn reader")
+		err = E.Append(err, r.asnReader.Close(), func(err error) error {
+			return E.Cause(err, "close asn reader")
+		})
+		monitor.Finish()
+	}
 	if r.interfaceMonitor != nil {
 		monitor.Start("close interface monitor")
 		err = E.Append(err, r.interfaceMonitor.Close(), func(err error) error {
@@ -738,11 +893,16 @@ func (r *Router) Close() error {
 		})
 		monitor.Finish()
 	}
+	monitor.Start("close MPTCP endpoints")
+	err = E.Append(err, r.mptcpManager.Close(), func(err error) error {
+		return E.Cause(err, "close MPTCP endpoints")
+	})
+	monitor.Finish()
 	return err
 }
 
 func (r *Router) PostStart() error {
-	monitor := taskmonitor.New(r.logger, C.StopTimeout)
+	monitor := taskmonitor.NewProfile(r.logger, C.StopTimeout)
 	if len(r.ruleSets) > 0 {
 		monitor.Start("initialize rule-set")
 		ruleSetStartContext := NewRuleSetStartContext()
@@ -757,7 +917,7 @@ func (r *Router) PostStart() error {
 				return nil
 			})
 		}
-		ruleSetStartGroup.Concurrency(5)
+		ruleSetStartGroup.Concurrency(r.startConcurrency)
 		ruleSetStartGroup.FastFail()
 		err := ruleSetStartGroup.Run(r.ctx)
 		monitor.Finish()
@@ -782,11 +942,16 @@ func (r *Router) PostStart() error {
 	if needProcessFromRuleSet || r.needFindProcess {
 		if r.platformInterface != nil {
 			r.processSearcher = r.platformInterface
+			r.needProcessPath = true
 		} else {
+			if needProcessFromRuleSet {
+				r.needProcessPath = true
+			}
 			monitor.Start("initialize process searcher")
 			searcher, err := process.NewSearcher(process.Config{
-				Logger:         r.logger,
-				PackageManager: r.packageManager,
+				Logger:          r.logger,
+				PackageManager:  r.packageManager,
+				SkipProcessPath: !r.needProcessPath,
 			})
 			monitor.Finish()
 			if err != nil {
@@ -798,16 +963,23 @@ func (r *Router) PostStart() error {
 			}
 		}
 	}
-	if (needWIFIStateFromRuleSet || r.needWIFIState) && r.platformInterface != nil {
-		monitor.Start("initialize WIFI state")
-		r.needWIFIState = true
-		r.interfaceMonitor.RegisterCallback(func(_ int) {
+	if needWIFIStateFromRuleSet || r.needWIFIState {
+		if r.platformInterface != nil {
+			monitor.Start("initialize WIFI state")
+			r.needWIFIState = true
+			r.interfaceMonitor.RegisterCallback(func(_ int) {
+				r.updateWIFIState()
+			})
 			r.updateWIFIState()
-		})
-		r.updateWIFIState()
-		monitor.Finish()
+			monitor.Finish()
+		} else {
+			r.logger.Warn("wifi_ssid and wifi_bssid rules never match without a platform interface, which is only provided by graphical clients")
+		}
 	}
-	for i, rule := range r.rules {
+	monitor.Start("build domain trie")
+	r.buildDomainTrie()
+	monitor.Finish()
+	for i, rule := range r.loadState().rules {
 		monitor.Start("initialize rule[", i, "]")
 		err := rule.Start()
 		monitor.Finish()
@@ -815,6 +987,14 @@ func (r *Router) PostStart() error {
 			return E.Cause(err, "initialize rule[", i, "]")
 		}
 	}
+	if r.dnsAdBlocker != nil {
+		monitor.Start("initialize dns adblock")
+		err := r.dnsAdBlocker.Start()
+		monitor.Finish()
+		if err != nil {
+			return E.Cause(err, "initialize dns adblock")
+		}
+	}
 	for _, ruleSet := range r.ruleSets {
 		monitor.Start("post start rule_set[", ruleSet.Name(), "]")
 		err := ruleSet.PostStart()
@@ -822,27 +1002,48 @@ func (r *Router) PostStart() error {
 		if err != nil {
 			return E.Cause(err, "post start rule_set[", ruleSet.Name(), "]")
 		}
+		ruleSet.RegisterCallback(r.ruleSetUpdated)
 	}
 	r.started = true
 	return nil
 }
 
+// ruleSetUpdated applies an updated rule-set to the running router. Matching
+// already uses the new rules, but cached DNS responses may have been resolved
+// by servers selected with the previous ones.
+func (r *Router) ruleSetUpdated(ruleSet adapter.RuleSet) {
+	metadata := ruleSet.Metadata()
+	if metadata.ContainsProcessRule && (r.processSearcher == nil || !r.needProcessPath) {
+		r.logger.Warn("rule-set ", ruleSet.Name(), ": process rules require a restart")
+	}
+	if metadata.ContainsWIFIRule && !r.needWIFIState {
+		r.logger.Warn("rule-set ", ruleSet.Name(), ": wifi rules require a restart")
+	}
+	if len(r.dnsRules) > 0 {
+		r.dnsClient.ClearCache()
+		if r.dnsCache != nil {
+			r.dnsCache.Clear()
+		}
+	}
+	r.buildDomainTrie()
+}
+
 func (r *Router) Cleanup() error {
 	for _, ruleSet := range r.ruleSetMap {
 		ruleSet.Cleanup()
 	}
+	r.buildDomainTrie()
 	runtime.GC()
 	return nil
 }
 
 func (r *Router) Outbound(tag string) (adapter.Outbound, bool) {
-	r.cacheAllOutboundByTagLocker.Lock()
-	outbound, loaded := r.cacheAllOutboundByTag[tag]
-	r.cacheAllOutboundByTagLocker.Unlock()
+	snapshot := r.loadOutboundSnapshot()
+	outbound, loaded := snapshot.outboundByTag[tag]
 	if loaded {
 		return outbound, true
 	}
-	outbound, loaded = r.outboundByTag[tag]
+	outbound, loaded = r.loadState().outboundByTag[tag]
 	if !loaded && len(r.outboundProviders) > 0 {
 		for _, provider := range r.outboundProviders {
 			outbound, loaded = provider.Outbound(tag)
@@ -852,24 +1053,23 @@ func (r *Router) Outbound(tag string) (adapter.Outbound, bool) {
 		}
 	}
 	if loaded {
-		r.cacheAllOutboundByTagLocker.Lock()
-		r.cacheAllOutboundByTag[tag] = outbound
-		r.cacheAllOutboundByTagLocker.Unlock()
+		r.cacheOutboundSnapshot(snapshot, snapshot.withOutbound(tag, outbound))
 	}
 	return outbound, loaded
 }
 
 func (r *Router) DefaultOutbound(network string) (adapter.Outbound, error) {
+	state := r.loadState()
 	if network == N.NetworkTCP {
-		if r.defaultOutboundForConnection == nil {
+		if state.defaultOutboundForConnection == nil {
 			return nil, E.New("missing default outbound for TCP connections")
 		}
-		return r.defaultOutboundForConnection, nil
+		return state.defaultOutboundForConnection, nil
 	} else {
-		if r.defaultOutboundForPacketConnection == nil {
+		if state.defaultOutboundForPacketConnection == nil {
 			return nil, E.New("missing default outbound for UDP connections")
 		}
-		return r.defaultOutboundForPacketConnection, nil
+		return state.defaultOutboundForPacketConnection, nil
 	}
 }
 
@@ -899,7 +1099,7 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
+		detour := r.loadState().inboundByTag[metadata.InboundDetour]
 		if detour == nil {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
@@ -921,6 +1121,9 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	}
 	conntrack.KillerCheck()
 	metadata.Network = N.NetworkTCP
+	if r.defaultBuffer != nil {
+		metadata.InboundOptions.Buffer = relay.MergeOptions(metadata.InboundOptions.Buffer, r.defaultBuffer)
+	}
 	switch metadata.Destination.Fqdn {
 	case mux.Destination.Fqdn:
 		return E.New("global multiplex is deprecated since sing-box v1.7.0, enable multiplex in inbound options instead.")
@@ -950,8 +1153,8 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 		conn = deadline.NewConn(conn)
 	}
 
-	if metadata.InboundOptions.SniffEnabled {
-		buffer := buf.NewPacket()
+	if metadata.InboundOptions.SniffEnabled && sniffPort(metadata.InboundOptions.SniffPorts, metadata.Destination.Port) {
+		buffer := newSniffBuffer(metadata.InboundOptions.SniffBufferSize)
 		sniffMetadata, err := sniff.PeekStream(
 			ctx,
 			conn,
@@ -961,21 +1164,11 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 			sniff.TLSClientHello,
 			sniff.HTTPHost,
 			sniff.BitTorrent,
+			sniff.SSH,
+			sniff.RDP,
 		)
 		if sniffMetadata != nil {
-			metadata.Protocol = sniffMetadata.Protocol
-			metadata.Domain = sniffMetadata.Domain
-			if metadata.InboundOptions.SniffOverrideDestination && M.IsDomainName(metadata.Domain) {
-				metadata.Destination = M.Socksaddr{
-					Fqdn: metadata.Domain,
-					Port: metadata.Destination.Port,
-				}
-			}
-			if metadata.Domain != "" {
-				r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
-			} else {
-				r.logger.DebugContext(ctx, "sniffed protocol: ", metadata.Protocol)
-			}
+			r.applySniffMetadata(ctx, &metadata, sniffMetadata, "sniffed protocol: ")
 		} else if err != nil {
 			r.logger.TraceContext(ctx, "sniffed no protocol: ", err)
 		}
@@ -1007,13 +1200,22 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForConnection)
+	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.loadState().defaultOutboundForConnection, &conn, nil)
 	if err != nil {
 		return err
 	}
+	if detour == nil {
+		return r.routeConnectionAction(ctx, conn, metadata, matchedRule.Action())
+	}
 	if !common.Contains(detour.Network(), N.NetworkTCP) {
 		return E.New("missing supported outbound, closing connection")
 	}
+	if matchedRule != nil {
+		if _, overridden := overrideDestination(&metadata, matchedRule.Action()); overridden {
+			r.logger.DebugContext(ctx, "override destination to ", metadata.Destination)
+		}
+	}
+	ctx = r.checkTrace(ctx, &metadata, matchedRule, detour)
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
@@ -1024,6 +1226,15 @@ func (r *Router) RouteConnection(ctx context.Context, conn net.Conn, metadata ad
 			conn = statsService.RoutedConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
+	for _, tracker := range r.trackers {
+		conn = tracker.RoutedConnection(ctx, conn, metadata, matchedRule, detour)
+		if trackerConn, isTracker := conn.(adapter.Tracker); isTracker {
+			defer trackerConn.Leave()
+		}
+	}
+	if matchedRule != nil && matchedRule.Mirror() != "" {
+		conn = r.mirrors[matchedRule.Mirror()].NewConn(ctx, conn, metadata, detour.Tag())
+	}
 	return detour.NewConnection(ctx, conn, metadata)
 }
 
@@ -1035,7 +1246,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		if metadata.LastInbound == metadata.InboundDetour {
 			return E.New("routing loop on detour: ", metadata.InboundDetour)
 		}
-		detour := r.inboundByTag[metadata.InboundDetour]
+		detour := r.loadState().inboundByTag[metadata.InboundDetour]
 		if detour == nil {
 			return E.New("inbound detour not found: ", metadata.InboundDetour)
 		}
@@ -1077,7 +1288,8 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		conn = deadline.NewPacketConn(bufio.NewNetPacketConn(conn))
 	}*/
 
-	if metadata.InboundOptions.SniffEnabled || metadata.Destination.Addr.IsUnspecified() {
+	sniffEnabled := metadata.InboundOptions.SniffEnabled && sniffPort(metadata.InboundOptions.SniffPorts, metadata.Destination.Port)
+	if sniffEnabled || metadata.Destination.Addr.IsUnspecified() {
 		buffer := buf.NewPacket()
 		destination, err := conn.ReadPacket(buffer)
 		if err != nil {
@@ -1087,7 +1299,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 		if metadata.Destination.Addr.IsUnspecified() {
 			metadata.Destination = destination
 		}
-		if metadata.InboundOptions.SniffEnabled {
+		if sniffEnabled {
 			sniffMetadata, _ := sniff.PeekPacket(
 				ctx,
 				buffer.Bytes(),
@@ -1099,19 +1311,7 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 				sniff.DTLSRecord,
 			)
 			if sniffMetadata != nil {
-				metadata.Protocol = sniffMetadata.Protocol
-				metadata.Domain = sniffMetadata.Domain
-				if metadata.InboundOptions.SniffOverrideDestination && M.IsDomainName(metadata.Domain) {
-					metadata.Destination = M.Socksaddr{
-						Fqdn: metadata.Domain,
-						Port: metadata.Destination.Port,
-					}
-				}
-				if metadata.Domain != "" {
-					r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
-				} else {
-					r.logger.DebugContext(ctx, "sniffed packet protocol: ", metadata.Protocol)
-				}
+				r.applySniffMetadata(ctx, &metadata, sniffMetadata, "sniffed packet protocol: ")
 			}
 		}
 		conn = bufio.NewCachedPacketConn(conn, buffer, destination)
@@ -1136,13 +1336,32 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 	} else if metadata.Destination.IsIPv6() {
 		metadata.IPVersion = 6
 	}
-	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.defaultOutboundForPacketConnection)
+	ctx, matchedRule, detour, err := r.match(ctx, &metadata, r.loadState().defaultOutboundForPacketConnection, nil, &conn)
 	if err != nil {
 		return err
 	}
+	if detour == nil {
+		return r.routePacketConnectionAction(ctx, conn, metadata, matchedRule.Action())
+	}
 	if !common.Contains(detour.Network(), N.NetworkUDP) {
 		return E.New("missing supported outbound, closing packet connection")
 	}
+	var (
+		overrideOrigin M.Socksaddr
+		overridden     bool
+	)
+	if matchedRule != nil {
+		overrideOrigin, overridden = overrideDestination(&metadata, matchedRule.Action())
+		if overridden {
+			r.logger.DebugContext(ctx, "override destination to ", metadata.Destination)
+		}
+	}
+	ctx = r.checkTrace(ctx, &metadata, matchedRule, detour)
+	if matchedRule != nil && matchedRule.UDPTimeout() > 0 {
+		metadata.UDPTimeout = matchedRule.UDPTimeout()
+	} else if metadata.Protocol != "" {
+		metadata.UDPTimeout = r.udpTimeouts[metadata.Protocol]
+	}
 	if r.clashServer != nil {
 		trackerConn, tracker := r.clashServer.RoutedPacketConnection(ctx, conn, metadata, matchedRule)
 		defer tracker.Leave()
@@ -1153,14 +1372,34 @@ func (r *Router) RoutePacketConnection(ctx context.Context, conn N.PacketConn, m
 			conn = statsService.RoutedPacketConnection(metadata.Inbound, detour.Tag(), metadata.User, conn)
 		}
 	}
+	for _, tracker := range r.trackers {
+		conn = tracker.RoutedPacketConnection(ctx, conn, metadata, matchedRule, detour)
+		if trackerConn, isTracker := conn.(adapter.Tracker); isTracker {
+			defer trackerConn.Leave()
+		}
+	}
+	if matchedRule != nil && matchedRule.Mirror() != "" {
+		conn = r.mirrors[matchedRule.Mirror()].NewPacketConn(ctx, conn, metadata, detour.Tag())
+	}
 	if metadata.FakeIP {
 		conn = bufio.NewNATPacketConn(bufio.NewNetPacketConn(conn), metadata.OriginDestination, metadata.Destination)
+	} else if overridden {
+		conn = &overridePacketConn{PacketConn: conn, origin: overrideOrigin.Unwrap(), destination: metadata.Destination.Unwrap()}
 	}
 	return detour.NewPacketConnection(ctx, conn, metadata)
 }
 
-func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (context.Context, adapter.Rule, adapter.Outbound, error) {
-	matchRule, matchOutbound := r.match0(ctx, metadata, defaultOutbound)
+// match returns the rule and outbound for the connection, or the rule with
+// a nil outbound if its action handles the connection by itself. Sniff and
+// resolve actions update metadata, conn and packetConn and matching goes on.
+func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound, conn *net.Conn, packetConn *N.PacketConn) (context.Context, adapter.Rule, adapter.Outbound, error) {
+	matchRule, matchOutbound, err := r.match0(ctx, metadata, defaultOutbound, conn, packetConn)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if matchOutbound == nil {
+		return ctx, matchRule, nil, nil
+	}
 	if contextOutbound, loaded := outbound.TagFromContext(ctx); loaded {
 		if contextOutbound == matchOutbound.Tag() {
 			return nil, nil, nil, E.New("connection loopback in outbound/", matchOutbound.Type(), "[", matchOutbound.Tag(), "]")
@@ -1170,7 +1409,7 @@ func (r *Router) match(ctx context.Context, metadata *adapter.InboundContext, de
 	return ctx, matchRule, matchOutbound, nil
 }
 
-func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound) (adapter.Rule, adapter.Outbound) {
+func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, defaultOutbound adapter.Outbound, conn *net.Conn, packetConn *N.PacketConn) (adapter.Rule, adapter.Outbound, error) {
 	if r.processSearcher != nil {
 		var originDestination netip.AddrPort
 		if metadata.OriginDestination.IsValid() {
@@ -1186,34 +1425,73 @@ func (r *Router) match0(ctx context.Context, metadata *adapter.InboundContext, d
 				r.logger.InfoContext(ctx, "found process path: ", processInfo.ProcessPath)
 			} else if processInfo.PackageName != "" {
 				r.logger.InfoContext(ctx, "found package name: ", processInfo.PackageName)
+			} else if processInfo.AppID != "" {
+				r.logger.InfoContext(ctx, "found app id: ", processInfo.AppID)
+			} else if processInfo.User != "" {
+				r.logger.InfoContext(ctx, "found user: ", processInfo.User)
 			} else if processInfo.UserId != -1 {
-				if /*needUserName &&*/ true {
-					osUser, _ := user.LookupId(F.ToString(processInfo.UserId))
-					if osUser != nil {
-						processInfo.User = osUser.Username
-					}
-				}
-				if processInfo.User != "" {
-					r.logger.InfoContext(ctx, "found user: ", processInfo.User)
-				} else {
-					r.logger.InfoContext(ctx, "found user id: ", processInfo.UserId)
-				}
+				r.logger.InfoContext(ctx, "found user id: ", processInfo.UserId)
 			}
 			metadata.ProcessInfo = processInfo
 		}
 	}
-	for i, rule := range r.rules {
+	for i, rule := range r.loadState().rules {
 		metadata.ResetRuleCache()
-		if rule.Match(metadata) {
+		if !rule.Match(metadata) {
+			continue
+		}
+		r.logger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", ruleTarget(rule))
+		action := rule.Action()
+		switch action.Action {
+		case C.RuleActionTypeRoute:
 			detour := rule.Outbound()
-			r.logger.DebugContext(ctx, "match[", i, "] ", rule.String(), " => ", detour)
 			if outbound, loaded := r.Outbound(detour); loaded {
-				return rule, outbound
+				return rule, outbound, nil
 			}
 			r.logger.ErrorContext(ctx, "outbound not found: ", detour)
+		case C.RuleActionTypeSniff:
+			if conn != nil {
+				r.sniffConnection(ctx, metadata, action, conn)
+			} else {
+				err := r.sniffPacketConnection(ctx, metadata, action, packetConn)
+				if err != nil {
+					return nil, nil, err
+				}
+			}
+		case C.RuleActionTypeResolve:
+			err := r.resolveDestination(ctx, metadata, action)
+			if err != nil {
+				return nil, nil, err
+			}
+		default:
+			return rule, nil, nil
 		}
 	}
-	return nil, defaultOutbound
+	return nil, defaultOutbound, nil
+}
+
+func (r *Router) routeConnectionAction(ctx context.Context, conn net.Conn, metadata adapter.InboundContext, action option.RuleAction) error {
+	switch action.Action {
+	case C.RuleActionTypeHijackDNS:
+		return r.dnsHijack.NewConnection(ctx, conn, metadata)
+	case C.RuleActionTypeDrop:
+		action.Method = C.RuleActionRejectMethodDrop
+	}
+	r.logger.InfoContext(ctx, "rejected connection to ", metadata.Destination)
+	rejectConnection(conn, action.Method)
+	return nil
+}
+
+func (r *Router) routePacketConnectionAction(ctx context.Context, conn N.PacketConn, metadata adapter.InboundContext, action option.RuleAction) error {
+	switch action.Action {
+	case C.RuleActionTypeHijackDNS:
+		return r.dnsHijack.NewPacketConnection(ctx, conn, metadata)
+	case C.RuleActionTypeDrop:
+		action.Method = C.RuleActionRejectMethodDrop
+	}
+	r.logger.InfoContext(ctx, "rejected packet connection to ", metadata.Destination)
+	rejectPacketConnection(conn, action.Method)
+	return nil
 }
 
 func (r *Router) InterfaceFinder() control.InterfaceFinder {
@@ -1274,6 +1552,10 @@ func (r *Router) AutoRedirectOutputMark() uint32 {
 	return r.autoRedirectOutputMark
 }
 
+func (r *Router) RegisterMultipathTCPInterfaces(interfaces []string, flags uint32) error {
+	return r.mptcpManager.Register(interfaces, flags)
+}
+
 func (r *Router) DefaultInterface() string {
 	return r.defaultInterface
 }
@@ -1282,12 +1564,27 @@ func (r *Router) DefaultMark() uint32 {
 	return r.defaultMark
 }
 
+func (r *Router) DefaultBuffer() *option.BufferOptions {
+	return r.defaultBuffer
+}
+
 func (r *Router) Rules() []adapter.Rule {
-	return r.rules
+	return r.loadState().rules
 }
 
 func (r *Router) WIFIState() adapter.WIFIState {
-	return r.wifiState
+	state := r.wifiState.Load()
+	if state == nil {
+		return adapter.WIFIState{}
+	}
+	return *state
+}
+
+func (r *Router) TimeFunc() func() time.Time {
+	if r.timeService == nil {
+		return time.Now
+	}
+	return r.timeService.TimeFunc()
 }
 
 func (r *Router) NetworkMonitor() tun.NetworkUpdateMonitor {
@@ -1318,6 +1615,123 @@ func (r *Router) SetV2RayServer(server adapter.V2RayServer) {
 	r.v2rayServer = server
 }
 
+func (r *Router) AppendTracker(tracker adapter.ConnectionTracker) {
+	r.trackers = append(r.trackers, tracker)
+}
+
+// EnableConnectionTracking starts recording routed connections for
+// Connections, ClosedConnections and CloseConnection.
+func (r *Router) EnableConnectionTracking() {
+	if r.connections != nil {
+		return
+	}
+	r.connections = newConnectionTracker(r)
+	r.AppendTracker(r.connections)
+}
+
+func (r *Router) Connections() []adapter.TrackedConnection {
+	if r.connections == nil {
+		return nil
+	}
+	return r.connections.Connections()
+}
+
+func (r *Router) ClosedConnections() []adapter.TrackedConnection {
+	if r.connections == nil {
+		return nil
+	}
+	return r.connections.ClosedConnections()
+}
+
+func (r *Router) CloseConnection(id string) bool {
+	if r.connections == nil {
+		return false
+	}
+	return r.connections.CloseConnection(id)
+}
+
+type traceRequest struct {
+	match   func(metadata *adapter.InboundContext, outbound string) bool
+	matched chan context.Context
+}
+
+func (r *Router) TraceNextConnection(ctx context.Context, match func(metadata *adapter.InboundContext, outbound string) bool) (context.Context, error) {
+	request := &traceRequest{
+		match:   match,
+		matched: make(chan context.Context, 1),
+	}
+	r.traceAccess.Lock()
+	r.traceRequests = append(r.traceRequests, request)
+	r.traceRequestCount.Store(int32(len(r.traceRequests)))
+	r.traceAccess.Unlock()
+	select {
+	case connCtx := <-request.matched:
+		return connCtx, nil
+	case <-ctx.Done():
+	}
+	r.traceAccess.Lock()
+	r.traceRequests = common.Filter(r.traceRequests, func(it *traceRequest) bool {
+		return it != request
+	})
+	r.traceRequestCount.Store(int32(len(r.traceRequests)))
+	r.traceAccess.Unlock()
+	select {
+	case connCtx := <-request.matched:
+		return connCtx, nil
+	default:
+		return nil, ctx.Err()
+	}
+}
+
+// checkTrace runs after sniffing and routing, so that trace requests can
+// filter by sniffed domain and outbound. The steps taken so far are logged as
+// a summary, everything after is logged as it happens.
+func (r *Router) checkTrace(ctx context.Context, metadata *adapter.InboundContext, matchedRule adapter.Rule, detour adapter.Outbound) context.Context {
+	if r.traceRequestCount.Load() == 0 {
+		return ctx
+	}
+	r.traceAccess.Lock()
+	var matched bool
+	for i, request := range r.traceRequests {
+		if !request.match(metadata, detour.Tag()) {
+			continue
+		}
+		r.traceRequests = append(r.traceRequests[:i:i], r.traceRequests[i+1:]...)
+		r.traceRequestCount.Store(int32(len(r.traceRequests)))
+		ctx = log.ContextWithTrace(ctx, r.logger)
+		request.matched <- ctx
+		matched = true
+		break
+	}
+	r.traceAccess.Unlock()
+	if !matched {
+		return ctx
+	}
+	r.logger.InfoContext(ctx, "tracing ", metadata.Network, " connection from ", metadata.Source, " to ", metadata.Destination, " via inbound/", metadata.InboundType, "[", metadata.Inbound, "]")
+	if metadata.FakeIP {
+		r.logger.InfoContext(ctx, "found fakeip domain: ", metadata.Destination.Fqdn, " for ", metadata.OriginDestination)
+	}
+	if metadata.Protocol != "" {
+		if metadata.Domain != "" {
+			r.logger.InfoContext(ctx, "sniffed protocol: ", metadata.Protocol, ", domain: ", metadata.Domain)
+		} else {
+			r.logger.InfoContext(ctx, "sniffed protocol: ", metadata.Protocol)
+		}
+	}
+	if len(metadata.DestinationAddresses) > 0 {
+		r.logger.InfoContext(ctx, "resolved ", metadata.Destination.Fqdn, " to [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
+	}
+	for i, rule := range r.loadState().rules {
+		if rule == matchedRule {
+			r.logger.InfoContext(ctx, "match[", i, "] ", rule.String(), " => ", detour.Tag())
+			return ctx
+		}
+		r.logger.InfoContext(ctx, "not match[", i, "] ", rule.String())
+	}
+	r.logger.InfoContext(ctx, "no rule matched, use final outbound ", detour.Tag())
+	return ctx
+}
+
 func (r *Router) OnPackagesUpdated(packages int, sharedUsers int) {
 	r.logger.Info("updated packages list: ", packages, " packages, ", sharedUsers, " shared users")
 }
@@ -1360,7 +1774,7 @@ func (r *Router) notifyNetworkUpdate(event int) {
 func (r *Router) ResetNetwork() error {
 	conntrack.Close()
 
-	for _, outbound := range r.outbounds {
+	for _, outbound := range r.loadState().outbounds {
 		listener, isListener := outbound.(adapter.InterfaceUpdateListener)
 		if isListener {
 			listener.InterfaceUpdated()
@@ -1378,8 +1792,9 @@ func (r *Router) updateWIFIState() {
 		return
 	}
 	state := r.platformInterface.ReadWIFIState()
-	if state != r.wifiState {
-		r.wifiState = state
+	state.BSSID = strings.ToLower(state.BSSID)
+	if state != r.WIFIState() {
+		r.wifiState.Store(&state)
 		if state.SSID == "" && state.BSSID == "" {
 			r.logger.Info("updated WIFI state: disconnected")
 		} else {
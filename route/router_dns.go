@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
 	"github.com/sagernet/sing-dns"
 	"github.com/sagernet/sing/common/cache"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -41,6 +42,10 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 	if metadata == nil {
 		panic("no context")
 	}
+	if index == -1 && r.lanDNSTransport != nil && isLANHostname(metadata.Domain) {
+		r.dnsLogger.DebugContext(ctx, "match lan hostname => ", r.lanDNSTransport.Name())
+		return dns.ContextWithDisableCache(ctx, true), r.lanDNSTransport, r.defaultDomainStrategy, nil, -1
+	}
 	if index < len(r.dnsRules) {
 		dnsRules := r.dnsRules
 		if index != -1 {
@@ -52,21 +57,48 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 			}
 			metadata.ResetRuleCache()
 			if rule.Match(metadata) {
-				detour := rule.Outbound()
-				transport, loaded := r.transportMap[detour]
+				ruleIndex := currentRuleIndex
+				if index != -1 {
+					ruleIndex += index + 1
+				}
+				if rule.DisableFakeIP() {
+					metadata.DisableFakeIP = true
+				}
+				if staticTransport := rule.StaticTransport(); staticTransport != nil {
+					if !staticTransport.Contains(metadata.Domain) {
+						continue
+					}
+					r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => static")
+					return dns.ContextWithDisableCache(ctx, true), staticTransport, r.defaultDomainStrategy, rule, ruleIndex
+				}
+				server := rule.Outbound()
+				if server == "" {
+					r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => fakeip disabled")
+					continue
+				}
+				var (
+					transport dns.Transport
+					loaded    bool
+				)
+				detour := rule.Detour()
+				if detour != "" {
+					transport, loaded = r.dnsDetourTransports[dnsDetourKey{server, detour}]
+				} else {
+					transport, loaded = r.transportMap[server]
+				}
 				if !loaded {
-					r.dnsLogger.ErrorContext(ctx, "transport not found: ", detour)
+					r.dnsLogger.ErrorContext(ctx, "transport not found: ", server)
 					continue
 				}
 				_, isFakeIP := transport.(adapter.FakeIPTransport)
-				if isFakeIP && !allowFakeIP {
+				if isFakeIP && (!allowFakeIP || metadata.DisableFakeIP) {
 					continue
 				}
-				ruleIndex := currentRuleIndex
-				if index != -1 {
-					ruleIndex += index + 1
+				if detour != "" {
+					r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => ", server, " via ", detour)
+				} else {
+					r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => ", server)
 				}
-				r.dnsLogger.DebugContext(ctx, "match[", ruleIndex, "] ", rule.String(), " => ", detour)
 				if isFakeIP || rule.DisableCache() {
 					ctx = dns.ContextWithDisableCache(ctx, true)
 				}
@@ -94,6 +126,10 @@ func (r *Router) matchDNS(ctx context.Context, allowFakeIP bool, index int, isAd
 func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
 	if len(message.Question) > 0 {
 		r.dnsLogger.DebugContext(ctx, "exchange ", formatQuestion(message.Question[0].String()))
+		if r.dnsAdBlocker != nil && r.dnsAdBlocker.Block(ctx, fqdnToDomain(message.Question[0].Name)) {
+			r.dnsLogger.InfoContext(ctx, "blocked ", formatQuestion(message.Question[0].String()))
+			return r.dnsAdBlocker.Response(message), nil
+		}
 	}
 	var (
 		response  *mDNS.Msg
@@ -101,7 +137,11 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 		transport dns.Transport
 		err       error
 	)
-	response, cached = r.dnsClient.ExchangeCache(ctx, message)
+	if r.dnsCache != nil {
+		response, cached = r.dnsCache.ExchangeCache(ctx, message)
+	} else {
+		response, cached = r.dnsClient.ExchangeCache(ctx, message)
+	}
 	if !cached {
 		var metadata *adapter.InboundContext
 		ctx, metadata = adapter.ExtendContext(ctx)
@@ -129,14 +169,28 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 			)
 			dnsCtx, transport, strategy, rule, ruleIndex = r.matchDNS(ctx, true, ruleIndex, isAddressQuery(message))
 			dnsCtx = adapter.OverrideContext(dnsCtx)
+			var responseFilter adapter.DNSResponseFilter
+			if rule != nil {
+				responseFilter = rule.ResponseFilter()
+			}
+			if responseFilter != nil {
+				if filtered := responseFilter.FilterQuery(message); filtered != nil {
+					r.dnsLogger.DebugContext(ctx, "filtered query for ", formatQuestion(message.Question[0].String()))
+					response, err = filtered, nil
+					break
+				}
+			}
 			if rule != nil && rule.WithAddressLimit() {
 				addressLimit = true
+				var dnssecResult *dnssecResult
+				dnsCtx, dnssecResult = contextWithDNSSECResult(dnsCtx)
 				response, err = r.dnsClient.ExchangeWithResponseCheck(dnsCtx, transport, message, strategy, func(response *mDNS.Msg) bool {
 					addresses, addrErr := dns.MessageToAddresses(response)
 					if addrErr != nil {
 						return false
 					}
 					metadata.DestinationAddresses = addresses
+					metadata.DNSSECValidated = dnssecResult.Validated()
 					return rule.MatchAddressLimit(metadata)
 				})
 			} else {
@@ -160,6 +214,9 @@ func (r *Router) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, er
 			if addressLimit && rejected {
 				continue
 			}
+			if err == nil && responseFilter != nil {
+				response = responseFilter.FilterResponse(response)
+			}
 			break
 		}
 	}
@@ -187,10 +244,22 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 		cached        bool
 		err           error
 	)
-	responseAddrs, cached = r.dnsClient.LookupCache(ctx, domain, strategy)
+	if r.dnsAdBlocker != nil && r.dnsAdBlocker.Block(ctx, domain) {
+		r.dnsLogger.InfoContext(ctx, "blocked lookup for ", domain)
+		return nil, dns.RCodeNameError
+	}
+	if r.dnsCache != nil {
+		responseAddrs, cached = r.dnsCache.LookupCache(ctx, domain, strategy)
+	} else {
+		responseAddrs, cached = r.dnsClient.LookupCache(ctx, domain, strategy)
+	}
 	if cached {
+		if log.IsTraceContext(ctx) {
+			r.dnsLogger.DebugContext(ctx, "lookup succeed for ", domain, " (cached): ", strings.Join(F.MapToString(responseAddrs), " "))
+		}
 		return responseAddrs, nil
 	}
+	start := time.Now()
 	r.dnsLogger.DebugContext(ctx, "lookup domain ", domain)
 	ctx, metadata := adapter.ExtendContext(ctx)
 	metadata.Destination = M.Socksaddr{}
@@ -214,14 +283,22 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 		}
 		if rule != nil && rule.WithAddressLimit() {
 			addressLimit = true
+			var dnssecResult *dnssecResult
+			dnsCtx, dnssecResult = contextWithDNSSECResult(dnsCtx)
 			responseAddrs, err = r.dnsClient.LookupWithResponseCheck(dnsCtx, transport, domain, strategy, func(responseAddrs []netip.Addr) bool {
 				metadata.DestinationAddresses = responseAddrs
+				metadata.DNSSECValidated = dnssecResult.Validated()
 				return rule.MatchAddressLimit(metadata)
 			})
 		} else {
 			addressLimit = false
 			responseAddrs, err = r.dnsClient.Lookup(dnsCtx, transport, domain, strategy)
 		}
+		if err == nil && rule != nil {
+			if responseFilter := rule.ResponseFilter(); responseFilter != nil {
+				responseAddrs, err = responseFilter.FilterAddresses(responseAddrs)
+			}
+		}
 		if err != nil {
 			if errors.Is(err, dns.ErrResponseRejectedCached) {
 				r.dnsLogger.DebugContext(ctx, "response rejected for ", domain, " (cached)")
@@ -241,6 +318,7 @@ func (r *Router) Lookup(ctx context.Context, domain string, strategy dns.DomainS
 	if len(responseAddrs) > 0 {
 		r.dnsLogger.InfoContext(ctx, "lookup succeed for ", domain, ": ", strings.Join(F.MapToString(responseAddrs), " "))
 	}
+	log.TraceEvent(ctx, "lookup ", domain, " finished in ", time.Since(start))
 	return responseAddrs, err
 }
 
@@ -248,8 +326,18 @@ func (r *Router) LookupDefault(ctx context.Context, domain string) ([]netip.Addr
 	return r.Lookup(ctx, domain, dns.DomainStrategyAsIS)
 }
 
+func (r *Router) DNSAdBlocker() adapter.DNSAdBlocker {
+	if r.dnsAdBlocker == nil {
+		return nil
+	}
+	return r.dnsAdBlocker
+}
+
 func (r *Router) ClearDNSCache() {
 	r.dnsClient.ClearCache()
+	if r.dnsCache != nil {
+		r.dnsCache.Clear()
+	}
 	if r.platformInterface != nil {
 		r.platformInterface.ClearDNSCache()
 	}
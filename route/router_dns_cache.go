@@ -0,0 +1,37 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing/service"
+)
+
+func (r *Router) dnsCacheFile() adapter.CacheFile {
+	cacheFile := service.FromContext[adapter.CacheFile](r.ctx)
+	if cacheFile == nil || !cacheFile.StoreDNS() {
+		return nil
+	}
+	return cacheFile
+}
+
+func (r *Router) loadDNSCache() {
+	cacheFile := r.dnsCacheFile()
+	if cacheFile == nil {
+		return
+	}
+	if r.dnsCache == nil {
+		r.dnsLogger.Warn("store_dns in cache file requires dns.cache_capacity")
+		return
+	}
+	loaded := r.dnsCache.Restore(cacheFile.LoadDNSCache())
+	if loaded > 0 {
+		r.dnsLogger.Info("loaded ", loaded, " DNS cache entries")
+	}
+}
+
+func (r *Router) saveDNSCache() error {
+	cacheFile := r.dnsCacheFile()
+	if cacheFile == nil || r.dnsCache == nil {
+		return nil
+	}
+	return cacheFile.SaveDNSCache(r.dnsCache.Dump())
+}
@@ -0,0 +1,57 @@
+package route
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/sing-box/common/dialer"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	N "github.com/sagernet/sing/common/network"
+)
+
+type dnsDetourKey struct {
+	server string
+	detour string
+}
+
+// newDNSDetourTransport creates a copy of the server transport that connects
+// through the outbound detour of DNS rules.
+func (r *Router) newDNSDetourTransport(ctx context.Context, logFactory log.Factory, server option.DNSServerOptions, dnsOptions option.DNSOptions, resolver dns.Transport, detour string) (dns.Transport, error) {
+	switch server.Address {
+	case "local", "fakeip", C.DNSServerAddressRace:
+		return nil, E.New("detour is not supported for ", server.Address, " server: ", server.Tag)
+	}
+	var detourDialer N.Dialer = dialer.NewDetour(r, detour)
+	if server.AddressResolver != "" {
+		detourDialer = dns.NewDialerWrapper(detourDialer, r.dnsClient, resolver, dns.DomainStrategy(server.AddressStrategy), time.Duration(server.AddressFallbackDelay))
+	}
+	name := F.ToString(server.Tag, "/", detour)
+	transport, err := dns.CreateTransport(dns.TransportOptions{
+		Context: ctx,
+		Logger:  logFactory.NewLogger(F.ToString("dns/transport[", name, "]")),
+		Name:    name,
+		Dialer:  detourDialer,
+		Address: server.Address,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if server.ClientSubnet != nil {
+		transport = wrapClientSubnet(transport, server.ClientSubnet.Build())
+	} else if dnsOptions.ClientSubnet != nil {
+		transport = wrapClientSubnet(transport, dnsOptions.ClientSubnet.Build())
+	}
+	transport, err = wrapDNSSEC(transport, server.DNSSEC)
+	if err != nil {
+		return nil, err
+	}
+	if r.dnsCache != nil {
+		transport = r.dnsCache.Wrap(transport)
+	}
+	return transport, nil
+}
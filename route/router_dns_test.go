@@ -0,0 +1,164 @@
+package route
+
+import (
+	"context"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/json"
+
+	mDNS "github.com/miekg/dns"
+	"github.com/stretchr/testify/require"
+)
+
+type testFakeIPTransport struct {
+	testDNSTransport
+}
+
+func (t *testFakeIPTransport) Store() adapter.FakeIPStore {
+	return nil
+}
+
+func TestMatchDNSDisableFakeIP(t *testing.T) {
+	t.Parallel()
+	var rulesOptions []option.DNSRule
+	err := json.Unmarshal([]byte(`[
+  { "domain_suffix": "stun.l.google.com", "fakeip": false },
+  { "domain_suffix": "ntp.org", "fakeip": false, "server": "fake" },
+  { "query_type": "A", "server": "fake" }
+]`), &rulesOptions)
+	require.NoError(t, err)
+	_, err = NewDNSRule(nil, log.NewNOPFactory().Logger(), option.DNSRule{
+		Type:           "default",
+		DefaultOptions: option.DefaultDNSRule{Domain: []string{"example.com"}},
+	}, true)
+	require.EqualError(t, err, "missing server field")
+	defaultTransport := &testDNSTransport{name: "default"}
+	fakeTransport := &testFakeIPTransport{testDNSTransport{name: "fake"}}
+	router := &Router{
+		dnsLogger:        log.NewNOPFactory().Logger(),
+		defaultTransport: defaultTransport,
+		transportMap: map[string]dns.Transport{
+			"default": defaultTransport,
+			"fake":    fakeTransport,
+		},
+	}
+	for i, ruleOptions := range rulesOptions {
+		rule, err := NewDNSRule(nil, router.dnsLogger, ruleOptions, true)
+		require.NoError(t, err, i)
+		router.dnsRules = append(router.dnsRules, rule)
+	}
+	for _, testCase := range []struct {
+		domain    string
+		transport dns.Transport
+	}{
+		{"example.com", fakeTransport},
+		{"stun.l.google.com", defaultTransport},
+		{"pool.ntp.org", defaultTransport},
+	} {
+		ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{
+			Domain:    testCase.domain,
+			QueryType: mDNS.TypeA,
+		})
+		_, transport, _, _, _ := router.matchDNS(ctx, true, -1, true)
+		require.Equal(t, testCase.transport, transport, testCase.domain)
+	}
+}
+
+func TestMatchDNSStatic(t *testing.T) {
+	t.Parallel()
+	var rulesOptions []option.DNSRule
+	err := json.Unmarshal([]byte(`[
+  { "domain_suffix": "lan", "action": "static", "answer": "nas.lan. IN A 192.168.1.2" },
+  { "query_type": "A", "server": "default" }
+]`), &rulesOptions)
+	require.NoError(t, err)
+	defaultTransport := &testDNSTransport{name: "default"}
+	router := &Router{
+		dnsLogger:        log.NewNOPFactory().Logger(),
+		defaultTransport: defaultTransport,
+		transportMap:     map[string]dns.Transport{"default": defaultTransport},
+	}
+	for i, ruleOptions := range rulesOptions {
+		rule, err := NewDNSRule(nil, router.dnsLogger, ruleOptions, true)
+		require.NoError(t, err, i)
+		router.dnsRules = append(router.dnsRules, rule)
+	}
+	for _, testCase := range []struct {
+		domain string
+		static bool
+	}{
+		{"nas.lan", true},
+		{"printer.lan", false},
+		{"example.com", false},
+	} {
+		ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{
+			Domain:    testCase.domain,
+			QueryType: mDNS.TypeA,
+		})
+		_, transport, _, _, _ := router.matchDNS(ctx, true, -1, true)
+		_, isStatic := transport.(*staticDNSTransport)
+		require.Equal(t, testCase.static, isStatic, testCase.domain)
+	}
+	for _, testCase := range []struct {
+		rule string
+		err  string
+	}{
+		{`{ "domain": "nas.lan", "action": "static" }`, "missing answer or hosts_path"},
+		{`{ "domain": "nas.lan", "action": "static", "server": "default", "answer": "nas.lan. IN A 192.168.1.2" }`, "server is not allowed for the static action"},
+		{`{ "domain": "nas.lan", "server": "default", "answer": "nas.lan. IN A 192.168.1.2" }`, "answer and hosts_path are only allowed for the static action"},
+		{`{ "domain": "nas.lan", "action": "reject", "server": "default" }`, "unknown DNS rule action: reject"},
+	} {
+		var ruleOptions option.DNSRule
+		require.NoError(t, json.Unmarshal([]byte(testCase.rule), &ruleOptions))
+		_, err = NewDNSRule(nil, router.dnsLogger, ruleOptions, true)
+		require.EqualError(t, err, testCase.err)
+	}
+}
+
+func TestMatchDNSDetour(t *testing.T) {
+	t.Parallel()
+	var rulesOptions []option.DNSRule
+	err := json.Unmarshal([]byte(`[
+  { "domain_suffix": "google.com", "server": "remote", "detour": "proxy" },
+  { "query_type": "A", "server": "remote" }
+]`), &rulesOptions)
+	require.NoError(t, err)
+	defaultTransport := &testDNSTransport{name: "default"}
+	remoteTransport := &testDNSTransport{name: "remote"}
+	proxyTransport := &testDNSTransport{name: "remote/proxy"}
+	router := &Router{
+		dnsLogger:        log.NewNOPFactory().Logger(),
+		defaultTransport: defaultTransport,
+		transportMap:     map[string]dns.Transport{"remote": remoteTransport},
+		dnsDetourTransports: map[dnsDetourKey]dns.Transport{
+			{"remote", "proxy"}: proxyTransport,
+		},
+	}
+	for i, ruleOptions := range rulesOptions {
+		rule, err := NewDNSRule(nil, router.dnsLogger, ruleOptions, true)
+		require.NoError(t, err, i)
+		router.dnsRules = append(router.dnsRules, rule)
+	}
+	for _, testCase := range []struct {
+		domain    string
+		transport dns.Transport
+	}{
+		{"www.google.com", proxyTransport},
+		{"example.com", remoteTransport},
+	} {
+		ctx := adapter.WithContext(context.Background(), &adapter.InboundContext{
+			Domain:    testCase.domain,
+			QueryType: mDNS.TypeA,
+		})
+		_, transport, _, _, _ := router.matchDNS(ctx, true, -1, true)
+		require.Equal(t, testCase.transport, transport, testCase.domain)
+	}
+	var ruleOptions option.DNSRule
+	require.NoError(t, json.Unmarshal([]byte(`{ "domain": "example.com", "fakeip": false, "detour": "proxy" }`), &ruleOptions))
+	_, err = NewDNSRule(nil, router.dnsLogger, ruleOptions, true)
+	require.EqualError(t, err, "detour requires server")
+}
@@ -0,0 +1,65 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/common/domaintrie"
+)
+
+type domainItemRule interface {
+	appendDomainItems(items []*DomainItem) []*DomainItem
+}
+
+func appendDomainItems(items []*DomainItem, rule any) []*DomainItem {
+	if itemRule, isItemRule := rule.(domainItemRule); isItemRule {
+		return itemRule.appendDomainItems(items)
+	}
+	return items
+}
+
+// buildDomainTrie moves the domain and domain_suffix items of all rules and
+// loaded rule-sets into one trie, so that their domains are stored once and
+// a connection walks it once for all of them. It is rebuilt after reloads and
+// rule-set updates, dropping the items of replaced rules, and binary
+// rule-sets not decoded yet are left alone.
+func (r *Router) buildDomainTrie() {
+	r.domainTrieAccess.Lock()
+	defer r.domainTrieAccess.Unlock()
+	var items []*DomainItem
+	for _, rule := range r.loadState().rules {
+		items = appendDomainItems(items, rule)
+	}
+	for _, rule := range r.dnsRules {
+		items = appendDomainItems(items, rule)
+	}
+	for _, ruleSet := range r.ruleSets {
+		ruleSetWithState, loaded := ruleSet.(interface{ loadSnapshot() *ruleSetSnapshot })
+		if !loaded {
+			continue
+		}
+		for _, rule := range ruleSetWithState.loadSnapshot().rules {
+			items = appendDomainItems(items, rule)
+		}
+	}
+	keys, trieItems := useDomainTrie(items)
+	if trieItems > 0 {
+		r.logger.Debug("built domain trie of ", keys, " keys for ", trieItems, " rule items")
+	}
+}
+
+func useDomainTrie(items []*DomainItem) (keys int, trieItems int) {
+	if len(items) < 2 {
+		return
+	}
+	builder := domaintrie.NewBuilder()
+	trieKeys := make(map[*domaintrie.Trie]map[uint32][]string)
+	for i, item := range items {
+		item.addToTrie(builder, uint32(i), trieKeys)
+	}
+	trie := builder.Build()
+	if trie == nil {
+		return
+	}
+	for i, item := range items {
+		item.useTrie(trie, uint32(i))
+	}
+	return builder.Len(), len(items)
+}
@@ -0,0 +1,72 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDomainTrie(t *testing.T) {
+	t.Parallel()
+	var ruleOptions []option.Rule
+	err := json.Unmarshal([]byte(`[
+  { "domain": "example.com", "domain_suffix": ".example.org", "outbound": "a" },
+  { "domain_suffix": ["google.com", "cn"], "outbound": "b" },
+  {
+    "type": "logical",
+    "mode": "and",
+    "rules": [
+      { "domain_suffix": "example.org" },
+      { "domain": "www.example.org", "invert": true }
+    ],
+    "outbound": "c"
+  }
+]`), &ruleOptions)
+	require.NoError(t, err)
+	var (
+		rules []adapter.Rule
+		items []*DomainItem
+	)
+	for _, options := range ruleOptions {
+		rule, err := NewRule(nil, log.NewNOPFactory().Logger(), options, true)
+		require.NoError(t, err)
+		rules = append(rules, rule)
+		items = appendDomainItems(items, rule)
+	}
+	require.Len(t, items, 4)
+	domains := []string{"example.com", "a.example.com", "example.org", "a.example.org", "www.example.org", "google.com", "mail.google.com", "baidu.cn", "Baidu.CN", "notgoogle.com"}
+	expected := make([][]bool, len(domains))
+	for i, domain := range domains {
+		for _, rule := range rules {
+			expected[i] = append(expected[i], rule.Match(&adapter.InboundContext{Destination: M.Socksaddr{Fqdn: domain}}))
+		}
+	}
+	keys, trieItems := useDomainTrie(items)
+	require.Equal(t, 4, trieItems)
+	require.Equal(t, 8, keys)
+	for i, domain := range domains {
+		metadata := &adapter.InboundContext{Destination: M.Socksaddr{Fqdn: domain}}
+		for j, rule := range rules {
+			metadata.ResetRuleCache()
+			require.Equal(t, expected[i][j], rule.Match(metadata), domain)
+		}
+	}
+
+	// rebuilding without the first rule drops its keys
+	keys, trieItems = useDomainTrie(items[1:])
+	require.Equal(t, 3, trieItems)
+	require.Equal(t, 7, keys)
+	for i, domain := range domains {
+		metadata := &adapter.InboundContext{Destination: M.Socksaddr{Fqdn: domain}}
+		for j, rule := range rules[1:] {
+			metadata.ResetRuleCache()
+			require.Equal(t, expected[i][j+1], rule.Match(metadata), domain)
+		}
+	}
+}
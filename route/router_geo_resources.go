@@ -1,18 +1,25 @@
 package route
 
 import (
+	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"io"
 	"net"
 	"net/http"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/compress"
 	"github.com/sagernet/sing-box/common/geoip"
 	"github.com/sagernet/sing-box/common/geosite"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
 	M "github.com/sagernet/sing/common/metadata"
 	"github.com/sagernet/sing/common/rw"
@@ -20,23 +27,37 @@ import (
 )
 
 func (r *Router) GeoIPReader() *geoip.Reader {
-	return r.geoIPReader
+	return r.geoIPReader.Load()
 }
 
-func (r *Router) LoadGeosite(code string) (adapter.Rule, error) {
-	rule, cached := r.geositeCache[code]
+func (r *Router) ASNReader() *geoip.ASNReader {
+	return r.asnReader
+}
+
+func (r *Router) LoadGeosite(codes ...string) (adapter.Rule, error) {
+	cacheKey := strings.Join(codes, ",")
+	rule, cached := r.geositeCache[cacheKey]
 	if cached {
 		return rule, nil
 	}
-	items, err := r.geositeReader.Read(code)
-	if err != nil {
-		return nil, err
+	compiledRules := make([]option.DefaultRule, 0, len(codes))
+	for _, code := range codes {
+		compiledRule, loaded := r.geositeCompileCache[code]
+		if !loaded {
+			items, err := r.geositeReader.Read(code)
+			if err != nil {
+				return nil, err
+			}
+			compiledRule = geosite.Compile(items)
+			r.geositeCompileCache[code] = compiledRule
+		}
+		compiledRules = append(compiledRules, compiledRule)
 	}
-	rule, err = NewDefaultRule(r, nil, geosite.Compile(items))
+	rule, err := NewDefaultRule(r, nil, geosite.Merge(compiledRules))
 	if err != nil {
 		return nil, err
 	}
-	r.geositeCache[code] = rule
+	r.geositeCache[cacheKey] = rule
 	return rule, nil
 }
 
@@ -77,12 +98,55 @@ func (r *Router) prepareGeoIPDatabase() error {
 			return err
 		}
 	}
-	geoReader, codes, err := geoip.Open(geoPath)
+	geoReader, codes, err := r.openGeoIPDatabase(geoPath)
 	if err != nil {
 		return E.Cause(err, "open geoip database")
 	}
-	r.logger.Info("loaded geoip database: ", len(codes), " codes")
-	r.geoIPReader = geoReader
+	if len(codes) > 0 {
+		r.logger.Info("loaded geoip database: ", len(codes), " codes")
+	} else {
+		r.logger.Info("loaded geoip database: ", geoReader.Type())
+	}
+	r.geoIPReader.Store(geoReader)
+	r.geoIPPath = geoPath
+	return nil
+}
+
+// openGeoIPDatabase reads the database into memory if it is updated, as
+// replaced readers are left to the garbage collector instead of closed
+// under running lookups, and mapped files can not be replaced on Windows.
+func (r *Router) openGeoIPDatabase(path string) (*geoip.Reader, []string, error) {
+	if r.geoIPOptions.UpdateInterval == 0 {
+		return geoip.Open(path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return geoip.FromBytes(content)
+}
+
+// prepareASNDatabase opens a MaxMind compatible ASN database, which is not
+// downloaded automatically as no free distribution is available.
+func (r *Router) prepareASNDatabase() error {
+	var asnPath string
+	if r.geoIPOptions.ASNPath != "" {
+		asnPath = r.geoIPOptions.ASNPath
+	} else {
+		asnPath = "GeoLite2-ASN.mmdb"
+		if foundPath, loaded := C.FindPath(asnPath); loaded {
+			asnPath = foundPath
+		}
+	}
+	if !rw.IsFile(asnPath) {
+		asnPath = filemanager.BasePath(r.ctx, asnPath)
+	}
+	asnReader, err := geoip.OpenASN(asnPath)
+	if err != nil {
+		return E.Cause(err, "open asn database")
+	}
+	r.logger.Info("loaded asn database: ", asnReader.Type())
+	r.asnReader = asnReader
 	return nil
 }
 
@@ -129,81 +193,151 @@ func (r *Router) prepareGeositeDatabase() error {
 	} else {
 		return E.Cause(err, "open geosite database")
 	}
+	r.geositePath = geoPath
 	return nil
 }
 
-func (r *Router) downloadGeoIPDatabase(savePath string) error {
-	var downloadURL string
-	if r.geoIPOptions.DownloadURL != "" {
-		downloadURL = r.geoIPOptions.DownloadURL
-	} else {
-		downloadURL = "https://github.com/SagerNet/sing-geoip/releases/latest/download/geoip.db"
+// compileGeositeRules loads the geosite items of all rules from the opened
+// database, which is closed afterwards.
+func (r *Router) compileGeositeRules() error {
+	for _, rule := range r.loadState().rules {
+		err := rule.UpdateGeosite()
+		if err != nil {
+			r.logger.Error("failed to initialize geosite: ", err)
+		}
 	}
-	r.logger.Info("downloading geoip database")
-	var detour adapter.Outbound
-	if r.geoIPOptions.DownloadDetour != "" {
-		outbound, loaded := r.Outbound(r.geoIPOptions.DownloadDetour)
-		if !loaded {
-			return E.New("detour outbound not found: ", r.geoIPOptions.DownloadDetour)
+	for _, rule := range r.dnsRules {
+		err := rule.UpdateGeosite()
+		if err != nil {
+			r.logger.Error("failed to initialize geosite: ", err)
 		}
-		detour = outbound
-	} else {
-		detour = r.defaultOutboundForConnection
 	}
+	err := common.Close(r.geositeReader)
+	if err != nil {
+		return err
+	}
+	r.geositeCache = nil
+	r.geositeCompileCache = nil
+	r.geositeReader = nil
+	return nil
+}
 
-	if parentDir := filepath.Dir(savePath); parentDir != "" {
-		filemanager.MkdirAll(r.ctx, parentDir, 0o755)
+func (r *Router) startGeoUpdate() {
+	ctx, cancel := context.WithCancel(r.ctx)
+	var started bool
+	if r.needGeoIPDatabase && r.geoIPOptions.UpdateInterval > 0 {
+		go r.loopGeoUpdate(ctx, "geoip", r.geoIPPath, time.Duration(r.geoIPOptions.UpdateInterval), r.updateGeoIPDatabase)
+		started = true
+	}
+	if r.needGeositeDatabase && r.geositeOptions.UpdateInterval > 0 {
+		go r.loopGeoUpdate(ctx, "geosite", r.geositePath, time.Duration(r.geositeOptions.UpdateInterval), r.updateGeositeDatabase)
+		started = true
 	}
+	if started {
+		r.geoUpdateCancel = cancel
+	} else {
+		cancel()
+	}
+}
 
-	httpClient := &http.Client{
-		Transport: &http.Transport{
-			ForceAttemptHTTP2:   true,
-			TLSHandshakeTimeout: 5 * time.Second,
-			DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
-				return detour.DialContext(ctx, network, M.ParseSocksaddr(addr))
-			},
-		},
+// loopGeoUpdate updates the database once its file is older than interval.
+func (r *Router) loopGeoUpdate(ctx context.Context, name string, path string, interval time.Duration, update func() error) {
+	next := interval
+	if stat, err := os.Stat(path); err == nil {
+		next = max(interval-time.Since(stat.ModTime()), 0)
 	}
-	defer httpClient.CloseIdleConnections()
-	request, err := http.NewRequest("GET", downloadURL, nil)
+	timer := time.NewTimer(next)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		err := update()
+		if err != nil {
+			r.logger.Error("update ", name, " database: ", err)
+		}
+		timer.Reset(interval)
+	}
+}
+
+func (r *Router) updateGeoIPDatabase() error {
+	err := r.downloadGeoIPDatabase(r.geoIPPath)
 	if err != nil {
 		return err
 	}
-	response, err := httpClient.Do(request.WithContext(r.ctx))
+	geoReader, codes, err := r.openGeoIPDatabase(r.geoIPPath)
 	if err != nil {
-		return err
+		return E.Cause(err, "open geoip database")
 	}
-	defer response.Body.Close()
+	r.geoIPReader.Store(geoReader)
+	if len(codes) > 0 {
+		r.logger.Info("updated geoip database: ", len(codes), " codes")
+	} else {
+		r.logger.Info("updated geoip database: ", geoReader.Type())
+	}
+	return nil
+}
 
-	saveFile, err := filemanager.Create(r.ctx, savePath)
+func (r *Router) updateGeositeDatabase() error {
+	err := r.downloadGeositeDatabase(r.geositePath)
 	if err != nil {
-		return E.Cause(err, "open output file: ", downloadURL)
+		return err
 	}
-	_, err = io.Copy(saveFile, response.Body)
-	saveFile.Close()
+	geoReader, codes, err := geosite.Open(r.geositePath)
 	if err != nil {
-		filemanager.Remove(r.ctx, savePath)
+		return E.Cause(err, "open geosite database")
 	}
-	return err
+	r.geositeReader = geoReader
+	r.geositeCache = make(map[string]adapter.Rule)
+	r.geositeCompileCache = make(map[string]option.DefaultRule)
+	r.logger.Info("updated geosite database: ", len(codes), " codes")
+	return r.compileGeositeRules()
+}
+
+func (r *Router) downloadGeoIPDatabase(savePath string) error {
+	downloadURL := r.geoIPOptions.DownloadURL
+	if downloadURL == "" {
+		downloadURL = "https://github.com/SagerNet/sing-geoip/releases/latest/download/geoip.db"
+	}
+	r.logger.Info("downloading geoip database")
+	return r.downloadGeoResource(downloadURL, r.geoIPOptions.DownloadDetour, r.geoIPOptions.ChecksumURL, savePath, func(path string) error {
+		reader, _, err := geoip.Open(path)
+		if err != nil {
+			return err
+		}
+		return reader.Close()
+	})
 }
 
 func (r *Router) downloadGeositeDatabase(savePath string) error {
-	var downloadURL string
-	if r.geositeOptions.DownloadURL != "" {
-		downloadURL = r.geositeOptions.DownloadURL
-	} else {
+	downloadURL := r.geositeOptions.DownloadURL
+	if downloadURL == "" {
 		downloadURL = "https://github.com/SagerNet/sing-geosite/releases/latest/download/geosite.db"
 	}
 	r.logger.Info("downloading geosite database")
+	return r.downloadGeoResource(downloadURL, r.geositeOptions.DownloadDetour, r.geositeOptions.ChecksumURL, savePath, func(path string) error {
+		reader, _, err := geosite.Open(path)
+		if err != nil {
+			return err
+		}
+		return reader.Close()
+	})
+}
+
+// downloadGeoResource downloads to a temporary file, which only replaces
+// savePath after it matches the checksum and passes validate.
+func (r *Router) downloadGeoResource(downloadURL string, downloadDetour string, checksumURL string, savePath string, validate func(path string) error) error {
 	var detour adapter.Outbound
-	if r.geositeOptions.DownloadDetour != "" {
-		outbound, loaded := r.Outbound(r.geositeOptions.DownloadDetour)
+	if downloadDetour != "" {
+		outbound, loaded := r.Outbound(downloadDetour)
 		if !loaded {
-			return E.New("detour outbound not found: ", r.geositeOptions.DownloadDetour)
+			return E.New("detour outbound not found: ", downloadDetour)
 		}
 		detour = outbound
 	} else {
-		detour = r.defaultOutboundForConnection
+		detour = r.loadState().defaultOutboundForConnection
 	}
 
 	if parentDir := filepath.Dir(savePath); parentDir != "" {
@@ -220,24 +354,82 @@ func (r *Router) downloadGeositeDatabase(savePath string) error {
 		},
 	}
 	defer httpClient.CloseIdleConnections()
+	var checksum []byte
+	if checksumURL != "" {
+		var err error
+		checksum, err = fetchChecksum(r.ctx, httpClient, checksumURL)
+		if err != nil {
+			return E.Cause(err, "fetch checksum")
+		}
+	}
 	request, err := http.NewRequest("GET", downloadURL, nil)
 	if err != nil {
 		return err
 	}
+	compress.SetAcceptEncoding(request)
 	response, err := httpClient.Do(request.WithContext(r.ctx))
 	if err != nil {
 		return err
 	}
 	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return E.New("unexpected status: ", response.Status)
+	}
+	body, err := compress.DecodeResponse(response)
+	if err != nil {
+		return err
+	}
+	defer body.Close()
 
-	saveFile, err := filemanager.Create(r.ctx, savePath)
+	tempPath := savePath + ".tmp"
+	saveFile, err := filemanager.Create(r.ctx, tempPath)
 	if err != nil {
 		return E.Cause(err, "open output file: ", downloadURL)
 	}
-	_, err = io.Copy(saveFile, response.Body)
+	hash := sha256.New()
+	_, err = io.Copy(io.MultiWriter(saveFile, hash), body)
 	saveFile.Close()
+	if err == nil && checksum != nil && !bytes.Equal(hash.Sum(nil), checksum) {
+		err = E.New("checksum mismatch: expected ", hex.EncodeToString(checksum), ", got ", hex.EncodeToString(hash.Sum(nil)))
+	}
+	if err == nil {
+		err = validate(tempPath)
+	}
+	if err == nil {
+		err = os.Rename(tempPath, savePath)
+	}
 	if err != nil {
-		filemanager.Remove(r.ctx, savePath)
+		filemanager.Remove(r.ctx, tempPath)
 	}
 	return err
 }
+
+// fetchChecksum reads a SHA-256 checksum file in the sha256sum format,
+// where the first field is the hex encoded checksum.
+func fetchChecksum(ctx context.Context, httpClient *http.Client, checksumURL string) ([]byte, error) {
+	request, err := http.NewRequestWithContext(ctx, "GET", checksumURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	content, err := io.ReadAll(io.LimitReader(response.Body, 4096))
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(string(content))
+	if len(fields) == 0 {
+		return nil, E.New("empty checksum")
+	}
+	checksum, err := hex.DecodeString(fields[0])
+	if err != nil || len(checksum) != sha256.Size {
+		return nil, E.New("invalid SHA-256 checksum: ", fields[0])
+	}
+	return checksum, nil
+}
@@ -0,0 +1,95 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	E "github.com/sagernet/sing/common/exceptions"
+)
+
+func outboundsReplaced(oldState *routingState, newState *routingState) bool {
+	for tag, outbound := range oldState.outboundByTag {
+		if newState.outboundByTag[tag] != outbound {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Router) newRules(options []option.Rule) ([]adapter.Rule, error) {
+	rules := make([]adapter.Rule, 0, len(options))
+	for i, ruleOptions := range options {
+		routeRule, err := NewRule(r, r.logger, ruleOptions, true)
+		if err != nil {
+			return nil, E.Cause(err, "parse rule[", i, "]")
+		}
+		if mirror := routeRule.Mirror(); mirror != "" && r.mirrors[mirror] == nil {
+			return nil, E.New("parse rule[", i, "]: mirror not found: ", mirror)
+		}
+		rules = append(rules, routeRule)
+	}
+	return rules, nil
+}
+
+// Reload replaces the inbounds, outbounds, rules and final outbound of the
+// started router. Connections already routed keep the outbound they matched.
+// Rules that depend on resources only prepared at start, such as the geosite
+// database or the process searcher, require a restart instead.
+func (r *Router) Reload(inbounds []adapter.Inbound, outbounds []adapter.Outbound, ruleOptions []option.Rule, final string, defaultOutbound func() adapter.Outbound) error {
+	if hasRule(ruleOptions, isGeositeRule) {
+		return E.New("geosite rules require a restart")
+	}
+	if hasRule(ruleOptions, isGeoIPRule) && !r.needGeoIPDatabase {
+		return E.New("geoip rules require a restart")
+	}
+	if hasRule(ruleOptions, isASNRule) && r.asnReader == nil {
+		return E.New("asn rules require a restart")
+	}
+	if hasRule(ruleOptions, isProcessRule) && r.processSearcher == nil {
+		return E.New("process rules require a restart")
+	}
+	if hasRule(ruleOptions, isProcessPathRule) && !r.needProcessPath {
+		return E.New("process rules require a restart")
+	}
+	if hasRule(ruleOptions, isPackageRule) && C.IsAndroid && r.platformInterface == nil && r.packageManager == nil {
+		return E.New("package rules require a restart")
+	}
+	if hasRule(ruleOptions, isWIFIRule) && !r.needWIFIState {
+		return E.New("wifi rules require a restart")
+	}
+	rules, err := r.newRules(ruleOptions)
+	if err != nil {
+		return err
+	}
+	state, err := newRoutingState(inbounds, outbounds, rules, final, defaultOutbound)
+	if err != nil {
+		return err
+	}
+	for i, rule := range rules {
+		err = rule.Start()
+		if err != nil {
+			for _, startedRule := range rules[:i] {
+				startedRule.Close()
+			}
+			return E.Cause(err, "initialize rule[", i, "]")
+		}
+	}
+	oldState := r.state.Swap(state)
+	r.outboundSnapshot.Store(&outboundSnapshot{outboundByTag: state.outboundByTag})
+	if oldState != nil {
+		for i, rule := range oldState.rules {
+			err = rule.Close()
+			if err != nil {
+				r.logger.Error(E.Cause(err, "close rule[", i, "]"))
+			}
+		}
+		// DNS servers may keep connections through replaced outbounds
+		if outboundsReplaced(oldState, state) {
+			for _, transport := range r.transports {
+				transport.Reset()
+			}
+		}
+	}
+	r.buildDomainTrie()
+	return nil
+}
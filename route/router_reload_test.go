@@ -0,0 +1,31 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReloadASNRuleRequiresRestart(t *testing.T) {
+	t.Parallel()
+	router := &Router{}
+	err := router.Reload(nil, nil, []option.Rule{{
+		Type:           C.RuleTypeDefault,
+		DefaultOptions: option.DefaultRule{ASN: []uint32{13335}},
+	}}, "", nil)
+	require.ErrorContains(t, err, "restart")
+}
+
+func TestOutboundsReplaced(t *testing.T) {
+	t.Parallel()
+	proxy := &testOutbound{tag: "proxy"}
+	direct := &testOutbound{tag: "direct"}
+	oldState := &routingState{outboundByTag: map[string]adapter.Outbound{"proxy": proxy, "direct": direct}}
+	require.False(t, outboundsReplaced(oldState, &routingState{outboundByTag: map[string]adapter.Outbound{"proxy": proxy, "direct": direct, "new": &testOutbound{tag: "new"}}}))
+	require.True(t, outboundsReplaced(oldState, &routingState{outboundByTag: map[string]adapter.Outbound{"proxy": &testOutbound{tag: "proxy"}, "direct": direct}}))
+	require.True(t, outboundsReplaced(oldState, &routingState{outboundByTag: map[string]adapter.Outbound{"proxy": proxy}}))
+}
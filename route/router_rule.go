@@ -62,6 +62,14 @@ func isGeoIPDNSRule(rule option.DefaultDNSRule) bool {
 	return len(rule.SourceGeoIP) > 0 && common.Any(rule.SourceGeoIP, notPrivateNode) || len(rule.GeoIP) > 0 && common.Any(rule.GeoIP, notPrivateNode)
 }
 
+func isASNRule(rule option.DefaultRule) bool {
+	return len(rule.SourceASN) > 0 || len(rule.ASN) > 0
+}
+
+func isASNDNSRule(rule option.DefaultDNSRule) bool {
+	return len(rule.SourceASN) > 0 || len(rule.ASN) > 0
+}
+
 func isGeositeRule(rule option.DefaultRule) bool {
 	return len(rule.Geosite) > 0
 }
@@ -71,17 +79,31 @@ func isGeositeDNSRule(rule option.DefaultDNSRule) bool {
 }
 
 func isProcessRule(rule option.DefaultRule) bool {
-	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0
+	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0 || len(rule.AppID) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0
 }
 
 func isProcessDNSRule(rule option.DefaultDNSRule) bool {
-	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0
+	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0 || len(rule.AppID) > 0 || len(rule.User) > 0 || len(rule.UserID) > 0
+}
+
+// isProcessPathRule reports whether the rule needs the process path, which is
+// more expensive to find than the owning user on Linux.
+func isProcessPathRule(rule option.DefaultRule) bool {
+	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0
+}
+
+func isProcessPathDNSRule(rule option.DefaultDNSRule) bool {
+	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0
 }
 
 func isProcessHeadlessRule(rule option.DefaultHeadlessRule) bool {
 	return len(rule.ProcessName) > 0 || len(rule.ProcessPath) > 0 || len(rule.PackageName) > 0
 }
 
+func isPackageRule(rule option.DefaultRule) bool {
+	return len(rule.PackageName) > 0
+}
+
 func notPrivateNode(code string) bool {
 	return code != "private"
 }
@@ -0,0 +1,141 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing/common"
+	E "github.com/sagernet/sing/common/exceptions"
+	N "github.com/sagernet/sing/common/network"
+)
+
+// outboundSnapshot is never modified after being published, so the
+// connection hot path can read it without holding any lock.
+type outboundSnapshot struct {
+	outbounds     []adapter.Outbound
+	outboundByTag map[string]adapter.Outbound
+}
+
+func (s *outboundSnapshot) withOutbound(tag string, outbound adapter.Outbound) *outboundSnapshot {
+	newSnapshot := &outboundSnapshot{
+		outbounds:     s.outbounds,
+		outboundByTag: make(map[string]adapter.Outbound, len(s.outboundByTag)+1),
+	}
+	for existsTag, existsOutbound := range s.outboundByTag {
+		newSnapshot.outboundByTag[existsTag] = existsOutbound
+	}
+	newSnapshot.outboundByTag[tag] = outbound
+	return newSnapshot
+}
+
+func (s *outboundSnapshot) withOutbounds(outbounds []adapter.Outbound) *outboundSnapshot {
+	return &outboundSnapshot{
+		outbounds:     outbounds,
+		outboundByTag: s.outboundByTag,
+	}
+}
+
+func (r *Router) loadOutboundSnapshot() *outboundSnapshot {
+	snapshot := r.outboundSnapshot.Load()
+	if snapshot == nil {
+		return &outboundSnapshot{}
+	}
+	return snapshot
+}
+
+// cacheOutboundSnapshot publishes newSnapshot only if the snapshot it was
+// derived from is still current, so that outbounds resolved before a provider
+// update are never cached after it.
+func (r *Router) cacheOutboundSnapshot(oldSnapshot *outboundSnapshot, newSnapshot *outboundSnapshot) {
+	r.outboundSnapshot.CompareAndSwap(oldSnapshot, newSnapshot)
+}
+
+// routingState holds everything a reload replaces. Like outboundSnapshot, it
+// is never modified after being published.
+type routingState struct {
+	inbounds                           []adapter.Inbound
+	inboundByTag                       map[string]adapter.Inbound
+	outbounds                          []adapter.Outbound
+	outboundByTag                      map[string]adapter.Outbound
+	rules                              []adapter.Rule
+	defaultDetour                      string
+	defaultOutboundForConnection       adapter.Outbound
+	defaultOutboundForPacketConnection adapter.Outbound
+}
+
+func newRoutingState(inbounds []adapter.Inbound, outbounds []adapter.Outbound, rules []adapter.Rule, defaultDetour string, defaultOutbound func() adapter.Outbound) (*routingState, error) {
+	inboundByTag := make(map[string]adapter.Inbound)
+	for _, inbound := range inbounds {
+		inboundByTag[inbound.Tag()] = inbound
+	}
+	outboundByTag := make(map[string]adapter.Outbound)
+	for _, detour := range outbounds {
+		outboundByTag[detour.Tag()] = detour
+	}
+	var defaultOutboundForConnection adapter.Outbound
+	var defaultOutboundForPacketConnection adapter.Outbound
+	if defaultDetour != "" {
+		detour, loaded := outboundByTag[defaultDetour]
+		if !loaded {
+			return nil, E.New("default detour not found: ", defaultDetour)
+		}
+		if common.Contains(detour.Network(), N.NetworkTCP) {
+			defaultOutboundForConnection = detour
+		}
+		if common.Contains(detour.Network(), N.NetworkUDP) {
+			defaultOutboundForPacketConnection = detour
+		}
+	}
+	if defaultOutboundForConnection == nil {
+		for _, detour := range outbounds {
+			if common.Contains(detour.Network(), N.NetworkTCP) {
+				defaultOutboundForConnection = detour
+				break
+			}
+		}
+	}
+	if defaultOutboundForPacketConnection == nil {
+		for _, detour := range outbounds {
+			if common.Contains(detour.Network(), N.NetworkUDP) {
+				defaultOutboundForPacketConnection = detour
+				break
+			}
+		}
+	}
+	if defaultOutboundForConnection == nil || defaultOutboundForPacketConnection == nil {
+		detour := defaultOutbound()
+		if defaultOutboundForConnection == nil {
+			defaultOutboundForConnection = detour
+		}
+		if defaultOutboundForPacketConnection == nil {
+			defaultOutboundForPacketConnection = detour
+		}
+		outbounds = append(outbounds, detour)
+		outboundByTag[detour.Tag()] = detour
+	}
+	for i, rule := range rules {
+		if rule.Action().Action != C.RuleActionTypeRoute {
+			continue
+		}
+		if _, loaded := outboundByTag[rule.Outbound()]; !loaded {
+			return nil, E.New("outbound not found for rule[", i, "]: ", rule.Outbound())
+		}
+	}
+	return &routingState{
+		inbounds:                           inbounds,
+		inboundByTag:                       inboundByTag,
+		outbounds:                          outbounds,
+		outboundByTag:                      outboundByTag,
+		rules:                              rules,
+		defaultDetour:                      defaultDetour,
+		defaultOutboundForConnection:       defaultOutboundForConnection,
+		defaultOutboundForPacketConnection: defaultOutboundForPacketConnection,
+	}, nil
+}
+
+func (r *Router) loadState() *routingState {
+	state := r.state.Load()
+	if state == nil {
+		return &routingState{}
+	}
+	return state
+}
@@ -0,0 +1,20 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCacheOutboundSnapshotAfterReset(t *testing.T) {
+	t.Parallel()
+	router := &Router{}
+	snapshot := &outboundSnapshot{}
+	router.outboundSnapshot.Store(snapshot)
+	resetSnapshot := &outboundSnapshot{}
+	router.outboundSnapshot.Store(resetSnapshot)
+	router.cacheOutboundSnapshot(snapshot, snapshot.withOutbound("provided", nil))
+	require.Same(t, resetSnapshot, router.loadOutboundSnapshot())
+	router.cacheOutboundSnapshot(resetSnapshot, resetSnapshot.withOutbound("provided", nil))
+	require.Contains(t, router.loadOutboundSnapshot().outboundByTag, "provided")
+}
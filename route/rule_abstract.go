@@ -3,9 +3,11 @@ package route
 import (
 	"io"
 	"strings"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	F "github.com/sagernet/sing/common/format"
 )
@@ -21,6 +23,9 @@ type abstractDefaultRule struct {
 	ruleSetItem             RuleItem
 	invert                  bool
 	outbound                string
+	udpTimeout              time.Duration
+	mirror                  string
+	action                  option.RuleAction
 }
 
 func (r *abstractDefaultRule) Type() string {
@@ -63,6 +68,15 @@ func (r *abstractDefaultRule) UpdateGeosite() error {
 	return nil
 }
 
+func (r *abstractDefaultRule) appendDomainItems(items []*DomainItem) []*DomainItem {
+	for _, item := range r.allItems {
+		if domainItem, isDomainItem := item.(*DomainItem); isDomainItem {
+			items = append(items, domainItem)
+		}
+	}
+	return items
+}
+
 func (r *abstractDefaultRule) Match(metadata *adapter.InboundContext) bool {
 	if len(r.allItems) == 0 {
 		return true
@@ -154,6 +168,18 @@ func (r *abstractDefaultRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractDefaultRule) UDPTimeout() time.Duration {
+	return r.udpTimeout
+}
+
+func (r *abstractDefaultRule) Mirror() string {
+	return r.mirror
+}
+
+func (r *abstractDefaultRule) Action() option.RuleAction {
+	return ruleAction(r.action)
+}
+
 func (r *abstractDefaultRule) String() string {
 	if !r.invert {
 		return strings.Join(F.MapToString(r.allItems), " ")
@@ -163,10 +189,13 @@ func (r *abstractDefaultRule) String() string {
 }
 
 type abstractLogicalRule struct {
-	rules    []adapter.HeadlessRule
-	mode     string
-	invert   bool
-	outbound string
+	rules      []adapter.HeadlessRule
+	mode       string
+	invert     bool
+	outbound   string
+	udpTimeout time.Duration
+	mirror     string
+	action     option.RuleAction
 }
 
 func (r *abstractLogicalRule) Type() string {
@@ -217,6 +246,13 @@ func (r *abstractLogicalRule) Close() error {
 	return nil
 }
 
+func (r *abstractLogicalRule) appendDomainItems(items []*DomainItem) []*DomainItem {
+	for _, rule := range r.rules {
+		items = appendDomainItems(items, rule)
+	}
+	return items
+}
+
 func (r *abstractLogicalRule) Match(metadata *adapter.InboundContext) bool {
 	if r.mode == C.LogicalTypeAnd {
 		return common.All(r.rules, func(it adapter.HeadlessRule) bool {
@@ -235,6 +271,18 @@ func (r *abstractLogicalRule) Outbound() string {
 	return r.outbound
 }
 
+func (r *abstractLogicalRule) UDPTimeout() time.Duration {
+	return r.udpTimeout
+}
+
+func (r *abstractLogicalRule) Mirror() string {
+	return r.mirror
+}
+
+func (r *abstractLogicalRule) Action() option.RuleAction {
+	return ruleAction(r.action)
+}
+
 func (r *abstractLogicalRule) String() string {
 	var op string
 	switch r.mode {
@@ -243,9 +291,15 @@ func (r *abstractLogicalRule) String() string {
 	case C.LogicalTypeOr:
 		op = "||"
 	}
+	ruleStrings := common.Map(r.rules, func(it adapter.HeadlessRule) string {
+		if rule, isLogical := it.(interface{ Type() string }); isLogical && rule.Type() == C.RuleTypeLogical && !strings.HasPrefix(it.String(), "!(") {
+			return "(" + it.String() + ")"
+		}
+		return it.String()
+	})
 	if !r.invert {
-		return strings.Join(F.MapToString(r.rules), " "+op+" ")
+		return strings.Join(ruleStrings, " "+op+" ")
 	} else {
-		return "!(" + strings.Join(F.MapToString(r.rules), " "+op+" ") + ")"
+		return "!(" + strings.Join(ruleStrings, " "+op+" ") + ")"
 	}
 }
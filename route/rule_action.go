@@ -0,0 +1,320 @@
+package route
+
+import (
+	"context"
+	"net"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/sniff"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	dns "github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/buf"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+)
+
+var (
+	streamSniffers = map[string][]sniff.StreamSniffer{
+		C.ProtocolDNS:        {sniff.StreamDomainNameQuery},
+		C.ProtocolTLS:        {sniff.TLSClientHello},
+		C.ProtocolHTTP:       {sniff.HTTPHost},
+		C.ProtocolBitTorrent: {sniff.BitTorrent},
+		C.ProtocolSSH:        {sniff.SSH},
+		C.ProtocolRDP:        {sniff.RDP},
+	}
+	packetSniffers = map[string][]sniff.PacketSniffer{
+		C.ProtocolDNS:        {sniff.DomainNameQuery},
+		C.ProtocolQUIC:       {sniff.QUICClientHello},
+		C.ProtocolSTUN:       {sniff.STUNMessage},
+		C.ProtocolBitTorrent: {sniff.UTP, sniff.UDPTracker},
+		C.ProtocolDTLS:       {sniff.DTLSRecord},
+	}
+	streamSnifferOrder = []string{C.ProtocolDNS, C.ProtocolTLS, C.ProtocolHTTP, C.ProtocolBitTorrent, C.ProtocolSSH, C.ProtocolRDP}
+	packetSnifferOrder = []string{C.ProtocolDNS, C.ProtocolQUIC, C.ProtocolSTUN, C.ProtocolBitTorrent, C.ProtocolDTLS}
+)
+
+func ruleAction(action option.RuleAction) option.RuleAction {
+	if action.Action == "" {
+		action.Action = C.RuleActionTypeRoute
+	}
+	if action.Action == C.RuleActionTypeReject && action.Method == "" {
+		action.Method = C.RuleActionRejectMethodDefault
+	}
+	return action
+}
+
+func validateRuleAction(action option.RuleAction, outbound string) error {
+	action = ruleAction(action)
+	switch action.Action {
+	case C.RuleActionTypeRoute:
+		if outbound == "" {
+			return E.New("missing outbound field")
+		}
+		if action.OverrideAddress != "" && !M.ParseAddr(action.OverrideAddress).IsValid() && !M.IsDomainName(action.OverrideAddress) {
+			return E.New("invalid override_address: ", action.OverrideAddress)
+		}
+		return nil
+	case C.RuleActionTypeReject:
+		switch action.Method {
+		case C.RuleActionRejectMethodDefault, C.RuleActionRejectMethodDrop:
+		default:
+			return E.New("unknown reject method: ", action.Method)
+		}
+	case C.RuleActionTypeSniff:
+		for _, sniffer := range action.Sniffer {
+			if streamSniffers[sniffer] == nil && packetSniffers[sniffer] == nil {
+				return E.New("unknown sniffer: ", sniffer)
+			}
+		}
+	case C.RuleActionTypeDrop, C.RuleActionTypeHijackDNS, C.RuleActionTypeResolve:
+	default:
+		return E.New("unknown rule action: ", action.Action)
+	}
+	if outbound != "" {
+		return E.New("outbound is only allowed for the route action")
+	}
+	if action.OverrideAddress != "" || action.OverridePort != 0 {
+		return E.New("override_address and override_port are only allowed for the route action")
+	}
+	return nil
+}
+
+// overrideDestination rewrites the destination of metadata as configured in
+// the route action, and reports the original destination if changed.
+func overrideDestination(metadata *adapter.InboundContext, action option.RuleAction) (M.Socksaddr, bool) {
+	if action.OverrideAddress == "" && action.OverridePort == 0 {
+		return M.Socksaddr{}, false
+	}
+	originDestination := metadata.Destination
+	if action.OverrideAddress != "" {
+		metadata.Destination = M.ParseSocksaddrHostPort(action.OverrideAddress, metadata.Destination.Port)
+		metadata.DestinationAddresses = nil
+	}
+	if action.OverridePort != 0 {
+		metadata.Destination.Port = action.OverridePort
+	}
+	if metadata.Destination.IsIPv4() {
+		metadata.IPVersion = 4
+	} else if metadata.Destination.IsIPv6() {
+		metadata.IPVersion = 6
+	}
+	return originDestination, true
+}
+
+// ruleTarget describes where connections matching the rule go, for logs.
+func ruleTarget(rule adapter.Rule) string {
+	action := rule.Action()
+	switch action.Action {
+	case C.RuleActionTypeRoute:
+		return rule.Outbound()
+	case C.RuleActionTypeReject:
+		if action.Method != C.RuleActionRejectMethodDefault {
+			return action.Action + "(" + action.Method + ")"
+		}
+	}
+	return action.Action
+}
+
+// selectSniffers returns the sniffers named in names in their default order,
+// or all of them if names is empty.
+func selectSniffers[T any](sniffers map[string][]T, order []string, names []string) []T {
+	var selected []T
+	for _, name := range order {
+		if len(names) == 0 || common.Contains(names, name) {
+			selected = append(selected, sniffers[name]...)
+		}
+	}
+	return selected
+}
+
+// rejectConnection closes conn with a TCP reset if possible, or keeps it
+// open discarding all data for the drop method.
+func rejectConnection(conn net.Conn, method string) {
+	if method == C.RuleActionRejectMethodDrop {
+		dropConnection(conn, C.TCPKeepAliveInitial)
+		return
+	}
+	if tcpConn, isTCPConn := common.Cast[*net.TCPConn](conn); isTCPConn {
+		tcpConn.SetLinger(0)
+	}
+	conn.Close()
+}
+
+func dropConnection(conn net.Conn, timeout time.Duration) {
+	defer conn.Close()
+	buffer := buf.NewSize(buf.BufferSize)
+	defer buffer.Release()
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return
+		}
+		buffer.Reset()
+		_, err = buffer.ReadOnceFrom(conn)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func rejectPacketConnection(conn N.PacketConn, method string) {
+	if method == C.RuleActionRejectMethodDrop {
+		dropPacketConnection(conn, C.UDPTimeout)
+		return
+	}
+	conn.Close()
+}
+
+func dropPacketConnection(conn N.PacketConn, timeout time.Duration) {
+	defer conn.Close()
+	buffer := buf.NewPacket()
+	defer buffer.Release()
+	for {
+		err := conn.SetReadDeadline(time.Now().Add(timeout))
+		if err != nil {
+			return
+		}
+		buffer.Reset()
+		_, err = conn.ReadPacket(buffer)
+		if err != nil {
+			return
+		}
+	}
+}
+
+func sniffPort(ports []uint16, port uint16) bool {
+	return len(ports) == 0 || common.Contains(ports, port)
+}
+
+// newSniffBuffer limits the data read from streams before sniffing gives up,
+// which is also the data held back from the outbound meanwhile.
+func newSniffBuffer(size int) *buf.Buffer {
+	if size <= 0 {
+		return buf.NewPacket()
+	}
+	return buf.NewSize(size)
+}
+
+func (r *Router) sniffConnection(ctx context.Context, metadata *adapter.InboundContext, action option.RuleAction, conn *net.Conn) {
+	if metadata.Protocol != "" {
+		return
+	}
+	buffer := newSniffBuffer(action.BufferSize)
+	sniffMetadata, err := sniff.PeekStream(
+		ctx,
+		*conn,
+		buffer,
+		time.Duration(action.Timeout),
+		selectSniffers(streamSniffers, streamSnifferOrder, action.Sniffer)...,
+	)
+	if sniffMetadata != nil {
+		r.applySniffMetadata(ctx, metadata, sniffMetadata, "sniffed protocol: ")
+	} else if err != nil {
+		r.logger.TraceContext(ctx, "sniffed no protocol: ", err)
+	}
+	if !buffer.IsEmpty() {
+		*conn = bufio.NewCachedConn(*conn, buffer)
+	} else {
+		buffer.Release()
+	}
+}
+
+func (r *Router) sniffPacketConnection(ctx context.Context, metadata *adapter.InboundContext, action option.RuleAction, conn *N.PacketConn) error {
+	if metadata.Protocol != "" {
+		return nil
+	}
+	buffer := buf.NewPacket()
+	destination, err := (*conn).ReadPacket(buffer)
+	if err != nil {
+		buffer.Release()
+		return err
+	}
+	sniffMetadata, _ := sniff.PeekPacket(
+		ctx,
+		buffer.Bytes(),
+		selectSniffers(packetSniffers, packetSnifferOrder, action.Sniffer)...,
+	)
+	if sniffMetadata != nil {
+		r.applySniffMetadata(ctx, metadata, sniffMetadata, "sniffed packet protocol: ")
+	}
+	*conn = bufio.NewCachedPacketConn(*conn, buffer, destination)
+	return nil
+}
+
+func (r *Router) applySniffMetadata(ctx context.Context, metadata *adapter.InboundContext, sniffMetadata *adapter.InboundContext, message string) {
+	metadata.Protocol = sniffMetadata.Protocol
+	metadata.Domain = sniffMetadata.Domain
+	if metadata.InboundOptions.SniffOverrideDestination && M.IsDomainName(metadata.Domain) {
+		metadata.Destination = M.Socksaddr{
+			Fqdn: metadata.Domain,
+			Port: metadata.Destination.Port,
+		}
+	}
+	if metadata.Domain != "" {
+		r.logger.DebugContext(ctx, message, metadata.Protocol, ", domain: ", metadata.Domain)
+	} else {
+		r.logger.DebugContext(ctx, message, metadata.Protocol)
+	}
+}
+
+func (r *Router) resolveDestination(ctx context.Context, metadata *adapter.InboundContext, action option.RuleAction) error {
+	if !metadata.Destination.IsFqdn() {
+		return nil
+	}
+	var (
+		addresses []netip.Addr
+		err       error
+	)
+	ctx = adapter.WithContext(ctx, metadata)
+	if action.Server != "" {
+		transport, loaded := r.transportMap[action.Server]
+		if !loaded {
+			return E.New("DNS server not found: ", action.Server)
+		}
+		addresses, err = r.dnsClient.Lookup(ctx, transport, metadata.Destination.Fqdn, dns.DomainStrategy(action.Strategy))
+	} else {
+		addresses, err = r.Lookup(ctx, metadata.Destination.Fqdn, dns.DomainStrategy(action.Strategy))
+	}
+	if err != nil {
+		return err
+	}
+	metadata.DestinationAddresses = addresses
+	r.dnsLogger.DebugContext(ctx, "resolved [", strings.Join(F.MapToString(metadata.DestinationAddresses), " "), "]")
+	return nil
+}
+
+// overridePacketConn sends packets to the original destination to the
+// overridden one instead, and makes replies look like they come from the
+// original destination.
+type overridePacketConn struct {
+	N.PacketConn
+	origin      M.Socksaddr
+	destination M.Socksaddr
+}
+
+func (c *overridePacketConn) ReadPacket(buffer *buf.Buffer) (M.Socksaddr, error) {
+	destination, err := c.PacketConn.ReadPacket(buffer)
+	if err == nil && destination.Unwrap() == c.origin {
+		destination = c.destination
+	}
+	return destination, err
+}
+
+func (c *overridePacketConn) WritePacket(buffer *buf.Buffer, destination M.Socksaddr) error {
+	if destination.Unwrap() == c.destination {
+		destination = c.origin
+	}
+	return c.PacketConn.WritePacket(buffer, destination)
+}
+
+func (c *overridePacketConn) Upstream() any {
+	return c.PacketConn
+}
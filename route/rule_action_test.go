@@ -0,0 +1,42 @@
+package route
+
+import (
+	"testing"
+
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRuleAction(t *testing.T) {
+	t.Parallel()
+	for _, testCase := range []struct {
+		action   option.RuleAction
+		outbound string
+		err      string
+	}{
+		{option.RuleAction{}, "direct", ""},
+		{option.RuleAction{}, "", "missing outbound field"},
+		{option.RuleAction{Action: C.RuleActionTypeReject}, "", ""},
+		{option.RuleAction{Action: C.RuleActionTypeReject, Method: C.RuleActionRejectMethodDrop}, "", ""},
+		{option.RuleAction{Action: C.RuleActionTypeReject, Method: "icmp"}, "", "unknown reject method: icmp"},
+		{option.RuleAction{Action: C.RuleActionTypeReject}, "direct", "outbound is only allowed for the route action"},
+		{option.RuleAction{Action: C.RuleActionTypeSniff, Sniffer: []string{C.ProtocolTLS, C.ProtocolQUIC}}, "", ""},
+		{option.RuleAction{Action: C.RuleActionTypeSniff, Sniffer: []string{"ftp"}}, "", "unknown sniffer: ftp"},
+		{option.RuleAction{Action: "block"}, "", "unknown rule action: block"},
+		{option.RuleAction{OverrideAddress: "127.0.0.1", OverridePort: 5353}, "direct", ""},
+		{option.RuleAction{OverrideAddress: "dns.internal"}, "direct", ""},
+		{option.RuleAction{OverrideAddress: "not an address"}, "direct", "invalid override_address: not an address"},
+		{option.RuleAction{Action: C.RuleActionTypeReject, OverridePort: 53}, "", "override_address and override_port are only allowed for the route action"},
+	} {
+		err := validateRuleAction(testCase.action, testCase.outbound)
+		if testCase.err == "" {
+			require.NoError(t, err, testCase.action)
+		} else {
+			require.EqualError(t, err, testCase.err, testCase.action)
+		}
+	}
+	require.Len(t, selectSniffers(packetSniffers, packetSnifferOrder, []string{C.ProtocolBitTorrent}), 2)
+	require.Len(t, selectSniffers(streamSniffers, streamSnifferOrder, nil), len(streamSnifferOrder))
+}
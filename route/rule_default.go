@@ -1,6 +1,8 @@
 package route
 
 import (
+	"time"
+
 	"github.com/sagernet/sing-box/adapter"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/log"
@@ -14,16 +16,22 @@ func NewRule(router adapter.Router, logger log.ContextLogger, options option.Rul
 		if !options.DefaultOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.DefaultOptions.Outbound == "" && checkOutbound {
-			return nil, E.New("missing outbound field")
+		if checkOutbound {
+			err := validateRuleAction(options.DefaultOptions.RuleAction, options.DefaultOptions.Outbound)
+			if err != nil {
+				return nil, err
+			}
 		}
 		return NewDefaultRule(router, logger, options.DefaultOptions)
 	case C.RuleTypeLogical:
 		if !options.LogicalOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.LogicalOptions.Outbound == "" && checkOutbound {
-			return nil, E.New("missing outbound field")
+		if checkOutbound {
+			err := validateRuleAction(options.LogicalOptions.RuleAction, options.LogicalOptions.Outbound)
+			if err != nil {
+				return nil, err
+			}
 		}
 		return NewLogicalRule(router, logger, options.LogicalOptions)
 	default:
@@ -45,8 +53,11 @@ type RuleItem interface {
 func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options option.DefaultRule) (*DefaultRule, error) {
 	rule := &DefaultRule{
 		abstractDefaultRule{
-			invert:   options.Invert,
-			outbound: options.Outbound,
+			invert:     options.Invert,
+			outbound:   options.Outbound,
+			udpTimeout: time.Duration(options.UDPTimeout),
+			mirror:     options.Mirror,
+			action:     options.RuleAction,
 		},
 	}
 	if len(options.Inbound) > 0 {
@@ -112,6 +123,16 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceASN) > 0 {
+		item := NewASNItem(router, true, options.SourceASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ASN) > 0 {
+		item := NewASNItem(router, false, options.ASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourceIPCIDR) > 0 {
 		item, err := NewIPCIDRItem(true, options.SourceIPCIDR)
 		if err != nil {
@@ -179,6 +200,11 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.AppID) > 0 {
+		item := NewAppIDItem(options.AppID)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.User) > 0 {
 		item := NewUserItem(options.User)
 		rule.items = append(rule.items, item)
@@ -204,6 +230,22 @@ func NewDefaultRule(router adapter.Router, logger log.ContextLogger, options opt
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.TimeRange) > 0 {
+		item, err := NewTimeRangeItem(router, options.TimeRange)
+		if err != nil {
+			return nil, E.Cause(err, "time_range")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.Weekday) > 0 {
+		item, err := NewWeekdayItem(router, options.Weekday)
+		if err != nil {
+			return nil, E.Cause(err, "weekday")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.RuleSet) > 0 {
 		item := NewRuleSetItem(router, options.RuleSet, options.RuleSetIPCIDRMatchSource, false)
 		rule.items = append(rule.items, item)
@@ -221,9 +263,12 @@ type LogicalRule struct {
 func NewLogicalRule(router adapter.Router, logger log.ContextLogger, options option.LogicalRule) (*LogicalRule, error) {
 	r := &LogicalRule{
 		abstractLogicalRule{
-			rules:    make([]adapter.HeadlessRule, len(options.Rules)),
-			invert:   options.Invert,
-			outbound: options.Outbound,
+			rules:      make([]adapter.HeadlessRule, len(options.Rules)),
+			invert:     options.Invert,
+			outbound:   options.Outbound,
+			udpTimeout: time.Duration(options.UDPTimeout),
+			mirror:     options.Mirror,
+			action:     options.RuleAction,
 		},
 	}
 	switch options.Mode {
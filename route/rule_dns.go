@@ -17,7 +17,7 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 		if !options.DefaultOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.DefaultOptions.Server == "" && checkServer {
+		if options.DefaultOptions.Server == "" && checkServer && options.DefaultOptions.Action != C.DNSRuleActionTypeStatic && !isFakeIPDisabled(options.DefaultOptions.FakeIP) {
 			return nil, E.New("missing server field")
 		}
 		return NewDefaultDNSRule(router, logger, options.DefaultOptions)
@@ -25,7 +25,7 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 		if !options.LogicalOptions.IsValid() {
 			return nil, E.New("missing conditions")
 		}
-		if options.LogicalOptions.Server == "" && checkServer {
+		if options.LogicalOptions.Server == "" && checkServer && options.LogicalOptions.Action != C.DNSRuleActionTypeStatic && !isFakeIPDisabled(options.LogicalOptions.FakeIP) {
 			return nil, E.New("missing server field")
 		}
 		return NewLogicalDNSRule(router, logger, options.LogicalOptions)
@@ -34,13 +34,42 @@ func NewDNSRule(router adapter.Router, logger log.ContextLogger, options option.
 	}
 }
 
+func newDNSRuleStaticTransport(logger log.ContextLogger, action string, server string, answer []string, hostsPath []string) (*staticDNSTransport, error) {
+	switch action {
+	case "", C.DNSRuleActionTypeRoute:
+		if len(answer) > 0 || len(hostsPath) > 0 {
+			return nil, E.New("answer and hosts_path are only allowed for the static action")
+		}
+		return nil, nil
+	case C.DNSRuleActionTypeStatic:
+		if server != "" {
+			return nil, E.New("server is not allowed for the static action")
+		}
+		if len(answer) == 0 && len(hostsPath) == 0 {
+			return nil, E.New("missing answer or hosts_path")
+		}
+		return newStaticDNSTransport(logger, answer, hostsPath)
+	default:
+		return nil, E.New("unknown DNS rule action: ", action)
+	}
+}
+
+func isFakeIPDisabled(fakeIP *bool) bool {
+	return fakeIP != nil && !*fakeIP
+}
+
 var _ adapter.DNSRule = (*DefaultDNSRule)(nil)
 
 type DefaultDNSRule struct {
 	abstractDefaultRule
-	disableCache bool
-	rewriteTTL   *uint32
-	clientSubnet *netip.Prefix
+	disableCache    bool
+	rewriteTTL      *uint32
+	clientSubnet    *netip.Prefix
+	disableFakeIP   bool
+	staticTransport *staticDNSTransport
+	responseFilter  *dnsResponseFilter
+	detour          string
+	dnssecValidated bool
 }
 
 func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options option.DefaultDNSRule) (*DefaultDNSRule, error) {
@@ -49,9 +78,11 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 			invert:   options.Invert,
 			outbound: options.Server,
 		},
-		disableCache: options.DisableCache,
-		rewriteTTL:   options.RewriteTTL,
-		clientSubnet: (*netip.Prefix)(options.ClientSubnet),
+		disableCache:  options.DisableCache,
+		rewriteTTL:    options.RewriteTTL,
+		clientSubnet:  (*netip.Prefix)(options.ClientSubnet),
+		disableFakeIP: isFakeIPDisabled(options.FakeIP),
+		detour:        options.Detour,
 	}
 	if len(options.Inbound) > 0 {
 		item := NewInboundRule(options.Inbound)
@@ -121,6 +152,16 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.SourceASN) > 0 {
+		item := NewASNItem(router, true, options.SourceASN)
+		rule.sourceAddressItems = append(rule.sourceAddressItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.ASN) > 0 {
+		item := NewASNItem(router, false, options.ASN)
+		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.SourceIPCIDR) > 0 {
 		item, err := NewIPCIDRItem(true, options.SourceIPCIDR)
 		if err != nil {
@@ -147,6 +188,13 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.destinationIPCIDRItems = append(rule.destinationIPCIDRItems, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if options.DNSSECValidated {
+		item := NewDNSSECValidatedItem()
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+		rule.dnssecValidated = true
+		rule.disableCache = true
+	}
 	if len(options.SourcePort) > 0 {
 		item := NewPortItem(true, options.SourcePort)
 		rule.sourcePortItems = append(rule.sourcePortItems, item)
@@ -188,6 +236,11 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.AppID) > 0 {
+		item := NewAppIDItem(options.AppID)
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.User) > 0 {
 		item := NewUserItem(options.User)
 		rule.items = append(rule.items, item)
@@ -218,11 +271,43 @@ func NewDefaultDNSRule(router adapter.Router, logger log.ContextLogger, options
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if len(options.TimeRange) > 0 {
+		item, err := NewTimeRangeItem(router, options.TimeRange)
+		if err != nil {
+			return nil, E.Cause(err, "time_range")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
+	if len(options.Weekday) > 0 {
+		item, err := NewWeekdayItem(router, options.Weekday)
+		if err != nil {
+			return nil, E.Cause(err, "weekday")
+		}
+		rule.items = append(rule.items, item)
+		rule.allItems = append(rule.allItems, item)
+	}
 	if len(options.RuleSet) > 0 {
 		item := NewRuleSetItem(router, options.RuleSet, options.RuleSetIPCIDRMatchSource, options.RuleSetIPCIDRAcceptEmpty)
 		rule.items = append(rule.items, item)
 		rule.allItems = append(rule.allItems, item)
 	}
+	if options.Detour != "" && options.Server == "" {
+		return nil, E.New("detour requires server")
+	}
+	staticTransport, err := newDNSRuleStaticTransport(logger, options.Action, options.Server, options.Answer, options.HostsPath)
+	if err != nil {
+		return nil, err
+	}
+	rule.staticTransport = staticTransport
+	responseFilter, err := newDNSResponseFilter(options.BlockIPv6, options.BlockIPCIDR, options.RewriteRCode)
+	if err != nil {
+		return nil, err
+	}
+	if responseFilter != nil && !responseFilter.cacheResponse() {
+		rule.disableCache = true
+	}
+	rule.responseFilter = responseFilter
 	return rule, nil
 }
 
@@ -238,8 +323,50 @@ func (r *DefaultDNSRule) ClientSubnet() *netip.Prefix {
 	return r.clientSubnet
 }
 
+func (r *DefaultDNSRule) DisableFakeIP() bool {
+	return r.disableFakeIP
+}
+
+func (r *DefaultDNSRule) StaticTransport() adapter.DNSStaticTransport {
+	if r.staticTransport == nil {
+		return nil
+	}
+	return r.staticTransport
+}
+
+func (r *DefaultDNSRule) ResponseFilter() adapter.DNSResponseFilter {
+	if r.responseFilter == nil {
+		return nil
+	}
+	return r.responseFilter
+}
+
+func (r *DefaultDNSRule) Detour() string {
+	return r.detour
+}
+
+func (r *DefaultDNSRule) Start() error {
+	if r.staticTransport != nil {
+		err := r.staticTransport.Start()
+		if err != nil {
+			return err
+		}
+	}
+	return r.abstractDefaultRule.Start()
+}
+
+func (r *DefaultDNSRule) Close() error {
+	if r.staticTransport != nil {
+		err := r.staticTransport.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return r.abstractDefaultRule.Close()
+}
+
 func (r *DefaultDNSRule) WithAddressLimit() bool {
-	if len(r.destinationIPCIDRItems) > 0 {
+	if len(r.destinationIPCIDRItems) > 0 || r.dnssecValidated {
 		return true
 	}
 	for _, rawRule := range r.items {
@@ -270,9 +397,13 @@ var _ adapter.DNSRule = (*LogicalDNSRule)(nil)
 
 type LogicalDNSRule struct {
 	abstractLogicalRule
-	disableCache bool
-	rewriteTTL   *uint32
-	clientSubnet *netip.Prefix
+	disableCache    bool
+	rewriteTTL      *uint32
+	clientSubnet    *netip.Prefix
+	disableFakeIP   bool
+	staticTransport *staticDNSTransport
+	responseFilter  *dnsResponseFilter
+	detour          string
 }
 
 func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options option.LogicalDNSRule) (*LogicalDNSRule, error) {
@@ -282,9 +413,11 @@ func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options
 			invert:   options.Invert,
 			outbound: options.Server,
 		},
-		disableCache: options.DisableCache,
-		rewriteTTL:   options.RewriteTTL,
-		clientSubnet: (*netip.Prefix)(options.ClientSubnet),
+		disableCache:  options.DisableCache,
+		rewriteTTL:    options.RewriteTTL,
+		clientSubnet:  (*netip.Prefix)(options.ClientSubnet),
+		disableFakeIP: isFakeIPDisabled(options.FakeIP),
+		detour:        options.Detour,
 	}
 	switch options.Mode {
 	case C.LogicalTypeAnd:
@@ -301,6 +434,22 @@ func NewLogicalDNSRule(router adapter.Router, logger log.ContextLogger, options
 		}
 		r.rules[i] = rule
 	}
+	if options.Detour != "" && options.Server == "" {
+		return nil, E.New("detour requires server")
+	}
+	staticTransport, err := newDNSRuleStaticTransport(logger, options.Action, options.Server, options.Answer, options.HostsPath)
+	if err != nil {
+		return nil, err
+	}
+	r.staticTransport = staticTransport
+	responseFilter, err := newDNSResponseFilter(options.BlockIPv6, options.BlockIPCIDR, options.RewriteRCode)
+	if err != nil {
+		return nil, err
+	}
+	if responseFilter != nil && !responseFilter.cacheResponse() {
+		r.disableCache = true
+	}
+	r.responseFilter = responseFilter
 	return r, nil
 }
 
@@ -316,6 +465,48 @@ func (r *LogicalDNSRule) ClientSubnet() *netip.Prefix {
 	return r.clientSubnet
 }
 
+func (r *LogicalDNSRule) DisableFakeIP() bool {
+	return r.disableFakeIP
+}
+
+func (r *LogicalDNSRule) StaticTransport() adapter.DNSStaticTransport {
+	if r.staticTransport == nil {
+		return nil
+	}
+	return r.staticTransport
+}
+
+func (r *LogicalDNSRule) ResponseFilter() adapter.DNSResponseFilter {
+	if r.responseFilter == nil {
+		return nil
+	}
+	return r.responseFilter
+}
+
+func (r *LogicalDNSRule) Detour() string {
+	return r.detour
+}
+
+func (r *LogicalDNSRule) Start() error {
+	if r.staticTransport != nil {
+		err := r.staticTransport.Start()
+		if err != nil {
+			return err
+		}
+	}
+	return r.abstractLogicalRule.Start()
+}
+
+func (r *LogicalDNSRule) Close() error {
+	if r.staticTransport != nil {
+		err := r.staticTransport.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return r.abstractLogicalRule.Close()
+}
+
 func (r *LogicalDNSRule) WithAddressLimit() bool {
 	for _, rawRule := range r.rules {
 		switch rule := rawRule.(type) {
@@ -0,0 +1,43 @@
+package route
+
+import (
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var _ RuleItem = (*AppIDItem)(nil)
+
+type AppIDItem struct {
+	appIDs   []string
+	appIDMap map[string]bool
+}
+
+func NewAppIDItem(appIDList []string) *AppIDItem {
+	rule := &AppIDItem{
+		appIDs:   appIDList,
+		appIDMap: make(map[string]bool),
+	}
+	for _, appID := range appIDList {
+		rule.appIDMap[appID] = true
+	}
+	return rule
+}
+
+func (r *AppIDItem) Match(metadata *adapter.InboundContext) bool {
+	if metadata.ProcessInfo == nil || metadata.ProcessInfo.AppID == "" {
+		return false
+	}
+	return r.appIDMap[metadata.ProcessInfo.AppID]
+}
+
+func (r *AppIDItem) String() string {
+	var description string
+	pLen := len(r.appIDs)
+	if pLen == 1 {
+		description = "app_id=" + r.appIDs[0]
+	} else {
+		description = "app_id=[" + strings.Join(r.appIDs, " ") + "]"
+	}
+	return description
+}
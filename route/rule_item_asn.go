@@ -0,0 +1,68 @@
+package route
+
+import (
+	"net/netip"
+	"strings"
+
+	"github.com/sagernet/sing-box/adapter"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*ASNItem)(nil)
+
+type ASNItem struct {
+	router   adapter.Router
+	isSource bool
+	asns     []uint32
+	asnMap   map[uint32]bool
+}
+
+func NewASNItem(router adapter.Router, isSource bool, asns []uint32) *ASNItem {
+	asnMap := make(map[uint32]bool)
+	for _, asn := range asns {
+		asnMap[asn] = true
+	}
+	return &ASNItem{
+		router:   router,
+		isSource: isSource,
+		asns:     asns,
+		asnMap:   asnMap,
+	}
+}
+
+func (r *ASNItem) Match(metadata *adapter.InboundContext) bool {
+	asnReader := r.router.ASNReader()
+	if asnReader == nil {
+		return false
+	}
+	var destination netip.Addr
+	if r.isSource {
+		destination = metadata.Source.Addr
+	} else {
+		destination = metadata.Destination.Addr
+	}
+	if destination.IsValid() {
+		return r.asnMap[asnReader.Lookup(destination)]
+	}
+	for _, destinationAddress := range metadata.DestinationAddresses {
+		if r.asnMap[asnReader.Lookup(destinationAddress)] {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *ASNItem) String() string {
+	var description string
+	if r.isSource {
+		description = "source_asn="
+	} else {
+		description = "asn="
+	}
+	if len(r.asns) == 1 {
+		description += F.ToString(r.asns[0])
+	} else {
+		description += "[" + strings.Join(F.MapToString(r.asns), " ") + "]"
+	}
+	return description
+}
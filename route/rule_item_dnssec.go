@@ -0,0 +1,25 @@
+package route
+
+import (
+	"github.com/sagernet/sing-box/adapter"
+)
+
+var _ RuleItem = (*DNSSECValidatedItem)(nil)
+
+type DNSSECValidatedItem struct{}
+
+func NewDNSSECValidatedItem() *DNSSECValidatedItem {
+	return &DNSSECValidatedItem{}
+}
+
+func (r *DNSSECValidatedItem) Match(metadata *adapter.InboundContext) bool {
+	// matched after the response like address filter items
+	if metadata.IgnoreDestinationIPCIDRMatch {
+		return true
+	}
+	return metadata.DNSSECValidated
+}
+
+func (r *DNSSECValidatedItem) String() string {
+	return "dnssec_validated=true"
+}
@@ -4,16 +4,26 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/domaintrie"
+	"github.com/sagernet/sing/common/atomic"
 	"github.com/sagernet/sing/common/domain"
 )
 
 var _ RuleItem = (*DomainItem)(nil)
 
 type DomainItem struct {
-	matcher     *domain.Matcher
+	matcher     atomic.Pointer[domainMatcher]
 	description string
 }
 
+// domainMatcher is either the matcher of the item or its ID in the trie
+// shared by all rules, which replaces it once built.
+type domainMatcher struct {
+	matcher *domain.Matcher
+	trie    *domaintrie.Trie
+	trieID  uint32
+}
+
 func NewDomainItem(domains []string, domainSuffixes []string) *DomainItem {
 	var description string
 	if dLen := len(domains); dLen > 0 {
@@ -37,17 +47,17 @@ func NewDomainItem(domains []string, domainSuffixes []string) *DomainItem {
 			description += "domain_suffix=[" + strings.Join(domainSuffixes, " ") + "]"
 		}
 	}
-	return &DomainItem{
-		domain.NewMatcher(domains, domainSuffixes),
-		description,
-	}
+	return newDomainItem(domain.NewMatcher(domains, domainSuffixes), description)
 }
 
 func NewRawDomainItem(matcher *domain.Matcher) *DomainItem {
-	return &DomainItem{
-		matcher,
-		"domain/domain_suffix=<binary>",
-	}
+	return newDomainItem(matcher, "domain/domain_suffix=<binary>")
+}
+
+func newDomainItem(matcher *domain.Matcher, description string) *DomainItem {
+	item := &DomainItem{description: description}
+	item.matcher.Store(&domainMatcher{matcher: matcher})
+	return item
 }
 
 func (r *DomainItem) Match(metadata *adapter.InboundContext) bool {
@@ -60,7 +70,33 @@ func (r *DomainItem) Match(metadata *adapter.InboundContext) bool {
 	if domainHost == "" {
 		return false
 	}
-	return r.matcher.Match(strings.ToLower(domainHost))
+	domainHost = strings.ToLower(domainHost)
+	matcher := r.matcher.Load()
+	if matcher.trie != nil {
+		return matcher.trie.Match(&metadata.DomainTrieCache, domainHost, matcher.trieID)
+	}
+	return matcher.matcher.Match(domainHost)
+}
+
+// addToTrie adds the domains of the item to builder, from its own matcher or
+// from the keys of the trie it uses, cached by trieKeys.
+func (r *DomainItem) addToTrie(builder *domaintrie.Builder, id uint32, trieKeys map[*domaintrie.Trie]map[uint32][]string) {
+	matcher := r.matcher.Load()
+	if matcher.matcher != nil {
+		domains, domainSuffixes := matcher.matcher.Dump()
+		builder.Add(id, domains, domainSuffixes)
+		return
+	}
+	keys, loaded := trieKeys[matcher.trie]
+	if !loaded {
+		keys = matcher.trie.Keys()
+		trieKeys[matcher.trie] = keys
+	}
+	builder.AddKeys(id, keys[matcher.trieID])
+}
+
+func (r *DomainItem) useTrie(trie *domaintrie.Trie, id uint32) {
+	r.matcher.Store(&domainMatcher{trie: trie, trieID: id})
 }
 
 func (r *DomainItem) String() string {
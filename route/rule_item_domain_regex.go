@@ -25,6 +25,14 @@ func NewDomainRegexItem(expressions []string) (*DomainRegexItem, error) {
 		}
 		matchers = append(matchers, matcher)
 	}
+	if len(matchers) > 1 {
+		// match all expressions in a single pass
+		combinedExpression := "(?:" + strings.Join(expressions, ")|(?:") + ")"
+		combinedMatcher, err := regexp.Compile(combinedExpression)
+		if err == nil {
+			matchers = []*regexp.Regexp{combinedMatcher}
+		}
+	}
 	description := "domain_regex="
 	eLen := len(expressions)
 	if eLen == 1 {
@@ -5,16 +5,17 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing/common/atomic"
 	E "github.com/sagernet/sing/common/exceptions"
 )
 
 var _ RuleItem = (*GeositeItem)(nil)
 
 type GeositeItem struct {
-	router   adapter.Router
-	logger   log.ContextLogger
-	codes    []string
-	matchers []adapter.Rule
+	router  adapter.Router
+	logger  log.ContextLogger
+	codes   []string
+	matcher atomic.TypedValue[adapter.Rule]
 }
 
 func NewGeositeItem(router adapter.Router, logger log.ContextLogger, codes []string) *GeositeItem {
@@ -26,25 +27,20 @@ func NewGeositeItem(router adapter.Router, logger log.ContextLogger, codes []str
 }
 
 func (r *GeositeItem) Update() error {
-	matchers := make([]adapter.Rule, 0, len(r.codes))
-	for _, code := range r.codes {
-		matcher, err := r.router.LoadGeosite(code)
-		if err != nil {
-			return E.Cause(err, "read geosite")
-		}
-		matchers = append(matchers, matcher)
+	matcher, err := r.router.LoadGeosite(r.codes...)
+	if err != nil {
+		return E.Cause(err, "read geosite")
 	}
-	r.matchers = matchers
+	r.matcher.Store(matcher)
 	return nil
 }
 
 func (r *GeositeItem) Match(metadata *adapter.InboundContext) bool {
-	for _, matcher := range r.matchers {
-		if matcher.Match(metadata) {
-			return true
-		}
+	matcher := r.matcher.Load()
+	if matcher == nil {
+		return false
 	}
-	return false
+	return matcher.Match(metadata)
 }
 
 func (r *GeositeItem) String() string {
@@ -5,6 +5,7 @@ import (
 	"strings"
 
 	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
 )
 
 var _ RuleItem = (*ProcessItem)(nil)
@@ -20,16 +21,25 @@ func NewProcessItem(processNameList []string) *ProcessItem {
 		processMap: make(map[string]bool),
 	}
 	for _, processName := range processNameList {
-		rule.processMap[processName] = true
+		rule.processMap[processKey(processName)] = true
 	}
 	return rule
 }
 
+// processKey normalizes a process name or path for matching, as file names
+// are case-insensitive on Windows.
+func processKey(name string) string {
+	if C.IsWindows {
+		return strings.ToLower(filepath.Clean(name))
+	}
+	return name
+}
+
 func (r *ProcessItem) Match(metadata *adapter.InboundContext) bool {
 	if metadata.ProcessInfo == nil || metadata.ProcessInfo.ProcessPath == "" {
 		return false
 	}
-	return r.processMap[filepath.Base(metadata.ProcessInfo.ProcessPath)]
+	return r.processMap[processKey(filepath.Base(metadata.ProcessInfo.ProcessPath))]
 }
 
 func (r *ProcessItem) String() string {
@@ -19,7 +19,7 @@ func NewProcessPathItem(processNameList []string) *ProcessPathItem {
 		processMap: make(map[string]bool),
 	}
 	for _, processName := range processNameList {
-		rule.processMap[processName] = true
+		rule.processMap[processKey(processName)] = true
 	}
 	return rule
 }
@@ -28,7 +28,7 @@ func (r *ProcessPathItem) Match(metadata *adapter.InboundContext) bool {
 	if metadata.ProcessInfo == nil || metadata.ProcessInfo.ProcessPath == "" {
 		return false
 	}
-	return r.processMap[metadata.ProcessInfo.ProcessPath]
+	return r.processMap[processKey(metadata.ProcessInfo.ProcessPath)]
 }
 
 func (r *ProcessPathItem) String() string {
@@ -0,0 +1,134 @@
+package route
+
+import (
+	"strings"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+)
+
+var _ RuleItem = (*TimeRangeItem)(nil)
+
+// TimeRangeItem matches the local time of day against ranges in HH:MM-HH:MM
+// format, where the start is inclusive and the end is exclusive. A range
+// ending before it starts spans midnight.
+type TimeRangeItem struct {
+	router     adapter.Router
+	timeRanges []string
+	rangeList  []timeRange
+}
+
+type timeRange struct {
+	start time.Duration
+	end   time.Duration
+}
+
+func NewTimeRangeItem(router adapter.Router, timeRanges []string) (*TimeRangeItem, error) {
+	rangeList := make([]timeRange, 0, len(timeRanges))
+	for _, rangeString := range timeRanges {
+		startString, endString, loaded := strings.Cut(rangeString, "-")
+		if !loaded {
+			return nil, E.New("bad time range: ", rangeString)
+		}
+		start, err := parseTimeOfDay(startString)
+		if err != nil {
+			return nil, E.Cause(err, "bad time range: ", rangeString)
+		}
+		end, err := parseTimeOfDay(endString)
+		if err != nil {
+			return nil, E.Cause(err, "bad time range: ", rangeString)
+		}
+		rangeList = append(rangeList, timeRange{start, end})
+	}
+	return &TimeRangeItem{
+		router:     router,
+		timeRanges: timeRanges,
+		rangeList:  rangeList,
+	}, nil
+}
+
+func parseTimeOfDay(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if value == "24:00" {
+		return 24 * time.Hour, nil
+	}
+	clock, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(clock.Hour())*time.Hour + time.Duration(clock.Minute())*time.Minute, nil
+}
+
+func (r *TimeRangeItem) Match(metadata *adapter.InboundContext) bool {
+	now := r.router.TimeFunc()().Local()
+	hour, minute, second := now.Clock()
+	timeOfDay := time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute + time.Duration(second)*time.Second
+	for _, rangeItem := range r.rangeList {
+		if rangeItem.start <= rangeItem.end {
+			if timeOfDay >= rangeItem.start && timeOfDay < rangeItem.end {
+				return true
+			}
+		} else if timeOfDay >= rangeItem.start || timeOfDay < rangeItem.end {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *TimeRangeItem) String() string {
+	if len(r.timeRanges) == 1 {
+		return F.ToString("time_range=", r.timeRanges[0])
+	}
+	return F.ToString("time_range=[", strings.Join(r.timeRanges, " "), "]")
+}
+
+var _ RuleItem = (*WeekdayItem)(nil)
+
+type WeekdayItem struct {
+	router      adapter.Router
+	weekdays    []string
+	weekdayMask uint8
+}
+
+func NewWeekdayItem(router adapter.Router, weekdays []string) (*WeekdayItem, error) {
+	var weekdayMask uint8
+	for _, weekdayString := range weekdays {
+		weekday, loaded := parseWeekday(weekdayString)
+		if !loaded {
+			return nil, E.New("bad weekday: ", weekdayString)
+		}
+		weekdayMask |= 1 << weekday
+	}
+	return &WeekdayItem{
+		router:      router,
+		weekdays:    weekdays,
+		weekdayMask: weekdayMask,
+	}, nil
+}
+
+func parseWeekday(value string) (time.Weekday, bool) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if len(value) < 3 {
+		return 0, false
+	}
+	for weekday := time.Sunday; weekday <= time.Saturday; weekday++ {
+		name := strings.ToLower(weekday.String())
+		if value == name || value == name[:3] {
+			return weekday, true
+		}
+	}
+	return 0, false
+}
+
+func (r *WeekdayItem) Match(metadata *adapter.InboundContext) bool {
+	return r.weekdayMask&(1<<r.router.TimeFunc()().Local().Weekday()) != 0
+}
+
+func (r *WeekdayItem) String() string {
+	if len(r.weekdays) == 1 {
+		return F.ToString("weekday=", r.weekdays[0])
+	}
+	return F.ToString("weekday=[", strings.Join(r.weekdays, " "), "]")
+}
@@ -0,0 +1,58 @@
+package route
+
+import (
+	"testing"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testTimeRouter struct {
+	adapter.Router
+	now time.Time
+}
+
+func (r *testTimeRouter) TimeFunc() func() time.Time {
+	return func() time.Time {
+		return r.now
+	}
+}
+
+func TestTimeRuleItems(t *testing.T) {
+	t.Parallel()
+	router := &testTimeRouter{}
+	workHours, err := NewTimeRangeItem(router, []string{"09:00-18:00"})
+	require.NoError(t, err)
+	night, err := NewTimeRangeItem(router, []string{"22:30-06:00", "12:00-13:00"})
+	require.NoError(t, err)
+	weekdays, err := NewWeekdayItem(router, []string{"Mon", "tuesday", "wed", "THU", "fri"})
+	require.NoError(t, err)
+	for _, testCase := range []struct {
+		now       string
+		workHours bool
+		night     bool
+		weekday   bool
+	}{
+		{"2024-06-03 09:00", true, false, true},
+		{"2024-06-03 17:59", true, false, true},
+		{"2024-06-03 18:00", false, false, true},
+		{"2024-06-03 12:30", true, true, true},
+		{"2024-06-08 23:00", false, true, false},
+		{"2024-06-09 05:59", false, true, false},
+		{"2024-06-09 06:00", false, false, false},
+	} {
+		router.now, err = time.ParseInLocation("2006-01-02 15:04", testCase.now, time.Local)
+		require.NoError(t, err)
+		require.Equal(t, testCase.workHours, workHours.Match(nil), testCase.now)
+		require.Equal(t, testCase.night, night.Match(nil), testCase.now)
+		require.Equal(t, testCase.weekday, weekdays.Match(nil), testCase.now)
+	}
+	for _, timeRange := range []string{"09:00", "9-18", "09:00-25:00"} {
+		_, err = NewTimeRangeItem(router, []string{timeRange})
+		require.Error(t, err, timeRange)
+	}
+	_, err = NewWeekdayItem(router, []string{"mo"})
+	require.Error(t, err)
+}
@@ -18,7 +18,7 @@ type WIFIBSSIDItem struct {
 func NewWIFIBSSIDItem(router adapter.Router, bssidList []string) *WIFIBSSIDItem {
 	bssidMap := make(map[string]bool)
 	for _, bssid := range bssidList {
-		bssidMap[bssid] = true
+		bssidMap[strings.ToLower(bssid)] = true
 	}
 	return &WIFIBSSIDItem{
 		bssidList,
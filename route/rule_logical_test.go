@@ -0,0 +1,67 @@
+package route
+
+import (
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common/json"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedLogicalRule(t *testing.T) {
+	t.Parallel()
+	var options option.Rule
+	err := json.Unmarshal([]byte(`{
+  "type": "logical",
+  "mode": "or",
+  "rules": [
+    {
+      "type": "logical",
+      "mode": "and",
+      "rules": [
+        { "domain_suffix": "netflix.com" },
+        { "source_ip_cidr": "192.168.1.0/24", "invert": true }
+      ]
+    },
+    {
+      "type": "logical",
+      "mode": "and",
+      "invert": true,
+      "rules": [
+        { "port": [80, 443] },
+        { "network": "udp", "invert": true }
+      ]
+    }
+  ],
+  "outbound": "direct"
+}`), &options)
+	require.NoError(t, err)
+	rule, err := NewRule(nil, log.NewNOPFactory().Logger(), options, true)
+	require.NoError(t, err)
+	require.Equal(t, "(domain_suffix=netflix.com && !(source_ip_cidr=192.168.1.0/24)) || !(port=[80 443] && !(network=udp))", rule.String())
+	for _, testCase := range []struct {
+		source  string
+		network string
+		domain  string
+		port    uint16
+		match   bool
+	}{
+		{"10.0.0.1", "tcp", "www.netflix.com", 443, true},
+		{"192.168.1.2", "tcp", "www.netflix.com", 443, false},
+		{"192.168.1.2", "udp", "www.netflix.com", 443, true},
+		{"192.168.1.2", "tcp", "example.com", 8080, true},
+		{"10.0.0.1", "tcp", "example.com", 80, false},
+	} {
+		metadata := &adapter.InboundContext{
+			Network:     testCase.network,
+			Source:      M.ParseSocksaddrHostPort(testCase.source, 10000),
+			Destination: M.Socksaddr{Fqdn: testCase.domain, Port: testCase.port},
+			Domain:      testCase.domain,
+		}
+		require.Equal(t, testCase.match, rule.Match(metadata), testCase)
+	}
+}
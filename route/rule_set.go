@@ -2,15 +2,23 @@ package route
 
 import (
 	"context"
+	"io"
 	"net"
 	"net/http"
+	"runtime/debug"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/sagernet/sing-box/adapter"
+	"github.com/sagernet/sing-box/common/convert"
+	"github.com/sagernet/sing-box/common/srs"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
@@ -24,11 +32,51 @@ func NewRuleSet(ctx context.Context, router adapter.Router, logger logger.Contex
 		return NewLocalRuleSet(router, logger, options)
 	case C.RuleSetTypeRemote:
 		return NewRemoteRuleSet(ctx, router, logger, options), nil
+	case C.RuleSetTypeComposite:
+		return NewCompositeRuleSet(router, options)
 	default:
 		return nil, E.New("unknown rule-set type: ", options.Type)
 	}
 }
 
+func parseClashRuleSet(logger logger.Logger, tag string, content []byte, behavior string) ([]option.HeadlessRule, error) {
+	rules, warnings, err := convert.ParseClashRuleSet(content, behavior)
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		logger.Warn("rule-set ", tag, ": ", warning)
+	}
+	if len(rules) == 0 {
+		return nil, E.New("empty clash rule-set")
+	}
+	return rules, nil
+}
+
+// parseFilterRuleSet parses an AdGuard filter list or a hosts file.
+func parseFilterRuleSet(logger logger.Logger, tag string, content []byte, format string) ([]option.HeadlessRule, error) {
+	var (
+		rules    []option.HeadlessRule
+		warnings convert.Warnings
+		err      error
+	)
+	if format == C.RuleSetFormatAdGuard {
+		rules, warnings, err = convert.ParseAdGuardRuleSet(content)
+	} else {
+		rules, warnings, err = convert.ParseHostsRuleSet(content)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for _, warning := range warnings {
+		logger.Warn("rule-set ", tag, ": ", warning)
+	}
+	if len(rules) == 0 {
+		return nil, E.New("empty ", format, " rule-set")
+	}
+	return rules, nil
+}
+
 func extractIPSetFromRule(rawRule adapter.HeadlessRule) []*netipx.IPSet {
 	switch rule := rawRule.(type) {
 	case *DefaultHeadlessRule:
@@ -86,3 +134,175 @@ func (c *RuleSetStartContext) Close() {
 		client.CloseIdleConnections()
 	}
 }
+
+// ruleSetSnapshot is replaced as a whole on reload, so Match never observes
+// rules and metadata from different versions and needs no lock.
+type ruleSetSnapshot struct {
+	rules    []adapter.HeadlessRule
+	metadata adapter.RuleSetMetadata
+	decoder  *ruleSetDecoder
+}
+
+func (s *ruleSetSnapshot) loadRules() []adapter.HeadlessRule {
+	if s.decoder != nil {
+		return s.decoder.load()
+	}
+	return s.rules
+}
+
+type ruleSetState struct {
+	snapshot atomic.Pointer[ruleSetSnapshot]
+}
+
+func (s *ruleSetState) loadSnapshot() *ruleSetSnapshot {
+	snapshot := s.snapshot.Load()
+	if snapshot == nil {
+		return &ruleSetSnapshot{}
+	}
+	return snapshot
+}
+
+func (s *ruleSetState) storeSnapshot(snapshot *ruleSetSnapshot) {
+	oldSnapshot := s.snapshot.Swap(snapshot)
+	if oldSnapshot != nil && oldSnapshot.decoder != nil {
+		oldSnapshot.decoder.Close()
+	}
+}
+
+func (s *ruleSetState) storeRules(rules []adapter.HeadlessRule, metadata adapter.RuleSetMetadata) {
+	s.storeSnapshot(&ruleSetSnapshot{rules: rules, metadata: metadata})
+}
+
+func (s *ruleSetState) storeDecoder(decoder *ruleSetDecoder, metadata adapter.RuleSetMetadata) {
+	s.storeSnapshot(&ruleSetSnapshot{metadata: metadata, decoder: decoder})
+}
+
+func (s *ruleSetState) releaseRules() {
+	s.storeSnapshot(&ruleSetSnapshot{metadata: s.loadSnapshot().metadata})
+}
+
+func (s *ruleSetState) String() string {
+	return strings.Join(F.MapToString(s.loadSnapshot().loadRules()), " ")
+}
+
+func (s *ruleSetState) Metadata() adapter.RuleSetMetadata {
+	return s.loadSnapshot().metadata
+}
+
+func (s *ruleSetState) ExtractIPSet() []*netipx.IPSet {
+	return common.FlatMap(s.loadSnapshot().loadRules(), extractIPSetFromRule)
+}
+
+func (s *ruleSetState) Match(metadata *adapter.InboundContext) bool {
+	for _, rule := range s.loadSnapshot().loadRules() {
+		if rule.Match(metadata) {
+			return true
+		}
+	}
+	return false
+}
+
+// ruleSetDecoder builds the rules of a binary rule-set when it is first
+// matched, so that a rule-set which is never consulted costs only its
+// compressed content, which is memory-mapped for local files.
+type ruleSetDecoder struct {
+	router  adapter.Router
+	logger  logger.Logger
+	tag     string
+	content io.Reader
+	closer  io.Closer
+	access  sync.Mutex
+	loaded  atomic.Pointer[[]adapter.HeadlessRule]
+}
+
+// newRuleSetDecoder reads the rule-set once to validate it and collect its
+// metadata; the decoded options are dropped until the rules are needed.
+func newRuleSetDecoder(router adapter.Router, logger logger.Logger, tag string, content io.Reader, closer io.Closer) (*ruleSetDecoder, adapter.RuleSetMetadata, error) {
+	var metadata adapter.RuleSetMetadata
+	plainRuleSet, err := readMappedRuleSet(content)
+	if err != nil {
+		return nil, metadata, err
+	}
+	metadata.ContainsProcessRule = hasHeadlessRule(plainRuleSet.Rules, isProcessHeadlessRule)
+	metadata.ContainsWIFIRule = hasHeadlessRule(plainRuleSet.Rules, isWIFIHeadlessRule)
+	metadata.ContainsIPCIDRRule = hasHeadlessRule(plainRuleSet.Rules, isIPCIDRHeadlessRule)
+	metadata.Format = C.RuleSetFormatBinary
+	metadata.LastUpdated = time.Now()
+	metadata.RuleNum = len(plainRuleSet.Rules)
+	return &ruleSetDecoder{
+		router:  router,
+		logger:  logger,
+		tag:     tag,
+		content: content,
+		closer:  closer,
+	}, metadata, nil
+}
+
+func (d *ruleSetDecoder) load() []adapter.HeadlessRule {
+	if rules := d.loaded.Load(); rules != nil {
+		return *rules
+	}
+	d.access.Lock()
+	defer d.access.Unlock()
+	if rules := d.loaded.Load(); rules != nil {
+		return *rules
+	}
+	rules, err := d.decode()
+	if err != nil {
+		d.logger.Error(E.Cause(err, "decode rule-set ", d.tag))
+	}
+	d.loaded.Store(&rules)
+	d.release()
+	return rules
+}
+
+func (d *ruleSetDecoder) decode() ([]adapter.HeadlessRule, error) {
+	if d.content == nil {
+		return nil, E.New("rule-set closed")
+	}
+	if seeker, isSeeker := d.content.(io.Seeker); isSeeker {
+		_, err := seeker.Seek(0, io.SeekStart)
+		if err != nil {
+			return nil, err
+		}
+	}
+	plainRuleSet, err := readMappedRuleSet(d.content)
+	if err != nil {
+		return nil, err
+	}
+	rules := make([]adapter.HeadlessRule, len(plainRuleSet.Rules))
+	for i, ruleOptions := range plainRuleSet.Rules {
+		rules[i], err = NewHeadlessRule(d.router, ruleOptions)
+		if err != nil {
+			return nil, E.Cause(err, "parse rule_set.rules.[", i, "]")
+		}
+	}
+	return rules, nil
+}
+
+// readMappedRuleSet turns a fault on the mapped file, such as after it was
+// truncated by a concurrent rewrite, into an error.
+func readMappedRuleSet(content io.Reader) (plainRuleSet option.PlainRuleSet, err error) {
+	defer debug.SetPanicOnFault(debug.SetPanicOnFault(true))
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = E.New("read rule-set: ", recovered)
+		}
+	}()
+	return srs.Read(content, false)
+}
+
+func (d *ruleSetDecoder) release() {
+	d.content = nil
+	if d.closer != nil {
+		d.closer.Close()
+		d.closer = nil
+	}
+}
+
+func (d *ruleSetDecoder) Close() error {
+	d.access.Lock()
+	defer d.access.Unlock()
+	d.release()
+	return nil
+}
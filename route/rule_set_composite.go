@@ -0,0 +1,320 @@
+package route
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/option"
+	"github.com/sagernet/sing/common"
+	"github.com/sagernet/sing/common/atomic"
+	E "github.com/sagernet/sing/common/exceptions"
+	F "github.com/sagernet/sing/common/format"
+	"github.com/sagernet/sing/common/x/list"
+
+	"go4.org/netipx"
+)
+
+var _ adapter.RuleSet = (*CompositeRuleSet)(nil)
+
+// CompositeRuleSet matches by combining the results of other rule-sets,
+// which keep their own sources and updates. In exclude mode, the first
+// rule-set is matched without any of the others.
+type CompositeRuleSet struct {
+	router         adapter.Router
+	tag            string
+	mode           string
+	tags           []string
+	resolveOnce    sync.Once
+	resolveErr     error
+	ruleSets       []adapter.RuleSet
+	memberElements []*list.Element[adapter.RuleSetUpdateCallback]
+	refs           atomic.Int32
+	callbackAccess sync.Mutex
+	callbacks      list.List[adapter.RuleSetUpdateCallback]
+}
+
+func NewCompositeRuleSet(router adapter.Router, options option.RuleSet) (*CompositeRuleSet, error) {
+	mode := options.CompositeOptions.Mode
+	switch mode {
+	case "":
+		mode = C.RuleSetCompositeModeUnion
+	case C.RuleSetCompositeModeUnion, C.RuleSetCompositeModeIntersect, C.RuleSetCompositeModeExclude:
+	default:
+		return nil, E.New("unknown composite mode: ", mode)
+	}
+	if len(options.CompositeOptions.RuleSet) == 0 {
+		return nil, E.New("missing rule_set")
+	}
+	if common.Contains(options.CompositeOptions.RuleSet, options.Tag) {
+		return nil, E.New("rule-set references itself")
+	}
+	return &CompositeRuleSet{
+		router: router,
+		tag:    options.Tag,
+		mode:   mode,
+		tags:   options.CompositeOptions.RuleSet,
+	}, nil
+}
+
+func (s *CompositeRuleSet) Name() string {
+	return s.tag
+}
+
+func (s *CompositeRuleSet) Type() string {
+	return C.RuleSetTypeComposite
+}
+
+// resolve looks up the member rule-sets once. Rules may reference the
+// composite rule-set before it is started, so every entry point resolves.
+func (s *CompositeRuleSet) resolve() error {
+	s.resolveOnce.Do(func() {
+		err := s.checkLoop(s.tag, []string{s.tag})
+		if err != nil {
+			s.resolveErr = err
+			return
+		}
+		ruleSets := make([]adapter.RuleSet, 0, len(s.tags))
+		for _, tag := range s.tags {
+			ruleSet, loaded := s.router.RuleSet(tag)
+			if !loaded {
+				s.resolveErr = E.New("rule-set not found: ", tag)
+				return
+			}
+			ruleSets = append(ruleSets, ruleSet)
+		}
+		s.ruleSets = ruleSets
+	})
+	return s.resolveErr
+}
+
+func (s *CompositeRuleSet) checkLoop(tag string, path []string) error {
+	ruleSet, loaded := s.router.RuleSet(tag)
+	if !loaded {
+		return nil
+	}
+	composite, isComposite := ruleSet.(*CompositeRuleSet)
+	if !isComposite {
+		return nil
+	}
+	for _, member := range composite.tags {
+		if common.Contains(path, member) {
+			return E.New("loop detected: ", strings.Join(append(path, member), " -> "))
+		}
+		err := s.checkLoop(member, append(path, member))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *CompositeRuleSet) StartContext(ctx context.Context, startContext adapter.RuleSetStartContext) error {
+	err := s.resolve()
+	if err != nil {
+		return err
+	}
+	for _, ruleSet := range s.ruleSets {
+		s.memberElements = append(s.memberElements, ruleSet.RegisterCallback(func(it adapter.RuleSet) {
+			s.notifyCallbacks()
+		}))
+	}
+	return nil
+}
+
+func (s *CompositeRuleSet) PostStart() error {
+	return nil
+}
+
+func (s *CompositeRuleSet) Metadata() adapter.RuleSetMetadata {
+	metadata := adapter.RuleSetMetadata{
+		Format: s.mode,
+	}
+	if s.resolve() != nil {
+		return metadata
+	}
+	for _, ruleSet := range s.ruleSets {
+		memberMetadata := ruleSet.Metadata()
+		metadata.ContainsProcessRule = metadata.ContainsProcessRule || memberMetadata.ContainsProcessRule
+		metadata.ContainsWIFIRule = metadata.ContainsWIFIRule || memberMetadata.ContainsWIFIRule
+		metadata.ContainsIPCIDRRule = metadata.ContainsIPCIDRRule || memberMetadata.ContainsIPCIDRRule
+		metadata.RuleNum += memberMetadata.RuleNum
+		if memberMetadata.LastUpdated.After(metadata.LastUpdated) {
+			metadata.LastUpdated = memberMetadata.LastUpdated
+		}
+	}
+	if metadata.LastUpdated.IsZero() {
+		metadata.LastUpdated = time.Now()
+	}
+	return metadata
+}
+
+// ExtractIPSet combines the destination IP CIDR rules of the members with
+// the same operation used for matching.
+func (s *CompositeRuleSet) ExtractIPSet() []*netipx.IPSet {
+	if s.resolve() != nil {
+		return nil
+	}
+	if s.mode == C.RuleSetCompositeModeUnion {
+		return common.FlatMap(s.ruleSets, adapter.RuleSet.ExtractIPSet)
+	}
+	var builder netipx.IPSetBuilder
+	for _, ipSet := range s.ruleSets[0].ExtractIPSet() {
+		builder.AddSet(ipSet)
+	}
+	for _, ruleSet := range s.ruleSets[1:] {
+		var memberBuilder netipx.IPSetBuilder
+		for _, ipSet := range ruleSet.ExtractIPSet() {
+			memberBuilder.AddSet(ipSet)
+		}
+		memberSet, err := memberBuilder.IPSet()
+		if err != nil {
+			return nil
+		}
+		if s.mode == C.RuleSetCompositeModeIntersect {
+			builder.Intersect(memberSet)
+		} else {
+			builder.RemoveSet(memberSet)
+		}
+	}
+	ipSet, err := builder.IPSet()
+	if err != nil || len(ipSet.Prefixes()) == 0 {
+		return nil
+	}
+	return []*netipx.IPSet{ipSet}
+}
+
+func (s *CompositeRuleSet) IncRef() {
+	s.refs.Add(1)
+	if s.resolve() == nil {
+		for _, ruleSet := range s.ruleSets {
+			ruleSet.IncRef()
+		}
+	}
+}
+
+func (s *CompositeRuleSet) DecRef() {
+	if s.refs.Add(-1) < 0 {
+		panic("rule-set: negative refs")
+	}
+	if s.resolve() == nil {
+		for _, ruleSet := range s.ruleSets {
+			ruleSet.DecRef()
+		}
+	}
+}
+
+func (s *CompositeRuleSet) Cleanup() {
+}
+
+func (s *CompositeRuleSet) RegisterCallback(callback adapter.RuleSetUpdateCallback) *list.Element[adapter.RuleSetUpdateCallback] {
+	s.callbackAccess.Lock()
+	defer s.callbackAccess.Unlock()
+	return s.callbacks.PushBack(callback)
+}
+
+func (s *CompositeRuleSet) UnregisterCallback(element *list.Element[adapter.RuleSetUpdateCallback]) {
+	s.callbackAccess.Lock()
+	defer s.callbackAccess.Unlock()
+	s.callbacks.Remove(element)
+}
+
+func (s *CompositeRuleSet) notifyCallbacks() {
+	s.callbackAccess.Lock()
+	callbacks := s.callbacks.Array()
+	s.callbackAccess.Unlock()
+	for _, callback := range callbacks {
+		callback(s)
+	}
+}
+
+// Update updates all members, which are shared with other references.
+func (s *CompositeRuleSet) Update(ctx context.Context) error {
+	err := s.resolve()
+	if err != nil {
+		return err
+	}
+	var errors []error
+	for _, ruleSet := range s.ruleSets {
+		err = ruleSet.Update(ctx)
+		if err != nil {
+			errors = append(errors, E.Cause(err, "update rule-set ", ruleSet.Name()))
+		}
+	}
+	return E.Errors(errors...)
+}
+
+// Match evaluates every member from the rule cache state it was called
+// with, as items matched by one member must not count for the others, and
+// leaves the state of the members deciding the result.
+func (s *CompositeRuleSet) Match(metadata *adapter.InboundContext) bool {
+	if s.resolve() != nil {
+		return false
+	}
+	initial := loadRuleMatchState(metadata)
+	match := func(ruleSet adapter.RuleSet) bool {
+		initial.store(metadata)
+		return ruleSet.Match(metadata)
+	}
+	switch s.mode {
+	case C.RuleSetCompositeModeIntersect:
+		if common.All(s.ruleSets, match) {
+			return true
+		}
+	case C.RuleSetCompositeModeExclude:
+		if match(s.ruleSets[0]) {
+			matched := loadRuleMatchState(metadata)
+			if !common.Any(s.ruleSets[1:], match) {
+				matched.store(metadata)
+				return true
+			}
+		}
+	default:
+		if common.Any(s.ruleSets, match) {
+			return true
+		}
+	}
+	initial.store(metadata)
+	return false
+}
+
+type ruleMatchState struct {
+	sourceAddress      bool
+	sourcePort         bool
+	destinationAddress bool
+	destinationPort    bool
+	didMatch           bool
+}
+
+func loadRuleMatchState(metadata *adapter.InboundContext) ruleMatchState {
+	return ruleMatchState{
+		sourceAddress:      metadata.SourceAddressMatch,
+		sourcePort:         metadata.SourcePortMatch,
+		destinationAddress: metadata.DestinationAddressMatch,
+		destinationPort:    metadata.DestinationPortMatch,
+		didMatch:           metadata.DidMatch,
+	}
+}
+
+func (s ruleMatchState) store(metadata *adapter.InboundContext) {
+	metadata.SourceAddressMatch = s.sourceAddress
+	metadata.SourcePortMatch = s.sourcePort
+	metadata.DestinationAddressMatch = s.destinationAddress
+	metadata.DestinationPortMatch = s.destinationPort
+	metadata.DidMatch = s.didMatch
+}
+
+func (s *CompositeRuleSet) String() string {
+	return F.ToString(s.mode, "(", strings.Join(s.tags, " "), ")")
+}
+
+func (s *CompositeRuleSet) Close() error {
+	for i, element := range s.memberElements {
+		s.ruleSets[i].UnregisterCallback(element)
+	}
+	s.memberElements = nil
+	return nil
+}
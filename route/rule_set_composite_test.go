@@ -0,0 +1,94 @@
+package route
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/adapter"
+	C "github.com/sagernet/sing-box/constant"
+	"github.com/sagernet/sing-box/log"
+	"github.com/sagernet/sing-box/option"
+	M "github.com/sagernet/sing/common/metadata"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testRuleSetRouter struct {
+	adapter.Router
+	ruleSets map[string]adapter.RuleSet
+}
+
+func (r *testRuleSetRouter) RuleSet(tag string) (adapter.RuleSet, bool) {
+	ruleSet, loaded := r.ruleSets[tag]
+	return ruleSet, loaded
+}
+
+func newTestInlineRuleSet(t *testing.T, tag string, rule option.DefaultHeadlessRule) adapter.RuleSet {
+	ruleSet, err := NewLocalRuleSet(nil, log.NewNOPFactory().Logger(), option.RuleSet{
+		Type: C.RuleSetTypeInline,
+		Tag:  tag,
+		InlineOptions: option.PlainRuleSet{
+			Rules: []option.HeadlessRule{{Type: C.RuleTypeDefault, DefaultOptions: rule}},
+		},
+	})
+	require.NoError(t, err)
+	return ruleSet
+}
+
+func TestCompositeRuleSet(t *testing.T) {
+	t.Parallel()
+	router := &testRuleSetRouter{ruleSets: map[string]adapter.RuleSet{
+		"ads": newTestInlineRuleSet(t, "ads", option.DefaultHeadlessRule{
+			DomainSuffix: []string{"ads.com", "track.com"},
+			IPCIDR:       []string{"10.0.0.0/8"},
+		}),
+		"allow": newTestInlineRuleSet(t, "allow", option.DefaultHeadlessRule{
+			Domain: []string{"ok.track.com"},
+			IPCIDR: []string{"10.1.0.0/16"},
+		}),
+	}}
+	newComposite := func(tag string, mode string, tags ...string) *CompositeRuleSet {
+		ruleSet, err := NewCompositeRuleSet(router, option.RuleSet{
+			Type:             C.RuleSetTypeComposite,
+			Tag:              tag,
+			CompositeOptions: option.CompositeRuleSet{Mode: mode, RuleSet: tags},
+		})
+		require.NoError(t, err)
+		router.ruleSets[tag] = ruleSet
+		return ruleSet
+	}
+	union := newComposite("union", C.RuleSetCompositeModeUnion, "ads", "allow")
+	intersect := newComposite("intersect", C.RuleSetCompositeModeIntersect, "ads", "allow")
+	exclude := newComposite("exclude", C.RuleSetCompositeModeExclude, "ads", "allow")
+	nested := newComposite("nested", C.RuleSetCompositeModeExclude, "union", "exclude")
+	for _, ruleSet := range []*CompositeRuleSet{union, intersect, exclude, nested} {
+		require.NoError(t, ruleSet.StartContext(nil, nil))
+	}
+	for _, testCase := range []struct {
+		domain    string
+		union     bool
+		intersect bool
+		exclude   bool
+	}{
+		{"x.ads.com", true, false, true},
+		{"ok.track.com", true, true, false},
+		{"example.com", false, false, false},
+	} {
+		match := func(ruleSet adapter.RuleSet) bool {
+			return ruleSet.Match(&adapter.InboundContext{Domain: testCase.domain})
+		}
+		require.Equal(t, testCase.union, match(union), testCase.domain)
+		require.Equal(t, testCase.intersect, match(intersect), testCase.domain)
+		require.Equal(t, testCase.exclude, match(exclude), testCase.domain)
+		require.Equal(t, testCase.union && !testCase.exclude, match(nested), testCase.domain)
+	}
+	ipSets := exclude.ExtractIPSet()
+	require.Len(t, ipSets, 1)
+	require.True(t, ipSets[0].Contains(netip.MustParseAddr("10.2.0.1")))
+	require.False(t, ipSets[0].Contains(netip.MustParseAddr("10.1.0.1")))
+	require.True(t, exclude.Match(&adapter.InboundContext{Destination: M.ParseSocksaddr("10.2.0.1:443")}))
+
+	loop := newComposite("loop", C.RuleSetCompositeModeUnion, "ads", "loop2")
+	newComposite("loop2", C.RuleSetCompositeModeUnion, "loop")
+	require.ErrorContains(t, loop.StartContext(nil, nil), "loop detected")
+}
@@ -4,37 +4,36 @@ import (
 	"context"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
 	"github.com/sagernet/fswatch"
 	"github.com/sagernet/sing-box/adapter"
-	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/common/mmap"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
 	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/logger"
 	"github.com/sagernet/sing/common/x/list"
-
-	"go4.org/netipx"
 )
 
 var _ adapter.RuleSet = (*LocalRuleSet)(nil)
 
 type LocalRuleSet struct {
-	router        adapter.Router
-	logger        logger.Logger
-	tag           string
-	rules         []adapter.HeadlessRule
-	metadata      adapter.RuleSetMetadata
-	fileFormat    string
-	watcher       *fswatch.Watcher
-	refs          atomic.Int32
-	localFilePath string
+	router adapter.Router
+	logger logger.Logger
+	tag    string
+	ruleSetState
+	fileFormat     string
+	behavior       string
+	watcher        *fswatch.Watcher
+	refs           atomic.Int32
+	localFilePath  string
+	callbackAccess sync.Mutex
+	callbacks      list.List[adapter.RuleSetUpdateCallback]
 }
 
 func NewLocalRuleSet(router adapter.Router, logger logger.Logger, options option.RuleSet) (*LocalRuleSet, error) {
@@ -43,6 +42,7 @@ func NewLocalRuleSet(router adapter.Router, logger logger.Logger, options option
 		logger:     logger,
 		tag:        options.Tag,
 		fileFormat: options.Format,
+		behavior:   options.Behavior,
 	}
 	if options.Type == C.RuleSetTypeInline {
 		if len(options.InlineOptions.Rules) == 0 {
@@ -87,10 +87,6 @@ func (s *LocalRuleSet) Type() string {
 	return "local"
 }
 
-func (s *LocalRuleSet) String() string {
-	return strings.Join(F.MapToString(s.rules), " ")
-}
-
 func (s *LocalRuleSet) StartContext(ctx context.Context, startContext adapter.RuleSetStartContext) error {
 	if s.watcher != nil {
 		err := s.watcher.Start()
@@ -118,18 +114,45 @@ func (s *LocalRuleSet) reloadFile(path string) error {
 			return err
 		}
 	case C.RuleSetFormatBinary:
-		setFile, err := os.Open(path)
+		setFile, err := mmap.Open(path)
+		if err != nil {
+			return err
+		}
+		decoder, metadata, err := newRuleSetDecoder(s.router, s.logger, s.tag, setFile.Reader(), setFile)
+		if err != nil {
+			setFile.Close()
+			return err
+		}
+		s.storeDecoder(decoder, metadata)
+		s.notifyCallbacks()
+		return nil
+	case C.RuleSetFormatClash:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		plainRuleSet.Rules, err = parseClashRuleSet(s.logger, s.tag, content, s.behavior)
 		if err != nil {
 			return err
 		}
-		plainRuleSet, err = srs.Read(setFile, false)
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatHosts:
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		plainRuleSet.Rules, err = parseFilterRuleSet(s.logger, s.tag, content, s.fileFormat)
 		if err != nil {
 			return err
 		}
 	default:
 		return E.New("unknown rule-set format: ", s.fileFormat)
 	}
-	return s.reloadRules(plainRuleSet.Rules)
+	err := s.reloadRules(plainRuleSet.Rules)
+	if err != nil {
+		return err
+	}
+	s.notifyCallbacks()
+	return nil
 }
 
 func (s *LocalRuleSet) reloadRules(headlessRules []option.HeadlessRule) error {
@@ -148,8 +171,7 @@ func (s *LocalRuleSet) reloadRules(headlessRules []option.HeadlessRule) error {
 	metadata.Format = s.fileFormat
 	metadata.LastUpdated = time.Now()
 	metadata.RuleNum = len(rules)
-	s.rules = rules
-	s.metadata = metadata
+	s.storeRules(rules, metadata)
 	return nil
 }
 
@@ -157,14 +179,6 @@ func (s *LocalRuleSet) PostStart() error {
 	return nil
 }
 
-func (s *LocalRuleSet) Metadata() adapter.RuleSetMetadata {
-	return s.metadata
-}
-
-func (s *LocalRuleSet) ExtractIPSet() []*netipx.IPSet {
-	return common.FlatMap(s.rules, extractIPSetFromRule)
-}
-
 func (s *LocalRuleSet) IncRef() {
 	s.refs.Add(1)
 }
@@ -177,15 +191,29 @@ func (s *LocalRuleSet) DecRef() {
 
 func (s *LocalRuleSet) Cleanup() {
 	if s.refs.Load() == 0 {
-		s.rules = nil
+		s.releaseRules()
 	}
 }
 
 func (s *LocalRuleSet) RegisterCallback(callback adapter.RuleSetUpdateCallback) *list.Element[adapter.RuleSetUpdateCallback] {
-	return nil
+	s.callbackAccess.Lock()
+	defer s.callbackAccess.Unlock()
+	return s.callbacks.PushBack(callback)
 }
 
 func (s *LocalRuleSet) UnregisterCallback(element *list.Element[adapter.RuleSetUpdateCallback]) {
+	s.callbackAccess.Lock()
+	defer s.callbackAccess.Unlock()
+	s.callbacks.Remove(element)
+}
+
+func (s *LocalRuleSet) notifyCallbacks() {
+	s.callbackAccess.Lock()
+	callbacks := s.callbacks.Array()
+	s.callbackAccess.Unlock()
+	for _, callback := range callbacks {
+		callback(s)
+	}
 }
 
 func (s *LocalRuleSet) Update(_ context.Context) error {
@@ -200,15 +228,6 @@ func (s *LocalRuleSet) Update(_ context.Context) error {
 }
 
 func (s *LocalRuleSet) Close() error {
-	s.rules = nil
+	s.releaseRules()
 	return common.Close(common.PtrOrNil(s.watcher))
 }
-
-func (s *LocalRuleSet) Match(metadata *adapter.InboundContext) bool {
-	for _, rule := range s.rules {
-		if rule.Match(metadata) {
-			return true
-		}
-	}
-	return false
-}
@@ -6,28 +6,26 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"os"
+	"path/filepath"
 	"runtime"
-	"strings"
 	"sync"
 	"time"
 
 	"github.com/sagernet/sing-box/adapter"
-	"github.com/sagernet/sing-box/common/srs"
+	"github.com/sagernet/sing-box/common/compress"
 	C "github.com/sagernet/sing-box/constant"
 	"github.com/sagernet/sing-box/option"
-	"github.com/sagernet/sing/common"
 	"github.com/sagernet/sing/common/atomic"
 	E "github.com/sagernet/sing/common/exceptions"
-	F "github.com/sagernet/sing/common/format"
 	"github.com/sagernet/sing/common/json"
 	"github.com/sagernet/sing/common/logger"
 	M "github.com/sagernet/sing/common/metadata"
 	N "github.com/sagernet/sing/common/network"
 	"github.com/sagernet/sing/common/x/list"
 	"github.com/sagernet/sing/service"
+	"github.com/sagernet/sing/service/filemanager"
 	"github.com/sagernet/sing/service/pause"
-
-	"go4.org/netipx"
 )
 
 var _ adapter.RuleSet = (*RemoteRuleSet)(nil)
@@ -38,10 +36,10 @@ type RemoteRuleSet struct {
 	router         adapter.Router
 	logger         logger.ContextLogger
 	options        option.RuleSet
-	metadata       adapter.RuleSetMetadata
 	updateInterval time.Duration
+	path           string
 	dialer         N.Dialer
-	rules          []adapter.HeadlessRule
+	ruleSetState
 	lastUpdated    time.Time
 	lastEtag       string
 	updateTicker   *time.Ticker
@@ -60,6 +58,10 @@ func NewRemoteRuleSet(ctx context.Context, router adapter.Router, logger logger.
 	} else {
 		updateInterval = 24 * time.Hour
 	}
+	var path string
+	if options.RemoteOptions.Path != "" {
+		path = filemanager.BasePath(ctx, options.RemoteOptions.Path)
+	}
 	return &RemoteRuleSet{
 		ctx:            ctx,
 		cancel:         cancel,
@@ -67,6 +69,7 @@ func NewRemoteRuleSet(ctx context.Context, router adapter.Router, logger logger.
 		logger:         logger,
 		options:        options,
 		updateInterval: updateInterval,
+		path:           path,
 		pauseManager:   service.FromContext[pause.Manager](ctx),
 		updateChan:     make(chan context.CancelCauseFunc),
 	}
@@ -80,10 +83,6 @@ func (s *RemoteRuleSet) Type() string {
 	return "remote"
 }
 
-func (s *RemoteRuleSet) String() string {
-	return strings.Join(F.MapToString(s.rules), " ")
-}
-
 func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext adapter.RuleSetStartContext) error {
 	var dialer N.Dialer
 	if s.options.RemoteOptions.DownloadDetour != "" {
@@ -111,6 +110,12 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext adapter.R
 			s.lastEtag = savedSet.LastEtag
 		}
 	}
+	if s.lastUpdated.IsZero() && s.path != "" {
+		err := s.loadSavedFile()
+		if err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("restore saved rule-set ", s.options.Tag, ": ", err)
+		}
+	}
 	if s.lastUpdated.IsZero() {
 		err := s.fetchOnce(ctx, startContext)
 		if err != nil {
@@ -121,17 +126,44 @@ func (s *RemoteRuleSet) StartContext(ctx context.Context, startContext adapter.R
 	return nil
 }
 
-func (s *RemoteRuleSet) PostStart() error {
-	go s.loopUpdate()
+// loadSavedFile restores the copy written to path by the last download,
+// taking its modification time as the last update.
+func (s *RemoteRuleSet) loadSavedFile() error {
+	fileInfo, err := os.Stat(s.path)
+	if err != nil {
+		return err
+	}
+	content, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	err = s.loadBytes(content)
+	if err != nil {
+		return err
+	}
+	s.lastUpdated = fileInfo.ModTime()
 	return nil
 }
 
-func (s *RemoteRuleSet) Metadata() adapter.RuleSetMetadata {
-	return s.metadata
+func (s *RemoteRuleSet) saveFile(content []byte) error {
+	if parentDir := filepath.Dir(s.path); parentDir != "" {
+		filemanager.MkdirAll(s.ctx, parentDir, 0o755)
+	}
+	tempPath := s.path + ".tmp"
+	err := filemanager.WriteFile(s.ctx, tempPath, content, 0o644)
+	if err != nil {
+		return err
+	}
+	err = os.Rename(tempPath, s.path)
+	if err != nil {
+		os.Remove(tempPath)
+	}
+	return err
 }
 
-func (s *RemoteRuleSet) ExtractIPSet() []*netipx.IPSet {
-	return common.FlatMap(s.rules, extractIPSetFromRule)
+func (s *RemoteRuleSet) PostStart() error {
+	go s.loopUpdate()
+	return nil
 }
 
 func (s *RemoteRuleSet) IncRef() {
@@ -146,7 +178,7 @@ func (s *RemoteRuleSet) DecRef() {
 
 func (s *RemoteRuleSet) Cleanup() {
 	if s.refs.Load() == 0 {
-		s.rules = nil
+		s.releaseRules()
 	}
 }
 
@@ -179,7 +211,20 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 			return err
 		}
 	case C.RuleSetFormatBinary:
-		plainRuleSet, err = srs.Read(bytes.NewReader(content), false)
+		decoder, metadata, err := newRuleSetDecoder(s.router, s.logger, s.options.Tag, bytes.NewReader(content), nil)
+		if err != nil {
+			return err
+		}
+		s.storeDecoder(decoder, metadata)
+		s.notifyCallbacks()
+		return nil
+	case C.RuleSetFormatClash:
+		plainRuleSet.Rules, err = parseClashRuleSet(s.logger, s.options.Tag, content, s.options.Behavior)
+		if err != nil {
+			return err
+		}
+	case C.RuleSetFormatAdGuard, C.RuleSetFormatHosts:
+		plainRuleSet.Rules, err = parseFilterRuleSet(s.logger, s.options.Tag, content, s.options.Format)
 		if err != nil {
 			return err
 		}
@@ -193,20 +238,25 @@ func (s *RemoteRuleSet) loadBytes(content []byte) error {
 			return E.Cause(err, "parse rule_set.rules.[", i, "]")
 		}
 	}
-	s.metadata.ContainsProcessRule = hasHeadlessRule(plainRuleSet.Rules, isProcessHeadlessRule)
-	s.metadata.ContainsWIFIRule = hasHeadlessRule(plainRuleSet.Rules, isWIFIHeadlessRule)
-	s.metadata.ContainsIPCIDRRule = hasHeadlessRule(plainRuleSet.Rules, isIPCIDRHeadlessRule)
-	s.metadata.Format = s.options.Format
-	s.metadata.LastUpdated = time.Now()
-	s.metadata.RuleNum = len(rules)
-	s.rules = rules
+	var metadata adapter.RuleSetMetadata
+	metadata.ContainsProcessRule = hasHeadlessRule(plainRuleSet.Rules, isProcessHeadlessRule)
+	metadata.ContainsWIFIRule = hasHeadlessRule(plainRuleSet.Rules, isWIFIHeadlessRule)
+	metadata.ContainsIPCIDRRule = hasHeadlessRule(plainRuleSet.Rules, isIPCIDRHeadlessRule)
+	metadata.Format = s.options.Format
+	metadata.LastUpdated = time.Now()
+	metadata.RuleNum = len(rules)
+	s.storeRules(rules, metadata)
+	s.notifyCallbacks()
+	return nil
+}
+
+func (s *RemoteRuleSet) notifyCallbacks() {
 	s.callbackAccess.Lock()
 	callbacks := s.callbacks.Array()
 	s.callbackAccess.Unlock()
 	for _, callback := range callbacks {
 		callback(s)
 	}
-	return nil
 }
 
 func (s *RemoteRuleSet) loopUpdate() {
@@ -215,7 +265,7 @@ func (s *RemoteRuleSet) loopUpdate() {
 		if err != nil {
 			s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
 		} else if s.refs.Load() == 0 {
-			s.rules = nil
+			s.releaseRules()
 		}
 	}
 	for {
@@ -229,7 +279,7 @@ func (s *RemoteRuleSet) loopUpdate() {
 			if err != nil {
 				s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
 			} else if s.refs.Load() == 0 {
-				s.rules = nil
+				s.releaseRules()
 			}
 		case cancel := <-s.updateChan:
 			s.pauseManager.WaitActive()
@@ -237,7 +287,7 @@ func (s *RemoteRuleSet) loopUpdate() {
 			if err != nil {
 				s.logger.Error("fetch rule-set ", s.options.Tag, ": ", err)
 			} else if s.refs.Load() == 0 {
-				s.rules = nil
+				s.releaseRules()
 			}
 			cancel(err)
 		}
@@ -266,7 +316,10 @@ func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.Rule
 	}
 	if s.lastEtag != "" {
 		request.Header.Set("If-None-Match", s.lastEtag)
+	} else if !s.lastUpdated.IsZero() {
+		request.Header.Set("If-Modified-Since", s.lastUpdated.UTC().Format(http.TimeFormat))
 	}
+	compress.SetAcceptEncoding(request)
 	response, err := httpClient.Do(request.WithContext(ctx))
 	if err != nil {
 		return err
@@ -274,7 +327,14 @@ func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.Rule
 	switch response.StatusCode {
 	case http.StatusOK:
 	case http.StatusNotModified:
+		response.Body.Close()
 		s.lastUpdated = time.Now()
+		if s.path != "" {
+			err = os.Chtimes(s.path, s.lastUpdated, s.lastUpdated)
+			if err != nil && !os.IsNotExist(err) {
+				s.logger.Error("save rule-set updated time: ", err)
+			}
+		}
 		cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 		if cacheFile != nil {
 			savedRuleSet := cacheFile.LoadRuleSet(s.options.Tag)
@@ -290,24 +350,36 @@ func (s *RemoteRuleSet) fetchOnce(ctx context.Context, startContext adapter.Rule
 		s.logger.Info("update rule-set ", s.options.Tag, ": not modified")
 		return nil
 	default:
+		response.Body.Close()
 		return E.New("unexpected status: ", response.Status)
 	}
-	content, err := io.ReadAll(response.Body)
+	body, err := compress.DecodeResponse(response)
 	if err != nil {
 		response.Body.Close()
 		return err
 	}
+	content, err := io.ReadAll(body)
+	if err != nil {
+		body.Close()
+		return err
+	}
 	err = s.loadBytes(content)
 	if err != nil {
-		response.Body.Close()
+		body.Close()
 		return err
 	}
-	response.Body.Close()
+	body.Close()
 	eTagHeader := response.Header.Get("Etag")
 	if eTagHeader != "" {
 		s.lastEtag = eTagHeader
 	}
 	s.lastUpdated = time.Now()
+	if s.path != "" {
+		err = s.saveFile(content)
+		if err != nil {
+			s.logger.Error("save rule-set file: ", err)
+		}
+	}
 	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 	if cacheFile != nil {
 		err = cacheFile.SaveRuleSet(s.options.Tag, &adapter.SavedRuleSet{
@@ -342,18 +414,9 @@ func (s *RemoteRuleSet) Update(ctx context.Context) error {
 }
 
 func (s *RemoteRuleSet) Close() error {
-	s.rules = nil
+	s.releaseRules()
 	s.updateTicker.Stop()
 	s.cancel()
 	close(s.updateChan)
 	return nil
 }
-
-func (s *RemoteRuleSet) Match(metadata *adapter.InboundContext) bool {
-	for _, rule := range s.rules {
-		if rule.Match(metadata) {
-			return true
-		}
-	}
-	return false
-}
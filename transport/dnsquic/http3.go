@@ -0,0 +1,132 @@
+//go:build with_quic
+
+package dnsquic
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"os"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/http3"
+	"github.com/sagernet/sing-dns"
+	"github.com/sagernet/sing/common/bufio"
+	E "github.com/sagernet/sing/common/exceptions"
+	M "github.com/sagernet/sing/common/metadata"
+	N "github.com/sagernet/sing/common/network"
+
+	mDNS "github.com/miekg/dns"
+)
+
+var _ dns.Transport = (*HTTP3Transport)(nil)
+
+func init() {
+	dns.RegisterTransport([]string{"h3"}, func(options dns.TransportOptions) (dns.Transport, error) {
+		return NewHTTP3Transport(options)
+	})
+}
+
+// HTTP3Transport implements DNS over HTTPS over HTTP/3. Queries are sent as
+// GET requests, which may go out in 0-RTT data of resumed connections.
+type HTTP3Transport struct {
+	name        string
+	destination *url.URL
+	transport   *http3.RoundTripper
+}
+
+func NewHTTP3Transport(options dns.TransportOptions) (*HTTP3Transport, error) {
+	serverURL, err := url.Parse(options.Address)
+	if err != nil {
+		return nil, err
+	}
+	serverURL.Scheme = "https"
+	return &HTTP3Transport{
+		name:        options.Name,
+		destination: serverURL,
+		transport: &http3.RoundTripper{
+			Dial: func(ctx context.Context, addr string, tlsCfg *tls.Config, cfg *quic.Config) (quic.EarlyConnection, error) {
+				destinationAddr := M.ParseSocksaddr(addr)
+				conn, dialErr := options.Dialer.DialContext(ctx, N.NetworkUDP, destinationAddr)
+				if dialErr != nil {
+					return nil, dialErr
+				}
+				earlyConnection, dialErr := quic.DialEarly(ctx, bufio.NewUnbindPacketConn(conn), conn.RemoteAddr(), tlsCfg, cfg)
+				if dialErr != nil {
+					conn.Close()
+					return nil, dialErr
+				}
+				return earlyConnection, nil
+			},
+			TLSClientConfig: &tls.Config{
+				ClientSessionCache: tls.NewLRUClientSessionCache(0),
+			},
+		},
+	}, nil
+}
+
+func (t *HTTP3Transport) Name() string {
+	return t.name
+}
+
+func (t *HTTP3Transport) Start() error {
+	return nil
+}
+
+func (t *HTTP3Transport) Reset() {
+	_ = t.transport.Close()
+}
+
+func (t *HTTP3Transport) Close() error {
+	return t.transport.Close()
+}
+
+func (t *HTTP3Transport) Raw() bool {
+	return true
+}
+
+func (t *HTTP3Transport) Exchange(ctx context.Context, message *mDNS.Msg) (*mDNS.Msg, error) {
+	// the message ID should be zero for caching, see RFC 8484 section 4.1
+	exMessage := *message
+	exMessage.Id = 0
+	exMessage.Compress = true
+	rawMessage, err := exMessage.Pack()
+	if err != nil {
+		return nil, err
+	}
+	requestURL := *t.destination
+	query := requestURL.Query()
+	query.Set("dns", base64.RawURLEncoding.EncodeToString(rawMessage))
+	requestURL.RawQuery = query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http3.MethodGet0RTT, requestURL.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Accept", dns.MimeType)
+	response, err := t.transport.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return nil, E.New("unexpected status: ", response.Status)
+	}
+	rawMessage, err = io.ReadAll(io.LimitReader(response.Body, mDNS.MaxMsgSize))
+	if err != nil {
+		return nil, err
+	}
+	var responseMessage mDNS.Msg
+	err = responseMessage.Unpack(rawMessage)
+	if err != nil {
+		return nil, err
+	}
+	return &responseMessage, nil
+}
+
+func (t *HTTP3Transport) Lookup(ctx context.Context, domain string, strategy dns.DomainStrategy) ([]netip.Addr, error) {
+	return nil, os.ErrInvalid
+}
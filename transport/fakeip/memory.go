@@ -6,23 +6,34 @@ import (
 
 	"github.com/sagernet/sing-box/adapter"
 	"github.com/sagernet/sing/common/logger"
+	"github.com/sagernet/sing/common/x/list"
 )
 
 var _ adapter.FakeIPStorage = (*MemoryStorage)(nil)
 
+// MemoryStorage keeps FakeIP mappings in least recently used order for each
+// address family, evicting the oldest ones beyond capacity if set.
 type MemoryStorage struct {
 	addressAccess sync.RWMutex
 	domainAccess  sync.RWMutex
 	addressCache  map[netip.Addr]string
 	domainCache4  map[string]netip.Addr
 	domainCache6  map[string]netip.Addr
+	capacity      int
+	orderAccess   sync.Mutex
+	order4        list.List[netip.Addr]
+	order6        list.List[netip.Addr]
+	orderElements map[netip.Addr]*list.Element[netip.Addr]
+	evicted       func(address netip.Addr, domain string)
 }
 
-func NewMemoryStorage() *MemoryStorage {
+func NewMemoryStorage(capacity int) *MemoryStorage {
 	return &MemoryStorage{
-		addressCache: make(map[netip.Addr]string),
-		domainCache4: make(map[string]netip.Addr),
-		domainCache6: make(map[string]netip.Addr),
+		addressCache:  make(map[netip.Addr]string),
+		domainCache4:  make(map[string]netip.Addr),
+		domainCache6:  make(map[string]netip.Addr),
+		capacity:      capacity,
+		orderElements: make(map[netip.Addr]*list.Element[netip.Addr]),
 	}
 }
 
@@ -53,37 +64,126 @@ func (s *MemoryStorage) FakeIPStore(address netip.Addr, domain string) error {
 	} else {
 		s.domainCache6[domain] = address
 	}
+	s.orderAccess.Lock()
+	order := s.orderOf(address)
+	if element, loaded := s.orderElements[address]; loaded {
+		order.MoveToBack(element)
+	} else {
+		s.orderElements[address] = order.PushBack(address)
+	}
+	for s.capacity > 0 && order.Len() > s.capacity {
+		oldest := order.Remove(order.Front())
+		delete(s.orderElements, oldest)
+		oldDomain := s.deleteAddress(oldest)
+		if s.evicted != nil {
+			s.evicted(oldest, oldDomain)
+		}
+	}
+	s.orderAccess.Unlock()
 	s.domainAccess.Unlock()
 	s.addressAccess.Unlock()
 	return nil
 }
 
+func (s *MemoryStorage) orderOf(address netip.Addr) *list.List[netip.Addr] {
+	if address.Is4() {
+		return &s.order4
+	}
+	return &s.order6
+}
+
+func (s *MemoryStorage) deleteAddress(address netip.Addr) string {
+	domain, loaded := s.addressCache[address]
+	if !loaded {
+		return ""
+	}
+	delete(s.addressCache, address)
+	if address.Is4() {
+		if s.domainCache4[domain] == address {
+			delete(s.domainCache4, domain)
+		}
+	} else {
+		if s.domainCache6[domain] == address {
+			delete(s.domainCache6, domain)
+		}
+	}
+	return domain
+}
+
+func (s *MemoryStorage) touch(address netip.Addr) {
+	s.orderAccess.Lock()
+	if element, loaded := s.orderElements[address]; loaded {
+		s.orderOf(address).MoveToBack(element)
+	}
+	s.orderAccess.Unlock()
+}
+
+// exists reports whether address is in use without updating its usage.
+func (s *MemoryStorage) exists(address netip.Addr) bool {
+	s.addressAccess.RLock()
+	_, loaded := s.addressCache[address]
+	s.addressAccess.RUnlock()
+	return loaded
+}
+
+// oldest returns the least recently used address of the address family.
+func (s *MemoryStorage) oldest(isIPv6 bool) (netip.Addr, bool) {
+	s.orderAccess.Lock()
+	defer s.orderAccess.Unlock()
+	order := &s.order4
+	if isIPv6 {
+		order = &s.order6
+	}
+	if order.Len() == 0 {
+		return netip.Addr{}, false
+	}
+	return order.Front().Value, true
+}
+
 func (s *MemoryStorage) FakeIPStoreAsync(address netip.Addr, domain string, logger logger.Logger) {
 	_ = s.FakeIPStore(address, domain)
 }
 
 func (s *MemoryStorage) FakeIPLoad(address netip.Addr) (string, bool) {
 	s.addressAccess.RLock()
-	defer s.addressAccess.RUnlock()
 	domain, loaded := s.addressCache[address]
+	s.addressAccess.RUnlock()
+	if loaded {
+		s.touch(address)
+	}
 	return domain, loaded
 }
 
 func (s *MemoryStorage) FakeIPLoadDomain(domain string, isIPv6 bool) (netip.Addr, bool) {
 	s.domainAccess.RLock()
-	defer s.domainAccess.RUnlock()
+	var (
+		address netip.Addr
+		loaded  bool
+	)
 	if !isIPv6 {
-		address, loaded := s.domainCache4[domain]
-		return address, loaded
+		address, loaded = s.domainCache4[domain]
 	} else {
-		address, loaded := s.domainCache6[domain]
-		return address, loaded
+		address, loaded = s.domainCache6[domain]
+	}
+	s.domainAccess.RUnlock()
+	if loaded {
+		s.touch(address)
 	}
+	return address, loaded
 }
 
 func (s *MemoryStorage) FakeIPReset() error {
+	s.addressAccess.Lock()
+	s.domainAccess.Lock()
 	s.addressCache = make(map[netip.Addr]string)
 	s.domainCache4 = make(map[string]netip.Addr)
 	s.domainCache6 = make(map[string]netip.Addr)
+	s.orderAccess.Lock()
+	s.order4.Init()
+	s.order6.Init()
+	s.orderElements = make(map[netip.Addr]*list.Element[netip.Addr])
+	s.orderAccess.Unlock()
+	s.domainAccess.Unlock()
+	s.addressAccess.Unlock()
 	return nil
 }
@@ -0,0 +1,85 @@
+package fakeip
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStorageEviction(t *testing.T) {
+	t.Parallel()
+	address := func(i int) netip.Addr {
+		return netip.AddrFrom4([4]byte{198, 18, 0, byte(i)})
+	}
+	for _, testCase := range []struct {
+		name     string
+		capacity int
+		store    []string
+		touch    []int
+		evicted  []string
+		retained []string
+	}{
+		{
+			name:     "unlimited",
+			store:    []string{"a.com", "b.com", "c.com"},
+			retained: []string{"a.com", "b.com", "c.com"},
+		},
+		{
+			name:     "oldest evicted",
+			capacity: 2,
+			store:    []string{"a.com", "b.com", "c.com"},
+			evicted:  []string{"a.com"},
+			retained: []string{"b.com", "c.com"},
+		},
+		{
+			name:     "recently used retained",
+			capacity: 2,
+			store:    []string{"a.com", "b.com"},
+			touch:    []int{0},
+			evicted:  []string{"b.com"},
+			retained: []string{"a.com", "c.com"},
+		},
+	} {
+		testCase := testCase
+		t.Run(testCase.name, func(t *testing.T) {
+			t.Parallel()
+			storage := NewMemoryStorage(testCase.capacity)
+			for i, domain := range testCase.store {
+				require.NoError(t, storage.FakeIPStore(address(i), domain))
+			}
+			for _, i := range testCase.touch {
+				_, loaded := storage.FakeIPLoad(address(i))
+				require.True(t, loaded)
+			}
+			if len(testCase.touch) > 0 {
+				require.NoError(t, storage.FakeIPStore(address(len(testCase.store)), "c.com"))
+			}
+			for _, domain := range testCase.evicted {
+				_, loaded := storage.FakeIPLoadDomain(domain, false)
+				require.False(t, loaded, domain)
+			}
+			for _, domain := range testCase.retained {
+				storedAddress, loaded := storage.FakeIPLoadDomain(domain, false)
+				require.True(t, loaded, domain)
+				storedDomain, loaded := storage.FakeIPLoad(storedAddress)
+				require.True(t, loaded)
+				require.Equal(t, domain, storedDomain)
+			}
+		})
+	}
+}
+
+func TestMemoryStorageReplace(t *testing.T) {
+	t.Parallel()
+	storage := NewMemoryStorage(2)
+	address := netip.MustParseAddr("198.18.0.2")
+	require.NoError(t, storage.FakeIPStore(address, "a.com"))
+	require.NoError(t, storage.FakeIPStore(address, "b.com"))
+	_, loaded := storage.FakeIPLoadDomain("a.com", false)
+	require.False(t, loaded)
+	domain, loaded := storage.FakeIPLoad(address)
+	require.True(t, loaded)
+	require.Equal(t, "b.com", domain)
+	require.Equal(t, 1, storage.order4.Len())
+}
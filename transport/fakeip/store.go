@@ -3,6 +3,8 @@ package fakeip
 import (
 	"context"
 	"net/netip"
+	"sort"
+	"sync"
 
 	"github.com/sagernet/sing-box/adapter"
 	E "github.com/sagernet/sing/common/exceptions"
@@ -12,38 +14,47 @@ import (
 
 var _ adapter.FakeIPStore = (*Store)(nil)
 
+// Store allocates addresses from the ranges in order, and reuses the least
+// recently used ones once a range is exhausted. Mappings are kept in memory
+// and written through to the cache file if store_fakeip is enabled.
 type Store struct {
 	ctx          context.Context
 	logger       logger.Logger
 	inet4Range   netip.Prefix
 	inet6Range   netip.Prefix
-	storage      adapter.FakeIPStorage
+	capacity     int
+	access       sync.Mutex
+	memory       *MemoryStorage
+	cacheFile    adapter.CacheFile
 	inet4Current netip.Addr
 	inet6Current netip.Addr
 }
 
-func NewStore(ctx context.Context, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix) *Store {
+func NewStore(ctx context.Context, logger logger.Logger, inet4Range netip.Prefix, inet6Range netip.Prefix, capacity int) *Store {
 	return &Store{
 		ctx:        ctx,
 		logger:     logger,
 		inet4Range: inet4Range,
 		inet6Range: inet6Range,
+		capacity:   capacity,
 	}
 }
 
 func (s *Store) Start() error {
-	var storage adapter.FakeIPStorage
+	s.memory = NewMemoryStorage(s.capacity)
 	cacheFile := service.FromContext[adapter.CacheFile](s.ctx)
 	if cacheFile != nil && cacheFile.StoreFakeIP() {
-		storage = cacheFile
+		s.cacheFile = cacheFile
+		s.memory.evicted = s.deletePersisted
 	}
-	if storage == nil {
-		storage = NewMemoryStorage()
+	var metadata *adapter.FakeIPMetadata
+	if s.cacheFile != nil {
+		metadata = s.cacheFile.FakeIPMetadata()
 	}
-	metadata := storage.FakeIPMetadata()
 	if metadata != nil && metadata.Inet4Range == s.inet4Range && metadata.Inet6Range == s.inet6Range {
 		s.inet4Current = metadata.Inet4Current
 		s.inet6Current = metadata.Inet6Current
+		s.loadPersisted()
 	} else {
 		if s.inet4Range.IsValid() {
 			s.inet4Current = s.inet4Range.Addr().Next().Next()
@@ -51,30 +62,86 @@ func (s *Store) Start() error {
 		if s.inet6Range.IsValid() {
 			s.inet6Current = s.inet6Range.Addr().Next().Next()
 		}
-		_ = storage.FakeIPReset()
+		if s.cacheFile != nil {
+			_ = s.cacheFile.FakeIPReset()
+		}
 	}
-	s.storage = storage
 	return nil
 }
 
+// loadPersisted loads mappings from the cache file in allocation order, so
+// that the least recently allocated ones are evicted first.
+func (s *Store) loadPersisted() {
+	mappings := s.cacheFile.FakeIPLoadAll()
+	addresses := make([]netip.Addr, 0, len(mappings))
+	for address := range mappings {
+		if s.Contains(address) {
+			addresses = append(addresses, address)
+		}
+	}
+	sort.Slice(addresses, func(i, j int) bool {
+		return s.allocationOrder(addresses[i]) < s.allocationOrder(addresses[j]) ||
+			s.allocationOrder(addresses[i]) == s.allocationOrder(addresses[j]) && addresses[i].Less(addresses[j])
+	})
+	for _, address := range addresses {
+		_ = s.memory.FakeIPStore(address, mappings[address])
+	}
+	if len(addresses) > 0 {
+		s.logger.Debug("loaded ", len(addresses), " fakeip mappings")
+	}
+}
+
+// allocationOrder puts addresses after the current one, which are allocated
+// in the previous round, before the others.
+func (s *Store) allocationOrder(address netip.Addr) int {
+	current := s.inet4Current
+	if address.Is6() {
+		current = s.inet6Current
+	}
+	if current.Less(address) {
+		return 0
+	}
+	return 1
+}
+
+func (s *Store) deletePersisted(address netip.Addr, domain string) {
+	go func() {
+		err := s.cacheFile.FakeIPDelete(address, domain)
+		if err != nil {
+			s.logger.Warn("delete FakeIP cache: ", err)
+		}
+	}()
+}
+
 func (s *Store) Contains(address netip.Addr) bool {
 	return s.inet4Range.Contains(address) || s.inet6Range.Contains(address)
 }
 
 func (s *Store) Close() error {
-	if s.storage == nil {
+	if s.cacheFile == nil {
 		return nil
 	}
-	return s.storage.FakeIPSaveMetadata(&adapter.FakeIPMetadata{
+	s.access.Lock()
+	defer s.access.Unlock()
+	return s.cacheFile.FakeIPSaveMetadata(s.metadata())
+}
+
+func (s *Store) metadata() *adapter.FakeIPMetadata {
+	return &adapter.FakeIPMetadata{
 		Inet4Range:   s.inet4Range,
 		Inet6Range:   s.inet6Range,
 		Inet4Current: s.inet4Current,
 		Inet6Current: s.inet6Current,
-	})
+	}
 }
 
 func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
-	if address, loaded := s.storage.FakeIPLoadDomain(domain, isIPv6); loaded {
+	if address, loaded := s.memory.FakeIPLoadDomain(domain, isIPv6); loaded {
+		return address, nil
+	}
+	s.access.Lock()
+	defer s.access.Unlock()
+	if address, loaded := s.memory.FakeIPLoadDomain(domain, isIPv6); loaded {
 		return address, nil
 	}
 	var address netip.Addr
@@ -82,37 +149,44 @@ func (s *Store) Create(domain string, isIPv6 bool) (netip.Addr, error) {
 		if !s.inet4Current.IsValid() {
 			return netip.Addr{}, E.New("missing IPv4 fakeip address range")
 		}
-		nextAddress := s.inet4Current.Next()
-		if !s.inet4Range.Contains(nextAddress) {
-			nextAddress = s.inet4Range.Addr().Next().Next()
+		address = s.inet4Current.Next()
+		if !s.inet4Range.Contains(address) {
+			address = s.inet4Range.Addr().Next().Next()
 		}
-		s.inet4Current = nextAddress
-		address = nextAddress
+		s.inet4Current = address
 	} else {
 		if !s.inet6Current.IsValid() {
 			return netip.Addr{}, E.New("missing IPv6 fakeip address range")
 		}
-		nextAddress := s.inet6Current.Next()
-		if !s.inet6Range.Contains(nextAddress) {
-			nextAddress = s.inet6Range.Addr().Next().Next()
+		address = s.inet6Current.Next()
+		if !s.inet6Range.Contains(address) {
+			address = s.inet6Range.Addr().Next().Next()
 		}
-		s.inet6Current = nextAddress
-		address = nextAddress
+		s.inet6Current = address
+	}
+	if s.memory.exists(address) {
+		if oldest, loaded := s.memory.oldest(isIPv6); loaded {
+			address = oldest
+		}
+	}
+	_ = s.memory.FakeIPStore(address, domain)
+	if s.cacheFile != nil {
+		s.cacheFile.FakeIPStoreAsync(address, domain, s.logger)
+		s.cacheFile.FakeIPSaveMetadataAsync(s.metadata())
 	}
-	s.storage.FakeIPStoreAsync(address, domain, s.logger)
-	s.storage.FakeIPSaveMetadataAsync(&adapter.FakeIPMetadata{
-		Inet4Range:   s.inet4Range,
-		Inet6Range:   s.inet6Range,
-		Inet4Current: s.inet4Current,
-		Inet6Current: s.inet6Current,
-	})
 	return address, nil
 }
 
 func (s *Store) Lookup(address netip.Addr) (string, bool) {
-	return s.storage.FakeIPLoad(address)
+	return s.memory.FakeIPLoad(address)
 }
 
 func (s *Store) Reset() error {
-	return s.storage.FakeIPReset()
+	s.access.Lock()
+	defer s.access.Unlock()
+	_ = s.memory.FakeIPReset()
+	if s.cacheFile != nil {
+		return s.cacheFile.FakeIPReset()
+	}
+	return nil
 }
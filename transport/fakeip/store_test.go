@@ -0,0 +1,55 @@
+package fakeip
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing/common/logger"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoreReuseLeastRecentlyUsed(t *testing.T) {
+	t.Parallel()
+	store := NewStore(context.Background(), logger.NOP(), netip.MustParsePrefix("198.18.0.0/29"), netip.Prefix{}, 0)
+	require.NoError(t, store.Start())
+	addresses := make(map[string]netip.Addr)
+	for _, domain := range []string{"a.com", "b.com", "c.com", "d.com", "e.com", "f.com"} {
+		address, err := store.Create(domain, false)
+		require.NoError(t, err)
+		require.True(t, store.Contains(address))
+		require.NotContains(t, addresses, address, domain)
+		addresses[domain] = address
+	}
+	address, err := store.Create("a.com", false)
+	require.NoError(t, err)
+	require.Equal(t, addresses["a.com"], address)
+	address, err = store.Create("g.com", false)
+	require.NoError(t, err)
+	require.Equal(t, addresses["b.com"], address)
+	domain, loaded := store.Lookup(addresses["a.com"])
+	require.True(t, loaded)
+	require.Equal(t, "a.com", domain)
+	_, loaded = store.memory.FakeIPLoadDomain("b.com", false)
+	require.False(t, loaded)
+	_, err = store.Create("a.com", true)
+	require.EqualError(t, err, "missing IPv6 fakeip address range")
+}
+
+func TestStoreCapacity(t *testing.T) {
+	t.Parallel()
+	store := NewStore(context.Background(), logger.NOP(), netip.MustParsePrefix("198.18.0.0/15"), netip.Prefix{}, 2)
+	require.NoError(t, store.Start())
+	first, err := store.Create("a.com", false)
+	require.NoError(t, err)
+	for _, domain := range []string{"b.com", "c.com"} {
+		_, err = store.Create(domain, false)
+		require.NoError(t, err)
+	}
+	_, loaded := store.Lookup(first)
+	require.False(t, loaded)
+	address, err := store.Create("a.com", false)
+	require.NoError(t, err)
+	require.NotEqual(t, first, address)
+}
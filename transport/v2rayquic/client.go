@@ -23,17 +23,23 @@ import (
 var _ adapter.V2RayClientTransport = (*Client)(nil)
 
 type Client struct {
-	ctx        context.Context
-	dialer     N.Dialer
-	serverAddr M.Socksaddr
-	tlsConfig  tls.Config
-	quicConfig *quic.Config
-	connAccess sync.Mutex
-	conn       quic.Connection
-	rawConn    net.Conn
+	ctx           context.Context
+	dialer        N.Dialer
+	serverAddr    M.Socksaddr
+	tlsConfig     tls.Config
+	quicConfig    *quic.Config
+	congestion    string
+	initialWindow uint32
+	connAccess    sync.Mutex
+	conn          quic.Connection
+	rawConn       net.Conn
 }
 
 func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, options option.V2RayQUICOptions, tlsConfig tls.Config) (adapter.V2RayClientTransport, error) {
+	err := checkCongestion(options.CongestionControl, options.InitialCongestionWindow)
+	if err != nil {
+		return nil, err
+	}
 	quicConfig := &quic.Config{
 		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows,
 	}
@@ -41,11 +47,13 @@ func NewClient(ctx context.Context, dialer N.Dialer, serverAddr M.Socksaddr, opt
 		tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
 	}
 	return &Client{
-		ctx:        ctx,
-		dialer:     dialer,
-		serverAddr: serverAddr,
-		tlsConfig:  tlsConfig,
-		quicConfig: quicConfig,
+		ctx:           ctx,
+		dialer:        dialer,
+		serverAddr:    serverAddr,
+		tlsConfig:     tlsConfig,
+		quicConfig:    quicConfig,
+		congestion:    options.CongestionControl,
+		initialWindow: options.InitialCongestionWindow,
 	}, nil
 }
 
@@ -79,6 +87,7 @@ func (c *Client) offerNew() (quic.Connection, error) {
 		packetConn.Close()
 		return nil, err
 	}
+	setCongestion(c.ctx, quicConn, c.congestion, c.initialWindow)
 	c.conn = quicConn
 	c.rawConn = udpConn
 	return quicConn, nil
@@ -0,0 +1,72 @@
+//go:build with_quic
+
+package v2rayquic
+
+import (
+	"context"
+	"time"
+
+	"github.com/sagernet/quic-go"
+	"github.com/sagernet/quic-go/congestion"
+	congestion_meta1 "github.com/sagernet/sing-quic/congestion_meta1"
+	congestion_meta2 "github.com/sagernet/sing-quic/congestion_meta2"
+	E "github.com/sagernet/sing/common/exceptions"
+	"github.com/sagernet/sing/common/ntp"
+)
+
+func checkCongestion(congestionName string, initialWindow uint32) error {
+	switch congestionName {
+	case "":
+		if initialWindow > 0 {
+			return E.New("initial_congestion_window requires congestion_control")
+		}
+	case "bbr", "bbr_meta_v1":
+	case "cubic", "new_reno":
+		if initialWindow > 0 {
+			return E.New("initial_congestion_window is unsupported by ", congestionName)
+		}
+	default:
+		return E.New("unknown congestion control algorithm: ", congestionName)
+	}
+	return nil
+}
+
+func setCongestion(ctx context.Context, connection quic.Connection, congestionName string, initialWindow uint32) {
+	timeFunc := ntp.TimeFuncFromContext(ctx)
+	if timeFunc == nil {
+		timeFunc = time.Now
+	}
+	initialPacketSize := congestion.ByteCount(connection.Config().InitialPacketSize)
+	if initialWindow == 0 {
+		initialWindow = congestion_meta1.InitialCongestionWindow
+	}
+	switch congestionName {
+	case "cubic":
+		connection.SetCongestionControl(congestion_meta1.NewCubicSender(
+			congestion_meta1.DefaultClock{TimeFunc: timeFunc},
+			initialPacketSize,
+			false,
+			nil,
+		))
+	case "new_reno":
+		connection.SetCongestionControl(congestion_meta1.NewCubicSender(
+			congestion_meta1.DefaultClock{TimeFunc: timeFunc},
+			initialPacketSize,
+			true,
+			nil,
+		))
+	case "bbr_meta_v1":
+		connection.SetCongestionControl(congestion_meta1.NewBBRSender(
+			congestion_meta1.DefaultClock{TimeFunc: timeFunc},
+			initialPacketSize,
+			congestion.ByteCount(initialWindow)*congestion_meta1.InitialMaxDatagramSize,
+			congestion_meta1.DefaultBBRMaxCongestionWindow*congestion_meta1.InitialMaxDatagramSize,
+		))
+	case "bbr":
+		connection.SetCongestionControl(congestion_meta2.NewBbrSender(
+			congestion_meta2.DefaultClock{TimeFunc: timeFunc},
+			initialPacketSize,
+			congestion.ByteCount(initialWindow),
+		))
+	}
+}
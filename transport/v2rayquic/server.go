@@ -22,15 +22,21 @@ import (
 var _ adapter.V2RayServerTransport = (*Server)(nil)
 
 type Server struct {
-	ctx          context.Context
-	tlsConfig    tls.ServerConfig
-	quicConfig   *quic.Config
-	handler      adapter.V2RayServerTransportHandler
-	udpListener  net.PacketConn
-	quicListener qtls.Listener
+	ctx           context.Context
+	tlsConfig     tls.ServerConfig
+	quicConfig    *quic.Config
+	congestion    string
+	initialWindow uint32
+	handler       adapter.V2RayServerTransportHandler
+	udpListener   net.PacketConn
+	quicListener  qtls.Listener
 }
 
 func NewServer(ctx context.Context, options option.V2RayQUICOptions, tlsConfig tls.ServerConfig, handler adapter.V2RayServerTransportHandler) (adapter.V2RayServerTransport, error) {
+	err := checkCongestion(options.CongestionControl, options.InitialCongestionWindow)
+	if err != nil {
+		return nil, err
+	}
 	quicConfig := &quic.Config{
 		DisablePathMTUDiscovery: !C.IsLinux && !C.IsWindows,
 	}
@@ -38,10 +44,12 @@ func NewServer(ctx context.Context, options option.V2RayQUICOptions, tlsConfig t
 		tlsConfig.SetNextProtos([]string{http3.NextProtoH3})
 	}
 	server := &Server{
-		ctx:        ctx,
-		tlsConfig:  tlsConfig,
-		quicConfig: quicConfig,
-		handler:    handler,
+		ctx:           ctx,
+		tlsConfig:     tlsConfig,
+		quicConfig:    quicConfig,
+		congestion:    options.CongestionControl,
+		initialWindow: options.InitialCongestionWindow,
+		handler:       handler,
 	}
 	return server, nil
 }
@@ -71,6 +79,7 @@ func (s *Server) acceptLoop() {
 		if err != nil {
 			return
 		}
+		setCongestion(s.ctx, conn, s.congestion, s.initialWindow)
 		go func() {
 			hErr := s.streamAcceptLoop(conn)
 			if hErr != nil {
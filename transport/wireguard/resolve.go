@@ -19,7 +19,7 @@ type PeerConfig struct {
 	Endpoint       netip.AddrPort
 	PublicKey      string
 	PreSharedKey   string
-	AllowedIPs     []string
+	AllowedIPs     []netip.Prefix
 	Reserved       [3]uint8
 }
 
@@ -30,7 +30,7 @@ func (c PeerConfig) GenerateIpcLines() string {
 		ipcLines += "\npreshared_key=" + c.PreSharedKey
 	}
 	for _, allowedIP := range c.AllowedIPs {
-		ipcLines += "\nallowed_ip=" + allowedIP
+		ipcLines += "\nallowed_ip=" + allowedIP.String()
 	}
 	return ipcLines
 }
@@ -39,9 +39,7 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 	var peers []PeerConfig
 	if len(options.Peers) > 0 {
 		for peerIndex, rawPeer := range options.Peers {
-			peer := PeerConfig{
-				AllowedIPs: rawPeer.AllowedIPs,
-			}
+			var peer PeerConfig
 			destination := rawPeer.ServerOptions.Build()
 			if destination.IsFqdn() {
 				peer.destination = destination
@@ -66,11 +64,22 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 			if len(rawPeer.AllowedIPs) == 0 {
 				return nil, E.New("missing allowed_ips for peer ", peerIndex)
 			}
-			if len(rawPeer.Reserved) > 0 {
-				if len(rawPeer.Reserved) != 3 {
-					return nil, E.New("invalid reserved value for peer ", peerIndex, ", required 3 bytes, got ", len(peer.Reserved))
+			for _, allowedIP := range rawPeer.AllowedIPs {
+				prefix, err := netip.ParsePrefix(allowedIP)
+				if err != nil {
+					return nil, E.Cause(err, "parse allowed_ips for peer ", peerIndex)
+				}
+				peer.AllowedIPs = append(peer.AllowedIPs, prefix.Masked())
+			}
+			reserved := rawPeer.Reserved
+			if len(reserved) == 0 {
+				reserved = options.Reserved
+			}
+			if len(reserved) > 0 {
+				if len(reserved) != 3 {
+					return nil, E.New("invalid reserved value for peer ", peerIndex, ", required 3 bytes, got ", len(reserved))
 				}
-				copy(peer.Reserved[:], options.Reserved)
+				copy(peer.Reserved[:], reserved)
 			}
 			peers = append(peers, peer)
 		}
@@ -88,10 +97,10 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 			}
 		}
 		if addressHas4 {
-			peer.AllowedIPs = append(peer.AllowedIPs, netip.PrefixFrom(netip.IPv4Unspecified(), 0).String())
+			peer.AllowedIPs = append(peer.AllowedIPs, netip.PrefixFrom(netip.IPv4Unspecified(), 0))
 		}
 		if addressHas6 {
-			peer.AllowedIPs = append(peer.AllowedIPs, netip.PrefixFrom(netip.IPv6Unspecified(), 0).String())
+			peer.AllowedIPs = append(peer.AllowedIPs, netip.PrefixFrom(netip.IPv6Unspecified(), 0))
 		}
 		destination := options.ServerOptions.Build()
 		if destination.IsFqdn() {
@@ -116,7 +125,7 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 		}
 		if len(options.Reserved) > 0 {
 			if len(options.Reserved) != 3 {
-				return nil, E.New("invalid reserved value, required 3 bytes, got ", len(peer.Reserved))
+				return nil, E.New("invalid reserved value, required 3 bytes, got ", len(options.Reserved))
 			}
 			copy(peer.Reserved[:], options.Reserved)
 		}
@@ -125,6 +134,23 @@ func ParsePeers(options option.WireGuardOutboundOptions) ([]PeerConfig, error) {
 	return peers, nil
 }
 
+// SelectPeer returns the index of the peer whose allowed IPs route
+// destination, preferring the longest matching prefix as WireGuard does.
+func SelectPeer(peers []PeerConfig, destination netip.Addr) (int, bool) {
+	destination = destination.Unmap()
+	selected := -1
+	selectedBits := -1
+	for peerIndex, peer := range peers {
+		for _, prefix := range peer.AllowedIPs {
+			if prefix.Bits() > selectedBits && prefix.Contains(destination) {
+				selected = peerIndex
+				selectedBits = prefix.Bits()
+			}
+		}
+	}
+	return selected, selected >= 0
+}
+
 func ResolvePeers(ctx context.Context, router adapter.Router, peers []PeerConfig) error {
 	for peerIndex, peer := range peers {
 		if peer.Endpoint.IsValid() {
@@ -0,0 +1,59 @@
+package wireguard
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/sagernet/sing-box/option"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSelectPeer(t *testing.T) {
+	t.Parallel()
+	peers := []PeerConfig{
+		{AllowedIPs: []netip.Prefix{netip.MustParsePrefix("0.0.0.0/0")}},
+		{AllowedIPs: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/64")}},
+		{AllowedIPs: []netip.Prefix{netip.MustParsePrefix("10.1.0.0/16")}},
+	}
+	for _, testCase := range []struct {
+		destination string
+		peer        int
+	}{
+		{"1.1.1.1", 0},
+		{"10.0.0.1", 1},
+		{"10.1.2.3", 2},
+		{"::ffff:10.1.2.3", 2},
+		{"fd00::1", 1},
+		{"2001:db8::1", -1},
+	} {
+		peer, loaded := SelectPeer(peers, netip.MustParseAddr(testCase.destination))
+		require.Equal(t, testCase.peer >= 0, loaded, testCase.destination)
+		require.Equal(t, testCase.peer, peer, testCase.destination)
+	}
+}
+
+func TestParsePeers(t *testing.T) {
+	t.Parallel()
+	const publicKey = "Z1XXLsKYkYxuiYjJIkRvtIKFepCYHTgON+GwPq7SOV4="
+	peers, err := ParsePeers(option.WireGuardOutboundOptions{
+		Reserved: []uint8{1, 2, 3},
+		Peers: []option.WireGuardPeer{
+			{PublicKey: publicKey, AllowedIPs: []string{"10.0.0.1/8"}},
+			{PublicKey: publicKey, AllowedIPs: []string{"0.0.0.0/0", "::/0"}, Reserved: []uint8{4, 5, 6}},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")}, peers[0].AllowedIPs)
+	require.Equal(t, [3]uint8{1, 2, 3}, peers[0].Reserved)
+	require.Equal(t, [3]uint8{4, 5, 6}, peers[1].Reserved)
+
+	for _, peer := range []option.WireGuardPeer{
+		{PublicKey: publicKey},
+		{PublicKey: publicKey, AllowedIPs: []string{"10.0.0.1"}},
+		{PublicKey: publicKey, AllowedIPs: []string{"0.0.0.0/0"}, Reserved: []uint8{1}},
+	} {
+		_, err = ParsePeers(option.WireGuardOutboundOptions{Peers: []option.WireGuardPeer{peer}})
+		require.Error(t, err)
+	}
+}